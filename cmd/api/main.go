@@ -14,6 +14,9 @@ import (
 	"github.com/internal/indexer/internal/api/query"
 	"github.com/internal/indexer/internal/api/server"
 	"github.com/internal/indexer/internal/api/service"
+	"github.com/internal/indexer/internal/stream"
+	"github.com/internal/indexer/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func main() {
@@ -51,6 +54,14 @@ func main() {
 	}
 	defer store.Close()
 
+	for chain, schema := range cfg.Database.ChainSchemas {
+		if err := store.AddChainDB(types.ChainID(chain), cfg.Database.DSNWithSchema(schema), cfg.Database.MaxConnections); err != nil {
+			logger.Error("failed to connect to chain schema", "chain", chain, "schema", schema, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("using dedicated schema", "chain", chain, "schema", schema)
+	}
+
 	// 3. Setup Cache
 	redisCache, err := cache.NewRedisCache(cfg.Redis)
 	if err != nil {
@@ -61,6 +72,15 @@ func main() {
 
 	// 4. Setup Service
 	svc := service.New(store, redisCache)
+	if len(cfg.MethodSelectors) > 0 {
+		svc.SetMethodSelectors(cfg.MethodSelectors)
+	}
+	svc.SetHealthCheck(cfg.Server.HealthCheckTimeout, cfg.Server.HealthCheckCacheTTL)
+	svc.SetCacheTimeout(cfg.Redis.OpTimeout)
+	svc.SetCacheFirstPageOnly(cfg.Redis.CacheFirstPageOnly)
+	svc.SetSyncingListTTL(cfg.Redis.SyncingListTTL, cfg.Redis.SyncingBlocksBehind)
+	svc.SetFeeStatsChains(cfg.Server.FeeStatsChains)
+	prometheus.MustRegister(svc.IndexingCollector([]types.ChainID{types.ChainBTC, types.ChainETH}, logger))
 
 	// 5. Setup Auth Middleware
 	authMiddleware := auth.New(redisCache, cfg.Auth)
@@ -68,6 +88,13 @@ func main() {
 	// 6. Setup Server
 	srv := server.New(cfg.Server, svc, authMiddleware)
 
+	// New blocks arrive from the indexer over Redis pub/sub
+	streamCtx, stopStream := context.WithCancel(context.Background())
+	defer stopStream()
+	blockHub := stream.NewHub()
+	go stream.Receive(streamCtx, blockHub, redisCache, logger)
+	srv.SetBlockHub(blockHub)
+
 	// 7. Start Server with Graceful Shutdown
 	go func() {
 		if err := srv.Start(); err != nil {
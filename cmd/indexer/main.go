@@ -4,11 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
 
 	"github.com/internal/indexer/internal/api/cache"
 	apiconfig "github.com/internal/indexer/internal/api/config"
@@ -17,9 +23,12 @@ import (
 	"github.com/internal/indexer/internal/poller"
 	"github.com/internal/indexer/internal/poller/btc"
 	"github.com/internal/indexer/internal/poller/eth"
+	"github.com/internal/indexer/internal/publish"
 	"github.com/internal/indexer/internal/reorg"
 	"github.com/internal/indexer/internal/server"
 	"github.com/internal/indexer/internal/storage"
+	"github.com/internal/indexer/internal/stream"
+	"github.com/internal/indexer/internal/webhook"
 	"github.com/internal/indexer/pkg/types"
 
 	_ "github.com/lib/pq"
@@ -27,21 +36,30 @@ import (
 
 func main() {
 	configPath := flag.String("config", "config.yaml", "path to configuration file")
+	recomputeLogCounts := flag.Bool("recompute-log-counts", false, "recount transactions.log_count from stored events for chains with store_log_count, then exit")
+	recomputeTxCounts := flag.Bool("recompute-tx-counts", false, "recount blocks.tx_count from stored transactions for every enabled chain, then exit")
+	migrateDownTo := flag.Int("migrate-down", -1, "roll the schema back to this migration version (0 = undo all), then exit")
 	flag.Parse()
 
 	// Setup structured logging
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.LevelKey && a.Value.Any() == coordinator.LevelNotice {
+				a.Value = slog.StringValue("NOTICE")
+			}
+			return a
+		},
 	}))
 	slog.SetDefault(logger)
 
-	if err := run(*configPath, logger); err != nil {
+	if err := run(*configPath, *recomputeLogCounts, *recomputeTxCounts, *migrateDownTo, logger); err != nil {
 		logger.Error("fatal error", "error", err)
 		os.Exit(1)
 	}
 }
 
-func run(configPath string, logger *slog.Logger) error {
+func run(configPath string, recomputeLogCounts, recomputeTxCounts bool, migrateDownTo int, logger *slog.Logger) error {
 	// Load configuration
 	cfg, err := config.Load(configPath)
 	if err != nil {
@@ -73,13 +91,54 @@ func run(configPath string, logger *slog.Logger) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Chains with a dedicated schema get their own pool scoped via search_path
+	for chainName, chainCfg := range cfg.Chains {
+		if !chainCfg.Enabled || chainCfg.Schema == "" {
+			continue
+		}
+
+		chainDB, err := sql.Open("postgres", cfg.Database.DSNWithSchema(chainCfg.Schema))
+		if err != nil {
+			return err
+		}
+		defer chainDB.Close()
+
+		chainDB.SetMaxOpenConns(cfg.Database.MaxConnections)
+
+		if err := chainDB.Ping(); err != nil {
+			return err
+		}
+
+		store.RegisterChainDB(types.ChainID(chainName), chainCfg.Schema, chainDB)
+		store.SetPartitionSize(types.ChainID(chainName), chainCfg.PartitionSize)
+		logger.Info("using dedicated schema", "chain", chainName, "schema", chainCfg.Schema)
+	}
+
+	if migrateDownTo >= 0 {
+		if err := store.MigrateDown(ctx, migrateDownTo); err != nil {
+			return err
+		}
+		logger.Info("database migrations rolled back", "version", migrateDownTo)
+		return nil
+	}
+
 	if err := store.Migrate(ctx); err != nil {
 		return err
 	}
 	logger.Info("database migrations complete")
 
+	if recomputeLogCounts {
+		return recomputeAllLogCounts(ctx, cfg, store, logger)
+	}
+	if recomputeTxCounts {
+		return recomputeAllTxCounts(ctx, cfg, store, logger)
+	}
+
 	// Create HTTP server
 	httpServer := server.New(cfg.Server.HealthPort, cfg.Server.MetricsPort, logger)
+	httpServer.SetAdminToken(cfg.Server.AdminToken)
+	httpServer.SetMaxAdminOperations(cfg.Server.MaxAdminOps)
+	httpServer.SetHealthThresholds(cfg.Server.HealthHaltAfter, cfg.Server.HealthMaxLag)
 
 	// Initialize Redis Cache
 	redisCfg := apiconfig.RedisConfig{
@@ -105,8 +164,47 @@ func run(configPath string, logger *slog.Logger) error {
 		defer redisCache.Close()
 	}
 
+	// Optional broker publishing of committed data (relayed from the DB, at-least-once)
+	var publisher publish.Publisher
+	if cfg.Publish.Enabled {
+		publisher = publish.NewKafkaPublisher(publish.KafkaConfig{
+			Brokers:      cfg.Publish.Brokers,
+			TopicPrefix:  cfg.Publish.TopicPrefix,
+			BatchSize:    cfg.Publish.BatchSize,
+			BatchTimeout: cfg.Publish.BatchTimeout,
+		})
+		defer publisher.Close()
+		logger.Info("publishing indexed data",
+			"driver", cfg.Publish.Driver,
+			"brokers", cfg.Publish.Brokers,
+			"topic_prefix", cfg.Publish.TopicPrefix,
+		)
+	}
+	var relays []*publish.Relay
+
+	// New-block summaries for the API's SSE stream, relayed over Redis pub/sub
+	var blockHub *stream.Hub
+	if redisCache != nil {
+		blockHub = stream.NewHub()
+		go stream.Forward(ctx, blockHub, redisCache, logger)
+	}
+
+	// Optional per-address webhooks, delivered in the background
+	var webhooks *webhook.Dispatcher
+	if cfg.Webhooks.Enabled {
+		webhooks = webhook.New(store, webhook.Config{
+			Workers:      cfg.Webhooks.Workers,
+			QueueSize:    cfg.Webhooks.QueueSize,
+			Timeout:      cfg.Webhooks.Timeout,
+			MaxAttempts:  cfg.Webhooks.MaxAttempts,
+			RetryBackoff: cfg.Webhooks.RetryBackoff,
+		}, logger)
+		httpServer.SetWebhooks(webhooks)
+	}
+
 	// Create coordinators for enabled chains
 	var coordinators []*coordinator.Coordinator
+	var chainNames []string
 
 	for chainName, chainCfg := range cfg.Chains {
 		if !chainCfg.Enabled {
@@ -119,44 +217,110 @@ func run(configPath string, logger *slog.Logger) error {
 		switch chainName {
 		case "btc":
 			chainID = types.ChainBTC
-			chainPoller = btc.New(chainCfg.RPCURL, chainCfg.BatchSize)
+			btcPoller := btc.New(chainCfg.RPCURL, chainCfg.BatchSize)
+			btcPoller.SetMaxResponseSize(chainCfg.MaxResponseSize)
+			btcPoller.SetFetchConcurrency(chainCfg.FetchConcurrency)
+			btcPoller.SetSkipTransactions(chainCfg.SkipTransactions)
+			btcPoller.SetMaxInputLookups(chainCfg.MaxInputLookups)
+			btcPoller.SetRPCAuth(chainCfg.RPCUser, chainCfg.RPCPassword, chainCfg.RPCCookiePath)
+			btcPoller.SetFallbackRPCURLs(chainCfg.RPCURLs, chainCfg.RPCCooldown, logger.With("chain", "btc"))
+			chainPoller = btcPoller
+
+			// Mempool Poller (shares the block poller's RPC auth and failover)
+			if chainCfg.EnableMempool && redisCache != nil {
+				mp := btc.NewMempoolPoller(btcPoller, redisCache, logger)
+				mp.SetDedupeWindow(chainCfg.MempoolDedupe)
+				go mp.Start()
+				defer mp.Stop()
+				logger.Info("started mempool poller", "chain", chainName)
+			}
 
 		case "eth":
 			chainID = types.ChainETH
 
+			var fetcher *eth.ABIFetcher
+			if chainCfg.ABIExplorer.Enabled {
+				fetcher = eth.NewABIFetcher(
+					chainCfg.ABIExplorer.BaseURL,
+					chainCfg.ABIExplorer.APIKey,
+					chainCfg.ABIExplorer.CacheDir,
+					chainCfg.ABIExplorer.Timeout,
+				)
+			}
+
 			// Load contract ABIs
 			var contracts []eth.ContractConfig
 			for _, contractCfg := range chainCfg.Contracts {
-				abiData, err := os.ReadFile(contractCfg.ABIPath)
-				if err != nil {
-					logger.Warn("failed to load ABI, skipping contract",
+				// A contract without a usable ABI stays monitored with a nil ABI;
+				// the poller's missing_abi mode decides whether its logs are stored raw
+				contract := eth.ContractConfig{
+					Address: eth.HexToAddress(contractCfg.Address),
+					Name:    contractCfg.Address, // Use address as name if not specified
+				}
+
+				if contractCfg.ABIPath == "" {
+					logger.Info("no ABI configured for contract",
+						"address", contractCfg.Address,
+						"missing_abi", chainCfg.MissingABI,
+					)
+				} else if parsedABI, err := loadABI(contractCfg.ABIPath); err != nil {
+					logger.Warn("failed to load ABI",
 						"address", contractCfg.Address,
+						"missing_abi", chainCfg.MissingABI,
 						"error", err,
 					)
-					continue
+				} else {
+					contract.ABI = parsedABI
+					logger.Info("loaded contract ABI",
+						"address", contractCfg.Address,
+					)
+				}
+
+				if contract.ABI == nil && fetcher != nil {
+					if parsedABI, err := fetcher.Fetch(ctx, contractCfg.Address); err != nil {
+						logger.Warn("failed to fetch ABI from explorer",
+							"address", contractCfg.Address,
+							"missing_abi", chainCfg.MissingABI,
+							"error", err,
+						)
+					} else {
+						contract.ABI = parsedABI
+						logger.Info("fetched contract ABI from explorer",
+							"address", contractCfg.Address,
+						)
+					}
 				}
 
-				parsedABI, err := eth.LoadABIFromJSON(abiData)
-				if err != nil {
-					logger.Warn("failed to parse ABI, skipping contract",
+				for _, path := range contractCfg.ImplementationABIPaths {
+					parsedABI, err := loadABI(path)
+					if err != nil {
+						logger.Warn("failed to load implementation ABI",
+							"address", contractCfg.Address,
+							"path", path,
+							"error", err,
+						)
+						continue
+					}
+					contract.ImplementationABIs = append(contract.ImplementationABIs, parsedABI)
+					logger.Info("loaded implementation ABI",
 						"address", contractCfg.Address,
-						"error", err,
+						"path", path,
 					)
-					continue
 				}
 
-				contracts = append(contracts, eth.ContractConfig{
-					Address: eth.HexToAddress(contractCfg.Address),
-					ABI:     parsedABI,
-					Name:    contractCfg.Address, // Use address as name if not specified
-				})
+				if chainCfg.FilterLogTopics && (contract.ABI != nil || len(contract.ImplementationABIs) > 0) {
+					contract.Topics = eth.EventTopics(append([]*abi.ABI{contract.ABI}, contract.ImplementationABIs...)...)
+					if contract.Topics == nil {
+						logger.Info("ABI has anonymous events, fetching contract logs unfiltered",
+							"address", contractCfg.Address,
+						)
+					}
+				}
 
-				logger.Info("loaded contract ABI",
-					"address", contractCfg.Address,
-				)
+				contracts = append(contracts, contract)
 			}
 
-			chainPoller = eth.NewPoller(
+			ethPoller := eth.NewPoller(
 				chainCfg.RPCURL,
 				chainCfg.BatchSize,
 				chainCfg.LogBatchSize,
@@ -165,10 +329,28 @@ func run(configPath string, logger *slog.Logger) error {
 				contracts,
 				logger,
 			)
+			ethPoller.SetDecodeLimits(chainCfg.MaxDecodeDepth, chainCfg.MaxDecodeElements)
+			ethPoller.SetMaxDecodedSize(chainCfg.MaxDecodedBytes)
+			ethPoller.SetMissingABIMode(eth.MissingABIMode(chainCfg.MissingABI))
+			if chainCfg.MaxLogsPerPoll > 0 {
+				ethPoller.SetMaxLogsPerPoll(chainCfg.MaxLogsPerPoll)
+			}
+			ethPoller.SetMaxLogAddresses(chainCfg.MaxLogAddresses)
+			ethPoller.SetReceiptStatus(chainCfg.EventTxStatus)
+			ethPoller.SetFetchReceipts(chainCfg.FetchReceipts)
+			ethPoller.SetRPCDedupe(chainCfg.DedupeRPC)
+			ethPoller.SetTokenDecimals(chainCfg.DefaultDecimals, chainCfg.TokenDecimals)
+			ethPoller.SetMaxResponseSize(chainCfg.MaxResponseSize)
+			ethPoller.SetFetchConcurrency(chainCfg.FetchConcurrency)
+			ethPoller.SetWSURL(chainCfg.WSURL)
+			ethPoller.SetFallbackRPCURLs(chainCfg.RPCURLs, chainCfg.RPCCooldown)
+			chainPoller = ethPoller
 
 			// Mempool Poller (Separate from main poller)
 			if chainCfg.EnableMempool && redisCache != nil {
 				mp := eth.NewMempoolPoller(chainCfg.RPCURL, redisCache, logger)
+				mp.SetDedupeWindow(chainCfg.MempoolDedupe)
+				mp.SetMaxResponseSize(chainCfg.MaxResponseSize)
 				go mp.Start()
 				defer mp.Stop()
 				logger.Info("started mempool poller", "chain", chainName)
@@ -179,6 +361,18 @@ func run(configPath string, logger *slog.Logger) error {
 			continue
 		}
 
+		if chainCfg.StrictWrite {
+			store.SetStrictWriteValidation(chainID, true)
+		}
+		if chainCfg.FinalityGuard {
+			store.SetRollbackFinalityGuard(chainID, true)
+		}
+		store.SetLogCounts(chainID, chainCfg.StoreLogCount)
+		store.SetUncles(chainID, chainCfg.StoreUncles)
+		if chainCfg.VerifyWrites {
+			store.SetWriteVerification(chainID, logger)
+		}
+
 		detector := reorg.New(store, chainCfg.MaxReorgDepth, logger)
 		coord := coordinator.New(
 			chainID,
@@ -189,8 +383,26 @@ func run(configPath string, logger *slog.Logger) error {
 			logger,
 		)
 
+		if publisher != nil {
+			relay := publish.NewRelay(chainID, store, publisher, publish.RelayConfig{
+				MaxBlocks: cfg.Publish.MaxBlocks,
+				Interval:  cfg.Publish.Interval,
+				Timeout:   cfg.Publish.Timeout,
+			}, logger)
+			coord.SetCommitHook(relay.Notify)
+			relays = append(relays, relay)
+		}
+
+		if blockHub != nil {
+			coord.SetBlockHub(blockHub)
+		}
+		if webhooks != nil {
+			coord.SetWebhooks(webhooks)
+		}
+
 		httpServer.RegisterCoordinator(chainID, coord)
 		coordinators = append(coordinators, coord)
+		chainNames = append(chainNames, chainName)
 
 		logger.Info("initialized chain coordinator",
 			"chain", chainName,
@@ -203,20 +415,93 @@ func run(configPath string, logger *slog.Logger) error {
 		logger.Warn("no chains enabled, server will still run but no indexing will occur")
 	}
 
+	if webhooks != nil {
+		chainIDs := make([]types.ChainID, len(chainNames))
+		for i, name := range chainNames {
+			chainIDs[i] = types.ChainID(name)
+		}
+		if err := webhooks.Load(ctx, chainIDs); err != nil {
+			return fmt.Errorf("loading webhook subscriptions: %w", err)
+		}
+	}
+
 	// Setup signal handling
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start coordinators
+	// Start coordinators, tracking each chain's exit for a bounded shutdown
 	var wg sync.WaitGroup
-	for _, coord := range coordinators {
+	var startedMu sync.Mutex
+	started := make(map[string]<-chan struct{})
+	startCoordinator := func(name string, c *coordinator.Coordinator) <-chan struct{} {
+		exited := make(chan struct{})
+		startedMu.Lock()
+		started[name] = exited
+		startedMu.Unlock()
 		wg.Add(1)
-		go func(c *coordinator.Coordinator) {
+		go func() {
 			defer wg.Done()
+			defer close(exited)
 			if err := c.Run(ctx); err != nil && err != context.Canceled {
 				logger.Error("coordinator error", "error", err)
 			}
-		}(coord)
+		}()
+		return exited
+	}
+
+	if cfg.Backfill.MaxConcurrent > 0 && len(coordinators) > cfg.Backfill.MaxConcurrent {
+		// Limit concurrent initial backfills: a chain holds a slot until it reaches
+		// the tip, then keeps running in live mode and the next chain starts.
+		order := backfillOrder(chainNames, cfg.Backfill.Order)
+		logger.Info("limiting concurrent backfills",
+			"max_concurrent", cfg.Backfill.MaxConcurrent,
+			"order", order,
+		)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots := make(chan struct{}, cfg.Backfill.MaxConcurrent)
+			for _, i := range order {
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+
+				logger.Info("starting chain backfill", "chain", chainNames[i])
+				c := coordinators[i]
+				exited := startCoordinator(chainNames[i], c)
+				go func() {
+					select {
+					case <-c.CaughtUp():
+					case <-exited:
+					case <-ctx.Done():
+					}
+					<-slots
+				}()
+			}
+		}()
+	} else {
+		for i, coord := range coordinators {
+			startCoordinator(chainNames[i], coord)
+		}
+	}
+
+	for _, relay := range relays {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			relay.Run(ctx)
+		}()
+	}
+
+	if webhooks != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			webhooks.Run(ctx)
+		}()
 	}
 
 	// Start HTTP server (non-blocking)
@@ -243,9 +528,126 @@ func run(configPath string, logger *slog.Logger) error {
 		logger.Warn("http server shutdown error", "error", err)
 	}
 
-	// Wait for coordinators to finish
-	wg.Wait()
+	// Wait for coordinators to finish, but not forever: an RPC call that ignores
+	// cancellation must not keep the process alive
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(cfg.Server.ShutdownTimeout):
+		startedMu.Lock()
+		pending := unfinishedChains(started)
+		startedMu.Unlock()
+		logger.Warn("shutdown timed out, exiting with work in flight",
+			"timeout", cfg.Server.ShutdownTimeout,
+			"chains", pending,
+		)
+		return fmt.Errorf("shutdown timed out after %s waiting for chains %v", cfg.Server.ShutdownTimeout, pending)
+	}
 
 	logger.Info("shutdown complete")
 	return nil
 }
+
+// unfinishedChains returns the sorted names of chains whose coordinator has not exited
+func unfinishedChains(started map[string]<-chan struct{}) []string {
+	var pending []string
+	for name, exited := range started {
+		select {
+		case <-exited:
+		default:
+			pending = append(pending, name)
+		}
+	}
+	slices.Sort(pending)
+	return pending
+}
+
+// loadABI reads and parses a contract ABI JSON file
+func loadABI(path string) (*abi.ABI, error) {
+	abiData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return eth.LoadABIFromJSON(abiData)
+}
+
+// backfillOrder returns coordinator indexes sorted by the configured chain order;
+// chains not listed start afterwards in name order
+func backfillOrder(chainNames, preferred []string) []int {
+	rank := func(name string) int {
+		if i := slices.Index(preferred, name); i >= 0 {
+			return i
+		}
+		return len(preferred)
+	}
+
+	order := make([]int, len(chainNames))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		if ra, rb := rank(chainNames[a]), rank(chainNames[b]); ra != rb {
+			return ra - rb
+		}
+		return strings.Compare(chainNames[a], chainNames[b])
+	})
+	return order
+}
+
+// recomputeAllLogCounts backfills transactions.log_count up to the checkpoint of every
+// enabled chain with store_log_count set
+func recomputeAllLogCounts(ctx context.Context, cfg *config.Config, store *storage.Storage, logger *slog.Logger) error {
+	for chainName, chainCfg := range cfg.Chains {
+		if !chainCfg.Enabled || !chainCfg.StoreLogCount {
+			continue
+		}
+		chainID := types.ChainID(chainName)
+
+		cp, err := store.GetCheckpoint(ctx, chainID)
+		if err != nil {
+			return err
+		}
+		if cp == nil {
+			logger.Info("no checkpoint, nothing to recount", "chain", chainName)
+			continue
+		}
+
+		updated, err := store.RecomputeLogCounts(ctx, chainID, 0, cp.LastHeight)
+		if err != nil {
+			return err
+		}
+		logger.Info("recomputed log counts", "chain", chainName, "to_height", cp.LastHeight, "transactions", updated)
+	}
+	return nil
+}
+
+// recomputeAllTxCounts backfills blocks.tx_count up to the checkpoint of every enabled chain
+func recomputeAllTxCounts(ctx context.Context, cfg *config.Config, store *storage.Storage, logger *slog.Logger) error {
+	for chainName, chainCfg := range cfg.Chains {
+		if !chainCfg.Enabled {
+			continue
+		}
+		chainID := types.ChainID(chainName)
+
+		cp, err := store.GetCheckpoint(ctx, chainID)
+		if err != nil {
+			return err
+		}
+		if cp == nil {
+			logger.Info("no checkpoint, nothing to recount", "chain", chainName)
+			continue
+		}
+
+		updated, err := store.RecomputeTxCounts(ctx, chainID, 0, cp.LastHeight)
+		if err != nil {
+			return err
+		}
+		logger.Info("recomputed tx counts", "chain", chainName, "to_height", cp.LastHeight, "blocks", updated)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
@@ -83,3 +84,24 @@ func (m *Middleware) Handler(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// AdminHandler restricts access to requests carrying one of the configured admin keys.
+// With no admin keys configured, admin endpoints are disabled.
+func (m *Middleware) AdminHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey := r.Header.Get("X-API-Key")
+		if apiKey == "" {
+			http.Error(w, "Missing API Key", http.StatusUnauthorized)
+			return
+		}
+
+		for _, adminKey := range m.cfg.AdminKeys {
+			if subtle.ConstantTimeCompare([]byte(apiKey), []byte(adminKey)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	})
+}
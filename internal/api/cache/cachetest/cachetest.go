@@ -0,0 +1,34 @@
+// Package cachetest provides an in-memory cache.Cache for tests
+package cachetest
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Map keeps JSON-encoded values in memory, like Redis, ignoring TTLs
+type Map map[string][]byte
+
+func (c Map) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	b, ok := c[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(b, dest)
+}
+
+func (c Map) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c[key] = b
+	return nil
+}
+
+func (c Map) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 0, nil
+}
+func (c Map) Ping(ctx context.Context) error { return nil }
+func (c Map) Close() error                   { return nil }
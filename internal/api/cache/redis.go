@@ -15,6 +15,7 @@ type Cache interface {
 	Get(ctx context.Context, key string, dest interface{}) (bool, error)
 	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
 	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Ping(ctx context.Context) error
 	Close() error
 }
 
@@ -49,6 +50,11 @@ func (c *RedisCache) Close() error {
 	return c.client.Close()
 }
 
+// Ping checks Redis connectivity
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
 // Get retrieves a value from cache and unmarshals it into dest.
 // Returns true if found, false if not found.
 func (c *RedisCache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
@@ -99,6 +105,48 @@ func (c *RedisCache) Incr(ctx context.Context, key string, ttl time.Duration) (i
 	return incr.Val(), nil
 }
 
+// Publish sends payload to subscribers of a pub/sub channel
+func (c *RedisCache) Publish(ctx context.Context, channel string, payload []byte) error {
+	if err := c.client.Publish(ctx, c.cfg.KeyPrefix+channel, payload).Err(); err != nil {
+		return fmt.Errorf("redis publish: %w", err)
+	}
+	return nil
+}
+
+// Subscribe returns the messages published to a pub/sub channel until ctx is
+// done. The client resubscribes on its own after a dropped connection; messages
+// published in the meantime are lost.
+func (c *RedisCache) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	pubsub := c.client.Subscribe(ctx, c.cfg.KeyPrefix+channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("redis subscribe: %w", err)
+	}
+
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+		msgs := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				select {
+				case out <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
 // Helper methods for key generation
 
 func BlockKey(chainID, hash string) string {
@@ -113,6 +161,16 @@ func LatestBlockKey(chainID string) string {
 	return fmt.Sprintf("block:%s:latest", chainID)
 }
 
+// LatestBlocksKey caches the latest block of every chain
+func LatestBlocksKey() string {
+	return "block:all:latest"
+}
+
 func TxKey(chainID, hash string) string {
 	return fmt.Sprintf("tx:%s:%s", chainID, hash)
 }
+
+// MempoolKey holds the pending txs a chain's mempool poller last listed
+func MempoolKey(chainID string) string {
+	return fmt.Sprintf("mempool:%s:latest", chainID)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -15,6 +16,9 @@ type Config struct {
 	Redis    RedisConfig    `yaml:"redis"`
 	Auth     AuthConfig     `yaml:"auth"`
 	Logging  LoggingConfig  `yaml:"logging"`
+
+	// MethodSelectors adds 4-byte selector -> signature entries on top of the built-in set
+	MethodSelectors map[string]string `yaml:"method_selectors,omitempty"`
 }
 
 // ServerConfig holds HTTP server settings
@@ -24,6 +28,25 @@ type ServerConfig struct {
 	WriteTimeout    time.Duration `yaml:"write_timeout"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
 	EnableMempool   bool          `yaml:"enable_mempool"`
+
+	// Max block span for a single /admin/export request
+	ExportMaxBlockRange uint64 `yaml:"export_max_block_range"`
+
+	HealthCheckTimeout  time.Duration `yaml:"health_check_timeout"`   // Per-dependency ping timeout
+	HealthCheckCacheTTL time.Duration `yaml:"health_check_cache_ttl"` // How long a health result is reused
+
+	MaxRequestCost int `yaml:"max_request_cost"` // Reject requests whose parameter cost exceeds this with 400 (0 = no limit)
+
+	EnableCompression *bool `yaml:"enable_compression"` // gzip/deflate responses for clients that accept it (default true)
+
+	// FeeStatsChains lists chains whose stored fees are complete (e.g. eth with
+	// receipts); /stats reports fee summaries for these only
+	FeeStatsChains []string `yaml:"fee_stats_chains,omitempty"`
+}
+
+// CompressionEnabled reports whether responses are compressed; unset means enabled
+func (s ServerConfig) CompressionEnabled() bool {
+	return s.EnableCompression == nil || *s.EnableCompression
 }
 
 // DatabaseConfig holds PostgreSQL connection settings
@@ -35,6 +58,9 @@ type DatabaseConfig struct {
 	Password       string `yaml:"password"`
 	MaxConnections int    `yaml:"max_connections"`
 	SSLMode        string `yaml:"ssl_mode"`
+
+	// ChainSchemas maps chain ID to a dedicated schema; must match the indexer's chains.<name>.schema
+	ChainSchemas map[string]string `yaml:"chain_schemas,omitempty"`
 }
 
 // RedisConfig holds Redis connection settings
@@ -45,12 +71,19 @@ type RedisConfig struct {
 	KeyPrefix     string        `yaml:"key_prefix"`
 	CacheTTL      time.Duration `yaml:"cache_ttl"`
 	ShortCacheTTL time.Duration `yaml:"short_cache_ttl"` // For volatile data like latest block
+	OpTimeout     time.Duration `yaml:"op_timeout"`      // Per Get/Set bound in the service layer (negative = unbounded)
+
+	CacheFirstPageOnly bool `yaml:"cache_first_page_only"` // Skip caching paginated list pages after the first
+
+	SyncingListTTL      time.Duration `yaml:"syncing_list_ttl"`      // Cap on list-page TTLs while a chain is catching up (0 = fixed TTLs)
+	SyncingBlocksBehind uint64        `yaml:"syncing_blocks_behind"` // Estimated blocks behind the tip that count as catching up
 }
 
 // AuthConfig holds API authentication settings
 type AuthConfig struct {
 	RateLimitRequests int           `yaml:"rate_limit_requests"`
 	RateLimitWindow   time.Duration `yaml:"rate_limit_window"`
+	AdminKeys         []string      `yaml:"admin_keys"` // X-API-Key values allowed on /admin endpoints
 }
 
 // LoggingConfig holds logging settings
@@ -71,6 +104,12 @@ func (d DatabaseConfig) DSN() string {
 	)
 }
 
+// DSNWithSchema returns a connection string whose search_path resolves unqualified
+// table names to schema first (shared objects such as extensions stay in public)
+func (d DatabaseConfig) DSNWithSchema(schema string) string {
+	return fmt.Sprintf("%s search_path=%s,public", d.DSN(), schema)
+}
+
 // Load reads configuration from a YAML file and expands environment variables
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -95,6 +134,9 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// schemaNamePattern restricts schema names to plain lowercase identifiers
+var schemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
 func (c *Config) validate() error {
 	if c.Database.Host == "" {
 		return fmt.Errorf("database.host is required")
@@ -105,6 +147,11 @@ func (c *Config) validate() error {
 	if c.Redis.Addr == "" {
 		return fmt.Errorf("redis.addr is required")
 	}
+	for chain, schema := range c.Database.ChainSchemas {
+		if !schemaNamePattern.MatchString(schema) {
+			return fmt.Errorf("database.chain_schemas.%s must match %s", chain, schemaNamePattern)
+		}
+	}
 	return nil
 }
 
@@ -121,6 +168,15 @@ func (c *Config) setDefaults() {
 	if c.Server.ShutdownTimeout == 0 {
 		c.Server.ShutdownTimeout = 5 * time.Second
 	}
+	if c.Server.ExportMaxBlockRange == 0 {
+		c.Server.ExportMaxBlockRange = 100000
+	}
+	if c.Server.HealthCheckTimeout == 0 {
+		c.Server.HealthCheckTimeout = 2 * time.Second
+	}
+	if c.Server.HealthCheckCacheTTL == 0 {
+		c.Server.HealthCheckCacheTTL = 2 * time.Second
+	}
 
 	if c.Database.Port == 0 {
 		c.Database.Port = 5432
@@ -135,6 +191,12 @@ func (c *Config) setDefaults() {
 	if c.Redis.ShortCacheTTL == 0 {
 		c.Redis.ShortCacheTTL = 15 * time.Second
 	}
+	if c.Redis.SyncingBlocksBehind == 0 {
+		c.Redis.SyncingBlocksBehind = 10
+	}
+	if c.Redis.OpTimeout == 0 {
+		c.Redis.OpTimeout = 250 * time.Millisecond
+	}
 
 	if c.Auth.RateLimitRequests == 0 {
 		c.Auth.RateLimitRequests = 1000
@@ -0,0 +1,109 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// GetAddressLedger returns the native-value ledger of address, newest first: a
+// credit for each transaction paying it, and a transfer debit plus a fee debit
+// for each transaction it sent. Entries follow the same rules as GetAddressBalance,
+// so they sum to the balance. cursor and limit page over transactions ("block_height,tx_index"
+// of the last one), so a page holds up to three entries per transaction.
+func (s *PostgresStore) GetAddressLedger(ctx context.Context, chainID types.ChainID, address string, cursor string, limit int) ([]types.LedgerEntry, string, error) {
+	address = types.NormalizeAddress(chainID, address)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	query := `
+		SELECT block_height, tx_hash, tx_index, COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, '0'), COALESCE(fee::text, ''), COALESCE(tx_status, ''), status
+		FROM transactions
+		WHERE chain_id = $1 AND (from_addr = $2 OR to_addr = $2) AND status != 'orphaned'`
+
+	args := []interface{}{chainID, address}
+
+	if cursor != "" {
+		var height uint64
+		var index int
+		if _, err := fmt.Sscanf(cursor, "%d,%d", &height, &index); err != nil {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+		query += ` AND (block_height, tx_index) < ($3, $4)`
+		args = append(args, height, index)
+	}
+
+	query += fmt.Sprintf(" ORDER BY block_height DESC, tx_index DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.conn(chainID).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("querying ledger: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []types.LedgerEntry
+	var txs int
+	var last types.Transaction
+	for rows.Next() {
+		tx := types.Transaction{ChainID: chainID}
+		if err := rows.Scan(&tx.BlockHeight, &tx.TxHash, &tx.TxIndex, &tx.FromAddr, &tx.ToAddr, &tx.Value, &tx.Fee, &tx.TxStatus, &tx.Status); err != nil {
+			return nil, "", fmt.Errorf("scanning ledger tx: %w", err)
+		}
+		entries = append(entries, ledgerEntries(&tx, address)...)
+		txs++
+		last = tx
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if txs == limit {
+		nextCursor = fmt.Sprintf("%d,%d", last.BlockHeight, last.TxIndex)
+	}
+
+	return entries, nextCursor, nil
+}
+
+// ledgerEntries expands a transaction into address's entries. Zero-value
+// transfers (e.g. ETH contract calls) and reverted transactions produce no transfer
+// entry, but their fee still does. A self-transfer yields both a credit and a debit.
+func ledgerEntries(tx *types.Transaction, address string) []types.LedgerEntry {
+	entry := func(direction, reason, amount, counterparty string) types.LedgerEntry {
+		return types.LedgerEntry{
+			ChainID:      tx.ChainID,
+			BlockHeight:  tx.BlockHeight,
+			TxHash:       tx.TxHash,
+			TxIndex:      tx.TxIndex,
+			Direction:    direction,
+			Reason:       reason,
+			Amount:       amount,
+			Counterparty: counterparty,
+			Status:       tx.Status,
+		}
+	}
+
+	moved := !isZeroAmount(tx.Value) && tx.TxStatus != types.TxStatusReverted
+
+	var entries []types.LedgerEntry
+	if tx.ToAddr == address && moved {
+		entries = append(entries, entry("credit", types.LedgerReasonTransfer, tx.Value, tx.FromAddr))
+	}
+	if tx.FromAddr == address {
+		if moved {
+			entries = append(entries, entry("debit", types.LedgerReasonTransfer, "-"+tx.Value, tx.ToAddr))
+		}
+		if !isZeroAmount(tx.Fee) {
+			entries = append(entries, entry("debit", types.LedgerReasonFee, "-"+tx.Fee, ""))
+		}
+	}
+	return entries
+}
+
+// isZeroAmount reports whether a stored decimal amount is empty or zero
+func isZeroAmount(s string) bool {
+	return s == "" || s == "0"
+}
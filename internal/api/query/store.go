@@ -4,31 +4,51 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/internal/indexer/pkg/types"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // Store defines the interface for database access
 type Store interface {
 	GetLatestBlock(ctx context.Context, chainID types.ChainID) (*types.Block, error)
+	GetLatestBlocks(ctx context.Context) (map[types.ChainID]*types.Block, error)
 	GetBlockByHeight(ctx context.Context, chainID types.ChainID, height uint64) (*types.Block, error)
 	GetBlockByHash(ctx context.Context, chainID types.ChainID, hash string) (*types.Block, error)
+	GetBlockRefByHeight(ctx context.Context, chainID types.ChainID, height uint64) (*types.BlockRef, error)
 	GetTx(ctx context.Context, chainID types.ChainID, hash string) (*types.Transaction, error)
-	GetTransactionsByAddress(ctx context.Context, chainID types.ChainID, address string, cursor string, limit int) ([]*types.Transaction, string, error)
+	GetFirstTransaction(ctx context.Context, chainID types.ChainID, address string) (*types.Transaction, error)
+	GetTransactionsByAddress(ctx context.Context, chainID types.ChainID, address string, direction TxDirection, cursor string, limit int) ([]*types.Transaction, string, error)
+	GetAddressLedger(ctx context.Context, chainID types.ChainID, address string, cursor string, limit int) ([]types.LedgerEntry, string, error)
+	GetTransactionsByAddresses(ctx context.Context, chainID types.ChainID, addresses []string, cursor string, limit int) ([]*types.Transaction, string, error)
 	GetTransactionsByBlock(ctx context.Context, chainID types.ChainID, blockID string, cursor string, limit int) ([]*types.Transaction, string, error)
 	GetLatestTransactions(ctx context.Context, chainID types.ChainID, limit int) ([]*types.Transaction, error)
 	GetNetworkStats(ctx context.Context, chainID types.ChainID) (*types.NetworkStats, error)
+	GetRecentFees(ctx context.Context, chainID types.ChainID, since time.Time) (total, avgPerTx string, err error)
+	GetChainSettings(ctx context.Context, chainID types.ChainID) (*types.ChainSettings, error)
+	GetIndexingStatus(ctx context.Context, chainID types.ChainID) (*types.IndexingStatus, error)
 	GetBlocksRange(ctx context.Context, chainID types.ChainID, fromHeight, toHeight uint64) ([]*types.BlockSummary, error)
+	GetBlocksDesc(ctx context.Context, chainID types.ChainID, cursor string, limit int) ([]*types.BlockSummary, string, error)
+	GetBlocksByMiner(ctx context.Context, chainID types.ChainID, miner string, cursor string, limit int) ([]*types.Block, string, error)
 	GetEvents(ctx context.Context, filter EventFilter) ([]*types.Event, string, error)
 	GetContract(ctx context.Context, chainID types.ChainID, address string) (*types.Contract, error)
 	GetAddressStats(ctx context.Context, chainID types.ChainID, address string) (*types.AddressStats, error)
+	GetFinalizedAddressStats(ctx context.Context, chainID types.ChainID, address string) (*types.AddressStats, error)
 	GetTokenBalances(ctx context.Context, chainID types.ChainID, address string) ([]types.TokenBalance, error)
-	GetTokenTransfers(ctx context.Context, chainID types.ChainID, address string, limit, offset int) ([]types.TokenTransfer, error)
-	GetAddressBalance(ctx context.Context, chainID types.ChainID, address string) (string, error)
+	GetTokenTransfers(ctx context.Context, chainID types.ChainID, address string, cursor string, limit int) ([]types.TokenTransfer, string, error)
+	GetTokenHolders(ctx context.Context, chainID types.ChainID, tokenAddress string, limit, offset int) ([]types.TokenHolder, error)
+	GetAddressTokenSummary(ctx context.Context, chainID types.ChainID, address string) (*types.AddressTokenSummary, error)
+	GetAddressBalance(ctx context.Context, chainID types.ChainID, address string, minConfirmations int, finalizedOnly bool) (string, error)
+	GetTokenDecimals(ctx context.Context, chainID types.ChainID, tokenAddrs []string) (map[string]int, error)
+	StreamEvents(ctx context.Context, filter EventFilter, fn func(*types.Event) error) error
+	GetLatestContractEvents(ctx context.Context, chainID types.ChainID, contractAddr string, limit int) ([]*types.Event, error)
+	GetEventsByTxSender(ctx context.Context, chainID types.ChainID, sender string, fromHeight, toHeight uint64, cursor string, limit int) ([]*types.Event, string, error)
 	SearchTokens(ctx context.Context, query string) ([]types.Token, error)
+	Ping(ctx context.Context) error
 	Close() error
 }
 
@@ -41,15 +61,51 @@ type EventFilter struct {
 	ToHeight     *uint64
 	Cursor       string
 	Limit        int
+
+	ExcludeReverted bool // Drop events whose transaction receipt reported a revert
 }
 
+// TxDirection selects an address's transactions by which side of them it is on
+type TxDirection string
+
+const (
+	DirectionAll      TxDirection = "all"      // Sent or received (default)
+	DirectionSent     TxDirection = "sent"     // from_addr is the address
+	DirectionReceived TxDirection = "received" // to_addr is the address
+)
+
 // PostgresStore implements Store for PostgreSQL
 type PostgresStore struct {
 	db *sql.DB
+
+	// Optional per-chain pools scoped to a dedicated schema via search_path
+	chainDBs map[types.ChainID]*sql.DB
 }
 
 // NewPostgresStore creates a new PostgresStore
 func NewPostgresStore(dsn string, maxConns int) (*PostgresStore, error) {
+	db, err := openDB(dsn, maxConns)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// AddChainDB routes queries for chainID to a separate pool (dsn should set search_path
+// to the chain's schema, see config.DatabaseConfig.DSNWithSchema)
+func (s *PostgresStore) AddChainDB(chainID types.ChainID, dsn string, maxConns int) error {
+	db, err := openDB(dsn, maxConns)
+	if err != nil {
+		return fmt.Errorf("chain %s: %w", chainID, err)
+	}
+	if s.chainDBs == nil {
+		s.chainDBs = make(map[types.ChainID]*sql.DB)
+	}
+	s.chainDBs[chainID] = db
+	return nil
+}
+
+func openDB(dsn string, maxConns int) (*sql.DB, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
@@ -60,49 +116,141 @@ func NewPostgresStore(dsn string, maxConns int) (*PostgresStore, error) {
 	db.SetConnMaxLifetime(time.Hour)
 
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("pinging database: %w", err)
 	}
+	return db, nil
+}
 
-	return &PostgresStore{db: db}, nil
+// conn returns the pool for a chain, falling back to the shared schema
+func (s *PostgresStore) conn(chainID types.ChainID) *sql.DB {
+	if db, ok := s.chainDBs[chainID]; ok {
+		return db
+	}
+	return s.db
+}
+
+// conns returns every distinct pool, shared first (for cross-chain queries)
+func (s *PostgresStore) conns() []*sql.DB {
+	all := []*sql.DB{s.db}
+	for _, db := range s.chainDBs {
+		all = append(all, db)
+	}
+	return all
+}
+
+// Ping checks connectivity of the shared pool and every per-chain pool
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	for _, db := range s.conns() {
+		if err := db.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *PostgresStore) Close() error {
+	for _, db := range s.chainDBs {
+		db.Close()
+	}
 	return s.db.Close()
 }
 
 // GetLatestBlock returns the latest block for a chain
 func (s *PostgresStore) GetLatestBlock(ctx context.Context, chainID types.ChainID) (*types.Block, error) {
 	query := `
-		SELECT chain_id, height, hash, parent_hash, timestamp, status, raw_data
+		SELECT chain_id, height, hash, parent_hash, timestamp, status, COALESCE(miner, ''), tx_count, raw_data,
+		       COALESCE(size, 0), COALESCE(stripped_size, 0), COALESCE(weight, 0)
 		FROM blocks
 		WHERE chain_id = $1
 		ORDER BY height DESC
 		LIMIT 1`
 
-	return s.scanBlock(s.db.QueryRowContext(ctx, query, chainID))
+	return s.scanBlock(s.conn(chainID).QueryRowContext(ctx, query, chainID))
+}
+
+// GetLatestBlocks returns the latest block of every chain with indexed blocks,
+// one DISTINCT ON query per pool
+func (s *PostgresStore) GetLatestBlocks(ctx context.Context) (map[types.ChainID]*types.Block, error) {
+	query := `
+		SELECT DISTINCT ON (chain_id)
+		       chain_id, height, hash, parent_hash, timestamp, status, COALESCE(miner, ''), tx_count, raw_data,
+		       COALESCE(size, 0), COALESCE(stripped_size, 0), COALESCE(weight, 0)
+		FROM blocks
+		ORDER BY chain_id, height DESC`
+
+	blocks := make(map[types.ChainID]*types.Block)
+	for _, db := range s.conns() {
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("querying latest blocks: %w", err)
+		}
+		for rows.Next() {
+			b, err := s.scanBlock(rows)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scanning latest block: %w", err)
+			}
+			// A chain with its own schema is only read from its own pool
+			if s.conn(b.ChainID) == db {
+				blocks[b.ChainID] = b
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("iterating latest blocks: %w", err)
+		}
+	}
+	return blocks, nil
 }
 
 // GetBlockByHeight returns a block by height
 func (s *PostgresStore) GetBlockByHeight(ctx context.Context, chainID types.ChainID, height uint64) (*types.Block, error) {
 	query := `
-		SELECT chain_id, height, hash, parent_hash, timestamp, status, raw_data
+		SELECT chain_id, height, hash, parent_hash, timestamp, status, COALESCE(miner, ''), tx_count, raw_data,
+		       COALESCE(size, 0), COALESCE(stripped_size, 0), COALESCE(weight, 0)
 		FROM blocks
 		WHERE chain_id = $1 AND height = $2`
 
-	return s.scanBlock(s.db.QueryRowContext(ctx, query, chainID, height))
+	return s.scanBlock(s.conn(chainID).QueryRowContext(ctx, query, chainID, height))
 }
 
 // GetBlockByHash returns a block by hash
 func (s *PostgresStore) GetBlockByHash(ctx context.Context, chainID types.ChainID, hash string) (*types.Block, error) {
 	query := `
-		SELECT chain_id, height, hash, parent_hash, timestamp, status, raw_data
+		SELECT chain_id, height, hash, parent_hash, timestamp, status, COALESCE(miner, ''), tx_count, raw_data,
+		       COALESCE(size, 0), COALESCE(stripped_size, 0), COALESCE(weight, 0)
 		FROM blocks
 		WHERE chain_id = $1 AND hash = $2`
 
-	return s.scanBlock(s.db.QueryRowContext(ctx, query, chainID, hash))
+	return s.scanBlock(s.conn(chainID).QueryRowContext(ctx, query, chainID, hash))
+}
+
+// GetBlockRefByHeight returns only the height and hash of a block (cheap neighbor lookup)
+func (s *PostgresStore) GetBlockRefByHeight(ctx context.Context, chainID types.ChainID, height uint64) (*types.BlockRef, error) {
+	query := `
+		SELECT height, hash
+		FROM blocks
+		WHERE chain_id = $1 AND height = $2
+		LIMIT 1`
+
+	var ref types.BlockRef
+	err := s.conn(chainID).QueryRowContext(ctx, query, chainID, height).Scan(&ref.Height, &ref.Hash)
+	if err == sql.ErrNoRows {
+		return nil, nil // Not indexed yet (tip)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-func (s *PostgresStore) scanBlock(row *sql.Row) (*types.Block, error) {
+func (s *PostgresStore) scanBlock(row rowScanner) (*types.Block, error) {
 	var b types.Block
 	var rawData []byte
 	err := row.Scan(
@@ -112,7 +260,12 @@ func (s *PostgresStore) scanBlock(row *sql.Row) (*types.Block, error) {
 		&b.ParentHash,
 		&b.Timestamp,
 		&b.Status,
+		&b.Miner,
+		&b.TxCount,
 		&rawData,
+		&b.Size,
+		&b.StrippedSize,
+		&b.Weight,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil // Not found
@@ -127,11 +280,11 @@ func (s *PostgresStore) scanBlock(row *sql.Row) (*types.Block, error) {
 // GetTx returns a transaction by hash
 func (s *PostgresStore) GetTx(ctx context.Context, chainID types.ChainID, hash string) (*types.Transaction, error) {
 	query := `
-		SELECT chain_id, block_height, block_hash, tx_hash, COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, '0'), COALESCE(fee::text, ''), COALESCE(gas_used, 0), status, raw_data, tx_index
+		SELECT chain_id, block_height, block_hash, tx_hash, COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, '0'), COALESCE(fee::text, ''), COALESCE(gas_used, 0), status, raw_data, tx_index, log_count
 		FROM transactions
 		WHERE chain_id = $1 AND tx_hash = $2`
 
-	row := s.db.QueryRowContext(ctx, query, chainID, hash)
+	row := s.conn(chainID).QueryRowContext(ctx, query, chainID, hash)
 	var tx types.Transaction
 	var rawData []byte
 	var value, fee, toAddr, fromAddr sql.NullString
@@ -149,6 +302,7 @@ func (s *PostgresStore) GetTx(ctx context.Context, chainID types.ChainID, hash s
 		&tx.Status,
 		&rawData,
 		&tx.TxIndex,
+		&tx.LogCount,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -164,56 +318,196 @@ func (s *PostgresStore) GetTx(ctx context.Context, chainID types.ChainID, hash s
 	return &tx, nil
 }
 
-// GetTransactionsByAddress returns transactions for an address with cursor-based pagination
-func (s *PostgresStore) GetTransactionsByAddress(ctx context.Context, chainID types.ChainID, address string, cursor string, limit int) ([]*types.Transaction, string, error) {
+// GetFirstTransaction returns the earliest non-orphaned transaction sent from or to
+// an address (lowest block_height, then tx_index), or nil if there is none. The
+// sender and recipient sides are each resolved by an index seek starting at the
+// address's first_seen_height, so it does not scan the address's history.
+func (s *PostgresStore) GetFirstTransaction(ctx context.Context, chainID types.ChainID, address string) (*types.Transaction, error) {
+	address = types.NormalizeAddress(chainID, address)
+
+	const cols = `chain_id, block_height, block_hash, tx_hash, COALESCE(from_addr, '') AS from_addr, COALESCE(to_addr, '') AS to_addr, COALESCE(value::text, '0') AS value, COALESCE(fee::text, '') AS fee, COALESCE(gas_used, 0) AS gas_used, status, raw_data, tx_index, log_count`
+	const from = `FROM transactions
+			WHERE chain_id = $1 AND status != 'orphaned'
+			  AND block_height >= COALESCE((SELECT first_seen_height FROM address_stats WHERE chain_id = $1 AND address = $2), 0)`
+
+	query := `
+		SELECT * FROM (
+			(SELECT ` + cols + ` ` + from + ` AND from_addr = $2 ORDER BY block_height, tx_index LIMIT 1)
+			UNION ALL
+			(SELECT ` + cols + ` ` + from + ` AND to_addr = $2 ORDER BY block_height, tx_index LIMIT 1)
+		) first_txs
+		ORDER BY block_height, tx_index
+		LIMIT 1`
+
+	var tx types.Transaction
+	var rawData []byte
+	err := s.conn(chainID).QueryRowContext(ctx, query, chainID, address).Scan(
+		&tx.ChainID,
+		&tx.BlockHeight,
+		&tx.BlockHash,
+		&tx.TxHash,
+		&tx.FromAddr,
+		&tx.ToAddr,
+		&tx.Value,
+		&tx.Fee,
+		&tx.GasUsed,
+		&tx.Status,
+		&rawData,
+		&tx.TxIndex,
+		&tx.LogCount,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying first transaction: %w", err)
+	}
+	tx.RawData = rawData
+	return &tx, nil
+}
+
+// GetTransactionsByAddress returns transactions sent from and/or to address per
+// direction, newest first. Like GetTransactionsByAddresses, the cursor is
+// "block_height,tx_index" of the last row returned, so blocks with more txs than
+// a page split cleanly.
+func (s *PostgresStore) GetTransactionsByAddress(ctx context.Context, chainID types.ChainID, address string, direction TxDirection, cursor string, limit int) ([]*types.Transaction, string, error) {
+	address = types.NormalizeAddress(chainID, address)
 	if limit <= 0 || limit > 100 {
 		limit = 20
 	}
 
-	// Cursor format: "block_height,tx_index" (simplified to just block_height for now if tx_index is not key, but usually it is).
-	// Actually, the requirements mentioned "cursor". Let's assume pagination by block_height DESC, then tx_hash (or some tie breaker).
-	// Since we don't have a unique ID other than tx_hash, let's use block_height + tx_hash as cursor if needed.
-	// But `transactions` table usually doesn't have a guaranteed order other than block_height logic.
-	// Let's rely on block_height DESC.
-
-	// Complex cursor logic: "height,tx_index" if we had tx_index.
-	// `types.Transaction` has `TxIndex`. Let's assume the DB has it or we rely on insertion order if missing?
-	// The schema in prompt didn't explicitly list `tx_index` in `transactions` table columns but `types.Transaction` has it.
-	// Let's assume `transactions` table has `tx_index` or similar implicit ordering.
-	// Actually, looking at prompt schema:
-	// transactions: chain_id, block_height, block_hash, tx_hash, from_addr, to_addr, value, fee, gas_used, status, raw_data.
-	// No tx_index in DB schema explicitly, but good practice to have it.
-	// Let's assume for now we order by block_height DESC. If multiple txs in same block, order is arbitrary without tx_index.
-	// To be safe, let's just use block_height for now, or maybe block_height, tx_hash.
+	var match string
+	switch direction {
+	case DirectionSent:
+		match = "from_addr = $2"
+	case DirectionReceived:
+		match = "to_addr = $2"
+	default:
+		match = "(from_addr = $2 OR to_addr = $2)"
+	}
 
 	query := `
-		SELECT chain_id, block_height, block_hash, tx_hash, COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, '0'), COALESCE(fee::text, ''), COALESCE(gas_used, 0), status, raw_data
+		SELECT chain_id, block_height, block_hash, tx_hash, COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, '0'), COALESCE(fee::text, ''), COALESCE(gas_used, 0), status, raw_data, tx_index, log_count
 		FROM transactions
-		WHERE chain_id = $1 AND (from_addr = $2 OR to_addr = $2)`
+		WHERE chain_id = $1 AND ` + match
 
 	args := []interface{}{chainID, address}
-	argIdx := 3
 
 	if cursor != "" {
-		// Parse cursor, e.g., "123456" (height)
-		// For stricter pagination we need a tie-breaker.
-		query += fmt.Sprintf(" AND block_height < $%d", argIdx)
-		args = append(args, cursor) // simplified cursor as just height for now
-		argIdx++
+		height, index, err := parseHeightIndexCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += ` AND (block_height, tx_index) < ($3, $4)`
+		args = append(args, height, index)
 	}
 
-	query += fmt.Sprintf(" ORDER BY block_height DESC LIMIT $%d", argIdx)
+	query += fmt.Sprintf(" ORDER BY block_height DESC, tx_index DESC LIMIT $%d", len(args)+1)
 	args = append(args, limit)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.conn(chainID).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, "", err
 	}
 	defer rows.Close()
 
 	var txs []*types.Transaction
-	var lastHeight uint64
+	for rows.Next() {
+		var tx types.Transaction
+		var rawData []byte
+		if err := rows.Scan(
+			&tx.ChainID,
+			&tx.BlockHeight,
+			&tx.BlockHash,
+			&tx.TxHash,
+			&tx.FromAddr,
+			&tx.ToAddr,
+			&tx.Value,
+			&tx.Fee,
+			&tx.GasUsed,
+			&tx.Status,
+			&rawData,
+			&tx.TxIndex,
+			&tx.LogCount,
+		); err != nil {
+			return nil, "", err
+		}
+		tx.RawData = rawData
+		txs = append(txs, &tx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(txs) == limit {
+		nextCursor = txCursor(txs[len(txs)-1])
+	}
+
+	return txs, nextCursor, nil
+}
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be parsed
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// parseHeightIndexCursor parses a "block_height,index" cursor, where index is a
+// tx_index or log_index
+func parseHeightIndexCursor(cursor string) (uint64, int, error) {
+	var height uint64
+	var index int
+	if _, err := fmt.Sscanf(cursor, "%d,%d", &height, &index); err != nil {
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	return height, index, nil
+}
+
+// txCursor returns the cursor of the page after tx
+func txCursor(tx *types.Transaction) string {
+	return fmt.Sprintf("%d,%d", tx.BlockHeight, tx.TxIndex)
+}
+
+// GetTransactionsByAddresses returns transactions sent from or to any of addresses,
+// newest first. The cursor is "block_height,tx_index" of the last row returned, so
+// pages never skip or repeat txs that share a block.
+//
+// Each ANY arm is served by the partial from_addr/to_addr index (a strict = ANY
+// implies IS NOT NULL), combined with a BitmapOr, so cost scales with the number
+// of matching rows rather than with the table.
+func (s *PostgresStore) GetTransactionsByAddresses(ctx context.Context, chainID types.ChainID, addresses []string, cursor string, limit int) ([]*types.Transaction, string, error) {
+	addresses = types.NormalizeAddresses(chainID, addresses)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if len(addresses) == 0 {
+		return nil, "", nil
+	}
+
+	query := `
+		SELECT chain_id, block_height, block_hash, tx_hash, COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, '0'), COALESCE(fee::text, ''), COALESCE(gas_used, 0), status, raw_data, tx_index, log_count
+		FROM transactions
+		WHERE chain_id = $1 AND (from_addr = ANY($2) OR to_addr = ANY($2))`
+
+	args := []interface{}{chainID, pq.Array(addresses)}
+
+	if cursor != "" {
+		height, index, err := parseHeightIndexCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += ` AND (block_height, tx_index) < ($3, $4)`
+		args = append(args, height, index)
+	}
+
+	query += fmt.Sprintf(" ORDER BY block_height DESC, tx_index DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.conn(chainID).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
 
+	var txs []*types.Transaction
 	for rows.Next() {
 		var tx types.Transaction
 		var rawData []byte
@@ -229,17 +523,21 @@ func (s *PostgresStore) GetTransactionsByAddress(ctx context.Context, chainID ty
 			&tx.GasUsed,
 			&tx.Status,
 			&rawData,
+			&tx.TxIndex,
+			&tx.LogCount,
 		); err != nil {
 			return nil, "", err
 		}
 		tx.RawData = rawData
 		txs = append(txs, &tx)
-		lastHeight = tx.BlockHeight
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
 	}
 
 	nextCursor := ""
 	if len(txs) == limit {
-		nextCursor = fmt.Sprintf("%d", lastHeight)
+		nextCursor = txCursor(txs[len(txs)-1])
 	}
 
 	return txs, nextCursor, nil
@@ -258,7 +556,7 @@ func (s *PostgresStore) GetTransactionsByBlock(ctx context.Context, chainID type
 	// DB schema has block_height and block_hash in transactions table.
 
 	query := `
-		SELECT chain_id, block_height, block_hash, tx_hash, COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, '0'), COALESCE(fee::text, ''), COALESCE(gas_used, 0), status, raw_data, tx_index
+		SELECT chain_id, block_height, block_hash, tx_hash, COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, '0'), COALESCE(fee::text, ''), COALESCE(gas_used, 0), status, raw_data, tx_index, log_count
 		FROM transactions
 		WHERE chain_id = $1`
 
@@ -287,7 +585,7 @@ func (s *PostgresStore) GetTransactionsByBlock(ctx context.Context, chainID type
 	query += fmt.Sprintf(" ORDER BY tx_index ASC LIMIT $%d", argIdx)
 	args = append(args, limit)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.conn(chainID).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, "", err
 	}
@@ -314,6 +612,7 @@ func (s *PostgresStore) GetTransactionsByBlock(ctx context.Context, chainID type
 			&tx.Status,
 			&rawData,
 			&tx.TxIndex,
+			&tx.LogCount,
 		); err != nil {
 			return nil, "", err
 		}
@@ -343,13 +642,13 @@ func (s *PostgresStore) GetLatestTransactions(ctx context.Context, chainID types
 	// "Returns most recent txs across latest indexed blocks"
 	// Sort by block_height DESC, tx_index DESC
 	query := `
-		SELECT chain_id, block_height, block_hash, tx_hash, COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, '0'), COALESCE(fee::text, ''), COALESCE(gas_used, 0), status, raw_data, tx_index
+		SELECT chain_id, block_height, block_hash, tx_hash, COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, '0'), COALESCE(fee::text, ''), COALESCE(gas_used, 0), status, raw_data, tx_index, log_count
 		FROM transactions
 		WHERE chain_id = $1
 		ORDER BY block_height DESC, tx_index DESC
 		LIMIT $2`
 
-	rows, err := s.db.QueryContext(ctx, query, chainID, limit)
+	rows, err := s.conn(chainID).QueryContext(ctx, query, chainID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -374,6 +673,7 @@ func (s *PostgresStore) GetLatestTransactions(ctx context.Context, chainID types
 			&tx.Status,
 			&rawData,
 			&tx.TxIndex,
+			&tx.LogCount,
 		); err != nil {
 			return nil, err
 		}
@@ -393,7 +693,7 @@ func (s *PostgresStore) GetNetworkStats(ctx context.Context, chainID types.Chain
 	stats := &types.NetworkStats{ChainID: chainID}
 
 	// 1. Latest Height
-	err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(height), 0) FROM blocks WHERE chain_id = $1", chainID).Scan(&stats.LatestHeight)
+	err := s.conn(chainID).QueryRowContext(ctx, "SELECT COALESCE(MAX(height), 0) FROM blocks WHERE chain_id = $1", chainID).Scan(&stats.LatestHeight)
 	if err != nil {
 		return nil, fmt.Errorf("getting max height: %w", err)
 	}
@@ -402,14 +702,14 @@ func (s *PostgresStore) GetNetworkStats(ctx context.Context, chainID types.Chain
 	// We need current time.
 	// Lag = now - latest_block_time
 	var latestTime time.Time
-	err = s.db.QueryRowContext(ctx, "SELECT timestamp FROM blocks WHERE chain_id = $1 AND height = $2", chainID, stats.LatestHeight).Scan(&latestTime)
+	err = s.conn(chainID).QueryRowContext(ctx, "SELECT timestamp FROM blocks WHERE chain_id = $1 AND height = $2", chainID, stats.LatestHeight).Scan(&latestTime)
 	if err == nil {
 		stats.IndexerLagSeconds = int64(time.Since(latestTime).Seconds())
 	}
 
 	// Blocks last minute
 	minuteAgo := time.Now().Add(-1 * time.Minute)
-	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM blocks WHERE chain_id = $1 AND timestamp >= $2", chainID, minuteAgo).Scan(&stats.BlocksLastMinute)
+	err = s.conn(chainID).QueryRowContext(ctx, "SELECT COUNT(*) FROM blocks WHERE chain_id = $1 AND timestamp >= $2", chainID, minuteAgo).Scan(&stats.BlocksLastMinute)
 	if err != nil {
 		return nil, fmt.Errorf("counting blocks: %w", err)
 	}
@@ -423,7 +723,7 @@ func (s *PostgresStore) GetNetworkStats(ctx context.Context, chainID types.Chain
 	// Wait, if I assume it exists, I should use it.
 	// But `GetTx` earlier didn't scan it. I should probably update `GetTx` too if I want to be consistent, but for now I only need it here.
 
-	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE chain_id = $1 AND created_at >= $2", chainID, minuteAgo).Scan(&stats.TxsLastMinute)
+	err = s.conn(chainID).QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE chain_id = $1 AND created_at >= $2", chainID, minuteAgo).Scan(&stats.TxsLastMinute)
 	if err != nil {
 		// Fallback if column doesn't exist? Users said "Transactions table includes... created_at".
 		// But if the migration wasn't run, it might fail. The user said "I already have... PostgreSQL with tables".
@@ -437,8 +737,8 @@ func (s *PostgresStore) GetNetworkStats(ctx context.Context, chainID types.Chain
 	// Or just hardcode based on chain? No, calculate it.
 	if stats.LatestHeight > 100 {
 		var t1, t2 time.Time
-		s.db.QueryRowContext(ctx, "SELECT timestamp FROM blocks WHERE chain_id = $1 AND height = $2", chainID, stats.LatestHeight).Scan(&t1)
-		s.db.QueryRowContext(ctx, "SELECT timestamp FROM blocks WHERE chain_id = $1 AND height = $2", chainID, stats.LatestHeight-100).Scan(&t2)
+		s.conn(chainID).QueryRowContext(ctx, "SELECT timestamp FROM blocks WHERE chain_id = $1 AND height = $2", chainID, stats.LatestHeight).Scan(&t1)
+		s.conn(chainID).QueryRowContext(ctx, "SELECT timestamp FROM blocks WHERE chain_id = $1 AND height = $2", chainID, stats.LatestHeight-100).Scan(&t2)
 		diff := t1.Sub(t2).Seconds()
 		if diff > 0 {
 			stats.AvgBlockTime = diff / 100.0
@@ -448,6 +748,72 @@ func (s *PostgresStore) GetNetworkStats(ctx context.Context, chainID types.Chain
 	return stats, nil
 }
 
+// GetRecentFees sums the fees of transactions indexed since the given time and
+// averages them per transaction, as decimal strings in the chain's base unit. Both
+// are empty when any of those transactions has no stored fee, since a partial sum
+// would understate the total; with no transactions the average is empty.
+func (s *PostgresStore) GetRecentFees(ctx context.Context, chainID types.ChainID, since time.Time) (string, string, error) {
+	var txs, withFee int64
+	var total, avg string
+	err := s.conn(chainID).QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(fee), COALESCE(SUM(fee), 0)::text, COALESCE(ROUND(AVG(fee)), 0)::text
+		FROM transactions
+		WHERE chain_id = $1 AND created_at >= $2 AND status != 'orphaned'`, chainID, since).Scan(&txs, &withFee, &total, &avg)
+	if err != nil {
+		return "", "", fmt.Errorf("summing fees: %w", err)
+	}
+
+	if withFee < txs {
+		return "", "", nil
+	}
+	if txs == 0 {
+		return total, "", nil
+	}
+	return total, avg, nil
+}
+
+// GetIndexingStatus returns checkpoint-derived indexing progress, or nil if the
+// indexer has not written a checkpoint for the chain. BlocksBehind is left to the
+// caller, which knows the chain's block time.
+func (s *PostgresStore) GetIndexingStatus(ctx context.Context, chainID types.ChainID) (*types.IndexingStatus, error) {
+	var st types.IndexingStatus
+	var blockTime sql.NullTime
+	err := s.conn(chainID).QueryRowContext(ctx, `
+		SELECT c.last_height, c.updated_at, b.timestamp
+		FROM checkpoints c
+		LEFT JOIN blocks b ON b.chain_id = c.chain_id AND b.height = c.last_height AND b.hash = c.last_hash
+		WHERE c.chain_id = $1`, chainID).Scan(&st.CheckpointHeight, &st.CheckpointUpdatedAt, &blockTime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting checkpoint: %w", err)
+	}
+
+	st.CheckpointAge = int64(time.Since(st.CheckpointUpdatedAt).Seconds())
+	if blockTime.Valid {
+		st.LagSeconds = int64(time.Since(blockTime.Time).Seconds())
+	}
+	return &st, nil
+}
+
+// GetChainSettings returns the settings published by the indexer, or nil if none
+func (s *PostgresStore) GetChainSettings(ctx context.Context, chainID types.ChainID) (*types.ChainSettings, error) {
+	var cs types.ChainSettings
+	err := s.conn(chainID).QueryRowContext(ctx, `
+		SELECT confirmation_depth, max_reorg_depth, start_height, contracts, updated_at
+		FROM chain_settings
+		WHERE chain_id = $1
+	`, chainID).Scan(&cs.ConfirmationDepth, &cs.MaxReorgDepth, &cs.StartHeight, pq.Array(&cs.Contracts), &cs.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying chain settings: %w", err)
+	}
+	return &cs, nil
+}
+
 // GetBlocksRange returns a summary of blocks in a range
 func (s *PostgresStore) GetBlocksRange(ctx context.Context, chainID types.ChainID, fromHeight, toHeight uint64) ([]*types.BlockSummary, error) {
 	// Limit range to avoid massive queries?
@@ -458,20 +824,14 @@ func (s *PostgresStore) GetBlocksRange(ctx context.Context, chainID types.ChainI
 		toHeight = fromHeight + 100
 	}
 
-	// We need tx_count.
-	// "Returns: height, timestamp, tx_count, status"
-	// We can use a subquery for tx_count or join.
-	// GROUP BY is needed.
-
+	// tx_count is stored on the block at write time
 	query := `
-		SELECT b.height, b.timestamp, b.status, COUNT(t.tx_hash)
-		FROM blocks b
-		LEFT JOIN transactions t ON b.chain_id = t.chain_id AND b.height = t.block_height
-		WHERE b.chain_id = $1 AND b.height >= $2 AND b.height <= $3
-		GROUP BY b.height, b.timestamp, b.status
-		ORDER BY b.height ASC`
+		SELECT height, timestamp, status, tx_count
+		FROM blocks
+		WHERE chain_id = $1 AND height >= $2 AND height <= $3
+		ORDER BY height ASC`
 
-	rows, err := s.db.QueryContext(ctx, query, chainID, fromHeight, toHeight)
+	rows, err := s.conn(chainID).QueryContext(ctx, query, chainID, fromHeight, toHeight)
 	if err != nil {
 		return nil, err
 	}
@@ -488,10 +848,122 @@ func (s *PostgresStore) GetBlocksRange(ctx context.Context, chainID types.ChainI
 	return blocks, nil
 }
 
+// GetBlocksDesc returns block summaries newest first, starting at the tip, using
+// keyset pagination on height (cursor is the last height returned), so a client
+// can page backward through the whole chain.
+func (s *PostgresStore) GetBlocksDesc(ctx context.Context, chainID types.ChainID, cursor string, limit int) ([]*types.BlockSummary, string, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	query := `
+		SELECT height, timestamp, status, tx_count
+		FROM blocks
+		WHERE chain_id = $1 AND status != 'orphaned'`
+
+	args := []interface{}{chainID}
+
+	if cursor != "" {
+		height, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+		query += ` AND height < $2`
+		args = append(args, height)
+	}
+
+	query += fmt.Sprintf(" ORDER BY height DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.conn(chainID).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var blocks []*types.BlockSummary
+	for rows.Next() {
+		var b types.BlockSummary
+		if err := rows.Scan(&b.Height, &b.Timestamp, &b.Status, &b.TxCount); err != nil {
+			return nil, "", err
+		}
+		blocks = append(blocks, &b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(blocks) == limit {
+		nextCursor = strconv.FormatUint(blocks[len(blocks)-1].Height, 10)
+	}
+
+	return blocks, nextCursor, nil
+}
+
+// GetBlocksByMiner returns canonical blocks produced by miner, newest first, using
+// keyset pagination on height (cursor is the last height returned). raw_data is
+// not loaded; fetch a block individually for it.
+func (s *PostgresStore) GetBlocksByMiner(ctx context.Context, chainID types.ChainID, miner string, cursor string, limit int) ([]*types.Block, string, error) {
+	miner = types.NormalizeAddress(chainID, miner)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	query := `
+		SELECT chain_id, height, hash, parent_hash, timestamp, status, miner, tx_count
+		FROM blocks
+		WHERE chain_id = $1 AND miner = $2 AND status != 'orphaned'`
+
+	args := []interface{}{chainID, miner}
+
+	if cursor != "" {
+		height, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+		query += ` AND height < $3`
+		args = append(args, height)
+	}
+
+	query += fmt.Sprintf(" ORDER BY height DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.conn(chainID).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var blocks []*types.Block
+	for rows.Next() {
+		var b types.Block
+		if err := rows.Scan(&b.ChainID, &b.Height, &b.Hash, &b.ParentHash, &b.Timestamp, &b.Status, &b.Miner, &b.TxCount); err != nil {
+			return nil, "", err
+		}
+		blocks = append(blocks, &b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(blocks) == limit {
+		nextCursor = strconv.FormatUint(blocks[len(blocks)-1].Height, 10)
+	}
+
+	return blocks, nextCursor, nil
+}
+
+// excludeRevertedClause drops events from reverted transactions. Events without a
+// fetched receipt status (NULL) are kept.
+const excludeRevertedClause = " AND tx_status IS DISTINCT FROM 'reverted'"
+
 // GetEvents returns events with filtering and pagination
 func (s *PostgresStore) GetEvents(ctx context.Context, filter EventFilter) ([]*types.Event, string, error) {
+	filter.ContractAddr = types.NormalizeAddress(filter.ChainID, filter.ContractAddr)
 	query := `
-		SELECT chain_id, block_height, block_hash, tx_hash, log_index, contract_addr, event_name, topic0, topics, data, status
+		SELECT chain_id, block_height, block_hash, tx_hash, log_index, contract_addr, event_name, topic0, topics, data, status, COALESCE(tx_status, '')
 		FROM events
 		WHERE chain_id = $1`
 
@@ -518,6 +990,9 @@ func (s *PostgresStore) GetEvents(ctx context.Context, filter EventFilter) ([]*t
 		args = append(args, *filter.ToHeight)
 		argIdx++
 	}
+	if filter.ExcludeReverted {
+		query += excludeRevertedClause
+	}
 
 	// Cursor logic (simple height based)
 	if filter.Cursor != "" {
@@ -533,7 +1008,7 @@ func (s *PostgresStore) GetEvents(ctx context.Context, filter EventFilter) ([]*t
 	query += fmt.Sprintf(" ORDER BY block_height DESC LIMIT $%d", argIdx)
 	args = append(args, limit)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.conn(filter.ChainID).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, "", err
 	}
@@ -559,6 +1034,7 @@ func (s *PostgresStore) GetEvents(ctx context.Context, filter EventFilter) ([]*t
 			&topicsJSON,
 			&dataJSON,
 			&e.Status,
+			&e.TxStatus,
 		); err != nil {
 			return nil, "", err
 		}
@@ -581,20 +1057,247 @@ func (s *PostgresStore) GetEvents(ctx context.Context, filter EventFilter) ([]*t
 	return events, nextCursor, nil
 }
 
-// GetAddressBalance calculates the balance for an address
-func (s *PostgresStore) GetAddressBalance(ctx context.Context, chainID types.ChainID, address string) (string, error) {
+// GetLatestContractEvents returns the most recent events for a contract, newest first.
+// Served by idx_events_contract_latest.
+func (s *PostgresStore) GetLatestContractEvents(ctx context.Context, chainID types.ChainID, contractAddr string, limit int) ([]*types.Event, error) {
+	contractAddr = types.NormalizeAddress(chainID, contractAddr)
+	query := `
+		SELECT chain_id, block_height, block_hash, tx_hash, log_index, contract_addr,
+		       COALESCE(event_name, ''), topic0, topics, data, status
+		FROM events
+		WHERE chain_id = $1 AND contract_addr = $2 AND status != 'orphaned'
+		ORDER BY block_height DESC, log_index DESC
+		LIMIT $3`
+
+	rows, err := s.conn(chainID).QueryContext(ctx, query, chainID, contractAddr, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying latest events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*types.Event
+	for rows.Next() {
+		var e types.Event
+		var topicsJSON, dataJSON []byte
+
+		if err := rows.Scan(
+			&e.ChainID, &e.BlockHeight, &e.BlockHash, &e.TxHash, &e.LogIndex, &e.ContractAddr,
+			&e.EventName, &e.Topic0, &topicsJSON, &dataJSON, &e.Status,
+		); err != nil {
+			return nil, fmt.Errorf("scanning event: %w", err)
+		}
+		if len(topicsJSON) > 0 {
+			json.Unmarshal(topicsJSON, &e.Topics)
+		}
+		e.Data = dataJSON
+
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// GetEventsByTxSender returns events emitted by transactions sent from sender within
+// [fromHeight, toHeight], newest first. The cursor is "block_height,log_index" of
+// the last row returned. The sender's transactions are found through
+// idx_transactions_from_height and their events through the (chain_id, tx_hash)
+// unique index; callers bound the range, since a busy sender's history is unbounded.
+func (s *PostgresStore) GetEventsByTxSender(ctx context.Context, chainID types.ChainID, sender string, fromHeight, toHeight uint64, cursor string, limit int) ([]*types.Event, string, error) {
+	sender = types.NormalizeAddress(chainID, sender)
+	if toHeight < fromHeight {
+		return nil, "", fmt.Errorf("invalid range")
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	// Repeating the range on events lets partitioned tables prune on both sides
+	query := `
+		SELECT e.chain_id, e.block_height, e.block_hash, e.tx_hash, e.log_index, e.contract_addr,
+		       COALESCE(e.event_name, ''), e.topic0, e.topics, e.data, e.status, COALESCE(e.tx_status, '')
+		FROM transactions t
+		JOIN events e ON e.chain_id = t.chain_id AND e.tx_hash = t.tx_hash
+		               AND e.block_hash = t.block_hash AND e.block_height = t.block_height
+		WHERE t.chain_id = $1 AND t.from_addr = $2 AND t.status != 'orphaned'
+		  AND t.block_height BETWEEN $3 AND $4 AND e.block_height BETWEEN $3 AND $4`
+
+	args := []interface{}{chainID, sender, fromHeight, toHeight}
+
+	if cursor != "" {
+		height, index, err := parseHeightIndexCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += ` AND (e.block_height, e.log_index) < ($5, $6)`
+		args = append(args, height, index)
+	}
+
+	query += fmt.Sprintf(" ORDER BY e.block_height DESC, e.log_index DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.conn(chainID).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("querying sender events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*types.Event
+	for rows.Next() {
+		var e types.Event
+		var topicsJSON, dataJSON []byte
+
+		if err := rows.Scan(
+			&e.ChainID, &e.BlockHeight, &e.BlockHash, &e.TxHash, &e.LogIndex, &e.ContractAddr,
+			&e.EventName, &e.Topic0, &topicsJSON, &dataJSON, &e.Status, &e.TxStatus,
+		); err != nil {
+			return nil, "", fmt.Errorf("scanning event: %w", err)
+		}
+		if len(topicsJSON) > 0 {
+			json.Unmarshal(topicsJSON, &e.Topics)
+		}
+		e.Data = dataJSON
+
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(events) == limit {
+		last := events[len(events)-1]
+		nextCursor = fmt.Sprintf("%d,%d", last.BlockHeight, last.LogIndex)
+	}
+
+	return events, nextCursor, nil
+}
+
+// exportFetchSize is the number of rows pulled per FETCH from an export cursor
+const exportFetchSize = 1000
+
+// StreamEvents calls fn for every non-orphaned event matching filter, in chain order.
+// Rows are pulled through a server-side cursor in fixed-size batches so memory stays
+// bounded regardless of range size. Cursor and Limit in filter are ignored.
+func (s *PostgresStore) StreamEvents(ctx context.Context, filter EventFilter, fn func(*types.Event) error) error {
+	filter.ContractAddr = types.NormalizeAddress(filter.ChainID, filter.ContractAddr)
+	tx, err := s.conn(filter.ChainID).BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("beginning export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		DECLARE export_events NO SCROLL CURSOR FOR
+		SELECT chain_id, block_height, block_hash, tx_hash, log_index, contract_addr,
+		       COALESCE(event_name, ''), topic0, topics, data, raw_data, status, decode_failed
+		FROM events
+		WHERE chain_id = $1 AND status != 'orphaned'`
+
+	args := []interface{}{filter.ChainID}
+	argIdx := 2
+
+	if filter.ContractAddr != "" {
+		query += fmt.Sprintf(" AND contract_addr = $%d", argIdx)
+		args = append(args, filter.ContractAddr)
+		argIdx++
+	}
+	if filter.Topic0 != "" {
+		query += fmt.Sprintf(" AND topic0 = $%d", argIdx)
+		args = append(args, filter.Topic0)
+		argIdx++
+	}
+	if filter.FromHeight != nil {
+		query += fmt.Sprintf(" AND block_height >= $%d", argIdx)
+		args = append(args, *filter.FromHeight)
+		argIdx++
+	}
+	if filter.ToHeight != nil {
+		query += fmt.Sprintf(" AND block_height <= $%d", argIdx)
+		args = append(args, *filter.ToHeight)
+	}
+	if filter.ExcludeReverted {
+		query += excludeRevertedClause
+	}
+	query += " ORDER BY block_height, log_index"
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("declaring export cursor: %w", err)
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM export_events", exportFetchSize)
+	for {
+		n, err := fetchEvents(ctx, tx, fetch, fn)
+		if err != nil {
+			return err
+		}
+		if n < exportFetchSize {
+			return nil
+		}
+	}
+}
+
+// fetchEvents runs one FETCH on the export cursor and returns the number of rows read
+func fetchEvents(ctx context.Context, tx *sql.Tx, fetch string, fn func(*types.Event) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		return 0, fmt.Errorf("fetching events: %w", err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var e types.Event
+		var topicsJSON, dataJSON, rawData []byte
+
+		if err := rows.Scan(
+			&e.ChainID, &e.BlockHeight, &e.BlockHash, &e.TxHash, &e.LogIndex, &e.ContractAddr,
+			&e.EventName, &e.Topic0, &topicsJSON, &dataJSON, &rawData, &e.Status, &e.DecodeFailed,
+		); err != nil {
+			return n, fmt.Errorf("scanning event: %w", err)
+		}
+		if len(topicsJSON) > 0 {
+			json.Unmarshal(topicsJSON, &e.Topics)
+		}
+		e.Data = dataJSON
+		e.RawData = rawData
+
+		if err := fn(&e); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// GetAddressBalance calculates the balance for an address.
+// With minConfirmations > 0 only transactions that are finalized or have at least
+// that many blocks on top of them (height <= tip - N) are counted. finalizedOnly
+// counts finalized transactions only and takes precedence over minConfirmations.
+func (s *PostgresStore) GetAddressBalance(ctx context.Context, chainID types.ChainID, address string, minConfirmations int, finalizedOnly bool) (string, error) {
+	address = types.NormalizeAddress(chainID, address)
 	var balance string
 	query := `
 		SELECT
 			(
-				COALESCE(SUM(CASE WHEN to_addr = $2 THEN value ELSE 0 END), 0) -
-				COALESCE(SUM(CASE WHEN from_addr = $2 THEN value ELSE 0 END), 0) -
+				COALESCE(SUM(CASE WHEN to_addr = $2 AND tx_status IS DISTINCT FROM 'reverted' THEN value ELSE 0 END), 0) -
+				COALESCE(SUM(CASE WHEN from_addr = $2 AND tx_status IS DISTINCT FROM 'reverted' THEN value ELSE 0 END), 0) -
 				COALESCE(SUM(CASE WHEN from_addr = $2 THEN fee ELSE 0 END), 0)
 			)::TEXT
 		FROM transactions
-		WHERE chain_id = $1 AND (from_addr = $2 OR to_addr = $2) AND status != 'orphaned'
-	`
-	err := s.db.QueryRowContext(ctx, query, chainID, address).Scan(&balance)
+		WHERE chain_id = $1 AND (from_addr = $2 OR to_addr = $2) AND status != 'orphaned'`
+	args := []interface{}{chainID, address}
+
+	if finalizedOnly {
+		query += ` AND status = 'finalized'`
+	} else if minConfirmations > 0 {
+		query += `
+			AND (
+				status = 'finalized' OR
+				block_height <= (SELECT COALESCE(MAX(height), 0) FROM blocks WHERE chain_id = $1 AND status != 'orphaned') - $3
+			)`
+		args = append(args, minConfirmations)
+	}
+
+	err := s.conn(chainID).QueryRowContext(ctx, query, args...).Scan(&balance)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "0", nil // Or 0 if no txs
@@ -612,8 +1315,9 @@ func (s *PostgresStore) GetAddressBalance(ctx context.Context, chainID types.Cha
 
 // GetContract returns a contract by address
 func (s *PostgresStore) GetContract(ctx context.Context, chainID types.ChainID, address string) (*types.Contract, error) {
+	address = types.NormalizeAddress(chainID, address)
 	var c types.Contract
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(chainID).QueryRowContext(ctx, `
 		SELECT chain_id, address, creator_addr, tx_hash, block_height, created_at
 		FROM contracts
 		WHERE chain_id = $1 AND address = $2
@@ -631,8 +1335,9 @@ func (s *PostgresStore) GetContract(ctx context.Context, chainID types.ChainID,
 
 // GetAddressStats returns analytics for an address
 func (s *PostgresStore) GetAddressStats(ctx context.Context, chainID types.ChainID, address string) (*types.AddressStats, error) {
+	address = types.NormalizeAddress(chainID, address)
 	var stats types.AddressStats
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(chainID).QueryRowContext(ctx, `
 		SELECT chain_id, address, balance, total_received, total_sent, tx_count, first_seen_height, last_seen_height, last_updated_at
 		FROM address_stats
 		WHERE chain_id = $1 AND address = $2
@@ -651,14 +1356,54 @@ func (s *PostgresStore) GetAddressStats(ctx context.Context, chainID types.Chain
 	return &stats, nil
 }
 
+// GetFinalizedAddressStats computes an address's stats from its finalized transactions.
+// address_stats is updated as blocks are written, pending ones included, so finalized
+// figures are aggregated from transactions on each call instead. LastUpdatedAt is
+// left zero. Returns nil when the address has no finalized transactions.
+func (s *PostgresStore) GetFinalizedAddressStats(ctx context.Context, chainID types.ChainID, address string) (*types.AddressStats, error) {
+	address = types.NormalizeAddress(chainID, address)
+	var txCount int
+	var firstSeen, lastSeen sql.NullInt64
+	stats := types.AddressStats{ChainID: chainID, Address: address}
+	err := s.conn(chainID).QueryRowContext(ctx, `
+		SELECT
+			(
+				COALESCE(SUM(CASE WHEN to_addr = $2 AND tx_status IS DISTINCT FROM 'reverted' THEN value ELSE 0 END), 0) -
+				COALESCE(SUM(CASE WHEN from_addr = $2 AND tx_status IS DISTINCT FROM 'reverted' THEN value ELSE 0 END), 0) -
+				COALESCE(SUM(CASE WHEN from_addr = $2 THEN fee ELSE 0 END), 0)
+			)::TEXT,
+			COALESCE(SUM(CASE WHEN to_addr = $2 AND tx_status IS DISTINCT FROM 'reverted' THEN value ELSE 0 END), 0)::TEXT,
+			COALESCE(SUM(CASE WHEN from_addr = $2 AND tx_status IS DISTINCT FROM 'reverted' THEN value ELSE 0 END), 0)::TEXT,
+			COUNT(*) FILTER (WHERE from_addr = $2) + COUNT(*) FILTER (WHERE to_addr = $2),
+			MIN(block_height),
+			MAX(block_height)
+		FROM transactions
+		WHERE chain_id = $1 AND (from_addr = $2 OR to_addr = $2) AND status = 'finalized'
+	`, string(chainID), address).Scan(
+		&stats.Balance, &stats.TotalReceived, &stats.TotalSent, &txCount, &firstSeen, &lastSeen,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("aggregating finalized address stats: %w", err)
+	}
+	if txCount == 0 {
+		return nil, nil
+	}
+
+	stats.TxCount = txCount
+	stats.FirstSeenHeight = firstSeen.Int64
+	stats.LastSeenHeight = lastSeen.Int64
+	return &stats, nil
+}
+
 func (s *PostgresStore) GetTokenBalances(ctx context.Context, chainID types.ChainID, address string) ([]types.TokenBalance, error) {
+	address = types.NormalizeAddress(chainID, address)
 	query := `
 		SELECT chain_id, address, token_address, balance, last_updated_at
 		FROM token_balances
 		WHERE chain_id = $1 AND address = $2 AND balance > 0
 		ORDER BY balance DESC
 	`
-	rows, err := s.db.QueryContext(ctx, query, chainID, address)
+	rows, err := s.conn(chainID).QueryContext(ctx, query, chainID, address)
 	if err != nil {
 		return nil, fmt.Errorf("querying token balances: %w", err)
 	}
@@ -675,17 +1420,38 @@ func (s *PostgresStore) GetTokenBalances(ctx context.Context, chainID types.Chai
 	return balances, nil
 }
 
-func (s *PostgresStore) GetTokenTransfers(ctx context.Context, chainID types.ChainID, address string, limit, offset int) ([]types.TokenTransfer, error) {
+// GetTokenTransfers returns the token transfers sent from or to address, newest
+// first. The cursor is "block_height,log_index" of the last transfer returned, so
+// pages stay consistent while new transfers arrive at the tip.
+func (s *PostgresStore) GetTokenTransfers(ctx context.Context, chainID types.ChainID, address string, cursor string, limit int) ([]types.TokenTransfer, string, error) {
+	address = types.NormalizeAddress(chainID, address)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
 	query := `
 		SELECT chain_id, tx_hash, log_index, token_address, from_addr, to_addr, amount, block_height, block_hash, timestamp
 		FROM token_transfers
-		WHERE chain_id = $1 AND (from_addr = $2 OR to_addr = $2)
-		ORDER BY block_height DESC, log_index DESC
-		LIMIT $3 OFFSET $4
-	`
-	rows, err := s.db.QueryContext(ctx, query, chainID, address, limit, offset)
+		WHERE chain_id = $1 AND (from_addr = $2 OR to_addr = $2)`
+
+	args := []interface{}{chainID, address}
+
+	if cursor != "" {
+		height, index, err := parseHeightIndexCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += ` AND (block_height, log_index) < ($3, $4)`
+		args = append(args, height, index)
+	}
+
+	// One extra row tells whether there is a next page, so the last page has no cursor
+	query += fmt.Sprintf(" ORDER BY block_height DESC, log_index DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit+1)
+
+	rows, err := s.conn(chainID).QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("querying token transfers: %w", err)
+		return nil, "", fmt.Errorf("querying token transfers: %w", err)
 	}
 	defer rows.Close()
 
@@ -697,11 +1463,131 @@ func (s *PostgresStore) GetTokenTransfers(ctx context.Context, chainID types.Cha
 			&t.FromAddr, &t.ToAddr, &t.Amount,
 			&t.BlockHeight, &t.BlockHash, &t.Timestamp,
 		); err != nil {
-			return nil, fmt.Errorf("scanning token transfer: %w", err)
+			return nil, "", fmt.Errorf("scanning token transfer: %w", err)
 		}
 		transfers = append(transfers, t)
 	}
-	return transfers, nil
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(transfers) > limit {
+		transfers = transfers[:limit]
+		last := transfers[limit-1]
+		nextCursor = fmt.Sprintf("%d,%d", last.BlockHeight, last.LogIndex)
+	}
+	return transfers, nextCursor, nil
+}
+
+// GetTokenHolders returns the addresses with a positive balance of tokenAddress,
+// largest first. balance is NUMERIC, so it sorts by value; ties are broken by
+// address to keep offset pages stable. Rank counts from 1 across pages.
+func (s *PostgresStore) GetTokenHolders(ctx context.Context, chainID types.ChainID, tokenAddress string, limit, offset int) ([]types.TokenHolder, error) {
+	tokenAddress = types.NormalizeAddress(chainID, tokenAddress)
+	query := `
+		SELECT address, balance
+		FROM token_balances
+		WHERE chain_id = $1 AND token_address = $2 AND balance > 0
+		ORDER BY balance DESC, address
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := s.conn(chainID).QueryContext(ctx, query, chainID, tokenAddress, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("querying token holders: %w", err)
+	}
+	defer rows.Close()
+
+	holders := []types.TokenHolder{}
+	for rows.Next() {
+		h := types.TokenHolder{Rank: offset + len(holders) + 1}
+		if err := rows.Scan(&h.Address, &h.Balance); err != nil {
+			return nil, fmt.Errorf("scanning token holder: %w", err)
+		}
+		holders = append(holders, h)
+	}
+	return holders, rows.Err()
+}
+
+// MaxTokenSummaryTransfers caps how many of an address's most recent token transfers
+// GetAddressTokenSummary aggregates, bounding the cost for addresses with huge histories
+const MaxTokenSummaryTransfers = 50000
+
+// GetAddressTokenSummary returns per-token transfer counts and in/out totals for an
+// address, with each token's maintained balance from token_balances. The transfers
+// are read through the from_addr/to_addr indexes, newest first, up to
+// MaxTokenSummaryTransfers; past that the summary is marked Truncated.
+func (s *PostgresStore) GetAddressTokenSummary(ctx context.Context, chainID types.ChainID, address string) (*types.AddressTokenSummary, error) {
+	address = types.NormalizeAddress(chainID, address)
+	query := `
+		WITH recent AS (
+			SELECT token_address, from_addr, to_addr, amount, block_height
+			FROM token_transfers
+			WHERE chain_id = $1 AND (from_addr = $2 OR to_addr = $2)
+			ORDER BY block_height DESC, log_index DESC
+			LIMIT $3
+		)
+		SELECT r.token_address, COUNT(*),
+			COALESCE(SUM(r.amount) FILTER (WHERE r.to_addr = $2), 0)::TEXT,
+			COALESCE(SUM(r.amount) FILTER (WHERE r.from_addr = $2), 0)::TEXT,
+			COALESCE(b.balance, 0)::TEXT,
+			MAX(r.block_height)
+		FROM recent r
+		LEFT JOIN token_balances b ON b.chain_id = $1 AND b.address = $2 AND b.token_address = r.token_address
+		GROUP BY r.token_address, b.balance
+		ORDER BY COUNT(*) DESC, r.token_address
+	`
+	rows, err := s.conn(chainID).QueryContext(ctx, query, string(chainID), address, MaxTokenSummaryTransfers)
+	if err != nil {
+		return nil, fmt.Errorf("querying token summary: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &types.AddressTokenSummary{ChainID: chainID, Address: address, Tokens: []types.TokenSummary{}}
+	var total int
+	for rows.Next() {
+		var t types.TokenSummary
+		if err := rows.Scan(&t.TokenAddress, &t.TransferCount, &t.TotalIn, &t.TotalOut, &t.Balance, &t.LastTransferHeight); err != nil {
+			return nil, fmt.Errorf("scanning token summary: %w", err)
+		}
+		summary.Tokens = append(summary.Tokens, t)
+		total += t.TransferCount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating token summary: %w", err)
+	}
+	summary.Truncated = total >= MaxTokenSummaryTransfers
+	return summary, nil
+}
+
+// GetTokenDecimals returns known decimals keyed by token address.
+// Tokens without metadata (or with NULL decimals) are absent from the map.
+func (s *PostgresStore) GetTokenDecimals(ctx context.Context, chainID types.ChainID, tokenAddrs []string) (map[string]int, error) {
+	decimals := make(map[string]int)
+	if len(tokenAddrs) == 0 {
+		return decimals, nil
+	}
+
+	query := `
+		SELECT address, decimals
+		FROM tokens
+		WHERE chain_id = $1 AND address = ANY($2) AND decimals IS NOT NULL
+	`
+	rows, err := s.conn(chainID).QueryContext(ctx, query, chainID, pq.Array(tokenAddrs))
+	if err != nil {
+		return nil, fmt.Errorf("querying token decimals: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var addr string
+		var d int
+		if err := rows.Scan(&addr, &d); err != nil {
+			return nil, fmt.Errorf("scanning token decimals: %w", err)
+		}
+		decimals[addr] = d
+	}
+	return decimals, rows.Err()
 }
 
 func (s *PostgresStore) SearchTokens(ctx context.Context, q string) ([]types.Token, error) {
@@ -717,7 +1603,23 @@ func (s *PostgresStore) SearchTokens(ctx context.Context, q string) ([]types.Tok
 	// TODO: Add ordering by similarity if simple ILIKE is not enough, but ILIKE is standard for fuzzy start.
 	// For better ranking: ORDER BY similarity(name, $2) DESC
 
-	rows, err := s.db.QueryContext(ctx, query, match)
+	// Tokens may live in per-chain schemas; search each pool until the limit is reached
+	var tokens []types.Token
+	for _, db := range s.conns() {
+		found, err := searchTokensIn(ctx, db, query, match)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, found...)
+		if len(tokens) >= 10 {
+			return tokens[:10], nil
+		}
+	}
+	return tokens, nil
+}
+
+func searchTokensIn(ctx context.Context, db *sql.DB, query, match string) ([]types.Token, error) {
+	rows, err := db.QueryContext(ctx, query, match)
 	if err != nil {
 		return nil, fmt.Errorf("searching tokens: %w", err)
 	}
@@ -2,6 +2,10 @@ package query
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -21,8 +25,8 @@ func TestGetLatestBlock(t *testing.T) {
 	chainID := types.ChainBTC
 	now := time.Now()
 
-	rows := sqlmock.NewRows([]string{"chain_id", "height", "hash", "parent_hash", "timestamp", "status", "raw_data"}).
-		AddRow("btc", 100, "hash123", "hash122", now, "finalized", []byte("{}"))
+	rows := sqlmock.NewRows([]string{"chain_id", "height", "hash", "parent_hash", "timestamp", "status", "miner", "tx_count", "raw_data", "size", "stripped_size", "weight"}).
+		AddRow("btc", 100, "hash123", "hash122", now, "finalized", "bc1qminer", 3, []byte("{}"), 1500000, 900000, 3990000)
 
 	mock.ExpectQuery("^SELECT (.+) FROM blocks WHERE chain_id = \\$1 ORDER BY height DESC LIMIT 1$").
 		WithArgs(chainID).
@@ -42,12 +46,65 @@ func TestGetLatestBlock(t *testing.T) {
 	if block.Height != 100 {
 		t.Errorf("expected height 100, got %d", block.Height)
 	}
+	if block.TxCount != 3 {
+		t.Errorf("expected tx count 3, got %d", block.TxCount)
+	}
+	if block.Size != 1500000 || block.StrippedSize != 900000 || block.Weight != 3990000 {
+		t.Errorf("unexpected size/weight: %d/%d/%d", block.Size, block.StrippedSize, block.Weight)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
 
+func TestGetLatestBlocks(t *testing.T) {
+	shared, sharedMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer shared.Close()
+	ethDB, ethMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer ethDB.Close()
+
+	// eth lives in its own schema; the shared pool still has an old eth row
+	store := &PostgresStore{db: shared, chainDBs: map[types.ChainID]*sql.DB{types.ChainETH: ethDB}}
+	columns := []string{"chain_id", "height", "hash", "parent_hash", "timestamp", "status", "miner", "tx_count", "raw_data", "size", "stripped_size", "weight"}
+	now := time.Now()
+
+	sharedMock.ExpectQuery("^SELECT DISTINCT ON \\(chain_id\\) (.+) FROM blocks ORDER BY chain_id, height DESC$").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("btc", 800000, "btchash", "btcparent", now, "pending", "", 2500, nil, 0, 0, 0).
+			AddRow("eth", 5, "stale", "staleparent", now, "finalized", "", 1, nil, 0, 0, 0))
+	ethMock.ExpectQuery("^SELECT DISTINCT ON \\(chain_id\\) (.+) FROM blocks ORDER BY chain_id, height DESC$").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("eth", 19000000, "ethhash", "ethparent", now, "pending", "0xminer", 150, nil, 0, 0, 0))
+
+	blocks, err := store.GetLatestBlocks(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 chains, got %d", len(blocks))
+	}
+	if b := blocks[types.ChainBTC]; b == nil || b.Height != 800000 {
+		t.Errorf("unexpected btc block: %+v", b)
+	}
+	if b := blocks[types.ChainETH]; b == nil || b.Height != 19000000 || b.Hash != "ethhash" {
+		t.Errorf("expected eth block from its own schema, got %+v", b)
+	}
+
+	if err := sharedMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+	if err := ethMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestGetTransactionsByBlock(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -62,9 +119,9 @@ func TestGetTransactionsByBlock(t *testing.T) {
 
 	rows := sqlmock.NewRows([]string{
 		"chain_id", "block_height", "block_hash", "tx_hash", "from_addr", "to_addr",
-		"value", "fee", "gas_used", "status", "raw_data", "tx_index",
+		"value", "fee", "gas_used", "status", "raw_data", "tx_index", "log_count",
 	}).AddRow(
-		"eth", 100, "hash100", "tx1", "from1", "to1", "1000", "21000", 21000, "finalized", []byte("{}"), 0,
+		"eth", 100, "hash100", "tx1", "from1", "to1", "1000", "21000", 21000, "finalized", []byte("{}"), 0, 3,
 	)
 
 	// Expect query for height
@@ -84,6 +141,9 @@ func TestGetTransactionsByBlock(t *testing.T) {
 	if txs[0].TxHash != "tx1" {
 		t.Errorf("expected tx1, got %s", txs[0].TxHash)
 	}
+	if txs[0].LogCount != 3 {
+		t.Errorf("expected log count 3, got %d", txs[0].LogCount)
+	}
 }
 
 func TestGetNetworkStats(t *testing.T) {
@@ -136,3 +196,941 @@ func TestGetNetworkStats(t *testing.T) {
 		t.Errorf("expected 5 blocks, got %d", stats.BlocksLastMinute)
 	}
 }
+
+func TestGetBlockRefByHeight(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainETH
+
+	mock.ExpectQuery("^SELECT height, hash FROM blocks WHERE chain_id = \\$1 AND height = \\$2 LIMIT 1$").
+		WithArgs(chainID, 101).
+		WillReturnRows(sqlmock.NewRows([]string{"height", "hash"}).AddRow(101, "hash101"))
+
+	ref, err := store.GetBlockRefByHeight(context.Background(), chainID, 101)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ref == nil || ref.Hash != "hash101" {
+		t.Errorf("expected ref hash101, got %+v", ref)
+	}
+
+	// Tip: no successor indexed yet
+	mock.ExpectQuery("^SELECT height, hash FROM blocks").
+		WithArgs(chainID, 102).
+		WillReturnRows(sqlmock.NewRows([]string{"height", "hash"}))
+
+	ref, err = store.GetBlockRefByHeight(context.Background(), chainID, 102)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ref != nil {
+		t.Errorf("expected nil ref at tip, got %+v", ref)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetChainSettings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainETH
+
+	mock.ExpectQuery("SELECT confirmation_depth, max_reorg_depth, start_height, contracts, updated_at\\s+FROM chain_settings").
+		WithArgs(chainID).
+		WillReturnRows(sqlmock.NewRows([]string{"confirmation_depth", "max_reorg_depth", "start_height", "contracts", "updated_at"}).
+			AddRow(12, 64, 17000000, "{0xabc,0xdef}", time.Now()))
+
+	cs, err := store.GetChainSettings(context.Background(), chainID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cs == nil || cs.ConfirmationDepth != 12 || cs.MaxReorgDepth != 64 || cs.StartHeight != 17000000 {
+		t.Errorf("expected depths 12/64 from 17000000, got %+v", cs)
+	}
+	if len(cs.Contracts) != 2 || cs.Contracts[1] != "0xdef" {
+		t.Errorf("expected 2 contracts, got %v", cs.Contracts)
+	}
+
+	// Indexer has not published settings yet
+	mock.ExpectQuery("FROM chain_settings").
+		WithArgs(types.ChainBTC).
+		WillReturnRows(sqlmock.NewRows([]string{"confirmation_depth", "max_reorg_depth", "start_height", "contracts", "updated_at"}))
+
+	cs, err = store.GetChainSettings(context.Background(), types.ChainBTC)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cs != nil {
+		t.Errorf("expected nil settings, got %+v", cs)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetAddressBalance_MinConfirmations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainBTC
+	addr := "bc1qtest"
+
+	mock.ExpectQuery("status != 'orphaned' AND \\( status = 'finalized' OR block_height <= \\(SELECT COALESCE\\(MAX\\(height\\), 0\\) FROM blocks").
+		WithArgs(chainID, addr, 6).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("150000"))
+
+	balance, err := store.GetAddressBalance(context.Background(), chainID, addr, 6, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if balance != "150000" {
+		t.Errorf("expected balance 150000, got %s", balance)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetAddressBalance_FinalizedOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainBTC
+	addr := "bc1qtest"
+
+	// finalized_only replaces the confirmation window, so there is no $3
+	mock.ExpectQuery("status != 'orphaned' AND status = 'finalized'$").
+		WithArgs(chainID, addr).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("100000"))
+
+	balance, err := store.GetAddressBalance(context.Background(), chainID, addr, 6, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if balance != "100000" {
+		t.Errorf("expected balance 100000, got %s", balance)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetFinalizedAddressStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainBTC
+	ctx := context.Background()
+
+	mock.ExpectQuery("FROM transactions WHERE chain_id = \\$1 AND \\(from_addr = \\$2 OR to_addr = \\$2\\) AND status = 'finalized'").
+		WithArgs("btc", "bc1qtest").
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "received", "sent", "tx_count", "first", "last"}).
+			AddRow("700", "1000", "300", 3, 10, 42))
+
+	stats, err := store.GetFinalizedAddressStats(ctx, chainID, "bc1qtest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if stats == nil || stats.Balance != "700" || stats.TxCount != 3 || stats.FirstSeenHeight != 10 || stats.LastSeenHeight != 42 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// No finalized transactions yet
+	mock.ExpectQuery("status = 'finalized'").
+		WithArgs("btc", "bc1qnew").
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "received", "sent", "tx_count", "first", "last"}).
+			AddRow("0", "0", "0", 0, nil, nil))
+
+	if stats, err := store.GetFinalizedAddressStats(ctx, chainID, "bc1qnew"); err != nil || stats != nil {
+		t.Errorf("expected nil stats, got %+v, %v", stats, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	from, to := uint64(100), uint64(200)
+	filter := EventFilter{ChainID: types.ChainETH, ContractAddr: "0xabc", FromHeight: &from, ToHeight: &to}
+
+	cols := []string{"chain_id", "block_height", "block_hash", "tx_hash", "log_index", "contract_addr",
+		"event_name", "topic0", "topics", "data", "raw_data", "status", "decode_failed"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^DECLARE export_events NO SCROLL CURSOR FOR SELECT").
+		WithArgs(types.ChainETH, "0xabc", from, to).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^FETCH 1000 FROM export_events$").
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow("eth", 150, "0xb", "0xt", 0, "0xabc", "Transfer", "0xt0", []byte(`["0xt0"]`), []byte(`{"value":"1"}`), []byte(`{}`), "pending", false).
+			AddRow("eth", 151, "0xc", "0xu", 1, "0xabc", "", "0xt0", []byte(`["0xt0"]`), nil, []byte(`{}`), "pending", true))
+	mock.ExpectRollback()
+
+	var got []*types.Event
+	err = store.StreamEvents(context.Background(), filter, func(e *types.Event) error {
+		got = append(got, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(got))
+	}
+	if got[0].EventName != "Transfer" || string(got[0].Data) != `{"value":"1"}` {
+		t.Errorf("unexpected first event: %+v", got[0])
+	}
+	if !got[1].DecodeFailed || len(got[1].Topics) != 1 {
+		t.Errorf("unexpected second event: %+v", got[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetLatestContractEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectQuery("ORDER BY block_height DESC, log_index DESC LIMIT \\$3").
+		WithArgs(types.ChainETH, "0xabc", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"chain_id", "block_height", "block_hash", "tx_hash", "log_index",
+			"contract_addr", "event_name", "topic0", "topics", "data", "status"}).
+			AddRow("eth", 200, "0xb2", "0xt2", 3, "0xabc", "Transfer", "0xt0", []byte(`["0xt0"]`), []byte(`{}`), "pending").
+			AddRow("eth", 200, "0xb2", "0xt2", 1, "0xabc", "Approval", "0xt1", []byte(`["0xt1"]`), []byte(`{}`), "pending"))
+
+	events, err := store.GetLatestContractEvents(context.Background(), types.ChainETH, "0xabc", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(events) != 2 || events[0].LogIndex != 3 {
+		t.Errorf("unexpected events: %+v", events)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetTransactionsByAddresses(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	chainID := types.ChainETH
+	addresses := []string{"0xaaa", "0xbbb"}
+
+	rows := sqlmock.NewRows([]string{
+		"chain_id", "block_height", "block_hash", "tx_hash", "from_addr", "to_addr",
+		"value", "fee", "gas_used", "status", "raw_data", "tx_index", "log_count",
+	}).
+		AddRow("eth", 100, "hash100", "tx2", "0xaaa", "0xccc", "1", "21000", 21000, "finalized", []byte("{}"), 4, 0).
+		AddRow("eth", 100, "hash100", "tx1", "0xccc", "0xbbb", "1", "21000", 21000, "finalized", []byte("{}"), 2, 1)
+
+	mock.ExpectQuery("^SELECT (.+) FROM transactions WHERE chain_id = \\$1 AND \\(from_addr = ANY\\(\\$2\\) OR to_addr = ANY\\(\\$2\\)\\) AND \\(block_height, tx_index\\) < \\(\\$3, \\$4\\) ORDER BY block_height DESC, tx_index DESC LIMIT \\$5$").
+		WithArgs(chainID, sqlmock.AnyArg(), uint64(101), 0, 2).
+		WillReturnRows(rows)
+
+	txs, cursor, err := store.GetTransactionsByAddresses(context.Background(), chainID, addresses, "101,0", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 txs, got %d", len(txs))
+	}
+	// A full page hands back the last row's position so the next page starts within block 100
+	if cursor != "100,2" {
+		t.Errorf("expected cursor 100,2, got %q", cursor)
+	}
+
+	if _, _, err := store.GetTransactionsByAddresses(context.Background(), chainID, addresses, "bogus", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetBlocksByMiner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	chainID := types.ChainETH
+	miner := "0xfee"
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"chain_id", "height", "hash", "parent_hash", "timestamp", "status", "miner", "tx_count"}).
+		AddRow("eth", 180, "hash180", "hash179", now, "finalized", miner, 12).
+		AddRow("eth", 150, "hash150", "hash149", now, "finalized", miner, 0)
+
+	mock.ExpectQuery("^SELECT (.+) FROM blocks WHERE chain_id = \\$1 AND miner = \\$2 AND status != 'orphaned' AND height < \\$3 ORDER BY height DESC LIMIT \\$4$").
+		WithArgs(chainID, miner, uint64(200), 2).
+		WillReturnRows(rows)
+
+	blocks, cursor, err := store.GetBlocksByMiner(context.Background(), chainID, miner, "200", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(blocks) != 2 || blocks[0].Miner != miner {
+		t.Fatalf("expected 2 blocks by %s, got %+v", miner, blocks)
+	}
+	if cursor != "150" {
+		t.Errorf("expected cursor 150, got %q", cursor)
+	}
+
+	if _, _, err := store.GetBlocksByMiner(context.Background(), chainID, miner, "bogus", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetEventsByTxSender(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainETH
+
+	rows := sqlmock.NewRows([]string{"chain_id", "block_height", "block_hash", "tx_hash", "log_index", "contract_addr", "event_name", "topic0", "topics", "data", "status", "tx_status"}).
+		AddRow("eth", 120, "0xb120", "0xt1", 7, "0xtoken", "Transfer", "0xddf2", []byte(`["0xddf2"]`), []byte(`{}`), "finalized", "").
+		AddRow("eth", 120, "0xb120", "0xt1", 3, "0xtoken", "Approval", "0x8c5b", []byte(`["0x8c5b"]`), []byte(`{}`), "finalized", "")
+
+	// The sender is lowercased and the cursor resumes below (height, log_index)
+	mock.ExpectQuery("FROM transactions t JOIN events e ON (.+) WHERE t.chain_id = \\$1 AND t.from_addr = \\$2 AND t.status != 'orphaned' AND t.block_height BETWEEN \\$3 AND \\$4 AND e.block_height BETWEEN \\$3 AND \\$4 AND \\(e.block_height, e.log_index\\) < \\(\\$5, \\$6\\) ORDER BY e.block_height DESC, e.log_index DESC LIMIT \\$7$").
+		WithArgs(chainID, "0xabc", uint64(100), uint64(200), uint64(150), 0, 2).
+		WillReturnRows(rows)
+
+	events, cursor, err := store.GetEventsByTxSender(context.Background(), chainID, "0xABC", 100, 200, "150,0", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(events) != 2 || events[0].EventName != "Transfer" || len(events[0].Topics) != 1 {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if cursor != "120,3" {
+		t.Errorf("expected cursor 120,3, got %q", cursor)
+	}
+
+	if _, _, err := store.GetEventsByTxSender(context.Background(), chainID, "0xabc", 100, 200, "bogus", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+	if _, _, err := store.GetEventsByTxSender(context.Background(), chainID, "0xabc", 200, 100, "", 2); err == nil {
+		t.Error("expected error for inverted range")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetAddressLedger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainETH
+
+	rows := sqlmock.NewRows([]string{"block_height", "tx_hash", "tx_index", "from_addr", "to_addr", "value", "fee", "tx_status", "status"}).
+		AddRow(120, "0xt3", 4, "0xabc", "0xabc", "5", "1", "", "finalized").          // Self-transfer
+		AddRow(110, "0xt2", 2, "0xabc", "0xtoken", "0", "3", "success", "finalized"). // Contract call: fee only
+		AddRow(100, "0xt1", 0, "0xdef", "0xabc", "50", "2", "", "finalized").         // Received: sender pays the fee
+		AddRow(90, "0xt0", 1, "0xabc", "0xdef", "9", "4", "reverted", "finalized")    // Reverted: fee only, no value moved
+
+	mock.ExpectQuery("FROM transactions WHERE chain_id = \\$1 AND \\(from_addr = \\$2 OR to_addr = \\$2\\) AND status != 'orphaned' AND \\(block_height, tx_index\\) < \\(\\$3, \\$4\\) ORDER BY block_height DESC, tx_index DESC LIMIT \\$5$").
+		WithArgs(chainID, "0xabc", uint64(130), 0, 4).
+		WillReturnRows(rows)
+
+	entries, cursor, err := store.GetAddressLedger(context.Background(), chainID, "0xABC", "130,0", 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []struct{ tx, direction, reason, amount string }{
+		{"0xt3", "credit", types.LedgerReasonTransfer, "5"},
+		{"0xt3", "debit", types.LedgerReasonTransfer, "-5"},
+		{"0xt3", "debit", types.LedgerReasonFee, "-1"},
+		{"0xt2", "debit", types.LedgerReasonFee, "-3"},
+		{"0xt1", "credit", types.LedgerReasonTransfer, "50"},
+		{"0xt0", "debit", types.LedgerReasonFee, "-4"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.TxHash != w.tx || e.Direction != w.direction || e.Reason != w.reason || e.Amount != w.amount {
+			t.Errorf("entry %d: expected %+v, got %+v", i, w, e)
+		}
+	}
+	if entries[4].Counterparty != "0xdef" {
+		t.Errorf("expected counterparty 0xdef, got %q", entries[4].Counterparty)
+	}
+	if cursor != "90,1" {
+		t.Errorf("expected cursor 90,1, got %q", cursor)
+	}
+
+	if _, _, err := store.GetAddressLedger(context.Background(), chainID, "0xabc", "bogus", 3); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetFirstTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainETH
+
+	rows := sqlmock.NewRows([]string{"chain_id", "block_height", "block_hash", "tx_hash", "from_addr", "to_addr", "value", "fee", "gas_used", "status", "raw_data", "tx_index", "log_count"}).
+		AddRow("eth", 42, "0xb42", "0xfirst", "0xdef", "0xabc", "100", "1", 21000, "finalized", nil, 3, 0)
+
+	// Both sides are bounded by address_stats.first_seen_height and seek the lowest (height, index)
+	mock.ExpectQuery("block_height >= COALESCE\\(\\(SELECT first_seen_height FROM address_stats WHERE chain_id = \\$1 AND address = \\$2\\), 0\\) AND from_addr = \\$2 ORDER BY block_height, tx_index LIMIT 1\\) UNION ALL (.+) AND to_addr = \\$2 ORDER BY block_height, tx_index LIMIT 1\\) \\) first_txs ORDER BY block_height, tx_index LIMIT 1").
+		WithArgs(chainID, "0xabc").
+		WillReturnRows(rows)
+
+	tx, err := store.GetFirstTransaction(context.Background(), chainID, "0xABC")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tx == nil || tx.TxHash != "0xfirst" || tx.BlockHeight != 42 || tx.TxIndex != 3 {
+		t.Fatalf("unexpected tx: %+v", tx)
+	}
+
+	mock.ExpectQuery("first_txs").
+		WithArgs(chainID, "0xnone").
+		WillReturnRows(sqlmock.NewRows([]string{"chain_id"}))
+
+	tx, err = store.GetFirstTransaction(context.Background(), chainID, "0xnone")
+	if err != nil || tx != nil {
+		t.Errorf("expected nil tx for an address without transactions, got %+v, %v", tx, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetRecentFees(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainETH
+	since := time.Now().Add(-time.Minute)
+	feeQuery := "SELECT COUNT\\(\\*\\), COUNT\\(fee\\), COALESCE\\(SUM\\(fee\\), 0\\)::text, COALESCE\\(ROUND\\(AVG\\(fee\\)\\), 0\\)::text FROM transactions WHERE chain_id = \\$1 AND created_at >= \\$2 AND status != 'orphaned'"
+	columns := []string{"count", "with_fee", "total", "avg"}
+
+	mock.ExpectQuery(feeQuery).WithArgs(chainID, since).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(4, 4, "84000000000000", "21000000000000"))
+	total, avg, err := store.GetRecentFees(context.Background(), chainID, since)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if total != "84000000000000" || avg != "21000000000000" {
+		t.Errorf("unexpected fees: total %q, avg %q", total, avg)
+	}
+
+	// One transaction without a fee makes the window incomplete
+	mock.ExpectQuery(feeQuery).WithArgs(chainID, since).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(4, 3, "63000000000000", "21000000000000"))
+	total, avg, err = store.GetRecentFees(context.Background(), chainID, since)
+	if err != nil || total != "" || avg != "" {
+		t.Errorf("expected empty fees for incomplete data, got %q, %q, %v", total, avg, err)
+	}
+
+	// No transactions: nothing paid, no average
+	mock.ExpectQuery(feeQuery).WithArgs(chainID, since).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(0, 0, "0", "0"))
+	total, avg, err = store.GetRecentFees(context.Background(), chainID, since)
+	if err != nil || total != "0" || avg != "" {
+		t.Errorf("expected zero total without average, got %q, %q, %v", total, avg, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetBlocksDesc(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainBTC
+	now := time.Now()
+	columns := []string{"height", "timestamp", "status", "tx_count"}
+
+	// First page starts at the tip
+	mock.ExpectQuery("FROM blocks WHERE chain_id = \\$1 AND status != 'orphaned' ORDER BY height DESC LIMIT \\$2$").
+		WithArgs(chainID, 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(102, now, "pending", 5).
+			AddRow(101, now, "pending", 3))
+
+	blocks, cursor, err := store.GetBlocksDesc(context.Background(), chainID, "", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(blocks) != 2 || blocks[0].Height != 102 || cursor != "101" {
+		t.Fatalf("unexpected first page: %d blocks, cursor %q", len(blocks), cursor)
+	}
+
+	// The next page resumes below the cursor and ends at the oldest block
+	mock.ExpectQuery("FROM blocks WHERE chain_id = \\$1 AND status != 'orphaned' AND height < \\$2 ORDER BY height DESC LIMIT \\$3$").
+		WithArgs(chainID, uint64(101), 2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(100, now, "finalized", 1))
+
+	blocks, cursor, err = store.GetBlocksDesc(context.Background(), chainID, cursor, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(blocks) != 1 || blocks[0].Height != 100 || cursor != "" {
+		t.Errorf("unexpected last page: %+v, cursor %q", blocks, cursor)
+	}
+
+	if _, _, err := store.GetBlocksDesc(context.Background(), chainID, "tip", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetIndexingStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	updated := time.Now().Add(-5 * time.Second)
+	blockTime := time.Now().Add(-120 * time.Second)
+
+	mock.ExpectQuery("^SELECT c.last_height, c.updated_at, b.timestamp FROM checkpoints c LEFT JOIN blocks b (.+) WHERE c.chain_id = \\$1$").
+		WithArgs(types.ChainETH).
+		WillReturnRows(sqlmock.NewRows([]string{"last_height", "updated_at", "timestamp"}).AddRow(500, updated, blockTime))
+
+	st, err := store.GetIndexingStatus(context.Background(), types.ChainETH)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if st.CheckpointHeight != 500 {
+		t.Errorf("expected height 500, got %d", st.CheckpointHeight)
+	}
+	if st.LagSeconds < 119 || st.LagSeconds > 125 {
+		t.Errorf("expected lag of about 120s, got %d", st.LagSeconds)
+	}
+	if st.CheckpointAge < 4 || st.CheckpointAge > 10 {
+		t.Errorf("expected checkpoint age of about 5s, got %d", st.CheckpointAge)
+	}
+
+	// No checkpoint yet
+	mock.ExpectQuery("^SELECT (.+) FROM checkpoints c").
+		WithArgs(types.ChainBTC).
+		WillReturnRows(sqlmock.NewRows([]string{"last_height", "updated_at", "timestamp"}))
+
+	st, err = store.GetIndexingStatus(context.Background(), types.ChainBTC)
+	if err != nil || st != nil {
+		t.Errorf("expected nil status without a checkpoint, got %+v, %v", st, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetEvents_ExcludeReverted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	chainID := types.ChainETH
+
+	rows := sqlmock.NewRows([]string{"chain_id", "block_height", "block_hash", "tx_hash", "log_index", "contract_addr", "event_name", "topic0", "topics", "data", "status", "tx_status"}).
+		AddRow("eth", 10, "hash10", "0xtx", 0, "0xabc", "Transfer", "0x01", []byte(`["0x01"]`), []byte(`{}`), "finalized", "success")
+
+	mock.ExpectQuery("^SELECT (.+) FROM events WHERE chain_id = \\$1 AND tx_status IS DISTINCT FROM 'reverted' ORDER BY block_height DESC LIMIT \\$2$").
+		WithArgs(chainID, 20).
+		WillReturnRows(rows)
+
+	events, _, err := store.GetEvents(context.Background(), EventFilter{ChainID: chainID, ExcludeReverted: true})
+	if err != nil {
+		t.Fatalf("GetEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].TxStatus != types.TxStatusSuccess {
+		t.Errorf("expected 1 successful event, got %+v", events)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAddressLookups_Normalized(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	ctx := context.Background()
+	now := time.Now()
+
+	// Mixed-case (EIP-55) ETH lookups hit the lowercase stored form
+	mock.ExpectQuery("^SELECT (.+) FROM token_balances WHERE chain_id = \\$1 AND address = \\$2").
+		WithArgs(types.ChainETH, "0x52908400098527886e0f7030069857d2e4169ee7").
+		WillReturnRows(sqlmock.NewRows([]string{"chain_id", "address", "token_address", "balance", "last_updated_at"}).
+			AddRow("eth", "0x52908400098527886e0f7030069857d2e4169ee7", "0xtoken", "5", now))
+
+	balances, err := store.GetTokenBalances(ctx, types.ChainETH, "0x52908400098527886E0F7030069857D2E4169EE7")
+	if err != nil {
+		t.Fatalf("GetTokenBalances: %v", err)
+	}
+	if len(balances) != 1 {
+		t.Errorf("expected 1 balance, got %d", len(balances))
+	}
+
+	// BTC addresses are case-sensitive and passed through unchanged
+	mock.ExpectQuery("^SELECT (.+) FROM address_stats WHERE chain_id = \\$1 AND address = \\$2").
+		WithArgs("btc", "1BoatSLRHtKNngkdXEeobR76b53LETtpyT").
+		WillReturnRows(sqlmock.NewRows([]string{"chain_id", "address", "balance", "total_received", "total_sent", "tx_count", "first_seen_height", "last_seen_height", "last_updated_at"}))
+
+	if _, err := store.GetAddressStats(ctx, types.ChainBTC, "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"); err != nil {
+		t.Fatalf("GetAddressStats: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetAddressTokenSummary(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectQuery("^WITH recent AS \\(\\s*SELECT (.+) FROM token_transfers WHERE chain_id = \\$1 AND \\(from_addr = \\$2 OR to_addr = \\$2\\)(.+)LEFT JOIN token_balances").
+		WithArgs("eth", "0x52908400098527886e0f7030069857d2e4169ee7", MaxTokenSummaryTransfers).
+		WillReturnRows(sqlmock.NewRows([]string{"token_address", "count", "total_in", "total_out", "balance", "last_height"}).
+			AddRow("0xtoken1", 3, "500", "200", "300", 120).
+			AddRow("0xtoken2", 1, "0", "0", "0", 90))
+
+	summary, err := store.GetAddressTokenSummary(context.Background(), types.ChainETH, "0x52908400098527886E0F7030069857D2E4169EE7")
+	if err != nil {
+		t.Fatalf("GetAddressTokenSummary: %v", err)
+	}
+	if summary.Address != "0x52908400098527886e0f7030069857d2e4169ee7" || summary.Truncated {
+		t.Errorf("unexpected summary header: %+v", summary)
+	}
+	if len(summary.Tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(summary.Tokens))
+	}
+	first := summary.Tokens[0]
+	if first.TokenAddress != "0xtoken1" || first.TransferCount != 3 || first.TotalIn != "500" || first.TotalOut != "200" || first.Balance != "300" || first.LastTransferHeight != 120 {
+		t.Errorf("unexpected first token: %+v", first)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetTransactionsByAddress_DenseBlockCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	ctx := context.Background()
+
+	// Block 10 holds more of each address's txs than fit on a page
+	type row struct {
+		height uint64
+		index  int
+		from   string
+		to     string
+	}
+	chainRows := []row{
+		{11, 0, "0xa", "0xb"},
+		{10, 4, "0xb", "0xc"},
+		{10, 3, "0xa", "0xc"},
+		{10, 2, "0xb", "0xa"},
+		{10, 1, "0xa", "0xb"},
+		{10, 0, "0xb", "0xc"},
+		{9, 7, "0xa", "0xc"},
+	}
+	columns := []string{"chain_id", "block_height", "block_hash", "tx_hash", "from_addr", "to_addr", "value", "fee", "gas_used", "status", "raw_data", "tx_index", "log_count"}
+
+	// expectPage serves what the query returns for address after (height, index):
+	// matching rows in (block_height, tx_index) DESC order, limit 2
+	expectPage := func(address string, cursor []driver.Value) {
+		rows := sqlmock.NewRows(columns)
+		n := 0
+		for _, r := range chainRows {
+			if r.from != address && r.to != address {
+				continue
+			}
+			if cursor != nil {
+				h, i := cursor[0].(uint64), cursor[1].(int)
+				if r.height > h || (r.height == h && r.index >= i) {
+					continue
+				}
+			}
+			if n == 2 {
+				break
+			}
+			rows.AddRow("eth", r.height, "hash", fmt.Sprintf("tx%d_%d", r.height, r.index), r.from, r.to, "0", "", 0, "finalized", nil, r.index, 0)
+			n++
+		}
+		args := []driver.Value{types.ChainETH, address}
+		q := "AND \\(from_addr = \\$2 OR to_addr = \\$2\\) ORDER BY block_height DESC, tx_index DESC LIMIT \\$3$"
+		if cursor != nil {
+			args = append(args, cursor...)
+			q = "AND \\(block_height, tx_index\\) < \\(\\$3, \\$4\\) ORDER BY block_height DESC, tx_index DESC LIMIT \\$5$"
+		}
+		mock.ExpectQuery(q).WithArgs(append(args, 2)...).WillReturnRows(rows)
+	}
+
+	for _, tc := range []struct {
+		address string
+		want    []string
+		cursors [][]driver.Value
+	}{
+		{"0xa", []string{"tx11_0", "tx10_3", "tx10_2", "tx10_1", "tx9_7"}, [][]driver.Value{nil, {uint64(10), 3}, {uint64(10), 1}}},
+		{"0xb", []string{"tx11_0", "tx10_4", "tx10_2", "tx10_1", "tx10_0"}, [][]driver.Value{nil, {uint64(10), 4}, {uint64(10), 1}}},
+	} {
+		var got []string
+		cursor := ""
+		for page, c := range tc.cursors {
+			expectPage(tc.address, c)
+			txs, next, err := store.GetTransactionsByAddress(ctx, types.ChainETH, tc.address, DirectionAll, cursor, 2)
+			if err != nil {
+				t.Fatalf("%s page %d: %v", tc.address, page, err)
+			}
+			for _, tx := range txs {
+				got = append(got, tx.TxHash)
+			}
+			cursor = next
+		}
+		if cursor != "" {
+			t.Errorf("%s: expected no cursor after the last page, got %q", tc.address, cursor)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.address, tc.want, got)
+		}
+	}
+
+	if _, _, err := store.GetTransactionsByAddress(ctx, types.ChainETH, "0xa", DirectionAll, "10", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor for a height-only cursor, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetTransactionsByAddress_Direction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	columns := []string{"chain_id", "block_height", "block_hash", "tx_hash", "from_addr", "to_addr", "value", "fee", "gas_used", "status", "raw_data", "tx_index", "log_count"}
+
+	for _, tc := range []struct {
+		direction TxDirection
+		where     string
+	}{
+		{DirectionSent, "WHERE chain_id = \\$1 AND from_addr = \\$2 ORDER BY"},
+		{DirectionReceived, "WHERE chain_id = \\$1 AND to_addr = \\$2 ORDER BY"},
+		{DirectionAll, "WHERE chain_id = \\$1 AND \\(from_addr = \\$2 OR to_addr = \\$2\\) ORDER BY"},
+	} {
+		mock.ExpectQuery(tc.where).
+			WithArgs(types.ChainETH, "0xa", 20).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		if _, _, err := store.GetTransactionsByAddress(context.Background(), types.ChainETH, "0xa", tc.direction, "", 20); err != nil {
+			t.Fatalf("%s: %v", tc.direction, err)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetTokenHolders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	// Ordered by the NUMERIC column itself, never a text cast that would put "9" above "10"
+	mock.ExpectQuery("^SELECT address, balance FROM token_balances WHERE chain_id = \\$1 AND token_address = \\$2 AND balance > 0 ORDER BY balance DESC, address LIMIT \\$3 OFFSET \\$4$").
+		WithArgs(types.ChainETH, "0x52908400098527886e0f7030069857d2e4169ee7", 2, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"address", "balance"}).
+			AddRow("0xb", "100000000000000000000").
+			AddRow("0xa", "9"))
+
+	holders, err := store.GetTokenHolders(context.Background(), types.ChainETH, "0x52908400098527886E0F7030069857D2E4169EE7", 2, 10)
+	if err != nil {
+		t.Fatalf("GetTokenHolders: %v", err)
+	}
+	if len(holders) != 2 || holders[0].Rank != 11 || holders[1].Rank != 12 {
+		t.Fatalf("expected ranks 11 and 12 on the second page, got %+v", holders)
+	}
+	if holders[0].Address != "0xb" || holders[0].Balance != "100000000000000000000" {
+		t.Errorf("unexpected first holder %+v", holders[0])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetTokenTransfers_Cursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	ctx := context.Background()
+	now := time.Now()
+	columns := []string{"chain_id", "tx_hash", "log_index", "token_address", "from_addr", "to_addr", "amount", "block_height", "block_hash", "timestamp"}
+
+	// First page: newest first, one row past the limit to detect the next page
+	mock.ExpectQuery("AND \\(from_addr = \\$2 OR to_addr = \\$2\\) ORDER BY block_height DESC, log_index DESC LIMIT \\$3$").
+		WithArgs(types.ChainETH, "0xa", 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("eth", "tx3", 5, "0xt", "0xa", "0xb", "1", 20, "h20", now).
+			AddRow("eth", "tx2", 1, "0xt", "0xb", "0xa", "1", 20, "h20", now).
+			AddRow("eth", "tx1", 9, "0xt", "0xa", "0xb", "1", 19, "h19", now))
+
+	transfers, next, err := store.GetTokenTransfers(ctx, types.ChainETH, "0xa", "", 2)
+	if err != nil {
+		t.Fatalf("GetTokenTransfers: %v", err)
+	}
+	if len(transfers) != 2 || transfers[0].TxHash != "tx3" || transfers[1].TxHash != "tx2" {
+		t.Fatalf("expected tx3 then tx2, got %+v", transfers)
+	}
+	if next != "20,1" {
+		t.Fatalf("expected cursor 20,1, got %q", next)
+	}
+
+	// Last page: fewer rows than the lookahead, so no cursor even when the page is full
+	mock.ExpectQuery("AND \\(block_height, log_index\\) < \\(\\$3, \\$4\\) ORDER BY block_height DESC, log_index DESC LIMIT \\$5$").
+		WithArgs(types.ChainETH, "0xa", uint64(20), 1, 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("eth", "tx1", 9, "0xt", "0xa", "0xb", "1", 19, "h19", now).
+			AddRow("eth", "tx0", 0, "0xt", "0xb", "0xa", "1", 18, "h18", now))
+
+	transfers, next, err = store.GetTokenTransfers(ctx, types.ChainETH, "0xa", next, 2)
+	if err != nil {
+		t.Fatalf("GetTokenTransfers: %v", err)
+	}
+	if len(transfers) != 2 || transfers[0].TxHash != "tx1" || next != "" {
+		t.Errorf("expected the last 2 transfers and no cursor, got %d and %q", len(transfers), next)
+	}
+
+	if _, _, err := store.GetTokenTransfers(ctx, types.ChainETH, "0xa", "20", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
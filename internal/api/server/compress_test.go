@@ -0,0 +1,89 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/internal/indexer/internal/api/config"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                      "",
+		"gzip":                  "gzip",
+		"deflate, gzip;q=0.5":   "gzip",
+		"deflate":               "deflate",
+		"gzip;q=0, deflate":     "deflate",
+		"GZIP ; q=1":            "gzip",
+		"br, identity":          "",
+		"gzip;q=0, deflate;q=0": "",
+	} {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"raw_data":"00"}`, 200)
+	s := &Server{}
+	h := s.compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, large[:100])
+			io.WriteString(w, large[100:])
+		case "/metrics":
+			io.WriteString(w, large)
+		default:
+			io.WriteString(w, `{"status":"ok"}`)
+		}
+	}))
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/large", "gzip")
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected a gzipped 201 varying on Accept-Encoding, got %d %v", rec.Code, rec.Header())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != large {
+		t.Errorf("decompressed body differs from the original")
+	}
+
+	// Short bodies, clients without gzip/deflate and /metrics are sent as is
+	for _, tc := range []struct{ path, acceptEncoding, body string }{
+		{"/small", "gzip", `{"status":"ok"}`},
+		{"/large", "", large},
+		{"/metrics", "gzip", large},
+	} {
+		rec := get(tc.path, tc.acceptEncoding)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != tc.body {
+			t.Errorf("%s (Accept-Encoding %q): expected an uncompressed body, got %v", tc.path, tc.acceptEncoding, rec.Header())
+		}
+	}
+
+	// Disabled by config
+	off := false
+	s.cfg = config.ServerConfig{EnableCompression: &off}
+	h = s.compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, large)
+	}))
+	if rec := get("/large", "gzip"); rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected no compression when disabled, got %v", rec.Header())
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Request cost model. Each request costs costBase plus points for the query
+// parameters that make it expensive; requests above server.max_request_cost are
+// rejected before reaching a handler. The store still applies its own caps.
+const (
+	costBase       = 1
+	costPerLimit   = 10  // 1 point per 10 rows requested (limit)
+	costPerOffset  = 100 // 1 point per 100 rows skipped (offset)
+	costPerRange   = 10  // 1 point per 10 blocks spanned (from/to, from_height/to_height)
+	costIncludeRaw = 5   // include_raw=true
+	costNeighbors  = 2   // neighbors=true
+)
+
+// requestCost returns the cost of a request's query parameters. Malformed
+// numbers cost nothing here; the handler rejects or defaults them.
+func requestCost(q url.Values) int {
+	cost := costBase
+
+	if limit, err := strconv.Atoi(q.Get("limit")); err == nil && limit > 0 {
+		cost += ceilDiv(limit, costPerLimit)
+	}
+	if offset, err := strconv.Atoi(q.Get("offset")); err == nil && offset > 0 {
+		cost += ceilDiv(offset, costPerOffset)
+	}
+	cost += rangeCost(q.Get("from"), q.Get("to"))
+	cost += rangeCost(q.Get("from_height"), q.Get("to_height"))
+
+	if q.Get("include_raw") == "true" {
+		cost += costIncludeRaw
+	}
+	if q.Get("neighbors") == "true" {
+		cost += costNeighbors
+	}
+	return cost
+}
+
+// rangeCost charges for the block span between from and to, when both are set
+func rangeCost(fromStr, toStr string) int {
+	from, err := strconv.ParseUint(fromStr, 10, 64)
+	if err != nil {
+		return 0
+	}
+	to, err := strconv.ParseUint(toStr, 10, 64)
+	if err != nil || to < from {
+		return 0
+	}
+	span := min(to-from, 1<<40) + 1 // Capped far past any budget, so it cannot overflow
+	return ceilDiv(int(span), costPerRange)
+}
+
+// ceilDiv divides n by d rounding up, without overflowing near MaxInt
+func ceilDiv(n, d int) int {
+	q := n / d
+	if n%d != 0 {
+		q++
+	}
+	return q
+}
+
+// costLimit rejects requests whose cost exceeds server.max_request_cost with a 400.
+// A budget of zero or less disables the check.
+func (s *Server) costLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if budget := s.cfg.MaxRequestCost; budget > 0 {
+			if cost := requestCost(r.URL.Query()); cost > budget {
+				http.Error(w, fmt.Sprintf("request cost %d exceeds budget %d: lower limit, offset or range, or drop include_raw/neighbors", cost, budget), http.StatusBadRequest)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/internal/indexer/internal/api/config"
+)
+
+func TestRequestCost(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{query: "", want: 1},
+		{query: "limit=20", want: 3},
+		{query: "limit=25", want: 4},
+		{query: "limit=100&offset=1000", want: 21},
+		{query: "from=100&to=199", want: 11},
+		{query: "from_height=100&to_height=100", want: 2},
+		{query: "from=200&to=100", want: 1},
+		{query: "include_raw=true&neighbors=true", want: 8},
+		{query: "limit=abc&offset=-5", want: 1},
+		{query: "from=0&to=18446744073709551615", want: 1 + (1<<40)/10 + 1},
+	}
+
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		if got := requestCost(q); got != tt.want {
+			t.Errorf("requestCost(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCostLimit(t *testing.T) {
+	s := &Server{cfg: config.ServerConfig{MaxRequestCost: 10}}
+	h := s.costLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for query, want := range map[string]int{
+		"limit=50":                  http.StatusOK,
+		"limit=50&include_raw=true": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events?"+query, nil))
+		if rec.Code != want {
+			t.Errorf("%s: status %d, want %d", query, rec.Code, want)
+		}
+	}
+
+	// No budget configured: everything passes
+	s.cfg.MaxRequestCost = 0
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events?limit=100000", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 without a budget, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/internal/indexer/internal/api/query"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// exportFlushEvery controls how many NDJSON lines are buffered before flushing
+const exportFlushEvery = 500
+
+// exportEvent is one NDJSON line of an event export
+type exportEvent struct {
+	ChainID      types.ChainID     `json:"chain_id"`
+	BlockHeight  uint64            `json:"block_height"`
+	BlockHash    string            `json:"block_hash"`
+	TxHash       string            `json:"tx_hash"`
+	LogIndex     int               `json:"log_index"`
+	ContractAddr string            `json:"contract_addr"`
+	EventName    string            `json:"event_name,omitempty"`
+	Topic0       string            `json:"topic0"`
+	Topics       []string          `json:"topics"`
+	Params       json.RawMessage   `json:"params,omitempty"` // Decoded event params
+	Raw          json.RawMessage   `json:"raw,omitempty"`    // Original log
+	Status       types.BlockStatus `json:"status"`
+	DecodeFailed bool              `json:"decode_failed"`
+}
+
+// parseExportRange reads the required from_height/to_height params and enforces the max span
+func (s *Server) parseExportRange(r *http.Request) (from, to uint64, err error) {
+	q := r.URL.Query()
+	from, err = strconv.ParseUint(q.Get("from_height"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("from_height is required")
+	}
+	to, err = strconv.ParseUint(q.Get("to_height"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("to_height is required")
+	}
+	if to < from {
+		return 0, 0, fmt.Errorf("to_height must be >= from_height")
+	}
+	if to-from+1 > s.cfg.ExportMaxBlockRange {
+		return 0, 0, fmt.Errorf("range exceeds max of %d blocks", s.cfg.ExportMaxBlockRange)
+	}
+	return from, to, nil
+}
+
+func (s *Server) handleExportEvents(w http.ResponseWriter, r *http.Request) {
+	chain := chi.URLParam(r, "chain")
+
+	from, to, err := s.parseExportRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := query.EventFilter{
+		ChainID:      types.ChainID(chain),
+		ContractAddr: r.URL.Query().Get("contract"),
+		Topic0:       r.URL.Query().Get("topic0"),
+		FromHeight:   &from,
+		ToHeight:     &to,
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-events-%d-%d.ndjson", chain, from, to))
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+	enc := json.NewEncoder(w)
+	count := 0
+
+	err = s.service.StreamEvents(r.Context(), filter, func(e *types.Event) error {
+		line := exportEvent{
+			ChainID:      e.ChainID,
+			BlockHeight:  e.BlockHeight,
+			BlockHash:    e.BlockHash,
+			TxHash:       e.TxHash,
+			LogIndex:     e.LogIndex,
+			ContractAddr: e.ContractAddr,
+			EventName:    e.EventName,
+			Topic0:       e.Topic0,
+			Topics:       e.Topics,
+			Status:       e.Status,
+			DecodeFailed: e.DecodeFailed,
+		}
+		if json.Valid(e.Data) {
+			line.Params = e.Data
+		}
+		if json.Valid(e.RawData) {
+			line.Raw = e.RawData
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			// Long exports outlive the server write timeout; extend it while progressing
+			rc.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+			return rc.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent; report the failure as a trailing NDJSON line
+		fmt.Printf("Export error: %v\n", err)
+		enc.Encode(map[string]string{"error": "export aborted"})
+	}
+	rc.Flush()
+}
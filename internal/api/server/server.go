@@ -3,9 +3,11 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -16,6 +18,7 @@ import (
 	"github.com/internal/indexer/internal/api/config"
 	"github.com/internal/indexer/internal/api/query"
 	"github.com/internal/indexer/internal/api/service"
+	"github.com/internal/indexer/internal/stream"
 	"github.com/internal/indexer/pkg/types"
 )
 
@@ -26,6 +29,10 @@ type Server struct {
 	auth    *auth.Middleware
 	router  *chi.Mux
 	srv     *http.Server
+	blocks  *stream.Hub   // New-block summaries for /stream/blocks; nil disables it
+	done    chan struct{} // Closed on shutdown to end open streams
+
+	shutdownOnce sync.Once
 }
 
 // New creates a new HTTP server
@@ -34,6 +41,7 @@ func New(cfg config.ServerConfig, svc *service.Service, auth *auth.Middleware) *
 		cfg:     cfg,
 		service: svc,
 		auth:    auth,
+		done:    make(chan struct{}),
 	}
 	s.setupRouter()
 	return s
@@ -45,6 +53,7 @@ func (s *Server) setupRouter() {
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
+	r.Use(s.compress)
 	r.Use(middleware.Recoverer)
 
 	// Basic CORS for dev
@@ -64,17 +73,27 @@ func (s *Server) setupRouter() {
 
 	r.Get("/search", s.handleSearch) // New endpoint
 
+	// Public so browsers can use EventSource, which cannot send X-API-Key
+	r.Get("/stream/blocks/{chain}", s.handleStreamBlocks)
+
 	// Authenticated endpoints
 	r.Group(func(r chi.Router) {
 		r.Use(s.auth.Handler) // Apply Rate Limit & API Key check
+		r.Use(s.costLimit)
 
 		// Blocks
 		r.Get("/blocks/latest", s.handleGetLatestBlock)
+		r.Get("/blocks/latest/all", s.handleGetLatestBlocks)
 		r.Get("/blocks/{chain}/{id}", s.handleGetBlock) // id can be height or hash
 
 		// Transactions
 		r.Get("/tx/{chain}/{hash}", s.handleGetTx)
 		r.Get("/address/{chain}/{address}/txs", s.handleGetAddressTxs)
+		r.Get("/address/{chain}/{address}/ledger", s.handleGetAddressLedger)
+		r.Get("/address/{chain}/{address}/first-tx", s.handleGetFirstTx)
+		r.Get("/address/{chain}/{address}/token-summary", s.handleGetAddressTokenSummary)
+		r.Post("/txs/by-addresses", s.handleGetAddressesTxs)
+		r.Get("/addresses/{chain}/{address}/blocks", s.handleGetMinerBlocks)
 		r.Get("/blocks/{chain}/{id}/txs", s.handleGetBlockTxs)                  // New endpoint
 		r.Get("/txs/latest", s.handleGetLatestTxs)                              // New endpoint
 		r.Get("/balance/{chain}/{address}", s.handleGetAddressBalance)          // New endpoint
@@ -82,15 +101,26 @@ func (s *Server) setupRouter() {
 		r.Get("/tokens/{chain}/{address}/balances", s.handleGetTokenBalances)   // New endpoint
 		r.Get("/tokens/{chain}/{address}/transfers", s.handleGetTokenTransfers) // New endpoint
 		r.Get("/txs/pending/{chain}", s.handleGetPendingTxs)                    // New endpoint
+		r.Get("/token/{chain}/{address}/holders", s.handleGetTokenHolders)
 
 		// Events
 		r.Get("/contract/{chain}/{address}/events", s.handleGetContractEvents)
+		r.Get("/contract/{chain}/{address}/events/latest", s.handleGetLatestContractEvents)
 		r.Get("/events", s.handleGetEvents)
+		r.Get("/address/{chain}/{address}/tx-events", s.handleGetSenderEvents)
 
 		// Stats & Ranges
 		r.Get("/stats/{chain}", s.handleGetStats)                          // New endpoint
 		r.Get("/stats/address/{chain}/{address}", s.handleGetAddressStats) // New endpoint
 		r.Get("/blocks/{chain}/range", s.handleGetBlocksRange)             // New endpoint
+		r.Get("/settings/{chain}", s.handleGetChainSettings)
+	})
+
+	// Admin endpoints (admin API keys only)
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(s.auth.AdminHandler)
+
+		r.Get("/export/{chain}/events", s.handleExportEvents)
 	})
 
 	s.router = r
@@ -114,6 +144,7 @@ func (s *Server) Start() error {
 
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() { close(s.done) })
 	if s.srv != nil {
 		return s.srv.Shutdown(ctx)
 	}
@@ -123,8 +154,13 @@ func (s *Server) Shutdown(ctx context.Context) error {
 // Handlers
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+	status := s.service.CheckHealth(r.Context())
+
+	code := http.StatusOK
+	if status.Status != service.HealthOK {
+		code = http.StatusServiceUnavailable
+	}
+	jsonResponse(w, code, status)
 }
 
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
@@ -159,12 +195,42 @@ func (s *Server) handleGetLatestBlock(w http.ResponseWriter, r *http.Request) {
 
 	// Strip RawData by default to reduce payload size
 	if r.URL.Query().Get("include_raw") != "true" {
-		b.RawData = nil
+		b = withoutRawData(b)
 	}
 
 	jsonResponse(w, http.StatusOK, b)
 }
 
+// handleGetLatestBlocks returns the latest block of every indexed chain, keyed by chain
+func (s *Server) handleGetLatestBlocks(w http.ResponseWriter, r *http.Request) {
+	blocks, err := s.service.GetLatestBlocks(r.Context())
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("include_raw") != "true" {
+		stripped := make(map[types.ChainID]*types.Block, len(blocks))
+		for chain, b := range blocks {
+			stripped[chain] = withoutRawData(b)
+		}
+		blocks = stripped
+	}
+
+	jsonResponse(w, http.StatusOK, blocks)
+}
+
+// withoutRawData returns a copy of b without RawData. Blocks from the service
+// may be shared with concurrent requests, so they are never modified in place.
+func withoutRawData(b *types.Block) *types.Block {
+	if b == nil {
+		return nil
+	}
+	c := *b
+	c.RawData = nil
+	return &c
+}
+
 func (s *Server) handleGetBlock(w http.ResponseWriter, r *http.Request) {
 	chain := chi.URLParam(r, "chain")
 	id := chi.URLParam(r, "id") // height or hash
@@ -190,7 +256,21 @@ func (s *Server) handleGetBlock(w http.ResponseWriter, r *http.Request) {
 
 	// Strip RawData by default
 	if r.URL.Query().Get("include_raw") != "true" {
-		b.RawData = nil
+		b = withoutRawData(b)
+	}
+
+	if r.URL.Query().Get("neighbors") == "true" {
+		prev, next, err := s.service.GetBlockNeighbors(r.Context(), b)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		jsonResponse(w, http.StatusOK, struct {
+			*types.Block
+			Prev *types.BlockRef `json:"prev"`
+			Next *types.BlockRef `json:"next"`
+		}{Block: b, Prev: prev, Next: next})
+		return
 	}
 
 	jsonResponse(w, http.StatusOK, b)
@@ -210,7 +290,7 @@ func (s *Server) handleGetTx(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jsonResponse(w, http.StatusOK, tx)
+	jsonResponse(w, http.StatusOK, s.service.EnrichTx(tx))
 }
 
 func (s *Server) handleGetBlockTxs(w http.ResponseWriter, r *http.Request) {
@@ -311,8 +391,35 @@ func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusOK, stats)
 }
 
+func (s *Server) handleGetChainSettings(w http.ResponseWriter, r *http.Request) {
+	chain := chi.URLParam(r, "chain")
+	settings, err := s.service.GetChainSettings(r.Context(), types.ChainID(chain))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if settings == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	jsonResponse(w, http.StatusOK, settings)
+}
+
 func (s *Server) handleGetBlocksRange(w http.ResponseWriter, r *http.Request) {
 	chain := chi.URLParam(r, "chain")
+
+	// direction=desc browses backward from the tip with a cursor; the default
+	// ascending mode returns a bounded from/to window for charts
+	switch r.URL.Query().Get("direction") {
+	case "", "asc":
+	case "desc":
+		s.handleGetBlocksDesc(w, r, types.ChainID(chain))
+		return
+	default:
+		http.Error(w, "direction must be asc or desc", http.StatusBadRequest)
+		return
+	}
+
 	fromStr := r.URL.Query().Get("from")
 	toStr := r.URL.Query().Get("to")
 
@@ -328,11 +435,42 @@ func (s *Server) handleGetBlocksRange(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusOK, blocks)
 }
 
+func (s *Server) handleGetBlocksDesc(w http.ResponseWriter, r *http.Request, chainID types.ChainID) {
+	cursor := r.URL.Query().Get("cursor")
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			limit = l
+		}
+	}
+
+	blocks, nextCursor, err := s.service.GetBlocksDesc(r.Context(), chainID, cursor, limit)
+	if errors.Is(err, query.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	resp := struct {
+		Data   []*types.BlockSummary `json:"data"`
+		Cursor string                `json:"cursor,omitempty"`
+	}{
+		Data:   blocks,
+		Cursor: nextCursor,
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
 func (s *Server) handleGetAddressStats(w http.ResponseWriter, r *http.Request) {
 	chain := chi.URLParam(r, "chain")
 	address := chi.URLParam(r, "address")
 
-	stats, err := s.service.GetAddressStats(r.Context(), types.ChainID(chain), address)
+	finalizedOnly := r.URL.Query().Get("finalized_only") == "true"
+
+	stats, err := s.service.GetAddressStats(r.Context(), types.ChainID(chain), address, finalizedOnly)
 	if err != nil {
 		internalError(w, err)
 		return
@@ -361,7 +499,126 @@ func (s *Server) handleGetAddressTxs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	txs, nextCursor, err := s.service.GetTransactionsByAddress(r.Context(), types.ChainID(chain), address, cursor, limit)
+	direction := query.TxDirection(r.URL.Query().Get("direction"))
+	switch direction {
+	case "":
+		direction = query.DirectionAll
+	case query.DirectionAll, query.DirectionSent, query.DirectionReceived:
+	default:
+		http.Error(w, "direction must be sent, received or all", http.StatusBadRequest)
+		return
+	}
+
+	txs, nextCursor, err := s.service.GetTransactionsByAddress(r.Context(), types.ChainID(chain), address, direction, cursor, limit)
+	if errors.Is(err, query.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	resp := struct {
+		Data   []*types.Transaction `json:"data"`
+		Cursor string               `json:"cursor,omitempty"`
+	}{
+		Data:   txs,
+		Cursor: nextCursor,
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGetFirstTx(w http.ResponseWriter, r *http.Request) {
+	chain := chi.URLParam(r, "chain")
+	address := chi.URLParam(r, "address")
+
+	tx, err := s.service.GetFirstTransaction(r.Context(), types.ChainID(chain), address)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if tx == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, s.service.EnrichTx(tx))
+}
+
+// nativeBalancesTracked reports whether a chain's transactions carry enough to
+// derive native balances from. A BTC transaction is stored with only its first
+// input's address as sender and its first output's as recipient, so balances
+// summed from them are wrong for multi-input and change-bearing transactions.
+func nativeBalancesTracked(chain types.ChainID) bool {
+	return chain != types.ChainBTC
+}
+
+func (s *Server) handleGetAddressLedger(w http.ResponseWriter, r *http.Request) {
+	chain := chi.URLParam(r, "chain")
+	address := chi.URLParam(r, "address")
+	if !nativeBalancesTracked(types.ChainID(chain)) {
+		http.Error(w, "native balances are not tracked for "+chain, http.StatusNotImplemented)
+		return
+	}
+	cursor := r.URL.Query().Get("cursor")
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			limit = l
+		}
+	}
+
+	entries, nextCursor, err := s.service.GetAddressLedger(r.Context(), types.ChainID(chain), address, cursor, limit)
+	if errors.Is(err, query.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	resp := struct {
+		Data   []types.LedgerEntry `json:"data"`
+		Cursor string              `json:"cursor,omitempty"`
+	}{
+		Data:   entries,
+		Cursor: nextCursor,
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGetAddressesTxs(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Chain     string   `json:"chain"`
+		Addresses []string `json:"addresses"`
+		Cursor    string   `json:"cursor"`
+		Limit     int      `json:"limit"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Chain == "" {
+		http.Error(w, "chain is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Addresses) == 0 {
+		http.Error(w, "addresses is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Addresses) > service.MaxAddressesPerQuery {
+		http.Error(w, fmt.Sprintf("at most %d addresses per request", service.MaxAddressesPerQuery), http.StatusBadRequest)
+		return
+	}
+
+	txs, nextCursor, err := s.service.GetTransactionsByAddresses(r.Context(), types.ChainID(req.Chain), req.Addresses, req.Cursor, req.Limit)
+	if errors.Is(err, query.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		internalError(w, err)
 		return
@@ -377,20 +634,69 @@ func (s *Server) handleGetAddressTxs(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusOK, resp)
 }
 
+func (s *Server) handleGetMinerBlocks(w http.ResponseWriter, r *http.Request) {
+	chain := chi.URLParam(r, "chain")
+	address := chi.URLParam(r, "address")
+	cursor := r.URL.Query().Get("cursor")
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			limit = l
+		}
+	}
+
+	blocks, nextCursor, err := s.service.GetBlocksByMiner(r.Context(), types.ChainID(chain), address, cursor, limit)
+	if errors.Is(err, query.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	resp := struct {
+		Data   []*types.Block `json:"data"`
+		Cursor string         `json:"cursor,omitempty"`
+	}{
+		Data:   blocks,
+		Cursor: nextCursor,
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
 func (s *Server) handleGetAddressBalance(w http.ResponseWriter, r *http.Request) {
 	chain := chi.URLParam(r, "chain")
 	address := chi.URLParam(r, "address")
+	if !nativeBalancesTracked(types.ChainID(chain)) {
+		http.Error(w, "native balances are not tracked for "+chain, http.StatusNotImplemented)
+		return
+	}
+
+	minConf := 0
+	if v := r.URL.Query().Get("min_confirmations"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid min_confirmations", http.StatusBadRequest)
+			return
+		}
+		minConf = n
+	}
 
-	balance, err := s.service.GetAddressBalance(r.Context(), types.ChainID(chain), address)
+	finalizedOnly := r.URL.Query().Get("finalized_only") == "true"
+
+	balance, err := s.service.GetAddressBalance(r.Context(), types.ChainID(chain), address, minConf, finalizedOnly)
 	if err != nil {
 		internalError(w, err)
 		return
 	}
 
-	jsonResponse(w, http.StatusOK, map[string]string{
-		"address": address,
-		"balance": balance,
-		"chain":   chain,
+	jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"address":           address,
+		"balance":           balance,
+		"chain":             chain,
+		"min_confirmations": minConf,
+		"finalized_only":    finalizedOnly,
 	})
 }
 
@@ -435,6 +741,28 @@ func (s *Server) handleGetContractEvents(w http.ResponseWriter, r *http.Request)
 	jsonResponse(w, http.StatusOK, resp)
 }
 
+func (s *Server) handleGetLatestContractEvents(w http.ResponseWriter, r *http.Request) {
+	chain := chi.URLParam(r, "chain")
+	address := chi.URLParam(r, "address")
+
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil {
+			limit = l
+		}
+	}
+
+	events, err := s.service.GetLatestContractEvents(r.Context(), types.ChainID(chain), address, limit)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, struct {
+		Data []*types.Event `json:"data"`
+	}{Data: events})
+}
+
 func (s *Server) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 	filter := s.parseEventFilter(r)
 
@@ -462,11 +790,65 @@ func (s *Server) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusOK, resp)
 }
 
+// handleGetSenderEvents lists events emitted by transactions sent from an address.
+// from_height and to_height are required and may span at most MaxTxSenderRange blocks.
+func (s *Server) handleGetSenderEvents(w http.ResponseWriter, r *http.Request) {
+	chain := chi.URLParam(r, "chain")
+	address := chi.URLParam(r, "address")
+	q := r.URL.Query()
+
+	from, err := strconv.ParseUint(q.Get("from_height"), 10, 64)
+	if err != nil {
+		http.Error(w, "from_height is required", http.StatusBadRequest)
+		return
+	}
+	to, err := strconv.ParseUint(q.Get("to_height"), 10, 64)
+	if err != nil {
+		http.Error(w, "to_height is required", http.StatusBadRequest)
+		return
+	}
+	if to < from {
+		http.Error(w, "to_height must be >= from_height", http.StatusBadRequest)
+		return
+	}
+	if to-from+1 > service.MaxTxSenderRange {
+		http.Error(w, fmt.Sprintf("range exceeds max of %d blocks", service.MaxTxSenderRange), http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	if limitStr := q.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			limit = l
+		}
+	}
+
+	events, nextCursor, err := s.service.GetEventsByTxSender(r.Context(), types.ChainID(chain), address, from, to, q.Get("cursor"), limit)
+	if errors.Is(err, query.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	resp := struct {
+		Data   []*types.Event `json:"data"`
+		Cursor string         `json:"cursor,omitempty"`
+	}{
+		Data:   events,
+		Cursor: nextCursor,
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
 func (s *Server) parseEventFilter(r *http.Request) query.EventFilter {
 	q := r.URL.Query()
 	f := query.EventFilter{
-		Topic0: q.Get("topic0"),
-		Cursor: q.Get("cursor"),
+		Topic0:          q.Get("topic0"),
+		Cursor:          q.Get("cursor"),
+		ExcludeReverted: q.Get("exclude_reverted") == "true",
 	}
 
 	if val := q.Get("from_height"); val != "" {
@@ -514,8 +896,8 @@ func (s *Server) handleGetTokenBalances(w http.ResponseWriter, r *http.Request)
 func (s *Server) handleGetTokenTransfers(w http.ResponseWriter, r *http.Request) {
 	chain := chi.URLParam(r, "chain")
 	address := chi.URLParam(r, "address")
+	cursor := r.URL.Query().Get("cursor")
 	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
 
 	limit := 20
 	if limitStr != "" {
@@ -523,20 +905,54 @@ func (s *Server) handleGetTokenTransfers(w http.ResponseWriter, r *http.Request)
 			limit = l
 		}
 	}
-	offset := 0
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil {
-			offset = o
-		}
+
+	transfers, nextCursor, err := s.service.GetTokenTransfers(r.Context(), types.ChainID(chain), address, cursor, limit)
+	if errors.Is(err, query.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	resp := struct {
+		Data   []service.TokenTransferView `json:"data"`
+		Cursor string                      `json:"cursor,omitempty"`
+	}{
+		Data:   transfers,
+		Cursor: nextCursor,
 	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGetTokenHolders(w http.ResponseWriter, r *http.Request) {
+	chain := chi.URLParam(r, "chain")
+	address := chi.URLParam(r, "address")
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	holders, err := s.service.GetTokenHolders(r.Context(), types.ChainID(chain), address, limit, offset)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, holders)
+}
+
+func (s *Server) handleGetAddressTokenSummary(w http.ResponseWriter, r *http.Request) {
+	chain := chi.URLParam(r, "chain")
+	address := chi.URLParam(r, "address")
 
-	transfers, err := s.service.GetTokenTransfers(r.Context(), types.ChainID(chain), address, limit, offset)
+	summary, err := s.service.GetAddressTokenSummary(r.Context(), types.ChainID(chain), address)
 	if err != nil {
 		internalError(w, err)
 		return
 	}
 
-	jsonResponse(w, http.StatusOK, transfers)
+	jsonResponse(w, http.StatusOK, summary)
 }
 
 func (s *Server) handleGetPendingTxs(w http.ResponseWriter, r *http.Request) {
@@ -553,12 +969,12 @@ func (s *Server) handleGetPendingTxs(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query().Get("q")
-	if len(q) < 2 {
-		http.Error(w, "query too short", http.StatusBadRequest)
+	if q == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
 		return
 	}
 
-	res, err := s.service.Search(r.Context(), q)
+	res, err := s.service.Search(r.Context(), types.ChainID(r.URL.Query().Get("chain")), q)
 	if err != nil {
 		internalError(w, err)
 		return
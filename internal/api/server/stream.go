@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/internal/indexer/internal/stream"
+	"github.com/internal/indexer/pkg/types"
+)
+
+const (
+	// streamKeepAlive is how often an idle stream gets a comment line, so
+	// proxies don't close it for inactivity
+	streamKeepAlive = 15 * time.Second
+	// streamWriteTimeout bounds each write; a client that stops reading is disconnected
+	streamWriteTimeout = 10 * time.Second
+)
+
+// SetBlockHub sets the hub /stream/blocks reads new blocks from
+func (s *Server) SetBlockHub(h *stream.Hub) {
+	s.blocks = h
+}
+
+// handleStreamBlocks pushes a summary of each newly indexed block of a chain as
+// a Server-Sent Event. A client too slow to keep up misses blocks rather than
+// delaying the others; it can fill gaps from /blocks/{chain}/range.
+func (s *Server) handleStreamBlocks(w http.ResponseWriter, r *http.Request) {
+	if s.blocks == nil {
+		http.Error(w, "block stream not available", http.StatusServiceUnavailable)
+		return
+	}
+	chain := types.ChainID(chi.URLParam(r, "chain"))
+
+	summaries, unsubscribe := s.blocks.Subscribe(chain)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	send := func(format string, args ...interface{}) error {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	// Opens the stream right away, before the first block arrives
+	if err := send(": connected\n\n"); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-keepAlive.C:
+			if err := send(": keep-alive\n\n"); err != nil {
+				return
+			}
+		case b := <-summaries:
+			data, err := json.Marshal(b)
+			if err != nil {
+				continue
+			}
+			if err := send("event: block\nid: %d\ndata: %s\n\n", b.Height, data); err != nil {
+				return
+			}
+		}
+	}
+}
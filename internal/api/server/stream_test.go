@@ -0,0 +1,68 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/internal/indexer/internal/stream"
+	"github.com/internal/indexer/pkg/types"
+)
+
+func TestStreamBlocks(t *testing.T) {
+	hub := stream.NewHub()
+	s := &Server{blocks: hub, done: make(chan struct{})}
+	r := chi.NewRouter()
+	r.Get("/stream/blocks/{chain}", s.handleStreamBlocks)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/stream/blocks/eth", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	if !lines.Scan() || lines.Text() != ": connected" || !lines.Scan() {
+		t.Fatalf("expected the connected comment first, got %q", lines.Text())
+	}
+
+	// Subscribed once connected; blocks of other chains are not sent
+	hub.Publish(stream.BlockSummary{ChainID: types.ChainBTC, Height: 1})
+	hub.Publish(stream.BlockSummary{ChainID: types.ChainETH, Height: 2, Hash: "0xabc"})
+
+	var event []string
+	for lines.Scan() && lines.Text() != "" {
+		event = append(event, lines.Text())
+	}
+	if len(event) != 3 || event[0] != "event: block" || event[1] != "id: 2" ||
+		!strings.HasPrefix(event[2], "data: ") || !strings.Contains(event[2], `"hash":"0xabc"`) {
+		t.Errorf("unexpected event %q", event)
+	}
+
+	// Shutdown ends open streams
+	s.Shutdown(context.Background())
+	for lines.Scan() {
+	}
+}
+
+func TestStreamBlocks_NoHub(t *testing.T) {
+	rec := httptest.NewRecorder()
+	(&Server{}).handleStreamBlocks(rec, httptest.NewRequest(http.MethodGet, "/stream/blocks/eth", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a hub, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// TokenTransferView is a token transfer with its amount shifted by the token's decimals.
+// Amount shadows the raw types.TokenTransfer.Amount and is omitted when decimals are unknown.
+type TokenTransferView struct {
+	types.TokenTransfer
+	Amount          string `json:"amount,omitempty"`
+	AmountRaw       string `json:"amount_raw"`
+	Decimals        *int   `json:"decimals,omitempty"`
+	DecimalsUnknown bool   `json:"decimals_unknown,omitempty"`
+}
+
+// TokenBalanceView is a token balance with its amount shifted by the token's decimals
+type TokenBalanceView struct {
+	types.TokenBalance
+	Amount          string `json:"amount,omitempty"`
+	AmountRaw       string `json:"amount_raw"`
+	Decimals        *int   `json:"decimals,omitempty"`
+	DecimalsUnknown bool   `json:"decimals_unknown,omitempty"`
+}
+
+// FormatAmount shifts a raw integer amount string by decimals, e.g. ("1500000", 6) -> "1.5".
+// Uses integer arithmetic only so arbitrarily large uint256 values keep full precision.
+func FormatAmount(raw string, decimals int) (string, error) {
+	if decimals < 0 {
+		return "", fmt.Errorf("negative decimals: %d", decimals)
+	}
+
+	n, ok := new(big.Int).SetString(raw, 10)
+	if !ok {
+		return "", fmt.Errorf("invalid amount: %q", raw)
+	}
+	if decimals == 0 {
+		return n.String(), nil
+	}
+
+	neg := n.Sign() < 0
+	digits := new(big.Int).Abs(n).String()
+
+	// Left-pad so there is at least one integer digit
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	intPart := digits[:len(digits)-decimals]
+	fracPart := strings.TrimRight(digits[len(digits)-decimals:], "0")
+
+	out := intPart
+	if fracPart != "" {
+		out += "." + fracPart
+	}
+	if neg {
+		out = "-" + out
+	}
+	return out, nil
+}
+
+// formatAmountFields resolves the shifted amount for a raw value given the known decimals map
+func formatAmountFields(raw, tokenAddr string, decimals map[string]int) (amount string, dec *int, unknown bool) {
+	d, ok := decimals[tokenAddr]
+	if !ok {
+		return "", nil, true
+	}
+	amount, err := FormatAmount(raw, d)
+	if err != nil {
+		return "", nil, true
+	}
+	return amount, &d, false
+}
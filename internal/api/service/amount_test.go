@@ -0,0 +1,35 @@
+package service
+
+import "testing"
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		raw      string
+		decimals int
+		want     string
+	}{
+		{"1500000", 6, "1.5"},
+		{"1000000000000000000", 18, "1"},
+		{"1", 18, "0.000000000000000001"},
+		{"0", 18, "0"},
+		{"123", 0, "123"},
+		{"-2500", 3, "-2.5"},
+		// max uint256 keeps full precision
+		{"115792089237316195423570985008687907853269984665640564039457584007913129639935", 18,
+			"115792089237316195423570985008687907853269984665640564039457.584007913129639935"},
+	}
+
+	for _, tt := range tests {
+		got, err := FormatAmount(tt.raw, tt.decimals)
+		if err != nil {
+			t.Fatalf("FormatAmount(%q, %d) error: %v", tt.raw, tt.decimals, err)
+		}
+		if got != tt.want {
+			t.Errorf("FormatAmount(%q, %d) = %q, want %q", tt.raw, tt.decimals, got, tt.want)
+		}
+	}
+
+	if _, err := FormatAmount("not-a-number", 18); err == nil {
+		t.Error("expected error for invalid amount")
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/internal/indexer/internal/api/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var cacheTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "api_cache_timeouts_total",
+	Help: "Cache operations abandoned after exceeding redis.op_timeout",
+}, []string{"op"})
+
+// timeoutCache bounds each cache operation so a slow (not down) Redis cannot
+// add its latency to every request. Reads that time out are treated as misses;
+// writes run in the background and never block the caller.
+type timeoutCache struct {
+	cache.Cache
+	timeout time.Duration
+}
+
+func (c *timeoutCache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	found, err := c.Cache.Get(ctx, key, dest)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		cacheTimeouts.WithLabelValues("get").Inc()
+		return false, nil
+	}
+	return found, err
+}
+
+func (c *timeoutCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	// Encode now so the background write never races with the caller using value
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+
+	// Detach from the request so the write survives the response being sent
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	go func() {
+		defer cancel()
+		if err := c.Cache.Set(ctx, key, json.RawMessage(raw), ttl); err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			cacheTimeouts.WithLabelValues("set").Inc()
+		}
+	}()
+	return nil
+}
+
+// SetCacheTimeout bounds every cache Get/Set made by the service. Zero or
+// negative keeps cache operations synchronous and unbounded.
+func (s *Service) SetCacheTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	if tc, ok := s.cache.(*timeoutCache); ok {
+		tc.timeout = d
+		return
+	}
+	s.cache = &timeoutCache{Cache: s.cache, timeout: d}
+}
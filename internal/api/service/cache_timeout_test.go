@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowCache blocks every operation for delay or until ctx is done
+type slowCache struct {
+	delay time.Duration
+
+	mu  sync.Mutex
+	set map[string][]byte
+}
+
+func (c *slowCache) wait(ctx context.Context) error {
+	select {
+	case <-time.After(c.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *slowCache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	if err := c.wait(ctx); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+func (c *slowCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	b, _ := json.Marshal(value)
+	c.mu.Lock()
+	c.set[key] = b
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *slowCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 0, nil
+}
+func (c *slowCache) Ping(ctx context.Context) error { return nil }
+func (c *slowCache) Close() error                   { return nil }
+
+func TestTimeoutCache_SlowGetIsMiss(t *testing.T) {
+	tc := &timeoutCache{Cache: &slowCache{delay: time.Second}, timeout: 10 * time.Millisecond}
+
+	start := time.Now()
+	var v string
+	found, err := tc.Get(context.Background(), "k", &v)
+	if err != nil || found {
+		t.Fatalf("expected a miss without error, got found=%v err=%v", found, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Get took %v, expected it to give up after the timeout", elapsed)
+	}
+}
+
+func TestTimeoutCache_SetDoesNotBlock(t *testing.T) {
+	inner := &slowCache{delay: 20 * time.Millisecond, set: make(map[string][]byte)}
+	tc := &timeoutCache{Cache: inner, timeout: time.Second}
+
+	start := time.Now()
+	if err := tc.Set(context.Background(), "k", map[string]int{"a": 1}, time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= inner.delay {
+		t.Errorf("Set took %v, expected it to return before the write completes", elapsed)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		inner.mu.Lock()
+		got, ok := inner.set["k"]
+		inner.mu.Unlock()
+		if ok {
+			if string(got) != `{"a":1}` {
+				t.Errorf("unexpected stored value %s", got)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("background Set never completed")
+}
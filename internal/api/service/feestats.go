@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// SetFeeStatsChains enables fee summaries in the network stats of the given
+// chains. Only list chains whose indexer stores every transaction's fee: BTC fees
+// need prevout resolution and ETH fees need receipts, and a sum over partial data
+// would be misleading.
+func (s *Service) SetFeeStatsChains(chains []string) {
+	s.feeStats = make(map[types.ChainID]bool, len(chains))
+	for _, c := range chains {
+		s.feeStats[types.ChainID(c)] = true
+	}
+}
+
+// addFeeStats fills the fee fields of st over the same one-minute window as
+// TxsLastMinute. They stay empty, and are omitted, when fee stats are disabled
+// for the chain or the window holds transactions without a stored fee.
+func (s *Service) addFeeStats(ctx context.Context, st *types.NetworkStats) error {
+	if !s.feeStats[st.ChainID] {
+		return nil
+	}
+
+	total, avg, err := s.store.GetRecentFees(ctx, st.ChainID, time.Now().Add(-time.Minute))
+	if err != nil {
+		return err
+	}
+	st.FeesLastMinute, st.AvgFeePerTx = total, avg
+	return nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/internal/indexer/internal/api/query"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// feeStore serves fixed fee sums; other Store methods are not used
+type feeStore struct {
+	query.Store
+	calls int
+}
+
+func (s *feeStore) GetRecentFees(ctx context.Context, chainID types.ChainID, since time.Time) (string, string, error) {
+	s.calls++
+	return "1000", "250", nil
+}
+
+func TestAddFeeStats(t *testing.T) {
+	store := &feeStore{}
+	svc := New(store, nil)
+	ctx := context.Background()
+
+	st := &types.NetworkStats{ChainID: types.ChainETH}
+	if err := svc.addFeeStats(ctx, st); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st.FeesLastMinute != "" || store.calls != 0 {
+		t.Errorf("expected no fee stats when disabled, got %q after %d queries", st.FeesLastMinute, store.calls)
+	}
+
+	svc.SetFeeStatsChains([]string{"eth"})
+	if err := svc.addFeeStats(ctx, st); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st.FeesLastMinute != "1000" || st.AvgFeePerTx != "250" {
+		t.Errorf("unexpected fee stats %q/%q", st.FeesLastMinute, st.AvgFeePerTx)
+	}
+
+	btc := &types.NetworkStats{ChainID: types.ChainBTC}
+	if err := svc.addFeeStats(ctx, btc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if btc.FeesLastMinute != "" || store.calls != 1 {
+		t.Errorf("expected no fee stats for a chain not listed, got %q", btc.FeesLastMinute)
+	}
+}
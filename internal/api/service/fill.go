@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultFillTimeout bounds a cache fill started by a request without a deadline
+const DefaultFillTimeout = 30 * time.Second
+
+// fill runs load for a cache key that missed, at most once at a time: callers
+// that miss while it runs wait for it and share its result instead of each
+// querying the store. load should populate the cache itself, so that callers
+// arriving after it finishes hit the cache instead.
+//
+// load is detached from the first caller's cancellation, since others may be
+// waiting on it, but keeps that caller's deadline (DefaultFillTimeout without
+// one) so a stuck query cannot run forever; each caller still stops waiting when
+// its own ctx ends. Shared results are the same values for every waiter and must
+// not be modified.
+func fill[T any](ctx context.Context, s *Service, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	timeout := DefaultFillTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	ch := s.flight.DoChan(key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		return load(loadCtx)
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/internal/indexer/internal/api/query"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// missCache never has a value, so every read is a miss; it counts the misses
+type missCache struct {
+	misses atomic.Int64
+}
+
+func (c *missCache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	c.misses.Add(1)
+	return false, nil
+}
+func (c *missCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return nil
+}
+func (c *missCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 0, nil
+}
+func (c *missCache) Ping(ctx context.Context) error { return nil }
+func (c *missCache) Close() error                   { return nil }
+
+// blockingStore holds GetLatestBlock calls until release is closed
+type blockingStore struct {
+	query.Store
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (s *blockingStore) GetLatestBlock(ctx context.Context, chainID types.ChainID) (*types.Block, error) {
+	s.calls.Add(1)
+	<-s.release
+	return &types.Block{ChainID: chainID, Height: 100, Hash: "0xabc"}, nil
+}
+
+func TestGetLatestBlock_ConcurrentMissesShareOneQuery(t *testing.T) {
+	const n = 20
+	store := &blockingStore{release: make(chan struct{})}
+	c := &missCache{}
+	svc := New(store, c)
+
+	var wg sync.WaitGroup
+	blocks := make([]*types.Block, n)
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			blocks[i], errs[i] = svc.GetLatestBlock(context.Background(), types.ChainETH)
+		}()
+	}
+
+	// Let every caller miss and join the query before it returns
+	deadline := time.Now().Add(5 * time.Second)
+	for c.misses.Load() < n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(store.release)
+	wg.Wait()
+
+	if got := store.calls.Load(); got != 1 {
+		t.Errorf("expected 1 store call for %d concurrent misses, got %d", n, got)
+	}
+	for i := range n {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: %v", i, errs[i])
+		}
+		if blocks[i] == nil || blocks[i].Height != 100 {
+			t.Fatalf("caller %d: unexpected block %+v", i, blocks[i])
+		}
+	}
+}
+
+func TestFill_CallerCancelDoesNotCancelLoad(t *testing.T) {
+	store := &blockingStore{release: make(chan struct{})}
+	svc := New(store, &missCache{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.GetLatestBlock(ctx, types.ChainETH)
+		done <- err
+	}()
+	for store.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The first caller gives up; one that joined still gets the result
+	joined := make(chan *types.Block, 1)
+	go func() {
+		b, _ := svc.GetLatestBlock(context.Background(), types.ChainETH)
+		joined <- b
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	close(store.release)
+	if b := <-joined; b == nil || b.Height != 100 {
+		t.Fatalf("expected the shared block, got %+v", b)
+	}
+	if got := store.calls.Load(); got != 1 {
+		t.Errorf("expected 1 store call, got %d", got)
+	}
+}
+
+func TestFill_LoadKeepsCallerDeadline(t *testing.T) {
+	svc := New(&blockingStore{}, &missCache{})
+
+	// The load outlives a caller that gives up, but not the caller's deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	loadErr := make(chan error, 1)
+	_, err := fill(ctx, svc, "deadline", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		loadErr <- ctx.Err()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the caller to time out, got %v", err)
+	}
+	select {
+	case err := <-loadErr:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the load to hit the deadline, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("load ran past the caller's deadline")
+	}
+
+	// Without a deadline, the load is bounded by DefaultFillTimeout
+	_, err = fill(context.Background(), svc, "default", func(ctx context.Context) (int, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok || time.Until(deadline) > DefaultFillTimeout {
+			t.Errorf("expected a deadline within %v, got %v (set %v)", DefaultFillTimeout, deadline, ok)
+		}
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("fill: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	HealthOK   = "ok"
+	HealthDown = "down"
+)
+
+// HealthStatus reports connectivity of the API's dependencies
+type HealthStatus struct {
+	Status       string            `json:"status"`
+	Dependencies map[string]string `json:"dependencies"`
+	CheckedAt    time.Time         `json:"checked_at"`
+}
+
+// healthChecker caches the last dependency check so frequent probes stay cheap
+type healthChecker struct {
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu   sync.Mutex
+	last *HealthStatus
+}
+
+// SetHealthCheck configures the per-dependency ping timeout and result cache TTL
+func (s *Service) SetHealthCheck(timeout, ttl time.Duration) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	s.health.timeout = timeout
+	s.health.ttl = ttl
+	s.health.last = nil
+}
+
+// CheckHealth pings the database and cache, reusing a recent result when available
+func (s *Service) CheckHealth(ctx context.Context) HealthStatus {
+	h := &s.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.last != nil && time.Since(h.last.CheckedAt) < h.ttl {
+		return *h.last
+	}
+
+	// The result is shared with other callers, so don't let this request's cancellation mark deps down
+	ctx = context.WithoutCancel(ctx)
+
+	st := &HealthStatus{
+		Status:       HealthOK,
+		Dependencies: make(map[string]string, 2),
+		CheckedAt:    time.Now(),
+	}
+	st.Dependencies["database"] = s.ping(ctx, h.timeout, s.store.Ping)
+	st.Dependencies["cache"] = s.ping(ctx, h.timeout, s.cache.Ping)
+	for _, dep := range st.Dependencies {
+		if dep != HealthOK {
+			st.Status = HealthDown
+		}
+	}
+
+	h.last = st
+	return *st
+}
+
+func (s *Service) ping(ctx context.Context, timeout time.Duration, fn func(context.Context) error) string {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := fn(ctx); err != nil {
+		return HealthDown
+	}
+	return HealthOK
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/internal/indexer/internal/api/cache/cachetest"
+	"github.com/internal/indexer/internal/api/query"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// holderStore records the page requested; other Store methods are not used
+type holderStore struct {
+	query.Store
+	calls         int
+	limit, offset int
+}
+
+func (s *holderStore) GetTokenHolders(ctx context.Context, chainID types.ChainID, tokenAddress string, limit, offset int) ([]types.TokenHolder, error) {
+	s.calls++
+	s.limit, s.offset = limit, offset
+	return []types.TokenHolder{{Rank: offset + 1, Address: "0xa", Balance: "1000"}}, nil
+}
+
+func TestGetTokenHolders(t *testing.T) {
+	store := &holderStore{}
+	svc := New(store, cachetest.Map{})
+	ctx := context.Background()
+
+	holders, err := svc.GetTokenHolders(ctx, types.ChainETH, "0xToken", 500, -5)
+	if err != nil {
+		t.Fatalf("GetTokenHolders: %v", err)
+	}
+	if store.limit != MaxTokenHolders || store.offset != 0 {
+		t.Errorf("expected limit %d and offset 0, got %d and %d", MaxTokenHolders, store.limit, store.offset)
+	}
+	if len(holders) != 1 || holders[0].Rank != 1 || holders[0].Balance != "1000" {
+		t.Errorf("unexpected holders %+v", holders)
+	}
+
+	// The same page is served from the cache, whatever the address case
+	if _, err := svc.GetTokenHolders(ctx, types.ChainETH, "0xtoken", 100, 0); err != nil {
+		t.Fatalf("GetTokenHolders: %v", err)
+	}
+	if store.calls != 1 {
+		t.Errorf("expected the second page read from the cache, got %d store calls", store.calls)
+	}
+
+	if _, err := svc.GetTokenHolders(ctx, types.ChainETH, "0xtoken", 0, 20); err != nil {
+		t.Fatalf("GetTokenHolders: %v", err)
+	}
+	if store.calls != 2 || store.limit != 20 || store.offset != 20 {
+		t.Errorf("expected a store call for the next page with the default limit, got %d calls, limit %d", store.calls, store.limit)
+	}
+}
+
+// addressStore counts balance and stats queries; other Store methods are not used
+type addressStore struct {
+	query.Store
+	balanceCalls, statsCalls int
+}
+
+func (s *addressStore) GetAddressBalance(ctx context.Context, chainID types.ChainID, address string, minConfirmations int, finalizedOnly bool) (string, error) {
+	s.balanceCalls++
+	return "100", nil
+}
+
+func (s *addressStore) GetAddressStats(ctx context.Context, chainID types.ChainID, address string) (*types.AddressStats, error) {
+	s.statsCalls++
+	return &types.AddressStats{}, nil
+}
+
+func TestAddressCacheKeysIgnoreCase(t *testing.T) {
+	store := &addressStore{}
+	svc := New(store, cachetest.Map{})
+	ctx := context.Background()
+
+	for _, addr := range []string{"0xAbCd", "0xabcd"} {
+		if _, err := svc.GetAddressBalance(ctx, types.ChainETH, addr, 0, false); err != nil {
+			t.Fatalf("GetAddressBalance: %v", err)
+		}
+		if _, err := svc.GetAddressStats(ctx, types.ChainETH, addr, false); err != nil {
+			t.Fatalf("GetAddressStats: %v", err)
+		}
+	}
+	if store.balanceCalls != 1 || store.statsCalls != 1 {
+		t.Errorf("expected one query each for the same address in any case, got %d balance and %d stats", store.balanceCalls, store.statsCalls)
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// nominalBlockTime is used to estimate blocks behind when no measured average is available
+var nominalBlockTime = map[types.ChainID]float64{
+	types.ChainBTC: 600,
+	types.ChainETH: 12,
+}
+
+// GetIndexingStatus returns DB-derived indexing progress for a chain, or nil if the
+// indexer has not written a checkpoint yet. avgBlockTime (seconds) refines the
+// blocks-behind estimate; zero falls back to the chain's nominal block time.
+func (s *Service) GetIndexingStatus(ctx context.Context, chainID types.ChainID, avgBlockTime float64) (*types.IndexingStatus, error) {
+	st, err := s.store.GetIndexingStatus(ctx, chainID)
+	if err != nil || st == nil {
+		return st, err
+	}
+
+	if avgBlockTime <= 0 {
+		avgBlockTime = nominalBlockTime[chainID]
+	}
+	st.BlocksBehind = estimateBlocksBehind(st.LagSeconds, avgBlockTime)
+	return st, nil
+}
+
+// estimateBlocksBehind converts how far the checkpoint block trails the wall clock
+// into whole block times, rounded down. Lag under one block time, which the newest
+// block can have on its own, is therefore 0 blocks behind.
+func estimateBlocksBehind(lagSeconds int64, blockTime float64) uint64 {
+	if blockTime <= 0 || lagSeconds <= 0 {
+		return 0
+	}
+	return uint64(float64(lagSeconds) / blockTime)
+}
+
+var (
+	indexingHeightDesc = prometheus.NewDesc("api_indexing_checkpoint_height",
+		"Last height written by the indexer, read from the checkpoints table", []string{"chain"}, nil)
+	indexingLagDesc = prometheus.NewDesc("api_indexing_lag_seconds",
+		"Seconds between now and the timestamp of the checkpoint block", []string{"chain"}, nil)
+	indexingAgeDesc = prometheus.NewDesc("api_indexing_checkpoint_age_seconds",
+		"Seconds since the indexer last advanced the checkpoint", []string{"chain"}, nil)
+	indexingBehindDesc = prometheus.NewDesc("api_indexing_blocks_behind",
+		"Estimated blocks between the checkpoint and the chain tip", []string{"chain"}, nil)
+)
+
+// indexingCollector reads indexing progress from the database on each scrape
+type indexingCollector struct {
+	svc     *Service
+	chains  []types.ChainID
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// IndexingCollector exposes DB-derived indexing progress for chains on the API's
+// /metrics, for deployments where the indexer's metrics port is not reachable.
+// Chains without a checkpoint, or whose query fails, are omitted; a failure is
+// logged rather than failing the whole scrape.
+func (s *Service) IndexingCollector(chains []types.ChainID, logger *slog.Logger) prometheus.Collector {
+	return &indexingCollector{svc: s, chains: chains, timeout: 2 * time.Second, logger: logger}
+}
+
+func (c *indexingCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- indexingHeightDesc
+	ch <- indexingLagDesc
+	ch <- indexingAgeDesc
+	ch <- indexingBehindDesc
+}
+
+func (c *indexingCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	for _, chainID := range c.chains {
+		st, err := c.svc.GetIndexingStatus(ctx, chainID, 0)
+		if err != nil {
+			c.logger.Warn("reading indexing status for metrics failed", "chain", chainID, "error", err)
+			continue
+		}
+		if st == nil {
+			continue
+		}
+
+		chain := string(chainID)
+		ch <- prometheus.MustNewConstMetric(indexingHeightDesc, prometheus.GaugeValue, float64(st.CheckpointHeight), chain)
+		ch <- prometheus.MustNewConstMetric(indexingLagDesc, prometheus.GaugeValue, float64(st.LagSeconds), chain)
+		ch <- prometheus.MustNewConstMetric(indexingAgeDesc, prometheus.GaugeValue, float64(st.CheckpointAge), chain)
+		ch <- prometheus.MustNewConstMetric(indexingBehindDesc, prometheus.GaugeValue, float64(st.BlocksBehind), chain)
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/internal/indexer/internal/api/query"
+	"github.com/internal/indexer/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestEstimateBlocksBehind(t *testing.T) {
+	tests := []struct {
+		lag       int64
+		blockTime float64
+		want      uint64
+	}{
+		{0, 12, 0},
+		{11, 12, 0}, // newest block is at most one block time old
+		{120, 12, 10},
+		{3600, 600, 6},
+		{100, 0, 0}, // unknown block time
+		{-5, 12, 0}, // clock skew
+	}
+
+	for _, tt := range tests {
+		if got := estimateBlocksBehind(tt.lag, tt.blockTime); got != tt.want {
+			t.Errorf("estimateBlocksBehind(%d, %v) = %d, want %d", tt.lag, tt.blockTime, got, tt.want)
+		}
+	}
+}
+
+// failingStatusStore fails the indexing status query for BTC; other Store methods are not used
+type failingStatusStore struct {
+	query.Store
+}
+
+func (s *failingStatusStore) GetIndexingStatus(ctx context.Context, chainID types.ChainID) (*types.IndexingStatus, error) {
+	if chainID == types.ChainBTC {
+		return nil, errors.New("connection refused")
+	}
+	return &types.IndexingStatus{CheckpointHeight: 100, CheckpointUpdatedAt: time.Now()}, nil
+}
+
+func TestIndexingCollector_SkipsFailedChain(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	svc := New(&failingStatusStore{}, nil)
+	reg.MustRegister(svc.IndexingCollector([]types.ChainID{types.ChainBTC, types.ChainETH}, slog.New(slog.DiscardHandler)))
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("expected the scrape to succeed with one chain failing, got %v", err)
+	}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			if chain := m.GetLabel()[0].GetValue(); chain != "eth" {
+				t.Errorf("%s: expected only eth, got %s", f.GetName(), chain)
+			}
+		}
+	}
+	if len(families) != 4 {
+		t.Errorf("expected the 4 indexing metrics for eth, got %d", len(families))
+	}
+}
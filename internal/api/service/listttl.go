@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// syncCheckInterval is how long a chain's catching-up state is reused before the
+// checkpoint is read again
+const syncCheckInterval = 5 * time.Second
+
+// syncingTTL shortens list-page cache TTLs while a chain's indexer is catching up,
+// when each cached page goes stale within a few blocks
+type syncingTTL struct {
+	ttl    time.Duration // Cap applied while syncing (0 = disabled)
+	behind uint64        // Estimated blocks behind at which a chain counts as syncing
+
+	mu      sync.Mutex
+	checked map[types.ChainID]syncCheck
+}
+
+type syncCheck struct {
+	syncing bool
+	at      time.Time
+}
+
+// SetSyncingListTTL caps the cache TTL of list pages (latest txs, block txs, events,
+// block ranges) at ttl while a chain is at least behind blocks behind its tip, as
+// estimated from the checkpoints table. At the tip the fixed per-endpoint TTLs apply.
+// A ttl of zero or less disables the cap.
+func (s *Service) SetSyncingListTTL(ttl time.Duration, behind uint64) {
+	s.syncing = &syncingTTL{ttl: ttl, behind: behind, checked: make(map[types.ChainID]syncCheck)}
+}
+
+// listTTL returns the cache TTL for a list page of chainID whose at-tip TTL is ttl
+func (s *Service) listTTL(ctx context.Context, chainID types.ChainID, ttl time.Duration) time.Duration {
+	if s.syncing == nil || s.syncing.ttl <= 0 || ttl <= s.syncing.ttl {
+		return ttl
+	}
+	if s.isSyncing(ctx, chainID) {
+		return s.syncing.ttl
+	}
+	return ttl
+}
+
+// isSyncing reports whether chainID is catching up, re-reading the checkpoint at
+// most once per syncCheckInterval. Errors and missing checkpoints count as at tip.
+func (s *Service) isSyncing(ctx context.Context, chainID types.ChainID) bool {
+	st := s.syncing
+	st.mu.Lock()
+	c, ok := st.checked[chainID]
+	st.mu.Unlock()
+	if ok && time.Since(c.at) < syncCheckInterval {
+		return c.syncing
+	}
+
+	status, err := s.GetIndexingStatus(ctx, chainID, 0)
+	syncing := err == nil && status != nil && status.BlocksBehind >= st.behind
+
+	st.mu.Lock()
+	st.checked[chainID] = syncCheck{syncing: syncing, at: time.Now()}
+	st.mu.Unlock()
+	return syncing
+}
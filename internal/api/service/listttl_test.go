@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/internal/indexer/internal/api/query"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// statusStore serves a fixed indexing status; other Store methods are not used
+type statusStore struct {
+	query.Store
+	status *types.IndexingStatus
+	reads  int
+}
+
+func (s *statusStore) GetIndexingStatus(ctx context.Context, chainID types.ChainID) (*types.IndexingStatus, error) {
+	s.reads++
+	return s.status, nil
+}
+
+func TestListTTL(t *testing.T) {
+	store := &statusStore{status: &types.IndexingStatus{LagSeconds: 3600}} // ~300 ETH blocks behind
+	svc := New(store, nil)
+	ctx := context.Background()
+
+	if got := svc.listTTL(ctx, types.ChainETH, 10*time.Second); got != 10*time.Second {
+		t.Errorf("without a syncing TTL: got %v, want 10s", got)
+	}
+
+	svc.SetSyncingListTTL(time.Second, 10)
+	if got := svc.listTTL(ctx, types.ChainETH, 10*time.Second); got != time.Second {
+		t.Errorf("while syncing: got %v, want 1s", got)
+	}
+	if got := svc.listTTL(ctx, types.ChainETH, 500*time.Millisecond); got != 500*time.Millisecond {
+		t.Errorf("TTL already under the cap: got %v, want 500ms", got)
+	}
+
+	// The state is reused within syncCheckInterval
+	svc.listTTL(ctx, types.ChainETH, 10*time.Second)
+	if store.reads != 1 {
+		t.Errorf("expected 1 checkpoint read, got %d", store.reads)
+	}
+
+	// At the tip the fixed TTL applies
+	store.status = &types.IndexingStatus{LagSeconds: 5}
+	svc.SetSyncingListTTL(time.Second, 10)
+	if got := svc.listTTL(ctx, types.ChainETH, 10*time.Second); got != 10*time.Second {
+		t.Errorf("at tip: got %v, want 10s", got)
+	}
+}
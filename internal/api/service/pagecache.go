@@ -0,0 +1,35 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SetCacheFirstPageOnly limits caching of cursor-paginated lists to the first
+// page. Deep pages are rarely requested twice within a TTL, so caching them
+// mostly churns Redis memory.
+func (s *Service) SetCacheFirstPageOnly(enabled bool) {
+	s.firstPageOnly = enabled
+}
+
+// normalizePage canonicalizes a cursor/limit pair so equivalent requests share
+// one cache key. limit is clamped the way the store clamps it; cursors are
+// heights or tx indexes, so "007" and " 7" both become "7". cacheable is false
+// for cursors that are not plain numbers and, with cache_first_page_only, for
+// every page after the first.
+func (s *Service) normalizePage(cursor string, limit, defaultLimit, maxLimit int) (string, int, bool) {
+	if limit <= 0 || limit > maxLimit {
+		limit = defaultLimit
+	}
+
+	cursor = strings.TrimSpace(cursor)
+	if cursor == "" {
+		return "", limit, true
+	}
+	n, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		// Leave it to the store to reject; never cache the result
+		return cursor, limit, false
+	}
+	return strconv.FormatUint(n, 10), limit, !s.firstPageOnly
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/internal/indexer/internal/api/cache/cachetest"
+	"github.com/internal/indexer/internal/api/query"
+	"github.com/internal/indexer/pkg/types"
+)
+
+func TestNormalizePage(t *testing.T) {
+	tests := []struct {
+		name          string
+		firstPageOnly bool
+		cursor        string
+		limit         int
+		wantCursor    string
+		wantLimit     int
+		wantCacheable bool
+	}{
+		{name: "first page default limit", cursor: "", limit: 0, wantCursor: "", wantLimit: 20, wantCacheable: true},
+		{name: "limit over max", cursor: "", limit: 500, wantCursor: "", wantLimit: 20, wantCacheable: true},
+		{name: "leading zeros", cursor: "007", limit: 50, wantCursor: "7", wantLimit: 50, wantCacheable: true},
+		{name: "whitespace", cursor: " 42 ", limit: 20, wantCursor: "42", wantLimit: 20, wantCacheable: true},
+		{name: "non-numeric cursor", cursor: "abc", limit: 20, wantCursor: "abc", wantLimit: 20, wantCacheable: false},
+		{name: "first page only, first page", firstPageOnly: true, cursor: "", limit: 20, wantCursor: "", wantLimit: 20, wantCacheable: true},
+		{name: "first page only, deep page", firstPageOnly: true, cursor: "100", limit: 20, wantCursor: "100", wantLimit: 20, wantCacheable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{firstPageOnly: tt.firstPageOnly}
+			cursor, limit, cacheable := s.normalizePage(tt.cursor, tt.limit, 20, 100)
+			if cursor != tt.wantCursor || limit != tt.wantLimit || cacheable != tt.wantCacheable {
+				t.Errorf("normalizePage(%q, %d) = (%q, %d, %v), want (%q, %d, %v)",
+					tt.cursor, tt.limit, cursor, limit, cacheable, tt.wantCursor, tt.wantLimit, tt.wantCacheable)
+			}
+		})
+	}
+}
+
+// eventStore counts event queries; other Store methods are not used
+type eventStore struct {
+	query.Store
+	calls int
+}
+
+func (s *eventStore) GetEvents(ctx context.Context, filter query.EventFilter) ([]*types.Event, string, error) {
+	s.calls++
+	return []*types.Event{{ChainID: filter.ChainID, ContractAddr: filter.ContractAddr}}, "", nil
+}
+
+func TestGetEvents_CacheKeyIgnoresAddressCase(t *testing.T) {
+	store := &eventStore{}
+	svc := New(store, cachetest.Map{})
+
+	for _, addr := range []string{"0xAbCd", "0xabcd", "0xABCD"} {
+		if _, _, err := svc.GetEvents(context.Background(), query.EventFilter{ChainID: types.ChainETH, ContractAddr: addr}); err != nil {
+			t.Fatalf("GetEvents: %v", err)
+		}
+	}
+	if store.calls != 1 {
+		t.Errorf("expected one store query for the same contract in any case, got %d", store.calls)
+	}
+}
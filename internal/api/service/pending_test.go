@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/internal/indexer/internal/api/cache"
+	"github.com/internal/indexer/internal/api/cache/cachetest"
+	"github.com/internal/indexer/pkg/types"
+)
+
+func TestGetPendingTransactions(t *testing.T) {
+	c := cachetest.Map{}
+	svc := New(nil, c)
+	ctx := context.Background()
+
+	// Nothing cached yet: an empty list, not nil, so the handler encodes []
+	txs, err := svc.GetPendingTransactions(ctx, types.ChainETH)
+	if err != nil {
+		t.Fatalf("GetPendingTransactions: %v", err)
+	}
+	if txs == nil || len(txs) != 0 {
+		t.Fatalf("expected an empty list on a cache miss, got %#v", txs)
+	}
+
+	// The list as the mempool poller stores it
+	seen := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c[cache.MempoolKey("eth")] = []byte(`[{"hash":"0xabc","from":"0x1","to":"0x2","value":"0x10","first_seen":"` + seen.Format(time.RFC3339) + `"}]`)
+
+	txs, err = svc.GetPendingTransactions(ctx, types.ChainETH)
+	if err != nil {
+		t.Fatalf("GetPendingTransactions: %v", err)
+	}
+	if len(txs) != 1 {
+		t.Fatalf("expected 1 pending tx, got %d", len(txs))
+	}
+	tx := txs[0]
+	if tx.TxHash != "0xabc" || tx.FromAddr != "0x1" || tx.ToAddr != "0x2" || tx.Value != "0x10" {
+		t.Errorf("unexpected tx: %+v", tx.Transaction)
+	}
+	if tx.Status != types.StatusPending || tx.ChainID != types.ChainETH || !tx.FirstSeen.Equal(seen) {
+		t.Errorf("unexpected status %q, chain %q or first seen %v", tx.Status, tx.ChainID, tx.FirstSeen)
+	}
+
+	// Other chains have their own list
+	if txs, _ := svc.GetPendingTransactions(ctx, types.ChainBTC); len(txs) != 0 {
+		t.Errorf("expected no BTC pending txs, got %d", len(txs))
+	}
+}
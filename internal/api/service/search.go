@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// Search result types, telling the frontend which page to route to
+const (
+	SearchBlock     = "block"
+	SearchTx        = "tx"
+	SearchAddress   = "address"
+	SearchTokenList = "token_list"
+)
+
+// SearchResult is the entity a search query resolved to
+type SearchResult struct {
+	Type string      `json:"type"` // One of the Search* types
+	Data interface{} `json:"data"` // *types.Block, *types.Transaction, *AddressSearchResult or []types.Token
+}
+
+// AddressSearchResult is an address found by search with its stats and token
+// balances. Stats is nil for an address the indexer has not seen.
+type AddressSearchResult struct {
+	Address       string              `json:"address"`
+	Stats         *types.AddressStats `json:"stats"`
+	TokenBalances []TokenBalanceView  `json:"token_balances"`
+}
+
+var (
+	searchHashPattern    = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{64}$`)
+	searchHeightPattern  = regexp.MustCompile(`^[0-9]+$`)
+	searchAddressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+// Search resolves a search box query on chainID by its shape: a 64-hex hash is
+// looked up as a tx, then a block; digits as a block height; a 0x-prefixed
+// 40-hex string as an address. Anything else, or any query without a chain,
+// is matched against token names and symbols. It returns nil when nothing matches.
+func (s *Service) Search(ctx context.Context, chainID types.ChainID, q string) (*SearchResult, error) {
+	q = strings.TrimSpace(q)
+
+	if chainID != "" {
+		switch {
+		case searchHashPattern.MatchString(q):
+			return s.searchHash(ctx, chainID, q)
+		case searchHeightPattern.MatchString(q):
+			height, err := strconv.ParseUint(q, 10, 64)
+			if err != nil {
+				return nil, nil // Too large to be a height
+			}
+			b, err := s.GetBlockByHeight(ctx, chainID, height)
+			if err != nil || b == nil {
+				return nil, err
+			}
+			return &SearchResult{Type: SearchBlock, Data: b}, nil
+		case searchAddressPattern.MatchString(q):
+			return s.searchAddress(ctx, chainID, q)
+		}
+	}
+
+	if len(q) < 3 {
+		return nil, nil
+	}
+	tokens, err := s.store.SearchTokens(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if chainID != "" {
+		matched := tokens[:0]
+		for _, t := range tokens {
+			if t.ChainID == chainID {
+				matched = append(matched, t)
+			}
+		}
+		tokens = matched
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return &SearchResult{Type: SearchTokenList, Data: tokens}, nil
+}
+
+// searchHash looks hash up as a transaction, then as a block
+func (s *Service) searchHash(ctx context.Context, chainID types.ChainID, hash string) (*SearchResult, error) {
+	tx, err := s.GetTx(ctx, chainID, hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx != nil {
+		return &SearchResult{Type: SearchTx, Data: tx}, nil
+	}
+
+	b, err := s.GetBlockByHash(ctx, chainID, hash)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return &SearchResult{Type: SearchBlock, Data: b}, nil
+}
+
+// searchAddress returns an address result even when the address has no
+// activity, so the frontend can still open its page
+func (s *Service) searchAddress(ctx context.Context, chainID types.ChainID, address string) (*SearchResult, error) {
+	address = types.NormalizeAddress(chainID, address)
+	stats, err := s.GetAddressStats(ctx, chainID, address, false)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := s.GetTokenBalances(ctx, chainID, address)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchResult{Type: SearchAddress, Data: &AddressSearchResult{
+		Address:       address,
+		Stats:         stats,
+		TokenBalances: balances,
+	}}, nil
+}
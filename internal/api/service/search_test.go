@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/internal/indexer/internal/api/cache/cachetest"
+	"github.com/internal/indexer/internal/api/query"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// searchStore holds one ETH block, tx and address; other Store methods are not used
+type searchStore struct {
+	query.Store
+}
+
+const (
+	searchTxHash    = "0x" + "aa" + "00000000000000000000000000000000000000000000000000000000000000"
+	searchBlockHash = "0x" + "bb" + "00000000000000000000000000000000000000000000000000000000000000"
+	searchAddr      = "0x52908400098527886e0f7030069857d2e4169ee7"
+)
+
+func (s *searchStore) GetTx(ctx context.Context, chainID types.ChainID, hash string) (*types.Transaction, error) {
+	if hash == searchTxHash {
+		return &types.Transaction{ChainID: chainID, TxHash: hash}, nil
+	}
+	return nil, nil
+}
+
+func (s *searchStore) GetBlockByHash(ctx context.Context, chainID types.ChainID, hash string) (*types.Block, error) {
+	if hash == searchBlockHash {
+		return &types.Block{ChainID: chainID, Height: 7, Hash: hash}, nil
+	}
+	return nil, nil
+}
+
+func (s *searchStore) GetBlockByHeight(ctx context.Context, chainID types.ChainID, height uint64) (*types.Block, error) {
+	if height == 7 {
+		return &types.Block{ChainID: chainID, Height: 7, Hash: searchBlockHash}, nil
+	}
+	return nil, nil
+}
+
+func (s *searchStore) GetAddressStats(ctx context.Context, chainID types.ChainID, address string) (*types.AddressStats, error) {
+	if address == searchAddr {
+		return &types.AddressStats{ChainID: chainID, Address: address, Balance: "5"}, nil
+	}
+	return nil, nil
+}
+
+func (s *searchStore) GetTokenBalances(ctx context.Context, chainID types.ChainID, address string) ([]types.TokenBalance, error) {
+	if address == searchAddr {
+		return []types.TokenBalance{{ChainID: chainID, Address: address, TokenAddress: "0xtoken", Balance: "100"}}, nil
+	}
+	return nil, nil
+}
+
+func (s *searchStore) GetTokenDecimals(ctx context.Context, chainID types.ChainID, tokenAddrs []string) (map[string]int, error) {
+	return map[string]int{"0xtoken": 2}, nil
+}
+
+func (s *searchStore) SearchTokens(ctx context.Context, q string) ([]types.Token, error) {
+	var tokens []types.Token
+	for _, t := range []types.Token{
+		{ChainID: types.ChainETH, Address: "0xusdc", Symbol: "USDC"},
+		{ChainID: "polygon", Address: "0xusdc2", Symbol: "USDC"},
+	} {
+		if strings.Contains(t.Symbol, strings.ToUpper(q)) {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
+
+func TestSearch(t *testing.T) {
+	svc := New(&searchStore{}, cachetest.Map{})
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name  string
+		chain types.ChainID
+		q     string
+		want  string // Result type, "" for no result
+	}{
+		{"tx hash", types.ChainETH, searchTxHash, SearchTx},
+		{"block hash after tx miss", types.ChainETH, searchBlockHash, SearchBlock},
+		{"unknown hash", types.ChainETH, "0x" + strings.Repeat("c", 64), ""},
+		{"height", types.ChainETH, "7", SearchBlock},
+		{"unindexed height", types.ChainETH, "8", ""},
+		{"height overflow", types.ChainETH, "99999999999999999999999", ""},
+		{"address", types.ChainETH, "0x52908400098527886E0F7030069857D2E4169EE7", SearchAddress},
+		{"unseen address", types.ChainETH, "0x" + strings.Repeat("1", 40), SearchAddress},
+		{"token", types.ChainETH, "usd", SearchTokenList},
+		{"no chain searches tokens only", "", "7", ""},
+		{"short token query", types.ChainETH, "us", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := svc.Search(ctx, tc.chain, tc.q)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			got := ""
+			if res != nil {
+				got = res.Type
+			}
+			if got != tc.want {
+				t.Errorf("expected %q, got %q (%+v)", tc.want, got, res)
+			}
+		})
+	}
+
+	res, err := svc.Search(ctx, types.ChainETH, "0x52908400098527886E0F7030069857D2E4169EE7")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	addr, ok := res.Data.(*AddressSearchResult)
+	if !ok || addr.Address != searchAddr || addr.Stats == nil || addr.Stats.Balance != "5" {
+		t.Fatalf("expected the normalized address with its stats, got %+v", res.Data)
+	}
+	if len(addr.TokenBalances) != 1 || addr.TokenBalances[0].Amount != "1" {
+		t.Errorf("expected one formatted token balance, got %+v", addr.TokenBalances)
+	}
+
+	// Clients see the documented snake_case fields
+	body, err := json.Marshal(addr)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	for _, key := range []string{"address", "stats", "token_balances"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("expected %q in %s", key, body)
+		}
+	}
+
+	// Token matches are limited to the requested chain
+	res, _ = svc.Search(ctx, types.ChainETH, "usdc")
+	if tokens, _ := res.Data.([]types.Token); len(tokens) != 1 || tokens[0].ChainID != types.ChainETH {
+		t.Errorf("expected only the ETH token, got %+v", res.Data)
+	}
+	res, _ = svc.Search(ctx, "", "usdc")
+	if tokens, _ := res.Data.([]types.Token); len(tokens) != 2 {
+		t.Errorf("expected tokens of every chain without a chain, got %+v", res.Data)
+	}
+}
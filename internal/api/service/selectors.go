@@ -0,0 +1,85 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// defaultSelectors seeds the registry with common 4-byte method selectors
+var defaultSelectors = map[string]string{
+	"0xa9059cbb": "transfer(address,uint256)",
+	"0x23b872dd": "transferFrom(address,address,uint256)",
+	"0x095ea7b3": "approve(address,uint256)",
+	"0x42842e0e": "safeTransferFrom(address,address,uint256)",
+	"0xb88d4fde": "safeTransferFrom(address,address,uint256,bytes)",
+	"0xa22cb465": "setApprovalForAll(address,bool)",
+	"0xd0e30db0": "deposit()",
+	"0x2e1a7d4d": "withdraw(uint256)",
+	"0x40c10f19": "mint(address,uint256)",
+	"0x42966c68": "burn(uint256)",
+	"0x7ff36ab5": "swapExactETHForTokens(uint256,address[],address,uint256)",
+	"0x18cbafe5": "swapExactTokensForETH(uint256,uint256,address[],address,uint256)",
+	"0x38ed1739": "swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+	"0x3593564c": "execute(bytes,bytes[],uint256)",
+	"0xac9650d8": "multicall(bytes[])",
+}
+
+// SelectorRegistry maps 4-byte method selectors to human-readable signatures
+type SelectorRegistry struct {
+	names map[string]string
+}
+
+// NewSelectorRegistry creates a registry seeded with common selectors plus any extras
+// (keys are "0x"-prefixed hex, case-insensitive; extras override the defaults)
+func NewSelectorRegistry(extra map[string]string) *SelectorRegistry {
+	names := make(map[string]string, len(defaultSelectors)+len(extra))
+	for sel, name := range defaultSelectors {
+		names[sel] = name
+	}
+	for sel, name := range extra {
+		sel = strings.ToLower(sel)
+		if !strings.HasPrefix(sel, "0x") {
+			sel = "0x" + sel
+		}
+		names[sel] = name
+	}
+	return &SelectorRegistry{names: names}
+}
+
+// Lookup returns the selector of hex calldata and its method name if known.
+// Plain value transfers (empty input) return an empty selector.
+func (r *SelectorRegistry) Lookup(input string) (selector, method string) {
+	input = strings.ToLower(input)
+	if !strings.HasPrefix(input, "0x") || len(input) < 10 {
+		return "", ""
+	}
+	selector = input[:10]
+	return selector, r.names[selector]
+}
+
+// TxView is a transaction enriched with its decoded method (ETH only)
+type TxView struct {
+	*types.Transaction
+	MethodSelector string `json:"method_selector,omitempty"`
+	Method         string `json:"method,omitempty"` // Empty when the selector is unknown
+}
+
+// EnrichTx attaches the method selector and name from the tx input data
+func (s *Service) EnrichTx(tx *types.Transaction) *TxView {
+	view := &TxView{Transaction: tx}
+	if tx.ChainID != types.ChainETH || len(tx.RawData) == 0 {
+		return view
+	}
+
+	var raw struct {
+		Input string `json:"input"`
+	}
+	if err := json.Unmarshal(tx.RawData, &raw); err != nil {
+		return view
+	}
+
+	view.MethodSelector, view.Method = s.selectors.Lookup(raw.Input)
+	return view
+}
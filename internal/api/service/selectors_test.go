@@ -0,0 +1,26 @@
+package service
+
+import "testing"
+
+func TestSelectorRegistry_Lookup(t *testing.T) {
+	r := NewSelectorRegistry(map[string]string{"12345678": "custom(uint256)"})
+
+	tests := []struct {
+		input    string
+		selector string
+		method   string
+	}{
+		{"0xa9059cbb000000000000000000000000ab", "0xa9059cbb", "transfer(address,uint256)"},
+		{"0x12345678", "0x12345678", "custom(uint256)"},
+		{"0xdeadbeef00", "0xdeadbeef", ""}, // unknown selector
+		{"0x", "", ""},                     // plain transfer
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		sel, method := r.Lookup(tt.input)
+		if sel != tt.selector || method != tt.method {
+			t.Errorf("Lookup(%q) = (%q, %q), want (%q, %q)", tt.input, sel, method, tt.selector, tt.method)
+		}
+	}
+}
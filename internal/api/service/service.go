@@ -7,30 +7,42 @@ import (
 	"fmt"
 	"time"
 
+	"golang.org/x/sync/singleflight"
+
 	"github.com/internal/indexer/internal/api/cache"
 	"github.com/internal/indexer/internal/api/query"
 	"github.com/internal/indexer/pkg/types"
 )
 
-type SearchResult struct {
-	Type string      `json:"type"` // "block", "tx", "address", "token_list"
-	Data interface{} `json:"data"`
-}
-
 // Service defines the business logic including caching
 type Service struct {
-	store query.Store
-	cache cache.Cache
+	store     query.Store
+	cache     cache.Cache
+	selectors *SelectorRegistry
+	health    healthChecker
+
+	firstPageOnly bool                   // Only cache the first page of cursor-paginated lists
+	syncing       *syncingTTL            // Shorter list TTLs while a chain is catching up (nil = fixed TTLs)
+	feeStats      map[types.ChainID]bool // Chains whose network stats include fee summaries
+
+	flight singleflight.Group // Collapses concurrent cache fills of the same key (see fill)
 }
 
 // New creates a new Service
 func New(store query.Store, cache cache.Cache) *Service {
 	return &Service{
-		store: store,
-		cache: cache,
+		store:     store,
+		cache:     cache,
+		selectors: NewSelectorRegistry(nil),
+		health:    healthChecker{timeout: 2 * time.Second, ttl: 2 * time.Second},
 	}
 }
 
+// SetMethodSelectors extends the built-in selector registry used for tx method names
+func (s *Service) SetMethodSelectors(extra map[string]string) {
+	s.selectors = NewSelectorRegistry(extra)
+}
+
 // GetLatestBlock returns the latest block, using cache
 func (s *Service) GetLatestBlock(ctx context.Context, chainID types.ChainID) (*types.Block, error) {
 	key := cache.LatestBlockKey(string(chainID))
@@ -42,24 +54,47 @@ func (s *Service) GetLatestBlock(ctx context.Context, chainID types.ChainID) (*t
 		return &block, nil
 	}
 
-	// db lookup
-	b, err := s.store.GetLatestBlock(ctx, chainID)
-	if err != nil {
-		return nil, err
-	}
-	if b == nil {
-		return nil, nil // Not found
-	}
+	return fill(ctx, s, key, func(ctx context.Context) (*types.Block, error) {
+		// db lookup
+		b, err := s.store.GetLatestBlock(ctx, chainID)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			return nil, nil // Not found
+		}
+
+		// cache populate (short TTL for latest)
+		// We use a short TTL because "latest" changes frequently.
+		// We defined ShortCacheTTL in config/redis.go (default 15s).
+		// But here we don't have access to config directly unless passed or hardcoded/method on cache.
+		// cache.Set uses default TTL if 0. We might want to pass explicit short TTL.
+		// Let's assume 5 seconds for latest block to be safe.
+		s.cache.Set(ctx, key, b, 5*time.Second)
+
+		return b, nil
+	})
+}
+
+// GetLatestBlocks returns the latest block of every indexed chain, cached as
+// briefly as GetLatestBlock
+func (s *Service) GetLatestBlocks(ctx context.Context) (map[types.ChainID]*types.Block, error) {
+	key := cache.LatestBlocksKey()
 
-	// cache populate (short TTL for latest)
-	// We use a short TTL because "latest" changes frequently.
-	// We defined ShortCacheTTL in config/redis.go (default 15s).
-	// But here we don't have access to config directly unless passed or hardcoded/method on cache.
-	// cache.Set uses default TTL if 0. We might want to pass explicit short TTL.
-	// Let's assume 5 seconds for latest block to be safe.
-	s.cache.Set(ctx, key, b, 5*time.Second)
+	var blocks map[types.ChainID]*types.Block
+	found, err := s.cache.Get(ctx, key, &blocks)
+	if err == nil && found {
+		return blocks, nil
+	}
 
-	return b, nil
+	return fill(ctx, s, key, func(ctx context.Context) (map[types.ChainID]*types.Block, error) {
+		blocks, err := s.store.GetLatestBlocks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.cache.Set(ctx, key, blocks, 5*time.Second)
+		return blocks, nil
+	})
 }
 
 // GetBlockByHeight returns a block by height, using cache
@@ -72,29 +107,31 @@ func (s *Service) GetBlockByHeight(ctx context.Context, chainID types.ChainID, h
 		return &block, nil
 	}
 
-	b, err := s.store.GetBlockByHeight(ctx, chainID, height)
-	if err != nil {
-		return nil, err
-	}
-	if b == nil {
-		return nil, nil
-	}
+	return fill(ctx, s, key, func(ctx context.Context) (*types.Block, error) {
+		b, err := s.store.GetBlockByHeight(ctx, chainID, height)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			return nil, nil
+		}
 
-	// Cache indefinitely/long TTL for historical blocks?
-	// If the block is NOT finalized, we should cache shortly.
-	// If finalized, longer.
-	ttl := 1 * time.Hour // Default long
-	if b.Status != types.StatusFinalized {
-		ttl = 10 * time.Second
-	}
+		// Cache indefinitely/long TTL for historical blocks?
+		// If the block is NOT finalized, we should cache shortly.
+		// If finalized, longer.
+		ttl := 1 * time.Hour // Default long
+		if b.Status != types.StatusFinalized {
+			ttl = 10 * time.Second
+		}
 
-	s.cache.Set(ctx, key, b, ttl)
+		s.cache.Set(ctx, key, b, ttl)
 
-	// Also cache by hash if possible?
-	// The prompt requirement implies lookups. We can dual-cache.
-	s.cache.Set(ctx, cache.BlockKey(string(chainID), b.Hash), b, ttl)
+		// Also cache by hash if possible?
+		// The prompt requirement implies lookups. We can dual-cache.
+		s.cache.Set(ctx, cache.BlockKey(string(chainID), b.Hash), b, ttl)
 
-	return b, nil
+		return b, nil
+	})
 }
 
 // GetBlockByHash returns a block by hash, using cache
@@ -107,24 +144,62 @@ func (s *Service) GetBlockByHash(ctx context.Context, chainID types.ChainID, has
 		return &block, nil
 	}
 
-	b, err := s.store.GetBlockByHash(ctx, chainID, hash)
-	if err != nil {
-		return nil, err
-	}
-	if b == nil {
-		return nil, nil
+	return fill(ctx, s, key, func(ctx context.Context) (*types.Block, error) {
+		b, err := s.store.GetBlockByHash(ctx, chainID, hash)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			return nil, nil
+		}
+
+		ttl := 1 * time.Hour
+		if b.Status != types.StatusFinalized {
+			ttl = 10 * time.Second
+		}
+
+		s.cache.Set(ctx, key, b, ttl)
+		// Key by height too
+		s.cache.Set(ctx, cache.BlockHeightKey(string(chainID), b.Height), b, ttl)
+
+		return b, nil
+	})
+}
+
+// GetBlockNeighbors returns the previous and next block references for navigation.
+// Prev is derived from the stored parent hash (nil at genesis); next is nil at the indexed tip.
+func (s *Service) GetBlockNeighbors(ctx context.Context, b *types.Block) (prev, next *types.BlockRef, err error) {
+	if b.Height > 0 && b.ParentHash != "" {
+		prev = &types.BlockRef{Height: b.Height - 1, Hash: b.ParentHash}
 	}
 
-	ttl := 1 * time.Hour
-	if b.Status != types.StatusFinalized {
-		ttl = 10 * time.Second
+	key := fmt.Sprintf("block:%s:%d:next", b.ChainID, b.Height)
+	var cached types.BlockRef
+	found, cacheErr := s.cache.Get(ctx, key, &cached)
+	if cacheErr == nil && found {
+		return prev, &cached, nil
 	}
 
-	s.cache.Set(ctx, key, b, ttl)
-	// Key by height too
-	s.cache.Set(ctx, cache.BlockHeightKey(string(chainID), b.Height), b, ttl)
+	next, err = fill(ctx, s, key, func(ctx context.Context) (*types.BlockRef, error) {
+		next, err := s.store.GetBlockRefByHeight(ctx, b.ChainID, b.Height+1)
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			// Only cache once the successor exists; the tip must be re-checked
+			ttl := 1 * time.Hour
+			if b.Status != types.StatusFinalized {
+				ttl = 10 * time.Second
+			}
+			s.cache.Set(ctx, key, next, ttl)
+		}
+		return next, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-	return b, nil
+	return prev, next, nil
 }
 
 // GetTx returns a transaction by hash, using cache
@@ -137,64 +212,95 @@ func (s *Service) GetTx(ctx context.Context, chainID types.ChainID, hash string)
 		return &tx, nil
 	}
 
-	t, err := s.store.GetTx(ctx, chainID, hash)
-	if err != nil {
-		return nil, err
-	}
-	if t == nil {
-		return nil, nil
-	}
+	return fill(ctx, s, key, func(ctx context.Context) (*types.Transaction, error) {
+		t, err := s.store.GetTx(ctx, chainID, hash)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, nil
+		}
 
-	s.cache.Set(ctx, key, t, 1*time.Hour) // Tx are usually immutable unless reorg
-	return t, nil
+		s.cache.Set(ctx, key, t, 1*time.Hour) // Tx are usually immutable unless reorg
+		return t, nil
+	})
 }
 
-// GetTransactionsByAddress returns txs for address
-func (s *Service) GetTransactionsByAddress(ctx context.Context, chainID types.ChainID, address, cursor string, limit int) ([]*types.Transaction, string, error) {
+// GetTransactionsByAddress returns txs for address, limited to one side per direction
+func (s *Service) GetTransactionsByAddress(ctx context.Context, chainID types.ChainID, address string, direction query.TxDirection, cursor string, limit int) ([]*types.Transaction, string, error) {
 	// List queries are harder to cache effectively due to cursors.
 	// We will skip caching for now or implement short caching based on params hash.
-	return s.store.GetTransactionsByAddress(ctx, chainID, address, cursor, limit)
+	return s.store.GetTransactionsByAddress(ctx, chainID, address, direction, cursor, limit)
+}
+
+// GetFirstTransaction returns the earliest transaction sent from or to address
+func (s *Service) GetFirstTransaction(ctx context.Context, chainID types.ChainID, address string) (*types.Transaction, error) {
+	return s.store.GetFirstTransaction(ctx, chainID, address)
+}
+
+// GetAddressLedger returns the signed native-value entries (transfers and fees) for address, newest first
+func (s *Service) GetAddressLedger(ctx context.Context, chainID types.ChainID, address, cursor string, limit int) ([]types.LedgerEntry, string, error) {
+	return s.store.GetAddressLedger(ctx, chainID, address, cursor, limit)
+}
+
+// MaxAddressesPerQuery caps the address set accepted by GetTransactionsByAddresses
+const MaxAddressesPerQuery = 100
+
+// GetTransactionsByAddresses returns txs touching any of addresses in one paginated query
+func (s *Service) GetTransactionsByAddresses(ctx context.Context, chainID types.ChainID, addresses []string, cursor string, limit int) ([]*types.Transaction, string, error) {
+	return s.store.GetTransactionsByAddresses(ctx, chainID, addresses, cursor, limit)
 }
 
-// GetEvents returns events based on filter, using cache for specific queries?
+// GetEvents returns events based on filter, caching pages by normalized cursor/limit
 func (s *Service) GetEvents(ctx context.Context, filter query.EventFilter) ([]*types.Event, string, error) {
-	// If query is broad, maybe cache?
-	// Let's generate a cache key from filter
-	cacheKey := fmt.Sprintf("events:%s:%s:%s:%s:%s:%s:%d",
-		filter.ChainID, filter.ContractAddr, filter.Topic0,
-		strPtr(filter.FromHeight), strPtr(filter.ToHeight), filter.Cursor, filter.Limit)
+	var cacheable bool
+	filter.Cursor, filter.Limit, cacheable = s.normalizePage(filter.Cursor, filter.Limit, 20, 100)
+	if !cacheable {
+		return s.store.GetEvents(ctx, filter)
+	}
+
+	cacheKey := fmt.Sprintf("events:%s:%s:%s:%s:%s:%s:%d:%t",
+		filter.ChainID, types.NormalizeAddress(filter.ChainID, filter.ContractAddr), filter.Topic0,
+		strPtr(filter.FromHeight), strPtr(filter.ToHeight), filter.Cursor, filter.Limit, filter.ExcludeReverted)
 
 	hashedKey := sha256.Sum256([]byte(cacheKey))
 	key := "req:events:" + hex.EncodeToString(hashedKey[:])
 
-	var cachedResult struct {
+	type eventPage struct {
 		Events []*types.Event
 		Cursor string
 	}
+	var cachedResult eventPage
 	found, err := s.cache.Get(ctx, key, &cachedResult)
 	if err == nil && found {
 		return cachedResult.Events, cachedResult.Cursor, nil
 	}
 
-	events, nextCursor, err := s.store.GetEvents(ctx, filter)
+	result, err := fill(ctx, s, key, func(ctx context.Context) (eventPage, error) {
+		events, nextCursor, err := s.store.GetEvents(ctx, filter)
+		if err != nil {
+			return eventPage{}, err
+		}
+
+		// Cache for short time
+		result := eventPage{Events: events, Cursor: nextCursor}
+		s.cache.Set(ctx, key, result, s.listTTL(ctx, filter.ChainID, 10*time.Second))
+		return result, nil
+	})
 	if err != nil {
 		return nil, "", err
 	}
 
-	// Cache for short time
-	result := struct {
-		Events []*types.Event
-		Cursor string
-	}{Events: events, Cursor: nextCursor}
-
-	s.cache.Set(ctx, key, result, 10*time.Second)
-
-	return events, nextCursor, nil
+	return result.Events, result.Cursor, nil
 }
 
 // GetBlockTransactions returns transactions for a block with pagination
 func (s *Service) GetBlockTransactions(ctx context.Context, chainID types.ChainID, blockID, cursor string, limit int) ([]*types.Transaction, string, error) {
-	// Cache page results?
+	cursor, limit, cacheable := s.normalizePage(cursor, limit, 25, 100)
+	if !cacheable {
+		return s.store.GetTransactionsByBlock(ctx, chainID, blockID, cursor, limit)
+	}
+
 	// Key: blocktx:{chain}:{id}:{cursor}:{limit}
 	// TTL: 15s
 	key := fmt.Sprintf("blocktx:%s:%s:%s:%d", chainID, blockID, cursor, limit)
@@ -209,13 +315,20 @@ func (s *Service) GetBlockTransactions(ctx context.Context, chainID types.ChainI
 		return page.Txs, page.Cursor, nil
 	}
 
-	txs, next, err := s.store.GetTransactionsByBlock(ctx, chainID, blockID, cursor, limit)
+	page, err = fill(ctx, s, key, func(ctx context.Context) (CachedPage, error) {
+		txs, next, err := s.store.GetTransactionsByBlock(ctx, chainID, blockID, cursor, limit)
+		if err != nil {
+			return CachedPage{}, err
+		}
+
+		page := CachedPage{Txs: txs, Cursor: next}
+		s.cache.Set(ctx, key, page, s.listTTL(ctx, chainID, 15*time.Second))
+		return page, nil
+	})
 	if err != nil {
 		return nil, "", err
 	}
-
-	s.cache.Set(ctx, key, CachedPage{Txs: txs, Cursor: next}, 15*time.Second)
-	return txs, next, nil
+	return page.Txs, page.Cursor, nil
 }
 
 // GetLatestTransactions returns latest tx feed
@@ -228,13 +341,15 @@ func (s *Service) GetLatestTransactions(ctx context.Context, chainID types.Chain
 		return txs, nil
 	}
 
-	txs, err = s.store.GetLatestTransactions(ctx, chainID, limit)
-	if err != nil {
-		return nil, err
-	}
+	return fill(ctx, s, key, func(ctx context.Context) ([]*types.Transaction, error) {
+		txs, err := s.store.GetLatestTransactions(ctx, chainID, limit)
+		if err != nil {
+			return nil, err
+		}
 
-	s.cache.Set(ctx, key, txs, 5*time.Second)
-	return txs, nil
+		s.cache.Set(ctx, key, txs, s.listTTL(ctx, chainID, 5*time.Second))
+		return txs, nil
+	})
 }
 
 // GetNetworkStats returns simple stats
@@ -247,16 +362,56 @@ func (s *Service) GetNetworkStats(ctx context.Context, chainID types.ChainID) (*
 		return &stats, nil
 	}
 
-	st, err := s.store.GetNetworkStats(ctx, chainID)
-	if err != nil {
-		return nil, err
-	}
-	if st == nil {
-		return nil, nil
+	return fill(ctx, s, key, func(ctx context.Context) (*types.NetworkStats, error) {
+		st, err := s.store.GetNetworkStats(ctx, chainID)
+		if err != nil {
+			return nil, err
+		}
+		if st == nil {
+			return nil, nil
+		}
+
+		st.Settings, err = s.store.GetChainSettings(ctx, chainID)
+		if err != nil {
+			return nil, err
+		}
+
+		st.Indexing, err = s.GetIndexingStatus(ctx, chainID, st.AvgBlockTime)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.addFeeStats(ctx, st); err != nil {
+			return nil, err
+		}
+
+		s.cache.Set(ctx, key, st, 3*time.Second)
+		return st, nil
+	})
+}
+
+// GetChainSettings returns the indexer configuration published for a chain
+func (s *Service) GetChainSettings(ctx context.Context, chainID types.ChainID) (*types.ChainSettings, error) {
+	key := fmt.Sprintf("settings:%s", chainID)
+
+	var cs types.ChainSettings
+	found, err := s.cache.Get(ctx, key, &cs)
+	if err == nil && found {
+		return &cs, nil
 	}
 
-	s.cache.Set(ctx, key, st, 3*time.Second)
-	return st, nil
+	return fill(ctx, s, key, func(ctx context.Context) (*types.ChainSettings, error) {
+		settings, err := s.store.GetChainSettings(ctx, chainID)
+		if err != nil {
+			return nil, err
+		}
+		if settings == nil {
+			return nil, nil
+		}
+
+		s.cache.Set(ctx, key, settings, 30*time.Second) // Only changes on indexer restart
+		return settings, nil
+	})
 }
 
 // GetBlocksRange returns block summaries for charts
@@ -272,13 +427,33 @@ func (s *Service) GetBlocksRange(ctx context.Context, chainID types.ChainID, fro
 		return blocks, nil
 	}
 
-	blocks, err = s.store.GetBlocksRange(ctx, chainID, from, to)
-	if err != nil {
-		return nil, err
-	}
+	return fill(ctx, s, key, func(ctx context.Context) ([]*types.BlockSummary, error) {
+		blocks, err := s.store.GetBlocksRange(ctx, chainID, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		s.cache.Set(ctx, key, blocks, s.listTTL(ctx, chainID, 10*time.Second)) // Broad TTL for simplicity
+		return blocks, nil
+	})
+}
+
+// GetBlocksDesc returns block summaries newest first, paging backward from the tip
+func (s *Service) GetBlocksDesc(ctx context.Context, chainID types.ChainID, cursor string, limit int) ([]*types.BlockSummary, string, error) {
+	return s.store.GetBlocksDesc(ctx, chainID, cursor, limit)
+}
 
-	s.cache.Set(ctx, key, blocks, 10*time.Second) // Broad TTL for simplicity
-	return blocks, nil
+// GetBlocksByMiner returns blocks produced by a miner/proposer address, newest first
+func (s *Service) GetBlocksByMiner(ctx context.Context, chainID types.ChainID, miner, cursor string, limit int) ([]*types.Block, string, error) {
+	return s.store.GetBlocksByMiner(ctx, chainID, miner, cursor, limit)
+}
+
+// MaxTxSenderRange caps the block span accepted by GetEventsByTxSender
+const MaxTxSenderRange = 10000
+
+// GetEventsByTxSender returns events emitted by transactions sent from sender, newest first
+func (s *Service) GetEventsByTxSender(ctx context.Context, chainID types.ChainID, sender string, fromHeight, toHeight uint64, cursor string, limit int) ([]*types.Event, string, error) {
+	return s.store.GetEventsByTxSender(ctx, chainID, sender, fromHeight, toHeight, cursor, limit)
 }
 
 func strPtr(u *uint64) string {
@@ -288,11 +463,12 @@ func strPtr(u *uint64) string {
 	return fmt.Sprintf("%d", *u)
 }
 
-// GetAddressBalance returns the balance for an address
-func (s *Service) GetAddressBalance(ctx context.Context, chainID types.ChainID, address string) (string, error) {
+// GetAddressBalance returns the balance for an address, optionally counting only
+// transactions with at least minConfirmations confirmations, or only finalized ones
+func (s *Service) GetAddressBalance(ctx context.Context, chainID types.ChainID, address string, minConfirmations int, finalizedOnly bool) (string, error) {
 	// Cache balance?
 	// It changes frequently. Short TTL.
-	key := fmt.Sprintf("balance:%s:%s", chainID, address)
+	key := fmt.Sprintf("balance:%s:%s:%d:%t", chainID, types.NormalizeAddress(chainID, address), minConfirmations, finalizedOnly)
 
 	var balance string
 	found, err := s.cache.Get(ctx, key, &balance)
@@ -300,12 +476,18 @@ func (s *Service) GetAddressBalance(ctx context.Context, chainID types.ChainID,
 		return balance, nil
 	}
 
-	balance, err = s.store.GetAddressBalance(ctx, chainID, address)
+	balance, err = fill(ctx, s, key, func(ctx context.Context) (string, error) {
+		balance, err := s.store.GetAddressBalance(ctx, chainID, address, minConfirmations, finalizedOnly)
+		if err != nil {
+			return "", err
+		}
+
+		s.cache.Set(ctx, key, balance, 5*time.Second)
+		return balance, nil
+	})
 	if err != nil {
 		return "0", err
 	}
-
-	s.cache.Set(ctx, key, balance, 5*time.Second)
 	return balance, nil
 }
 
@@ -313,7 +495,7 @@ func (s *Service) GetAddressBalance(ctx context.Context, chainID types.ChainID,
 func (s *Service) GetContract(ctx context.Context, chainID types.ChainID, address string) (*types.Contract, error) {
 	// Cache Key: contract:chain:address
 	// immutable data, long TTL
-	key := fmt.Sprintf("contract:%s:%s", chainID, address)
+	key := fmt.Sprintf("contract:%s:%s", chainID, types.NormalizeAddress(chainID, address))
 	var contract types.Contract
 	found, err := s.cache.Get(ctx, key, &contract)
 	if err != nil {
@@ -323,20 +505,26 @@ func (s *Service) GetContract(ctx context.Context, chainID types.ChainID, addres
 		return &contract, nil
 	}
 
-	c, err := s.store.GetContract(ctx, chainID, address)
-	if err != nil {
-		return nil, err
-	}
-	if c != nil {
-		s.cache.Set(ctx, key, c, 24*time.Hour)
-	}
+	return fill(ctx, s, key, func(ctx context.Context) (*types.Contract, error) {
+		c, err := s.store.GetContract(ctx, chainID, address)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			s.cache.Set(ctx, key, c, 24*time.Hour)
+		}
 
-	return c, nil
+		return c, nil
+	})
 }
 
-// GetAddressStats returns analytics for an address
-func (s *Service) GetAddressStats(ctx context.Context, chainID types.ChainID, address string) (*types.AddressStats, error) {
-	cacheKey := fmt.Sprintf("stats:%s:%s", chainID, address)
+// GetAddressStats returns analytics for an address. finalizedOnly aggregates the
+// finalized transactions on demand instead of reading the precomputed address_stats.
+func (s *Service) GetAddressStats(ctx context.Context, chainID types.ChainID, address string, finalizedOnly bool) (*types.AddressStats, error) {
+	cacheKey := fmt.Sprintf("stats:%s:%s", chainID, types.NormalizeAddress(chainID, address))
+	if finalizedOnly {
+		cacheKey += ":finalized"
+	}
 
 	// Check cache
 	var stats types.AddressStats
@@ -345,102 +533,207 @@ func (s *Service) GetAddressStats(ctx context.Context, chainID types.ChainID, ad
 		return &stats, nil
 	}
 
-	st, err := s.store.GetAddressStats(ctx, chainID, address)
-	if err != nil {
-		return nil, fmt.Errorf("getting address stats: %w", err)
+	getStats := s.store.GetAddressStats
+	if finalizedOnly {
+		getStats = s.store.GetFinalizedAddressStats
 	}
+	return fill(ctx, s, cacheKey, func(ctx context.Context) (*types.AddressStats, error) {
+		st, err := getStats(ctx, chainID, address)
+		if err != nil {
+			return nil, fmt.Errorf("getting address stats: %w", err)
+		}
 
-	if st != nil {
-		// Cache for 30 seconds (dynamic data)
-		s.cache.Set(ctx, cacheKey, st, 30*time.Second)
-	}
+		if st != nil {
+			// Cache for 30 seconds (dynamic data)
+			s.cache.Set(ctx, cacheKey, st, 30*time.Second)
+		}
 
-	return st, nil
+		return st, nil
+	})
 }
 
-func (s *Service) GetTokenBalances(ctx context.Context, chainID types.ChainID, address string) ([]types.TokenBalance, error) {
-	return s.store.GetTokenBalances(ctx, chainID, address)
-}
+// MaxLatestEvents caps the limit accepted by GetLatestContractEvents
+const MaxLatestEvents = 100
+
+// GetLatestContractEvents returns the newest events for a contract, cached briefly
+func (s *Service) GetLatestContractEvents(ctx context.Context, chainID types.ChainID, contractAddr string, limit int) ([]*types.Event, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > MaxLatestEvents {
+		limit = MaxLatestEvents
+	}
 
-func (s *Service) GetTokenTransfers(ctx context.Context, chainID types.ChainID, address string, limit, offset int) ([]types.TokenTransfer, error) {
-	return s.store.GetTokenTransfers(ctx, chainID, address, limit, offset)
+	key := fmt.Sprintf("events:latest:%s:%s:%d", chainID, types.NormalizeAddress(chainID, contractAddr), limit)
+	var events []*types.Event
+	found, err := s.cache.Get(ctx, key, &events)
+	if err == nil && found {
+		return events, nil
+	}
+
+	return fill(ctx, s, key, func(ctx context.Context) ([]*types.Event, error) {
+		events, err := s.store.GetLatestContractEvents(ctx, chainID, contractAddr, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		s.cache.Set(ctx, key, events, s.listTTL(ctx, chainID, 3*time.Second))
+		return events, nil
+	})
 }
 
-// GetPendingTransactions returns simplified pending txs from mempool
-func (s *Service) GetPendingTransactions(ctx context.Context, chainID types.ChainID) ([]*types.Transaction, error) {
-	key := fmt.Sprintf("mempool:%s:latest", chainID)
+// StreamEvents streams events matching filter to fn without caching (bulk export)
+func (s *Service) StreamEvents(ctx context.Context, filter query.EventFilter, fn func(*types.Event) error) error {
+	return s.store.StreamEvents(ctx, filter, fn)
+}
 
-	// internal struct matching MempoolPoller storage
-	type RPCTransaction struct {
-		Hash  string `json:"hash"`
-		From  string `json:"from"`
-		To    string `json:"to"`
-		Value string `json:"value"`
+// GetTokenBalances returns token balances with human-readable amounts
+func (s *Service) GetTokenBalances(ctx context.Context, chainID types.ChainID, address string) ([]TokenBalanceView, error) {
+	balances, err := s.store.GetTokenBalances(ctx, chainID, address)
+	if err != nil {
+		return nil, err
 	}
 
-	var rpcTxs []RPCTransaction
-	found, err := s.cache.Get(ctx, key, &rpcTxs)
-	if err == nil && found {
-		// Convert to types.Transaction
-		var txs []*types.Transaction
-		for _, rt := range rpcTxs {
-			txs = append(txs, &types.Transaction{
-				ChainID:  chainID,
-				TxHash:   rt.Hash,
-				FromAddr: rt.From,
-				ToAddr:   rt.To,
-				Value:    rt.Value,
-				Status:   types.StatusPending,
-			})
-		}
-		return txs, nil
+	addrs := make([]string, 0, len(balances))
+	for _, b := range balances {
+		addrs = append(addrs, b.TokenAddress)
+	}
+	decimals, err := s.store.GetTokenDecimals(ctx, chainID, addrs)
+	if err != nil {
+		return nil, err
 	}
 
-	return []*types.Transaction{}, nil
+	views := make([]TokenBalanceView, 0, len(balances))
+	for _, b := range balances {
+		v := TokenBalanceView{TokenBalance: b, AmountRaw: b.Balance}
+		v.Amount, v.Decimals, v.DecimalsUnknown = formatAmountFields(b.Balance, b.TokenAddress, decimals)
+		views = append(views, v)
+	}
+	return views, nil
 }
 
-// Search attempts to find any entity matching the query
-func (s *Service) Search(ctx context.Context, q string) (*SearchResult, error) {
-	// 1. Try Block Height (integer)
-	// 2. Try Hash (Block or Tx) - 64 hex chars (66 with 0x)
-	// 3. Try Address - 40 hex chars (42 with 0x) for ETH, or BTC format
-	// 4. Try Token Name/Symbol (Fuzzy)
+// GetTokenTransfers returns a page of token transfers with human-readable amounts
+// and the cursor of the next page ("" after the last one)
+func (s *Service) GetTokenTransfers(ctx context.Context, chainID types.ChainID, address, cursor string, limit int) ([]TokenTransferView, string, error) {
+	transfers, nextCursor, err := s.store.GetTokenTransfers(ctx, chainID, address, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
 
-	// Since we support multiple chains, we might need to search both or assume chain is needed?
-	// The prompt implies a Global Search. Frontend passes "q".
-	// We'll search primarily in ETH/BTC context.
+	addrs := make([]string, 0, len(transfers))
+	for _, t := range transfers {
+		addrs = append(addrs, t.TokenAddress)
+	}
+	decimals, err := s.store.GetTokenDecimals(ctx, chainID, addrs)
+	if err != nil {
+		return nil, "", err
+	}
 
-	// Helper to check regex/format? simpler: try fetch.
+	views := make([]TokenTransferView, 0, len(transfers))
+	for _, t := range transfers {
+		v := TokenTransferView{TokenTransfer: t, AmountRaw: t.Amount}
+		v.Amount, v.Decimals, v.DecimalsUnknown = formatAmountFields(t.Amount, t.TokenAddress, decimals)
+		views = append(views, v)
+	}
+	return views, nextCursor, nil
+}
 
-	// Try Token Search first if it looks like a name (not hex)
-	// But "0x..." is hex.
+// MaxTokenHolders caps the limit accepted by GetTokenHolders
+const MaxTokenHolders = 100
 
-	if len(q) < 3 {
-		return nil, nil
+// GetTokenHolders returns a page of a token's largest holders, cached briefly
+func (s *Service) GetTokenHolders(ctx context.Context, chainID types.ChainID, tokenAddress string, limit, offset int) ([]types.TokenHolder, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > MaxTokenHolders {
+		limit = MaxTokenHolders
+	}
+	if offset < 0 {
+		offset = 0
 	}
 
-	// Token Search logic:
-	// If it doesn't look like a hash/address, it's a keyword.
-	// Hashes usually start with 0x or are long hex.
-	isHex := (len(q) >= 2 && q[:2] == "0x") || (len(q) == 64)
+	key := fmt.Sprintf("tokenholders:%s:%s:%d:%d", chainID, types.NormalizeAddress(chainID, tokenAddress), limit, offset)
+	var holders []types.TokenHolder
+	found, err := s.cache.Get(ctx, key, &holders)
+	if err == nil && found {
+		return holders, nil
+	}
 
-	if !isHex {
-		tokens, err := s.store.SearchTokens(ctx, q)
-		if err == nil && len(tokens) > 0 {
-			return &SearchResult{Type: "token_list", Data: tokens}, nil
+	return fill(ctx, s, key, func(ctx context.Context) ([]types.TokenHolder, error) {
+		holders, err := s.store.GetTokenHolders(ctx, chainID, tokenAddress, limit, offset)
+		if err != nil {
+			return nil, fmt.Errorf("getting token holders: %w", err)
 		}
-		// If no tokens found, maybe it's a block height?
+
+		s.cache.Set(ctx, key, holders, s.listTTL(ctx, chainID, 30*time.Second))
+		return holders, nil
+	})
+}
+
+// GetAddressTokenSummary returns an address's per-token transfer totals, cached briefly
+func (s *Service) GetAddressTokenSummary(ctx context.Context, chainID types.ChainID, address string) (*types.AddressTokenSummary, error) {
+	cacheKey := fmt.Sprintf("tokensummary:%s:%s", chainID, types.NormalizeAddress(chainID, address))
+
+	var summary types.AddressTokenSummary
+	found, err := s.cache.Get(ctx, cacheKey, &summary)
+	if err == nil && found {
+		return &summary, nil
 	}
 
-	// Try generic search... for now, strict "Token Search" feature requested.
-	// The frontend handles smart routing for Blocks/Txs/Addresses.
-	// Backend Search is primarily for what frontend CANNOT do deterministically (Fuzzy).
+	return fill(ctx, s, cacheKey, func(ctx context.Context) (*types.AddressTokenSummary, error) {
+		sum, err := s.store.GetAddressTokenSummary(ctx, chainID, address)
+		if err != nil {
+			return nil, fmt.Errorf("getting token summary: %w", err)
+		}
+
+		// Cache for 30 seconds, like address stats
+		s.cache.Set(ctx, cacheKey, sum, 30*time.Second)
+		return sum, nil
+	})
+}
+
+// PendingTx is a mempool transaction with the time the indexer first saw it
+type PendingTx struct {
+	*types.Transaction
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// GetPendingTransactions returns the pending txs the chain's mempool poller last
+// listed. When nothing is cached (mempool polling disabled, or the list expired
+// because the poller stopped) it returns an empty list rather than an error.
+func (s *Service) GetPendingTransactions(ctx context.Context, chainID types.ChainID) ([]PendingTx, error) {
+	key := cache.MempoolKey(string(chainID))
 
-	// So we return Token results.
-	tokens, err := s.store.SearchTokens(ctx, q)
-	if err == nil && len(tokens) > 0 {
-		return &SearchResult{Type: "token_list", Data: tokens}, nil
+	// internal struct matching MempoolPoller storage
+	type RPCTransaction struct {
+		Hash      string    `json:"hash"`
+		From      string    `json:"from"`
+		To        string    `json:"to"`
+		Value     string    `json:"value"`
+		FirstSeen time.Time `json:"first_seen"`
+	}
+
+	var rpcTxs []RPCTransaction
+	found, err := s.cache.Get(ctx, key, &rpcTxs)
+	if err == nil && found {
+		// Convert to types.Transaction
+		txs := make([]PendingTx, 0, len(rpcTxs))
+		for _, rt := range rpcTxs {
+			txs = append(txs, PendingTx{
+				Transaction: &types.Transaction{
+					ChainID:  chainID,
+					TxHash:   rt.Hash,
+					FromAddr: rt.From,
+					ToAddr:   rt.To,
+					Value:    rt.Value,
+					Status:   types.StatusPending,
+				},
+				FirstSeen: rt.FirstSeen,
+			})
+		}
+		return txs, nil
 	}
 
-	return nil, nil
+	return []PendingTx{}, nil
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -15,6 +17,9 @@ type Config struct {
 	Chains   map[string]ChainConfig `yaml:"chains"`
 	Server   ServerConfig           `yaml:"server"`
 	Logging  LoggingConfig          `yaml:"logging"`
+	Backfill BackfillConfig         `yaml:"backfill"`
+	Publish  PublishConfig          `yaml:"publish"`
+	Webhooks WebhookConfig          `yaml:"webhooks"`
 }
 
 // DatabaseConfig holds PostgreSQL connection settings
@@ -40,6 +45,12 @@ func (d DatabaseConfig) DSN() string {
 	)
 }
 
+// DSNWithSchema returns a connection string whose search_path resolves unqualified
+// table names to schema first (shared objects such as extensions stay in public)
+func (d DatabaseConfig) DSNWithSchema(schema string) string {
+	return fmt.Sprintf("%s search_path=%s,public", d.DSN(), schema)
+}
+
 // RedisConfig holds Redis connection settings
 type RedisConfig struct {
 	Addr          string        `yaml:"addr"`
@@ -52,31 +63,148 @@ type RedisConfig struct {
 
 // ChainConfig holds configuration for a single blockchain
 type ChainConfig struct {
-	Enabled           bool          `yaml:"enabled"`
-	RPCURL            string        `yaml:"rpc_url"`
-	PollInterval      time.Duration `yaml:"poll_interval"`
-	BatchSize         int           `yaml:"batch_size"`
-	ConfirmationDepth int           `yaml:"confirmation_depth"`
-	StartHeight       uint64        `yaml:"start_height"`
-	MaxReorgDepth     int           `yaml:"max_reorg_depth"` // P1 alert if exceeded
-	EnableMempool     bool          `yaml:"enable_mempool"`
+	Enabled           bool            `yaml:"enabled"`
+	RPCURL            string          `yaml:"rpc_url"`
+	RPCURLs           []string        `yaml:"rpc_urls"`              // Fallback endpoints, failed over to in order when the active one errors, 5xxs or rate-limits
+	RPCCooldown       time.Duration   `yaml:"rpc_failover_cooldown"` // How long a failed endpoint is skipped while others are available (0 = 30s)
+	PollInterval      time.Duration   `yaml:"poll_interval"`
+	MinPollInterval   time.Duration   `yaml:"min_poll_interval"`  // Floor for dynamic intervals; also the catch-up interval while behind (0 = none)
+	MaxPollInterval   time.Duration   `yaml:"max_poll_interval"`  // Ceiling for dynamic intervals; also enables error backoff (0 = none)
+	IdlePollInterval  time.Duration   `yaml:"idle_poll_interval"` // Cap for backing off after consecutive polls that find no new block (0 = off)
+	BatchSize         int             `yaml:"batch_size"`
+	FetchConcurrency  int             `yaml:"fetch_concurrency"` // Blocks of a batch fetched at once, returned in height order (0 = 4)
+	ConfirmationDepth int             `yaml:"confirmation_depth"`
+	StartHeight       uint64          `yaml:"start_height"`
+	MaxReorgDepth     int             `yaml:"max_reorg_depth"` // P1 alert if exceeded
+	EnableMempool     bool            `yaml:"enable_mempool"`
+	MempoolDedupe     time.Duration   `yaml:"mempool_dedupe_window"`   // Keep dropped pending txs listed this long (negative = replace each poll)
+	Schema            string          `yaml:"schema"`                  // Dedicated Postgres schema (empty = shared public schema)
+	StrictWrite       bool            `yaml:"strict_write_validation"` // Reject writes whose heights/parent hashes don't chain from the checkpoint
+	FinalityGuard     bool            `yaml:"rollback_finality_guard"` // Refuse reorg rollbacks below the finalized height (halts like an over-deep reorg)
+	RecordReorgs      bool            `yaml:"record_reorgs"`           // Write each rolled-back reorg to the reorgs table (GET /admin/{chain}/reorgs)
+	WatchAddresses    []string        `yaml:"watch_addresses"`         // Only write transactions/token transfers touching these addresses (empty = all); blocks are always written
+	CaughtUpWebhook   string          `yaml:"caught_up_webhook"`       // POSTed once per process when the chain first reaches the tip
+	PartitionSize     uint64          `yaml:"partition_size"`          // Blocks per transactions/events partition (0 = unpartitioned; requires schema)
+	VerifyWrites      bool            `yaml:"verify_writes"`           // Recompute write aggregates in SQL and log/count mismatches (never fails writes)
+	StoreLogCount     bool            `yaml:"store_log_count"`         // Populate transactions.log_count from the batch's indexed events (backfill with -recompute-log-counts)
+	MaxResponseSize   int64           `yaml:"max_rpc_response_bytes"`  // Largest buffered RPC response before the call fails (0 = 256MB)
+	Retention         RetentionConfig `yaml:"retention"`               // Scheduled pruning of orphaned_blocks and finalized raw_data
 
 	// ETH-specific
-	LogBatchSize    int              `yaml:"log_batch_size"`    // Max blocks per eth_getLogs call
-	UseFinalizedTag bool             `yaml:"use_finalized_tag"` // Use finalized block tag
-	Contracts       []ContractConfig `yaml:"contracts,omitempty"`
+	LogBatchSize      int               `yaml:"log_batch_size"`          // Max blocks per eth_getLogs call
+	UseFinalizedTag   bool              `yaml:"use_finalized_tag"`       // Finalize up to the node's "finalized" block instead of confirmation_depth
+	WSURL             string            `yaml:"ws_url"`                  // ws:// or wss:// endpoint; polls at the tip are driven by a newHeads subscription
+	MaxDecodeDepth    int               `yaml:"max_decode_depth"`        // Max tuple/array nesting in decoded events
+	MaxDecodeElements int               `yaml:"max_decode_elements"`     // Max values formatted per decoded event
+	MaxDecodedBytes   int               `yaml:"max_decoded_event_bytes"` // Larger decoded params JSON is stored as decode_failed (0 = 1MB)
+	MissingABI        string            `yaml:"missing_abi"`             // "store_raw" (default) or "skip" for contracts without a usable ABI
+	MaxLogsPerPoll    int               `yaml:"max_logs_per_poll"`       // Events held per poll before the batch is cut short (0 = default)
+	MaxLogAddresses   int               `yaml:"max_log_addresses"`       // Contract addresses per eth_getLogs filter; more are split across calls (0 = all in one)
+	FilterLogTopics   bool              `yaml:"filter_log_topics"`       // Request only logs of events in each contract's ABI (plus token transfers)
+	StoreUncles       bool              `yaml:"store_uncles"`            // Record pre-Merge uncle references in the uncles table
+	EventTxStatus     bool              `yaml:"event_tx_status"`         // Fetch receipts to record each event's tx success/revert status
+	FetchReceipts     bool              `yaml:"fetch_receipts"`          // Fetch every block's receipts for tx gas used, fee and success/revert status
+	DedupeRPC         bool              `yaml:"dedupe_rpc"`              // Share one request between identical concurrent RPC calls
+	TokenDecimals     map[string]int    `yaml:"token_decimals"`          // Decimals by token address, used instead of calling decimals()
+	DefaultDecimals   int               `yaml:"token_decimals_default"`  // Decimals stored when decimals() reverts or returns no value
+	Contracts         []ContractConfig  `yaml:"contracts,omitempty"`
+	ABIExplorer       ABIExplorerConfig `yaml:"abi_explorer"` // Fetch ABIs for contracts without a local one
+
+	// BTC-specific
+	IndexGenesis     bool   `yaml:"index_genesis"`     // With start_height 0, also index the genesis block (height 0)
+	SkipTransactions bool   `yaml:"skip_transactions"` // Fetch blocks at getblock verbosity 1: smaller payloads, but no transactions, miner or tx_count
+	MaxInputLookups  int    `yaml:"max_input_lookups"` // Previous txs fetched per block to resolve input addresses and fees (0 = disabled; needs -txindex)
+	RPCUser          string `yaml:"rpc_user"`          // Basic Auth user for the node's RPC (rpcuser)
+	RPCPassword      string `yaml:"rpc_password"`      // Basic Auth password for the node's RPC (rpcpassword)
+	RPCCookiePath    string `yaml:"rpc_cookie_path"`   // bitcoind .cookie file, re-read per request; instead of rpc_user/rpc_password
+}
+
+// RetentionConfig bounds the growth of orphaned_blocks and the blocks and
+// transactions raw_data columns. Pruning runs in the background while either
+// orphaned_blocks_max_age or a raw_data rule other than all is set.
+type RetentionConfig struct {
+	OrphanedBlocksMaxAge time.Duration `yaml:"orphaned_blocks_max_age"` // Delete archived orphans this old once their height is finalized (0 = keep)
+	RawData              string        `yaml:"raw_data"`                // Finalized blocks that keep raw_data: all (default), pending (none) or events
+	RawDataBlocks        uint64        `yaml:"raw_data_blocks"`         // Apply the raw_data rule only this far below the finalized height (0 = to every finalized block)
+	Interval             time.Duration `yaml:"interval"`                // Time between prunes (0 = 1h)
+	BatchSize            int           `yaml:"batch_size"`              // Rows deleted, or block heights cleared, per statement (0 = 1000)
 }
 
-// ContractConfig defines a contract to monitor for events
+// PrunesRawData reports whether finalized raw_data is cleared
+func (r RetentionConfig) PrunesRawData() bool {
+	return r.RawData == "pending" || r.RawData == "events"
+}
+
+// Enabled reports whether anything is pruned
+func (r RetentionConfig) Enabled() bool {
+	return r.OrphanedBlocksMaxAge > 0 || r.PrunesRawData()
+}
+
+// ContractConfig defines a contract to monitor for events.
+// ABIPath may be empty; see ChainConfig.MissingABI for how such contracts are handled.
 type ContractConfig struct {
 	Address string `yaml:"address"`
 	ABIPath string `yaml:"abi_path"`
+	// ImplementationABIPaths are tried in order for logs abi_path has no event for,
+	// e.g. the current and past implementations of an EIP-1967 proxy
+	ImplementationABIPaths []string `yaml:"implementation_abi_paths"`
+}
+
+// ABIExplorerConfig enables fetching verified ABIs from an Etherscan-compatible API
+// for monitored contracts without a usable abi_path. Fetched ABIs are cached in
+// CacheDir; if a fetch fails the contract falls back to missing_abi handling.
+type ABIExplorerConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	BaseURL  string        `yaml:"base_url"`  // e.g. https://api.etherscan.io/api
+	APIKey   string        `yaml:"api_key"`   // Usually ${ETHERSCAN_API_KEY}
+	CacheDir string        `yaml:"cache_dir"` // Default "abi_cache"
+	Timeout  time.Duration `yaml:"timeout"`   // Per-request timeout (default 10s)
+}
+
+// BackfillConfig controls how chains share resources during initial catch-up.
+// With MaxConcurrent unset all chains start in parallel.
+type BackfillConfig struct {
+	MaxConcurrent int      `yaml:"max_concurrent"`  // Max chains catching up at once (0 = unlimited)
+	Order         []string `yaml:"order,omitempty"` // Chain start order when limited, e.g. [eth, btc]
+}
+
+// PublishConfig controls pushing committed data to a message broker.
+// A relay per chain publishes from the DB and tracks delivery in publish_checkpoints,
+// so broker outages delay delivery without blocking indexing (at-least-once).
+type PublishConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Driver       string        `yaml:"driver"`        // Only "kafka" is supported
+	Brokers      []string      `yaml:"brokers"`       // host:port list
+	TopicPrefix  string        `yaml:"topic_prefix"`  // Topics are <prefix>.blocks, <prefix>.transactions, <prefix>.events
+	MaxBlocks    int           `yaml:"max_blocks"`    // Blocks per published batch
+	Interval     time.Duration `yaml:"interval"`      // Relay idle/retry delay
+	BatchSize    int           `yaml:"batch_size"`    // Messages per broker request
+	BatchTimeout time.Duration `yaml:"batch_timeout"` // Max wait to fill a broker request
+	Timeout      time.Duration `yaml:"timeout"`       // Per-batch delivery timeout
+}
+
+// WebhookConfig controls POSTing new address activity to subscribed callback URLs.
+// Subscriptions are managed through /admin/{chain}/webhooks on the health port,
+// which requires server.admin_token.
+type WebhookConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Workers      int           `yaml:"workers"`       // Deliveries sent concurrently
+	QueueSize    int           `yaml:"queue_size"`    // Deliveries waiting for a worker before new ones are dropped
+	Timeout      time.Duration `yaml:"timeout"`       // Per-attempt request timeout
+	MaxAttempts  int           `yaml:"max_attempts"`  // Attempts per delivery, including the first
+	RetryBackoff time.Duration `yaml:"retry_backoff"` // Wait before the first retry, doubling after each (capped at 1m)
 }
 
 // ServerConfig holds HTTP server settings
 type ServerConfig struct {
-	HealthPort  int `yaml:"health_port"`
-	MetricsPort int `yaml:"metrics_port"`
+	HealthPort  int    `yaml:"health_port"`
+	MetricsPort int    `yaml:"metrics_port"`
+	AdminToken  string `yaml:"admin_token"`          // Required X-Admin-Token for /admin endpoints (empty = /admin disabled)
+	MaxAdminOps int    `yaml:"max_admin_operations"` // Heavy admin operations (e.g. redecode) allowed at once (0 = 1)
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`  // How long to wait for chains to stop before exiting anyway (default 30s)
+	HealthHaltAfter time.Duration `yaml:"health_halt_after"` // No successful poll for this long marks a chain halted in /healthz and /readyz (default 5m)
+	HealthMaxLag    time.Duration `yaml:"health_max_lag"`    // Last indexed block older than this marks a chain lagging (0 = never)
 }
 
 // LoggingConfig holds logging settings
@@ -109,6 +237,9 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// schemaNamePattern restricts schema names to plain lowercase identifiers
+var schemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
 func (c *Config) validate() error {
 	if c.Database.Host == "" {
 		return fmt.Errorf("database.host is required")
@@ -117,10 +248,102 @@ func (c *Config) validate() error {
 		return fmt.Errorf("database.name is required")
 	}
 
+	if c.Publish.Enabled {
+		if c.Publish.Driver != "" && c.Publish.Driver != "kafka" {
+			return fmt.Errorf("publish.driver must be kafka")
+		}
+		if len(c.Publish.Brokers) == 0 {
+			return fmt.Errorf("publish.brokers is required when publish is enabled")
+		}
+	}
+
+	if c.Webhooks.Workers < 0 || c.Webhooks.QueueSize < 0 || c.Webhooks.MaxAttempts < 0 {
+		return fmt.Errorf("webhooks.workers, queue_size and max_attempts must not be negative")
+	}
+	if c.Webhooks.Timeout < 0 || c.Webhooks.RetryBackoff < 0 {
+		return fmt.Errorf("webhooks.timeout and retry_backoff must not be negative")
+	}
+	if c.Webhooks.Enabled && c.Server.AdminToken == "" {
+		return fmt.Errorf("server.admin_token is required when webhooks are enabled")
+	}
+
+	if c.Server.ShutdownTimeout < 0 {
+		return fmt.Errorf("server.shutdown_timeout must not be negative")
+	}
+	if c.Server.HealthHaltAfter < 0 || c.Server.HealthMaxLag < 0 {
+		return fmt.Errorf("server health thresholds must not be negative")
+	}
+
 	for name, chain := range c.Chains {
 		if chain.Enabled && chain.RPCURL == "" {
 			return fmt.Errorf("chains.%s.rpc_url is required when enabled", name)
 		}
+		for _, u := range chain.RPCURLs {
+			if u == "" {
+				return fmt.Errorf("chains.%s.rpc_urls must not contain empty entries", name)
+			}
+		}
+		if chain.WSURL != "" && !strings.HasPrefix(chain.WSURL, "ws://") && !strings.HasPrefix(chain.WSURL, "wss://") {
+			return fmt.Errorf("chains.%s.ws_url must use ws:// or wss://", name)
+		}
+		if chain.RPCCooldown < 0 {
+			return fmt.Errorf("chains.%s.rpc_failover_cooldown must not be negative", name)
+		}
+		if chain.MissingABI != "" && chain.MissingABI != "store_raw" && chain.MissingABI != "skip" {
+			return fmt.Errorf("chains.%s.missing_abi must be store_raw or skip", name)
+		}
+		if chain.Schema != "" && !schemaNamePattern.MatchString(chain.Schema) {
+			return fmt.Errorf("chains.%s.schema must match %s", name, schemaNamePattern)
+		}
+		if chain.ABIExplorer.Enabled && chain.ABIExplorer.BaseURL == "" {
+			return fmt.Errorf("chains.%s.abi_explorer.base_url is required when enabled", name)
+		}
+		if chain.PartitionSize > 0 && chain.Schema == "" {
+			return fmt.Errorf("chains.%s.partition_size requires a dedicated schema", name)
+		}
+		switch chain.Retention.RawData {
+		case "", "all", "pending", "events":
+		default:
+			return fmt.Errorf("chains.%s.retention.raw_data must be all, pending or events", name)
+		}
+		if chain.Retention.RawDataBlocks > 0 && !chain.Retention.PrunesRawData() {
+			return fmt.Errorf("chains.%s.retention.raw_data_blocks requires raw_data pending or events", name)
+		}
+		if chain.Retention.OrphanedBlocksMaxAge < 0 || chain.Retention.Interval < 0 || chain.Retention.BatchSize < 0 {
+			return fmt.Errorf("chains.%s.retention values must not be negative", name)
+		}
+		if chain.ConfirmationDepth < 0 || chain.MaxReorgDepth < 0 {
+			return fmt.Errorf("chains.%s confirmation_depth and max_reorg_depth must not be negative", name)
+		}
+		if chain.MaxResponseSize < 0 {
+			return fmt.Errorf("chains.%s.max_rpc_response_bytes must not be negative", name)
+		}
+		if chain.MinPollInterval < 0 || chain.MaxPollInterval < 0 || chain.IdlePollInterval < 0 {
+			return fmt.Errorf("chains.%s poll interval bounds must not be negative", name)
+		}
+		if chain.MinPollInterval > 0 && chain.MaxPollInterval > 0 && chain.MinPollInterval > chain.MaxPollInterval {
+			return fmt.Errorf("chains.%s.min_poll_interval must not exceed max_poll_interval", name)
+		}
+		if chain.PollInterval > 0 && chain.PollInterval < chain.MinPollInterval {
+			return fmt.Errorf("chains.%s.poll_interval must be at least min_poll_interval", name)
+		}
+		if chain.PollInterval > 0 && chain.MaxPollInterval > 0 && chain.PollInterval > chain.MaxPollInterval {
+			return fmt.Errorf("chains.%s.poll_interval must not exceed max_poll_interval", name)
+		}
+		if chain.DefaultDecimals < 0 || chain.DefaultDecimals > 255 {
+			return fmt.Errorf("chains.%s.token_decimals_default must be between 0 and 255", name)
+		}
+		for addr, d := range chain.TokenDecimals {
+			if d < 0 || d > 255 {
+				return fmt.Errorf("chains.%s.token_decimals[%s] must be between 0 and 255", name, addr)
+			}
+		}
+		if chain.RPCCookiePath != "" && chain.RPCUser != "" {
+			return fmt.Errorf("chains.%s: set either rpc_cookie_path or rpc_user, not both", name)
+		}
+		if chain.RPCPassword != "" && chain.RPCUser == "" {
+			return fmt.Errorf("chains.%s.rpc_password requires rpc_user", name)
+		}
 	}
 
 	return nil
@@ -140,6 +363,47 @@ func (c *Config) setDefaults() {
 	if c.Server.MetricsPort == 0 {
 		c.Server.MetricsPort = 9090
 	}
+	if c.Server.ShutdownTimeout == 0 {
+		c.Server.ShutdownTimeout = 30 * time.Second
+	}
+
+	if c.Publish.Driver == "" {
+		c.Publish.Driver = "kafka"
+	}
+	if c.Publish.TopicPrefix == "" {
+		c.Publish.TopicPrefix = "indexer"
+	}
+	if c.Publish.MaxBlocks == 0 {
+		c.Publish.MaxBlocks = 100
+	}
+	if c.Publish.Interval == 0 {
+		c.Publish.Interval = time.Second
+	}
+	if c.Publish.BatchSize == 0 {
+		c.Publish.BatchSize = 100
+	}
+	if c.Publish.BatchTimeout == 0 {
+		c.Publish.BatchTimeout = time.Second
+	}
+	if c.Publish.Timeout == 0 {
+		c.Publish.Timeout = 10 * time.Second
+	}
+
+	if c.Webhooks.Workers == 0 {
+		c.Webhooks.Workers = 4
+	}
+	if c.Webhooks.QueueSize == 0 {
+		c.Webhooks.QueueSize = 1000
+	}
+	if c.Webhooks.Timeout == 0 {
+		c.Webhooks.Timeout = 10 * time.Second
+	}
+	if c.Webhooks.MaxAttempts == 0 {
+		c.Webhooks.MaxAttempts = 5
+	}
+	if c.Webhooks.RetryBackoff == 0 {
+		c.Webhooks.RetryBackoff = time.Second
+	}
 
 	if c.Logging.Level == "" {
 		c.Logging.Level = "info"
@@ -156,6 +420,13 @@ func (c *Config) setDefaults() {
 				chain.PollInterval = 2 * time.Second
 			}
 		}
+		// Keep the default inside explicit bounds
+		if chain.PollInterval < chain.MinPollInterval {
+			chain.PollInterval = chain.MinPollInterval
+		}
+		if chain.MaxPollInterval > 0 && chain.PollInterval > chain.MaxPollInterval {
+			chain.PollInterval = chain.MaxPollInterval
+		}
 		if chain.BatchSize == 0 {
 			chain.BatchSize = 100
 		}
@@ -169,11 +440,23 @@ func (c *Config) setDefaults() {
 		if chain.MaxReorgDepth == 0 {
 			chain.MaxReorgDepth = 100 // Default max reorg depth before P1 alert
 		}
+		if chain.MempoolDedupe == 0 {
+			chain.MempoolDedupe = 30 * time.Second
+		}
 		// ETH-specific defaults
 		if name == "eth" {
 			if chain.LogBatchSize == 0 {
 				chain.LogBatchSize = 2000 // Default blocks per eth_getLogs
 			}
+			if chain.MissingABI == "" {
+				chain.MissingABI = "store_raw"
+			}
+			if chain.ABIExplorer.CacheDir == "" {
+				chain.ABIExplorer.CacheDir = "abi_cache"
+			}
+			if chain.ABIExplorer.Timeout == 0 {
+				chain.ABIExplorer.Timeout = 10 * time.Second
+			}
 			// UseFinalizedTag defaults to true for ETH
 			// (zero value is false, so we check explicitly if not set)
 		}
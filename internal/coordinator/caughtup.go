@@ -0,0 +1,85 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// LevelNotice is the log level of one-off lifecycle milestones such as a chain
+// catching up, between info and warn so they can be filtered on without noise
+const LevelNotice = slog.LevelInfo + 2
+
+// caughtUpWebhookTimeout bounds the single POST to caught_up_webhook
+const caughtUpWebhookTimeout = 10 * time.Second
+
+// CaughtUpEvent is the JSON body POSTed to caught_up_webhook
+type CaughtUpEvent struct {
+	Chain      types.ChainID `json:"chain"`
+	Height     uint64        `json:"height"`
+	CaughtUpAt time.Time     `json:"caught_up_at"`
+}
+
+// markCaughtUp records that a poll reached the chain tip. Only the first call per
+// process does anything: later lag and catch-up cycles are normal live indexing.
+func (c *Coordinator) markCaughtUp(checkpointHeight uint64) {
+	c.caughtUpOnce.Do(func() {
+		c.metricsMu.RLock()
+		height := max(checkpointHeight, c.lastIndexedHeight)
+		c.metricsMu.RUnlock()
+
+		c.logger.Log(context.Background(), LevelNotice, "caught up with chain tip, entering live mode", "height", height)
+		close(c.caughtUp)
+
+		if url := c.chainConfig.CaughtUpWebhook; url != "" {
+			event := CaughtUpEvent{Chain: c.chainID, Height: height, CaughtUpAt: time.Now().UTC()}
+			go c.postCaughtUp(url, event)
+		}
+	})
+}
+
+func (c *Coordinator) isCaughtUp() bool {
+	select {
+	case <-c.caughtUp:
+		return true
+	default:
+		return false
+	}
+}
+
+// postCaughtUp delivers the caught-up event once; failures are logged, not retried
+func (c *Coordinator) postCaughtUp(url string, event CaughtUpEvent) {
+	if err := postJSON(url, event); err != nil {
+		c.logger.Warn("caught_up_webhook failed", "error", err)
+	}
+}
+
+func postJSON(url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), caughtUpWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
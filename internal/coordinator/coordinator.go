@@ -2,27 +2,42 @@ package coordinator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/internal/indexer/internal/config"
 	"github.com/internal/indexer/internal/poller"
 	"github.com/internal/indexer/internal/reorg"
 	"github.com/internal/indexer/internal/storage"
+	"github.com/internal/indexer/internal/stream"
+	"github.com/internal/indexer/internal/webhook"
 	"github.com/internal/indexer/pkg/types"
 )
 
 // MetricsSnapshot is a point-in-time copy of metrics (no mutex, safe to copy)
 type MetricsSnapshot struct {
+	StartedAt          time.Time // When Run started (zero while waiting for a backfill slot)
 	LastIndexedHeight  uint64
 	LastIndexedAt      time.Time
+	LastBlockTime      time.Time // Timestamp of the last indexed block
+	LastPollAt         time.Time // Last poll that completed without error (zero until the first)
+	ConsecutiveErrors  int       // Failed polls since the last successful one
 	LastPollDuration   time.Duration
 	TotalBlocksIndexed uint64
 	TotalPollErrors    uint64
 	TotalReorgs        uint64
 	LastReorgDepth     int
+	Paused             bool
+	CaughtUp           bool   // Set once the initial backfill reached the chain tip
+	VerifyMismatches   uint64 // Aggregate divergences found by write verification (0 when disabled)
+	OversizedEvents    uint64 // Events stored undecoded for exceeding the decoded size limit (ETH)
+	RPCEndpoint        int    // Index of the active RPC endpoint (0 = rpc_url, then rpc_urls)
+	RPCFailovers       uint64 // Times requests switched RPC endpoint
 }
 
 // Coordinator orchestrates the indexing loop for a chain
@@ -33,19 +48,41 @@ type Coordinator struct {
 	storage       *storage.Storage
 	reorgDetector *reorg.Detector
 	logger        *slog.Logger
+	onCommit      func()              // Optional; called after each successful write (must not block)
+	hub           *stream.Hub         // Optional; receives a summary of each written block
+	webhooks      *webhook.Dispatcher // Optional; notified of each written transaction and token transfer
+	watch         watchList           // watch_addresses; nil indexes every transaction
 
 	// Backpressure: semaphore to limit concurrent DB writes
 	writeSem chan struct{}
 
-	// Metrics (protected by metricsMu)
-	metricsMu          sync.RWMutex
-	lastIndexedHeight  uint64
-	lastIndexedAt      time.Time
-	lastPollDuration   time.Duration
-	totalBlocksIndexed uint64
-	totalPollErrors    uint64
-	totalReorgs        uint64
-	lastReorgDepth     int
+	// Counters, updated lock-free so polls and scrapes don't contend
+	totalBlocksIndexed atomic.Uint64
+	totalPollErrors    atomic.Uint64
+	totalReorgs        atomic.Uint64
+	consecutiveErrors  atomic.Int64
+
+	// Last-poll and last-reorg state, read together by the health check (protected by metricsMu)
+	metricsMu         sync.RWMutex
+	startedAt         time.Time
+	lastIndexedHeight uint64
+	lastIndexedAt     time.Time
+	lastBlockTime     time.Time
+	lastPollAt        time.Time
+	lastPollDuration  time.Duration
+	lastReorgDepth    int
+
+	// Operator pause: polls are skipped while set
+	paused atomic.Bool
+
+	// Set when the last poll returned a full batch (only touched by the Run loop)
+	behind bool
+	// Consecutive polls that found no new block (only touched by the Run loop)
+	idlePolls int
+
+	// Closed once a poll reaches the chain tip (initial backfill complete)
+	caughtUp     chan struct{}
+	caughtUpOnce sync.Once
 
 	// Shutdown
 	stopCh   chan struct{}
@@ -67,29 +104,149 @@ func New(
 		poller:        chainPoller,
 		storage:       store,
 		reorgDetector: detector,
+		watch:         newWatchList(chainID, chainConfig.WatchAddresses),
 		logger:        logger.With("chain", string(chainID)),
 		writeSem:      make(chan struct{}, 1), // Single writer
+		caughtUp:      make(chan struct{}),
 		stopCh:        make(chan struct{}),
 	}
 }
 
-// GetMetrics returns a snapshot of current metrics (thread-safe)
+// SetCommitHook registers fn to run after every successful block write,
+// e.g. to wake the publish relay. fn must not block.
+func (c *Coordinator) SetCommitHook(fn func()) {
+	c.onCommit = fn
+}
+
+// SetBlockHub registers a hub that is sent a summary of every written block
+func (c *Coordinator) SetBlockHub(h *stream.Hub) {
+	c.hub = h
+}
+
+// SetWebhooks registers a dispatcher that matches every written transaction
+// and token transfer against webhook subscriptions
+func (c *Coordinator) SetWebhooks(d *webhook.Dispatcher) {
+	c.webhooks = d
+}
+
+func (c *Coordinator) committed(blocks []types.Block, txs []types.Transaction, transfers []types.TokenTransfer) {
+	if c.onCommit != nil {
+		c.onCommit()
+	}
+	if c.hub != nil {
+		c.hub.PublishBlocks(blocks)
+	}
+	if c.webhooks != nil {
+		c.webhooks.Notify(c.chainID, txs, transfers)
+	}
+}
+
+// GetMetrics returns a snapshot of current metrics (thread-safe). Counters are read
+// lock-free; the last-poll and last-reorg fields are read together under the lock.
 func (c *Coordinator) GetMetrics() MetricsSnapshot {
+	m := MetricsSnapshot{
+		TotalBlocksIndexed: c.totalBlocksIndexed.Load(),
+		TotalPollErrors:    c.totalPollErrors.Load(),
+		TotalReorgs:        c.totalReorgs.Load(),
+		Paused:             c.paused.Load(),
+		CaughtUp:           c.isCaughtUp(),
+		ConsecutiveErrors:  int(c.consecutiveErrors.Load()),
+		VerifyMismatches:   c.storage.VerifyMismatches(c.chainID),
+	}
+
+	if p, ok := c.poller.(poller.DecodeStatsPoller); ok {
+		m.OversizedEvents = p.OversizedEvents()
+	}
+	if p, ok := c.poller.(poller.EndpointPoller); ok {
+		m.RPCEndpoint, m.RPCFailovers = p.RPCEndpoint()
+	}
+
 	c.metricsMu.RLock()
-	defer c.metricsMu.RUnlock()
-	return MetricsSnapshot{
-		LastIndexedHeight:  c.lastIndexedHeight,
-		LastIndexedAt:      c.lastIndexedAt,
-		LastPollDuration:   c.lastPollDuration,
-		TotalBlocksIndexed: c.totalBlocksIndexed,
-		TotalPollErrors:    c.totalPollErrors,
-		TotalReorgs:        c.totalReorgs,
-		LastReorgDepth:     c.lastReorgDepth,
+	m.StartedAt = c.startedAt
+	m.LastIndexedHeight = c.lastIndexedHeight
+	m.LastIndexedAt = c.lastIndexedAt
+	m.LastBlockTime = c.lastBlockTime
+	m.LastPollAt = c.lastPollAt
+	m.LastPollDuration = c.lastPollDuration
+	m.LastReorgDepth = c.lastReorgDepth
+	c.metricsMu.RUnlock()
+
+	return m
+}
+
+// Pause stops the coordinator from polling until Resume is called.
+// An in-flight poll completes normally.
+func (c *Coordinator) Pause() {
+	if !c.paused.Swap(true) {
+		c.logger.Info("indexing paused")
+	}
+}
+
+// Resume re-enables polling after Pause
+func (c *Coordinator) Resume() {
+	if c.paused.Swap(false) {
+		c.logger.Info("indexing resumed")
 	}
 }
 
+// Paused reports whether the coordinator is paused
+func (c *Coordinator) Paused() bool {
+	return c.paused.Load()
+}
+
+// ErrRedecodeUnsupported is returned by RedecodeFailedEvents for chains whose
+// poller cannot decode stored raw logs
+var ErrRedecodeUnsupported = errors.New("chain does not support event re-decoding")
+
+// RedecodeFailedEvents re-decodes a contract's stored decode_failed events with
+// the poller's current ABIs. Safe to call while indexing is running.
+func (c *Coordinator) RedecodeFailedEvents(ctx context.Context, contractAddr string) (decoded, failed int64, err error) {
+	d, ok := c.poller.(poller.RawLogDecoder)
+	if !ok {
+		return 0, 0, ErrRedecodeUnsupported
+	}
+	decoded, failed, err = c.storage.RedecodeFailedEvents(ctx, c.chainID, contractAddr, d.DecodeRawLog)
+	c.logger.Info("re-decoded failed events",
+		"contract", contractAddr,
+		"decoded", decoded,
+		"still_failed", failed,
+	)
+	return decoded, failed, err
+}
+
+// rollback rolls back a detected reorg, recording it in the reorgs table when
+// record_reorgs is enabled
+func (c *Coordinator) rollback(ctx context.Context, result *reorg.ReorgResult) error {
+	if !c.chainConfig.RecordReorgs {
+		return c.storage.Rollback(ctx, c.chainID, result.RollbackHeight, result.RollbackHash)
+	}
+	return c.storage.RollbackReorg(ctx, c.chainID, result.RollbackHash, types.Reorg{
+		ChainID:        c.chainID,
+		DetectedAt:     time.Now(),
+		RollbackHeight: result.RollbackHeight,
+		Depth:          result.Depth,
+		OldHash:        result.OldHash,
+		NewHash:        result.NewHash,
+	})
+}
+
+// Reorgs returns the chain's most recent recorded reorgs, newest first
+func (c *Coordinator) Reorgs(ctx context.Context, limit int) ([]types.Reorg, error) {
+	return c.storage.GetReorgs(ctx, c.chainID, limit)
+}
+
+// CaughtUp returns a channel that is closed once the coordinator has caught up
+// with the chain tip and is indexing in live mode
+func (c *Coordinator) CaughtUp() <-chan struct{} {
+	return c.caughtUp
+}
+
 // Run starts the indexing loop (blocking)
 func (c *Coordinator) Run(ctx context.Context) error {
+	c.metricsMu.Lock()
+	c.startedAt = time.Now()
+	c.metricsMu.Unlock()
+
 	c.logger.Info("starting coordinator",
 		"poll_interval", c.chainConfig.PollInterval,
 		"batch_size", c.chainConfig.BatchSize,
@@ -101,14 +258,33 @@ func (c *Coordinator) Run(ctx context.Context) error {
 		return fmt.Errorf("initializing checkpoint: %w", err)
 	}
 
-	ticker := time.NewTicker(c.chainConfig.PollInterval)
-	defer ticker.Stop()
+	if err := c.storage.SaveChainSettings(ctx, c.chainID, c.settings()); err != nil {
+		return fmt.Errorf("saving chain settings: %w", err)
+	}
+
+	if c.chainConfig.Retention.Enabled() {
+		go c.pruneLoop(ctx)
+	}
 
 	// Run first poll immediately
+	consecutiveErrors := 0
 	if err := c.poll(ctx); err != nil {
 		c.logger.Error("poll failed", "error", err)
+		consecutiveErrors++
+	}
+	c.recordPoll(consecutiveErrors)
+
+	// With a new-heads subscription, polls at the tip are driven by new heads and
+	// the timer only backs them up; without one (or once it drops) the timer drives
+	heads := c.subscribe(ctx)
+	var lastSubscribe time.Time
+	if heads != nil {
+		lastSubscribe = time.Now()
 	}
 
+	timer := time.NewTimer(c.nextWait(consecutiveErrors, heads != nil))
+	defer timer.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -117,15 +293,117 @@ func (c *Coordinator) Run(ctx context.Context) error {
 		case <-c.stopCh:
 			c.logger.Info("coordinator stopping due to stop signal")
 			return nil
-		case <-ticker.C:
-			if err := c.poll(ctx); err != nil {
-				c.logger.Error("poll failed", "error", err)
-				c.metricsMu.Lock()
-				c.totalPollErrors++
-				c.metricsMu.Unlock()
+		case _, ok := <-heads:
+			if !ok {
+				c.logger.Warn("new heads subscription ended, falling back to polling")
+				heads = nil
+				break
+			}
+		case <-timer.C:
+			if heads == nil && time.Since(lastSubscribe) >= resubscribeInterval {
+				heads = c.subscribe(ctx)
+				lastSubscribe = time.Now()
 			}
 		}
+
+		if err := c.poll(ctx); err != nil {
+			c.logger.Error("poll failed", "error", err)
+			c.totalPollErrors.Add(1)
+			consecutiveErrors++
+		} else {
+			consecutiveErrors = 0
+		}
+		c.recordPoll(consecutiveErrors)
+		timer.Reset(c.nextWait(consecutiveErrors, heads != nil))
+	}
+}
+
+// subscribedPollInterval is how often the timer still polls while a new-heads
+// subscription drives polling at the tip, in case it stalls without ending
+const subscribedPollInterval = time.Minute
+
+// resubscribeInterval is the least time between attempts to subscribe to new heads
+const resubscribeInterval = time.Minute
+
+// subscribe subscribes to new heads when the poller supports it, returning nil
+// (poll on the timer) when it does not or the subscription fails
+func (c *Coordinator) subscribe(ctx context.Context) <-chan uint64 {
+	s, ok := c.poller.(poller.HeadSubscriber)
+	if !ok {
+		return nil
+	}
+	heads, err := s.Subscribe(ctx)
+	if err != nil {
+		if !errors.Is(err, poller.ErrSubscriptionUnavailable) {
+			c.logger.Warn("new heads subscription failed, polling instead", "error", err)
+		}
+		return nil
+	}
+	c.logger.Info("subscribed to new heads")
+	return heads
+}
+
+// nextWait returns the timer delay before the next poll: nextInterval, stretched
+// to subscribedPollInterval while subscribed at the tip without errors
+func (c *Coordinator) nextWait(consecutiveErrors int, subscribed bool) time.Duration {
+	d := c.nextInterval(consecutiveErrors)
+	if subscribed && !c.behind && consecutiveErrors == 0 {
+		d = max(d, subscribedPollInterval)
 	}
+	return d
+}
+
+// recordPoll publishes the outcome of a poll for health checks
+func (c *Coordinator) recordPoll(consecutiveErrors int) {
+	c.consecutiveErrors.Store(int64(consecutiveErrors))
+	if consecutiveErrors == 0 {
+		c.metricsMu.Lock()
+		c.lastPollAt = time.Now()
+		c.metricsMu.Unlock()
+	}
+}
+
+// maxBackoffShift caps exponential error backoff at 2^maxBackoffShift * poll_interval
+const maxBackoffShift = 6
+
+// nextInterval returns the delay before the next poll. While behind the tip the
+// coordinator polls at min_poll_interval to catch up; after consecutive errors it
+// backs off exponentially. At the tip, consecutive empty polls double the interval
+// up to idle_poll_interval, and the first new block resets it. Every adjustment is
+// bounded by min/max_poll_interval.
+func (c *Coordinator) nextInterval(consecutiveErrors int) time.Duration {
+	cfg := c.chainConfig
+	d := cfg.PollInterval
+
+	switch {
+	case consecutiveErrors > 0 && cfg.MaxPollInterval > 0:
+		d = cfg.PollInterval << min(consecutiveErrors, maxBackoffShift)
+	case c.behind && cfg.MinPollInterval > 0:
+		d = cfg.MinPollInterval
+	case c.idlePolls > 0 && cfg.IdlePollInterval > cfg.PollInterval:
+		d = min(cfg.PollInterval<<min(c.idlePolls, maxBackoffShift), cfg.IdlePollInterval)
+	}
+
+	if d < cfg.MinPollInterval {
+		d = cfg.MinPollInterval
+	}
+	if cfg.MaxPollInterval > 0 && d > cfg.MaxPollInterval {
+		d = cfg.MaxPollInterval
+	}
+	return d
+}
+
+// settings returns the configuration published to chain_settings for the API
+func (c *Coordinator) settings() types.ChainSettings {
+	cs := types.ChainSettings{
+		ConfirmationDepth: c.chainConfig.ConfirmationDepth,
+		MaxReorgDepth:     c.chainConfig.MaxReorgDepth,
+		StartHeight:       c.chainConfig.StartHeight,
+	}
+	for _, contract := range c.chainConfig.Contracts {
+		cs.Contracts = append(cs.Contracts, strings.ToLower(contract.Address))
+	}
+	return cs
 }
 
 // Stop signals the coordinator to stop
@@ -135,7 +413,27 @@ func (c *Coordinator) Stop() {
 	})
 }
 
+// pollCutShort reports whether the poller stopped its last batch of n blocks
+// before the tip or batch size, logging why
+func (c *Coordinator) pollCutShort(n int) bool {
+	pp, ok := c.poller.(poller.PartialBatchPoller)
+	if !ok {
+		return false
+	}
+	err := pp.PollCutShort()
+	if err == nil {
+		return false
+	}
+	c.logger.Warn("batch cut short, indexing the blocks before it", "blocks", n, "reason", err)
+	return true
+}
+
 func (c *Coordinator) poll(ctx context.Context) error {
+	if c.paused.Load() {
+		c.logger.Debug("paused, skipping poll")
+		return nil
+	}
+
 	startTime := time.Now()
 
 	// Get current checkpoint
@@ -155,31 +453,69 @@ func (c *Coordinator) poll(ctx context.Context) error {
 	var blocks []types.Block
 	var txs []types.Transaction
 	var events []types.Event
+	var fetched int
+	var written bool // The event path wrote the batch itself
 
 	// Check if poller supports events (type assertion pattern)
 	if eventPoller, ok := c.poller.(poller.EventCapablePoller); ok {
 		var err error
+		var contracts []types.Contract
 		var tokens []types.Token
 		var transfers []types.TokenTransfer
-		blocks, txs, events, _, tokens, transfers, err = eventPoller.PollWithEvents(ctx, lastHeight)
+		blocks, txs, events, contracts, tokens, transfers, err = eventPoller.PollWithEvents(ctx, lastHeight)
 		if err != nil {
 			return fmt.Errorf("polling blocks with events: %w", err)
 		}
+		fetched = len(blocks)
+		if c.pollCutShort(len(blocks)) {
+			fetched = c.chainConfig.BatchSize // Cut short, not at the tip
+		}
 
-		// Write with tokens
-		if len(events) > 0 || len(tokens) > 0 || len(transfers) > 0 {
-			if err := c.storage.WriteBlocksWithEvents(ctx, c.chainID, blocks, txs, events, nil, tokens, transfers); err != nil {
+		// This path writes before reorg detection runs, so reject a forked batch here
+		if err := reorg.CheckBatch(blocks); err != nil {
+			return fmt.Errorf("polling blocks with events: %w", err)
+		}
+		txs, transfers = c.watch.filterTxs(txs), c.watch.filterTransfers(transfers)
+
+		// Write with events, created contracts and tokens
+		if len(events) > 0 || len(contracts) > 0 || len(tokens) > 0 || len(transfers) > 0 {
+			if err := c.storage.WriteBlocksWithEvents(ctx, c.chainID, blocks, txs, events, contracts, tokens, transfers); err != nil {
 				return fmt.Errorf("writing blocks with events: %w", err)
 			}
-			// Skip standard write
-			blocks = nil // mark as done
+			c.committed(blocks, txs, transfers)
+			written = true // Skip reorg detection and the standard write, but still finalize
 		}
+	} else if gp, ok := c.poller.(poller.GenesisPoller); ok && c.needsGenesis(checkpoint) {
+		// Poll starts after the checkpoint height, so genesis is written on its own first
+		var err error
+		blocks, txs, err = gp.PollGenesis(ctx)
+		if err != nil {
+			return fmt.Errorf("polling genesis: %w", err)
+		}
+		txs = c.watch.filterTxs(txs)
+		fetched = c.chainConfig.BatchSize // Not at the tip yet
 	} else {
 		var err error
 		blocks, txs, err = c.poller.Poll(ctx, lastHeight)
 		if err != nil {
 			return fmt.Errorf("polling blocks: %w", err)
 		}
+		txs = c.watch.filterTxs(txs)
+		fetched = len(blocks)
+		if c.pollCutShort(len(blocks)) {
+			fetched = c.chainConfig.BatchSize // Cut short, not at the tip
+		}
+	}
+
+	// A short batch means the poller hit the tip
+	c.behind = fetched >= c.chainConfig.BatchSize
+	if !c.behind {
+		defer c.markCaughtUp(lastHeight)
+	}
+	if fetched == 0 {
+		c.idlePolls++
+	} else {
+		c.idlePolls = 0
 	}
 
 	if len(blocks) == 0 {
@@ -194,39 +530,50 @@ func (c *Coordinator) poll(ctx context.Context) error {
 		"events", len(events),
 	)
 
-	// Check for reorg
-	reorgResult, err := c.reorgDetector.Detect(ctx, c.chainID, c.poller, blocks)
-	if err != nil {
-		// Check if it's a critical reorg depth error
-		c.logger.Error("reorg detection error", "error", err)
-		return fmt.Errorf("reorg detection: %w", err)
-	}
-
-	if reorgResult.Detected {
-		c.logger.Warn("handling reorg",
-			"rollback_height", reorgResult.RollbackHeight,
-			"depth", reorgResult.Depth,
-		)
+	// The event path checked its batch with reorg.CheckBatch before writing
+	if !written {
+		// Check for reorg
+		reorgResult, err := c.reorgDetector.Detect(ctx, c.chainID, c.poller, blocks)
+		if err != nil {
+			// Check if it's a critical reorg depth error
+			c.logger.Error("reorg detection error", "error", err)
+			return fmt.Errorf("reorg detection: %w", err)
+		}
 
-		c.metricsMu.Lock()
-		c.totalReorgs++
-		c.lastReorgDepth = reorgResult.Depth
-		c.metricsMu.Unlock()
+		if reorgResult.Detected {
+			c.logger.Warn("handling reorg",
+				"rollback_height", reorgResult.RollbackHeight,
+				"depth", reorgResult.Depth,
+			)
+
+			c.totalReorgs.Add(1)
+			c.metricsMu.Lock()
+			c.lastReorgDepth = reorgResult.Depth
+			c.metricsMu.Unlock()
+
+			// Acquire write semaphore for rollback
+			select {
+			case c.writeSem <- struct{}{}:
+				defer func() { <-c.writeSem }()
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 
-		// Acquire write semaphore for rollback
-		select {
-		case c.writeSem <- struct{}{}:
-			defer func() { <-c.writeSem }()
-		case <-ctx.Done():
-			return ctx.Err()
-		}
+			if err := c.rollback(ctx, reorgResult); err != nil {
+				if errors.Is(err, storage.ErrRollbackBelowFinalized) {
+					// Same P1 situation as an over-deep reorg: stop here every poll until resolved
+					c.logger.Error("CRITICAL: reorg reaches below finalized blocks - manual intervention required",
+						"rollback_height", reorgResult.RollbackHeight,
+						"depth", reorgResult.Depth,
+						"error", err,
+					)
+				}
+				return fmt.Errorf("rolling back: %w", err)
+			}
 
-		if err := c.storage.Rollback(ctx, c.chainID, reorgResult.RollbackHeight, reorgResult.RollbackHash); err != nil {
-			return fmt.Errorf("rolling back: %w", err)
+			// Re-poll from rollback point (will happen on next tick)
+			return nil
 		}
-
-		// Re-poll from rollback point (will happen on next tick)
-		return nil
 	}
 
 	// Acquire write semaphore
@@ -238,15 +585,8 @@ func (c *Coordinator) poll(ctx context.Context) error {
 	}
 
 	// Write blocks atomically with checkpoint (only if not already written)
-	if len(blocks) > 0 {
+	if !written {
 		if len(events) > 0 {
-			// This path should ideally cover the case where type assertion failed but we somehow have events?
-			// Actually, if we entered the first block `if eventPoller ...`, we already wrote.
-			// The only case blocks is NOT nil here is if type assertion failed (standard poller) OR if `len(events/tokens/txs) == 0`.
-
-			// If we are here and have events, it means we didn't write above.
-			// BUT, the logic above sets blocks=nil if it wrote.
-
 			if err := c.storage.WriteBlocksWithEvents(ctx, c.chainID, blocks, txs, events, nil, nil, nil); err != nil {
 				return fmt.Errorf("writing blocks with events: %w", err)
 			}
@@ -255,10 +595,11 @@ func (c *Coordinator) poll(ctx context.Context) error {
 				return fmt.Errorf("writing blocks: %w", err)
 			}
 		}
+		c.committed(blocks, txs, nil)
 	}
 
 	// Finalize old blocks
-	if err := c.storage.FinalizeBlocks(ctx, c.chainID, c.chainConfig.ConfirmationDepth); err != nil {
+	if err := c.finalize(ctx); err != nil {
 		c.logger.Warn("finalization failed", "error", err)
 		// Non-fatal, continue
 	}
@@ -267,11 +608,12 @@ func (c *Coordinator) poll(ctx context.Context) error {
 	lastBlock := blocks[len(blocks)-1]
 	pollDuration := time.Since(startTime)
 
+	c.totalBlocksIndexed.Add(uint64(len(blocks)))
 	c.metricsMu.Lock()
 	c.lastIndexedHeight = lastBlock.Height
 	c.lastIndexedAt = time.Now()
+	c.lastBlockTime = lastBlock.Timestamp
 	c.lastPollDuration = pollDuration
-	c.totalBlocksIndexed += uint64(len(blocks))
 	c.metricsMu.Unlock()
 
 	c.logger.Info("indexed blocks",
@@ -283,3 +625,28 @@ func (c *Coordinator) poll(ctx context.Context) error {
 
 	return nil
 }
+
+// finalize promotes settled blocks to finalized. With use_finalized_tag and a poller
+// that reports finality, blocks are finalized up to the node's finalized height;
+// otherwise up to the configured confirmation depth below the indexed tip.
+func (c *Coordinator) finalize(ctx context.Context) error {
+	fp, ok := c.poller.(poller.FinalityAwarePoller)
+	if !ok || !c.chainConfig.UseFinalizedTag {
+		return c.storage.FinalizeBlocks(ctx, c.chainID, c.chainConfig.ConfirmationDepth)
+	}
+
+	height, err := fp.GetFinalizedHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("getting finalized height: %w", err)
+	}
+	if height == 0 {
+		return nil // Early chain: nothing finalized yet
+	}
+	return c.storage.FinalizeUpTo(ctx, c.chainID, height)
+}
+
+// needsGenesis reports whether index_genesis applies and block 0 is not yet written:
+// a checkpoint still at height 0 without a hash is the fresh one from InitCheckpoint
+func (c *Coordinator) needsGenesis(cp *types.Checkpoint) bool {
+	return c.chainConfig.IndexGenesis && cp != nil && cp.LastHeight == 0 && cp.LastHash == ""
+}
@@ -0,0 +1,468 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/internal/indexer/internal/config"
+	"github.com/internal/indexer/internal/storage"
+	"github.com/internal/indexer/pkg/types"
+)
+
+func TestNextInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.ChainConfig
+		behind bool
+		idle   int
+		errors int
+		want   time.Duration
+	}{
+		{name: "no bounds", cfg: config.ChainConfig{PollInterval: 2 * time.Second}, want: 2 * time.Second},
+		{name: "no bounds while behind", cfg: config.ChainConfig{PollInterval: 2 * time.Second}, behind: true, want: 2 * time.Second},
+		{name: "no backoff without ceiling", cfg: config.ChainConfig{PollInterval: 2 * time.Second}, errors: 3, want: 2 * time.Second},
+		{
+			name:   "catch up at floor",
+			cfg:    config.ChainConfig{PollInterval: 2 * time.Second, MinPollInterval: 200 * time.Millisecond},
+			behind: true,
+			want:   200 * time.Millisecond,
+		},
+		{
+			name:   "backoff",
+			cfg:    config.ChainConfig{PollInterval: 2 * time.Second, MaxPollInterval: time.Minute},
+			errors: 2,
+			want:   8 * time.Second,
+		},
+		{
+			name:   "backoff capped at ceiling",
+			cfg:    config.ChainConfig{PollInterval: 2 * time.Second, MaxPollInterval: 10 * time.Second},
+			errors: 5,
+			want:   10 * time.Second,
+		},
+		{
+			name:   "errors take precedence over catch-up",
+			cfg:    config.ChainConfig{PollInterval: 2 * time.Second, MinPollInterval: time.Second, MaxPollInterval: time.Minute},
+			behind: true,
+			errors: 1,
+			want:   4 * time.Second,
+		},
+		{name: "no idle backoff without cap", cfg: config.ChainConfig{PollInterval: 2 * time.Second}, idle: 3, want: 2 * time.Second},
+		{
+			name: "idle backoff",
+			cfg:  config.ChainConfig{PollInterval: 2 * time.Second, IdlePollInterval: time.Minute},
+			idle: 2,
+			want: 8 * time.Second,
+		},
+		{
+			name: "idle backoff capped",
+			cfg:  config.ChainConfig{PollInterval: 2 * time.Second, IdlePollInterval: 10 * time.Second},
+			idle: 5,
+			want: 10 * time.Second,
+		},
+		{
+			name: "idle backoff within ceiling",
+			cfg:  config.ChainConfig{PollInterval: 2 * time.Second, MaxPollInterval: 5 * time.Second, IdlePollInterval: time.Minute},
+			idle: 5,
+			want: 5 * time.Second,
+		},
+		{
+			name: "new block resets idle backoff",
+			cfg:  config.ChainConfig{PollInterval: 2 * time.Second, IdlePollInterval: time.Minute},
+			idle: 0,
+			want: 2 * time.Second,
+		},
+		{
+			name:   "catch-up takes precedence over idle",
+			cfg:    config.ChainConfig{PollInterval: 2 * time.Second, MinPollInterval: time.Second, IdlePollInterval: time.Minute},
+			behind: true,
+			idle:   3,
+			want:   time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Coordinator{chainConfig: tt.cfg, behind: tt.behind, idlePolls: tt.idle}
+			if got := c.nextInterval(tt.errors); got != tt.want {
+				t.Errorf("nextInterval(%d) = %v, want %v", tt.errors, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextWait(t *testing.T) {
+	cfg := config.ChainConfig{PollInterval: 2 * time.Second, MinPollInterval: time.Second, MaxPollInterval: 10 * time.Minute}
+	tests := []struct {
+		name       string
+		subscribed bool
+		behind     bool
+		errors     int
+		want       time.Duration
+	}{
+		{name: "polling", want: 2 * time.Second},
+		{name: "subscribed at tip", subscribed: true, want: subscribedPollInterval},
+		{name: "subscribed while behind", subscribed: true, behind: true, want: time.Second},
+		{name: "subscribed after error", subscribed: true, errors: 1, want: 4 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Coordinator{chainConfig: cfg, behind: tt.behind}
+			if got := c.nextWait(tt.errors, tt.subscribed); got != tt.want {
+				t.Errorf("nextWait(%d, %v) = %v, want %v", tt.errors, tt.subscribed, got, tt.want)
+			}
+		})
+	}
+}
+
+// finalityPoller reports a fixed finalized height; other ChainPoller methods are unused
+type finalityPoller struct {
+	finalized uint64
+	err       error
+}
+
+func (p *finalityPoller) Poll(ctx context.Context, lastHeight uint64) ([]types.Block, []types.Transaction, error) {
+	return nil, nil, nil
+}
+func (p *finalityPoller) GetBlockByHash(ctx context.Context, hash string) (*types.Block, error) {
+	return nil, nil
+}
+func (p *finalityPoller) ChainID() types.ChainID                          { return types.ChainETH }
+func (p *finalityPoller) GetChainTip(ctx context.Context) (uint64, error) { return 0, nil }
+func (p *finalityPoller) GetFinalizedHeight(ctx context.Context) (uint64, error) {
+	return p.finalized, p.err
+}
+
+func TestFinalize_EarlyChain(t *testing.T) {
+	cfg := config.ChainConfig{UseFinalizedTag: true, ConfirmationDepth: 12}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Nothing finalized yet must not reach storage (nil here, so any write would panic)
+	c := New(types.ChainETH, cfg, &finalityPoller{}, nil, nil, logger)
+	if err := c.finalize(context.Background()); err != nil {
+		t.Errorf("expected no error before anything is finalized, got %v", err)
+	}
+
+	rpcErr := errors.New("rpc down")
+	c = New(types.ChainETH, cfg, &finalityPoller{err: rpcErr}, nil, nil, logger)
+	if err := c.finalize(context.Background()); !errors.Is(err, rpcErr) {
+		t.Errorf("expected finalized height error, got %v", err)
+	}
+}
+
+// staticPoller serves a chain ID; other ChainPoller methods are unused
+type staticPoller struct {
+	chainID types.ChainID
+}
+
+func (p *staticPoller) Poll(ctx context.Context, lastHeight uint64) ([]types.Block, []types.Transaction, error) {
+	return nil, nil, nil
+}
+func (p *staticPoller) GetBlockByHash(ctx context.Context, hash string) (*types.Block, error) {
+	return nil, nil
+}
+func (p *staticPoller) ChainID() types.ChainID                          { return p.chainID }
+func (p *staticPoller) GetChainTip(ctx context.Context) (uint64, error) { return 0, nil }
+
+func TestFinalize_ConfirmationDepthPerChain(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Both chains index a batch up to the same tip; each finalizes at its own depth
+	for _, tt := range []struct {
+		chainID types.ChainID
+		depth   int
+		want    uint64
+	}{
+		{types.ChainBTC, 6, 994},
+		{types.ChainETH, 12, 988},
+	} {
+		t.Run(string(tt.chainID), func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			blocks := []types.Block{
+				{ChainID: tt.chainID, Height: 999, Hash: "h999", ParentHash: "h998", Timestamp: time.Now()},
+				{ChainID: tt.chainID, Height: 1000, Hash: "h1000", ParentHash: "h999", Timestamp: time.Now()},
+			}
+			p := &eventPoller{
+				staticPoller: staticPoller{chainID: tt.chainID},
+				blocks:       blocks,
+				events:       []types.Event{{ChainID: tt.chainID, BlockHeight: 1000, BlockHash: "h1000", TxHash: "t1"}},
+			}
+
+			mock.ExpectQuery(`FROM checkpoints`).
+				WithArgs(string(tt.chainID)).
+				WillReturnRows(sqlmock.NewRows([]string{"chain_id", "last_height", "last_hash", "updated_at"}).
+					AddRow(string(tt.chainID), 998, "h998", time.Now()))
+			expectEventWrite(mock, blocks)
+			expectFinalize(mock, tt.chainID, 1000, tt.want)
+
+			cfg := config.ChainConfig{ConfirmationDepth: tt.depth, BatchSize: 10}
+			c := New(tt.chainID, cfg, p, storage.New(db), nil, logger)
+			if err := c.poll(context.Background()); err != nil {
+				t.Fatalf("poll: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// eventPoller returns one batch of blocks with events; other ChainPoller methods are unused
+type eventPoller struct {
+	staticPoller
+	blocks []types.Block
+	events []types.Event
+}
+
+func (p *eventPoller) PollWithEvents(ctx context.Context, lastHeight uint64) ([]types.Block, []types.Transaction, []types.Event, []types.Contract, []types.Token, []types.TokenTransfer, error) {
+	return p.blocks, nil, p.events, nil, nil, nil, nil
+}
+
+// expectEventWrite expects WriteBlocksWithEvents to find blocks already stored,
+// which keeps the mocked write short while poll carries on as after any write
+func expectEventWrite(mock sqlmock.Sqlmock, blocks []types.Block) {
+	rows := sqlmock.NewRows([]string{"hash"})
+	for _, b := range blocks {
+		rows.AddRow(b.Hash)
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT hash FROM blocks`).WillReturnRows(rows)
+	mock.ExpectExec(`INSERT INTO checkpoints`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+}
+
+// expectFinalize expects FinalizeBlocks to finalize up to height want below tip
+func expectFinalize(mock sqlmock.Sqlmock, chainID types.ChainID, tip, want uint64) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT last_height FROM checkpoints`).
+		WithArgs(string(chainID)).
+		WillReturnRows(sqlmock.NewRows([]string{"last_height"}).AddRow(tip))
+	for _, table := range []string{"blocks", "transactions", "events"} {
+		mock.ExpectExec(`UPDATE `+table+` SET status = 'finalized'`).
+			WithArgs(string(chainID), want).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func TestPoll_EventBatchFinalizes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	blocks := []types.Block{
+		{ChainID: types.ChainETH, Height: 1001, Hash: "0x1001", ParentHash: "0x1000", Timestamp: time.Now()},
+		{ChainID: types.ChainETH, Height: 1002, Hash: "0x1002", ParentHash: "0x1001", Timestamp: time.Now()},
+	}
+	p := &eventPoller{
+		staticPoller: staticPoller{chainID: types.ChainETH},
+		blocks:       blocks,
+		events:       []types.Event{{ChainID: types.ChainETH, BlockHeight: 1002, BlockHash: "0x1002", TxHash: "0xt1"}},
+	}
+
+	mock.ExpectQuery(`FROM checkpoints`).
+		WithArgs("eth").
+		WillReturnRows(sqlmock.NewRows([]string{"chain_id", "last_height", "last_hash", "updated_at"}).
+			AddRow("eth", 1000, "0x1000", time.Now()))
+	expectEventWrite(mock, blocks)
+	expectFinalize(mock, types.ChainETH, 1002, 990)
+
+	cfg := config.ChainConfig{ConfirmationDepth: 12, BatchSize: 10}
+	c := New(types.ChainETH, cfg, p, storage.New(db), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := c.poll(context.Background()); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expected the event batch to be finalized: %v", err)
+	}
+	if got := c.GetMetrics().LastIndexedHeight; got != 1002 {
+		t.Errorf("expected last indexed height 1002, got %d", got)
+	}
+}
+
+// cutShortPoller reports its batches as cut short by a failed block while err is set
+type cutShortPoller struct {
+	staticPoller
+	err error
+}
+
+func (p *cutShortPoller) PollCutShort() error { return p.err }
+
+func TestPoll_CutShortBatchIsNotCaughtUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	for range 2 {
+		mock.ExpectQuery(`FROM checkpoints`).
+			WithArgs("btc").
+			WillReturnRows(sqlmock.NewRows([]string{"chain_id", "last_height", "last_hash", "updated_at"}).
+				AddRow("btc", 1000, "h1000", time.Now()))
+	}
+
+	p := &cutShortPoller{staticPoller: staticPoller{chainID: types.ChainBTC}, err: errors.New("getting block 1001: timeout")}
+	cfg := config.ChainConfig{BatchSize: 10}
+	c := New(types.ChainBTC, cfg, p, storage.New(db), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if err := c.poll(context.Background()); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if !c.behind || c.isCaughtUp() {
+		t.Error("expected a batch cut short to leave the chain behind, not caught up")
+	}
+
+	p.err = nil
+	if err := c.poll(context.Background()); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if c.behind || !c.isCaughtUp() {
+		t.Error("expected an empty batch to mean caught up")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// cutShortEventPoller is an eventPoller whose batch was cut short
+type cutShortEventPoller struct {
+	eventPoller
+}
+
+func (p *cutShortEventPoller) PollCutShort() error {
+	return errors.New("max_logs_per_poll reached at block 1001")
+}
+
+func TestPoll_CutShortEventBatchIsNotCaughtUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	blocks := []types.Block{{ChainID: types.ChainETH, Height: 1001, Hash: "0x1001", ParentHash: "0x1000", Timestamp: time.Now()}}
+	p := &cutShortEventPoller{eventPoller{
+		staticPoller: staticPoller{chainID: types.ChainETH},
+		blocks:       blocks,
+		events:       []types.Event{{ChainID: types.ChainETH, BlockHeight: 1001, BlockHash: "0x1001", TxHash: "0xt1"}},
+	}}
+
+	mock.ExpectQuery(`FROM checkpoints`).
+		WithArgs("eth").
+		WillReturnRows(sqlmock.NewRows([]string{"chain_id", "last_height", "last_hash", "updated_at"}).
+			AddRow("eth", 1000, "0x1000", time.Now()))
+	expectEventWrite(mock, blocks)
+	expectFinalize(mock, types.ChainETH, 1001, 989)
+
+	cfg := config.ChainConfig{ConfirmationDepth: 12, BatchSize: 10}
+	c := New(types.ChainETH, cfg, p, storage.New(db), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := c.poll(context.Background()); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if !c.behind || c.isCaughtUp() {
+		t.Error("expected an event batch cut short to leave the chain behind, not caught up")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNeedsGenesis(t *testing.T) {
+	enabled := &Coordinator{chainConfig: config.ChainConfig{IndexGenesis: true}}
+
+	if !enabled.needsGenesis(&types.Checkpoint{LastHeight: 0}) {
+		t.Error("expected genesis on a fresh checkpoint at height 0")
+	}
+	if enabled.needsGenesis(&types.Checkpoint{LastHeight: 0, LastHash: "genesis"}) {
+		t.Error("expected no genesis once block 0 is written")
+	}
+	if enabled.needsGenesis(&types.Checkpoint{LastHeight: 100}) {
+		t.Error("expected no genesis when starting past height 0")
+	}
+
+	disabled := &Coordinator{}
+	if disabled.needsGenesis(&types.Checkpoint{LastHeight: 0}) {
+		t.Error("expected no genesis without index_genesis")
+	}
+}
+
+func TestWatchList(t *testing.T) {
+	w := newWatchList(types.ChainETH, []string{"0xAAAA", "0xbbbb"})
+
+	txs := []types.Transaction{
+		{TxHash: "t1", FromAddr: "0xaaaa", ToAddr: "0x1111"},
+		{TxHash: "t2", FromAddr: "0x2222", ToAddr: "0x3333"},
+		{TxHash: "t3", FromAddr: "0x4444", ToAddr: "0xbbbb"},
+		{TxHash: "t4", FromAddr: "0x5555"}, // Contract creation
+	}
+	got := w.filterTxs(txs)
+	if len(got) != 2 || got[0].TxHash != "t1" || got[1].TxHash != "t3" {
+		t.Errorf("expected t1 and t3, got %+v", got)
+	}
+
+	transfers := []types.TokenTransfer{
+		{TxHash: "t1", FromAddr: "0x1111", ToAddr: "0x2222"},
+		{TxHash: "t2", FromAddr: "0xbbbb", ToAddr: "0x2222"},
+	}
+	gotTransfers := w.filterTransfers(transfers)
+	if len(gotTransfers) != 1 || gotTransfers[0].TxHash != "t2" {
+		t.Errorf("expected t2, got %+v", gotTransfers)
+	}
+
+	// No watch list keeps everything
+	all := []types.Transaction{{TxHash: "t1"}, {TxHash: "t2"}}
+	if got := newWatchList(types.ChainETH, nil).filterTxs(all); len(got) != 2 {
+		t.Errorf("expected both txs without a watch list, got %d", len(got))
+	}
+}
+
+func TestMarkCaughtUp_FiresOnce(t *testing.T) {
+	events := make(chan CaughtUpEvent, 2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e CaughtUpEvent
+		json.NewDecoder(r.Body).Decode(&e)
+		events <- e
+	}))
+	defer hook.Close()
+
+	cfg := config.ChainConfig{CaughtUpWebhook: hook.URL}
+	c := New(types.ChainBTC, cfg, nil, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if c.isCaughtUp() {
+		t.Fatal("expected not caught up before the first short batch")
+	}
+
+	c.markCaughtUp(840000)
+	c.markCaughtUp(840005) // Later catch-ups after minor lag are ignored
+
+	if !c.isCaughtUp() {
+		t.Error("expected caught up")
+	}
+	select {
+	case e := <-events:
+		if e.Chain != types.ChainBTC || e.Height != 840000 {
+			t.Errorf("unexpected event: %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a webhook call")
+	}
+	select {
+	case e := <-events:
+		t.Errorf("expected a single webhook call, got another: %+v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,56 @@
+package coordinator
+
+import (
+	"context"
+	"time"
+
+	"github.com/internal/indexer/internal/storage"
+)
+
+// defaultPruneInterval is the time between prunes when retention.interval is unset
+const defaultPruneInterval = time.Hour
+
+// pruneLoop applies the chain's retention limits on a slow ticker until the
+// coordinator stops. Failures are logged and retried on the next tick.
+func (c *Coordinator) pruneLoop(ctx context.Context) {
+	interval := c.chainConfig.Retention.Interval
+	if interval <= 0 {
+		interval = defaultPruneInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			c.prune(ctx)
+		}
+	}
+}
+
+// prune runs a single retention pass
+func (c *Coordinator) prune(ctx context.Context) {
+	r := c.chainConfig.Retention
+	res, err := c.storage.Prune(ctx, c.chainID, storage.PruneOptions{
+		OrphanedMaxAge: r.OrphanedBlocksMaxAge,
+		RawData:        storage.RawDataRetention(r.RawData),
+		RawDataBlocks:  r.RawDataBlocks,
+		BatchSize:      r.BatchSize,
+	})
+	if err != nil {
+		c.logger.Error("prune failed", "error", err)
+		return
+	}
+	if res.OrphanedDeleted > 0 || res.RawDataCleared > 0 || res.TxRawDataCleared > 0 {
+		c.logger.Info("pruned old data",
+			"orphaned_blocks_deleted", res.OrphanedDeleted,
+			"raw_data_cleared", res.RawDataCleared,
+			"tx_raw_data_cleared", res.TxRawDataCleared,
+			"raw_data_pruned_to", res.RawDataPrunedUpTo,
+		)
+	}
+}
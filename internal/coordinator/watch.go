@@ -0,0 +1,53 @@
+package coordinator
+
+import "github.com/internal/indexer/pkg/types"
+
+// watchList is the set of watch_addresses for a chain, normalized for comparison.
+// A nil watchList keeps everything.
+type watchList map[string]struct{}
+
+func newWatchList(chainID types.ChainID, addrs []string) watchList {
+	if len(addrs) == 0 {
+		return nil
+	}
+	w := make(watchList, len(addrs))
+	for _, a := range types.NormalizeAddresses(chainID, addrs) {
+		w[a] = struct{}{}
+	}
+	return w
+}
+
+func (w watchList) has(addr string) bool {
+	_, ok := w[addr]
+	return ok
+}
+
+// filterTxs keeps the transactions whose from or to address is watched, in place
+func (w watchList) filterTxs(txs []types.Transaction) []types.Transaction {
+	if w == nil {
+		return txs
+	}
+	n := 0
+	for _, tx := range txs {
+		if w.has(tx.FromAddr) || w.has(tx.ToAddr) {
+			txs[n] = tx
+			n++
+		}
+	}
+	return txs[:n]
+}
+
+// filterTransfers keeps the token transfers whose from or to address is watched, in place
+func (w watchList) filterTransfers(transfers []types.TokenTransfer) []types.TokenTransfer {
+	if w == nil {
+		return transfers
+	}
+	n := 0
+	for _, t := range transfers {
+		if w.has(t.FromAddr) || w.has(t.ToAddr) {
+			transfers[n] = t
+			n++
+		}
+	}
+	return transfers[:n]
+}
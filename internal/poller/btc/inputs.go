@@ -0,0 +1,135 @@
+package btc
+
+import (
+	"context"
+	"math"
+)
+
+// rawTxBatchSize bounds how many getrawtransaction calls share one batch request
+const rawTxBatchSize = 100
+
+// prevout is a transaction output as seen by the input that spends it
+type prevout struct {
+	addr  string
+	value int64 // Satoshis
+}
+
+// SetMaxInputLookups enables resolving transaction inputs to the outputs they spend,
+// so FromAddr (the first input's address) and Fee are populated. Up to n previous
+// transactions per block are fetched with getrawtransaction, which needs a node
+// running with -txindex; inputs past the cap, or whose lookup fails, stay unresolved.
+// Zero or less disables resolution (the default).
+func (p *Poller) SetMaxInputLookups(n int) {
+	p.maxInputLookups = max(n, 0)
+}
+
+// btcToSats converts a BTC amount as returned by the RPC to satoshis
+func btcToSats(value float64) int64 {
+	return int64(math.Round(value * 1e8))
+}
+
+// txOutputs returns a decoded transaction's outputs in vout order
+func txOutputs(txMap map[string]interface{}) []prevout {
+	vouts, _ := txMap["vout"].([]interface{})
+	outs := make([]prevout, 0, len(vouts))
+	for _, vout := range vouts {
+		var out prevout
+		if voutMap, ok := vout.(map[string]interface{}); ok {
+			if value, ok := voutMap["value"].(float64); ok {
+				out.value = btcToSats(value)
+			}
+			if scriptPubKey, ok := voutMap["scriptPubKey"].(map[string]interface{}); ok {
+				out.addr, _ = scriptPubKey["address"].(string)
+			}
+		}
+		outs = append(outs, out)
+	}
+	return outs
+}
+
+// blockInputs returns the non-coinbase inputs of a block's transactions, and the
+// block's own outputs by txid, which inputs later in the block may spend
+func blockInputs(blockResp interface{}) ([]map[string]interface{}, map[string][]prevout) {
+	blockMap, _ := blockResp.(map[string]interface{})
+	txsRaw, _ := blockMap["tx"].([]interface{})
+
+	var vins []map[string]interface{}
+	outputs := make(map[string][]prevout, len(txsRaw))
+	for _, txRaw := range txsRaw {
+		txMap, ok := txRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if txid, ok := txMap["txid"].(string); ok {
+			outputs[txid] = txOutputs(txMap)
+		}
+		txVins, _ := txMap["vin"].([]interface{})
+		for _, vin := range txVins {
+			vinMap, ok := vin.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, isCoinbase := vinMap["coinbase"]; isCoinbase {
+				continue
+			}
+			vins = append(vins, vinMap)
+		}
+	}
+	return vins, outputs
+}
+
+// spentOutput returns the output an input spends, if it was resolved
+func spentOutput(vinMap map[string]interface{}, spent map[string][]prevout) (prevout, bool) {
+	txid, _ := vinMap["txid"].(string)
+	vout, ok := vinMap["vout"].(float64)
+	outs := spent[txid]
+	if !ok || vout < 0 || int(vout) >= len(outs) {
+		return prevout{}, false
+	}
+	return outs[int(vout)], true
+}
+
+// resolveInputs returns the outputs of the transactions vins spend from, by txid.
+// known holds outputs already available (the block's own transactions) and is
+// extended in place. Each other previous transaction is fetched once with batched
+// getrawtransaction calls, up to maxInputLookups per call; lookups past the cap are
+// skipped and lookups the node rejects (e.g. without -txindex) are left out, so
+// their inputs stay unresolved. A failed batch request is returned as an error.
+func (p *Poller) resolveInputs(ctx context.Context, vins []map[string]interface{}, known map[string][]prevout) (map[string][]prevout, error) {
+	var missing []string
+	queued := make(map[string]bool)
+	for _, vin := range vins {
+		txid, _ := vin["txid"].(string)
+		if txid == "" || queued[txid] {
+			continue
+		}
+		if _, ok := known[txid]; ok {
+			continue
+		}
+		if len(missing) >= p.maxInputLookups {
+			break
+		}
+		queued[txid] = true
+		missing = append(missing, txid)
+	}
+
+	for start := 0; start < len(missing); start += rawTxBatchSize {
+		batch := missing[start:min(start+rawTxBatchSize, len(missing))]
+		params := make([][]interface{}, len(batch))
+		for i, txid := range batch {
+			params[i] = []interface{}{txid, true}
+		}
+		results, err := p.rpcBatch(ctx, "getrawtransaction", params)
+		if err != nil {
+			return nil, err
+		}
+		for i, r := range results {
+			txMap, ok := r.Result.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			known[batch[i]] = txOutputs(txMap)
+		}
+	}
+	return known, nil
+}
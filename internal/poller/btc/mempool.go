@@ -0,0 +1,243 @@
+package btc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/internal/indexer/internal/api/cache"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// DefaultMempoolDedupeWindow is how long a pending tx stays listed after it was last seen
+const DefaultMempoolDedupeWindow = 30 * time.Second
+
+// maxPendingTxs caps the number of pending txs stored in the cache
+const maxPendingTxs = 50
+
+// mempoolPollInterval is longer than the ETH one: a verbose getrawmempool lists
+// every entry of the node's mempool, which runs to several MB when it is busy
+const mempoolPollInterval = 10 * time.Second
+
+// mempoolCacheTTL outlives a few missed polls before the list expires
+const mempoolCacheTTL = 30 * time.Second
+
+// confirmedLookback caps how many blocks back from the best block confirmedTxIDs
+// walks looking for one it already checked
+const confirmedLookback = 6
+
+// pendingTx is the cached summary of a pending transaction, in the same shape as
+// the ETH mempool poller's. A mempool entry carries no addresses or amounts, so
+// only the txid and the time the node accepted it are filled in.
+type pendingTx struct {
+	Hash      string    `json:"hash"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Value     string    `json:"value"`
+	FirstSeen time.Time `json:"first_seen"`
+	lastSeen  time.Time
+}
+
+// MempoolPoller polls the node's mempool for pending transactions
+type MempoolPoller struct {
+	poller *Poller // Issues the RPC calls, with its auth and endpoint failover
+	cache  cache.Cache
+	logger *slog.Logger
+	quit   chan struct{}
+
+	// Rolling set of recently seen pending txs (only touched by the poll loop)
+	dedupeWindow time.Duration
+	seen         map[string]*pendingTx
+	checked      []string // Blocks whose txids were dropped from seen, most recent last
+}
+
+// NewMempoolPoller creates a new MempoolPoller sending its RPC calls through p
+func NewMempoolPoller(p *Poller, cache cache.Cache, logger *slog.Logger) *MempoolPoller {
+	return &MempoolPoller{
+		poller:       p,
+		cache:        cache,
+		logger:       logger.With("component", "mempool_poller", "chain", types.ChainBTC),
+		quit:         make(chan struct{}),
+		dedupeWindow: DefaultMempoolDedupeWindow,
+		seen:         make(map[string]*pendingTx),
+	}
+}
+
+// SetDedupeWindow sets how long txs that drop out of the mempool stay listed.
+// Zero or negative disables merging: each poll replaces the list.
+func (p *MempoolPoller) SetDedupeWindow(d time.Duration) {
+	p.dedupeWindow = d
+}
+
+// Start begins polling for pending transactions
+func (p *MempoolPoller) Start() {
+	ticker := time.NewTicker(mempoolPollInterval)
+	defer ticker.Stop()
+
+	p.logger.Info("Starting Mempool Poller")
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.pollMempool(); err != nil {
+				p.logger.Error("Failed to poll mempool", "error", err)
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// Stop stops the poller
+func (p *MempoolPoller) Stop() {
+	close(p.quit)
+}
+
+func (p *MempoolPoller) pollMempool() error {
+	ctx, cancel := context.WithTimeout(context.Background(), mempoolPollInterval)
+	defer cancel()
+
+	resp, err := p.poller.rpcCall(ctx, "getrawmempool", []interface{}{true})
+	if err != nil {
+		return fmt.Errorf("getting mempool: %w", err)
+	}
+	entries, ok := resp.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected response type for getrawmempool: %T", resp)
+	}
+	current := newestEntries(entries, maxPendingTxs)
+
+	var txs []pendingTx
+	if p.dedupeWindow > 0 {
+		mined, err := p.confirmedTxIDs(ctx)
+		if err != nil {
+			// Not fatal: stale entries still expire after the window
+			p.logger.Debug("failed to fetch confirmed txids", "error", err)
+		}
+		txs = p.merge(current, mined, time.Now())
+	} else {
+		txs = current
+	}
+
+	if err := p.cache.Set(ctx, cache.MempoolKey(string(types.ChainBTC)), txs, mempoolCacheTTL); err != nil {
+		return fmt.Errorf("cache set: %w", err)
+	}
+	return nil
+}
+
+// newestEntries normalizes verbose getrawmempool entries into pending txs and
+// returns the limit most recently accepted, newest first
+func newestEntries(entries map[string]interface{}, limit int) []pendingTx {
+	txs := make([]pendingTx, 0, len(entries))
+	for txid, e := range entries {
+		tx := pendingTx{Hash: txid}
+		if entry, ok := e.(map[string]interface{}); ok {
+			if t, ok := entry["time"].(float64); ok {
+				tx.FirstSeen = time.Unix(int64(t), 0).UTC()
+			}
+		}
+		txs = append(txs, tx)
+	}
+	sortPending(txs)
+	if len(txs) > limit {
+		txs = txs[:limit]
+	}
+	return txs
+}
+
+// sortPending orders txs newest first, by txid within the same second
+func sortPending(txs []pendingTx) {
+	sort.Slice(txs, func(i, j int) bool {
+		if !txs[i].FirstSeen.Equal(txs[j].FirstSeen) {
+			return txs[i].FirstSeen.After(txs[j].FirstSeen)
+		}
+		return txs[i].Hash < txs[j].Hash
+	})
+}
+
+// merge folds the current pending txs into the rolling set, drops confirmed and
+// expired entries, and returns the newest maxPendingTxs entries
+func (p *MempoolPoller) merge(current []pendingTx, mined map[string]bool, now time.Time) []pendingTx {
+	for _, tx := range current {
+		if existing, ok := p.seen[tx.Hash]; ok {
+			existing.lastSeen = now
+			continue
+		}
+		if tx.FirstSeen.IsZero() {
+			tx.FirstSeen = now
+		}
+		tx.lastSeen = now
+		p.seen[tx.Hash] = &tx
+	}
+
+	list := make([]pendingTx, 0, len(p.seen))
+	for hash, tx := range p.seen {
+		if mined[hash] || now.Sub(tx.lastSeen) > p.dedupeWindow {
+			delete(p.seen, hash)
+			continue
+		}
+		list = append(list, *tx)
+	}
+	sortPending(list)
+
+	// Keep the cap on both the stored list and the in-memory set
+	if len(list) > maxPendingTxs {
+		for _, tx := range list[maxPendingTxs:] {
+			delete(p.seen, tx.Hash)
+		}
+		list = list[:maxPendingTxs]
+	}
+	return list
+}
+
+// confirmedTxIDs returns the txids of the blocks not checked since the last call,
+// walking back from the best block to the first one it checked (at most
+// confirmedLookback blocks), so blocks that were best only between two polls, or
+// that a reorg put in place of checked ones, are not missed. Txs confirmed in them
+// leave the mempool and are not listed again, so each block only needs checking
+// once. On error, the txids of the blocks checked so far are still returned.
+func (p *MempoolPoller) confirmedTxIDs(ctx context.Context) (map[string]bool, error) {
+	resp, err := p.poller.rpcCall(ctx, "getbestblockhash", nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting best block hash: %w", err)
+	}
+	hash, ok := resp.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response type for getbestblockhash: %T", resp)
+	}
+
+	// Txs listed before the first check are still in the mempool, so the first
+	// call only needs the best block
+	lookback := confirmedLookback
+	if len(p.checked) == 0 {
+		lookback = 1
+	}
+
+	mined := make(map[string]bool)
+	for i := 0; i < lookback && hash != "" && !slices.Contains(p.checked, hash); i++ {
+		resp, err = p.poller.rpcCall(ctx, "getblock", []interface{}{hash, verbosityTxIDs})
+		if err != nil {
+			return mined, fmt.Errorf("getting block %s: %w", hash, err)
+		}
+		blockMap, ok := resp.(map[string]interface{})
+		if !ok {
+			return mined, fmt.Errorf("unexpected response type for getblock: %T", resp)
+		}
+		txids, _ := blockMap["tx"].([]interface{})
+		for _, id := range txids {
+			if s, ok := id.(string); ok {
+				mined[s] = true
+			}
+		}
+
+		p.checked = append(p.checked, hash)
+		if len(p.checked) > confirmedLookback {
+			p.checked = p.checked[len(p.checked)-confirmedLookback:]
+		}
+		hash, _ = blockMap["previousblockhash"].(string)
+	}
+	return mined, nil
+}
@@ -0,0 +1,117 @@
+package btc
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/internal/indexer/internal/api/cache"
+	"github.com/internal/indexer/internal/api/cache/cachetest"
+)
+
+func TestMempoolPoller_Poll(t *testing.T) {
+	mempool := map[string]interface{}{
+		"aa": map[string]interface{}{"vsize": 141.0, "time": 1700000010.0},
+		"bb": map[string]interface{}{"vsize": 225.0, "time": 1700000000.0},
+	}
+	best := "b1"
+	blocks := map[string]map[string]interface{}{
+		"b1": {"hash": "b1", "previousblockhash": "b0", "tx": []interface{}{"cc"}},
+	}
+	blockCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var result interface{}
+		switch req.Method {
+		case "getrawmempool":
+			if len(req.Params) != 1 || req.Params[0] != true {
+				t.Errorf("expected verbose getrawmempool, got params %v", req.Params)
+			}
+			result = mempool
+		case "getbestblockhash":
+			result = best
+		case "getblock":
+			blockCalls++
+			result = blocks[req.Params[0].(string)]
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+	}))
+	defer server.Close()
+
+	c := cachetest.Map{}
+	mp := NewMempoolPoller(New(server.URL, 1), c, slog.New(slog.DiscardHandler))
+	list := func() []pendingTx {
+		t.Helper()
+		if err := mp.pollMempool(); err != nil {
+			t.Fatalf("pollMempool: %v", err)
+		}
+		var txs []pendingTx
+		if found, err := c.Get(context.Background(), cache.MempoolKey("btc"), &txs); !found || err != nil {
+			t.Fatalf("expected the list under mempool:btc:latest, got found=%v err=%v", found, err)
+		}
+		return txs
+	}
+
+	txs := list()
+	if len(txs) != 2 || txs[0].Hash != "aa" || txs[1].Hash != "bb" {
+		t.Fatalf("expected aa then bb, newest first, got %+v", txs)
+	}
+	if !txs[1].FirstSeen.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected first seen from the mempool entry time, got %v", txs[1].FirstSeen)
+	}
+
+	// bb is confirmed in a new best block; aa is evicted but stays within the window
+	best = "b2"
+	blocks["b2"] = map[string]interface{}{"hash": "b2", "previousblockhash": "b1", "tx": []interface{}{"bb"}}
+	mempool = map[string]interface{}{}
+	txs = list()
+	if len(txs) != 1 || txs[0].Hash != "aa" {
+		t.Errorf("expected only aa after bb was confirmed, got %+v", txs)
+	}
+
+	// The same best block is not fetched again
+	list()
+	if blockCalls != 2 {
+		t.Errorf("expected 2 getblock calls, got %d", blockCalls)
+	}
+
+	// aa is confirmed in b3, which b4 replaced as best before the next poll
+	best = "b4"
+	blocks["b3"] = map[string]interface{}{"hash": "b3", "previousblockhash": "b2", "tx": []interface{}{"aa"}}
+	blocks["b4"] = map[string]interface{}{"hash": "b4", "previousblockhash": "b3", "tx": []interface{}{}}
+	if txs = list(); len(txs) != 0 {
+		t.Errorf("expected aa to be dropped once confirmed in b3, got %+v", txs)
+	}
+	if blockCalls != 4 {
+		t.Errorf("expected b4 and b3 to be fetched back to the checked b2, got %d getblock calls", blockCalls)
+	}
+}
+
+func TestMempoolPoller_MergeCap(t *testing.T) {
+	mp := NewMempoolPoller(New("http://localhost:8332", 1), nil, slog.New(slog.DiscardHandler))
+
+	entries := make(map[string]interface{})
+	for i := 0; i < maxPendingTxs+10; i++ {
+		entries[string(rune('a'+i%26))+string(rune('a'+i/26))] = map[string]interface{}{"time": float64(1700000000 + i)}
+	}
+	current := newestEntries(entries, maxPendingTxs)
+	if len(current) != maxPendingTxs || !current[0].FirstSeen.Equal(time.Unix(1700000000+maxPendingTxs+9, 0)) {
+		t.Fatalf("expected the newest %d entries, newest first, got %d starting at %v", maxPendingTxs, len(current), current[0].FirstSeen)
+	}
+
+	// Older txs seen earlier are pushed out of the set by newer ones
+	mp.merge(current, nil, time.Now())
+	list := mp.merge(newestEntries(map[string]interface{}{"zz": map[string]interface{}{"time": 1800000000.0}}, maxPendingTxs), nil, time.Now())
+	if len(list) != maxPendingTxs || list[0].Hash != "zz" || len(mp.seen) != maxPendingTxs {
+		t.Errorf("expected list and set capped at %d with zz first, got %d (set %d)", maxPendingTxs, len(list), len(mp.seen))
+	}
+}
@@ -5,32 +5,139 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/internal/indexer/internal/poller"
 	"github.com/internal/indexer/pkg/types"
 )
 
 // Poller implements the ChainPoller interface for Bitcoin
 type Poller struct {
-	rpcURL    string
-	batchSize int
-	client    *http.Client
+	rpcURL           string
+	endpoints        *poller.Endpoints // rpcURL plus any fallbacks (see SetFallbackRPCURLs)
+	batchSize        int
+	maxResponseSize  int64 // Largest RPC response body read before failing
+	skipTxs          bool  // Fetch blocks without transaction data (see SetSkipTransactions)
+	maxInputLookups  int   // Previous transactions fetched per block to resolve inputs (0 = disabled)
+	fetchConcurrency int   // getblock batches, and blocks built, at once
+	client           *http.Client
+
+	// cutShort is the block failure that ended the last Poll's batch early, nil if
+	// none (see PollCutShort). Poll is only called from the chain's coordinator.
+	cutShort error
+
+	// RPC authentication (see SetRPCAuth)
+	rpcUser     string
+	rpcPassword string
+	cookiePath  string
+}
+
+// getblock verbosity levels
+const (
+	verbosityTxIDs = 1 // Block header fields with txids only
+	verbosityFull  = 2 // Block with decoded transactions
+)
+
+// getblockBatchSize bounds how many blocks one batched getblock call returns, as
+// a decoded full block can run to several MB of JSON
+const getblockBatchSize = 10
+
+// newEndpoints creates the poller's endpoint set. bitcoind answers failed calls with
+// HTTP 500, so that status is an RPC error rather than a reason to fail over.
+func newEndpoints(urls []string, cooldown time.Duration, logger *slog.Logger) *poller.Endpoints {
+	e := poller.NewEndpoints(urls, cooldown, logger)
+	e.TreatAsAnswer(http.StatusInternalServerError)
+	return e
 }
 
 // New creates a new BTC poller
 func New(rpcURL string, batchSize int) *Poller {
 	return &Poller{
-		rpcURL:    rpcURL,
-		batchSize: batchSize,
+		rpcURL:           rpcURL,
+		endpoints:        newEndpoints([]string{rpcURL}, 0, nil),
+		batchSize:        batchSize,
+		maxResponseSize:  poller.DefaultMaxResponseSize,
+		fetchConcurrency: poller.DefaultFetchConcurrency,
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
 	}
 }
 
+// SetMaxResponseSize bounds the RPC response bodies read by rpcCall; larger
+// responses fail with poller.ErrResponseTooLarge. Zero or less keeps the default.
+func (p *Poller) SetMaxResponseSize(n int64) {
+	if n > 0 {
+		p.maxResponseSize = n
+	}
+}
+
+// SetFallbackRPCURLs adds endpoints that requests fail over to when the active one
+// errors, returns a 5xx or rate-limits; an endpoint that failed is skipped for
+// cooldown (zero or less uses the default) while others are available. Failovers
+// are logged to logger.
+func (p *Poller) SetFallbackRPCURLs(urls []string, cooldown time.Duration, logger *slog.Logger) {
+	p.endpoints = newEndpoints(append([]string{p.rpcURL}, urls...), cooldown, logger)
+}
+
+// RPCEndpoint reports the index of the active RPC endpoint (0 = rpc_url) and how
+// many times requests have failed over
+func (p *Poller) RPCEndpoint() (active int, failovers uint64) {
+	return p.endpoints.Active(), p.endpoints.Failovers()
+}
+
+// SetFetchConcurrency sets how many getblock batches of a poll are fetched, and
+// how many blocks are built (resolving inputs), at once. Zero or less keeps the default.
+func (p *Poller) SetFetchConcurrency(n int) {
+	if n > 0 {
+		p.fetchConcurrency = n
+	}
+}
+
+// SetRPCAuth sets Basic Auth credentials for the node's RPC interface. With
+// cookiePath set, credentials are read from bitcoind's .cookie file instead, on
+// every request, since bitcoind writes a new cookie each time it starts. Empty
+// values leave the request unauthenticated (credentials in the URL still apply).
+func (p *Poller) SetRPCAuth(user, password, cookiePath string) {
+	p.rpcUser = user
+	p.rpcPassword = password
+	p.cookiePath = cookiePath
+}
+
+// setAuth adds the configured RPC credentials to req
+func (p *Poller) setAuth(req *http.Request) error {
+	if p.cookiePath != "" {
+		cookie, err := os.ReadFile(p.cookiePath)
+		if err != nil {
+			return fmt.Errorf("reading RPC cookie file: %w", err)
+		}
+		// The cookie is "__cookie__:<password>"
+		user, password, ok := strings.Cut(strings.TrimSpace(string(cookie)), ":")
+		if !ok {
+			return fmt.Errorf("malformed RPC cookie file %s", p.cookiePath)
+		}
+		req.SetBasicAuth(user, password)
+		return nil
+	}
+	if p.rpcUser != "" {
+		req.SetBasicAuth(p.rpcUser, p.rpcPassword)
+	}
+	return nil
+}
+
+// SetSkipTransactions fetches blocks with getblock verbosity 1, which lists txids
+// instead of full transactions and is a fraction of the payload. No transactions
+// are indexed, and the miner and tx_count are unknown, since both come from the
+// transaction data. Disabled (verbosity 2) by default.
+func (p *Poller) SetSkipTransactions(skip bool) {
+	p.skipTxs = skip
+}
+
 // ChainID returns the chain identifier
 func (p *Poller) ChainID() types.ChainID {
 	return types.ChainBTC
@@ -58,6 +165,7 @@ func (p *Poller) Poll(ctx context.Context, lastHeight uint64) ([]types.Block, []
 		return nil, nil, err
 	}
 
+	p.cutShort = nil
 	if lastHeight >= tip {
 		return nil, nil, nil // Already at tip
 	}
@@ -68,31 +176,121 @@ func (p *Poller) Poll(ctx context.Context, lastHeight uint64) ([]types.Block, []
 		endHeight = tip
 	}
 
+	blocks, txs, err := p.getBlocks(ctx, startHeight, endHeight)
+	if err != nil && len(blocks) > 0 && ctx.Err() == nil {
+		// Keep the blocks before the failed one; the next poll retries from there
+		p.cutShort = err
+		return blocks, txs, nil
+	}
+	return blocks, txs, err
+}
+
+// PollCutShort returns the block failure that ended the last Poll's batch before
+// the tip or batch size, or nil if it was not cut short
+func (p *Poller) PollCutShort() error {
+	return p.cutShort
+}
+
+// getBlocks fetches the blocks from..to with one batched getblockhash call and
+// batched getblock calls, instead of two round-trips per block. A block that
+// fails ends the result at the block before it, so the blocks returned stay
+// contiguous; the failure is returned with them (with no blocks when the first
+// block failed).
+func (p *Poller) getBlocks(ctx context.Context, from, to uint64) ([]types.Block, []types.Transaction, error) {
+	hashParams := make([][]interface{}, 0, to-from+1)
+	for height := from; height <= to; height++ {
+		hashParams = append(hashParams, []interface{}{height})
+	}
+	hashResults, err := p.rpcBatch(ctx, "getblockhash", hashParams)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting block hashes: %w", err)
+	}
+	hashes := make([]string, 0, len(hashResults))
+	var hashErr error
+	for i, r := range hashResults {
+		hash, ok := r.Result.(string)
+		if r.Err == nil && !ok {
+			r.Err = fmt.Errorf("unexpected response type for getblockhash: %T", r.Result)
+		}
+		if r.Err != nil {
+			hashErr = fmt.Errorf("getting block %d: getting block hash: %w", from+uint64(i), r.Err)
+			if i == 0 {
+				return nil, nil, hashErr
+			}
+			break
+		}
+		hashes = append(hashes, hash)
+	}
+
+	verbosity := verbosityFull
+	if p.skipTxs {
+		verbosity = verbosityTxIDs
+	}
+
+	// Fetch the blocks in chunks of getblockBatchSize, then build them, each step
+	// running fetchConcurrency at a time and stopping at the first failure in height order
+	chunks, chunkErr := poller.FetchOrdered(ctx, (len(hashes)+getblockBatchSize-1)/getblockBatchSize, p.fetchConcurrency,
+		func(ctx context.Context, c int) ([]rpcResult, error) {
+			chunk := hashes[c*getblockBatchSize : min((c+1)*getblockBatchSize, len(hashes))]
+			params := make([][]interface{}, len(chunk))
+			for i, hash := range chunk {
+				params[i] = []interface{}{hash, verbosity}
+			}
+			return p.rpcBatch(ctx, "getblock", params)
+		})
+	var raw []rpcResult
+	for _, chunk := range chunks {
+		raw = append(raw, chunk...)
+	}
+
+	built, buildErr := poller.FetchOrdered(ctx, len(raw), p.fetchConcurrency,
+		func(ctx context.Context, i int) (builtBlock, error) {
+			if raw[i].Err != nil {
+				return builtBlock{}, fmt.Errorf("getting block data: %w", raw[i].Err)
+			}
+			block, txs, err := p.buildBlock(ctx, from+uint64(i), hashes[i], raw[i].Result)
+			return builtBlock{block: block, txs: txs}, err
+		})
+
 	var blocks []types.Block
 	var allTxs []types.Transaction
+	for _, b := range built {
+		blocks = append(blocks, *b.block)
+		allTxs = append(allTxs, b.txs...)
+	}
 
-	for height := startHeight; height <= endHeight; height++ {
-		select {
-		case <-ctx.Done():
-			return nil, nil, ctx.Err()
-		default:
+	err = buildErr
+	if err == nil && chunkErr != nil {
+		err = fmt.Errorf("getting block data: %w", chunkErr)
+	}
+	if err != nil {
+		err = fmt.Errorf("getting block %d: %w", from+uint64(len(blocks)), err)
+		if len(blocks) == 0 || ctx.Err() != nil {
+			return nil, nil, err
 		}
+		return blocks, allTxs, err
+	}
+	return blocks, allTxs, hashErr
+}
 
-		block, txs, err := p.getBlockByHeight(ctx, height)
-		if err != nil {
-			return nil, nil, fmt.Errorf("getting block %d: %w", height, err)
-		}
+// builtBlock is one block of a poll batch with its transactions
+type builtBlock struct {
+	block *types.Block
+	txs   []types.Transaction
+}
 
-		blocks = append(blocks, *block)
-		allTxs = append(allTxs, txs...)
+// PollGenesis fetches the genesis block (height 0), for chains indexed from the start
+func (p *Poller) PollGenesis(ctx context.Context) ([]types.Block, []types.Transaction, error) {
+	block, txs, err := p.getBlockByHeight(ctx, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting genesis block: %w", err)
 	}
-
-	return blocks, allTxs, nil
+	return []types.Block{*block}, txs, nil
 }
 
 // GetBlockByHash fetches a block by its hash
 func (p *Poller) GetBlockByHash(ctx context.Context, hash string) (*types.Block, error) {
-	resp, err := p.rpcCall(ctx, "getblock", []interface{}{hash, 2}) // verbosity=2 for full tx data
+	resp, err := p.fetchBlock(ctx, hash)
 	if err != nil {
 		return nil, fmt.Errorf("getting block by hash: %w", err)
 	}
@@ -100,30 +298,58 @@ func (p *Poller) GetBlockByHash(ctx context.Context, hash string) (*types.Block,
 	return p.parseBlock(resp)
 }
 
-func (p *Poller) getBlockByHeight(ctx context.Context, height uint64) (*types.Block, []types.Transaction, error) {
-	// Get block hash at height
-	hashResp, err := p.rpcCall(ctx, "getblockhash", []interface{}{height})
+// GetCanonicalHash returns the hash of the main-chain block at height
+func (p *Poller) GetCanonicalHash(ctx context.Context, height uint64) (string, error) {
+	resp, err := p.rpcCall(ctx, "getblockhash", []interface{}{height})
 	if err != nil {
-		return nil, nil, fmt.Errorf("getting block hash: %w", err)
+		return "", fmt.Errorf("getting block hash: %w", err)
 	}
 
-	hash, ok := hashResp.(string)
+	hash, ok := resp.(string)
 	if !ok {
-		return nil, nil, fmt.Errorf("unexpected response type for getblockhash: %T", hashResp)
+		return "", fmt.Errorf("unexpected response type for getblockhash: %T", resp)
+	}
+	return hash, nil
+}
+
+func (p *Poller) getBlockByHeight(ctx context.Context, height uint64) (*types.Block, []types.Transaction, error) {
+	hash, err := p.GetCanonicalHash(ctx, height)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Get block with transactions
-	blockResp, err := p.rpcCall(ctx, "getblock", []interface{}{hash, 2})
+	blockResp, err := p.fetchBlock(ctx, hash)
 	if err != nil {
 		return nil, nil, fmt.Errorf("getting block data: %w", err)
 	}
 
+	return p.buildBlock(ctx, height, hash, blockResp)
+}
+
+// buildBlock parses the getblock response for the block at height with hash,
+// resolving inputs when enabled, and checks it is the block that was asked for
+func (p *Poller) buildBlock(ctx context.Context, height uint64, hash string, blockResp interface{}) (*types.Block, []types.Transaction, error) {
 	block, err := p.parseBlock(blockResp)
 	if err != nil {
 		return nil, nil, err
 	}
+	// getblock must return the block getblockhash named, at the height we asked for
+	if block.Hash != hash {
+		return nil, nil, fmt.Errorf("requested block %s, RPC returned block %s", hash, block.Hash)
+	}
+	if block.Height != height {
+		return nil, nil, fmt.Errorf("requested block %d, RPC returned block %d", height, block.Height)
+	}
 
-	txs, err := p.parseTransactions(blockResp, block)
+	var spent map[string][]prevout
+	if p.maxInputLookups > 0 && !p.skipTxs {
+		vins, outputs := blockInputs(blockResp)
+		if spent, err = p.resolveInputs(ctx, vins, outputs); err != nil {
+			return nil, nil, fmt.Errorf("resolving inputs: %w", err)
+		}
+	}
+
+	txs, err := p.parseTransactions(blockResp, block, spent)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -131,6 +357,17 @@ func (p *Poller) getBlockByHeight(ctx context.Context, height uint64) (*types.Bl
 	return block, txs, nil
 }
 
+// fetchBlock calls getblock at the configured verbosity. At verbosityTxIDs the
+// response's "tx" is a list of txid strings, which parseTransactions and
+// coinbaseAddress skip.
+func (p *Poller) fetchBlock(ctx context.Context, hash string) (interface{}, error) {
+	verbosity := verbosityFull
+	if p.skipTxs {
+		verbosity = verbosityTxIDs
+	}
+	return p.rpcCall(ctx, "getblock", []interface{}{hash, verbosity})
+}
+
 func (p *Poller) parseBlock(resp interface{}) (*types.Block, error) {
 	blockMap, ok := resp.(map[string]interface{})
 	if !ok {
@@ -143,6 +380,10 @@ func (p *Poller) parseBlock(resp interface{}) (*types.Block, error) {
 	hash, _ := blockMap["hash"].(string)
 	prevHash, _ := blockMap["previousblockhash"].(string)
 	timestamp, _ := blockMap["time"].(float64)
+	// Absent from some older nodes and non-Core backends; stored as NULL
+	size, _ := blockMap["size"].(float64)
+	strippedSize, _ := blockMap["strippedsize"].(float64)
+	weight, _ := blockMap["weight"].(float64)
 
 	return &types.Block{
 		ChainID:    types.ChainBTC,
@@ -151,11 +392,52 @@ func (p *Poller) parseBlock(resp interface{}) (*types.Block, error) {
 		ParentHash: prevHash,
 		Timestamp:  time.Unix(int64(timestamp), 0),
 		Status:     types.StatusPending,
+		Miner:      types.NormalizeAddress(types.ChainBTC, coinbaseAddress(blockMap)),
 		RawData:    rawData,
+
+		Size:         int(size),
+		StrippedSize: int(strippedSize),
+		Weight:       int(weight),
 	}, nil
 }
 
-func (p *Poller) parseTransactions(blockResp interface{}, block *types.Block) ([]types.Transaction, error) {
+// coinbaseAddress returns the first address paid by the block's coinbase
+// transaction, which identifies the miner or pool. Outputs without an address
+// (e.g. the OP_RETURN witness commitment) are skipped.
+func coinbaseAddress(blockMap map[string]interface{}) string {
+	txsRaw, ok := blockMap["tx"].([]interface{})
+	if !ok || len(txsRaw) == 0 {
+		return ""
+	}
+	coinbase, ok := txsRaw[0].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	vouts, _ := coinbase["vout"].([]interface{})
+	for _, vout := range vouts {
+		voutMap, ok := vout.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		scriptPubKey, ok := voutMap["scriptPubKey"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if addr, ok := scriptPubKey["address"].(string); ok && addr != "" {
+			return addr
+		}
+	}
+	return ""
+}
+
+// parseTransactions converts the block's transactions. spent holds the outputs of
+// previous transactions by txid (see resolveInputs); FromAddr is the first input's
+// address. Fee is totalIn - totalOut in satoshis, "0" for coinbase transactions,
+// and empty (stored as NULL) when any input is unresolved. With one sender and one
+// recipient per transaction, address totals derived from these are approximate
+// (other inputs and outputs, and change, are not attributed), so the API does not
+// serve BTC balances.
+func (p *Poller) parseTransactions(blockResp interface{}, block *types.Block, spent map[string][]prevout) ([]types.Transaction, error) {
 	blockMap, ok := blockResp.(map[string]interface{})
 	if !ok {
 		return nil, nil
@@ -181,28 +463,37 @@ func (p *Poller) parseTransactions(blockResp interface{}, block *types.Block) ([
 		var totalIn, totalOut int64
 
 		// Parse vout (outputs)
-		if vouts, ok := txMap["vout"].([]interface{}); ok {
-			for _, vout := range vouts {
-				if voutMap, ok := vout.(map[string]interface{}); ok {
-					if value, ok := voutMap["value"].(float64); ok {
-						totalOut += int64(value * 1e8) // Convert BTC to satoshi
-					}
-				}
-			}
+		for _, out := range txOutputs(txMap) {
+			totalOut += out.value
 		}
 
-		// Parse vin (inputs) - note: coinbase tx has no vin value
+		// Parse vin (inputs) - note: coinbase tx has no vin value. Regular inputs
+		// carry only the outpoint they spend, resolved through spent when enabled.
 		var fromAddr string
+		isCoinbase := false
+		resolved := false
 		if vins, ok := txMap["vin"].([]interface{}); ok {
-			for _, vin := range vins {
-				if vinMap, ok := vin.(map[string]interface{}); ok {
-					// Check if coinbase
-					if _, isCoinbase := vinMap["coinbase"]; isCoinbase {
-						fromAddr = "coinbase"
-						continue
-					}
-					// For regular inputs, we'd need to look up the previous tx
-					// For simplicity, we'll leave fromAddr empty for non-coinbase
+			resolved = len(vins) > 0
+			for j, vin := range vins {
+				vinMap, ok := vin.(map[string]interface{})
+				if !ok {
+					resolved = false
+					continue
+				}
+				// Check if coinbase
+				if _, ok := vinMap["coinbase"]; ok {
+					fromAddr = "coinbase"
+					isCoinbase = true
+					continue
+				}
+				out, ok := spentOutput(vinMap, spent)
+				if !ok {
+					resolved = false
+					continue
+				}
+				totalIn += out.value
+				if j == 0 {
+					fromAddr = out.addr
 				}
 			}
 		}
@@ -219,11 +510,20 @@ func (p *Poller) parseTransactions(blockResp interface{}, block *types.Block) ([
 			}
 		}
 
-		// Fee is input - output (but we don't have input values without extra lookups)
-		// For now, we skip fee calculation for BTC
-		fee := int64(0)
-		if totalIn > totalOut {
-			fee = totalIn - totalOut
+		// The genesis coinbase output was never added to the UTXO set and can't be
+		// spent, so it neither debits "coinbase" nor credits its recipient
+		if block.Height == 0 && fromAddr == "coinbase" {
+			fromAddr, toAddr = "", ""
+		}
+
+		// Fee is input - output, known only once every input's value was resolved.
+		// A coinbase pays no fee: its outputs are the subsidy plus the block's fees.
+		var fee string
+		switch {
+		case isCoinbase:
+			fee = "0"
+		case resolved && totalIn >= totalOut:
+			fee = strconv.FormatInt(totalIn-totalOut, 10)
 		}
 
 		tx := types.Transaction{
@@ -232,10 +532,10 @@ func (p *Poller) parseTransactions(blockResp interface{}, block *types.Block) ([
 			BlockHash:   block.Hash,
 			TxHash:      txHash,
 			TxIndex:     i,
-			FromAddr:    fromAddr,
-			ToAddr:      toAddr,
+			FromAddr:    types.NormalizeAddress(types.ChainBTC, fromAddr),
+			ToAddr:      types.NormalizeAddress(types.ChainBTC, toAddr),
 			Value:       strconv.FormatInt(totalOut, 10),
-			Fee:         strconv.FormatInt(fee, 10),
+			Fee:         fee,
 			Status:      types.StatusPending,
 			RawData:     rawData,
 		}
@@ -259,43 +559,116 @@ func (p *Poller) rpcCall(ctx context.Context, method string, params interface{})
 		"params":  params,
 	}
 
-	body, err := json.Marshal(reqBody)
+	respBody, err := p.post(ctx, method, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", p.rpcURL, bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+	var rpcResp struct {
+		Result interface{} `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := p.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("making request: %w", err)
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	if rpcResp.Error != nil {
+		return nil, fmt.Errorf("RPC error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+
+	return rpcResp.Result, nil
+}
+
+// rpcResult is the outcome of one call in a batch
+type rpcResult struct {
+	Result interface{}
+	Err    error
+}
+
+// rpcBatch sends one JSON-RPC batch calling method once per params entry and
+// returns the results in the same order. Errors of individual calls are kept in
+// their rpcResult; only a failure of the whole request is returned as an error.
+func (p *Poller) rpcBatch(ctx context.Context, method string, params [][]interface{}) ([]rpcResult, error) {
+	reqBody := make([]map[string]interface{}, len(params))
+	for i, ps := range params {
+		reqBody[i] = map[string]interface{}{
+			"jsonrpc": "1.0",
+			"id":      i,
+			"method":  method,
+			"params":  ps,
+		}
+	}
+
+	respBody, err := p.post(ctx, method, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, err
 	}
 
-	var rpcResp struct {
+	var rpcResps []struct {
+		ID     int         `json:"id"`
 		Result interface{} `json:"result"`
 		Error  *struct {
 			Code    int    `json:"code"`
 			Message string `json:"message"`
 		} `json:"error"`
 	}
+	if err := json.Unmarshal(respBody, &rpcResps); err != nil {
+		return nil, fmt.Errorf("parsing %s batch response: %w", method, err)
+	}
 
-	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+	// Batch responses may arrive in any order; match them to calls by id
+	results := make([]rpcResult, len(params))
+	answered := make([]bool, len(params))
+	for _, r := range rpcResps {
+		if r.ID < 0 || r.ID >= len(results) {
+			continue
+		}
+		answered[r.ID] = true
+		if r.Error != nil {
+			results[r.ID].Err = fmt.Errorf("RPC error %d: %s", r.Error.Code, r.Error.Message)
+			continue
+		}
+		results[r.ID].Result = r.Result
 	}
+	for i := range results {
+		if !answered[i] {
+			results[i].Err = fmt.Errorf("no response to %s call in batch", method)
+		}
+	}
+	return results, nil
+}
 
-	if rpcResp.Error != nil {
-		return nil, fmt.Errorf("RPC error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
+// post sends a JSON-RPC request body (a single call or a batch) and returns the
+// response body, bounded by maxResponseSize
+func (p *Poller) post(ctx context.Context, method string, reqBody interface{}) ([]byte, error) {
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	return rpcResp.Result, nil
+	resp, err := p.endpoints.Do(ctx, func(url string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if err := p.setAuth(req); err != nil {
+			return nil, err
+		}
+		return p.client.Do(req)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := poller.ReadResponse(resp.Body, p.maxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s response: %w", method, err)
+	}
+	return respBody, nil
 }
@@ -0,0 +1,527 @@
+package btc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/internal/indexer/internal/poller"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// mockRPCServer answers getblockhash with hash and getblock with block
+func mockRPCServer(hash string, block map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var result interface{}
+		switch req.Method {
+		case "getblockhash":
+			result = hash
+		case "getblock":
+			result = block
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+	}))
+}
+
+func TestPoller_GetBlockByHeightMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		block   map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "wrong height",
+			block:   map[string]interface{}{"hash": "aa", "height": 101.0, "previousblockhash": "99", "time": 0.0, "tx": []interface{}{}},
+			wantErr: "returned block 101",
+		},
+		{
+			name:    "wrong hash",
+			block:   map[string]interface{}{"hash": "bb", "height": 100.0, "previousblockhash": "99", "time": 0.0, "tx": []interface{}{}},
+			wantErr: "returned block bb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mockRPCServer("aa", tt.block)
+			defer server.Close()
+
+			_, _, err := New(server.URL, 10).getBlockByHeight(context.Background(), 100)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("consistent", func(t *testing.T) {
+		server := mockRPCServer("aa", map[string]interface{}{"hash": "aa", "height": 100.0, "previousblockhash": "99", "time": 0.0, "tx": []interface{}{}})
+		defer server.Close()
+
+		block, _, err := New(server.URL, 10).getBlockByHeight(context.Background(), 100)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if block.Height != 100 || block.Hash != "aa" {
+			t.Errorf("unexpected block %d %s", block.Height, block.Hash)
+		}
+	})
+}
+
+func TestPoller_ParseBlockSizeWeight(t *testing.T) {
+	p := New("http://unused", 10)
+
+	block, err := p.parseBlock(map[string]interface{}{
+		"hash": "aa", "height": 800000.0, "previousblockhash": "99", "time": 0.0, "tx": []interface{}{},
+		"size": 1620000.0, "strippedsize": 790000.0, "weight": 3990000.0,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if block.Size != 1620000 || block.StrippedSize != 790000 || block.Weight != 3990000 {
+		t.Errorf("unexpected size/stripped/weight: %d/%d/%d", block.Size, block.StrippedSize, block.Weight)
+	}
+
+	// Nodes that don't report them leave the fields unknown rather than failing
+	block, err = p.parseBlock(map[string]interface{}{
+		"hash": "bb", "height": 1.0, "previousblockhash": "aa", "time": 0.0, "tx": []interface{}{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if block.Size != 0 || block.StrippedSize != 0 || block.Weight != 0 {
+		t.Errorf("expected unknown size/weight, got %d/%d/%d", block.Size, block.StrippedSize, block.Weight)
+	}
+}
+
+func TestPoller_ResponseSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":"` + strings.Repeat("a", 4096) + `"}`))
+	}))
+	defer server.Close()
+
+	p := New(server.URL, 10)
+	p.SetMaxResponseSize(1024)
+
+	if _, err := p.rpcCall(context.Background(), "getbestblockhash", nil); !errors.Is(err, poller.ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func TestCoinbaseAddress(t *testing.T) {
+	block := map[string]interface{}{
+		"tx": []interface{}{
+			map[string]interface{}{
+				"vin": []interface{}{map[string]interface{}{"coinbase": "03abcd"}},
+				"vout": []interface{}{
+					map[string]interface{}{"value": 0.0, "scriptPubKey": map[string]interface{}{"type": "nulldata"}},
+					map[string]interface{}{"value": 3.125, "scriptPubKey": map[string]interface{}{"address": "bc1qpool"}},
+				},
+			},
+		},
+	}
+	if got := coinbaseAddress(block); got != "bc1qpool" {
+		t.Errorf("expected bc1qpool, got %q", got)
+	}
+
+	if got := coinbaseAddress(map[string]interface{}{"tx": []interface{}{}}); got != "" {
+		t.Errorf("expected empty address for block without txs, got %q", got)
+	}
+}
+
+func TestPoller_PollGenesis(t *testing.T) {
+	genesis := map[string]interface{}{
+		"hash": "000000000019d6", "height": 0.0, "time": 1231006505.0, // No previousblockhash
+		"tx": []interface{}{
+			map[string]interface{}{
+				"txid": "4a5e1e4baab89f",
+				"vin":  []interface{}{map[string]interface{}{"coinbase": "04ffff001d"}},
+				"vout": []interface{}{
+					map[string]interface{}{"value": 50.0, "scriptPubKey": map[string]interface{}{"address": "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"}},
+				},
+			},
+		},
+	}
+	server := mockRPCServer("000000000019d6", genesis)
+	defer server.Close()
+
+	blocks, txs, err := New(server.URL, 10).PollGenesis(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(blocks) != 1 || blocks[0].Height != 0 || blocks[0].ParentHash != "" {
+		t.Fatalf("expected genesis block without parent, got %+v", blocks)
+	}
+	if len(txs) != 1 {
+		t.Fatalf("expected 1 tx, got %d", len(txs))
+	}
+
+	// The unspendable genesis output must not move any balance
+	tx := txs[0]
+	if tx.FromAddr != "" || tx.ToAddr != "" {
+		t.Errorf("expected genesis coinbase without addresses, got from=%q to=%q", tx.FromAddr, tx.ToAddr)
+	}
+	if tx.Value != "5000000000" {
+		t.Errorf("expected value 5000000000, got %s", tx.Value)
+	}
+}
+
+func TestPoller_SkipTransactions(t *testing.T) {
+	var verbosities []float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var result interface{}
+		switch req.Method {
+		case "getblockhash":
+			result = "aa"
+		case "getblock":
+			verbosity, _ := req.Params[1].(float64)
+			verbosities = append(verbosities, verbosity)
+			// Verbosity 1 lists txids where verbosity 2 has full transactions
+			var tx interface{} = map[string]interface{}{
+				"txid": "cb",
+				"vin":  []interface{}{map[string]interface{}{"coinbase": "03abcd"}},
+				"vout": []interface{}{map[string]interface{}{"value": 3.125, "scriptPubKey": map[string]interface{}{"address": "bc1qpool"}}},
+			}
+			if verbosity == verbosityTxIDs {
+				tx = "cb"
+			}
+			result = map[string]interface{}{"hash": "aa", "height": 100.0, "previousblockhash": "99", "time": 0.0, "tx": []interface{}{tx}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+	}))
+	defer server.Close()
+
+	p := New(server.URL, 10)
+	block, txs, err := p.getBlockByHeight(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(txs) != 1 || block.Miner != "bc1qpool" {
+		t.Fatalf("expected full block by default, got %d txs, miner %q", len(txs), block.Miner)
+	}
+
+	p.SetSkipTransactions(true)
+	block, txs, err = p.getBlockByHeight(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if block.Hash != "aa" || block.Height != 100 {
+		t.Errorf("unexpected block %d %s", block.Height, block.Hash)
+	}
+	if len(txs) != 0 || block.Miner != "" {
+		t.Errorf("expected no transactions or miner, got %d txs, miner %q", len(txs), block.Miner)
+	}
+
+	if len(verbosities) != 2 || verbosities[0] != verbosityFull || verbosities[1] != verbosityTxIDs {
+		t.Errorf("expected getblock verbosity 2 then 1, got %v", verbosities)
+	}
+}
+
+func TestPoller_ResolveInputs(t *testing.T) {
+	block := map[string]interface{}{
+		"hash": "aa", "height": 100.0, "previousblockhash": "99", "time": 0.0,
+		"tx": []interface{}{
+			map[string]interface{}{
+				"txid": "cb",
+				"vin":  []interface{}{map[string]interface{}{"coinbase": "03abcd"}},
+				"vout": []interface{}{map[string]interface{}{"value": 3.125, "scriptPubKey": map[string]interface{}{"address": "bc1qpool"}}},
+			},
+			// Spends an earlier transaction and the coinbase above
+			map[string]interface{}{
+				"txid": "t1",
+				"vin": []interface{}{
+					map[string]interface{}{"txid": "prev", "vout": 1.0},
+					map[string]interface{}{"txid": "cb", "vout": 0.0},
+				},
+				"vout": []interface{}{map[string]interface{}{"value": 4.0, "scriptPubKey": map[string]interface{}{"address": "bc1qdest"}}},
+			},
+			// Spends a transaction the node cannot find
+			map[string]interface{}{
+				"txid": "t2",
+				"vin":  []interface{}{map[string]interface{}{"txid": "unknown", "vout": 0.0}},
+				"vout": []interface{}{map[string]interface{}{"value": 0.5, "scriptPubKey": map[string]interface{}{"address": "bc1qother"}}},
+			},
+		},
+	}
+	prev := map[string]interface{}{
+		"txid": "prev",
+		"vout": []interface{}{
+			map[string]interface{}{"value": 0.1, "scriptPubKey": map[string]interface{}{"address": "bc1qchange"}},
+			map[string]interface{}{"value": 1.0, "scriptPubKey": map[string]interface{}{"address": "bc1qsender"}},
+		},
+	}
+
+	var lookups []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(string(body), "[") {
+			var calls []struct {
+				ID     int           `json:"id"`
+				Params []interface{} `json:"params"`
+			}
+			json.Unmarshal(body, &calls)
+			var resps []map[string]interface{}
+			for _, c := range calls {
+				txid, _ := c.Params[0].(string)
+				lookups = append(lookups, txid)
+				if txid == "prev" {
+					resps = append(resps, map[string]interface{}{"id": c.ID, "result": prev})
+				} else {
+					resps = append(resps, map[string]interface{}{"id": c.ID, "error": map[string]interface{}{"code": -5, "message": "No such mempool or blockchain transaction"}})
+				}
+			}
+			json.NewEncoder(w).Encode(resps)
+			return
+		}
+		var req struct {
+			Method string `json:"method"`
+		}
+		json.Unmarshal(body, &req)
+		var result interface{} = "aa"
+		if req.Method == "getblock" {
+			result = block
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+	}))
+	defer server.Close()
+
+	p := New(server.URL, 10)
+
+	// Disabled by default: inputs stay unresolved
+	_, txs, err := p.getBlockByHeight(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lookups) != 0 || txs[1].FromAddr != "" || txs[1].Fee != "" {
+		t.Fatalf("expected no resolution by default, got %d lookups, from %q, fee %s", len(lookups), txs[1].FromAddr, txs[1].Fee)
+	}
+
+	p.SetMaxInputLookups(10)
+	_, txs, err = p.getBlockByHeight(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lookups) != 2 {
+		t.Errorf("expected one lookup per out-of-block prev tx, got %v", lookups)
+	}
+	if txs[1].FromAddr != "bc1qsender" {
+		t.Errorf("expected from = first input's address, got %q", txs[1].FromAddr)
+	}
+	// 1.0 + 3.125 in, 4.0 out
+	if txs[1].Fee != "12500000" {
+		t.Errorf("expected fee 12500000, got %s", txs[1].Fee)
+	}
+	if txs[2].FromAddr != "" || txs[2].Fee != "" {
+		t.Errorf("expected unresolvable input to stay empty, got from %q, fee %s", txs[2].FromAddr, txs[2].Fee)
+	}
+	if txs[0].FromAddr != "coinbase" || txs[0].Fee != "0" {
+		t.Errorf("expected coinbase unchanged, got from %q, fee %s", txs[0].FromAddr, txs[0].Fee)
+	}
+
+	// The cap bounds lookups per block
+	lookups = nil
+	p.SetMaxInputLookups(1)
+	if _, _, err := p.getBlockByHeight(context.Background(), 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lookups) != 1 {
+		t.Errorf("expected 1 lookup under the cap, got %v", lookups)
+	}
+}
+
+func TestPoller_ParseTransactionsFee(t *testing.T) {
+	var blockResp interface{}
+	if err := json.Unmarshal([]byte(`{
+		"hash": "aa", "height": 100, "previousblockhash": "99", "time": 0,
+		"tx": [
+			{"txid": "cb", "vin": [{"coinbase": "03abcd"}],
+			 "vout": [{"value": 3.13, "scriptPubKey": {"address": "bc1qpool"}}]},
+			{"txid": "t1", "vin": [{"txid": "prev", "vout": 0}, {"txid": "prev", "vout": 1}],
+			 "vout": [{"value": 0.7, "scriptPubKey": {"address": "bc1qdest"}}, {"value": 0.29, "scriptPubKey": {"address": "bc1qchange"}}]},
+			{"txid": "t2", "vin": [{"txid": "pruned", "vout": 0}],
+			 "vout": [{"value": 0.5, "scriptPubKey": {"address": "bc1qother"}}]}
+		]
+	}`), &blockResp); err != nil {
+		t.Fatalf("parsing block: %v", err)
+	}
+	spent := map[string][]prevout{
+		"prev": {{addr: "bc1qsender", value: 60000000}, {addr: "bc1qsender", value: 40000000}},
+	}
+
+	txs, err := New("", 10).parseTransactions(blockResp, &types.Block{Height: 100, Hash: "aa"}, spent)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(txs) != 3 {
+		t.Fatalf("expected 3 txs, got %d", len(txs))
+	}
+
+	// Coinbase outputs include the block's fees, which are not its own
+	if txs[0].FromAddr != "coinbase" || txs[0].Fee != "0" {
+		t.Errorf("expected coinbase with zero fee, got from %q, fee %q", txs[0].FromAddr, txs[0].Fee)
+	}
+	// 1.0 in, 0.99 out
+	if txs[1].FromAddr != "bc1qsender" || txs[1].Fee != "1000000" || txs[1].Value != "99000000" {
+		t.Errorf("expected fee 1000000 from bc1qsender, got from %q, fee %q, value %s", txs[1].FromAddr, txs[1].Fee, txs[1].Value)
+	}
+	// Unresolved input: unknown fee, stored as NULL
+	if txs[2].Fee != "" {
+		t.Errorf("expected empty fee for unresolved input, got %q", txs[2].Fee)
+	}
+}
+
+func TestPoller_RPCAuth(t *testing.T) {
+	var user, password string
+	var authed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, authed = r.BasicAuth()
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": 100.0})
+	}))
+	defer server.Close()
+
+	p := New(server.URL, 10)
+	if _, err := p.GetChainTip(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if authed {
+		t.Errorf("expected no credentials by default, got %q", user)
+	}
+
+	p.SetRPCAuth("rpcuser", "rpcpass", "")
+	if _, err := p.GetChainTip(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user != "rpcuser" || password != "rpcpass" {
+		t.Errorf("expected rpcuser:rpcpass, got %q:%q", user, password)
+	}
+
+	// The cookie is re-read per request, so a node restart's new cookie is picked up
+	cookiePath := filepath.Join(t.TempDir(), ".cookie")
+	p.SetRPCAuth("", "", cookiePath)
+	for _, secret := range []string{"first", "second"} {
+		if err := os.WriteFile(cookiePath, []byte("__cookie__:"+secret), 0600); err != nil {
+			t.Fatalf("writing cookie: %v", err)
+		}
+		if _, err := p.GetChainTip(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if user != "__cookie__" || password != secret {
+			t.Errorf("expected __cookie__:%s, got %q:%q", secret, user, password)
+		}
+	}
+
+	os.Remove(cookiePath)
+	if _, err := p.GetChainTip(context.Background()); err == nil || !strings.Contains(err.Error(), "cookie") {
+		t.Errorf("expected cookie read error, got %v", err)
+	}
+}
+
+// mockBatchServer answers getblockcount with tip and batched getblockhash and
+// getblock calls with empty blocks hashed "h<height>"; heights in failing get an
+// RPC error from getblock. It counts HTTP requests.
+func mockBatchServer(tip float64, failing map[float64]bool, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		body, _ := io.ReadAll(r.Body)
+		if !strings.HasPrefix(string(body), "[") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"result": tip})
+			return
+		}
+		var calls []struct {
+			ID     int           `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		json.Unmarshal(body, &calls)
+		var resps []map[string]interface{}
+		for _, c := range calls {
+			resp := map[string]interface{}{"id": c.ID}
+			switch c.Method {
+			case "getblockhash":
+				resp["result"] = fmt.Sprintf("h%v", c.Params[0])
+			case "getblock":
+				var height float64
+				fmt.Sscanf(c.Params[0].(string), "h%v", &height)
+				if failing[height] {
+					resp["error"] = map[string]interface{}{"code": -1, "message": "block unavailable"}
+				} else {
+					resp["result"] = map[string]interface{}{"hash": c.Params[0], "height": height, "previousblockhash": fmt.Sprintf("h%v", height-1), "time": 0.0, "tx": []interface{}{}}
+				}
+			}
+			resps = append(resps, resp)
+		}
+		// Batch responses may come back in any order
+		for i, j := 0, len(resps)-1; i < j; i, j = i+1, j-1 {
+			resps[i], resps[j] = resps[j], resps[i]
+		}
+		json.NewEncoder(w).Encode(resps)
+	}))
+}
+
+func TestPoller_PollBatched(t *testing.T) {
+	var requests int
+	server := mockBatchServer(130, nil, &requests)
+	defer server.Close()
+
+	blocks, _, err := New(server.URL, 25).Poll(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(blocks) != 25 || blocks[0].Height != 101 || blocks[24].Height != 125 || blocks[24].Hash != "h125" {
+		t.Fatalf("expected blocks 101-125 in order, got %d blocks", len(blocks))
+	}
+	// getblockcount, one getblockhash batch, three getblock batches
+	if requests != 5 {
+		t.Errorf("expected 5 HTTP requests, got %d", requests)
+	}
+}
+
+func TestPoller_PollBatchedPartialFailure(t *testing.T) {
+	var requests int
+	server := mockBatchServer(110, map[float64]bool{104: true, 106: true}, &requests)
+	defer server.Close()
+
+	// A failed block ends the batch at the block before it, and is reported
+	p := New(server.URL, 10)
+	blocks, _, err := p.Poll(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(blocks) != 3 || blocks[2].Height != 103 {
+		t.Fatalf("expected blocks 101-103, got %d blocks", len(blocks))
+	}
+	if err := p.PollCutShort(); err == nil || !strings.Contains(err.Error(), "getting block 104") {
+		t.Errorf("expected the batch to be cut short at block 104, got %v", err)
+	}
+
+	// A batch that reaches the tip clears it
+	if _, _, err := p.Poll(context.Background(), 106); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.PollCutShort(); err != nil {
+		t.Errorf("expected a full batch, got %v", err)
+	}
+
+	// Failing on the first block is an error
+	if _, _, err := New(server.URL, 10).Poll(context.Background(), 103); err == nil || !strings.Contains(err.Error(), "block unavailable") {
+		t.Errorf("expected error for block 104, got %v", err)
+	}
+}
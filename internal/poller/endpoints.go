@@ -0,0 +1,151 @@
+package poller
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultFailoverCooldown is how long an endpoint that just failed is skipped
+// while others are available
+const DefaultFailoverCooldown = 30 * time.Second
+
+// Endpoints rotates RPC requests across a chain's endpoints. Requests go to the
+// active endpoint; on a connection error, a 5xx or a 429 it is put on cooldown and
+// the request is retried on the next endpoint in round-robin order that is not
+// cooling down, which then stays active.
+type Endpoints struct {
+	urls     []string
+	cooldown time.Duration
+	logger   *slog.Logger
+	answers  map[int]bool // Status codes >= 500 that are RPC answers, not endpoint failures
+
+	mu       sync.Mutex
+	active   int
+	failedAt []time.Time
+
+	failovers atomic.Uint64
+}
+
+// NewEndpoints creates an endpoint set starting at urls[0]. A cooldown of zero or
+// less uses DefaultFailoverCooldown; a nil logger discards failover logs.
+func NewEndpoints(urls []string, cooldown time.Duration, logger *slog.Logger) *Endpoints {
+	if cooldown <= 0 {
+		cooldown = DefaultFailoverCooldown
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Endpoints{
+		urls:     urls,
+		cooldown: cooldown,
+		logger:   logger,
+		failedAt: make([]time.Time, len(urls)),
+	}
+}
+
+// TreatAsAnswer makes responses with the given 5xx status codes count as answers
+// rather than endpoint failures, for nodes that report RPC errors with them (e.g.
+// bitcoind answers a failed call with HTTP 500 and a JSON-RPC error body)
+func (e *Endpoints) TreatAsAnswer(codes ...int) {
+	e.answers = make(map[int]bool, len(codes))
+	for _, code := range codes {
+		e.answers[code] = true
+	}
+}
+
+// Active returns the index of the endpoint requests currently go to
+func (e *Endpoints) Active() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.active
+}
+
+// Failovers returns how many times the active endpoint has changed
+func (e *Endpoints) Failovers() uint64 {
+	return e.failovers.Load()
+}
+
+// Do calls send with the active endpoint's URL, failing over as described on
+// Endpoints, each endpoint being tried at most once. The last attempt's response
+// or error is returned as is, so callers handle a failure of every endpoint the
+// same way as a failure of a single one. Cancellation of ctx never fails over.
+func (e *Endpoints) Do(ctx context.Context, send func(url string) (*http.Response, error)) (*http.Response, error) {
+	e.mu.Lock()
+	idx := e.active
+	e.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		resp, err := send(e.urls[idx])
+		if err != nil && ctx.Err() != nil {
+			return nil, err
+		}
+		if !e.retryable(resp, err) || attempt >= len(e.urls) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		idx = e.fail(idx, resp, err)
+	}
+}
+
+// retryable reports whether a request should be retried on another endpoint
+func (e *Endpoints) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	if e.answers[resp.StatusCode] {
+		return false
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// fail puts endpoint idx on cooldown and returns the endpoint to try next: the
+// next one in order not cooling down, or simply the next one if all are. The
+// active endpoint moves to it unless another request already moved it.
+func (e *Endpoints) fail(idx int, resp *http.Response, err error) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	e.failedAt[idx] = now
+	next := (idx + 1) % len(e.urls)
+	for i := 1; i < len(e.urls); i++ {
+		candidate := (idx + i) % len(e.urls)
+		if now.Sub(e.failedAt[candidate]) >= e.cooldown {
+			next = candidate
+			break
+		}
+	}
+
+	if e.active == idx {
+		e.active = next
+		e.failovers.Add(1)
+		reason := "connection error"
+		if err == nil {
+			reason = resp.Status
+		}
+		e.logger.Warn("RPC endpoint failed, switching endpoint",
+			"endpoint", redactURL(e.urls[idx]),
+			"next_endpoint", redactURL(e.urls[next]),
+			"reason", reason,
+			"error", err,
+		)
+	}
+	return next
+}
+
+// redactURL reduces an endpoint URL to its scheme and host for logging, as paths
+// and userinfo often carry API keys or credentials
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid URL"
+	}
+	return u.Scheme + "://" + u.Host
+}
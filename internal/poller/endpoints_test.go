@@ -0,0 +1,94 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEndpoints_Failover(t *testing.T) {
+	var hits [3]int
+	statuses := [3]int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	var urls []string
+	for i := range statuses {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[i]++
+			w.WriteHeader(statuses[i])
+		}))
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+
+	e := NewEndpoints(urls, 0, nil)
+	get := func() int {
+		t.Helper()
+		resp, err := e.Do(context.Background(), func(url string) (*http.Response, error) {
+			return http.Get(url)
+		})
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("expected failover to the healthy endpoint, got status %d", code)
+	}
+	if e.Active() != 2 || e.Failovers() != 2 {
+		t.Errorf("expected endpoint 2 active after 2 failovers, got %d after %d", e.Active(), e.Failovers())
+	}
+
+	// The healthy endpoint stays active
+	get()
+	if hits != [3]int{1, 1, 2} {
+		t.Errorf("unexpected hits per endpoint: %v", hits)
+	}
+
+	// Once it fails too, the others are still cooling down, so the next one in order is used
+	statuses[2] = http.StatusBadGateway
+	statuses[0] = http.StatusOK
+	if code := get(); code != http.StatusOK || e.Active() != 0 {
+		t.Errorf("expected endpoint 0 to answer, got status %d from endpoint %d", code, e.Active())
+	}
+}
+
+func TestEndpoints_NoFailover(t *testing.T) {
+	calls := 0
+	fail := func(status int) func(string) (*http.Response, error) {
+		return func(string) (*http.Response, error) {
+			calls++
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(status)
+			return rec.Result(), nil
+		}
+	}
+
+	// A single endpoint returns its failure as is
+	single := NewEndpoints([]string{"http://a"}, 0, nil)
+	if resp, _ := single.Do(context.Background(), fail(http.StatusServiceUnavailable)); resp.StatusCode != http.StatusServiceUnavailable || calls != 1 {
+		t.Errorf("expected one attempt returning 503, got %d attempts", calls)
+	}
+
+	// Statuses treated as answers don't fail over
+	calls = 0
+	e := NewEndpoints([]string{"http://a", "http://b"}, 0, nil)
+	e.TreatAsAnswer(http.StatusInternalServerError)
+	if resp, _ := e.Do(context.Background(), fail(http.StatusInternalServerError)); resp.StatusCode != http.StatusInternalServerError || calls != 1 || e.Failovers() != 0 {
+		t.Errorf("expected HTTP 500 to be returned without failover, got %d attempts", calls)
+	}
+
+	// Nor does cancellation
+	calls = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := e.Do(ctx, func(string) (*http.Response, error) {
+		calls++
+		return nil, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 || e.Failovers() != 0 {
+		t.Errorf("expected cancellation without failover, got %v after %d attempts", err, calls)
+	}
+}
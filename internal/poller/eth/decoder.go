@@ -1,9 +1,13 @@
 package eth
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -14,9 +18,28 @@ import (
 // ErrNoABI indicates no ABI is available for the contract
 var ErrNoABI = errors.New("no ABI available for contract")
 
-// ErrUnknownEvent indicates the event signature is not in the ABI
+// ErrUnknownEvent indicates no event in the ABI matches the log: topic0 is not a
+// known event signature and no anonymous event fits the log's topics and data
 var ErrUnknownEvent = errors.New("unknown event signature")
 
+// ErrAmbiguousEvent indicates more than one anonymous event in the ABI fits the log
+var ErrAmbiguousEvent = errors.New("log matches several anonymous events")
+
+// ErrDecodeTooComplex indicates a decoded value exceeded the nesting or element limits
+var ErrDecodeTooComplex = errors.New("decoded value exceeds complexity limits")
+
+// ErrDecodedTooLarge indicates an event's marshaled params exceeded the size limit
+var ErrDecodedTooLarge = errors.New("decoded params exceed size limit")
+
+const (
+	// DefaultMaxDecodeDepth is the maximum nesting of tuples/arrays formatted per value
+	DefaultMaxDecodeDepth = 8
+	// DefaultMaxDecodeElements is the maximum number of values formatted per event
+	DefaultMaxDecodeElements = 10000
+	// DefaultMaxDecodedBytes is the largest marshaled params JSON stored per event
+	DefaultMaxDecodedBytes = 1 << 20
+)
+
 // DecodedEvent represents a successfully decoded event
 type DecodedEvent struct {
 	Name   string                 `json:"name"`
@@ -25,33 +48,101 @@ type DecodedEvent struct {
 
 // Decoder handles ABI-based event decoding
 type Decoder struct {
-	abis map[common.Address]*abi.ABI
+	abis        map[common.Address]*abi.ABI
+	fallbacks   map[common.Address][]*abi.ABI // Tried in order when abis has no matching event
+	maxDepth    int
+	maxElements int
+	maxBytes    int
 }
 
 // NewDecoder creates a new decoder with the given contract ABIs
 func NewDecoder(contractABIs map[common.Address]*abi.ABI) *Decoder {
 	return &Decoder{
-		abis: contractABIs,
+		abis:        contractABIs,
+		maxDepth:    DefaultMaxDecodeDepth,
+		maxElements: DefaultMaxDecodeElements,
+		maxBytes:    DefaultMaxDecodedBytes,
 	}
 }
 
+// SetFallbackABIs sets ABIs tried in order for a contract's logs its own ABI has
+// no event for, such as the implementation ABIs of a proxy contract. A contract
+// may have fallbacks without an ABI of its own.
+func (d *Decoder) SetFallbackABIs(fallbacks map[common.Address][]*abi.ABI) {
+	d.fallbacks = fallbacks
+}
+
+// SetLimits bounds how deep and how large decoded tuple/array values may be.
+// Zero values keep the defaults.
+func (d *Decoder) SetLimits(maxDepth, maxElements int) {
+	if maxDepth > 0 {
+		d.maxDepth = maxDepth
+	}
+	if maxElements > 0 {
+		d.maxElements = maxElements
+	}
+}
+
+// SetMaxDecodedSize bounds the marshaled params JSON of one event, in bytes.
+// Zero keeps the default.
+func (d *Decoder) SetMaxDecodedSize(n int) {
+	if n > 0 {
+		d.maxBytes = n
+	}
+}
+
+// MarshalParams encodes a decoded event's params as stored in events.data.
+// Returns ErrDecodedTooLarge past the size limit, e.g. for an event carrying a
+// huge dynamic array, so the event is stored undecoded rather than as a huge row.
+func (d *Decoder) MarshalParams(ev *DecodedEvent) ([]byte, error) {
+	data, err := json.Marshal(ev.Params)
+	if err != nil {
+		return nil, fmt.Errorf("encoding decoded params: %w", err)
+	}
+	if len(data) > d.maxBytes {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrDecodedTooLarge, len(data), d.maxBytes)
+	}
+	return data, nil
+}
+
 // DecodeLog attempts to decode a log using the known ABIs
 // Returns (decoded, nil) on success
 // Returns (nil, error) on failure - caller should store raw log with decode_failed=true
 func (d *Decoder) DecodeLog(log ethtypes.Log) (*DecodedEvent, error) {
-	if len(log.Topics) == 0 {
-		return nil, errors.New("log has no topics")
+	var abis []*abi.ABI
+	if contractABI, ok := d.abis[log.Address]; ok {
+		abis = append(abis, contractABI)
 	}
-
-	contractABI, ok := d.abis[log.Address]
-	if !ok {
+	abis = append(abis, d.fallbacks[log.Address]...)
+	if len(abis) == 0 {
+		if len(log.Topics) == 0 {
+			return nil, errors.New("log has no topics")
+		}
 		return nil, fmt.Errorf("%w: %s", ErrNoABI, log.Address.Hex())
 	}
 
-	// Find event by topic0 (event signature hash)
-	event, err := contractABI.EventByID(log.Topics[0])
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, log.Topics[0].Hex())
+	// The contract's own ABI first, then each fallback; if none has the event,
+	// the first ABI's error is reported
+	var event *abi.Event
+	var firstErr error
+	for _, contractABI := range abis {
+		ev, err := findEvent(contractABI, log)
+		if err == nil {
+			event = ev
+			break
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if event == nil {
+		return nil, firstErr
+	}
+
+	// Anonymous events have no signature topic, so their indexed args start at topics[0]
+	topicOffset := 1
+	if event.Anonymous {
+		topicOffset = 0
 	}
 
 	// Decode indexed parameters from topics
@@ -67,19 +158,20 @@ func (d *Decoder) DecodeLog(log ethtypes.Log) (*DecodedEvent, error) {
 
 	params := make(map[string]interface{})
 
-	// Decode indexed parameters (from topics[1:])
+	// Decode indexed parameters (from topics[topicOffset:])
 	for i, arg := range indexed {
-		if i+1 >= len(log.Topics) {
+		if i+topicOffset >= len(log.Topics) {
 			break
 		}
-		// For indexed reference types (string, bytes, arrays), only hash is stored
-		if arg.Type.T == abi.StringTy || arg.Type.T == abi.BytesTy || arg.Type.T == abi.SliceTy || arg.Type.T == abi.ArrayTy {
-			params[arg.Name] = log.Topics[i+1].Hex() // Store as hash
+		topic := log.Topics[i+topicOffset]
+		// For indexed reference types (string, bytes, arrays, tuples), only hash is stored
+		if isHashedIndexedType(arg.Type) {
+			params[arg.Name] = topic.Hex() // Store as hash
 		} else {
 			// Decode simple indexed types
-			val, err := decodeIndexedArg(arg.Type, log.Topics[i+1])
+			val, err := decodeIndexedArg(arg.Type, topic)
 			if err != nil {
-				params[arg.Name] = log.Topics[i+1].Hex() // Fallback to hex
+				params[arg.Name] = topic.Hex() // Fallback to hex
 			} else {
 				params[arg.Name] = val
 			}
@@ -93,12 +185,17 @@ func (d *Decoder) DecodeLog(log ethtypes.Log) (*DecodedEvent, error) {
 			return nil, fmt.Errorf("unpack data failed: %w", err)
 		}
 
-		// Map values to non-indexed arguments
+		// Map values to non-indexed arguments (UnpackValues only returns non-indexed values)
+		f := &formatter{maxDepth: d.maxDepth, maxElements: d.maxElements}
 		nonIndexedIdx := 0
-		for i, input := range event.Inputs {
+		for _, input := range event.Inputs {
 			if !input.Indexed {
 				if nonIndexedIdx < len(values) {
-					params[input.Name] = formatValue(values[i])
+					formatted, err := f.format(values[nonIndexedIdx], 0)
+					if err != nil {
+						return nil, fmt.Errorf("formatting %s: %w", input.Name, err)
+					}
+					params[input.Name] = formatted
 					nonIndexedIdx++
 				}
 			}
@@ -111,10 +208,121 @@ func (d *Decoder) DecodeLog(log ethtypes.Log) (*DecodedEvent, error) {
 	}, nil
 }
 
+// findEvent returns the ABI event a log was emitted as: the one whose signature
+// is topic0 or, failing that, the only anonymous event the log's topics and data
+// fit. Anonymous events are told apart by their number of indexed args (one per
+// topic), the values the topics can hold for their types, and data that decodes
+// to their non-indexed args and re-encodes to the same bytes.
+func findEvent(contractABI *abi.ABI, log ethtypes.Log) (*abi.Event, error) {
+	if len(log.Topics) > 0 {
+		if event, err := contractABI.EventByID(log.Topics[0]); err == nil && !event.Anonymous {
+			return event, nil
+		}
+	}
+
+	names := make([]string, 0, len(contractABI.Events))
+	for name, event := range contractABI.Events {
+		if event.Anonymous {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var matches []string
+	for _, name := range names {
+		if anonymousEventFits(contractABI.Events[name], log) {
+			matches = append(matches, name)
+		}
+	}
+
+	switch {
+	case len(matches) == 1:
+		event := contractABI.Events[matches[0]]
+		return &event, nil
+	case len(matches) > 1:
+		return nil, fmt.Errorf("%w: %s", ErrAmbiguousEvent, strings.Join(matches, ", "))
+	case len(log.Topics) == 0:
+		return nil, fmt.Errorf("%w: log has no topics and no anonymous event matches", ErrUnknownEvent)
+	default:
+		return nil, fmt.Errorf("%w: %s (no anonymous event matches either)", ErrUnknownEvent, log.Topics[0].Hex())
+	}
+}
+
+// anonymousEventFits reports whether log could have been emitted as event
+func anonymousEventFits(event abi.Event, log ethtypes.Log) bool {
+	var indexed []abi.Argument
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if len(indexed) != len(log.Topics) {
+		return false
+	}
+	for i, arg := range indexed {
+		if !topicFits(arg.Type, log.Topics[i]) {
+			return false
+		}
+	}
+
+	nonIndexed := event.Inputs.NonIndexed()
+	if len(nonIndexed) == 0 {
+		return len(log.Data) == 0
+	}
+	values, err := nonIndexed.UnpackValues(log.Data)
+	if err != nil {
+		return false
+	}
+	// Unpacking ignores trailing bytes, so require the exact encoding
+	packed, err := nonIndexed.PackValues(values)
+	return err == nil && bytes.Equal(packed, log.Data)
+}
+
+// isHashedIndexedType reports whether an indexed arg of type t is stored in its
+// topic as the keccak256 hash of its value rather than the value itself
+func isHashedIndexedType(t abi.Type) bool {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return true
+	}
+	return false
+}
+
+// topicFits reports whether topic is a valid encoding of an indexed arg of type t
+func topicFits(t abi.Type, topic common.Hash) bool {
+	switch t.T {
+	case abi.AddressTy:
+		return topic.Big().BitLen() <= 160
+	case abi.BoolTy:
+		return topic.Big().BitLen() <= 1
+	case abi.UintTy:
+		return topic.Big().BitLen() <= t.Size
+	case abi.IntTy:
+		// Sign-extended: the bytes above the value are all 0x00 or all 0xff
+		pad := topic[:32-t.Size/8]
+		for _, b := range pad {
+			if b != pad[0] || (b != 0x00 && b != 0xff) {
+				return false
+			}
+		}
+		return true
+	case abi.FixedBytesTy:
+		// Right-padded with zeros
+		for _, b := range topic[t.Size:] {
+			if b != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	// Hashed reference types can hold any value
+	return true
+}
+
 // HasABI checks if an ABI is available for the given contract
 func (d *Decoder) HasABI(address common.Address) bool {
 	_, ok := d.abis[address]
-	return ok
+	return ok || len(d.fallbacks[address]) > 0
 }
 
 // decodeIndexedArg decodes a simple indexed argument from a topic
@@ -131,24 +339,109 @@ func decodeIndexedArg(t abi.Type, topic common.Hash) (interface{}, error) {
 	}
 }
 
-// formatValue converts ABI-decoded values to JSON-safe formats
+// formatValue converts ABI-decoded values to JSON-safe formats using the default limits.
+// Values that exceed the limits are returned unformatted.
 func formatValue(v interface{}) interface{} {
+	f := &formatter{maxDepth: DefaultMaxDecodeDepth, maxElements: DefaultMaxDecodeElements}
+	formatted, err := f.format(v, 0)
+	if err != nil {
+		return v
+	}
+	return formatted
+}
+
+// formatter recursively converts decoded values (including tuples and nested arrays)
+// into JSON-safe values, enforcing depth and element budgets against adversarial ABIs
+type formatter struct {
+	maxDepth    int
+	maxElements int
+	elements    int
+}
+
+func (f *formatter) format(v interface{}, depth int) (interface{}, error) {
+	if depth > f.maxDepth {
+		return nil, fmt.Errorf("%w: nesting deeper than %d", ErrDecodeTooComplex, f.maxDepth)
+	}
+	f.elements++
+	if f.elements > f.maxElements {
+		return nil, fmt.Errorf("%w: more than %d elements", ErrDecodeTooComplex, f.maxElements)
+	}
+
 	switch val := v.(type) {
+	case nil:
+		return nil, nil
 	case common.Address:
-		return val.Hex()
+		return val.Hex(), nil
 	case common.Hash:
-		return val.Hex()
+		return val.Hex(), nil
 	case []byte:
-		return fmt.Sprintf("0x%x", val)
+		return fmt.Sprintf("0x%x", val), nil
 	case [32]byte:
-		return fmt.Sprintf("0x%x", val[:])
-	default:
-		// Try to use Stringer interface for big.Int etc
-		if stringer, ok := v.(fmt.Stringer); ok {
-			return stringer.String()
+		return fmt.Sprintf("0x%x", val[:]), nil
+	case *big.Int:
+		if val == nil {
+			return nil, nil
 		}
-		return v
+		return val.String(), nil
+	case string, bool:
+		return val, nil
 	}
+
+	// Try to use Stringer interface for remaining named types
+	if stringer, ok := v.(fmt.Stringer); ok {
+		return stringer.String(), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return f.format(rv.Elem().Interface(), depth)
+
+	case reflect.Struct:
+		// Tuples are decoded into anonymous structs whose json tags carry the ABI names
+		out := make(map[string]interface{}, rv.NumField())
+		rt := rv.Type()
+		for i := 0; i < rv.NumField(); i++ {
+			field := rt.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if tag := field.Tag.Get("json"); tag != "" {
+				name = tag
+			}
+			formatted, err := f.format(rv.Field(i).Interface(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[name] = formatted
+		}
+		return out, nil
+
+	case reflect.Slice, reflect.Array:
+		// Fixed-size byte arrays (bytes1..bytes32) are rendered as hex
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			for i := range b {
+				b[i] = byte(rv.Index(i).Uint())
+			}
+			return fmt.Sprintf("0x%x", b), nil
+		}
+		out := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			formatted, err := f.format(rv.Index(i).Interface(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = formatted
+		}
+		return out, nil
+	}
+
+	return v, nil
 }
 
 // LoadABIFromJSON parses an ABI from JSON bytes
@@ -1,6 +1,11 @@
 package eth
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -121,3 +126,435 @@ func TestFormatValue(t *testing.T) {
 		})
 	}
 }
+
+func TestDecoder_TupleEvent(t *testing.T) {
+	contractAddr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	abiJSON := `[{"anonymous":false,"inputs":[{"indexed":true,"name":"owner","type":"address"},{"indexed":false,"name":"order","type":"tuple","components":[{"name":"maker","type":"address"},{"name":"amount","type":"uint256"},{"name":"fills","type":"uint256[]"}]}],"name":"OrderPlaced","type":"event"}]`
+	parsedABI, err := LoadABIFromJSON([]byte(abiJSON))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	event := parsedABI.Events["OrderPlaced"]
+
+	order := struct {
+		Maker  common.Address `json:"maker"`
+		Amount *big.Int       `json:"amount"`
+		Fills  []*big.Int     `json:"fills"`
+	}{
+		Maker:  common.HexToAddress("0x00000000000000000000000000000000000000aa"),
+		Amount: big.NewInt(1000),
+		Fills:  []*big.Int{big.NewInt(1), big.NewInt(2)},
+	}
+	data, err := event.Inputs.NonIndexed().Pack(order)
+	if err != nil {
+		t.Fatalf("failed to pack tuple: %v", err)
+	}
+
+	decoder := NewDecoder(map[common.Address]*abi.ABI{contractAddr: parsedABI})
+	decoded, err := decoder.DecodeLog(ethtypes.Log{
+		Address: contractAddr,
+		Topics: []common.Hash{
+			event.ID,
+			common.BytesToHash(common.HexToAddress("0x00000000000000000000000000000000000000bb").Bytes()),
+		},
+		Data: data,
+	})
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	encoded, err := json.Marshal(decoded.Params)
+	if err != nil {
+		t.Fatalf("decoded params are not JSON-safe: %v", err)
+	}
+
+	var roundTrip map[string]interface{}
+	if err := json.Unmarshal(encoded, &roundTrip); err != nil {
+		t.Fatalf("failed to re-read params: %v", err)
+	}
+	tuple, ok := roundTrip["order"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected order to be an object, got %s", encoded)
+	}
+	if tuple["maker"] != order.Maker.Hex() {
+		t.Errorf("unexpected maker %v", tuple["maker"])
+	}
+	if tuple["amount"] != "1000" {
+		t.Errorf("expected amount 1000, got %v", tuple["amount"])
+	}
+	fills, ok := tuple["fills"].([]interface{})
+	if !ok || len(fills) != 2 || fills[1] != "2" {
+		t.Errorf("unexpected fills %v", tuple["fills"])
+	}
+	if roundTrip["owner"] != common.HexToAddress("0x00000000000000000000000000000000000000bb").Hex() {
+		t.Errorf("unexpected owner %v", roundTrip["owner"])
+	}
+}
+
+func TestDecoder_DeeplyNestedArray(t *testing.T) {
+	contractAddr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	abiJSON := `[{"anonymous":false,"inputs":[{"indexed":false,"name":"grid","type":"uint256[][][]"}],"name":"Grid","type":"event"}]`
+	parsedABI, err := LoadABIFromJSON([]byte(abiJSON))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	event := parsedABI.Events["Grid"]
+
+	grid := [][][]*big.Int{{{big.NewInt(1), big.NewInt(2)}, {big.NewInt(3)}}}
+	data, err := event.Inputs.Pack(grid)
+	if err != nil {
+		t.Fatalf("failed to pack grid: %v", err)
+	}
+	log := ethtypes.Log{
+		Address: contractAddr,
+		Topics:  []common.Hash{event.ID},
+		Data:    data,
+	}
+
+	decoder := NewDecoder(map[common.Address]*abi.ABI{contractAddr: parsedABI})
+	decoded, err := decoder.DecodeLog(log)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if _, err := json.Marshal(decoded.Params); err != nil {
+		t.Fatalf("decoded params are not JSON-safe: %v", err)
+	}
+
+	// Depth guard
+	decoder.SetLimits(2, 0)
+	if _, err := decoder.DecodeLog(log); !errors.Is(err, ErrDecodeTooComplex) {
+		t.Errorf("expected ErrDecodeTooComplex for depth limit, got %v", err)
+	}
+
+	// Element guard
+	decoder = NewDecoder(map[common.Address]*abi.ABI{contractAddr: parsedABI})
+	decoder.SetLimits(0, 4)
+	if _, err := decoder.DecodeLog(log); !errors.Is(err, ErrDecodeTooComplex) {
+		t.Errorf("expected ErrDecodeTooComplex for element limit, got %v", err)
+	}
+}
+
+func TestDecoder_MarshalParamsSizeLimit(t *testing.T) {
+	contractAddr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	abiJSON := `[{"anonymous":false,"inputs":[{"indexed":false,"name":"ids","type":"uint256[]"}],"name":"Batch","type":"event"}]`
+	parsedABI, err := LoadABIFromJSON([]byte(abiJSON))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	event := parsedABI.Events["Batch"]
+
+	ids := make([]*big.Int, 2000)
+	for i := range ids {
+		ids[i] = new(big.Int).Lsh(big.NewInt(int64(i+1)), 200)
+	}
+	data, err := event.Inputs.Pack(ids)
+	if err != nil {
+		t.Fatalf("failed to pack ids: %v", err)
+	}
+	log := ethtypes.Log{
+		Address: contractAddr,
+		Topics:  []common.Hash{event.ID},
+		Data:    data,
+	}
+
+	decoder := NewDecoder(map[common.Address]*abi.ABI{contractAddr: parsedABI})
+	decoded, err := decoder.DecodeLog(log)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if _, err := decoder.MarshalParams(decoded); err != nil {
+		t.Fatalf("expected params within the default limit, got %v", err)
+	}
+
+	decoder.SetMaxDecodedSize(4096)
+	if _, err := decoder.MarshalParams(decoded); !errors.Is(err, ErrDecodedTooLarge) {
+		t.Errorf("expected ErrDecodedTooLarge, got %v", err)
+	}
+}
+
+func TestPoller_ParseLogOversized(t *testing.T) {
+	contractAddr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	abiJSON := `[{"anonymous":false,"inputs":[{"indexed":false,"name":"ids","type":"uint256[]"}],"name":"Batch","type":"event"}]`
+	parsedABI, err := LoadABIFromJSON([]byte(abiJSON))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	event := parsedABI.Events["Batch"]
+
+	ids := make([]*big.Int, 2000)
+	for i := range ids {
+		ids[i] = new(big.Int).Lsh(big.NewInt(int64(i+1)), 200)
+	}
+	data, err := event.Inputs.Pack(ids)
+	if err != nil {
+		t.Fatalf("failed to pack ids: %v", err)
+	}
+
+	p := NewPoller("http://unused", 100, 2000, true, 12, []ContractConfig{{Address: contractAddr, ABI: parsedABI}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.SetMaxDecodedSize(4096)
+
+	logMap := func(topic0 common.Hash) map[string]interface{} {
+		return map[string]interface{}{
+			"address":         contractAddr.Hex(),
+			"blockNumber":     "0x10",
+			"blockHash":       "0xb1",
+			"transactionHash": "0xt1",
+			"logIndex":        "0x0",
+			"topics":          []interface{}{topic0.Hex()},
+			"data":            "0x" + common.Bytes2Hex(data),
+		}
+	}
+
+	// Too large to store decoded: undecoded, and marked so redecode skips it
+	e, err := p.parseLog(logMap(event.ID), map[uint64]map[common.Address]int{})
+	if err != nil {
+		t.Fatalf("parseLog: %v", err)
+	}
+	if !e.DecodeFailed || !e.Oversized {
+		t.Errorf("expected an oversized undecoded event, got decode_failed=%v oversized=%v", e.DecodeFailed, e.Oversized)
+	}
+
+	// An unknown event fails to decode but is not oversized
+	e, err = p.parseLog(logMap(common.HexToHash("0x01")), map[uint64]map[common.Address]int{})
+	if err != nil {
+		t.Fatalf("parseLog: %v", err)
+	}
+	if !e.DecodeFailed || e.Oversized {
+		t.Errorf("expected a plain decode failure, got decode_failed=%v oversized=%v", e.DecodeFailed, e.Oversized)
+	}
+}
+
+func TestDecoder_AnonymousEvent(t *testing.T) {
+	contractAddr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	// Deposit has one indexed arg, Flagged two; Ping has none
+	abiJSON := `[
+		{"anonymous":true,"inputs":[{"indexed":true,"name":"account","type":"address"},{"indexed":false,"name":"amount","type":"uint256"}],"name":"Deposit","type":"event"},
+		{"anonymous":true,"inputs":[{"indexed":true,"name":"account","type":"address"},{"indexed":true,"name":"flag","type":"bool"}],"name":"Flagged","type":"event"},
+		{"anonymous":true,"inputs":[],"name":"Ping","type":"event"},
+		{"anonymous":false,"inputs":[{"indexed":false,"name":"value","type":"uint256"}],"name":"Named","type":"event"}
+	]`
+	parsedABI, err := LoadABIFromJSON([]byte(abiJSON))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	decoder := NewDecoder(map[common.Address]*abi.ABI{contractAddr: parsedABI})
+
+	account := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	amount, err := parsedABI.Events["Deposit"].Inputs.NonIndexed().Pack(big.NewInt(500))
+	if err != nil {
+		t.Fatalf("failed to pack amount: %v", err)
+	}
+
+	// The first indexed arg is in topics[0], not a signature hash
+	decoded, err := decoder.DecodeLog(ethtypes.Log{
+		Address: contractAddr,
+		Topics:  []common.Hash{common.BytesToHash(account.Bytes())},
+		Data:    amount,
+	})
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if decoded.Name != "Deposit" || decoded.Params["account"] != account.Hex() || decoded.Params["amount"] != "500" {
+		t.Errorf("unexpected decoded event: %+v", decoded)
+	}
+
+	decoded, err = decoder.DecodeLog(ethtypes.Log{
+		Address: contractAddr,
+		Topics:  []common.Hash{common.BytesToHash(account.Bytes()), common.BigToHash(big.NewInt(1))},
+	})
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if decoded.Name != "Flagged" || decoded.Params["flag"] != true {
+		t.Errorf("unexpected decoded event: %+v", decoded)
+	}
+
+	if decoded, err := decoder.DecodeLog(ethtypes.Log{Address: contractAddr}); err != nil || decoded.Name != "Ping" {
+		t.Errorf("expected a log without topics or data to decode as Ping, got %+v, %v", decoded, err)
+	}
+
+	// Signature matches still take precedence
+	value, _ := parsedABI.Events["Named"].Inputs.NonIndexed().Pack(big.NewInt(7))
+	if decoded, err := decoder.DecodeLog(ethtypes.Log{Address: contractAddr, Topics: []common.Hash{parsedABI.Events["Named"].ID}, Data: value}); err != nil || decoded.Name != "Named" {
+		t.Errorf("expected Named by topic0, got %+v, %v", decoded, err)
+	}
+
+	tests := []struct {
+		name string
+		log  ethtypes.Log
+	}{
+		{"topic too wide for an address", ethtypes.Log{Address: contractAddr, Topics: []common.Hash{common.HexToHash("0xff00000000000000000000000000000000000000000000000000000000000001")}, Data: amount}},
+		{"trailing data", ethtypes.Log{Address: contractAddr, Topics: []common.Hash{common.BytesToHash(account.Bytes())}, Data: append(amount, make([]byte, 32)...)}},
+		{"bool out of range", ethtypes.Log{Address: contractAddr, Topics: []common.Hash{common.BytesToHash(account.Bytes()), common.BigToHash(big.NewInt(2))}}},
+		{"too many topics", ethtypes.Log{Address: contractAddr, Topics: make([]common.Hash, 3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decoder.DecodeLog(tt.log); !errors.Is(err, ErrUnknownEvent) {
+				t.Errorf("expected ErrUnknownEvent, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDecoder_AmbiguousAnonymousEvent(t *testing.T) {
+	contractAddr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	abiJSON := `[
+		{"anonymous":true,"inputs":[{"indexed":true,"name":"from","type":"address"}],"name":"Left","type":"event"},
+		{"anonymous":true,"inputs":[{"indexed":true,"name":"to","type":"address"}],"name":"Joined","type":"event"}
+	]`
+	parsedABI, err := LoadABIFromJSON([]byte(abiJSON))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	decoder := NewDecoder(map[common.Address]*abi.ABI{contractAddr: parsedABI})
+
+	_, err = decoder.DecodeLog(ethtypes.Log{
+		Address: contractAddr,
+		Topics:  []common.Hash{common.BytesToHash(common.HexToAddress("0xaa").Bytes())},
+	})
+	if !errors.Is(err, ErrAmbiguousEvent) {
+		t.Errorf("expected ErrAmbiguousEvent, got %v", err)
+	}
+}
+
+func TestDecoder_TupleArrayEvent(t *testing.T) {
+	contractAddr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	abiJSON := `[{"anonymous":false,"inputs":[{"indexed":false,"name":"ranges","type":"tuple[]","components":[{"name":"lower","type":"uint256"},{"name":"upper","type":"uint256"}]},{"indexed":false,"name":"owners","type":"address[2]"}],"name":"RangesSet","type":"event"}]`
+	parsedABI, err := LoadABIFromJSON([]byte(abiJSON))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	event := parsedABI.Events["RangesSet"]
+
+	type rng struct {
+		Lower *big.Int `json:"lower"`
+		Upper *big.Int `json:"upper"`
+	}
+	owners := [2]common.Address{common.HexToAddress("0xaa"), common.HexToAddress("0xbb")}
+	data, err := event.Inputs.NonIndexed().Pack([]rng{{big.NewInt(1), big.NewInt(2)}, {big.NewInt(3), big.NewInt(4)}}, owners)
+	if err != nil {
+		t.Fatalf("failed to pack tuple array: %v", err)
+	}
+
+	decoder := NewDecoder(map[common.Address]*abi.ABI{contractAddr: parsedABI})
+	decoded, err := decoder.DecodeLog(ethtypes.Log{Address: contractAddr, Topics: []common.Hash{event.ID}, Data: data})
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	encoded, err := json.Marshal(decoded.Params)
+	if err != nil {
+		t.Fatalf("decoded params are not JSON-safe: %v", err)
+	}
+	want := `{"owners":["` + owners[0].Hex() + `","` + owners[1].Hex() + `"],"ranges":[{"lower":"1","upper":"2"},{"lower":"3","upper":"4"}]}`
+	if string(encoded) != want {
+		t.Errorf("unexpected params\n got: %s\nwant: %s", encoded, want)
+	}
+}
+
+func TestDecoder_UniswapV3Swap(t *testing.T) {
+	pool := common.HexToAddress("0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640")
+
+	abiJSON := `[{"anonymous":false,"inputs":[{"indexed":true,"name":"sender","type":"address"},{"indexed":true,"name":"recipient","type":"address"},{"indexed":false,"name":"amount0","type":"int256"},{"indexed":false,"name":"amount1","type":"int256"},{"indexed":false,"name":"sqrtPriceX96","type":"uint160"},{"indexed":false,"name":"liquidity","type":"uint128"},{"indexed":false,"name":"tick","type":"int24"}],"name":"Swap","type":"event"}]`
+	parsedABI, err := LoadABIFromJSON([]byte(abiJSON))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	event := parsedABI.Events["Swap"]
+
+	sqrtPrice, _ := new(big.Int).SetString("1461446703485210103287273052203988822378723970341", 10)
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(-5000), big.NewInt(2500), sqrtPrice, big.NewInt(1e18), big.NewInt(-201234))
+	if err != nil {
+		t.Fatalf("failed to pack swap: %v", err)
+	}
+
+	decoder := NewDecoder(map[common.Address]*abi.ABI{pool: parsedABI})
+	sender := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	decoded, err := decoder.DecodeLog(ethtypes.Log{
+		Address: pool,
+		Topics:  []common.Hash{event.ID, common.BytesToHash(sender.Bytes()), common.BytesToHash(sender.Bytes())},
+		Data:    data,
+	})
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"sender":       sender.Hex(),
+		"recipient":    sender.Hex(),
+		"amount0":      "-5000",
+		"amount1":      "2500",
+		"sqrtPriceX96": sqrtPrice.String(),
+		"liquidity":    "1000000000000000000",
+		"tick":         "-201234",
+	}
+	for name, v := range want {
+		if decoded.Params[name] != v {
+			t.Errorf("%s: expected %v, got %v (%T)", name, v, decoded.Params[name], decoded.Params[name])
+		}
+	}
+}
+
+func TestDecoder_ImplementationABIFallback(t *testing.T) {
+	proxyAddr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	proxyABI, err := LoadABIFromJSON([]byte(`[{"anonymous":false,"inputs":[{"indexed":false,"name":"implementation","type":"address"}],"name":"Upgraded","type":"event"}]`))
+	if err != nil {
+		t.Fatalf("failed to parse proxy ABI: %v", err)
+	}
+	implABI, err := LoadABIFromJSON([]byte(`[{"anonymous":false,"inputs":[{"indexed":true,"name":"account","type":"address"},{"indexed":false,"name":"amount","type":"uint256"}],"name":"Staked","type":"event"}]`))
+	if err != nil {
+		t.Fatalf("failed to parse implementation ABI: %v", err)
+	}
+
+	staked := implABI.Events["Staked"]
+	amount, _ := staked.Inputs.NonIndexed().Pack(big.NewInt(42))
+	stakedLog := ethtypes.Log{
+		Address: proxyAddr,
+		Topics:  []common.Hash{staked.ID, common.BytesToHash(common.HexToAddress("0xaa").Bytes())},
+		Data:    amount,
+	}
+	upgraded := proxyABI.Events["Upgraded"]
+	impl, _ := upgraded.Inputs.NonIndexed().Pack(common.HexToAddress("0xbb"))
+	upgradedLog := ethtypes.Log{Address: proxyAddr, Topics: []common.Hash{upgraded.ID}, Data: impl}
+
+	// Without the implementation ABI the proxy's events decode, its implementation's do not
+	decoder := NewDecoder(map[common.Address]*abi.ABI{proxyAddr: proxyABI})
+	if _, err := decoder.DecodeLog(stakedLog); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("expected ErrUnknownEvent without the implementation ABI, got %v", err)
+	}
+
+	decoder.SetFallbackABIs(map[common.Address][]*abi.ABI{proxyAddr: {implABI}})
+	decoded, err := decoder.DecodeLog(stakedLog)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if decoded.Name != "Staked" || decoded.Params["amount"] != "42" {
+		t.Errorf("unexpected decoded event: %+v", decoded)
+	}
+	if decoded, err := decoder.DecodeLog(upgradedLog); err != nil || decoded.Name != "Upgraded" {
+		t.Errorf("expected the proxy's own event to decode, got %+v, %v", decoded, err)
+	}
+
+	// Fallbacks alone are enough
+	implOnly := NewDecoder(nil)
+	implOnly.SetFallbackABIs(map[common.Address][]*abi.ABI{proxyAddr: {implABI}})
+	if !implOnly.HasABI(proxyAddr) {
+		t.Error("expected HasABI with only fallback ABIs")
+	}
+	if decoded, err := implOnly.DecodeLog(stakedLog); err != nil || decoded.Name != "Staked" {
+		t.Errorf("expected Staked from the fallback ABI, got %+v, %v", decoded, err)
+	}
+
+	// Both ABIs' events are filtered on, each once
+	if topics := EventTopics(proxyABI, implABI, implABI, nil); len(topics) != 2 {
+		t.Errorf("expected 2 topics across proxy and implementation ABIs, got %d", len(topics))
+	}
+}
@@ -0,0 +1,106 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// ABIFetcher downloads verified contract ABIs from an Etherscan-compatible explorer
+// API (module=contract&action=getabi) and caches them on disk, so each contract is
+// fetched once rather than on every start.
+type ABIFetcher struct {
+	baseURL  string
+	apiKey   string
+	cacheDir string
+	client   *http.Client
+}
+
+// NewABIFetcher creates a fetcher; cacheDir is created on first write
+func NewABIFetcher(baseURL, apiKey, cacheDir string, timeout time.Duration) *ABIFetcher {
+	return &ABIFetcher{
+		baseURL:  baseURL,
+		apiKey:   apiKey,
+		cacheDir: cacheDir,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Fetch returns the ABI for address, from the disk cache if present
+func (f *ABIFetcher) Fetch(ctx context.Context, address string) (*abi.ABI, error) {
+	path := filepath.Join(f.cacheDir, strings.ToLower(address)+".json")
+
+	if data, err := os.ReadFile(path); err == nil {
+		return LoadABIFromJSON(data)
+	}
+
+	data, err := f.download(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := LoadABIFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed cache write only costs a re-fetch next start
+	if err := os.MkdirAll(f.cacheDir, 0o755); err == nil {
+		os.WriteFile(path, data, 0o644)
+	}
+
+	return parsed, nil
+}
+
+func (f *ABIFetcher) download(ctx context.Context, address string) ([]byte, error) {
+	q := url.Values{}
+	q.Set("module", "contract")
+	q.Set("action", "getabi")
+	q.Set("address", address)
+	if f.apiKey != "" {
+		q.Set("apikey", f.apiKey)
+	}
+
+	sep := "?"
+	if strings.Contains(f.baseURL, "?") {
+		sep = "&"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.baseURL+sep+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("explorer request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("explorer returned HTTP %d", resp.StatusCode)
+	}
+
+	// The ABI arrives as a JSON-encoded string in result; on failure result holds the reason
+	var body struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Result  string `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding explorer response: %w", err)
+	}
+	if body.Status != "1" {
+		return nil, fmt.Errorf("explorer: %s: %s", body.Message, body.Result)
+	}
+
+	return []byte(body.Result), nil
+}
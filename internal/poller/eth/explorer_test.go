@@ -0,0 +1,77 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const transferABI = `[{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"}]`
+
+func explorerServer(t *testing.T, calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		q := r.URL.Query()
+		if q.Get("module") != "contract" || q.Get("action") != "getabi" || q.Get("apikey") != "key" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+
+		resp := map[string]string{"status": "1", "message": "OK", "result": transferABI}
+		if q.Get("address") != "0x00000000000000000000000000000000000000AA" {
+			resp = map[string]string{"status": "0", "message": "NOTOK", "result": "Contract source code not verified"}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestABIFetcher_FetchAndCache(t *testing.T) {
+	var calls int
+	server := explorerServer(t, &calls)
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "abis")
+	f := NewABIFetcher(server.URL, "key", dir, time.Second)
+
+	parsed, err := f.Fetch(context.Background(), "0x00000000000000000000000000000000000000AA")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := parsed.Events["Transfer"]; !ok {
+		t.Error("expected Transfer event in fetched ABI")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "0x00000000000000000000000000000000000000aa.json")); err != nil {
+		t.Errorf("expected ABI cached on disk: %v", err)
+	}
+
+	// Second fetch is served from the cache
+	if _, err := f.Fetch(context.Background(), "0x00000000000000000000000000000000000000AA"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 explorer call, got %d", calls)
+	}
+}
+
+func TestABIFetcher_Unverified(t *testing.T) {
+	var calls int
+	server := explorerServer(t, &calls)
+	defer server.Close()
+
+	dir := t.TempDir()
+	f := NewABIFetcher(server.URL, "key", dir, time.Second)
+
+	if _, err := f.Fetch(context.Background(), "0x00000000000000000000000000000000000000bb"); err == nil {
+		t.Fatal("expected error for unverified contract")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected nothing cached on failure, found %d files", len(entries))
+	}
+}
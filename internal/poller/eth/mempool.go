@@ -7,29 +7,70 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/internal/indexer/internal/api/cache"
+	"github.com/internal/indexer/internal/poller"
+	"github.com/internal/indexer/pkg/types"
 )
 
+// DefaultMempoolDedupeWindow is how long a pending tx stays listed after it was last seen
+const DefaultMempoolDedupeWindow = 30 * time.Second
+
+// maxPendingTxs caps the number of pending txs stored in the cache
+const maxPendingTxs = 50
+
+// pendingTx is the cached summary of a pending transaction
+type pendingTx struct {
+	Hash      string    `json:"hash"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Value     string    `json:"value"`
+	FirstSeen time.Time `json:"first_seen"`
+	lastSeen  time.Time
+}
+
 // MempoolPoller polls for pending transactions
 type MempoolPoller struct {
-	rpcURL string
-	cache  cache.Cache
-	logger *slog.Logger
-	quit   chan struct{}
+	rpcURL          string
+	cache           cache.Cache
+	logger          *slog.Logger
+	quit            chan struct{}
+	maxResponseSize int64 // Largest RPC response body read
+
+	// Rolling set of recently seen pending txs (only touched by the poll loop)
+	dedupeWindow time.Duration
+	seen         map[string]*pendingTx
 }
 
 // NewMempoolPoller creates a new MempoolPoller
 func NewMempoolPoller(rpcURL string, cache cache.Cache, logger *slog.Logger) *MempoolPoller {
 	return &MempoolPoller{
-		rpcURL: rpcURL,
-		cache:  cache,
-		logger: logger.With("component", "mempool_poller"),
-		quit:   make(chan struct{}),
+		rpcURL:          rpcURL,
+		cache:           cache,
+		logger:          logger.With("component", "mempool_poller"),
+		quit:            make(chan struct{}),
+		maxResponseSize: poller.DefaultMaxResponseSize,
+		dedupeWindow:    DefaultMempoolDedupeWindow,
+		seen:            make(map[string]*pendingTx),
+	}
+}
+
+// SetMaxResponseSize bounds the RPC response bodies read; larger responses fail
+// with poller.ErrResponseTooLarge. Zero or less keeps the default.
+func (p *MempoolPoller) SetMaxResponseSize(n int64) {
+	if n > 0 {
+		p.maxResponseSize = n
 	}
 }
 
+// SetDedupeWindow sets how long txs that drop out of the pending block stay listed.
+// Zero or negative disables merging: each poll replaces the list.
+func (p *MempoolPoller) SetDedupeWindow(d time.Duration) {
+	p.dedupeWindow = d
+}
+
 // Start begins polling for pending transactions
 func (p *MempoolPoller) Start() {
 	ticker := time.NewTicker(2 * time.Second)
@@ -60,24 +101,14 @@ func (p *MempoolPoller) pollPending() error {
 
 	// Create request
 	reqBody := []byte(`{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["pending", true],"id":1}`)
-	resp, err := p.doRPC(reqBody)
+	respBody, err := p.doRPC(reqBody)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	// custom struct to parse simplified RPC response for mempool
-	type RPCTransaction struct {
-		Hash string `json:"hash"`
-		// Add other fields if needed for UI, e.g. From, To, Value
-		From  string `json:"from"`
-		To    string `json:"to"`
-		Value string `json:"value"`
-	}
 
 	var rpcResp struct {
 		Result *struct {
-			Transactions []RPCTransaction `json:"transactions"`
+			Transactions []pendingTx `json:"transactions"`
 		} `json:"result"`
 		Error *struct {
 			Code    int    `json:"code"`
@@ -85,7 +116,7 @@ func (p *MempoolPoller) pollPending() error {
 		} `json:"error"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
 		return fmt.Errorf("decoding response: %w", err)
 	}
 
@@ -97,26 +128,28 @@ func (p *MempoolPoller) pollPending() error {
 		return nil // No pending block or empty
 	}
 
-	// Extract transactions
-	// We only want a summary list for the mempool view
-	// Store in Redis with short TTL
-
-	// Simplify: just store the whole list of tx hashes or a few details?
-	// The verified checking plan says "/txs/pending/{chain}" endpoint.
-	// Frontend wants to show "Pending Transactions".
-	// Let's store the full slice of transactions as JSON
-
+	// Store a capped summary list in Redis with short TTL for /txs/pending/{chain}
 	ctx := context.Background()
-	key := "mempool:eth:latest"
-
-	// Convert to simpler struct if needed, but types.Block already has simplified Txs?
-	// types.Block has Txs []Transaction
-
-	// We'll limit the number of txs stored to avoid huge redis payloads if pending is massive
-	maxTxs := 50
-	txs := rpcResp.Result.Transactions
-	if len(txs) > maxTxs {
-		txs = txs[:maxTxs]
+	key := cache.MempoolKey(string(types.ChainETH))
+
+	var txs []pendingTx
+	if p.dedupeWindow > 0 {
+		mined, err := p.latestBlockTxHashes()
+		if err != nil {
+			// Not fatal: stale entries still expire after the window
+			p.logger.Debug("failed to fetch latest block tx hashes", "error", err)
+		}
+		txs = p.merge(rpcResp.Result.Transactions, mined, time.Now())
+	} else {
+		// We'll limit the number of txs stored to avoid huge redis payloads if pending is massive
+		txs = rpcResp.Result.Transactions
+		if len(txs) > maxPendingTxs {
+			txs = txs[:maxPendingTxs]
+		}
+		now := time.Now()
+		for i := range txs {
+			txs[i].FirstSeen = now
+		}
 	}
 
 	if err := p.cache.Set(ctx, key, txs, 15*time.Second); err != nil {
@@ -126,13 +159,77 @@ func (p *MempoolPoller) pollPending() error {
 	return nil
 }
 
-func (p *MempoolPoller) doRPC(body []byte) (*http.Response, error) {
-	// Basic HTTP client
+// merge folds the current pending txs into the rolling set, drops mined and expired
+// entries, and returns the newest maxPendingTxs entries ordered by first-seen time.
+// The set keeps every tx still pending, not just the listed ones, so a tx pushed
+// out of the list keeps its first-seen time rather than coming back as new.
+func (p *MempoolPoller) merge(current []pendingTx, mined map[string]bool, now time.Time) []pendingTx {
+	for _, tx := range current {
+		if existing, ok := p.seen[tx.Hash]; ok {
+			existing.lastSeen = now
+			continue
+		}
+		tx.FirstSeen = now
+		tx.lastSeen = now
+		p.seen[tx.Hash] = &tx
+	}
+
+	list := make([]pendingTx, 0, len(p.seen))
+	for hash, tx := range p.seen {
+		if mined[hash] || now.Sub(tx.lastSeen) > p.dedupeWindow {
+			delete(p.seen, hash)
+			continue
+		}
+		list = append(list, *tx)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].FirstSeen.Equal(list[j].FirstSeen) {
+			return list[i].FirstSeen.After(list[j].FirstSeen)
+		}
+		return list[i].Hash < list[j].Hash
+	})
+
+	if len(list) > maxPendingTxs {
+		list = list[:maxPendingTxs]
+	}
+	return list
+}
+
+// latestBlockTxHashes returns the tx hashes of the latest mined block
+func (p *MempoolPoller) latestBlockTxHashes() (map[string]bool, error) {
+	reqBody := []byte(`{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["latest", false],"id":1}`)
+	respBody, err := p.doRPC(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var rpcResp struct {
+		Result *struct {
+			Transactions []string `json:"transactions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if rpcResp.Result == nil {
+		return nil, nil
+	}
+
+	mined := make(map[string]bool, len(rpcResp.Result.Transactions))
+	for _, h := range rpcResp.Result.Transactions {
+		mined[h] = true
+	}
+	return mined, nil
+}
+
+// doRPC posts a JSON-RPC request and returns the response body, read up to maxResponseSize
+func (p *MempoolPoller) doRPC(body []byte) ([]byte, error) {
 	client := &http.Client{Timeout: 5 * time.Second}
 	resp, err := client.Post(p.rpcURL, "application/json", bytes.NewReader(body))
-	// Note: bytes import needed
 	if err != nil {
 		return nil, err
 	}
-	return resp, nil
+	defer resp.Body.Close()
+	return poller.ReadResponse(resp.Body, p.maxResponseSize)
 }
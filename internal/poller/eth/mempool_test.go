@@ -0,0 +1,89 @@
+package eth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/internal/indexer/internal/poller"
+)
+
+func TestMempoolPoller_Merge(t *testing.T) {
+	p := NewMempoolPoller("http://localhost:8545", nil, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	p.SetDedupeWindow(10 * time.Second)
+
+	t0 := time.Unix(1700000000, 0)
+
+	list := p.merge([]pendingTx{{Hash: "0xa"}, {Hash: "0xb"}}, nil, t0)
+	if len(list) != 2 {
+		t.Fatalf("expected 2 txs, got %d", len(list))
+	}
+
+	// 0xa drops out of the pending block but stays within the window; 0xc is new
+	list = p.merge([]pendingTx{{Hash: "0xb"}, {Hash: "0xc"}}, nil, t0.Add(2*time.Second))
+	if len(list) != 3 {
+		t.Fatalf("expected 3 txs within dedupe window, got %d", len(list))
+	}
+	if list[0].Hash != "0xc" || !list[0].FirstSeen.Equal(t0.Add(2*time.Second)) {
+		t.Errorf("expected newest tx 0xc first, got %+v", list[0])
+	}
+	for _, tx := range list {
+		if tx.Hash == "0xb" && !tx.FirstSeen.Equal(t0) {
+			t.Errorf("expected 0xb to keep its first-seen time, got %v", tx.FirstSeen)
+		}
+	}
+
+	// 0xb is mined; 0xa has expired
+	list = p.merge([]pendingTx{{Hash: "0xc"}}, map[string]bool{"0xb": true}, t0.Add(11*time.Second))
+	if len(list) != 1 || list[0].Hash != "0xc" {
+		t.Errorf("expected only 0xc after mining and expiry, got %+v", list)
+	}
+}
+
+func TestMempoolPoller_MergeCap(t *testing.T) {
+	p := NewMempoolPoller("http://localhost:8545", nil, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	var current []pendingTx
+	for i := 0; i < maxPendingTxs+10; i++ {
+		current = append(current, pendingTx{Hash: string(rune('a'+i%26)) + string(rune('a'+i/26))})
+	}
+
+	t0 := time.Unix(1700000000, 0)
+	list := p.merge(current, nil, t0)
+	if len(list) != maxPendingTxs {
+		t.Errorf("expected list capped at %d, got %d", maxPendingTxs, len(list))
+	}
+	if len(p.seen) != len(current) {
+		t.Errorf("expected every pending tx tracked, got %d of %d", len(p.seen), len(current))
+	}
+
+	// Txs left out of the first list are not seen as new on the next poll
+	current = append(current, pendingTx{Hash: "new"})
+	list = p.merge(current, nil, t0.Add(2*time.Second))
+	if len(list) != maxPendingTxs || list[0].Hash != "new" {
+		t.Fatalf("expected the new tx first in a list of %d, got %d starting with %s", maxPendingTxs, len(list), list[0].Hash)
+	}
+	for _, tx := range list[1:] {
+		if !tx.FirstSeen.Equal(t0) {
+			t.Errorf("expected %s to keep its first-seen time, got %v", tx.Hash, tx.FirstSeen)
+		}
+	}
+}
+
+func TestMempoolPoller_ResponseSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"transactions":[{"hash":"0x` + strings.Repeat("a", 4096) + `"}]}}`))
+	}))
+	defer server.Close()
+
+	p := NewMempoolPoller(server.URL, nil, slog.New(slog.DiscardHandler))
+	p.SetMaxResponseSize(1024)
+	if err := p.pollPending(); !errors.Is(err, poller.ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
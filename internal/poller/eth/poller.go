@@ -2,21 +2,27 @@ package eth
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"math/big"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/internal/indexer/internal/poller"
 	"github.com/internal/indexer/pkg/types"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -28,23 +34,90 @@ const (
 	MaxLogBatchRetries = 5
 	// MaxEventsPerBlockPerContract prevents log-based DoS
 	MaxEventsPerBlockPerContract = 1000
+	// DefaultMaxLogsPerPoll caps the events held in memory by a single poll
+	DefaultMaxLogsPerPoll = 50000
 )
 
-// ContractConfig holds configuration for a monitored contract
+// MissingABIMode controls how logs from monitored contracts without an ABI are handled.
+// Contracts that are not monitored at all are never fetched (eth_getLogs is address-filtered).
+type MissingABIMode string
+
+const (
+	// MissingABIStoreRaw fetches the logs and stores them undecoded with decode_failed=true
+	MissingABIStoreRaw MissingABIMode = "store_raw"
+	// MissingABISkip excludes ABI-less contracts from the log filter entirely
+	MissingABISkip MissingABIMode = "skip"
+)
+
+// ErrPendingBlock is returned when the RPC answers with the not-yet-mined pending
+// block (null hash). Pending data belongs to the mempool poller, never to the
+// canonical tables.
+var ErrPendingBlock = errors.New("pending block")
+
+// ContractConfig holds configuration for a monitored contract (ABI may be nil).
+// With Topics set, only logs whose topic0 is one of them (or a token transfer
+// signature) are fetched for the contract; see EventTopics.
 type ContractConfig struct {
 	Address common.Address
 	ABI     *abi.ABI
 	Name    string
+	Topics  []common.Hash
+
+	// ImplementationABIs are tried in order for logs ABI has no event for, e.g.
+	// the implementations behind an EIP-1967 proxy, whose logs carry the proxy's
+	// address but the implementation's events
+	ImplementationABIs []*abi.ABI
+}
+
+// hasABI reports whether the contract has any ABI to decode its logs with
+func (c ContractConfig) hasABI() bool {
+	return c.ABI != nil || len(c.ImplementationABIs) > 0
+}
+
+// EventTopics returns the topic0 of each event in the given ABIs, for
+// ContractConfig.Topics; pass a proxy's ABI along with its implementation ABIs.
+// Anonymous events have no topic0 to filter on, so if any ABI declares one it
+// returns nil. Nil ABIs are skipped.
+func EventTopics(abis ...*abi.ABI) []common.Hash {
+	seen := make(map[common.Hash]bool)
+	var topics []common.Hash
+	for _, a := range abis {
+		if a == nil {
+			continue
+		}
+		for _, ev := range a.Events {
+			if ev.Anonymous {
+				return nil
+			}
+			if !seen[ev.ID] {
+				seen[ev.ID] = true
+				topics = append(topics, ev.ID)
+			}
+		}
+	}
+	slices.SortFunc(topics, func(x, y common.Hash) int { return x.Cmp(y) })
+	return topics
 }
 
 // Poller implements ChainPoller for Ethereum
 type Poller struct {
 	rpcURL            string
+	endpoints         *poller.Endpoints // rpcURL plus any fallbacks (see SetFallbackRPCURLs)
+	wsURL             string            // WebSocket endpoint for newHeads subscriptions (see Subscribe)
 	batchSize         int
 	logBatchSize      int
 	useFinalizedTag   bool
 	confirmationDepth int
 	contracts         []ContractConfig
+	contractTopics    map[common.Address]map[common.Hash]bool // Topic0 values selected per contract, for contracts with Topics
+	missingABI        MissingABIMode
+	maxLogsPerPoll    int
+	maxLogAddresses   int // Contract addresses per eth_getLogs filter (0 = all in one)
+	receiptStatus     bool
+	fetchReceipts     bool                // Fetch every block's receipts for gas used, fee and tx status
+	inflight          *singleflight.Group // Shares identical concurrent rpcCalls when set
+	maxResponseSize   int64               // Largest buffered RPC response body
+	fetchConcurrency  int                 // Blocks of a batch fetched at once
 	decoder           *Decoder
 	client            *http.Client
 	logger            *slog.Logger
@@ -52,11 +125,18 @@ type Poller struct {
 	// Metrics
 	logsIndexed     uint64
 	decodeFailures  uint64
+	oversizedEvents atomic.Uint64 // Events stored undecoded for exceeding the params size limit
 	rateLimitHits   uint64
 	rangeReductions uint64
 
-	// Cache
-	knownTokens map[common.Address]bool
+	// cutShort is why the last poll's batch stopped early, nil if it did not (see
+	// PollCutShort). Polls are only made from the chain's coordinator.
+	cutShort error
+
+	// Token metadata
+	knownTokens       map[common.Address]types.Token // Metadata of tokens already seen
+	defaultDecimals   int                            // Stored when decimals() reverts or returns no value
+	decimalsOverrides map[common.Address]int         // Used instead of calling decimals()
 }
 
 // NewPoller creates a new ETH poller
@@ -71,29 +151,216 @@ func NewPoller(
 ) *Poller {
 	// Build ABI map for decoder
 	abiMap := make(map[common.Address]*abi.ABI)
+	fallbackABIs := make(map[common.Address][]*abi.ABI)
+	contractTopics := make(map[common.Address]map[common.Hash]bool)
 	for _, c := range contracts {
 		if c.ABI != nil {
 			abiMap[c.Address] = c.ABI
 		}
+		if len(c.ImplementationABIs) > 0 {
+			fallbackABIs[c.Address] = c.ImplementationABIs
+		}
+		if len(c.Topics) > 0 {
+			contractTopics[c.Address] = make(map[common.Hash]bool, len(c.Topics))
+			for _, t := range c.Topics {
+				contractTopics[c.Address][t] = true
+			}
+		}
 	}
 
 	if logBatchSize == 0 {
 		logBatchSize = DefaultLogBatchSize
 	}
 
+	logger = logger.With("chain", "eth")
+
+	decoder := NewDecoder(abiMap)
+	decoder.SetFallbackABIs(fallbackABIs)
+
 	return &Poller{
 		rpcURL:            rpcURL,
+		endpoints:         poller.NewEndpoints([]string{rpcURL}, 0, logger),
 		batchSize:         batchSize,
 		logBatchSize:      logBatchSize,
 		useFinalizedTag:   useFinalizedTag,
 		confirmationDepth: confirmationDepth,
 		contracts:         contracts,
-		decoder:           NewDecoder(abiMap),
+		contractTopics:    contractTopics,
+		missingABI:        MissingABIStoreRaw,
+		maxLogsPerPoll:    DefaultMaxLogsPerPoll,
+		maxResponseSize:   poller.DefaultMaxResponseSize,
+		fetchConcurrency:  poller.DefaultFetchConcurrency,
+		decoder:           decoder,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		logger:      logger.With("chain", "eth"),
-		knownTokens: make(map[common.Address]bool),
+		logger:      logger,
+		knownTokens: make(map[common.Address]types.Token),
+	}
+}
+
+// SetFallbackRPCURLs adds endpoints that requests fail over to when the active one
+// errors, returns a 5xx or rate-limits; an endpoint that failed is skipped for
+// cooldown (zero or less uses the default) while others are available
+func (p *Poller) SetFallbackRPCURLs(urls []string, cooldown time.Duration) {
+	p.endpoints = poller.NewEndpoints(append([]string{p.rpcURL}, urls...), cooldown, p.logger)
+}
+
+// RPCEndpoint reports the index of the active RPC endpoint (0 = rpc_url) and how
+// many times requests have failed over
+func (p *Poller) RPCEndpoint() (active int, failovers uint64) {
+	return p.endpoints.Active(), p.endpoints.Failovers()
+}
+
+// SetFetchConcurrency sets how many blocks of a poll batch are fetched at once.
+// Zero or less keeps the default.
+func (p *Poller) SetFetchConcurrency(n int) {
+	if n > 0 {
+		p.fetchConcurrency = n
+	}
+}
+
+// SetDecodeLimits bounds the nesting depth and element count of decoded event values
+func (p *Poller) SetDecodeLimits(maxDepth, maxElements int) {
+	p.decoder.SetLimits(maxDepth, maxElements)
+}
+
+// SetMaxDecodedSize bounds the decoded params JSON stored per event; larger events
+// are stored undecoded with decode_failed set. Zero or less keeps the default.
+func (p *Poller) SetMaxDecodedSize(n int) {
+	p.decoder.SetMaxDecodedSize(n)
+}
+
+// SetMissingABIMode sets how monitored contracts without an ABI are handled
+func (p *Poller) SetMissingABIMode(mode MissingABIMode) {
+	if mode == "" {
+		mode = MissingABIStoreRaw
+	}
+	p.missingABI = mode
+}
+
+// SetTokenDecimals sets how token decimals are resolved when a token is first seen.
+// Tokens in overrides are stored with the given decimals without calling decimals();
+// tokens whose decimals() reverts or returns no usable value get defaultDecimals.
+func (p *Poller) SetTokenDecimals(defaultDecimals int, overrides map[string]int) {
+	p.defaultDecimals = defaultDecimals
+	p.decimalsOverrides = make(map[common.Address]int, len(overrides))
+	for addr, d := range overrides {
+		p.decimalsOverrides[common.HexToAddress(addr)] = d
+	}
+}
+
+// SetMaxLogsPerPoll caps the events a poll accumulates. Once n is reached the poll
+// ends at the current block and the remaining blocks are picked up by the next poll.
+// Zero or negative means unlimited.
+func (p *Poller) SetMaxLogsPerPoll(n int) {
+	p.maxLogsPerPoll = n
+}
+
+// SetMaxLogAddresses caps the contract addresses sent in one eth_getLogs filter,
+// for providers that limit the address array. Larger contract sets are split into
+// shards fetched one after another and merged; max_logs_per_poll applies to each
+// shard. Zero or negative means no cap.
+func (p *Poller) SetMaxLogAddresses(n int) {
+	p.maxLogAddresses = n
+}
+
+// SetReceiptStatus enables fetching the receipt of every transaction that emitted a
+// monitored event, so events carry their transaction's success/revert status.
+// Costs one eth_getTransactionReceipt call per such transaction.
+func (p *Poller) SetReceiptStatus(enabled bool) {
+	p.receiptStatus = enabled
+}
+
+// SetFetchReceipts enables fetching the receipts of every block's transactions, with
+// one eth_getBlockReceipts call per block (or one eth_getTransactionReceipt per
+// transaction where that is unsupported), to set each transaction's GasUsed, Fee
+// (gasUsed * effectiveGasPrice) and TxStatus. Disabled by default.
+func (p *Poller) SetFetchReceipts(enabled bool) {
+	p.fetchReceipts = enabled
+}
+
+// SetRPCDedupe makes concurrent rpcCalls with the same method and params share one
+// upstream request, e.g. GetBlockByHash from a reorg walk-back racing the poll loop.
+func (p *Poller) SetRPCDedupe(enabled bool) {
+	if enabled {
+		p.inflight = new(singleflight.Group)
+	} else {
+		p.inflight = nil
+	}
+}
+
+// SetMaxResponseSize bounds the RPC response bodies buffered by rpcCall; larger
+// responses fail with poller.ErrResponseTooLarge. Streamed eth_getLogs responses
+// are bounded by max_logs_per_poll instead. Zero or less keeps the default.
+func (p *Poller) SetMaxResponseSize(n int64) {
+	if n > 0 {
+		p.maxResponseSize = n
+	}
+}
+
+// logFilter is one eth_getLogs filter: contract addresses and, when set, the
+// topic0 values their logs are restricted to
+type logFilter struct {
+	addresses []string
+	topics    []string
+}
+
+// logFilters returns the filters to request logs with. Contracts without topics
+// share an address-only filter; contracts with topics share one on the union of
+// their topics and the token transfer signatures, since one eth_getLogs filter
+// applies its topics to every address. Logs another contract's topics let through
+// are dropped by wantsLog.
+func (p *Poller) logFilters() []logFilter {
+	var plain, filtered logFilter
+	topics := make(map[common.Hash]bool)
+	for _, c := range p.contracts {
+		if !c.hasABI() && p.missingABI == MissingABISkip {
+			continue
+		}
+		if len(c.Topics) == 0 {
+			plain.addresses = append(plain.addresses, c.Address.Hex())
+			continue
+		}
+		filtered.addresses = append(filtered.addresses, c.Address.Hex())
+		for _, t := range c.Topics {
+			topics[t] = true
+		}
+	}
+
+	var filters []logFilter
+	if len(plain.addresses) > 0 {
+		filters = append(filters, plain)
+	}
+	if len(filtered.addresses) > 0 {
+		topics[transferTopic], topics[transferSingleTopic], topics[transferBatchTopic] = true, true, true
+		for t := range topics {
+			filtered.topics = append(filtered.topics, t.Hex())
+		}
+		slices.Sort(filtered.topics)
+		filters = append(filters, filtered)
+	}
+	return filters
+}
+
+// wantsLog reports whether a log is one its contract's topics select, or is from
+// a contract without topics
+func (p *Poller) wantsLog(logMap map[string]interface{}) bool {
+	addr, _ := logMap["address"].(string)
+	topics := p.contractTopics[common.HexToAddress(addr)]
+	if topics == nil {
+		return true
+	}
+	logTopics, _ := logMap["topics"].([]interface{})
+	if len(logTopics) == 0 {
+		return false
+	}
+	topic0, _ := logTopics[0].(string)
+	switch t := common.HexToHash(topic0); t {
+	case transferTopic, transferSingleTopic, transferBatchTopic:
+		return true
+	default:
+		return topics[t]
 	}
 }
 
@@ -117,7 +384,10 @@ func (p *Poller) GetChainTip(ctx context.Context) (uint64, error) {
 	return parseHexUint64(hexNum)
 }
 
-// GetFinalizedHeight returns the finalized block height
+// GetFinalizedHeight returns the finalized block height: the node's "finalized" block
+// when useFinalizedTag is set, otherwise tip minus confirmation depth. It returns 0
+// while nothing is finalized yet (a chain shorter than the confirmation depth, or a
+// node that reports no finalized block).
 func (p *Poller) GetFinalizedHeight(ctx context.Context) (uint64, error) {
 	if !p.useFinalizedTag {
 		// Fallback to confirmation depth
@@ -125,10 +395,7 @@ func (p *Poller) GetFinalizedHeight(ctx context.Context) (uint64, error) {
 		if err != nil {
 			return 0, err
 		}
-		if tip < uint64(p.confirmationDepth) {
-			return 0, nil
-		}
-		return tip - uint64(p.confirmationDepth), nil
+		return types.HeightBelow(tip, p.confirmationDepth), nil
 	}
 
 	resp, err := p.rpcCall(ctx, "eth_getBlockByNumber", []interface{}{"finalized", false})
@@ -139,10 +406,7 @@ func (p *Poller) GetFinalizedHeight(ctx context.Context) (uint64, error) {
 		if tipErr != nil {
 			return 0, tipErr
 		}
-		if tip < uint64(p.confirmationDepth) {
-			return 0, nil
-		}
-		return tip - uint64(p.confirmationDepth), nil
+		return types.HeightBelow(tip, p.confirmationDepth), nil
 	}
 
 	if resp == nil {
@@ -170,6 +434,7 @@ func (p *Poller) Poll(ctx context.Context, lastHeight uint64) ([]types.Block, []
 
 // PollWithEvents fetches blocks, transactions, events, created contracts, tokens, and transfers
 func (p *Poller) PollWithEvents(ctx context.Context, lastHeight uint64) ([]types.Block, []types.Transaction, []types.Event, []types.Contract, []types.Token, []types.TokenTransfer, error) {
+	p.cutShort = nil
 	tip, err := p.GetChainTip(ctx)
 	if err != nil {
 		return nil, nil, nil, nil, nil, nil, err
@@ -190,101 +455,131 @@ func (p *Poller) PollWithEvents(ctx context.Context, lastHeight uint64) ([]types
 	var blocks []types.Block
 	var allTxs []types.Transaction
 	var createdContracts []types.Contract
+	var allEvents []types.Event
+	fetchedTo := endHeight
+	blocksTo := endHeight
 
-	// Fetch blocks and transactions
-	for height := startHeight; height <= endHeight; height++ {
-		select {
-		case <-ctx.Done():
-			return nil, nil, nil, nil, nil, nil, ctx.Err()
-		default:
+	// Blocks and logs come from different RPC methods, so fetch them concurrently.
+	// A failure on either side cancels the other.
+	g, gctx := errgroup.WithContext(ctx)
+
+	if len(p.contracts) > 0 {
+		g.Go(func() error {
+			events, to, err := p.fetchLogs(gctx, startHeight, endHeight)
+			if err != nil {
+				return fmt.Errorf("fetching logs: %w", err)
+			}
+			allEvents, fetchedTo = events, to
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		fetched, err := poller.FetchOrdered(gctx, int(endHeight-startHeight+1), p.fetchConcurrency, func(ctx context.Context, i int) (fetchedBlock, error) {
+			block, txs, contracts, err := p.getBlockByNumber(ctx, startHeight+uint64(i))
+			return fetchedBlock{block: block, txs: txs, contracts: contracts}, err
+		})
+		for _, f := range fetched {
+			blocks = append(blocks, *f.block)
+			allTxs = append(allTxs, f.txs...)
+			createdContracts = append(createdContracts, f.contracts...)
 		}
 
-		block, txs, contracts, err := p.getBlockByNumber(ctx, height)
+		height := startHeight + uint64(len(fetched))
+		if errors.Is(err, ErrPendingBlock) {
+			// The node is still building this height; index up to the block
+			// before it and retry on the next poll
+			p.logger.Warn("RPC returned the pending block, stopping batch", "height", height)
+			blocksTo = height - 1
+			return nil
+		}
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, fmt.Errorf("getting block %d: %w", height, err)
+			return fmt.Errorf("getting block %d: %w", height, err)
 		}
+		return nil
+	})
 
-		blocks = append(blocks, *block)
-		allTxs = append(allTxs, txs...)
-		createdContracts = append(createdContracts, contracts...)
+	if err := g.Wait(); err != nil {
+		return nil, nil, nil, nil, nil, nil, err
 	}
 
-	// Fetch events if contracts are configured
-	var allEvents []types.Event
-	if len(p.contracts) > 0 {
-		events, err := p.fetchLogs(ctx, startHeight, endHeight)
-		if err != nil {
-			return nil, nil, nil, nil, nil, nil, fmt.Errorf("fetching logs: %w", err)
-		}
-		allEvents = events
-	}
-
-	// Fetch ERC20 Transfers from all blocks (standard topic filter)
-	// Topic0: 0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef
-	transferTopic := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
-
-	// We want to fetch ALL transfers, not just monitored contracts.
-	// But `fetchLogs` filters by p.contracts.
-	// For "Token Intelligence", we usually want a global indexer.
-	// As per requirements "Token Intelligence (ERC20 Analytics)", we likely want global coverage or at least coverage for the existing logs if we only monitor specific contracts.
-	// However, usually an indexer for tokens monitors EVERYTHING.
-	// Limitation: If p.contracts is set, we only get those. If p.contracts is empty, fetchLogs does nothing?
-	// Let's assume for this task we piggyback on existing log fetching OR we do a separate pass for Transfers if we want global.
-	// Given the prompt "Implement Token Intelligence", I'll assume we parse the logs we ALREADY fetch (if any) AND maybe fetch global transfers if no contracts filter is likely used?
-	// Actually, `PollWithEvents` implementation currently depends on `p.contracts`.
-	// If `p.contracts` is populated, it filters. If empty, `fetchLogs` returns empty.
-	// FOR NOW: I will extract token transfers from the `allEvents` we already decoded, AND attempts to decode `Transfer` from any log that matches the topic, even if `decodeFailed` was true (raw log).
-
-	var tokens []types.Token
-	var tokenTransfers []types.TokenTransfer
-
-	// Use specific Transfer extraction from logs
-	for _, event := range allEvents {
-		if event.Topic0 == transferTopic.Hex() && len(event.Topics) == 3 {
-			// ERC20 Transfer(from indexed, to indexed, value)
-			tokenAddr := event.ContractAddr
-			from := common.HexToAddress(event.Topics[1]).Hex()
-			to := common.HexToAddress(event.Topics[2]).Hex()
-
-			// Value is data - decode from RawData because Data might be JSON
-			var rawLog map[string]interface{}
-			_ = json.Unmarshal([]byte(event.RawData), &rawLog)
-			dataHex, _ := rawLog["data"].(string)
-
-			valBig := new(big.Int).SetBytes(common.FromHex(dataHex))
-			if valBig == nil {
-				valBig = big.NewInt(0)
+	// On log-dense ranges the log fetch stops early to bound memory; drop the
+	// blocks it did not cover so the next poll picks them up with their events.
+	if fetchedTo < endHeight {
+		blocks, allTxs, createdContracts = trimToHeight(fetchedTo, blocks, allTxs, createdContracts)
+		p.cutShort = fmt.Errorf("max_logs_per_poll reached at block %d", fetchedTo)
+	}
+	// Likewise drop logs past a pending block, which has no canonical hash yet
+	if blocksTo < fetchedTo {
+		p.cutShort = fmt.Errorf("%w at height %d", ErrPendingBlock, blocksTo+1)
+		n := 0
+		for _, e := range allEvents {
+			if e.BlockHeight <= blocksTo {
+				allEvents[n] = e
+				n++
 			}
+		}
+		allEvents = allEvents[:n]
+	}
 
-			tokenTransfers = append(tokenTransfers, types.TokenTransfer{
-				ChainID:      types.ChainETH,
-				TxHash:       event.TxHash,
-				LogIndex:     uint(event.LogIndex),
-				TokenAddress: tokenAddr,
-				FromAddr:     from,
-				ToAddr:       to,
-				Amount:       valBig.String(),
-				BlockHeight:  event.BlockHeight,
-				BlockHash:    event.BlockHash,
-				Timestamp:    time.Unix(0, 0), // Ideally we need block timestamp here. Storage has it.
-			})
-
-			// Check for new token
-			cAddr := common.HexToAddress(tokenAddr)
-			if !p.knownTokens[cAddr] {
-				// Fetch metadata - avoid blocking if possible, or simple blocking is fine for now
-				meta, err := p.fetchTokenMetadata(ctx, tokenAddr)
-				if err == nil && meta != nil {
-					tokens = append(tokens, *meta)
-					p.knownTokens[cAddr] = true
-				}
-			}
+	if p.receiptStatus {
+		if err := p.annotateTxStatus(ctx, allEvents); err != nil {
+			return nil, nil, nil, nil, nil, nil, err
 		}
 	}
 
+	// Token transfers are recognized by signature among the fetched logs
+	tokens, tokenTransfers, err := p.extractTokenTransfers(ctx, blocks, allEvents)
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, err
+	}
+
 	return blocks, allTxs, allEvents, createdContracts, tokens, tokenTransfers, nil
 }
 
+// PollCutShort returns why the last poll's batch stopped before the tip or batch
+// size (the log cap, or a block the node is still building), or nil
+func (p *Poller) PollCutShort() error {
+	return p.cutShort
+}
+
+// fetchedBlock is one block of a poll batch with its transactions and created contracts
+type fetchedBlock struct {
+	block     *types.Block
+	txs       []types.Transaction
+	contracts []types.Contract
+}
+
+// trimToHeight drops blocks, transactions, and created contracts above height
+func trimToHeight(height uint64, blocks []types.Block, txs []types.Transaction, contracts []types.Contract) ([]types.Block, []types.Transaction, []types.Contract) {
+	n := 0
+	for _, b := range blocks {
+		if b.Height <= height {
+			blocks[n] = b
+			n++
+		}
+	}
+	blocks = blocks[:n]
+
+	n = 0
+	for _, t := range txs {
+		if t.BlockHeight <= height {
+			txs[n] = t
+			n++
+		}
+	}
+	txs = txs[:n]
+
+	n = 0
+	for _, c := range contracts {
+		if c.BlockHeight <= height {
+			contracts[n] = c
+			n++
+		}
+	}
+	return blocks, txs, contracts[:n]
+}
+
 // GetBlockByHash fetches a block by hash for reorg detection
 func (p *Poller) GetBlockByHash(ctx context.Context, hash string) (*types.Block, error) {
 	resp, err := p.rpcCall(ctx, "eth_getBlockByHash", []interface{}{hash, true})
@@ -299,6 +594,24 @@ func (p *Poller) GetBlockByHash(ctx context.Context, hash string) (*types.Block,
 	return p.parseBlock(resp)
 }
 
+// GetCanonicalHash returns the hash of the canonical block at height
+func (p *Poller) GetCanonicalHash(ctx context.Context, height uint64) (string, error) {
+	resp, err := p.rpcCall(ctx, "eth_getBlockByNumber", []interface{}{fmt.Sprintf("0x%x", height), false})
+	if err != nil {
+		return "", fmt.Errorf("eth_getBlockByNumber: %w", err)
+	}
+
+	blockMap, ok := resp.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("block %d not found", height)
+	}
+	hash, _ := blockMap["hash"].(string)
+	if hash == "" {
+		return "", ErrPendingBlock
+	}
+	return hash, nil
+}
+
 func (p *Poller) getBlockByNumber(ctx context.Context, height uint64) (*types.Block, []types.Transaction, []types.Contract, error) {
 	hexHeight := fmt.Sprintf("0x%x", height)
 
@@ -316,6 +629,11 @@ func (p *Poller) getBlockByNumber(ctx context.Context, height uint64) (*types.Bl
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	// Never trust the provider to answer the question asked: a block filed under the
+	// wrong height would corrupt the index
+	if block.Height != height {
+		return nil, nil, nil, fmt.Errorf("requested block %d, RPC returned block %d", height, block.Height)
+	}
 
 	txs, contracts, err := p.parseTransactions(ctx, resp, block)
 	if err != nil {
@@ -331,6 +649,12 @@ func (p *Poller) parseBlock(resp interface{}) (*types.Block, error) {
 		return nil, fmt.Errorf("unexpected block response type: %T", resp)
 	}
 
+	// The pending block reports a null hash (and, on some nodes, a null number);
+	// it must not reach the validation below as if it were a malformed mined block
+	if blockMap["hash"] == nil {
+		return nil, ErrPendingBlock
+	}
+
 	rawData, _ := json.Marshal(blockMap)
 
 	numHex, _ := blockMap["number"].(string)
@@ -342,6 +666,8 @@ func (p *Poller) parseBlock(resp interface{}) (*types.Block, error) {
 	hash, _ := blockMap["hash"].(string)
 	parentHash, _ := blockMap["parentHash"].(string)
 	timestampHex, _ := blockMap["timestamp"].(string)
+	miner, _ := blockMap["miner"].(string) // fee recipient after the merge
+	uncles := parseUncles(blockMap["uncles"])
 
 	timestamp, _ := parseHexUint64(timestampHex)
 
@@ -357,10 +683,25 @@ func (p *Poller) parseBlock(resp interface{}) (*types.Block, error) {
 		ParentHash: parentHash,
 		Timestamp:  time.Unix(int64(timestamp), 0),
 		Status:     types.StatusPending,
+		Miner:      types.NormalizeAddress(types.ChainETH, miner),
+		Uncles:     uncles,
 		RawData:    rawData,
 	}, nil
 }
 
+// parseUncles returns the uncle hashes of a block. Post-Merge blocks always report
+// an empty list, which yields nil.
+func parseUncles(v interface{}) []string {
+	raw, _ := v.([]interface{})
+	var uncles []string
+	for _, u := range raw {
+		if hash, ok := u.(string); ok {
+			uncles = append(uncles, hash)
+		}
+	}
+	return uncles
+}
+
 func (p *Poller) parseTransactions(ctx context.Context, blockResp interface{}, block *types.Block) ([]types.Transaction, []types.Contract, error) {
 	blockMap, ok := blockResp.(map[string]interface{})
 	if !ok {
@@ -375,6 +716,14 @@ func (p *Poller) parseTransactions(ctx context.Context, blockResp interface{}, b
 	var txs []types.Transaction
 	var contracts []types.Contract
 
+	var receipts map[string]*txReceipt
+	if p.fetchReceipts {
+		var err error
+		if receipts, err = p.fetchBlockReceipts(ctx, block.Hash, txsRaw); err != nil {
+			return nil, nil, fmt.Errorf("fetching receipts for block %d: %w", block.Height, err)
+		}
+	}
+
 	for i, txRaw := range txsRaw {
 		txMap, ok := txRaw.(map[string]interface{})
 		if !ok {
@@ -386,6 +735,7 @@ func (p *Poller) parseTransactions(ctx context.Context, blockResp interface{}, b
 		txHash, _ := txMap["hash"].(string)
 		from, _ := txMap["from"].(string)
 		to, _ := txMap["to"].(string) // May be null for contract creation
+		from, to = types.NormalizeAddress(types.ChainETH, from), types.NormalizeAddress(types.ChainETH, to)
 		valueHex, _ := txMap["value"].(string)
 
 		value := parseHexBigInt(valueHex)
@@ -402,22 +752,33 @@ func (p *Poller) parseTransactions(ctx context.Context, blockResp interface{}, b
 			Status:      types.StatusPending,
 			RawData:     rawData,
 		}
+		receipt, haveReceipt := receipts[txHash]
+		if haveReceipt {
+			applyReceipt(&tx, receipt, txMap)
+		}
 
 		txs = append(txs, tx)
 
 		// Check for contract creation
 		if to == "" {
-			// Fetch receipt to get contract address
-			receipt, err := p.fetchTransactionReceipt(ctx, txHash)
-			if err != nil {
-				p.logger.Warn("failed to fetch receipt for contract creation", "tx", txHash, "error", err)
-				continue
+			// Fetch receipt to get contract address; without it the contract would be
+			// lost, so fail the batch and retry it
+			if !haveReceipt {
+				var err error
+				receipt, err = p.fetchTransactionReceipt(ctx, txHash)
+				if err != nil {
+					return nil, nil, fmt.Errorf("fetching receipt for contract creation %s: %w", txHash, err)
+				}
+				if receipt == nil {
+					return nil, nil, fmt.Errorf("no receipt for contract creation %s", txHash)
+				}
 			}
 
-			if receipt != nil && receipt.ContractAddress != "" {
+			// A reverted creation deploys nothing at its contractAddress
+			if receipt.Status != "0x0" && receipt.ContractAddress != "" {
 				contracts = append(contracts, types.Contract{
 					ChainID:     types.ChainETH,
-					Address:     receipt.ContractAddress,
+					Address:     types.NormalizeAddress(types.ChainETH, receipt.ContractAddress),
 					CreatorAddr: from,
 					TxHash:      txHash,
 					BlockHeight: block.Height,
@@ -431,7 +792,11 @@ func (p *Poller) parseTransactions(ctx context.Context, blockResp interface{}, b
 }
 
 type txReceipt struct {
-	ContractAddress string `json:"contractAddress"`
+	TxHash            string
+	ContractAddress   string
+	Status            string   // "0x1" success, "0x0" reverted; absent before Byzantium
+	GasUsed           uint64   // Zero if absent
+	EffectiveGasPrice *big.Int // Nil if absent (some pre-London receipts)
 }
 
 func (p *Poller) fetchTransactionReceipt(ctx context.Context, txHash string) (*txReceipt, error) {
@@ -447,18 +812,81 @@ func (p *Poller) fetchTransactionReceipt(ctx context.Context, txHash string) (*t
 	if !ok {
 		return nil, fmt.Errorf("invalid receipt format")
 	}
+	return parseReceipt(receiptMap), nil
+}
 
-	addr, _ := receiptMap["contractAddress"].(string)
-	return &txReceipt{ContractAddress: addr}, nil
+// annotateTxStatus sets TxStatus on events from their transaction's receipt, fetching
+// each receipt once. Pre-Byzantium receipts have no status and leave TxStatus empty.
+func (p *Poller) annotateTxStatus(ctx context.Context, events []types.Event) error {
+	statuses := make(map[string]string)
+	for i := range events {
+		status, ok := statuses[events[i].TxHash]
+		if !ok {
+			receipt, err := p.fetchTransactionReceipt(ctx, events[i].TxHash)
+			if err != nil {
+				return fmt.Errorf("fetching receipt for %s: %w", events[i].TxHash, err)
+			}
+			if receipt != nil {
+				switch receipt.Status {
+				case "0x1":
+					status = types.TxStatusSuccess
+				case "0x0":
+					status = types.TxStatusReverted
+				}
+			}
+			statuses[events[i].TxHash] = status
+		}
+		events[i].TxStatus = status
+	}
+	return nil
 }
 
-func (p *Poller) fetchLogs(ctx context.Context, fromBlock, toBlock uint64) ([]types.Event, error) {
-	// Build contract address filter
-	addresses := make([]string, len(p.contracts))
-	for i, c := range p.contracts {
-		addresses[i] = c.Address.Hex()
+// fetchLogs returns events for [fromBlock, toBlock] and the last height actually covered,
+// which is below toBlock when maxLogsPerPoll was reached
+func (p *Poller) fetchLogs(ctx context.Context, fromBlock, toBlock uint64) ([]types.Event, uint64, error) {
+	filters := p.logFilters()
+	if len(filters) == 0 {
+		return nil, toBlock, nil // An empty filter would match every log on chain
+	}
+
+	// Each shard only fetches up to the height the previous ones covered, so a
+	// shard cut short by maxLogsPerPoll shortens the whole poll
+	var allEvents []types.Event
+	covered := toBlock
+	shards := 0
+	for _, filter := range filters {
+		shardSize := p.maxLogAddresses
+		if shardSize <= 0 || shardSize > len(filter.addresses) {
+			shardSize = len(filter.addresses)
+		}
+		for shard := range slices.Chunk(filter.addresses, shardSize) {
+			events, fetchedTo, err := p.fetchShardLogs(ctx, fromBlock, covered, logFilter{addresses: shard, topics: filter.topics})
+			if err != nil {
+				return nil, 0, err
+			}
+			allEvents = append(allEvents, events...)
+			covered = fetchedTo
+			shards++
+		}
+	}
+
+	if shards > 1 {
+		allEvents = slices.DeleteFunc(allEvents, func(e types.Event) bool { return e.BlockHeight > covered })
+		slices.SortFunc(allEvents, func(a, b types.Event) int {
+			if c := cmp.Compare(a.BlockHeight, b.BlockHeight); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.LogIndex, b.LogIndex)
+		})
 	}
 
+	return allEvents, covered, nil
+}
+
+// fetchShardLogs fetches the logs of one address shard in log_batch_size ranges,
+// halving the range when the provider rejects it as too large and backing off when
+// rate limited. Returns the events and the last height covered.
+func (p *Poller) fetchShardLogs(ctx context.Context, fromBlock, toBlock uint64, filter logFilter) ([]types.Event, uint64, error) {
 	var allEvents []types.Event
 	currentFrom := fromBlock
 	batchSize := uint64(p.logBatchSize)
@@ -467,7 +895,7 @@ func (p *Poller) fetchLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ty
 	for currentFrom <= toBlock {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, 0, ctx.Err()
 		default:
 		}
 
@@ -476,7 +904,15 @@ func (p *Poller) fetchLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ty
 			currentTo = toBlock
 		}
 
-		events, err := p.fetchLogsRange(ctx, currentFrom, currentTo, addresses)
+		limit := 0
+		if p.maxLogsPerPoll > 0 {
+			limit = p.maxLogsPerPoll - len(allEvents)
+			if limit <= 0 {
+				return allEvents, currentFrom - 1, nil
+			}
+		}
+
+		events, fetchedTo, err := p.fetchLogsRange(ctx, currentFrom, currentTo, filter, limit)
 		if err != nil {
 			// Check for rate limit
 			if isRateLimitError(err) {
@@ -484,7 +920,7 @@ func (p *Poller) fetchLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ty
 				time.Sleep(time.Second * time.Duration(1<<retries)) // Exponential backoff
 				retries++
 				if retries > MaxLogBatchRetries {
-					return nil, fmt.Errorf("max retries exceeded after rate limiting: %w", err)
+					return nil, 0, fmt.Errorf("max retries exceeded after rate limiting: %w", err)
 				}
 				continue
 			}
@@ -494,67 +930,93 @@ func (p *Poller) fetchLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ty
 				p.rangeReductions++
 				batchSize = batchSize / 2
 				if batchSize < MinLogBatchSize {
-					return nil, fmt.Errorf("log batch size reduced below minimum: %w", err)
+					return nil, 0, fmt.Errorf("log batch size reduced below minimum: %w", err)
 				}
 				p.logger.Warn("reducing log batch size", "new_size", batchSize)
 				retries++
 				if retries > MaxLogBatchRetries {
-					return nil, fmt.Errorf("max log batch retries exceeded: %w", err)
+					return nil, 0, fmt.Errorf("max log batch retries exceeded: %w", err)
 				}
 				continue
 			}
 
-			return nil, err
+			return nil, 0, err
 		}
 
 		allEvents = append(allEvents, events...)
-		currentFrom = currentTo + 1
 		retries = 0 // Reset retries on success
+
+		if fetchedTo < currentTo {
+			p.logger.Info("log limit reached, shortening poll",
+				"events", len(allEvents),
+				"limit", p.maxLogsPerPoll,
+				"to", fetchedTo,
+			)
+			return allEvents, fetchedTo, nil
+		}
+		currentFrom = currentTo + 1
 	}
 
-	return allEvents, nil
+	return allEvents, toBlock, nil
 }
 
-func (p *Poller) fetchLogsRange(ctx context.Context, fromBlock, toBlock uint64, addresses []string) ([]types.Event, error) {
+// errLogLimit stops a log stream once the per-poll limit is reached at a block boundary
+var errLogLimit = errors.New("log limit reached")
+
+// fetchLogsRange returns the events in [fromBlock, toBlock] and the last height fully covered.
+// With limit > 0, reading stops at the first block boundary after limit events, so a block's
+// logs are never split across polls.
+func (p *Poller) fetchLogsRange(ctx context.Context, fromBlock, toBlock uint64, filter logFilter, limit int) ([]types.Event, uint64, error) {
 	params := map[string]interface{}{
 		"fromBlock": fmt.Sprintf("0x%x", fromBlock),
 		"toBlock":   fmt.Sprintf("0x%x", toBlock),
-		"address":   addresses,
+		"address":   filter.addresses,
 	}
-
-	resp, err := p.rpcCall(ctx, "eth_getLogs", []interface{}{params})
-	if err != nil {
-		return nil, err
-	}
-
-	logsRaw, ok := resp.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("unexpected logs response type: %T", resp)
+	if len(filter.topics) > 0 {
+		params["topics"] = []interface{}{filter.topics}
 	}
 
 	// Track events per block per contract for DoS protection
 	eventCounts := make(map[uint64]map[common.Address]int)
 
+	// Logs are decoded one at a time so only the parsed events stay resident
 	var events []types.Event
-	for _, logRaw := range logsRaw {
-		logMap, ok := logRaw.(map[string]interface{})
-		if !ok {
-			continue
+	err := p.rpcStream(ctx, "eth_getLogs", []interface{}{params}, func(raw json.RawMessage) error {
+		var logMap map[string]interface{}
+		if err := json.Unmarshal(raw, &logMap); err != nil {
+			return nil // Not a log object
+		}
+		if !p.wantsLog(logMap) {
+			return nil
+		}
+
+		if limit > 0 && len(events) >= limit {
+			blockNumHex, _ := logMap["blockNumber"].(string)
+			if blockNum, err := parseHexUint64(blockNumHex); err == nil && blockNum > events[len(events)-1].BlockHeight {
+				return errLogLimit
+			}
 		}
 
 		event, err := p.parseLog(logMap, eventCounts)
 		if err != nil {
 			p.logger.Warn("failed to parse log", "error", err)
-			continue
+			return nil
 		}
 
 		if event != nil {
 			events = append(events, *event)
 			p.logsIndexed++
 		}
+		return nil
+	})
+	if errors.Is(err, errLogLimit) {
+		return events, events[len(events)-1].BlockHeight, nil
+	}
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return events, nil
+	return events, toBlock, nil
 }
 
 func (p *Poller) parseLog(logMap map[string]interface{}, eventCounts map[uint64]map[common.Address]int) (*types.Event, error) {
@@ -595,30 +1057,31 @@ func (p *Poller) parseLog(logMap map[string]interface{}, eventCounts map[uint64]
 	dataHex, _ := logMap["data"].(string)
 	rawData, _ := json.Marshal(logMap)
 
-	// Build go-ethereum log for decoder
-	var ethTopics []common.Hash
-	for _, t := range topics {
-		ethTopics = append(ethTopics, common.HexToHash(t))
-	}
-	ethLog := ethtypes.Log{
-		Address: address,
-		Topics:  ethTopics,
-		Data:    common.FromHex(dataHex),
-	}
-
 	// Attempt decode
 	var eventName string
 	var decodedData []byte
-	var decodeFailed bool
-
-	decoded, err := p.decoder.DecodeLog(ethLog)
+	var decodeFailed, oversized bool
+
+	decoded, err := p.decoder.DecodeLog(toEthLog(address, topics, dataHex))
+	if err == nil {
+		decodedData, err = p.decoder.MarshalParams(decoded)
+		if errors.Is(err, ErrDecodedTooLarge) {
+			oversized = true
+			p.oversizedEvents.Add(1)
+			p.logger.Warn("decoded event too large, storing undecoded",
+				"contract", addressStr,
+				"tx", txHash,
+				"log_index", logIndex,
+				"error", err,
+			)
+		}
+	}
 	if err != nil {
 		p.decodeFailures++
 		decodeFailed = true
 		p.logger.Debug("decode failed", "error", err, "contract", addressStr)
 	} else {
 		eventName = decoded.Name
-		decodedData, _ = json.Marshal(decoded.Params)
 	}
 
 	topic0 := ""
@@ -632,7 +1095,7 @@ func (p *Poller) parseLog(logMap map[string]interface{}, eventCounts map[uint64]
 		BlockHash:    blockHash,
 		TxHash:       txHash,
 		LogIndex:     int(logIndex),
-		ContractAddr: addressStr,
+		ContractAddr: types.NormalizeAddress(types.ChainETH, addressStr),
 		EventName:    eventName,
 		Topic0:       topic0,
 		Topics:       topics,
@@ -640,11 +1103,198 @@ func (p *Poller) parseLog(logMap map[string]interface{}, eventCounts map[uint64]
 		RawData:      rawData,
 		Status:       types.StatusPending,
 		DecodeFailed: decodeFailed,
+		Oversized:    oversized,
 	}, nil
 }
 
+// DecodeRawLog decodes an event from its stored raw_data (the eth_getLogs log
+// object) with the poller's current ABIs. Used to re-decode events stored with
+// decode_failed=true once their ABI has been added or fixed.
+func (p *Poller) DecodeRawLog(rawData []byte) (string, []byte, error) {
+	var raw struct {
+		Address string   `json:"address"`
+		Topics  []string `json:"topics"`
+		Data    string   `json:"data"`
+	}
+	if err := json.Unmarshal(rawData, &raw); err != nil {
+		return "", nil, fmt.Errorf("parsing raw log: %w", err)
+	}
+
+	decoded, err := p.decoder.DecodeLog(toEthLog(common.HexToAddress(raw.Address), raw.Topics, raw.Data))
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := p.decoder.MarshalParams(decoded)
+	if err != nil {
+		return "", nil, err
+	}
+	return decoded.Name, data, nil
+}
+
+// toEthLog builds the go-ethereum log the decoder works on
+func toEthLog(address common.Address, topics []string, dataHex string) ethtypes.Log {
+	var ethTopics []common.Hash
+	for _, t := range topics {
+		ethTopics = append(ethTopics, common.HexToHash(t))
+	}
+	return ethtypes.Log{
+		Address: address,
+		Topics:  ethTopics,
+		Data:    common.FromHex(dataHex),
+	}
+}
+
 // rpcCall makes a JSON-RPC call
 func (p *Poller) rpcCall(ctx context.Context, method string, params interface{}) (interface{}, error) {
+	respBody, err := p.rpcBody(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rpcResp struct {
+		Result interface{} `json:"result"`
+		Error  *rpcError   `json:"error"`
+	}
+
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	if rpcResp.Error != nil {
+		return nil, rpcResp.Error
+	}
+
+	return rpcResp.Result, nil
+}
+
+// rpcBody returns the raw response body of a JSON-RPC call. With dedupe enabled,
+// identical in-flight calls share one request; each caller still decodes its own
+// copy of the body, so results are never shared between callers.
+func (p *Poller) rpcBody(ctx context.Context, method string, params interface{}) ([]byte, error) {
+	if p.inflight == nil {
+		return p.readRPC(ctx, method, params)
+	}
+
+	key, err := json.Marshal([]interface{}{method, params})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request key: %w", err)
+	}
+
+	ch := p.inflight.DoChan(string(key), func() (interface{}, error) {
+		// Detached so the caller that started the request giving up does not fail
+		// the others waiting on it; the client timeout still bounds it
+		return p.readRPC(context.WithoutCancel(ctx), method, params)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]byte), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// readRPC performs one JSON-RPC request and reads the whole response body, up to
+// maxResponseSize
+func (p *Poller) readRPC(ctx context.Context, method string, params interface{}) ([]byte, error) {
+	resp, err := p.doRPC(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := poller.ReadResponse(resp.Body, p.maxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s response: %w", method, err)
+	}
+	return respBody, nil
+}
+
+// rpcStream makes a JSON-RPC call whose result is a JSON array and hands each element
+// to fn as it is decoded, so large responses are never held in memory in full
+func (p *Poller) rpcStream(ctx context.Context, method string, params interface{}, fn func(json.RawMessage) error) error {
+	resp, err := p.doRPC(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	if err := expectDelim(dec, '{'); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("parsing response: %w", err)
+		}
+
+		switch tok {
+		case "error":
+			var rpcErr *rpcError
+			if err := dec.Decode(&rpcErr); err != nil {
+				return fmt.Errorf("parsing response: %w", err)
+			}
+			if rpcErr != nil {
+				return rpcErr
+			}
+		case "result":
+			if err := decodeArray(dec, fn); err != nil {
+				return err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("parsing response: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// decodeArray streams the elements of a JSON array (or null) to fn
+func decodeArray(dec *json.Decoder, fn func(json.RawMessage) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return fmt.Errorf("unexpected result: %v", tok)
+	}
+
+	for dec.More() {
+		var elem json.RawMessage
+		if err := dec.Decode(&elem); err != nil {
+			return fmt.Errorf("parsing response: %w", err)
+		}
+		if err := fn(elem); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// doRPC sends a JSON-RPC request; the caller must close the response body
+func (p *Poller) doRPC(ctx context.Context, method string, params interface{}) (*http.Response, error) {
 	if params == nil {
 		params = []interface{}{}
 	}
@@ -661,45 +1311,41 @@ func (p *Poller) rpcCall(ctx context.Context, method string, params interface{})
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", p.rpcURL, bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := p.client.Do(req)
+	resp, err := p.endpoints.Do(ctx, func(url string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return p.client.Do(req)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("making request: %w", err)
 	}
-	defer resp.Body.Close()
 
 	// Check for rate limiting
 	if resp.StatusCode == 429 {
+		resp.Body.Close()
 		return nil, fmt.Errorf("rate limited: HTTP 429")
 	}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
-	}
-
-	var rpcResp struct {
-		Result interface{} `json:"result"`
-		Error  *struct {
-			Code    int    `json:"code"`
-			Message string `json:"message"`
-		} `json:"error"`
-	}
+	return resp, nil
+}
 
-	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
-	}
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
 
-	if rpcResp.Error != nil {
-		return nil, fmt.Errorf("RPC error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
-	}
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("RPC error %d: %s", e.Code, e.Message)
+}
 
-	return rpcResp.Result, nil
+// OversizedEvents returns how many events were stored undecoded because their
+// decoded params exceeded the size limit
+func (p *Poller) OversizedEvents() uint64 {
+	return p.oversizedEvents.Load()
 }
 
 // GetMetrics returns ETH-specific metrics
@@ -797,78 +1443,3 @@ func containsImpl(s, substr string) bool {
 	}
 	return false
 }
-
-// Token Metadata Fetching
-
-func (p *Poller) fetchTokenMetadata(ctx context.Context, tokenAddr string) (*types.Token, error) {
-	// Simple caching strategy is already in place via knownTokens map (in-memory).
-	// Call RPC for name, symbol, decimals.
-	// Use eth_call
-
-	name, _ := p.callStringMethod(ctx, tokenAddr, "06fdde03")    // name()
-	symbol, _ := p.callStringMethod(ctx, tokenAddr, "95d89b41")  // symbol()
-	decimals, _ := p.callUint8Method(ctx, tokenAddr, "313ce567") // decimals()
-
-	return &types.Token{
-		ChainID:         types.ChainETH,
-		Address:         tokenAddr,
-		Name:            name,
-		Symbol:          symbol,
-		Decimals:        int(decimals),
-		FirstSeenHeight: 0, // Filled by storage or caller
-		LastSeenHeight:  0,
-		CreatedAt:       time.Now(),
-	}, nil
-}
-
-func (p *Poller) callStringMethod(ctx context.Context, to string, selector string) (string, error) {
-	callMsg := map[string]string{
-		"to":   to,
-		"data": "0x" + selector,
-	}
-	resp, err := p.rpcCall(ctx, "eth_call", []interface{}{callMsg, "latest"})
-	if err != nil {
-		return "", err
-	}
-
-	resHex, ok := resp.(string)
-	if !ok {
-		return "", fmt.Errorf("not string")
-	}
-
-	b := common.FromHex(resHex)
-	if len(b) == 0 {
-		return "", nil
-	}
-
-	// Try to decode as string using ABI
-	stringABI, _ := abi.NewType("string", "", nil)
-	args := abi.Arguments{{Type: stringABI}}
-	if vals, err := args.Unpack(b); err == nil {
-		if s, ok := vals[0].(*string); ok {
-			return *s, nil
-		}
-		if s, ok := vals[0].(string); ok {
-			return s, nil
-		}
-	}
-	return "", fmt.Errorf("decode failed")
-}
-
-func (p *Poller) callUint8Method(ctx context.Context, to string, selector string) (uint8, error) {
-	callMsg := map[string]string{
-		"to":   to,
-		"data": "0x" + selector,
-	}
-	resp, err := p.rpcCall(ctx, "eth_call", []interface{}{callMsg, "latest"})
-	if err != nil {
-		return 0, err
-	}
-	resHex, ok := resp.(string)
-	if !ok {
-		return 0, fmt.Errorf("not string")
-	}
-
-	b := parseHexBigInt(resHex)
-	return uint8(b.Uint64()), nil
-}
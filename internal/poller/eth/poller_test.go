@@ -3,13 +3,25 @@ package eth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"log/slog"
 	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/internal/indexer/internal/poller"
+	"github.com/internal/indexer/pkg/types"
 )
 
 func TestPoller_ChainID(t *testing.T) {
@@ -177,6 +189,47 @@ func TestPoller_GetChainTip(t *testing.T) {
 	}
 }
 
+func TestPoller_GetFinalizedHeight(t *testing.T) {
+	tests := []struct {
+		name      string
+		useTag    bool
+		tip       string
+		finalized interface{}
+		want      uint64
+	}{
+		{name: "depth below confirmation depth", tip: "0x5", want: 0},
+		{name: "depth at confirmation depth", tip: "0xc", want: 0},
+		{name: "depth past confirmation depth", tip: "0x100", want: 244},
+		{name: "tag with nothing finalized", useTag: true, tip: "0x5", finalized: nil, want: 0},
+		{name: "tag", useTag: true, tip: "0x100", finalized: map[string]interface{}{"number": "0xe0"}, want: 224},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mockRPCServer(func(method string, params interface{}) interface{} {
+				switch method {
+				case "eth_blockNumber":
+					return tt.tip
+				case "eth_getBlockByNumber":
+					return tt.finalized
+				}
+				return nil
+			})
+			defer server.Close()
+
+			poller := NewPoller(server.URL, 100, 2000, tt.useTag, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			got, err := poller.GetFinalizedHeight(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetFinalizedHeight() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestPoller_Poll_AtTip(t *testing.T) {
 	server := mockRPCServer(func(method string, params interface{}) interface{} {
 		if method == "eth_blockNumber" {
@@ -201,3 +254,1309 @@ func TestPoller_Poll_AtTip(t *testing.T) {
 		t.Errorf("expected no txs when at tip, got %d", len(txs))
 	}
 }
+
+func TestPoller_MonitoredContractWithoutABI(t *testing.T) {
+	const contractAddr = "0x00000000000000000000000000000000000000aa"
+
+	var getLogsCalls int
+	var requestedAddrs []interface{}
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		if method != "eth_getLogs" {
+			return nil
+		}
+		getLogsCalls++
+		filter := params.([]interface{})[0].(map[string]interface{})
+		requestedAddrs = filter["address"].([]interface{})
+		return []interface{}{
+			map[string]interface{}{
+				"address":         contractAddr,
+				"blockNumber":     "0x10",
+				"blockHash":       "0xblock",
+				"transactionHash": "0xtx",
+				"logIndex":        "0x0",
+				"topics":          []interface{}{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+				"data":            "0x01",
+			},
+		}
+	})
+	defer server.Close()
+
+	contracts := []ContractConfig{{Address: HexToAddress(contractAddr)}} // monitored, no ABI
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	t.Run("store_raw", func(t *testing.T) {
+		poller := NewPoller(server.URL, 100, 2000, true, 12, contracts, logger)
+
+		events, _, err := poller.fetchLogs(context.Background(), 16, 16)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(requestedAddrs) != 1 {
+			t.Fatalf("expected log filter on the monitored contract, got %v", requestedAddrs)
+		}
+		if len(events) != 1 {
+			t.Fatalf("expected 1 raw event, got %d", len(events))
+		}
+		if !events[0].DecodeFailed || events[0].EventName != "" || len(events[0].RawData) == 0 {
+			t.Errorf("expected undecoded event with raw data, got %+v", events[0])
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		getLogsCalls = 0
+		poller := NewPoller(server.URL, 100, 2000, true, 12, contracts, logger)
+		poller.SetMissingABIMode(MissingABISkip)
+
+		events, _, err := poller.fetchLogs(context.Background(), 16, 16)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if getLogsCalls != 0 {
+			t.Errorf("expected no eth_getLogs call when all contracts lack ABIs, got %d", getLogsCalls)
+		}
+		if len(events) != 0 {
+			t.Errorf("expected no events, got %d", len(events))
+		}
+	})
+}
+
+func TestPoller_DecodeRawLogAfterABIAdded(t *testing.T) {
+	const contractAddr = "0x00000000000000000000000000000000000000aa"
+
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		if method != "eth_getLogs" {
+			return nil
+		}
+		return []interface{}{
+			map[string]interface{}{
+				"address":         contractAddr,
+				"blockNumber":     "0x10",
+				"blockHash":       "0xblock",
+				"transactionHash": "0xtx",
+				"logIndex":        "0x0",
+				"topics": []interface{}{
+					"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+					"0x0000000000000000000000000000000000000000000000000000000000000001",
+					"0x0000000000000000000000000000000000000000000000000000000000000002",
+				},
+				"data": "0x00000000000000000000000000000000000000000000000000000000000003e8",
+			},
+		}
+	})
+	defer server.Close()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	// Indexed before the ABI was configured: stored raw with decode_failed
+	before := NewPoller(server.URL, 100, 2000, true, 12, []ContractConfig{{Address: HexToAddress(contractAddr)}}, logger)
+	events, _, err := before.fetchLogs(context.Background(), 16, 16)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || !events[0].DecodeFailed {
+		t.Fatalf("expected 1 undecoded event, got %+v", events)
+	}
+	if _, _, err := before.DecodeRawLog(events[0].RawData); !errors.Is(err, ErrNoABI) {
+		t.Errorf("expected ErrNoABI without an ABI, got %v", err)
+	}
+
+	// Restarted with the ABI: the stored raw_data now decodes
+	parsedABI, err := LoadABIFromJSON([]byte(transferABI))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	after := NewPoller(server.URL, 100, 2000, true, 12, []ContractConfig{{Address: HexToAddress(contractAddr), ABI: parsedABI}}, logger)
+
+	name, data, err := after.DecodeRawLog(events[0].RawData)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if name != "Transfer" {
+		t.Errorf("expected Transfer, got %q", name)
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(data, &params); err != nil {
+		t.Fatalf("decoded data is not JSON: %v", err)
+	}
+	if params["value"] != "1000" || params["to"] != "0x0000000000000000000000000000000000000002" {
+		t.Errorf("unexpected params: %v", params)
+	}
+}
+
+func TestPoller_FetchLogsAddressShards(t *testing.T) {
+	// Contract i logs once, at block 16 + i%2 with log index i
+	var contracts []ContractConfig
+	for i := 0; i < 5; i++ {
+		contracts = append(contracts, ContractConfig{Address: HexToAddress(fmt.Sprintf("0x%040x", i+1))})
+	}
+
+	var filters []map[string]interface{}
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		if method != "eth_getLogs" {
+			return nil
+		}
+		filter := params.([]interface{})[0].(map[string]interface{})
+		filters = append(filters, filter)
+		toBlock, _ := parseHexUint64(filter["toBlock"].(string))
+
+		var logs []interface{}
+		for _, block := range []uint64{16, 17} {
+			for _, addr := range filter["address"].([]interface{}) {
+				i, _ := parseHexUint64(addr.(string))
+				if 16+(i-1)%2 != block || block > toBlock {
+					continue
+				}
+				logs = append(logs, map[string]interface{}{
+					"address":         addr,
+					"blockNumber":     fmt.Sprintf("0x%x", block),
+					"blockHash":       "0xblock",
+					"transactionHash": fmt.Sprintf("0xtx%d", i),
+					"logIndex":        fmt.Sprintf("0x%x", i-1),
+					"topics":          []interface{}{"0x01"},
+					"data":            "0x",
+				})
+			}
+		}
+		return logs
+	})
+	defer server.Close()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("merged", func(t *testing.T) {
+		filters = nil
+		poller := NewPoller(server.URL, 100, 2000, true, 12, contracts, logger)
+		poller.SetMaxLogAddresses(2)
+
+		events, fetchedTo, err := poller.fetchLogs(context.Background(), 16, 17)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(filters) != 3 {
+			t.Fatalf("expected 5 addresses in 3 eth_getLogs calls, got %d", len(filters))
+		}
+		for _, f := range filters {
+			if n := len(f["address"].([]interface{})); n > 2 {
+				t.Errorf("expected at most 2 addresses per call, got %d", n)
+			}
+		}
+		if fetchedTo != 17 || len(events) != 5 {
+			t.Fatalf("expected 5 events through block 17, got %d through %d", len(events), fetchedTo)
+		}
+		// Merged in chain order: block 16 holds log indexes 0, 2, 4 and block 17 holds 1, 3
+		want := []int{0, 2, 4, 1, 3}
+		for i, e := range events {
+			if e.LogIndex != want[i] {
+				t.Errorf("event %d: expected log index %d, got %d (block %d)", i, want[i], e.LogIndex, e.BlockHeight)
+			}
+		}
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		filters = nil
+		poller := NewPoller(server.URL, 100, 2000, true, 12, contracts, logger)
+		poller.SetMaxLogAddresses(2)
+		poller.SetMaxLogsPerPoll(1)
+
+		// The first shard stops after block 16, so later shards don't fetch past it
+		events, fetchedTo, err := poller.fetchLogs(context.Background(), 16, 17)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fetchedTo != 16 {
+			t.Fatalf("expected poll cut at block 16, got %d", fetchedTo)
+		}
+		for _, f := range filters[1:] {
+			if f["toBlock"] != "0x10" {
+				t.Errorf("expected later shards to stop at block 16, got toBlock %v", f["toBlock"])
+			}
+		}
+		if len(events) != 3 {
+			t.Fatalf("expected the 3 block-16 events, got %+v", events)
+		}
+		for _, e := range events {
+			if e.BlockHeight != 16 {
+				t.Errorf("expected no event past block 16, got one in block %d", e.BlockHeight)
+			}
+		}
+	})
+}
+
+func TestPoller_PollWithEventsLogCapCutsShort(t *testing.T) {
+	const contractAddr = "0x00000000000000000000000000000000000000aa"
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		switch method {
+		case "eth_blockNumber":
+			return "0x11"
+		case "eth_getBlockByNumber":
+			height, _ := parseHexUint64(params.([]interface{})[0].(string))
+			return map[string]interface{}{
+				"number":       fmt.Sprintf("0x%x", height),
+				"hash":         fmt.Sprintf("0x%064x", height),
+				"parentHash":   fmt.Sprintf("0x%064x", height-1),
+				"timestamp":    "0x0",
+				"transactions": []interface{}{},
+			}
+		case "eth_getLogs":
+			filter := params.([]interface{})[0].(map[string]interface{})
+			fromBlock, _ := parseHexUint64(filter["fromBlock"].(string))
+			var logs []interface{}
+			for i, block := range []uint64{16, 17} {
+				if block < fromBlock {
+					continue
+				}
+				logs = append(logs, map[string]interface{}{
+					"address":         contractAddr,
+					"blockNumber":     fmt.Sprintf("0x%x", block),
+					"blockHash":       fmt.Sprintf("0x%064x", block),
+					"transactionHash": fmt.Sprintf("0xtx%d", i),
+					"logIndex":        "0x0",
+					"topics":          []interface{}{"0x01"},
+					"data":            "0x",
+				})
+			}
+			return logs
+		}
+		return nil
+	})
+	defer server.Close()
+
+	contracts := []ContractConfig{{Address: HexToAddress(contractAddr)}}
+	poller := NewPoller(server.URL, 100, 2000, true, 12, contracts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	poller.SetMaxLogsPerPoll(1)
+
+	// The log cap stops the batch after block 16, short of the tip
+	blocks, _, _, _, _, _, err := poller.PollWithEvents(context.Background(), 15)
+	if err != nil {
+		t.Fatalf("PollWithEvents: %v", err)
+	}
+	if len(blocks) != 1 || blocks[0].Height != 16 {
+		t.Fatalf("expected only block 16, got %+v", blocks)
+	}
+	if err := poller.PollCutShort(); err == nil {
+		t.Error("expected the batch reported cut short by the log cap")
+	}
+
+	// The rest of the range fits
+	if _, _, _, _, _, _, err := poller.PollWithEvents(context.Background(), 16); err != nil {
+		t.Fatalf("PollWithEvents: %v", err)
+	}
+	if err := poller.PollCutShort(); err != nil {
+		t.Errorf("expected a full batch, got %v", err)
+	}
+}
+
+// syntheticLogsServer serves n logs spread evenly over blocks [from, from+blocks) for eth_getLogs
+func syntheticLogsServer(contractAddr string, from uint64, blocks, n int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":[`))
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			block := from + uint64(i*blocks/n)
+			fmt.Fprintf(w, `{"address":"%s","blockNumber":"0x%x","blockHash":"0xblock","transactionHash":"0xtx%d","logIndex":"0x%x","topics":["0x01"],"data":"0x"}`,
+				contractAddr, block, i, i)
+		}
+		w.Write([]byte(`]}`))
+	}))
+}
+
+func TestPoller_FetchLogsLargeResponse(t *testing.T) {
+	const contractAddr = "0x00000000000000000000000000000000000000aa"
+	const numLogs = 20000
+
+	server := syntheticLogsServer(contractAddr, 100, 100, numLogs)
+	defer server.Close()
+
+	contracts := []ContractConfig{{Address: HexToAddress(contractAddr)}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("unlimited", func(t *testing.T) {
+		poller := NewPoller(server.URL, 100, 2000, true, 12, contracts, logger)
+		poller.SetMaxLogsPerPoll(0)
+
+		events, fetchedTo, err := poller.fetchLogs(context.Background(), 100, 199)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events) != numLogs {
+			t.Errorf("expected %d events, got %d", numLogs, len(events))
+		}
+		if fetchedTo != 199 {
+			t.Errorf("expected full range fetched, got to %d", fetchedTo)
+		}
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		poller := NewPoller(server.URL, 100, 2000, true, 12, contracts, logger)
+		poller.SetMaxLogsPerPoll(5000)
+
+		events, fetchedTo, err := poller.fetchLogs(context.Background(), 100, 199)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// 200 logs per block: the limit falls on a block boundary after block 124
+		if fetchedTo != 124 {
+			t.Errorf("expected poll cut at block 124, got %d", fetchedTo)
+		}
+		if len(events) != 5000 {
+			t.Errorf("expected 5000 events, got %d", len(events))
+		}
+		if last := events[len(events)-1].BlockHeight; last != fetchedTo {
+			t.Errorf("expected last event in block %d, got %d", fetchedTo, last)
+		}
+	})
+}
+
+func BenchmarkPoller_FetchLogsRange(b *testing.B) {
+	const contractAddr = "0x00000000000000000000000000000000000000aa"
+
+	server := syntheticLogsServer(contractAddr, 100, 100, 20000)
+	defer server.Close()
+
+	contracts := []ContractConfig{{Address: HexToAddress(contractAddr)}}
+	poller := NewPoller(server.URL, 100, 2000, true, 12, contracts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	filter := poller.logFilters()[0]
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := poller.fetchLogsRange(context.Background(), 100, 199, filter, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPoller_PollWithEventsConcurrentFetch(t *testing.T) {
+	const contractAddr = "0x00000000000000000000000000000000000000aa"
+
+	contracts := []ContractConfig{{Address: HexToAddress(contractAddr)}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	block := func(params interface{}) interface{} {
+		height, _ := parseHexUint64(params.([]interface{})[0].(string))
+		return map[string]interface{}{
+			"number":       fmt.Sprintf("0x%x", height),
+			"hash":         fmt.Sprintf("0x%064x", height),
+			"parentHash":   fmt.Sprintf("0x%064x", height-1),
+			"timestamp":    "0x0",
+			"transactions": []interface{}{},
+		}
+	}
+
+	t.Run("both paths run", func(t *testing.T) {
+		// Each RPC method waits for the other to be called, so a serial poll would stall
+		blockCalled, logsCalled := make(chan struct{}), make(chan struct{})
+		var blockOnce, logsOnce sync.Once
+
+		server := mockRPCServer(func(method string, params interface{}) interface{} {
+			switch method {
+			case "eth_blockNumber":
+				return "0x11"
+			case "eth_getBlockByNumber":
+				blockOnce.Do(func() { close(blockCalled) })
+				select {
+				case <-logsCalled:
+				case <-time.After(2 * time.Second):
+					return nil
+				}
+				return block(params)
+			case "eth_getLogs":
+				logsOnce.Do(func() { close(logsCalled) })
+				select {
+				case <-blockCalled:
+				case <-time.After(2 * time.Second):
+					return nil
+				}
+				return []interface{}{
+					map[string]interface{}{
+						"address":         contractAddr,
+						"blockNumber":     "0x10",
+						"blockHash":       fmt.Sprintf("0x%064x", 16),
+						"transactionHash": "0xtx",
+						"logIndex":        "0x0",
+						"topics":          []interface{}{"0x01"},
+						"data":            "0x",
+					},
+				}
+			}
+			return nil
+		})
+		defer server.Close()
+
+		poller := NewPoller(server.URL, 2, 2000, true, 12, contracts, logger)
+
+		blocks, _, events, _, _, _, err := poller.PollWithEvents(context.Background(), 15)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(blocks) != 2 {
+			t.Errorf("expected 2 blocks, got %d", len(blocks))
+		}
+		if len(events) != 1 {
+			t.Errorf("expected 1 event, got %d", len(events))
+		}
+	})
+
+	t.Run("error cancels other path", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Method string `json:"method"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+
+			switch req.Method {
+			case "eth_blockNumber":
+				w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x11"}`))
+			case "eth_getLogs":
+				w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"boom"}}`))
+			default:
+				// Hang until the poller gives up on the request
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			}
+		}))
+		defer server.Close()
+
+		poller := NewPoller(server.URL, 2, 2000, true, 12, contracts, logger)
+
+		start := time.Now()
+		_, _, _, _, _, _, err := poller.PollWithEvents(context.Background(), 15)
+		if err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Fatalf("expected logs error to propagate, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("PollWithEvents took %v, expected the block fetch to be cancelled", elapsed)
+		}
+	})
+}
+
+func TestPoller_GetBlockByNumberWrongHeight(t *testing.T) {
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		if method != "eth_getBlockByNumber" {
+			return nil
+		}
+		// Asked for 0x10, answers with 0x11
+		return map[string]interface{}{
+			"number":       "0x11",
+			"hash":         fmt.Sprintf("0x%064x", 17),
+			"parentHash":   fmt.Sprintf("0x%064x", 16),
+			"timestamp":    "0x0",
+			"transactions": []interface{}{},
+		}
+	})
+	defer server.Close()
+
+	poller := NewPoller(server.URL, 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, _, _, err := poller.getBlockByNumber(context.Background(), 16)
+	if err == nil || !strings.Contains(err.Error(), "returned block 17") {
+		t.Fatalf("expected height mismatch error, got %v", err)
+	}
+}
+
+func TestPoller_ResponseSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":"0x%s"}`, strings.Repeat("0", 4096))
+	}))
+	defer server.Close()
+
+	p := NewPoller(server.URL, 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.SetMaxResponseSize(1024)
+
+	if _, err := p.GetChainTip(context.Background()); !errors.Is(err, poller.ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+
+	p.SetMaxResponseSize(8192)
+	if _, err := p.GetChainTip(context.Background()); err != nil {
+		t.Fatalf("unexpected error under the limit: %v", err)
+	}
+}
+
+func TestPoller_PendingBlockNotIndexed(t *testing.T) {
+	// Tip is 0x11 but the node answers for 0x11 with the pending block (null hash)
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		switch method {
+		case "eth_blockNumber":
+			return "0x11"
+		case "eth_getBlockByNumber":
+			if params.([]interface{})[0] == "0x11" {
+				return map[string]interface{}{
+					"number":       "0x11",
+					"hash":         nil,
+					"nonce":        nil,
+					"parentHash":   fmt.Sprintf("0x%064x", 16),
+					"timestamp":    "0x0",
+					"transactions": []interface{}{},
+				}
+			}
+			return map[string]interface{}{
+				"number":       "0x10",
+				"hash":         fmt.Sprintf("0x%064x", 16),
+				"parentHash":   fmt.Sprintf("0x%064x", 15),
+				"timestamp":    "0x0",
+				"transactions": []interface{}{},
+			}
+		}
+		return nil
+	})
+	defer server.Close()
+
+	poller := NewPoller(server.URL, 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, _, _, err := poller.getBlockByNumber(context.Background(), 17); !errors.Is(err, ErrPendingBlock) {
+		t.Fatalf("expected ErrPendingBlock, got %v", err)
+	}
+
+	blocks, _, err := poller.Poll(context.Background(), 15)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(blocks) != 1 || blocks[0].Height != 16 {
+		t.Fatalf("expected only block 16 to be indexed, got %+v", blocks)
+	}
+	if err := poller.PollCutShort(); !errors.Is(err, ErrPendingBlock) {
+		t.Errorf("expected the batch reported cut short at the pending block, got %v", err)
+	}
+
+	// Nothing indexable when the next height is still pending
+	blocks, _, err = poller.Poll(context.Background(), 16)
+	if err != nil || len(blocks) != 0 {
+		t.Fatalf("expected no blocks and no error, got %d blocks, %v", len(blocks), err)
+	}
+}
+
+func TestPoller_ParseBlockUncles(t *testing.T) {
+	poller := NewPoller("http://unused", 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	block := map[string]interface{}{
+		"number":     "0x10",
+		"hash":       fmt.Sprintf("0x%064x", 16),
+		"parentHash": fmt.Sprintf("0x%064x", 15),
+		"timestamp":  "0x0",
+		"uncles":     []interface{}{fmt.Sprintf("0x%064x", 0xa), fmt.Sprintf("0x%064x", 0xb)},
+	}
+	b, err := poller.parseBlock(block)
+	if err != nil {
+		t.Fatalf("parseBlock: %v", err)
+	}
+	if len(b.Uncles) != 2 || b.Uncles[1] != fmt.Sprintf("0x%064x", 0xb) {
+		t.Errorf("expected 2 uncles in order, got %v", b.Uncles)
+	}
+
+	// Post-Merge blocks report an empty list
+	block["uncles"] = []interface{}{}
+	b, err = poller.parseBlock(block)
+	if err != nil {
+		t.Fatalf("parseBlock: %v", err)
+	}
+	if b.Uncles != nil {
+		t.Errorf("expected no uncles, got %v", b.Uncles)
+	}
+}
+
+func TestPoller_AnnotateTxStatus(t *testing.T) {
+	var receiptCalls int
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		if method != "eth_getTransactionReceipt" {
+			return nil
+		}
+		receiptCalls++
+		switch params.([]interface{})[0] {
+		case "0xok":
+			return map[string]interface{}{"status": "0x1"}
+		case "0xreverted":
+			return map[string]interface{}{"status": "0x0"}
+		default:
+			return map[string]interface{}{} // Pre-Byzantium: no status field
+		}
+	})
+	defer server.Close()
+
+	poller := NewPoller(server.URL, 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	events := []types.Event{
+		{TxHash: "0xok", LogIndex: 0},
+		{TxHash: "0xreverted", LogIndex: 1},
+		{TxHash: "0xreverted", LogIndex: 2},
+		{TxHash: "0xold", LogIndex: 3},
+	}
+	if err := poller.annotateTxStatus(context.Background(), events); err != nil {
+		t.Fatalf("annotateTxStatus: %v", err)
+	}
+
+	want := []string{types.TxStatusSuccess, types.TxStatusReverted, types.TxStatusReverted, ""}
+	for i, e := range events {
+		if e.TxStatus != want[i] {
+			t.Errorf("event %d: TxStatus = %q, want %q", i, e.TxStatus, want[i])
+		}
+	}
+	if receiptCalls != 3 {
+		t.Errorf("expected one receipt call per tx (3), got %d", receiptCalls)
+	}
+}
+
+func TestPoller_RPCDedupe(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	hash := fmt.Sprintf("0x%064x", 16)
+
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		requests.Add(1)
+		<-release // Hold the first request so the others pile up behind it
+		return map[string]interface{}{
+			"number":     "0x10",
+			"hash":       hash,
+			"parentHash": fmt.Sprintf("0x%064x", 15),
+			"timestamp":  "0x0",
+		}
+	})
+	defer server.Close()
+
+	poller := NewPoller(server.URL, 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	poller.SetRPCDedupe(true)
+
+	const callers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			block, err := poller.GetBlockByHash(context.Background(), hash)
+			if err == nil && block.Height != 16 {
+				err = fmt.Errorf("unexpected height %d", block.Height)
+			}
+			errs <- err
+		}()
+	}
+
+	// Let every caller join the in-flight request before it completes
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("GetBlockByHash: %v", err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected 1 upstream request, got %d", n)
+	}
+}
+
+func TestPoller_FetchTokenMetadataDecimals(t *testing.T) {
+	const (
+		sixDecimals = "0x00000000000000000000000000000000000000a1"
+		reverting   = "0x00000000000000000000000000000000000000a2"
+		noMethod    = "0x00000000000000000000000000000000000000a3"
+		overridden  = "0x00000000000000000000000000000000000000a4"
+		rateLimited = "0x00000000000000000000000000000000000000a5"
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var call struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		json.Unmarshal(req.Params[0], &call)
+
+		if call.Data != "0x313ce567" {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x"}`))
+			return
+		}
+		switch call.To {
+		case sixDecimals:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x0000000000000000000000000000000000000000000000000000000000000006"}`))
+		case reverting:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"execution reverted"}}`))
+		case noMethod:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x"}`))
+		case rateLimited:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"limit exceeded"}}`))
+		default:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x0000000000000000000000000000000000000000000000000000000000000012"}`))
+		}
+	}))
+	defer server.Close()
+
+	poller := NewPoller(server.URL, 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	poller.SetTokenDecimals(18, map[string]int{strings.ToUpper(overridden[2:]): 8})
+
+	tests := []struct {
+		token string
+		want  int
+	}{
+		{token: sixDecimals, want: 6},
+		{token: reverting, want: 18},
+		{token: noMethod, want: 18},
+		{token: overridden, want: 8},
+	}
+	for _, tt := range tests {
+		meta, _, err := poller.fetchTokenMetadata(context.Background(), tt.token, 100)
+		if err != nil {
+			t.Fatalf("token %s: %v", tt.token, err)
+		}
+		if meta.Decimals != tt.want {
+			t.Errorf("token %s: expected %d decimals, got %d", tt.token, tt.want, meta.Decimals)
+		}
+	}
+
+	// A call that failed for reasons unrelated to the token is not defaulted
+	if meta, _, err := poller.fetchTokenMetadata(context.Background(), rateLimited, 100); err == nil {
+		t.Errorf("expected an error for a rate limited decimals() call, got %d decimals", meta.Decimals)
+	}
+}
+
+func TestPoller_FetchReceipts(t *testing.T) {
+	block := map[string]interface{}{
+		"number":     "0x10",
+		"hash":       fmt.Sprintf("0x%064x", 16),
+		"parentHash": fmt.Sprintf("0x%064x", 15),
+		"timestamp":  "0x0",
+		"transactions": []interface{}{
+			map[string]interface{}{"hash": "0xok", "from": "0xaa", "to": "0xbb", "value": "0x64", "gasPrice": "0x5"},
+			map[string]interface{}{"hash": "0xreverted", "from": "0xaa", "to": "0xcc", "value": "0x32", "gasPrice": "0x7"},
+		},
+	}
+	receipts := map[string]map[string]interface{}{
+		"0xok":       {"transactionHash": "0xok", "status": "0x1", "gasUsed": "0x5208", "effectiveGasPrice": "0x3"},
+		"0xreverted": {"transactionHash": "0xreverted", "status": "0x0", "gasUsed": "0x100"}, // No effectiveGasPrice: falls back to gasPrice
+	}
+
+	for _, blockReceipts := range []bool{true, false} {
+		var methods []string
+		server := mockRPCServer(func(method string, params interface{}) interface{} {
+			methods = append(methods, method)
+			switch method {
+			case "eth_getBlockByNumber":
+				return block
+			case "eth_getBlockReceipts":
+				if !blockReceipts {
+					return nil // Unsupported: falls back to per-tx receipts
+				}
+				return []interface{}{receipts["0xok"], receipts["0xreverted"]}
+			case "eth_getTransactionReceipt":
+				return receipts[params.([]interface{})[0].(string)]
+			}
+			return nil
+		})
+
+		poller := NewPoller(server.URL, 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		poller.SetFetchReceipts(true)
+
+		_, txs, _, err := poller.getBlockByNumber(context.Background(), 16)
+		server.Close()
+		if err != nil {
+			t.Fatalf("getBlockByNumber: %v", err)
+		}
+		if len(txs) != 2 {
+			t.Fatalf("expected 2 txs, got %d", len(txs))
+		}
+
+		ok, reverted := txs[0], txs[1]
+		if ok.GasUsed != 21000 || ok.Fee != "63000" || ok.TxStatus != types.TxStatusSuccess {
+			t.Errorf("block receipts %v: unexpected successful tx: gas %d, fee %s, status %q", blockReceipts, ok.GasUsed, ok.Fee, ok.TxStatus)
+		}
+		// Reverted txs are still indexed, with their value, but flagged
+		if reverted.GasUsed != 256 || reverted.Fee != "1792" || reverted.TxStatus != types.TxStatusReverted || reverted.Value != "50" {
+			t.Errorf("block receipts %v: unexpected reverted tx: gas %d, fee %s, status %q, value %s", blockReceipts, reverted.GasUsed, reverted.Fee, reverted.TxStatus, reverted.Value)
+		}
+
+		wantCalls := 2 // eth_getBlockByNumber, eth_getBlockReceipts
+		if !blockReceipts {
+			wantCalls += 2 // One eth_getTransactionReceipt per tx
+		}
+		if len(methods) != wantCalls {
+			t.Errorf("block receipts %v: expected %d RPC calls, got %v", blockReceipts, wantCalls, methods)
+		}
+	}
+}
+
+func TestPoller_PollWithEventsContractCreation(t *testing.T) {
+	creation := "0x" + strings.Repeat("ab", 32)
+	reverted := "0x" + strings.Repeat("cd", 32)
+	var receiptDown atomic.Bool
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		switch method {
+		case "eth_blockNumber":
+			return "0x10"
+		case "eth_getBlockByNumber":
+			return map[string]interface{}{
+				"number":     "0x10",
+				"hash":       fmt.Sprintf("0x%064x", 16),
+				"parentHash": fmt.Sprintf("0x%064x", 15),
+				"timestamp":  "0x64",
+				"transactions": []interface{}{
+					map[string]interface{}{"hash": "0xcall", "from": "0xAA00000000000000000000000000000000000001", "to": "0xbb00000000000000000000000000000000000002", "value": "0x0"},
+					map[string]interface{}{"hash": creation, "from": "0xAA00000000000000000000000000000000000001", "to": nil, "value": "0x0"},
+					map[string]interface{}{"hash": reverted, "from": "0xAA00000000000000000000000000000000000001", "to": nil, "value": "0x0"},
+				},
+			}
+		case "eth_getTransactionReceipt":
+			switch params.([]interface{})[0] {
+			case creation:
+				if receiptDown.Load() {
+					return nil
+				}
+				return map[string]interface{}{"transactionHash": creation, "status": "0x1", "contractAddress": "0xCC00000000000000000000000000000000000003"}
+			case reverted:
+				return map[string]interface{}{"transactionHash": reverted, "status": "0x0", "contractAddress": "0xDD00000000000000000000000000000000000004"}
+			}
+		case "eth_getLogs":
+			return []interface{}{}
+		}
+		return nil
+	})
+	defer server.Close()
+
+	poller := NewPoller(server.URL, 100, 2000, false, 0, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	blocks, _, _, contracts, _, _, err := poller.PollWithEvents(context.Background(), 15)
+	if err != nil {
+		t.Fatalf("PollWithEvents: %v", err)
+	}
+	if len(blocks) != 1 {
+		t.Fatalf("expected 1 block, got %d", len(blocks))
+	}
+	// The reverted creation deployed nothing
+	if len(contracts) != 1 {
+		t.Fatalf("expected 1 created contract, got %+v", contracts)
+	}
+	c := contracts[0]
+	if c.Address != "0xcc00000000000000000000000000000000000003" || c.CreatorAddr != "0xaa00000000000000000000000000000000000001" ||
+		c.TxHash != creation || c.BlockHeight != 16 || c.CreatedAt.Unix() != 100 {
+		t.Errorf("unexpected contract: %+v", c)
+	}
+
+	// A creation whose receipt can't be fetched fails the batch rather than losing the contract
+	receiptDown.Store(true)
+	if _, _, _, _, _, _, err := poller.PollWithEvents(context.Background(), 15); err == nil || !strings.Contains(err.Error(), creation) {
+		t.Errorf("expected the missing receipt to fail the batch, got %v", err)
+	}
+}
+
+func TestPoller_ExtractTokenTransfers(t *testing.T) {
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		return nil // name(), symbol() and decimals() all unavailable
+	})
+	defer server.Close()
+
+	poller := NewPoller(server.URL, 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	poller.SetTokenDecimals(18, nil)
+
+	const (
+		erc20   = "0x00000000000000000000000000000000000000b1"
+		erc721  = "0x00000000000000000000000000000000000000b2"
+		erc1155 = "0x00000000000000000000000000000000000000b3"
+		alice   = "0x00000000000000000000000000000000000000a1"
+		bob     = "0x00000000000000000000000000000000000000a2"
+	)
+	topicOf := func(addr string) string { return "0x" + strings.Repeat("0", 24) + addr[2:] }
+	word := func(n int64) string { return fmt.Sprintf("%064x", n) }
+	event := func(height uint64, logIndex int, contract string, data string, topics ...string) types.Event {
+		raw, _ := json.Marshal(map[string]interface{}{"data": data})
+		return types.Event{
+			BlockHeight:  height,
+			TxHash:       fmt.Sprintf("0x%064x", height),
+			LogIndex:     logIndex,
+			ContractAddr: contract,
+			Topic0:       topics[0],
+			Topics:       topics,
+			RawData:      raw,
+		}
+	}
+	batchData, err := transferBatchArgs.Pack(
+		[]*big.Int{big.NewInt(1), big.NewInt(2)},
+		[]*big.Int{big.NewInt(5), big.NewInt(7)},
+	)
+	if err != nil {
+		t.Fatalf("packing batch data: %v", err)
+	}
+
+	events := []types.Event{
+		event(10, 0, erc20, "0x"+word(1000), transferTopic.Hex(), topicOf(alice), topicOf(bob)),
+		event(10, 1, erc721, "0x", transferTopic.Hex(), topicOf(alice), topicOf(bob), "0x"+word(42)),
+		event(11, 0, erc1155, "0x"+word(3)+word(9), transferSingleTopic.Hex(), topicOf(alice), topicOf(alice), topicOf(bob)),
+		event(11, 1, erc1155, fmt.Sprintf("0x%x", batchData), transferBatchTopic.Hex(), topicOf(alice), topicOf(bob), topicOf(alice)),
+		event(12, 0, erc20, "0x"+word(1), transferTopic.Hex(), topicOf(bob), topicOf(alice)),
+		event(12, 1, erc20, "0x", "0x"+word(99)), // unrelated event
+	}
+	blocks := []types.Block{
+		{Height: 10, Timestamp: time.Unix(1000, 0)},
+		{Height: 11, Timestamp: time.Unix(1012, 0)},
+		{Height: 12, Timestamp: time.Unix(1024, 0)},
+	}
+
+	tokens, transfers, err := poller.extractTokenTransfers(context.Background(), blocks, events)
+	if err != nil {
+		t.Fatalf("extractTokenTransfers: %v", err)
+	}
+
+	want := []struct {
+		token, from, to, amount string
+		height                  uint64
+	}{
+		{erc20, alice, bob, "1000", 10},
+		{erc721, alice, bob, "1", 10},
+		{erc1155, alice, bob, "9", 11},
+		{erc1155, bob, alice, "12", 11},
+		{erc20, bob, alice, "1", 12},
+	}
+	if len(transfers) != len(want) {
+		t.Fatalf("expected %d transfers, got %d: %+v", len(want), len(transfers), transfers)
+	}
+	for i, w := range want {
+		tr := transfers[i]
+		if tr.TokenAddress != w.token || tr.FromAddr != w.from || tr.ToAddr != w.to || tr.Amount != w.amount {
+			t.Errorf("transfer %d: got %s %s->%s %s, want %s %s->%s %s",
+				i, tr.TokenAddress, tr.FromAddr, tr.ToAddr, tr.Amount, w.token, w.from, w.to, w.amount)
+		}
+		if !tr.Timestamp.Equal(blocks[w.height-10].Timestamp) {
+			t.Errorf("transfer %d: expected block %d timestamp, got %v", i, w.height, tr.Timestamp)
+		}
+	}
+
+	seen := map[string][2]uint64{erc20: {10, 12}, erc721: {10, 10}, erc1155: {11, 11}}
+	if len(tokens) != len(seen) {
+		t.Fatalf("expected %d tokens, got %d", len(seen), len(tokens))
+	}
+	for _, tok := range tokens {
+		heights, ok := seen[tok.Address]
+		if !ok {
+			t.Errorf("unexpected token %s", tok.Address)
+			continue
+		}
+		if tok.FirstSeenHeight != heights[0] || tok.LastSeenHeight != heights[1] {
+			t.Errorf("token %s: seen %d-%d, want %d-%d", tok.Address, tok.FirstSeenHeight, tok.LastSeenHeight, heights[0], heights[1])
+		}
+		if tok.Decimals != 18 {
+			t.Errorf("token %s: expected default decimals, got %d", tok.Address, tok.Decimals)
+		}
+	}
+}
+
+func TestPoller_TokenMetadata(t *testing.T) {
+	const (
+		standard = "0x00000000000000000000000000000000000000c1"
+		bytes32  = "0x00000000000000000000000000000000000000c2"
+	)
+	stringResult := func(s string) string {
+		packed, _ := stringArgs.Pack(s)
+		return fmt.Sprintf("0x%x", packed)
+	}
+	bytes32Result := func(s string) string {
+		return "0x" + fmt.Sprintf("%x", s) + strings.Repeat("0", 64-2*len(s))
+	}
+
+	var calls atomic.Int32
+	var blockTags sync.Map
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		if method != "eth_call" {
+			return nil
+		}
+		calls.Add(1)
+		args := params.([]interface{})
+		call := args[0].(map[string]interface{})
+		blockTags.Store(args[1], true)
+		to, data := call["to"].(string), call["data"].(string)
+		switch data {
+		case "0x" + selectorName:
+			if to == bytes32 {
+				return bytes32Result("Maker")
+			}
+			return stringResult("Wrapped Ether")
+		case "0x" + selectorSymbol:
+			if to == bytes32 {
+				return bytes32Result("MKR")
+			}
+			return stringResult("WETH")
+		case "0x" + selectorDecimals:
+			return fmt.Sprintf("0x%064x", 18)
+		}
+		return nil
+	})
+	defer server.Close()
+
+	poller := NewPoller(server.URL, 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		token, name, symbol string
+	}{
+		{standard, "Wrapped Ether", "WETH"},
+		{bytes32, "Maker", "MKR"},
+	}
+	for _, tt := range tests {
+		token, err := poller.tokenMetadata(context.Background(), tt.token, 0x64)
+		if err != nil {
+			t.Fatalf("token %s: %v", tt.token, err)
+		}
+		if token.Name != tt.name || token.Symbol != tt.symbol || token.Decimals != 18 {
+			t.Errorf("token %s: got %q %q %d, want %q %q 18", tt.token, token.Name, token.Symbol, token.Decimals, tt.name, tt.symbol)
+		}
+	}
+	if _, ok := blockTags.Load("0x64"); !ok {
+		t.Error("expected metadata to be fetched at the discovery block")
+	}
+	if _, ok := blockTags.Load("latest"); ok {
+		t.Error("expected no fallback to the latest block")
+	}
+
+	// Cached tokens are not queried again
+	before := calls.Load()
+	if _, err := poller.tokenMetadata(context.Background(), standard, 0x65); err != nil {
+		t.Fatalf("tokenMetadata: %v", err)
+	}
+	if got := calls.Load(); got != before {
+		t.Errorf("expected cached metadata, got %d more eth_call", got-before)
+	}
+}
+
+func TestPoller_TokenMetadataFailures(t *testing.T) {
+	const (
+		flaky     = "0x00000000000000000000000000000000000000d1" // name() rate limited once
+		pruned    = "0x00000000000000000000000000000000000000d2" // No state at the discovery block
+		reverting = "0x00000000000000000000000000000000000000d3" // name() and symbol() revert
+	)
+	packed, _ := stringArgs.Pack("Token")
+	token := fmt.Sprintf(`"0x%x"`, packed)
+
+	var flakyFailed atomic.Bool
+	var latest sync.Map // Tokens called at latest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var call struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		json.Unmarshal(req.Params[0], &call)
+		var tag string
+		json.Unmarshal(req.Params[1], &tag)
+		if tag == "latest" {
+			latest.Store(call.To, true)
+		}
+
+		result := token
+		if call.Data == "0x"+selectorDecimals {
+			result = fmt.Sprintf(`"0x%064x"`, 18)
+		}
+		switch {
+		case call.To == flaky && call.Data == "0x"+selectorName && flakyFailed.CompareAndSwap(false, true):
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"limit exceeded"}}`))
+		case call.To == pruned && tag != "latest":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"missing trie node 5e1c (path ) state 0x5e1c is not available"}}`))
+		case call.To == reverting && call.Data != "0x"+selectorDecimals:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"execution reverted"}}`))
+		default:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`))
+		}
+	}))
+	defer server.Close()
+
+	poller := NewPoller(server.URL, 100, 2000, true, 12, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// A name() call that failed for a reason other than the token is not cached
+	meta, err := poller.tokenMetadata(context.Background(), flaky, 0x64)
+	if err != nil {
+		t.Fatalf("tokenMetadata: %v", err)
+	}
+	if meta.Name != "" || meta.Symbol != "Token" {
+		t.Errorf("expected no name on the first fetch, got %q %q", meta.Name, meta.Symbol)
+	}
+	if meta, err = poller.tokenMetadata(context.Background(), flaky, 0x65); err != nil || meta.Name != "Token" {
+		t.Errorf("expected the name to be fetched again, got %q (%v)", meta.Name, err)
+	}
+
+	// Missing state at the discovery block falls back to latest
+	if meta, err = poller.tokenMetadata(context.Background(), pruned, 0x64); err != nil || meta.Name != "Token" {
+		t.Errorf("expected metadata from the latest block, got %q (%v)", meta.Name, err)
+	}
+	if _, ok := latest.Load(pruned); !ok {
+		t.Error("expected a fallback to latest for missing state")
+	}
+
+	// Reverts are the token's answer: not retried at latest, and cached
+	if meta, err = poller.tokenMetadata(context.Background(), reverting, 0x64); err != nil || meta.Name != "" || meta.Decimals != 18 {
+		t.Errorf("expected empty name and symbol, got %+v (%v)", meta, err)
+	}
+	if _, ok := latest.Load(reverting); ok {
+		t.Error("expected no fallback to latest for a revert")
+	}
+	if _, ok := poller.knownTokens[common.HexToAddress(reverting)]; !ok {
+		t.Error("expected metadata of a token whose calls revert to be cached")
+	}
+}
+
+func TestPoller_FetchLogsTopicFilter(t *testing.T) {
+	const (
+		approvalAddr = "0x00000000000000000000000000000000000000a1" // ABI with Approval
+		depositAddr  = "0x00000000000000000000000000000000000000a2" // ABI with Deposit
+		plainAddr    = "0x00000000000000000000000000000000000000a3" // no topics
+	)
+	approvalABI, err := LoadABIFromJSON([]byte(`[{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"spender","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	depositABI, err := LoadABIFromJSON([]byte(`[{"type":"event","name":"Deposit","inputs":[{"name":"dst","type":"address","indexed":true},{"name":"wad","type":"uint256","indexed":false}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	approval := approvalABI.Events["Approval"].ID.Hex()
+	deposit := depositABI.Events["Deposit"].ID.Hex()
+
+	var filters []map[string]interface{}
+	server := mockRPCServer(func(method string, params interface{}) interface{} {
+		if method != "eth_getLogs" {
+			return nil
+		}
+		filter := params.([]interface{})[0].(map[string]interface{})
+		filters = append(filters, filter)
+
+		log := func(addr, topic0 string, logIndex int) interface{} {
+			return map[string]interface{}{
+				"address":         addr,
+				"blockNumber":     "0x10",
+				"blockHash":       "0xblock",
+				"transactionHash": "0xtx",
+				"logIndex":        fmt.Sprintf("0x%x", logIndex),
+				"topics":          []interface{}{topic0},
+				"data":            "0x",
+			}
+		}
+		if _, ok := filter["topics"]; !ok {
+			return []interface{}{log(plainAddr, fmt.Sprintf("0x%064x", 1), 0)}
+		}
+		// The shared filter lets the Deposit signature through for the Approval contract too
+		return []interface{}{
+			log(approvalAddr, approval, 1),
+			log(approvalAddr, deposit, 2),
+			log(approvalAddr, transferTopic.Hex(), 3),
+			log(depositAddr, deposit, 4),
+		}
+	})
+	defer server.Close()
+
+	contracts := []ContractConfig{
+		{Address: HexToAddress(approvalAddr), ABI: approvalABI, Topics: EventTopics(approvalABI)},
+		{Address: HexToAddress(depositAddr), ABI: depositABI, Topics: EventTopics(depositABI)},
+		{Address: HexToAddress(plainAddr)},
+	}
+	poller := NewPoller(server.URL, 100, 2000, true, 12, contracts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	events, _, err := poller.fetchLogs(context.Background(), 16, 16)
+	if err != nil {
+		t.Fatalf("fetchLogs: %v", err)
+	}
+
+	if len(filters) != 2 {
+		t.Fatalf("expected an address-only and a topic filter, got %v", filters)
+	}
+	topics, _ := filters[1]["topics"].([]interface{})
+	if len(topics) != 1 {
+		t.Fatalf("expected a topics[0] filter, got %v", filters[1]["topics"])
+	}
+	want := map[string]bool{approval: true, deposit: true, transferTopic.Hex(): true, transferSingleTopic.Hex(): true, transferBatchTopic.Hex(): true}
+	got, _ := topics[0].([]interface{})
+	if len(got) != len(want) {
+		t.Errorf("expected topics %v, got %v", want, got)
+	}
+	for _, topic := range got {
+		if !want[topic.(string)] {
+			t.Errorf("unexpected topic %v in filter", topic)
+		}
+	}
+
+	var logIndexes []int
+	for _, e := range events {
+		logIndexes = append(logIndexes, e.LogIndex)
+	}
+	if fmt.Sprint(logIndexes) != "[0 1 3 4]" {
+		t.Errorf("expected the Deposit log of the Approval contract to be dropped, got log indexes %v", logIndexes)
+	}
+}
+
+// newHeadsService serves eth_subscribe("newHeads"), sending the queued heads
+type newHeadsService struct {
+	heads []string
+}
+
+func (s *newHeadsService) NewHeads(ctx context.Context) (*gethrpc.Subscription, error) {
+	notifier, ok := gethrpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, gethrpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	go func() {
+		for _, number := range s.heads {
+			notifier.Notify(sub.ID, map[string]string{"number": number})
+		}
+	}()
+	return sub, nil
+}
+
+func TestPoller_Subscribe(t *testing.T) {
+	srv := gethrpc.NewServer()
+	if err := srv.RegisterName("eth", &newHeadsService{heads: []string{"0x10", "0x11"}}); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(srv.WebsocketHandler([]string{"*"}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("not configured", func(t *testing.T) {
+		p := NewPoller(server.URL, 100, 2000, true, 12, nil, logger)
+		if _, err := p.Subscribe(context.Background()); !errors.Is(err, poller.ErrSubscriptionUnavailable) {
+			t.Errorf("expected ErrSubscriptionUnavailable, got %v", err)
+		}
+		p.SetWSURL(server.URL) // http://
+		if _, err := p.Subscribe(context.Background()); err == nil {
+			t.Error("expected an error for a non-WebSocket URL")
+		}
+	})
+
+	t.Run("new heads", func(t *testing.T) {
+		p := NewPoller(server.URL, 100, 2000, true, 12, nil, logger)
+		p.SetWSURL("ws" + strings.TrimPrefix(server.URL, "http"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		heads, err := p.Subscribe(ctx)
+		if err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+
+		// The receiver may miss the first head, but always sees the latest
+		var last uint64
+		for last != 0x11 {
+			select {
+			case h := <-heads:
+				last = h
+			case <-time.After(2 * time.Second):
+				t.Fatalf("timed out waiting for heads, last %d", last)
+			}
+		}
+
+		cancel()
+		select {
+		case _, ok := <-heads:
+			if ok {
+				t.Error("expected no further heads")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected the channel to close on cancellation")
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// parseReceipt extracts the fields the indexer uses from a JSON-RPC receipt
+func parseReceipt(receiptMap map[string]interface{}) *txReceipt {
+	r := &txReceipt{}
+	r.TxHash, _ = receiptMap["transactionHash"].(string)
+	r.ContractAddress, _ = receiptMap["contractAddress"].(string)
+	r.Status, _ = receiptMap["status"].(string)
+	if gasUsed, ok := receiptMap["gasUsed"].(string); ok {
+		r.GasUsed, _ = parseHexUint64(gasUsed)
+	}
+	if price, ok := receiptMap["effectiveGasPrice"].(string); ok && price != "" {
+		r.EffectiveGasPrice = parseHexBigInt(price)
+	}
+	return r
+}
+
+// fetchBlockReceipts returns the receipts of a block's transactions by tx hash.
+// It uses eth_getBlockReceipts and, when the node does not support it (or the
+// call fails), fetches each transaction's receipt instead.
+func (p *Poller) fetchBlockReceipts(ctx context.Context, blockHash string, txsRaw []interface{}) (map[string]*txReceipt, error) {
+	receipts := make(map[string]*txReceipt, len(txsRaw))
+	if len(txsRaw) == 0 {
+		return receipts, nil
+	}
+
+	resp, err := p.rpcCall(ctx, "eth_getBlockReceipts", []interface{}{blockHash})
+	if err == nil {
+		if list, ok := resp.([]interface{}); ok && len(list) == len(txsRaw) {
+			for _, item := range list {
+				if receiptMap, ok := item.(map[string]interface{}); ok {
+					r := parseReceipt(receiptMap)
+					receipts[r.TxHash] = r
+				}
+			}
+			return receipts, nil
+		}
+		err = fmt.Errorf("unexpected eth_getBlockReceipts response: %T", resp)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	p.logger.Debug("eth_getBlockReceipts unavailable, fetching receipts per transaction", "block", blockHash, "error", err)
+
+	for _, txRaw := range txsRaw {
+		txMap, ok := txRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		txHash, _ := txMap["hash"].(string)
+		r, err := p.fetchTransactionReceipt(ctx, txHash)
+		if err != nil {
+			return nil, fmt.Errorf("fetching receipt for %s: %w", txHash, err)
+		}
+		if r != nil {
+			receipts[txHash] = r
+		}
+	}
+	return receipts, nil
+}
+
+// applyReceipt sets a transaction's GasUsed, Fee and TxStatus from its receipt.
+// Fee is gasUsed * effectiveGasPrice in wei, falling back to the transaction's
+// gasPrice for receipts without effectiveGasPrice. A reverted transaction keeps
+// its Value but is flagged TxStatusReverted, as no value was transferred.
+func applyReceipt(tx *types.Transaction, r *txReceipt, txMap map[string]interface{}) {
+	tx.GasUsed = r.GasUsed
+
+	price := r.EffectiveGasPrice
+	if price == nil {
+		if gasPrice, ok := txMap["gasPrice"].(string); ok {
+			price = parseHexBigInt(gasPrice)
+		}
+	}
+	if price != nil {
+		tx.Fee = new(big.Int).Mul(new(big.Int).SetUint64(r.GasUsed), price).String()
+	}
+
+	switch r.Status {
+	case "0x1":
+		tx.TxStatus = types.TxStatusSuccess
+	case "0x0":
+		tx.TxStatus = types.TxStatusReverted
+	}
+}
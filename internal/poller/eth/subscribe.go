@@ -0,0 +1,83 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/internal/indexer/internal/poller"
+)
+
+// SetWSURL sets a WebSocket endpoint (ws:// or wss://) to subscribe to new heads
+// on. Requests still go to the HTTP endpoints; an empty URL disables subscribing.
+func (p *Poller) SetWSURL(wsURL string) {
+	p.wsURL = wsURL
+}
+
+// Subscribe subscribes to newHeads over the WebSocket endpoint and returns a channel
+// of new head block numbers. Only the latest number is kept while the receiver is
+// busy. The channel is closed when ctx is done or the subscription drops, after
+// which the caller should fall back to polling. Without a ws:// or wss:// endpoint
+// it fails with poller.ErrSubscriptionUnavailable.
+func (p *Poller) Subscribe(ctx context.Context) (<-chan uint64, error) {
+	if p.wsURL == "" {
+		return nil, poller.ErrSubscriptionUnavailable
+	}
+	u, err := url.Parse(p.wsURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ws_url: %w", err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fmt.Errorf("ws_url must use ws:// or wss://, got %q", u.Scheme)
+	}
+
+	client, err := rpc.DialContext(ctx, p.wsURL)
+	if err != nil {
+		return nil, fmt.Errorf("dialing WebSocket endpoint: %w", err)
+	}
+	headers := make(chan struct {
+		Number string `json:"number"`
+	})
+	sub, err := client.EthSubscribe(ctx, headers, "newHeads")
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("subscribing to newHeads: %w", err)
+	}
+
+	heads := make(chan uint64, 1)
+	go func() {
+		defer close(heads)
+		defer client.Close()
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-sub.Err():
+				p.logger.Warn("newHeads subscription dropped", "error", err)
+				return
+			case h := <-headers:
+				height, err := parseHexUint64(h.Number)
+				if err != nil {
+					p.logger.Warn("invalid newHeads block number", "number", h.Number, "error", err)
+					continue
+				}
+				// Replace a number the receiver has not taken yet; only this
+				// goroutine sends, so the send after draining cannot block
+				select {
+				case heads <- height:
+				default:
+					select {
+					case <-heads:
+					default:
+					}
+					heads <- height
+				}
+			}
+		}
+	}()
+
+	return heads, nil
+}
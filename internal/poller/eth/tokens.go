@@ -0,0 +1,216 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// ERC-20 metadata selectors
+const (
+	selectorName     = "06fdde03" // name()
+	selectorSymbol   = "95d89b41" // symbol()
+	selectorDecimals = "313ce567" // decimals()
+)
+
+// errUnusableResult marks a call that succeeded but returned nothing the method's
+// return type can be read from, e.g. a contract without the method
+var errUnusableResult = errors.New("unusable result")
+
+// stringArgs decodes an ABI-encoded string return value
+var stringArgs = func() abi.Arguments {
+	stringType, _ := abi.NewType("string", "", nil)
+	return abi.Arguments{{Type: stringType}}
+}()
+
+// tokenMetadata returns a token's name, symbol and decimals, fetched at height the
+// first time the token is seen and cached for the life of the poller. Metadata
+// missing a name or symbol for a reason other than the token itself is not
+// cached, so it is fetched again the next time the token moves.
+func (p *Poller) tokenMetadata(ctx context.Context, tokenAddr string, height uint64) (types.Token, error) {
+	addr := common.HexToAddress(tokenAddr)
+	if token, ok := p.knownTokens[addr]; ok {
+		return token, nil
+	}
+	token, complete, err := p.fetchTokenMetadata(ctx, tokenAddr, height)
+	if err != nil {
+		return types.Token{}, err
+	}
+	if complete {
+		p.knownTokens[addr] = *token
+	}
+	return *token, nil
+}
+
+// fetchTokenMetadata calls name(), symbol() and decimals() on a token at height.
+// Methods that revert or return nothing usable leave the name or symbol empty and
+// the decimals at the configured default. A decimals() call that fails for any
+// other reason (transport, rate limit) is returned, since a wrong default would
+// replace the stored decimals; a name() or symbol() call that does only leaves
+// the metadata incomplete.
+func (p *Poller) fetchTokenMetadata(ctx context.Context, tokenAddr string, height uint64) (token *types.Token, complete bool, err error) {
+	complete = true
+	name, err := p.callStringMethod(ctx, tokenAddr, selectorName, height)
+	if err != nil {
+		p.logger.Debug("token name unavailable", "token", tokenAddr, "error", err)
+		complete = complete && isTokenFault(err)
+	}
+	symbol, err := p.callStringMethod(ctx, tokenAddr, selectorSymbol, height)
+	if err != nil {
+		p.logger.Debug("token symbol unavailable", "token", tokenAddr, "error", err)
+		complete = complete && isTokenFault(err)
+	}
+
+	decimals, err := p.tokenDecimals(ctx, tokenAddr, height)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &types.Token{
+		ChainID:   types.ChainETH,
+		Address:   tokenAddr,
+		Name:      name,
+		Symbol:    symbol,
+		Decimals:  decimals,
+		CreatedAt: time.Now(),
+	}, complete, nil
+}
+
+// tokenDecimals resolves a token's decimals: a configured override first, then
+// decimals(), then the configured default when the call reverts or returns nothing usable
+func (p *Poller) tokenDecimals(ctx context.Context, tokenAddr string, height uint64) (int, error) {
+	if d, ok := p.decimalsOverrides[common.HexToAddress(tokenAddr)]; ok {
+		return d, nil
+	}
+	d, err := p.callUint8Method(ctx, tokenAddr, selectorDecimals, height)
+	if err != nil {
+		if !isTokenFault(err) {
+			return 0, fmt.Errorf("calling decimals() on %s: %w", tokenAddr, err)
+		}
+		p.logger.Warn("token decimals unavailable, using default",
+			"token", tokenAddr,
+			"default", p.defaultDecimals,
+			"error", err,
+		)
+		return p.defaultDecimals, nil
+	}
+	return int(d), nil
+}
+
+// isTokenFault reports whether a metadata call failed because of the token itself
+// (it reverted or returned nothing usable), so calling it again would not help
+func isTokenFault(err error) bool {
+	return errors.Is(err, errUnusableResult) || isExecutionError(err)
+}
+
+// isExecutionError reports whether err is the node saying the call itself failed
+// (reverted or hit an invalid opcode), rather than a transport, rate limit or
+// node state problem that may succeed when retried
+func isExecutionError(err error) bool {
+	var rpcErr *rpcError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	if rpcErr.Code == 3 {
+		return true // Geth's code for reverts carrying revert data
+	}
+	msg := strings.ToLower(rpcErr.Message)
+	return strings.Contains(msg, "revert") || strings.Contains(msg, "invalid opcode")
+}
+
+// isStateUnavailable reports whether err is the node lacking the state at the
+// requested block (pruned, or a block it does not have), rather than the call failing
+func isStateUnavailable(err error) bool {
+	var rpcErr *rpcError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	msg := strings.ToLower(rpcErr.Message)
+	for _, s := range []string{"missing trie node", "header not found", "historical state", "state not available", "state is not available", "pruned"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ethCall calls a no-argument method on a contract at height and returns the raw
+// result. Nodes that have pruned the state at height reject the call, so a call
+// refused for missing state is retried against the latest block.
+func (p *Poller) ethCall(ctx context.Context, to string, selector string, height uint64) ([]byte, error) {
+	callMsg := map[string]string{
+		"to":   to,
+		"data": "0x" + selector,
+	}
+	resp, err := p.rpcCall(ctx, "eth_call", []interface{}{callMsg, fmt.Sprintf("0x%x", height)})
+	if err != nil {
+		if !isStateUnavailable(err) {
+			return nil, err
+		}
+		resp, err = p.rpcCall(ctx, "eth_call", []interface{}{callMsg, "latest"})
+		if err != nil {
+			return nil, err
+		}
+	}
+	resHex, ok := resp.(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected eth_call result type %T", errUnusableResult, resp)
+	}
+	return common.FromHex(resHex), nil
+}
+
+func (p *Poller) callStringMethod(ctx context.Context, to string, selector string, height uint64) (string, error) {
+	b, err := p.ethCall(ctx, to, selector, height)
+	if err != nil {
+		return "", err
+	}
+	// Contracts without the method (or EOAs) return empty data rather than reverting
+	if len(b) == 0 {
+		return "", nil
+	}
+	return decodeStringResult(b)
+}
+
+func (p *Poller) callUint8Method(ctx context.Context, to string, selector string, height uint64) (uint8, error) {
+	b, err := p.ethCall(ctx, to, selector, height)
+	if err != nil {
+		return 0, err
+	}
+	// Contracts without the method (or EOAs) return empty data rather than reverting
+	if len(b) == 0 {
+		return 0, fmt.Errorf("%w: empty result", errUnusableResult)
+	}
+	v := new(big.Int).SetBytes(b)
+	if !v.IsUint64() || v.Uint64() > math.MaxUint8 {
+		return 0, fmt.Errorf("%w: value %s out of uint8 range", errUnusableResult, v)
+	}
+	return uint8(v.Uint64()), nil
+}
+
+// decodeStringResult decodes a name() or symbol() return value. Most tokens return
+// an ABI-encoded string; some older ones (e.g. MKR) return a NUL-padded bytes32,
+// which is decoded from the raw bytes instead.
+func decodeStringResult(b []byte) (string, error) {
+	if vals, err := stringArgs.Unpack(b); err == nil {
+		if s, ok := vals[0].(string); ok {
+			return s, nil
+		}
+	}
+	if len(b) == 32 {
+		s := bytes.TrimRight(b, "\x00")
+		if utf8.Valid(s) {
+			return string(s), nil
+		}
+	}
+	return "", fmt.Errorf("%w: undecodable string result of %d bytes", errUnusableResult, len(b))
+}
@@ -0,0 +1,146 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// Token transfer signatures, recognized by topic0 whether or not the emitting
+// contract has a configured ABI
+var (
+	// Transfer(address,address,uint256): ERC-20 with the value in data,
+	// ERC-721 with the tokenId as a third indexed topic
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	// ERC-1155 TransferSingle(operator, from, to, id, value)
+	transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	// ERC-1155 TransferBatch(operator, from, to, ids, values)
+	transferBatchTopic = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// transferBatchArgs decodes the non-indexed ids and values of a TransferBatch log
+var transferBatchArgs = func() abi.Arguments {
+	uintSlice, _ := abi.NewType("uint256[]", "", nil)
+	return abi.Arguments{{Type: uintSlice}, {Type: uintSlice}}
+}()
+
+// topicAddress returns the normalized address held in an indexed topic
+func topicAddress(topic string) string {
+	return types.NormalizeAddress(types.ChainETH, common.HexToAddress(topic).Hex())
+}
+
+// parseTokenTransfer extracts a token movement from a Transfer, TransferSingle or
+// TransferBatch log. The amount is the number of units moved: the value for ERC-20,
+// 1 for an ERC-721 token, the value for TransferSingle, and the sum of the values for
+// TransferBatch, which is stored as one transfer since rows are keyed by log index.
+// Token ids are not recorded. Logs that match none of the signatures, or whose
+// topics or data don't fit them, are ignored.
+func parseTokenTransfer(ev types.Event) (types.TokenTransfer, bool) {
+	if len(ev.Topics) == 0 {
+		return types.TokenTransfer{}, false
+	}
+
+	var rawLog map[string]interface{}
+	_ = json.Unmarshal(ev.RawData, &rawLog)
+	dataHex, _ := rawLog["data"].(string)
+	data := common.FromHex(dataHex)
+
+	var from, to string
+	amount := new(big.Int)
+	switch common.HexToHash(ev.Topics[0]) {
+	case transferTopic:
+		switch len(ev.Topics) {
+		case 3: // ERC-20
+			if len(data) != 32 {
+				return types.TokenTransfer{}, false
+			}
+			amount.SetBytes(data)
+		case 4: // ERC-721
+			amount.SetInt64(1)
+		default:
+			return types.TokenTransfer{}, false
+		}
+		from, to = topicAddress(ev.Topics[1]), topicAddress(ev.Topics[2])
+	case transferSingleTopic:
+		if len(ev.Topics) != 4 || len(data) != 64 {
+			return types.TokenTransfer{}, false
+		}
+		amount.SetBytes(data[32:])
+		from, to = topicAddress(ev.Topics[2]), topicAddress(ev.Topics[3])
+	case transferBatchTopic:
+		if len(ev.Topics) != 4 {
+			return types.TokenTransfer{}, false
+		}
+		decoded, err := transferBatchArgs.Unpack(data)
+		if err != nil || len(decoded) != 2 {
+			return types.TokenTransfer{}, false
+		}
+		values, ok := decoded[1].([]*big.Int)
+		if !ok {
+			return types.TokenTransfer{}, false
+		}
+		for _, v := range values {
+			amount.Add(amount, v)
+		}
+		from, to = topicAddress(ev.Topics[2]), topicAddress(ev.Topics[3])
+	default:
+		return types.TokenTransfer{}, false
+	}
+
+	return types.TokenTransfer{
+		ChainID:      types.ChainETH,
+		TxHash:       ev.TxHash,
+		LogIndex:     uint(ev.LogIndex),
+		TokenAddress: ev.ContractAddr,
+		FromAddr:     from,
+		ToAddr:       to,
+		Amount:       amount.String(),
+		BlockHeight:  ev.BlockHeight,
+		BlockHash:    ev.BlockHash,
+	}, true
+}
+
+// extractTokenTransfers returns the token transfers among events, timestamped
+// with their block, and a token for each contract that emitted one, carrying the
+// heights it was first and last seen at in this batch.
+func (p *Poller) extractTokenTransfers(ctx context.Context, blocks []types.Block, events []types.Event) ([]types.Token, []types.TokenTransfer, error) {
+	blockTimes := make(map[uint64]time.Time, len(blocks))
+	for _, b := range blocks {
+		blockTimes[b.Height] = b.Timestamp
+	}
+
+	var transfers []types.TokenTransfer
+	var tokens []types.Token
+	seen := make(map[string]int) // token address -> index in tokens
+	for _, ev := range events {
+		tr, ok := parseTokenTransfer(ev)
+		if !ok {
+			continue
+		}
+		tr.Timestamp = blockTimes[tr.BlockHeight]
+		transfers = append(transfers, tr)
+
+		if i, ok := seen[tr.TokenAddress]; ok {
+			tokens[i].FirstSeenHeight = min(tokens[i].FirstSeenHeight, tr.BlockHeight)
+			tokens[i].LastSeenHeight = max(tokens[i].LastSeenHeight, tr.BlockHeight)
+			continue
+		}
+
+		token, err := p.tokenMetadata(ctx, tr.TokenAddress, tr.BlockHeight)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching token metadata: %w", err)
+		}
+		token.FirstSeenHeight = tr.BlockHeight
+		token.LastSeenHeight = tr.BlockHeight
+		seen[tr.TokenAddress] = len(tokens)
+		tokens = append(tokens, token)
+	}
+	return tokens, transfers, nil
+}
@@ -0,0 +1,65 @@
+package poller
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultFetchConcurrency is how many blocks of a poll batch are fetched at once
+const DefaultFetchConcurrency = 4
+
+// FetchOrdered calls fetch for indexes 0 to n-1 on up to workers goroutines and
+// returns the results in index order. Indexes are started in ascending order, and
+// once one fails no higher index is started; fetches already running below it
+// complete. The results before the lowest failing index are returned with its
+// error, so the outcome is the same as fetching one index after another,
+// whichever goroutine fails first. Cancelling ctx stops new fetches and reaches
+// running ones through the ctx passed to fetch.
+func FetchOrdered[T any](ctx context.Context, n, workers int, fetch func(ctx context.Context, i int) (T, error)) ([]T, error) {
+	results := make([]T, n)
+	errs := make([]error, n)
+
+	var next atomic.Int64
+	var failed atomic.Int64 // Lowest failing index, n while none has failed
+	failed.Store(int64(n))
+	setFailed := func(i int64) {
+		for {
+			cur := failed.Load()
+			if i >= cur || failed.CompareAndSwap(cur, i) {
+				return
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range max(1, min(workers, n)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := next.Add(1) - 1
+				if i >= int64(n) || i > failed.Load() {
+					return
+				}
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					setFailed(i)
+					return
+				}
+				results[i], errs[i] = fetch(ctx, int(i))
+				if errs[i] != nil {
+					setFailed(i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return results[:i], err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,82 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchOrdered(t *testing.T) {
+	var running, peak atomic.Int32
+	results, err := FetchOrdered(context.Background(), 20, 4, func(ctx context.Context, i int) (int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		// Later indexes finish first
+		time.Sleep(time.Duration(20-i) * 100 * time.Microsecond)
+		return i * 10, nil
+	})
+	if err != nil {
+		t.Fatalf("FetchOrdered: %v", err)
+	}
+	for i, r := range results {
+		if r != i*10 {
+			t.Fatalf("result %d out of order: %v", i, results)
+		}
+	}
+	if peak.Load() > 4 {
+		t.Errorf("expected at most 4 concurrent fetches, got %d", peak.Load())
+	}
+}
+
+func TestFetchOrdered_LowestErrorWins(t *testing.T) {
+	// Index 7 fails immediately, index 3 fails only after a delay; the error
+	// returned is always index 3's, with the results before it
+	var started atomic.Int32
+	results, err := FetchOrdered(context.Background(), 20, 8, func(ctx context.Context, i int) (int, error) {
+		started.Add(1)
+		switch i {
+		case 3:
+			time.Sleep(5 * time.Millisecond)
+			return 0, fmt.Errorf("fetch %d failed", i)
+		case 7:
+			return 0, fmt.Errorf("fetch %d failed", i)
+		}
+		return i, nil
+	})
+	if err == nil || err.Error() != "fetch 3 failed" {
+		t.Fatalf("expected the error of index 3, got %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("expected the 3 results before the failure, got %v", results)
+	}
+	if started.Load() == 20 {
+		t.Error("expected indexes above the failure not to be started")
+	}
+}
+
+func TestFetchOrdered_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := FetchOrdered(ctx, 10, 2, func(ctx context.Context, i int) (int, error) {
+		if i == 1 {
+			cancel()
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return i, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	if len(results) > 1 {
+		t.Errorf("expected no results past the cancelled fetch, got %v", results)
+	}
+}
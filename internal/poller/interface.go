@@ -2,6 +2,7 @@ package poller
 
 import (
 	"context"
+	"errors"
 
 	"github.com/internal/indexer/pkg/types"
 )
@@ -26,3 +27,59 @@ type ChainPoller interface {
 type EventCapablePoller interface {
 	PollWithEvents(ctx context.Context, lastHeight uint64) ([]types.Block, []types.Transaction, []types.Event, []types.Contract, []types.Token, []types.TokenTransfer, error)
 }
+
+// GenesisPoller is implemented by pollers that can index the genesis block, which
+// Poll never returns since it starts after the checkpoint height
+type GenesisPoller interface {
+	PollGenesis(ctx context.Context) ([]types.Block, []types.Transaction, error)
+}
+
+// FinalityAwarePoller is implemented by pollers that can report the node's finalized height
+type FinalityAwarePoller interface {
+	// GetFinalizedHeight returns the highest finalized block, or 0 if nothing is finalized yet
+	GetFinalizedHeight(ctx context.Context) (uint64, error)
+}
+
+// CanonicalHashPoller is implemented by pollers that can report which block is
+// currently canonical at a height, used to re-validate a reorg's fork point
+type CanonicalHashPoller interface {
+	GetCanonicalHash(ctx context.Context, height uint64) (string, error)
+}
+
+// PartialBatchPoller is implemented by pollers whose Poll (or PollWithEvents) can
+// return a batch that stops before the tip and batch size rather than an error,
+// e.g. at a block that failed mid-batch or at a cap on logs held per poll.
+// PollCutShort returns why the most recent poll was cut short, or nil, so such a
+// batch is not mistaken for one that reached the tip.
+type PartialBatchPoller interface {
+	PollCutShort() error
+}
+
+// DecodeStatsPoller is implemented by pollers that count events stored undecoded
+// because their decoded params exceeded the size limit
+type DecodeStatsPoller interface {
+	OversizedEvents() uint64
+}
+
+// ErrSubscriptionUnavailable is returned by HeadSubscriber.Subscribe when the
+// poller is not configured to subscribe, so the caller keeps polling
+var ErrSubscriptionUnavailable = errors.New("new heads subscription not configured")
+
+// HeadSubscriber is implemented by pollers that can push new chain heads instead
+// of being polled for them. Subscribe returns a channel of new head heights that
+// is closed when the subscription ends; an error means polling must be used.
+type HeadSubscriber interface {
+	Subscribe(ctx context.Context) (<-chan uint64, error)
+}
+
+// EndpointPoller is implemented by pollers that fail over between RPC endpoints,
+// reporting the index of the active one (0 = rpc_url) and how often they switched
+type EndpointPoller interface {
+	RPCEndpoint() (active int, failovers uint64)
+}
+
+// RawLogDecoder is implemented by pollers that can re-decode a stored event from
+// its raw_data, returning the event name and decoded params JSON
+type RawLogDecoder interface {
+	DecodeRawLog(rawData []byte) (eventName string, data []byte, err error)
+}
@@ -0,0 +1,28 @@
+package poller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxResponseSize bounds a buffered RPC response body. Generous enough for
+// the largest blocks with full transactions, small enough that a misbehaving node
+// cannot exhaust memory.
+const DefaultMaxResponseSize int64 = 256 << 20
+
+// ErrResponseTooLarge is returned when an RPC response exceeds the size limit
+var ErrResponseTooLarge = errors.New("RPC response exceeds size limit")
+
+// ReadResponse reads body in full, failing with ErrResponseTooLarge once more than
+// limit bytes arrive instead of buffering the rest
+func ReadResponse(body io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w of %d bytes", ErrResponseTooLarge, limit)
+	}
+	return data, nil
+}
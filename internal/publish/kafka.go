@@ -0,0 +1,120 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+	"github.com/segmentio/kafka-go"
+)
+
+// Message types, also used as topic suffixes (<prefix>.blocks, ...)
+const (
+	TopicBlocks       = "blocks"
+	TopicTransactions = "transactions"
+	TopicEvents       = "events"
+)
+
+// KafkaConfig holds Kafka writer settings
+type KafkaConfig struct {
+	Brokers      []string
+	TopicPrefix  string
+	BatchSize    int
+	BatchTimeout time.Duration
+}
+
+// KafkaPublisher writes each block, transaction, and event as its own message.
+// Messages are keyed by chain and hash so re-publishes of the same item (e.g. after
+// a reorg) land on the same partition.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+	prefix string
+}
+
+// NewKafkaPublisher creates a publisher; brokers are dialed lazily on first write
+func NewKafkaPublisher(cfg KafkaConfig) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(cfg.Brokers...),
+			Balancer:               &kafka.Hash{},
+			BatchSize:              cfg.BatchSize,
+			BatchTimeout:           cfg.BatchTimeout,
+			RequiredAcks:           kafka.RequireOne,
+			AllowAutoTopicCreation: true,
+		},
+		prefix: cfg.TopicPrefix,
+	}
+}
+
+// Publish writes all messages for batch in one call
+func (p *KafkaPublisher) Publish(ctx context.Context, batch Batch) error {
+	msgs, err := p.messages(batch)
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if err := p.writer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("kafka write: %w", err)
+	}
+	return nil
+}
+
+// Close flushes pending writes and closes broker connections
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// envelope is the JSON payload of every published message
+type envelope struct {
+	Type    string        `json:"type"`
+	ChainID types.ChainID `json:"chain_id"`
+	Data    interface{}   `json:"data"`
+}
+
+func (p *KafkaPublisher) messages(batch Batch) ([]kafka.Message, error) {
+	msgs := make([]kafka.Message, 0, len(batch.Blocks)+len(batch.Txs)+len(batch.Events))
+
+	add := func(kind, key string, data interface{}) error {
+		value, err := json.Marshal(envelope{Type: kind, ChainID: batch.ChainID, Data: data})
+		if err != nil {
+			return fmt.Errorf("encoding %s message: %w", kind, err)
+		}
+		msgs = append(msgs, kafka.Message{
+			Topic: p.topic(kind),
+			Key:   []byte(string(batch.ChainID) + ":" + key),
+			Value: value,
+		})
+		return nil
+	}
+
+	for _, b := range batch.Blocks {
+		if err := add(TopicBlocks, b.Hash, b); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range batch.Txs {
+		if err := add(TopicTransactions, t.TxHash, t); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range batch.Events {
+		if err := add(TopicEvents, e.TxHash+":"+strconv.Itoa(e.LogIndex), e); err != nil {
+			return nil, err
+		}
+	}
+
+	return msgs, nil
+}
+
+func (p *KafkaPublisher) topic(kind string) string {
+	if p.prefix == "" {
+		return kind
+	}
+	return p.prefix + "." + kind
+}
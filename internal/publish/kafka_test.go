@@ -0,0 +1,48 @@
+package publish
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+func TestKafkaPublisher_Messages(t *testing.T) {
+	p := NewKafkaPublisher(KafkaConfig{Brokers: []string{"localhost:9092"}, TopicPrefix: "idx"})
+	defer p.Close()
+
+	msgs, err := p.messages(Batch{
+		ChainID: types.ChainETH,
+		Blocks:  []types.Block{{Height: 10, Hash: "0xb"}},
+		Txs:     []types.Transaction{{TxHash: "0xt"}},
+		Events:  []types.Event{{TxHash: "0xt", LogIndex: 2}},
+	})
+	if err != nil {
+		t.Fatalf("messages: %v", err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(msgs))
+	}
+
+	want := []struct{ topic, key string }{
+		{"idx.blocks", "eth:0xb"},
+		{"idx.transactions", "eth:0xt"},
+		{"idx.events", "eth:0xt:2"},
+	}
+	for i, w := range want {
+		if msgs[i].Topic != w.topic || string(msgs[i].Key) != w.key {
+			t.Errorf("message %d: got topic %s key %s, want %s %s", i, msgs[i].Topic, msgs[i].Key, w.topic, w.key)
+		}
+	}
+
+	var env struct {
+		Type    string `json:"type"`
+		ChainID string `json:"chain_id"`
+	}
+	if err := json.Unmarshal(msgs[0].Value, &env); err != nil {
+		t.Fatalf("decoding envelope: %v", err)
+	}
+	if env.Type != TopicBlocks || env.ChainID != "eth" {
+		t.Errorf("unexpected envelope %+v", env)
+	}
+}
@@ -0,0 +1,21 @@
+package publish
+
+import (
+	"context"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// Batch is a contiguous range of committed blocks with their transactions and events
+type Batch struct {
+	ChainID types.ChainID
+	Blocks  []types.Block
+	Txs     []types.Transaction
+	Events  []types.Event
+}
+
+// Publisher pushes newly indexed data to a message broker
+type Publisher interface {
+	Publish(ctx context.Context, batch Batch) error
+	Close() error
+}
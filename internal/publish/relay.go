@@ -0,0 +1,169 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// Source is the committed data and delivery checkpoint the relay reads from
+// (implemented by storage.Storage)
+type Source interface {
+	GetCheckpoint(ctx context.Context, chainID types.ChainID) (*types.Checkpoint, error)
+	GetPublishCheckpoint(ctx context.Context, chainID types.ChainID) (*types.PublishCheckpoint, error)
+	InitPublishedHeight(ctx context.Context, chainID types.ChainID, height uint64) error
+	AdvancePublishedHeight(ctx context.Context, chainID types.ChainID, from types.PublishCheckpoint, to uint64) (bool, error)
+	GetCommittedRange(ctx context.Context, chainID types.ChainID, fromHeight, toHeight uint64) ([]types.Block, []types.Transaction, []types.Event, error)
+}
+
+// RelayConfig tunes a Relay
+type RelayConfig struct {
+	MaxBlocks int           // Blocks per published batch
+	Interval  time.Duration // Idle/retry delay between catch-up passes
+	Timeout   time.Duration // Per-batch delivery timeout
+}
+
+// Relay delivers committed data to a Publisher with at-least-once semantics.
+// It runs separately from the indexing loop: it reads blocks committed beyond
+// the chain's published_up_to checkpoint from the DB, publishes them, and only
+// then advances the checkpoint. A broker outage therefore delays delivery but
+// never loses data or blocks DB writes; a crash between publish and checkpoint
+// update re-delivers that batch.
+type Relay struct {
+	chainID   types.ChainID
+	source    Source
+	publisher Publisher
+	cfg       RelayConfig
+	logger    *slog.Logger
+
+	wake chan struct{}
+}
+
+// NewRelay creates a relay for one chain
+func NewRelay(chainID types.ChainID, source Source, publisher Publisher, cfg RelayConfig, logger *slog.Logger) *Relay {
+	if cfg.MaxBlocks <= 0 {
+		cfg.MaxBlocks = 100
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &Relay{
+		chainID:   chainID,
+		source:    source,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger.With("component", "publish_relay", "chain", string(chainID)),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes the relay after a commit instead of waiting for the next interval.
+// It never blocks.
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-r.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+
+		caughtUp, err := r.publishNext(ctx)
+		if err != nil {
+			r.logger.Error("publish failed, will retry", "error", err)
+		}
+
+		// Keep draining a backlog without waiting
+		if err == nil && !caughtUp {
+			timer.Reset(0)
+		} else {
+			timer.Reset(r.cfg.Interval)
+		}
+	}
+}
+
+// publishNext delivers the next batch after the publish checkpoint.
+// caughtUp reports whether nothing committed remains unpublished.
+func (r *Relay) publishNext(ctx context.Context) (caughtUp bool, err error) {
+	cp, err := r.source.GetCheckpoint(ctx, r.chainID)
+	if err != nil {
+		return false, err
+	}
+	if cp == nil {
+		return true, nil
+	}
+
+	pub, err := r.source.GetPublishCheckpoint(ctx, r.chainID)
+	if err != nil {
+		return false, err
+	}
+	if pub == nil {
+		// First run: start from what is committed now rather than replaying history
+		r.logger.Info("initializing publish checkpoint", "height", cp.LastHeight)
+		return true, r.source.InitPublishedHeight(ctx, r.chainID, cp.LastHeight)
+	}
+	if pub.Height >= cp.LastHeight {
+		return true, nil
+	}
+
+	from := pub.Height + 1
+	to := cp.LastHeight
+	if to-from+1 > uint64(r.cfg.MaxBlocks) {
+		to = from + uint64(r.cfg.MaxBlocks) - 1
+	}
+
+	blocks, txs, events, err := r.source.GetCommittedRange(ctx, r.chainID, from, to)
+	if err != nil {
+		return false, fmt.Errorf("loading blocks %d-%d: %w", from, to, err)
+	}
+
+	if len(blocks) == 0 {
+		// A rollback removed the range after the checkpoint was read; wait for it to be rewritten
+		return true, nil
+	}
+
+	pubCtx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	err = r.publisher.Publish(pubCtx, Batch{ChainID: r.chainID, Blocks: blocks, Txs: txs, Events: events})
+	cancel()
+	if err != nil {
+		return false, fmt.Errorf("publishing blocks %d-%d: %w", from, to, err)
+	}
+
+	// Only what was delivered counts: the range may have been cut short by a rollback
+	to = blocks[len(blocks)-1].Height
+	advanced, err := r.source.AdvancePublishedHeight(ctx, r.chainID, *pub, to)
+	if err != nil {
+		return false, err
+	}
+	if !advanced {
+		r.logger.Info("publish checkpoint moved by rollback, republishing", "from", from)
+		return false, nil
+	}
+
+	r.logger.Debug("published blocks", "from", from, "to", to, "txs", len(txs), "events", len(events))
+	return to >= cp.LastHeight, nil
+}
@@ -0,0 +1,210 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// memSource is an in-memory Source with one block per height up to tip
+// (none above committed, when set)
+type memSource struct {
+	tip        uint64
+	committed  uint64
+	published  uint64
+	generation int64
+	hasPub     bool
+}
+
+func (s *memSource) GetCheckpoint(ctx context.Context, chainID types.ChainID) (*types.Checkpoint, error) {
+	return &types.Checkpoint{ChainID: chainID, LastHeight: s.tip}, nil
+}
+
+func (s *memSource) GetPublishCheckpoint(ctx context.Context, chainID types.ChainID) (*types.PublishCheckpoint, error) {
+	if !s.hasPub {
+		return nil, nil
+	}
+	return &types.PublishCheckpoint{Height: s.published, Generation: s.generation}, nil
+}
+
+func (s *memSource) InitPublishedHeight(ctx context.Context, chainID types.ChainID, height uint64) error {
+	if !s.hasPub {
+		s.published, s.hasPub = height, true
+	}
+	return nil
+}
+
+func (s *memSource) AdvancePublishedHeight(ctx context.Context, chainID types.ChainID, from types.PublishCheckpoint, to uint64) (bool, error) {
+	if s.published != from.Height || s.generation != from.Generation {
+		return false, nil
+	}
+	s.published = to
+	return true, nil
+}
+
+func (s *memSource) GetCommittedRange(ctx context.Context, chainID types.ChainID, from, to uint64) ([]types.Block, []types.Transaction, []types.Event, error) {
+	var blocks []types.Block
+	for h := from; h <= to && h <= s.tip && (s.committed == 0 || h <= s.committed); h++ {
+		blocks = append(blocks, types.Block{ChainID: chainID, Height: h})
+	}
+	return blocks, nil, nil, nil
+}
+
+// flakyPublisher fails while down is set and records delivered heights
+type flakyPublisher struct {
+	down    bool
+	heights []uint64
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, batch Batch) error {
+	if p.down {
+		return errors.New("broker unavailable")
+	}
+	for _, b := range batch.Blocks {
+		p.heights = append(p.heights, b.Height)
+	}
+	return nil
+}
+
+func (p *flakyPublisher) Close() error { return nil }
+
+func newTestRelay(src Source, pub Publisher) *Relay {
+	return NewRelay(types.ChainETH, src, pub, RelayConfig{MaxBlocks: 2}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRelay_InitializesAtCommittedHeight(t *testing.T) {
+	src := &memSource{tip: 50}
+	pub := &flakyPublisher{}
+	r := newTestRelay(src, pub)
+
+	caughtUp, err := r.publishNext(context.Background())
+	if err != nil || !caughtUp {
+		t.Fatalf("expected caught up without error, got %v %v", caughtUp, err)
+	}
+	if !src.hasPub || src.published != 50 {
+		t.Errorf("expected publish checkpoint initialized at 50, got %d", src.published)
+	}
+	if len(pub.heights) != 0 {
+		t.Errorf("expected history not to be replayed, got %v", pub.heights)
+	}
+}
+
+func TestRelay_CatchesUpAfterBrokerOutage(t *testing.T) {
+	src := &memSource{tip: 10, published: 5, hasPub: true}
+	pub := &flakyPublisher{down: true}
+	r := newTestRelay(src, pub)
+	ctx := context.Background()
+
+	// Broker down: nothing is lost, the checkpoint stays put
+	if _, err := r.publishNext(ctx); err == nil {
+		t.Fatal("expected publish error while broker is down")
+	}
+	if src.published != 5 {
+		t.Fatalf("expected checkpoint to stay at 5, got %d", src.published)
+	}
+
+	// More blocks are committed meanwhile
+	src.tip = 12
+	pub.down = false
+
+	for i := 0; i < 10; i++ {
+		caughtUp, err := r.publishNext(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if caughtUp {
+			break
+		}
+	}
+
+	want := []uint64{6, 7, 8, 9, 10, 11, 12}
+	if len(pub.heights) != len(want) {
+		t.Fatalf("expected heights %v, got %v", want, pub.heights)
+	}
+	for i, h := range want {
+		if pub.heights[i] != h {
+			t.Fatalf("expected heights %v, got %v", want, pub.heights)
+		}
+	}
+	if src.published != 12 {
+		t.Errorf("expected checkpoint at 12, got %d", src.published)
+	}
+}
+
+// rollbackSource rolls back to rollbackTo while the first batch is in flight,
+// as storage does: lowering the publish checkpoint to it and bumping the generation
+type rollbackSource struct {
+	memSource
+	rollbackTo uint64
+	rolledBack bool
+}
+
+func (s *rollbackSource) GetCommittedRange(ctx context.Context, chainID types.ChainID, from, to uint64) ([]types.Block, []types.Transaction, []types.Event, error) {
+	blocks, txs, events, err := s.memSource.GetCommittedRange(ctx, chainID, from, to)
+	if !s.rolledBack {
+		s.rolledBack = true
+		s.published = min(s.published, s.rollbackTo)
+		s.generation++
+	}
+	return blocks, txs, events, err
+}
+
+func TestRelay_RepublishesAfterRollback(t *testing.T) {
+	src := &rollbackSource{memSource: memSource{tip: 6, published: 5, hasPub: true}, rollbackTo: 3}
+	pub := &flakyPublisher{}
+	r := newTestRelay(src, pub)
+
+	caughtUp, err := r.publishNext(context.Background())
+	if err != nil || caughtUp {
+		t.Fatalf("expected another pass after rollback, got caughtUp=%v err=%v", caughtUp, err)
+	}
+	if src.published != 3 {
+		t.Errorf("expected checkpoint left at rollback height 3, got %d", src.published)
+	}
+}
+
+func TestRelay_RereadsRangeRolledBackAbovePublished(t *testing.T) {
+	// The rollback to 7 leaves the checkpoint at 5, but 8 was read before it
+	src := &rollbackSource{memSource: memSource{tip: 8, published: 5, hasPub: true}, rollbackTo: 7}
+	pub := &flakyPublisher{}
+	r := NewRelay(types.ChainETH, src, pub, RelayConfig{MaxBlocks: 10}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	caughtUp, err := r.publishNext(ctx)
+	if err != nil || caughtUp {
+		t.Fatalf("expected another pass after rollback, got caughtUp=%v err=%v", caughtUp, err)
+	}
+	if src.published != 5 {
+		t.Fatalf("expected checkpoint to stay at 5, got %d", src.published)
+	}
+
+	// The replacement range is delivered on the next pass
+	if _, err := r.publishNext(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if src.published != 8 {
+		t.Errorf("expected checkpoint at 8, got %d", src.published)
+	}
+	want := []uint64{6, 7, 8, 6, 7, 8}
+	if len(pub.heights) != len(want) {
+		t.Fatalf("expected heights %v, got %v", want, pub.heights)
+	}
+}
+
+func TestRelay_AdvancesOnlyToLastDeliveredBlock(t *testing.T) {
+	// The checkpoint says 10, but only 6 and 7 are readable (the rest is being rewritten)
+	src := &memSource{tip: 10, committed: 7, published: 5, hasPub: true}
+	pub := &flakyPublisher{}
+	r := NewRelay(types.ChainETH, src, pub, RelayConfig{MaxBlocks: 10}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := r.publishNext(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if src.published != 7 {
+		t.Errorf("expected checkpoint at the last delivered height 7, got %d", src.published)
+	}
+}
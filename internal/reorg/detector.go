@@ -2,17 +2,26 @@ package reorg
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
 	"github.com/internal/indexer/internal/poller"
-	"github.com/internal/indexer/internal/storage"
 	"github.com/internal/indexer/pkg/types"
 )
 
+// BlockReader is the stored-block lookup the detector needs (*storage.Storage)
+type BlockReader interface {
+	GetBlockByHeight(ctx context.Context, chainID types.ChainID, height uint64) (*types.Block, error)
+}
+
+// MaxForkPointAttempts bounds how often the fork point search restarts because
+// the chain reorganized again while it walked back
+const MaxForkPointAttempts = 3
+
 // Detector handles chain reorganization detection
 type Detector struct {
-	storage  *storage.Storage
+	storage  BlockReader
 	maxDepth int
 	logger   *slog.Logger
 }
@@ -23,10 +32,22 @@ type ReorgResult struct {
 	RollbackHeight uint64
 	RollbackHash   string
 	Depth          int
+	OldHash        string // Stored hash at the height where the mismatch was found
+	NewHash        string // The chain's hash at that height (the new block's parent hash)
 }
 
+// ErrInconsistentBatch is returned when a fetched batch is not a single chain of
+// blocks, e.g. a provider answered from different forks mid-batch. The batch must
+// be discarded and re-fetched rather than written.
+var ErrInconsistentBatch = errors.New("inconsistent block batch")
+
+// ErrUnstableForkPoint is returned when the fork point found by the walk-back was
+// no longer canonical on every one of MaxForkPointAttempts tries. Nothing is
+// rolled back; detection runs again on the next poll.
+var ErrUnstableForkPoint = errors.New("fork point kept changing during reorg detection")
+
 // New creates a new reorg detector
-func New(storage *storage.Storage, maxDepth int, logger *slog.Logger) *Detector {
+func New(storage BlockReader, maxDepth int, logger *slog.Logger) *Detector {
 	return &Detector{
 		storage:  storage,
 		maxDepth: maxDepth,
@@ -46,6 +67,13 @@ func (d *Detector) Detect(
 		return &ReorgResult{Detected: false}, nil
 	}
 
+	// A fork inside the batch would otherwise go unnoticed: only the first block is
+	// compared against storage below
+	if err := CheckBatch(newBlocks); err != nil {
+		d.logger.Warn("discarding inconsistent batch", "chain", chainID, "error", err)
+		return nil, err
+	}
+
 	firstNewBlock := newBlocks[0]
 
 	// If this is the first block we're indexing, no reorg possible
@@ -77,7 +105,94 @@ func (d *Detector) Detect(
 		"height", firstNewBlock.Height-1,
 	)
 
-	return d.findForkPoint(ctx, chainID, chainPoller, storedParent.Height)
+	result, err := d.findStableForkPoint(ctx, chainID, chainPoller, storedParent.Height)
+	if result != nil {
+		result.OldHash = storedParent.Hash
+		result.NewHash = firstNewBlock.ParentHash
+	}
+	return result, err
+}
+
+// findStableForkPoint runs findForkPoint and, for pollers that can report the
+// canonical chain, re-checks that the fork point is still canonical before it is
+// rolled back to. If the chain reorganized again during the walk-back the hashes
+// compared may be stale, so the search restarts, up to MaxForkPointAttempts times.
+func (d *Detector) findStableForkPoint(
+	ctx context.Context,
+	chainID types.ChainID,
+	chainPoller poller.ChainPoller,
+	startHeight uint64,
+) (*ReorgResult, error) {
+	canonical, ok := chainPoller.(poller.CanonicalHashPoller)
+	if !ok {
+		return d.findForkPoint(ctx, chainID, chainPoller, startHeight)
+	}
+
+	for attempt := 1; attempt <= MaxForkPointAttempts; attempt++ {
+		result, err := d.findForkPoint(ctx, chainID, chainPoller, startHeight)
+		if err != nil || result.RollbackHash == "" {
+			return result, err // Over max depth, or anchored on an unstored height
+		}
+
+		hash, err := canonical.GetCanonicalHash(ctx, result.RollbackHeight)
+		if err != nil {
+			return nil, fmt.Errorf("re-validating fork point %d: %w", result.RollbackHeight, err)
+		}
+		if hash == result.RollbackHash {
+			return result, nil
+		}
+
+		d.logger.Warn("fork point no longer canonical, restarting reorg detection",
+			"chain", chainID,
+			"height", result.RollbackHeight,
+			"fork_hash", result.RollbackHash,
+			"canonical_hash", hash,
+			"attempt", attempt,
+		)
+	}
+
+	d.logger.Error("CRITICAL: chain kept reorganizing during reorg detection",
+		"chain", chainID,
+		"attempts", MaxForkPointAttempts,
+		"start_height", startHeight,
+	)
+	return nil, fmt.Errorf("%w: %d attempts from height %d", ErrUnstableForkPoint, MaxForkPointAttempts, startHeight)
+}
+
+// CheckBatch verifies that blocks have consecutive heights and that each block's
+// parent hash is the hash of the block before it
+func CheckBatch(blocks []types.Block) error {
+	for i := 1; i < len(blocks); i++ {
+		prev, b := blocks[i-1], blocks[i]
+		if b.Height != prev.Height+1 {
+			return fmt.Errorf("%w: block %d follows block %d", ErrInconsistentBatch, b.Height, prev.Height)
+		}
+		if b.ParentHash != prev.Hash {
+			return fmt.Errorf("%w: block %d parent %s does not match block %d hash %s",
+				ErrInconsistentBatch, b.Height, b.ParentHash, prev.Height, prev.Hash)
+		}
+	}
+	return nil
+}
+
+// isCanonical reports whether a stored block is on the node's canonical chain.
+// Nodes keep serving orphaned blocks by hash, so pollers that can report the
+// canonical hash at a height are asked for that; for others, a block the node no
+// longer returns by hash is taken as orphaned.
+func (d *Detector) isCanonical(ctx context.Context, chainPoller poller.ChainPoller, stored *types.Block) (bool, error) {
+	if canonical, ok := chainPoller.(poller.CanonicalHashPoller); ok {
+		hash, err := canonical.GetCanonicalHash(ctx, stored.Height)
+		if err != nil {
+			return false, err
+		}
+		return hash == stored.Hash, nil
+	}
+
+	chainBlock, err := chainPoller.GetBlockByHash(ctx, stored.Hash)
+	if err != nil {
+		return false, nil // Not found on chain: orphaned
+	}
+	return chainBlock != nil && chainBlock.Hash == stored.Hash, nil
 }
 
 func (d *Detector) findForkPoint(
@@ -112,11 +227,12 @@ func (d *Detector) findForkPoint(
 			}, nil
 		}
 
-		// Get block from chain at same height
-		chainBlock, err := chainPoller.GetBlockByHash(ctx, storedBlock.Hash)
+		onChain, err := d.isCanonical(ctx, chainPoller, storedBlock)
 		if err != nil {
-			// Block not found on chain - it's orphaned, continue walking back
-			d.logger.Debug("block not found on chain",
+			return nil, fmt.Errorf("checking block %d on chain: %w", height, err)
+		}
+		if !onChain {
+			d.logger.Debug("stored block not canonical",
 				"chain", chainID,
 				"height", height,
 				"hash", storedBlock.Hash,
@@ -124,22 +240,19 @@ func (d *Detector) findForkPoint(
 			continue
 		}
 
-		// Check if the chain still has this block (meaning it's in canonical chain)
-		if chainBlock != nil && chainBlock.Hash == storedBlock.Hash {
-			// Found common ancestor
-			d.logger.Info("found fork point",
-				"chain", chainID,
-				"height", height,
-				"hash", storedBlock.Hash,
-				"depth", depth,
-			)
-			return &ReorgResult{
-				Detected:       true,
-				RollbackHeight: height,
-				RollbackHash:   storedBlock.Hash,
-				Depth:          depth,
-			}, nil
-		}
+		// Found common ancestor
+		d.logger.Info("found fork point",
+			"chain", chainID,
+			"height", height,
+			"hash", storedBlock.Hash,
+			"depth", depth,
+		)
+		return &ReorgResult{
+			Detected:       true,
+			RollbackHeight: height,
+			RollbackHash:   storedBlock.Hash,
+			Depth:          depth,
+		}, nil
 	}
 
 	// Exceeded max depth - this is a P1 situation
@@ -151,7 +264,7 @@ func (d *Detector) findForkPoint(
 
 	return &ReorgResult{
 		Detected:       true,
-		RollbackHeight: startHeight - uint64(d.maxDepth),
+		RollbackHeight: types.HeightBelow(startHeight, d.maxDepth),
 		RollbackHash:   "",
 		Depth:          d.maxDepth,
 	}, fmt.Errorf("reorg depth %d exceeds maximum %d - manual intervention required", depth, d.maxDepth)
@@ -2,12 +2,16 @@ package reorg_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"testing"
 	"time"
 
 	"log/slog"
 	"os"
 
+	"github.com/internal/indexer/internal/reorg"
 	"github.com/internal/indexer/pkg/types"
 )
 
@@ -154,3 +158,174 @@ func TestMaxReorgDepth(t *testing.T) {
 
 	t.Log("Max reorg depth test: implementation should cap at configured depth and error")
 }
+
+func TestDetect_MaxDepthPastGenesisClampsRollbackHeight(t *testing.T) {
+	mockStorage := NewMockStorage()
+	mockStorage.AddBlock(&types.Block{Height: 1, Hash: "hash1", ParentHash: "genesis"})
+	mockStorage.AddBlock(&types.Block{Height: 2, Hash: "hash2", ParentHash: "hash1"})
+	mockStorage.AddBlock(&types.Block{Height: 3, Hash: "hash3", ParentHash: "hash2"})
+
+	// The node knows none of the stored blocks, so the walk-back runs out of chain
+	// before reaching max depth
+	newBlocks := []types.Block{{Height: 4, Hash: "hash4_new", ParentHash: "hash3_new"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	result, err := reorg.New(mockStorage, 10, logger).Detect(context.Background(), types.ChainBTC, NewMockPoller(), newBlocks)
+	if err == nil {
+		t.Fatal("expected a max depth error")
+	}
+	if result == nil || result.RollbackHeight != 0 {
+		t.Errorf("expected rollback height clamped to 0, got %+v", result)
+	}
+}
+
+func TestCheckBatch(t *testing.T) {
+	good := []types.Block{
+		{Height: 3, Hash: "hash3", ParentHash: "hash2"},
+		{Height: 4, Hash: "hash4", ParentHash: "hash3"},
+		{Height: 5, Hash: "hash5", ParentHash: "hash4"},
+	}
+	if err := reorg.CheckBatch(good); err != nil {
+		t.Errorf("expected consistent batch, got %v", err)
+	}
+
+	broken := []types.Block{
+		{Height: 3, Hash: "hash3", ParentHash: "hash2"},
+		{Height: 4, Hash: "hash4_fork", ParentHash: "hash3_fork"}, // served from another fork
+		{Height: 5, Hash: "hash5", ParentHash: "hash4_fork"},
+	}
+	if err := reorg.CheckBatch(broken); !errors.Is(err, reorg.ErrInconsistentBatch) {
+		t.Errorf("expected ErrInconsistentBatch for broken parent link, got %v", err)
+	}
+
+	gap := []types.Block{
+		{Height: 3, Hash: "hash3", ParentHash: "hash2"},
+		{Height: 5, Hash: "hash5", ParentHash: "hash3"},
+	}
+	if err := reorg.CheckBatch(gap); !errors.Is(err, reorg.ErrInconsistentBatch) {
+		t.Errorf("expected ErrInconsistentBatch for height gap, got %v", err)
+	}
+}
+
+func TestDetect_InconsistentBatch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	// The batch is rejected before storage is consulted
+	detector := reorg.New(nil, 10, logger)
+
+	newBlocks := []types.Block{
+		{Height: 3, Hash: "hash3", ParentHash: "hash2"},
+		{Height: 4, Hash: "hash4", ParentHash: "hash3_other"},
+	}
+
+	result, err := detector.Detect(context.Background(), types.ChainBTC, NewMockPoller(), newBlocks)
+	if !errors.Is(err, reorg.ErrInconsistentBatch) {
+		t.Fatalf("expected ErrInconsistentBatch, got %v", err)
+	}
+	if result != nil {
+		t.Errorf("expected no reorg result for a discarded batch, got %+v", result)
+	}
+}
+
+// canonicalPoller is a MockPoller that also reports the canonical hash at a height
+type canonicalPoller struct {
+	*MockPoller
+	canonical func(height uint64) string
+}
+
+func (p *canonicalPoller) GetCanonicalHash(ctx context.Context, height uint64) (string, error) {
+	return p.canonical(height), nil
+}
+
+func TestDetect_RevalidatesForkPoint(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	setup := func() (*MockStorage, *MockPoller) {
+		mockStorage := NewMockStorage()
+		mockStorage.AddBlock(&types.Block{Height: 1, Hash: "hash1", ParentHash: "genesis"})
+		mockStorage.AddBlock(&types.Block{Height: 2, Hash: "hash2", ParentHash: "hash1"})
+		mockStorage.AddBlock(&types.Block{Height: 3, Hash: "hash3_orphan", ParentHash: "hash2"})
+
+		// The node still knows blocks 1 and 2; block 3 was reorged out
+		mockPoller := NewMockPoller()
+		mockPoller.AddBlock(&types.Block{Height: 1, Hash: "hash1"})
+		mockPoller.AddBlock(&types.Block{Height: 2, Hash: "hash2"})
+		return mockStorage, mockPoller
+	}
+	newBlocks := []types.Block{{Height: 4, Hash: "hash4", ParentHash: "hash3_new"}}
+
+	t.Run("stable", func(t *testing.T) {
+		mockStorage, mockPoller := setup()
+		p := &canonicalPoller{MockPoller: mockPoller, canonical: func(h uint64) string { return fmt.Sprintf("hash%d", h) }}
+
+		result, err := reorg.New(mockStorage, 10, logger).Detect(context.Background(), types.ChainBTC, p, newBlocks)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.RollbackHeight != 2 || result.RollbackHash != "hash2" {
+			t.Errorf("expected rollback to 2/hash2, got %+v", result)
+		}
+		if result.OldHash != "hash3_orphan" || result.NewHash != "hash3_new" {
+			t.Errorf("expected mismatch hash3_orphan -> hash3_new, got %+v", result)
+		}
+	})
+
+	t.Run("orphan still served by hash", func(t *testing.T) {
+		mockStorage, mockPoller := setup()
+		// Nodes keep orphaned blocks, so looking block 3 up by hash still succeeds
+		mockPoller.AddBlock(&types.Block{Height: 3, Hash: "hash3_orphan"})
+		p := &canonicalPoller{MockPoller: mockPoller, canonical: func(h uint64) string { return fmt.Sprintf("hash%d", h) }}
+
+		result, err := reorg.New(mockStorage, 10, logger).Detect(context.Background(), types.ChainBTC, p, newBlocks)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.RollbackHeight != 2 || result.RollbackHash != "hash2" {
+			t.Errorf("expected rollback to 2/hash2, got %+v", result)
+		}
+	})
+
+	t.Run("deepened mid-walk", func(t *testing.T) {
+		mockStorage, mockPoller := setup()
+		// Block 2 is reorged out after the first walk-back finds it
+		checks, reorged := 0, false
+		p := &canonicalPoller{MockPoller: mockPoller, canonical: func(h uint64) string {
+			checks++
+			if checks == 3 { // Re-check of the first fork point
+				reorged = true
+			}
+			if reorged && h >= 2 {
+				return fmt.Sprintf("hash%d_new", h)
+			}
+			return fmt.Sprintf("hash%d", h)
+		}}
+
+		result, err := reorg.New(mockStorage, 10, logger).Detect(context.Background(), types.ChainBTC, p, newBlocks)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.RollbackHeight != 1 || result.RollbackHash != "hash1" {
+			t.Errorf("expected the restarted search to roll back to 1/hash1, got %+v", result)
+		}
+	})
+
+	t.Run("never stable", func(t *testing.T) {
+		mockStorage, mockPoller := setup()
+		// The fork point moves between the walk finding it and its re-check
+		var last uint64
+		p := &canonicalPoller{MockPoller: mockPoller, canonical: func(h uint64) string {
+			defer func() { last = h }()
+			if h == last {
+				return "moving"
+			}
+			return fmt.Sprintf("hash%d", h)
+		}}
+
+		result, err := reorg.New(mockStorage, 10, logger).Detect(context.Background(), types.ChainBTC, p, newBlocks)
+		if !errors.Is(err, reorg.ErrUnstableForkPoint) {
+			t.Fatalf("expected ErrUnstableForkPoint, got %v", err)
+		}
+		if result != nil {
+			t.Errorf("expected no rollback, got %+v", result)
+		}
+	})
+}
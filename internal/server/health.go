@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/internal/indexer/internal/coordinator"
+)
+
+// DefaultHaltAfter is how long a chain may go without a successful poll before
+// it is reported halted
+const DefaultHaltAfter = 5 * time.Minute
+
+// Chain health states, in increasing severity. The overall status is the most
+// severe chain state; paused counts as ok since the operator stopped it.
+const (
+	StatusOK       = "ok"
+	StatusPaused   = "paused"
+	StatusStarting = "starting" // Not started yet, or no successful poll since it started
+	StatusLagging  = "lagging"  // Last indexed block is older than the max lag
+	StatusHalted   = "halted"   // No successful poll within the halt window
+)
+
+var statusSeverity = map[string]int{
+	StatusOK:       0,
+	StatusPaused:   0,
+	StatusStarting: 1,
+	StatusLagging:  2,
+	StatusHalted:   3,
+}
+
+// SetHealthThresholds sets when /healthz and /readyz report a chain unhealthy:
+// halted after haltAfter without a successful poll (zero or less keeps the default),
+// lagging once its last indexed block is older than maxLag (zero or less disables).
+func (s *Server) SetHealthThresholds(haltAfter, maxLag time.Duration) {
+	if haltAfter > 0 {
+		s.haltAfter = haltAfter
+	}
+	s.maxLag = maxLag
+}
+
+// HealthResponse is the health check response
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Chains map[string]ChainHealth `json:"chains"`
+}
+
+// ChainHealth contains health info for a single chain
+type ChainHealth struct {
+	Status            string    `json:"status"`
+	LastIndexedHeight uint64    `json:"last_indexed_height"`
+	LastIndexedAt     time.Time `json:"last_indexed_at"`
+	LastPollAt        time.Time `json:"last_poll_at"`
+	LagSeconds        int64     `json:"lag_seconds"`
+	BlockLagSeconds   int64     `json:"block_lag_seconds"` // Age of the last indexed block's timestamp
+	ConsecutiveErrors int       `json:"consecutive_errors"`
+	Paused            bool      `json:"paused"`
+}
+
+// chainStatus derives a chain's health state from its metrics. A chain still
+// waiting for a backfill slot has not started and is reported starting; the halt
+// window for its first poll runs from its own start.
+func chainStatus(m coordinator.MetricsSnapshot, now time.Time, haltAfter, maxLag time.Duration) string {
+	switch {
+	case m.Paused:
+		return StatusPaused
+	case m.LastPollAt.IsZero():
+		if !m.StartedAt.IsZero() && now.Sub(m.StartedAt) > haltAfter {
+			return StatusHalted
+		}
+		return StatusStarting
+	case now.Sub(m.LastPollAt) > haltAfter:
+		return StatusHalted
+	case maxLag > 0 && !m.LastBlockTime.IsZero() && now.Sub(m.LastBlockTime) > maxLag:
+		return StatusLagging
+	}
+	return StatusOK
+}
+
+// handleHealth serves /healthz and /readyz: per-chain state and an overall status,
+// with a 503 unless every chain is ok or paused
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	resp := HealthResponse{
+		Status: StatusOK,
+		Chains: make(map[string]ChainHealth),
+	}
+
+	for chainID, coord := range s.coordinators {
+		metrics := coord.GetMetrics()
+		status := chainStatus(metrics, now, s.haltAfter, s.maxLag)
+		if statusSeverity[status] > statusSeverity[resp.Status] {
+			resp.Status = status
+		}
+
+		// A paused chain is expected to fall behind; don't report it as lagging
+		var lagSeconds, blockLagSeconds float64
+		if !metrics.Paused {
+			lagSeconds = now.Sub(metrics.LastIndexedAt).Seconds()
+			if !metrics.LastBlockTime.IsZero() {
+				blockLagSeconds = now.Sub(metrics.LastBlockTime).Seconds()
+			}
+		}
+
+		resp.Chains[string(chainID)] = ChainHealth{
+			Status:            status,
+			LastIndexedHeight: metrics.LastIndexedHeight,
+			LastIndexedAt:     metrics.LastIndexedAt,
+			LastPollAt:        metrics.LastPollAt,
+			LagSeconds:        int64(lagSeconds),
+			BlockLagSeconds:   int64(blockLagSeconds),
+			ConsecutiveErrors: metrics.ConsecutiveErrors,
+			Paused:            metrics.Paused,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleLive serves /livez. It reflects only that the process can still serve
+// requests and take each coordinator's metrics lock, never chain state, so a
+// halted chain does not get the process restarted.
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
+	for _, coord := range s.coordinators {
+		coord.GetMetrics()
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
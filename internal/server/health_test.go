@@ -0,0 +1,47 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/internal/indexer/internal/coordinator"
+)
+
+func TestChainStatus(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-time.Minute)
+
+	tests := []struct {
+		name   string
+		m      coordinator.MetricsSnapshot
+		maxLag time.Duration
+		want   string
+	}{
+		{name: "healthy", m: coordinator.MetricsSnapshot{LastPollAt: now.Add(-time.Second), LastBlockTime: now.Add(-time.Hour)}, want: StatusOK},
+		{name: "no poll yet", m: coordinator.MetricsSnapshot{StartedAt: started}, want: StatusStarting},
+		{name: "never polled past halt window", m: coordinator.MetricsSnapshot{StartedAt: now.Add(-time.Hour)}, want: StatusHalted},
+		{name: "waiting for backfill slot", m: coordinator.MetricsSnapshot{}, want: StatusStarting},
+		{name: "polls failing", m: coordinator.MetricsSnapshot{LastPollAt: now.Add(-10 * time.Minute), ConsecutiveErrors: 40}, want: StatusHalted},
+		{name: "paused", m: coordinator.MetricsSnapshot{Paused: true, LastPollAt: now.Add(-time.Hour)}, want: StatusPaused},
+		{
+			name:   "deep lag",
+			m:      coordinator.MetricsSnapshot{LastPollAt: now, LastBlockTime: now.Add(-2 * time.Hour)},
+			maxLag: time.Hour,
+			want:   StatusLagging,
+		},
+		{
+			name:   "within max lag",
+			m:      coordinator.MetricsSnapshot{LastPollAt: now, LastBlockTime: now.Add(-30 * time.Minute)},
+			maxLag: time.Hour,
+			want:   StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chainStatus(tt.m, now, DefaultHaltAfter, tt.maxLag); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
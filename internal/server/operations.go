@@ -0,0 +1,69 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMaxAdminOperations is how many heavy admin operations may run at once
+const DefaultMaxAdminOperations = 1
+
+// Operation is a running heavy admin operation (e.g. a redecode)
+type Operation struct {
+	ID        uint64    `json:"id"`
+	Kind      string    `json:"kind"`
+	Chain     string    `json:"chain"`
+	Target    string    `json:"target,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// operations tracks running heavy admin operations and caps how many run at once,
+// shared by every admin handler that does bulk DB or RPC work
+type operations struct {
+	mu      sync.Mutex
+	limit   int
+	nextID  uint64
+	running map[uint64]Operation
+}
+
+func newOperations(limit int) *operations {
+	return &operations{
+		limit:   limit,
+		running: make(map[uint64]Operation),
+	}
+}
+
+// start registers an operation, or returns false if the limit is already reached
+func (o *operations) start(kind, chain, target string) (Operation, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.running) >= o.limit {
+		return Operation{}, false
+	}
+	o.nextID++
+	op := Operation{ID: o.nextID, Kind: kind, Chain: chain, Target: target, StartedAt: time.Now()}
+	o.running[op.ID] = op
+	return op, true
+}
+
+// finish releases an operation's slot
+func (o *operations) finish(id uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.running, id)
+}
+
+// list returns the running operations, oldest first
+func (o *operations) list() []Operation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	ops := make([]Operation, 0, len(o.running))
+	for _, op := range o.running {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].ID < ops[j].ID })
+	return ops
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOperations_Limit(t *testing.T) {
+	ops := newOperations(2)
+
+	first, ok := ops.start("redecode", "eth", "0xaa")
+	if !ok {
+		t.Fatal("expected first operation to start")
+	}
+	if _, ok := ops.start("redecode", "eth", "0xbb"); !ok {
+		t.Fatal("expected second operation to start")
+	}
+	if _, ok := ops.start("redecode", "eth", "0xcc"); ok {
+		t.Fatal("expected third operation to be rejected at the limit")
+	}
+
+	ops.finish(first.ID)
+	if _, ok := ops.start("redecode", "eth", "0xcc"); !ok {
+		t.Fatal("expected a slot after an operation finished")
+	}
+
+	list := ops.list()
+	if len(list) != 2 || list[0].Target != "0xbb" || list[1].Target != "0xcc" {
+		t.Errorf("expected running operations oldest first, got %+v", list)
+	}
+}
+
+func TestHandleOperations(t *testing.T) {
+	s := New(0, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.SetMaxAdminOperations(3)
+	op, _ := s.ops.start("redecode", "eth", "0xaa")
+	defer s.ops.finish(op.ID)
+
+	rec := httptest.NewRecorder()
+	s.handleOperations(rec, httptest.NewRequest(http.MethodGet, "/admin/operations", nil))
+
+	var resp struct {
+		Limit      int         `json:"limit"`
+		Operations []Operation `json:"operations"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Limit != 3 || len(resp.Operations) != 1 || resp.Operations[0].Kind != "redecode" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestAdmin_Token(t *testing.T) {
+	s := New(0, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	h := s.admin(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	do := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin/operations", nil)
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code
+	}
+
+	// Refused while no token is configured, whatever the request sends
+	if code := do(""); code != http.StatusForbidden {
+		t.Errorf("expected 403 without a configured token, got %d", code)
+	}
+
+	s.SetAdminToken("secret")
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "secret": http.StatusNoContent} {
+		if code := do(token); code != want {
+			t.Errorf("token %q: expected %d, got %d", token, want, code)
+		}
+	}
+}
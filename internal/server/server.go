@@ -2,14 +2,18 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/internal/indexer/internal/coordinator"
+	"github.com/internal/indexer/internal/webhook"
 	"github.com/internal/indexer/pkg/types"
 )
 
@@ -18,8 +22,15 @@ type Server struct {
 	healthPort   int
 	metricsPort  int
 	coordinators map[types.ChainID]*coordinator.Coordinator
+	adminToken   string
+	ops          *operations
+	webhooks     *webhook.Dispatcher // nil disables the webhook endpoints
 	logger       *slog.Logger
 
+	// Health thresholds (see SetHealthThresholds)
+	haltAfter time.Duration
+	maxLag    time.Duration
+
 	healthServer  *http.Server
 	metricsServer *http.Server
 }
@@ -30,7 +41,9 @@ func New(healthPort, metricsPort int, logger *slog.Logger) *Server {
 		healthPort:   healthPort,
 		metricsPort:  metricsPort,
 		coordinators: make(map[types.ChainID]*coordinator.Coordinator),
+		ops:          newOperations(DefaultMaxAdminOperations),
 		logger:       logger,
+		haltAfter:    DefaultHaltAfter,
 	}
 }
 
@@ -39,6 +52,21 @@ func (s *Server) RegisterCoordinator(chainID types.ChainID, c *coordinator.Coord
 	s.coordinators[chainID] = c
 }
 
+// SetAdminToken requires the given token in the X-Admin-Token header for /admin
+// endpoints. Without one they are refused.
+func (s *Server) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// SetMaxAdminOperations sets how many heavy admin operations may run at once;
+// further requests get a 429 until one finishes. Zero or less keeps the default.
+// Must be called before Start.
+func (s *Server) SetMaxAdminOperations(n int) {
+	if n > 0 {
+		s.ops = newOperations(n)
+	}
+}
+
 // Start starts the HTTP servers
 func (s *Server) Start(ctx context.Context) error {
 	var wg sync.WaitGroup
@@ -46,8 +74,19 @@ func (s *Server) Start(ctx context.Context) error {
 
 	// Health server
 	healthMux := http.NewServeMux()
+	healthMux.HandleFunc("/livez", s.handleLive)
 	healthMux.HandleFunc("/healthz", s.handleHealth)
-	healthMux.HandleFunc("/readyz", s.handleReady)
+	healthMux.HandleFunc("/readyz", s.handleHealth)
+
+	// Admin controls
+	healthMux.HandleFunc("POST /admin/{chain}/pause", s.admin(s.handlePause))
+	healthMux.HandleFunc("POST /admin/{chain}/resume", s.admin(s.handleResume))
+	healthMux.HandleFunc("POST /admin/{chain}/redecode", s.admin(s.handleRedecode))
+	healthMux.HandleFunc("GET /admin/{chain}/reorgs", s.admin(s.handleReorgs))
+	healthMux.HandleFunc("GET /admin/operations", s.admin(s.handleOperations))
+	healthMux.HandleFunc("POST /admin/{chain}/webhooks", s.admin(s.handleCreateWebhook))
+	healthMux.HandleFunc("GET /admin/{chain}/webhooks", s.admin(s.handleListWebhooks))
+	healthMux.HandleFunc("DELETE /admin/{chain}/webhooks/{id}", s.admin(s.handleDeleteWebhook))
 
 	s.healthServer = &http.Server{
 		Addr:         fmt.Sprintf(":%d", s.healthPort),
@@ -117,44 +156,151 @@ func (s *Server) Shutdown() error {
 	return nil
 }
 
-// HealthResponse is the health check response
-type HealthResponse struct {
-	Status string                 `json:"status"`
-	Chains map[string]ChainHealth `json:"chains"`
+// admin wraps an admin handler with the token check. Admin endpoints pause
+// indexing, run heavy queries and make the indexer POST to given URLs, so they
+// are refused outright while no token is configured.
+func (s *Server) admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			http.Error(w, "admin endpoints require server.admin_token", http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(s.adminToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// AdminResponse is returned by admin control endpoints
+type AdminResponse struct {
+	Chain  string `json:"chain"`
+	Paused bool   `json:"paused"`
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, true)
 }
 
-// ChainHealth contains health info for a single chain
-type ChainHealth struct {
-	LastIndexedHeight uint64    `json:"last_indexed_height"`
-	LastIndexedAt     time.Time `json:"last_indexed_at"`
-	LagSeconds        int64     `json:"lag_seconds"`
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, false)
 }
 
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	resp := HealthResponse{
-		Status: "ok",
-		Chains: make(map[string]ChainHealth),
+func (s *Server) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	chain := r.PathValue("chain")
+	coord, ok := s.coordinators[types.ChainID(chain)]
+	if !ok {
+		http.Error(w, "unknown chain", http.StatusNotFound)
+		return
 	}
 
-	for chainID, coord := range s.coordinators {
-		metrics := coord.GetMetrics()
-		lagSeconds := time.Since(metrics.LastIndexedAt).Seconds()
+	if paused {
+		coord.Pause()
+	} else {
+		coord.Resume()
+	}
+	s.logger.Info("admin indexing control", "chain", chain, "paused", paused)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AdminResponse{Chain: chain, Paused: coord.Paused()})
+}
+
+// RedecodeResponse is returned by the redecode admin endpoint
+type RedecodeResponse struct {
+	Chain       string `json:"chain"`
+	Contract    string `json:"contract"`
+	Decoded     int64  `json:"decoded"`
+	StillFailed int64  `json:"still_failed"`
+}
+
+// handleRedecode re-decodes a contract's decode_failed events with the ABIs the
+// indexer currently has loaded, e.g. after adding an abi_path and restarting
+func (s *Server) handleRedecode(w http.ResponseWriter, r *http.Request) {
+	chain := r.PathValue("chain")
+	coord, ok := s.coordinators[types.ChainID(chain)]
+	if !ok {
+		http.Error(w, "unknown chain", http.StatusNotFound)
+		return
+	}
+	contract := r.URL.Query().Get("contract")
+	if contract == "" {
+		http.Error(w, "contract is required", http.StatusBadRequest)
+		return
+	}
 
-		resp.Chains[string(chainID)] = ChainHealth{
-			LastIndexedHeight: metrics.LastIndexedHeight,
-			LastIndexedAt:     metrics.LastIndexedAt,
-			LagSeconds:        int64(lagSeconds),
+	op, ok := s.ops.start("redecode", chain, contract)
+	if !ok {
+		http.Error(w, "too many admin operations running; see GET /admin/operations", http.StatusTooManyRequests)
+		return
+	}
+	defer s.ops.finish(op.ID)
+
+	// A contract with a long history can take longer than the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	decoded, failed, err := coord.RedecodeFailedEvents(r.Context(), contract)
+	if errors.Is(err, coordinator.ErrRedecodeUnsupported) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.Error("redecode failed", "chain", chain, "contract", contract, "decoded", decoded, "error", err)
+		http.Error(w, "redecode failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RedecodeResponse{Chain: chain, Contract: contract, Decoded: decoded, StillFailed: failed})
+}
+
+// Reorg history page sizes for GET /admin/{chain}/reorgs
+const (
+	defaultReorgsLimit = 100
+	maxReorgsLimit     = 1000
+)
+
+// handleReorgs lists the chain's recorded reorgs, newest first. Reorgs are only
+// recorded while record_reorgs is enabled for the chain.
+func (s *Server) handleReorgs(w http.ResponseWriter, r *http.Request) {
+	chain := r.PathValue("chain")
+	coord, ok := s.coordinators[types.ChainID(chain)]
+	if !ok {
+		http.Error(w, "unknown chain", http.StatusNotFound)
+		return
+	}
+
+	limit := defaultReorgsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
 		}
+		limit = min(n, maxReorgsLimit)
+	}
+
+	reorgs, err := coord.Reorgs(r.Context(), limit)
+	if err != nil {
+		s.logger.Error("listing reorgs failed", "chain", chain, "error", err)
+		http.Error(w, "listing reorgs failed", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	json.NewEncoder(w).Encode(struct {
+		Chain  string        `json:"chain"`
+		Reorgs []types.Reorg `json:"reorgs"`
+	}{Chain: chain, Reorgs: reorgs})
 }
 
-func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
-	// Simple readiness check
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ok"))
+// handleOperations lists the heavy admin operations currently running
+func (s *Server) handleOperations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Limit      int         `json:"limit"`
+		Operations []Operation `json:"operations"`
+	}{Limit: s.ops.limit, Operations: s.ops.list()})
 }
 
 func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
@@ -192,6 +338,38 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "# TYPE indexer_last_reorg_depth gauge\n")
 		fmt.Fprintf(w, "indexer_last_reorg_depth{chain=\"%s\"} %d\n", chain, metrics.LastReorgDepth)
 
+		paused := 0
+		if metrics.Paused {
+			paused = 1
+		}
+		fmt.Fprintf(w, "# HELP indexer_paused Whether indexing is paused by an operator\n")
+		fmt.Fprintf(w, "# TYPE indexer_paused gauge\n")
+		fmt.Fprintf(w, "indexer_paused{chain=\"%s\"} %d\n", chain, paused)
+
+		caughtUp := 0
+		if metrics.CaughtUp {
+			caughtUp = 1
+		}
+		fmt.Fprintf(w, "# HELP indexer_caught_up Whether the chain's initial backfill has reached the tip\n")
+		fmt.Fprintf(w, "# TYPE indexer_caught_up gauge\n")
+		fmt.Fprintf(w, "indexer_caught_up{chain=\"%s\"} %d\n", chain, caughtUp)
+
+		fmt.Fprintf(w, "# HELP indexer_write_verify_mismatches_total Write aggregates that diverged from their SQL recomputation\n")
+		fmt.Fprintf(w, "# TYPE indexer_write_verify_mismatches_total counter\n")
+		fmt.Fprintf(w, "indexer_write_verify_mismatches_total{chain=\"%s\"} %d\n", chain, metrics.VerifyMismatches)
+
+		fmt.Fprintf(w, "# HELP indexer_events_oversized_total Events stored undecoded because their decoded params exceeded max_decoded_event_bytes\n")
+		fmt.Fprintf(w, "# TYPE indexer_events_oversized_total counter\n")
+		fmt.Fprintf(w, "indexer_events_oversized_total{chain=\"%s\"} %d\n", chain, metrics.OversizedEvents)
+
+		fmt.Fprintf(w, "# HELP indexer_rpc_active_endpoint Index of the RPC endpoint requests go to (0 = rpc_url, then rpc_urls in order)\n")
+		fmt.Fprintf(w, "# TYPE indexer_rpc_active_endpoint gauge\n")
+		fmt.Fprintf(w, "indexer_rpc_active_endpoint{chain=\"%s\"} %d\n", chain, metrics.RPCEndpoint)
+
+		fmt.Fprintf(w, "# HELP indexer_rpc_failovers_total Times requests failed over to another RPC endpoint\n")
+		fmt.Fprintf(w, "# TYPE indexer_rpc_failovers_total counter\n")
+		fmt.Fprintf(w, "indexer_rpc_failovers_total{chain=\"%s\"} %d\n", chain, metrics.RPCFailovers)
+
 		fmt.Fprintf(w, "\n")
 	}
 }
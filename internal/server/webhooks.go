@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/internal/indexer/internal/webhook"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// SetWebhooks enables the /admin/{chain}/webhooks endpoints, managing d's subscriptions
+func (s *Server) SetWebhooks(d *webhook.Dispatcher) {
+	s.webhooks = d
+}
+
+// webhookChain resolves the {chain} of a webhook request, writing the error
+// response when webhooks are disabled or the chain is not indexed
+func (s *Server) webhookChain(w http.ResponseWriter, r *http.Request) (types.ChainID, bool) {
+	if s.webhooks == nil {
+		http.Error(w, "webhooks are not enabled", http.StatusNotFound)
+		return "", false
+	}
+	chainID := types.ChainID(r.PathValue("chain"))
+	if _, ok := s.coordinators[chainID]; !ok {
+		http.Error(w, "unknown chain", http.StatusNotFound)
+		return "", false
+	}
+	return chainID, true
+}
+
+// handleCreateWebhook subscribes a callback URL to an address. The response
+// includes the signing secret, which is not shown again.
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	chainID, ok := s.webhookChain(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Address     string `json:"address"`
+		CallbackURL string `json:"callback_url"`
+		Secret      string `json:"secret"` // Optional; generated when empty
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	sub := types.WebhookSubscription{
+		ChainID:     chainID,
+		Address:     req.Address,
+		CallbackURL: req.CallbackURL,
+		Secret:      req.Secret,
+	}
+	if err := s.webhooks.Register(r.Context(), &sub); err != nil {
+		if errors.Is(err, webhook.ErrInvalidSubscription) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.logger.Error("registering webhook failed", "chain", chainID, "address", req.Address, "error", err)
+		http.Error(w, "registering webhook failed", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("webhook registered", "chain", chainID, "id", sub.ID, "address", sub.Address)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
+
+// handleListWebhooks lists a chain's subscriptions, without secrets
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	chainID, ok := s.webhookChain(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Chain         string                      `json:"chain"`
+		Subscriptions []types.WebhookSubscription `json:"subscriptions"`
+	}{Chain: string(chainID), Subscriptions: s.webhooks.List(chainID)})
+}
+
+// handleDeleteWebhook unsubscribes by subscription ID
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	chainID, ok := s.webhookChain(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "id must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.webhooks.Unregister(r.Context(), chainID, id)
+	if err != nil {
+		s.logger.Error("deleting webhook failed", "chain", chainID, "id", id, "error", err)
+		http.Error(w, "deleting webhook failed", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	s.logger.Info("webhook deleted", "chain", chainID, "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/internal/indexer/internal/config"
+	"github.com/internal/indexer/internal/coordinator"
+	"github.com/internal/indexer/internal/webhook"
+	"github.com/internal/indexer/pkg/types"
+)
+
+// webhookStore keeps subscriptions in a slice
+type webhookStore struct {
+	subs []types.WebhookSubscription
+}
+
+func (s *webhookStore) CreateWebhookSubscription(ctx context.Context, sub *types.WebhookSubscription) error {
+	sub.ID = int64(len(s.subs) + 1)
+	s.subs = append(s.subs, *sub)
+	return nil
+}
+
+func (s *webhookStore) DeleteWebhookSubscription(ctx context.Context, chainID types.ChainID, id int64) (bool, error) {
+	return id >= 1 && id <= int64(len(s.subs)), nil
+}
+
+func (s *webhookStore) GetWebhookSubscriptions(ctx context.Context, chainID types.ChainID) ([]types.WebhookSubscription, error) {
+	return s.subs, nil
+}
+
+func TestWebhookEndpoints(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := New(0, 0, logger)
+	s.RegisterCoordinator(types.ChainETH, coordinator.New(types.ChainETH, config.ChainConfig{}, nil, nil, nil, logger))
+
+	do := func(h http.HandlerFunc, method, target, body string, params map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for k, v := range params {
+			req.SetPathValue(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+	eth := map[string]string{"chain": "eth"}
+
+	// Disabled until a dispatcher is set
+	if rec := do(s.handleListWebhooks, http.MethodGet, "/admin/eth/webhooks", "", eth); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 with webhooks disabled, got %d", rec.Code)
+	}
+	s.SetWebhooks(webhook.New(&webhookStore{}, webhook.Config{}, logger))
+
+	rec := do(s.handleCreateWebhook, http.MethodPost, "/admin/eth/webhooks",
+		`{"address":"0xABC","callback_url":"https://example.com/hook"}`, eth)
+	var created types.WebhookSubscription
+	json.NewDecoder(rec.Body).Decode(&created)
+	if rec.Code != http.StatusCreated || created.ID != 1 || created.Address != "0xabc" || created.Secret == "" {
+		t.Fatalf("expected 201 with the subscription and its secret, got %d %+v", rec.Code, created)
+	}
+
+	for _, tc := range []struct {
+		body   string
+		params map[string]string
+		want   int
+	}{
+		{`{"address":"0xabc","callback_url":"not a url"}`, eth, http.StatusBadRequest},
+		{`{`, eth, http.StatusBadRequest},
+		{`{"address":"bc1q","callback_url":"https://example.com/hook"}`, map[string]string{"chain": "btc"}, http.StatusNotFound},
+	} {
+		if rec := do(s.handleCreateWebhook, http.MethodPost, "/admin/x/webhooks", tc.body, tc.params); rec.Code != tc.want {
+			t.Errorf("%s on %v: expected %d, got %d", tc.body, tc.params, tc.want, rec.Code)
+		}
+	}
+
+	rec = do(s.handleListWebhooks, http.MethodGet, "/admin/eth/webhooks", "", eth)
+	if !strings.Contains(rec.Body.String(), `"callback_url":"https://example.com/hook"`) || strings.Contains(rec.Body.String(), created.Secret) {
+		t.Errorf("expected the subscription listed without its secret, got %s", rec.Body)
+	}
+
+	for id, want := range map[string]int{"1": http.StatusNoContent, "9": http.StatusNotFound, "x": http.StatusBadRequest} {
+		rec := do(s.handleDeleteWebhook, http.MethodDelete, "/admin/eth/webhooks/"+id, "", map[string]string{"chain": "eth", "id": id})
+		if rec.Code != want {
+			t.Errorf("DELETE %s: expected %d, got %d", id, want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// logCountBatch is the number of blocks recounted per statement by RecomputeLogCounts
+const logCountBatch = 10000
+
+// SetLogCounts enables populating transactions.log_count for a chain from the events
+// written in the same batch. Only indexed events (monitored contracts) are counted.
+func (s *Storage) SetLogCounts(chainID types.ChainID, enabled bool) {
+	s.logCountChains[chainID] = enabled
+}
+
+// logCounts returns the number of events per tx hash, or nil when counting is off
+func (s *Storage) logCounts(chainID types.ChainID, events []types.Event) map[string]int {
+	if !s.logCountChains[chainID] {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, e := range events {
+		counts[e.TxHash]++
+	}
+	return counts
+}
+
+// RecomputeLogCounts rewrites transactions.log_count for heights [fromHeight, toHeight]
+// from the events table, in batches of logCountBatch blocks so no single statement
+// locks the whole table. Used to backfill rows written before counting was enabled.
+// Returns the number of transactions updated.
+func (s *Storage) RecomputeLogCounts(ctx context.Context, chainID types.ChainID, fromHeight, toHeight uint64) (int64, error) {
+	var total int64
+	for start := fromHeight; start <= toHeight; start += logCountBatch {
+		end := min(start+logCountBatch-1, toHeight)
+
+		res, err := s.conn(chainID).ExecContext(ctx, `
+			UPDATE transactions t
+			SET log_count = (
+				SELECT COUNT(*) FROM events e
+				WHERE e.chain_id = t.chain_id AND e.tx_hash = t.tx_hash AND e.block_hash = t.block_hash
+			)
+			WHERE t.chain_id = $1 AND t.block_height BETWEEN $2 AND $3
+		`, string(chainID), start, end)
+		if err != nil {
+			return total, fmt.Errorf("recomputing log counts %d-%d: %w", start, end, err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
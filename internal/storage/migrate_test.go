@@ -0,0 +1,18 @@
+package storage
+
+import "testing"
+
+func TestMigrationFiles(t *testing.T) {
+	up, down, err := migrationFiles()
+	if err != nil {
+		t.Fatalf("migrationFiles: %v", err)
+	}
+	if len(up) == 0 || len(down) != len(up) {
+		t.Fatalf("expected a down migration per up migration, got %d up and %d down", len(up), len(down))
+	}
+	for version := 1; version <= len(up); version++ {
+		if up[version] == "" {
+			t.Errorf("missing up migration %03d", version)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+-- Migration: 002_add_contracts_table.down.sql
+
+DROP TABLE IF EXISTS contracts;
@@ -0,0 +1,3 @@
+-- Migration: 003_add_address_stats.down.sql
+
+DROP TABLE IF EXISTS address_stats;
@@ -0,0 +1,5 @@
+-- Migration: 004_add_token_tables.down.sql
+
+DROP TABLE IF EXISTS token_balances;
+DROP TABLE IF EXISTS token_transfers;
+DROP TABLE IF EXISTS tokens;
@@ -0,0 +1,6 @@
+-- Migration: 005_add_trgm_extension.down.sql
+-- The pg_trgm extension is kept: it lives in the shared schema, where other
+-- schemas' indexes may still use it
+
+DROP INDEX IF EXISTS idx_tokens_symbol_trgm;
+DROP INDEX IF EXISTS idx_tokens_name_trgm;
@@ -0,0 +1,3 @@
+-- Migration: 006_add_events_latest_index.down.sql
+
+DROP INDEX IF EXISTS idx_events_contract_latest;
@@ -0,0 +1,4 @@
+-- Migration: 006_add_events_latest_index.up.sql
+
+-- Serves "latest N events for a contract" directly from the index without a sort
+CREATE INDEX IF NOT EXISTS idx_events_contract_latest ON events(chain_id, contract_addr, block_height DESC, log_index DESC);
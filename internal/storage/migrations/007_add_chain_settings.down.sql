@@ -0,0 +1,3 @@
+-- Migration: 007_add_chain_settings.down.sql
+
+DROP TABLE IF EXISTS chain_settings;
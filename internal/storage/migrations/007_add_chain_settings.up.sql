@@ -0,0 +1,9 @@
+-- Migration: 007_add_chain_settings.up.sql
+-- Indexer finality settings per chain, published at startup so the API can expose them
+
+CREATE TABLE IF NOT EXISTS chain_settings (
+    chain_id            VARCHAR(16) PRIMARY KEY,
+    confirmation_depth  INT NOT NULL,
+    max_reorg_depth     INT NOT NULL,
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
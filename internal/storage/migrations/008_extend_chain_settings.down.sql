@@ -0,0 +1,4 @@
+-- Migration: 008_extend_chain_settings.down.sql
+
+ALTER TABLE chain_settings DROP COLUMN IF EXISTS contracts;
+ALTER TABLE chain_settings DROP COLUMN IF EXISTS start_height;
@@ -0,0 +1,5 @@
+-- Migration: 008_extend_chain_settings.up.sql
+-- Start height and monitored contracts, so the API can describe what the indexer covers
+
+ALTER TABLE chain_settings ADD COLUMN IF NOT EXISTS start_height BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE chain_settings ADD COLUMN IF NOT EXISTS contracts TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+-- Migration: 009_add_publish_checkpoints.down.sql
+
+DROP TABLE IF EXISTS publish_checkpoints;
@@ -0,0 +1,8 @@
+-- Migration: 009_add_publish_checkpoints.up.sql
+-- Highest height delivered to the message broker; the publish relay catches up from here
+
+CREATE TABLE IF NOT EXISTS publish_checkpoints (
+    chain_id         VARCHAR(16) PRIMARY KEY,
+    published_up_to  BIGINT NOT NULL,
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- Migration: 010_add_block_miner.down.sql
+
+DROP INDEX IF EXISTS idx_blocks_miner;
+ALTER TABLE blocks DROP COLUMN IF EXISTS miner;
@@ -0,0 +1,6 @@
+-- Migration: 010_add_block_miner.up.sql
+-- Block producer (ETH fee recipient, BTC coinbase payout address) for per-miner block listings
+
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS miner VARCHAR(66);
+
+CREATE INDEX IF NOT EXISTS idx_blocks_miner ON blocks(chain_id, miner, height DESC) WHERE miner IS NOT NULL;
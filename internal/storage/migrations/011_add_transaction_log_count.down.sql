@@ -0,0 +1,3 @@
+-- Migration: 011_add_transaction_log_count.down.sql
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS log_count;
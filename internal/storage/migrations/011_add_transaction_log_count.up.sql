@@ -0,0 +1,4 @@
+-- Migration: 011_add_transaction_log_count.up.sql
+-- Per-transaction count of indexed events, so tx lists don't need a subquery on events
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS log_count INT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Migration: 012_add_block_tx_count.down.sql
+
+ALTER TABLE blocks DROP COLUMN IF EXISTS tx_count;
@@ -0,0 +1,4 @@
+-- Migration: 012_add_block_tx_count.up.sql
+-- Per-block transaction count, so block responses don't need a count over transactions
+
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS tx_count INT NOT NULL DEFAULT 0;
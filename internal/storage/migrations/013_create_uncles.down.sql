@@ -0,0 +1,3 @@
+-- Migration: 013_create_uncles.down.sql
+
+DROP TABLE IF EXISTS uncles;
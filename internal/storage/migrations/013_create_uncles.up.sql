@@ -0,0 +1,14 @@
+-- Migration: 013_create_uncles.up.sql
+-- Uncle (ommer) references of pre-Merge ETH blocks, written when store_uncles is enabled
+
+CREATE TABLE IF NOT EXISTS uncles (
+    chain_id        VARCHAR(16) NOT NULL,
+    block_height    BIGINT NOT NULL,
+    block_hash      VARCHAR(66) NOT NULL,
+    uncle_index     INT NOT NULL,
+    uncle_hash      VARCHAR(66) NOT NULL,
+    PRIMARY KEY (chain_id, block_hash, uncle_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_uncles_height ON uncles(chain_id, block_height);
+CREATE INDEX IF NOT EXISTS idx_uncles_hash ON uncles(chain_id, uncle_hash);
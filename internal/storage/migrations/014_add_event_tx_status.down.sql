@@ -0,0 +1,3 @@
+-- Migration: 014_add_event_tx_status.down.sql
+
+ALTER TABLE events DROP COLUMN IF EXISTS tx_status;
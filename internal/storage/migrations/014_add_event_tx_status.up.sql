@@ -0,0 +1,4 @@
+-- Migration: 014_add_event_tx_status.up.sql
+-- Receipt status of the emitting transaction ('success'/'reverted'), NULL when not fetched
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS tx_status VARCHAR(16);
@@ -0,0 +1,5 @@
+-- Migration: 015_normalize_eth_addresses.down.sql
+-- The original checksum casing is not recoverable, and lowercase addresses are
+-- what the code writes and looks up, so there is nothing to undo
+
+SELECT 1;
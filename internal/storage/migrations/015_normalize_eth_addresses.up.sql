@@ -0,0 +1,14 @@
+-- Migration: 015_normalize_eth_addresses.up.sql
+-- ETH addresses are stored lowercase (types.NormalizeAddress). Token transfer and balance
+-- rows were written with EIP-55 checksum casing; lowercase them so lookups match.
+-- BTC addresses are case-sensitive and left untouched.
+
+UPDATE token_transfers
+SET token_address = LOWER(token_address), from_addr = LOWER(from_addr), to_addr = LOWER(to_addr)
+WHERE chain_id = 'eth'
+  AND (token_address <> LOWER(token_address) OR from_addr <> LOWER(from_addr) OR to_addr <> LOWER(to_addr));
+
+UPDATE token_balances
+SET address = LOWER(address), token_address = LOWER(token_address)
+WHERE chain_id = 'eth'
+  AND (address <> LOWER(address) OR token_address <> LOWER(token_address));
@@ -0,0 +1,3 @@
+-- Migration: 016_add_transactions_from_height_index.down.sql
+
+DROP INDEX IF EXISTS idx_transactions_from_height;
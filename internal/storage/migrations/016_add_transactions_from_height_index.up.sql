@@ -0,0 +1,4 @@
+-- Migration: 016_add_transactions_from_height_index.up.sql
+-- Height-bounded lookups of a sender's transactions (events by tx sender)
+
+CREATE INDEX IF NOT EXISTS idx_transactions_from_height ON transactions(chain_id, from_addr, block_height) WHERE from_addr IS NOT NULL;
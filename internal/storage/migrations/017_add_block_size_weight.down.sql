@@ -0,0 +1,5 @@
+-- Migration: 017_add_block_size_weight.down.sql
+
+ALTER TABLE blocks DROP COLUMN IF EXISTS weight;
+ALTER TABLE blocks DROP COLUMN IF EXISTS stripped_size;
+ALTER TABLE blocks DROP COLUMN IF EXISTS size;
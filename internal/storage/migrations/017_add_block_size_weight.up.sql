@@ -0,0 +1,6 @@
+-- Migration: 017_add_block_size_weight.up.sql
+-- BTC block size, stripped size and weight from getblock (NULL when the node did not report them)
+
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS size INT;
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS stripped_size INT;
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS weight INT;
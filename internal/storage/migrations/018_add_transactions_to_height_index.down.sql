@@ -0,0 +1,3 @@
+-- Migration: 018_add_transactions_to_height_index.down.sql
+
+DROP INDEX IF EXISTS idx_transactions_to_height;
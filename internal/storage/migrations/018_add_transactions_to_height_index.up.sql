@@ -0,0 +1,4 @@
+-- Migration: 018_add_transactions_to_height_index.up.sql
+-- Height-ordered lookups of a recipient's transactions (an address's first transaction)
+
+CREATE INDEX IF NOT EXISTS idx_transactions_to_height ON transactions(chain_id, to_addr, block_height) WHERE to_addr IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Migration: 019_add_reorgs_table.down.sql
+
+DROP TABLE IF EXISTS reorgs;
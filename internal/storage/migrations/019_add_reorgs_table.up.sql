@@ -0,0 +1,14 @@
+-- Migration: 019_add_reorgs_table.up.sql
+-- Reorgs the indexer detected and rolled back, written when record_reorgs is enabled
+
+CREATE TABLE IF NOT EXISTS reorgs (
+    id              BIGSERIAL PRIMARY KEY,
+    chain_id        VARCHAR(16) NOT NULL,
+    detected_at     TIMESTAMPTZ NOT NULL,
+    rollback_height BIGINT NOT NULL,
+    depth           INT NOT NULL,
+    old_hash        VARCHAR(66) NOT NULL,
+    new_hash        VARCHAR(66) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reorgs_chain ON reorgs(chain_id, id DESC);
@@ -0,0 +1,3 @@
+-- Migration: 020_add_transactions_tx_status.down.sql
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS tx_status;
@@ -0,0 +1,4 @@
+-- Migration: 020_add_transactions_tx_status.up.sql
+-- ETH transaction outcome from its receipt ('success' or 'reverted'; NULL when receipts are not fetched)
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS tx_status VARCHAR(16);
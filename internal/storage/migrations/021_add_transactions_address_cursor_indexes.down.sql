@@ -0,0 +1,4 @@
+-- Migration: 021_add_transactions_address_cursor_indexes.down.sql
+
+DROP INDEX IF EXISTS idx_transactions_to_cursor;
+DROP INDEX IF EXISTS idx_transactions_from_cursor;
@@ -0,0 +1,5 @@
+-- Migration: 021_add_transactions_address_cursor_indexes.up.sql
+-- Address transaction pages ordered and keyed by (block_height, tx_index)
+
+CREATE INDEX IF NOT EXISTS idx_transactions_from_cursor ON transactions(chain_id, from_addr, block_height DESC, tx_index DESC) WHERE from_addr IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_to_cursor ON transactions(chain_id, to_addr, block_height DESC, tx_index DESC) WHERE to_addr IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Migration: 022_add_token_balances_holders_index.down.sql
+
+DROP INDEX IF EXISTS idx_token_balances_holders;
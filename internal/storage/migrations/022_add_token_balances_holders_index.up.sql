@@ -0,0 +1,4 @@
+-- Migration: 022_add_token_balances_holders_index.up.sql
+-- A token's holders ordered by balance (GET /token/{chain}/{address}/holders)
+
+CREATE INDEX IF NOT EXISTS idx_token_balances_holders ON token_balances(chain_id, token_address, balance DESC) WHERE balance > 0;
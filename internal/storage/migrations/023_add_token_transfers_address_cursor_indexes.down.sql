@@ -0,0 +1,4 @@
+-- Migration: 023_add_token_transfers_address_cursor_indexes.down.sql
+
+DROP INDEX IF EXISTS idx_token_transfers_to_cursor;
+DROP INDEX IF EXISTS idx_token_transfers_from_cursor;
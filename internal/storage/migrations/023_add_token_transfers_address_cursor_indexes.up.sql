@@ -0,0 +1,5 @@
+-- Migration: 023_add_token_transfers_address_cursor_indexes.up.sql
+-- An address's token transfers ordered and keyed by (block_height, log_index)
+
+CREATE INDEX IF NOT EXISTS idx_token_transfers_from_cursor ON token_transfers(chain_id, from_addr, block_height DESC, log_index DESC);
+CREATE INDEX IF NOT EXISTS idx_token_transfers_to_cursor ON token_transfers(chain_id, to_addr, block_height DESC, log_index DESC);
@@ -0,0 +1,3 @@
+-- Migration: 024_add_webhook_subscriptions_table.down.sql
+
+DROP TABLE IF EXISTS webhook_subscriptions;
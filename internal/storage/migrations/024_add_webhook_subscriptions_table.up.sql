@@ -0,0 +1,12 @@
+-- Migration: 024_add_webhook_subscriptions_table.up.sql
+-- Callback URLs notified of new transactions and token transfers touching an address
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id           BIGSERIAL PRIMARY KEY,
+    chain_id     VARCHAR(16) NOT NULL,
+    address      VARCHAR(66) NOT NULL,
+    callback_url TEXT NOT NULL,
+    secret       TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (chain_id, address, callback_url)
+);
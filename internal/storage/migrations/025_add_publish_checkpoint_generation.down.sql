@@ -0,0 +1,3 @@
+-- Migration: 025_add_publish_checkpoint_generation.down.sql
+
+ALTER TABLE publish_checkpoints DROP COLUMN IF EXISTS generation;
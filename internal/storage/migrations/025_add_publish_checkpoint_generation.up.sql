@@ -0,0 +1,5 @@
+-- Migration: 025_add_publish_checkpoint_generation.up.sql
+-- Bumped by every rollback so the publish relay can tell that a range it read
+-- may have been replaced, even when the rollback stayed above published_up_to
+
+ALTER TABLE publish_checkpoints ADD COLUMN IF NOT EXISTS generation BIGINT NOT NULL DEFAULT 0;
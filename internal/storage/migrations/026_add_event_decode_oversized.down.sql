@@ -0,0 +1,3 @@
+-- Migration: 026_add_event_decode_oversized.down.sql
+
+ALTER TABLE events DROP COLUMN IF EXISTS decode_oversized;
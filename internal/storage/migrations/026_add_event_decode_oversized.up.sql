@@ -0,0 +1,5 @@
+-- Migration: 026_add_event_decode_oversized.up.sql
+-- Events stored undecoded because their decoded params exceeded max_decoded_event_bytes.
+-- They also have decode_failed set, but re-decoding cannot fix them, so redecode skips them.
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS decode_oversized BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- Migration: 027_add_checkpoint_raw_data_pruned.down.sql
+
+ALTER TABLE checkpoints DROP COLUMN IF EXISTS raw_data_pruned_height;
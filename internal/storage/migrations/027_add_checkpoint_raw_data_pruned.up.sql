@@ -0,0 +1,5 @@
+-- Migration: 027_add_checkpoint_raw_data_pruned.up.sql
+-- Highest finalized height whose raw_data Prune has already cleared, so each prune
+-- resumes from there instead of rescanning for raw_data IS NOT NULL.
+
+ALTER TABLE checkpoints ADD COLUMN IF NOT EXISTS raw_data_pruned_height BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// Partitioned tables and the DDL that recreates each as a range-partitioned parent.
+// Postgres requires unique constraints on a partitioned table to include the
+// partition key, so block_height joins the primary key and the natural-key constraint.
+// Columns are listed in migrated-table order, since existing rows are copied with SELECT *.
+var partitionedTables = []struct {
+	name    string
+	create  string
+	indexes string
+}{
+	{
+		name: "transactions",
+		create: `
+			CREATE TABLE transactions (
+				id              BIGSERIAL,
+				chain_id        VARCHAR(16) NOT NULL,
+				block_height    BIGINT NOT NULL,
+				block_hash      VARCHAR(66) NOT NULL,
+				tx_hash         VARCHAR(66) NOT NULL,
+				tx_index        INT NOT NULL,
+				from_addr       VARCHAR(66),
+				to_addr         VARCHAR(66),
+				value           NUMERIC(78, 0),
+				fee             NUMERIC(78, 0),
+				gas_used        BIGINT,
+				status          VARCHAR(16) NOT NULL DEFAULT 'pending',
+				raw_data        TEXT,
+				created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				log_count       INT NOT NULL DEFAULT 0,
+				tx_status       VARCHAR(16)
+			) PARTITION BY RANGE (block_height)
+		`,
+		indexes: `
+			ALTER TABLE transactions ADD PRIMARY KEY (id, block_height);
+			ALTER TABLE transactions ADD CONSTRAINT transactions_chain_txhash_unique UNIQUE (chain_id, tx_hash, block_height);
+			CREATE INDEX IF NOT EXISTS idx_transactions_chain_block ON transactions(chain_id, block_height);
+			CREATE INDEX IF NOT EXISTS idx_transactions_from ON transactions(chain_id, from_addr) WHERE from_addr IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_transactions_to ON transactions(chain_id, to_addr) WHERE to_addr IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_transactions_hash ON transactions(chain_id, tx_hash);
+			CREATE INDEX IF NOT EXISTS idx_transactions_from_height ON transactions(chain_id, from_addr, block_height) WHERE from_addr IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_transactions_to_height ON transactions(chain_id, to_addr, block_height) WHERE to_addr IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_transactions_from_cursor ON transactions(chain_id, from_addr, block_height DESC, tx_index DESC) WHERE from_addr IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_transactions_to_cursor ON transactions(chain_id, to_addr, block_height DESC, tx_index DESC) WHERE to_addr IS NOT NULL;
+		`,
+	},
+	{
+		name: "events",
+		create: `
+			CREATE TABLE events (
+				id              BIGSERIAL,
+				chain_id        VARCHAR(16) NOT NULL DEFAULT 'eth',
+				block_height    BIGINT NOT NULL,
+				block_hash      VARCHAR(66) NOT NULL,
+				tx_hash         VARCHAR(66) NOT NULL,
+				log_index       INT NOT NULL,
+				contract_addr   VARCHAR(42) NOT NULL,
+				event_name      VARCHAR(128),
+				topic0          VARCHAR(66) NOT NULL,
+				topics          JSONB,
+				data            TEXT,
+				raw_data        TEXT,
+				status          VARCHAR(16) NOT NULL DEFAULT 'pending',
+				decode_failed   BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				tx_status       VARCHAR(16),
+				decode_oversized BOOLEAN NOT NULL DEFAULT FALSE
+			) PARTITION BY RANGE (block_height)
+		`,
+		indexes: `
+			ALTER TABLE events ADD PRIMARY KEY (id, block_height);
+			ALTER TABLE events ADD CONSTRAINT events_chain_tx_logindex_unique UNIQUE (chain_id, tx_hash, log_index, block_height);
+			CREATE INDEX IF NOT EXISTS idx_events_contract ON events(chain_id, contract_addr, block_height);
+			CREATE INDEX IF NOT EXISTS idx_events_block_height ON events(chain_id, block_height);
+			CREATE INDEX IF NOT EXISTS idx_events_topic0 ON events(chain_id, topic0);
+			CREATE INDEX IF NOT EXISTS idx_events_contract_latest ON events(chain_id, contract_addr, block_height DESC, log_index DESC);
+		`,
+	},
+}
+
+// SetPartitionSize enables height-range partitioning of the transactions and events
+// tables for a chain, with size blocks per partition. Must be called before Migrate,
+// which converts the chain's tables. The size cannot be changed once tables exist.
+func (s *Storage) SetPartitionSize(chainID types.ChainID, size uint64) {
+	if size == 0 {
+		return
+	}
+	s.partitionSizes[chainID] = size
+}
+
+// partitionTables converts a chain's transactions and events tables to range-partitioned
+// parents, copying any existing rows. Tables that are already partitioned are left alone.
+func (s *Storage) partitionTables(ctx context.Context, chainID types.ChainID) error {
+	size := s.partitionSizes[chainID]
+	db := s.conn(chainID)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning partition transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range partitionedTables {
+		var relkind string
+		if err := tx.QueryRowContext(ctx, `SELECT relkind FROM pg_class WHERE oid = $1::regclass`, t.name).Scan(&relkind); err != nil {
+			return fmt.Errorf("inspecting %s: %w", t.name, err)
+		}
+		if relkind == "p" {
+			continue
+		}
+
+		legacy := t.name + "_unpartitioned"
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE `+t.name+` RENAME TO `+legacy); err != nil {
+			return fmt.Errorf("renaming %s: %w", t.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, t.create); err != nil {
+			return fmt.Errorf("creating partitioned %s: %w", t.name, err)
+		}
+
+		var minHeight, maxHeight sql.NullInt64
+		if err := tx.QueryRowContext(ctx, `SELECT MIN(block_height), MAX(block_height) FROM `+legacy).Scan(&minHeight, &maxHeight); err != nil {
+			return fmt.Errorf("reading %s height range: %w", t.name, err)
+		}
+		if minHeight.Valid {
+			for _, start := range partitionStarts(uint64(minHeight.Int64), uint64(maxHeight.Int64), size) {
+				if err := createPartition(ctx, tx, t.name, start, size); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Indexes and constraints go on last: the legacy table still holds their names until dropped
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO `+t.name+` SELECT * FROM `+legacy+`;
+			SELECT setval(pg_get_serial_sequence('`+t.name+`', 'id'), COALESCE((SELECT MAX(id) FROM `+t.name+`), 0) + 1, false);
+			DROP TABLE `+legacy+`;
+		`)
+		if err != nil {
+			return fmt.Errorf("copying %s into partitions: %w", t.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, t.indexes); err != nil {
+			return fmt.Errorf("indexing partitioned %s: %w", t.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing partition conversion: %w", err)
+	}
+	return nil
+}
+
+// ensurePartitions creates the partitions holding heights first..last plus the one
+// after them, so writes always have somewhere to land. The created bound is cached
+// so the common case costs no round trip.
+func (s *Storage) ensurePartitions(ctx context.Context, chainID types.ChainID, first, last uint64) error {
+	size := s.partitionSizes[chainID]
+	if size == 0 {
+		return nil
+	}
+
+	s.partitionMu.Lock()
+	defer s.partitionMu.Unlock()
+
+	created, ok := s.partitionedTo[chainID]
+	if ok && last < created {
+		return nil
+	}
+	if ok && created > first {
+		first = created
+	}
+	to := (last/size + 1) * size // one partition of headroom past last
+
+	tx, err := s.conn(chainID).BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning partition transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	starts := partitionStarts(first, to, size)
+	for _, t := range partitionedTables {
+		for _, start := range starts {
+			if err := createPartition(ctx, tx, t.name, start, size); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing partitions: %w", err)
+	}
+	s.partitionedTo[chainID] = to
+	return nil
+}
+
+func createPartition(ctx context.Context, tx *sql.Tx, table string, start, size uint64) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%d) TO (%d)`,
+		partitionName(table, start), table, start, start+size,
+	))
+	if err != nil {
+		return fmt.Errorf("creating partition %s: %w", partitionName(table, start), err)
+	}
+	return nil
+}
+
+// partitionName is zero-padded so partitions list in height order
+func partitionName(table string, start uint64) string {
+	return fmt.Sprintf("%s_p%012d", table, start)
+}
+
+// partitionStarts returns the aligned start heights of every partition covering [from, to]
+func partitionStarts(from, to, size uint64) []uint64 {
+	var starts []uint64
+	for start := from / size * size; start <= to; start += size {
+		starts = append(starts, start)
+	}
+	return starts
+}
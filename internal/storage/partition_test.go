@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPartitionStarts(t *testing.T) {
+	tests := []struct {
+		from, to, size uint64
+		want           []uint64
+	}{
+		{0, 0, 100, []uint64{0}},
+		{150, 150, 100, []uint64{100}},
+		{150, 200, 100, []uint64{100, 200}},
+		{99, 301, 100, []uint64{0, 100, 200, 300}},
+	}
+
+	for _, tt := range tests {
+		got := partitionStarts(tt.from, tt.to, tt.size)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("partitionStarts(%d, %d, %d) = %v, want %v", tt.from, tt.to, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestPartitionName(t *testing.T) {
+	if got := partitionName("events", 1000000); got != "events_p000001000000" {
+		t.Errorf("unexpected partition name %s", got)
+	}
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// DefaultPruneBatchSize is the number of rows each prune statement touches
+const DefaultPruneBatchSize = 1000
+
+// PruneOptions selects what Prune removes; zero values keep everything
+type PruneOptions struct {
+	OrphanedMaxAge time.Duration    // Delete orphaned_blocks archived longer ago than this, at finalized heights
+	RawData        RawDataRetention // Finalized blocks (and their transactions) that keep raw_data ("" = RetainRawAll)
+	RawDataBlocks  uint64           // Apply RawData only to blocks more than this far below the finalized height
+	BatchSize      int              // Rows deleted, or block heights cleared, per statement (0 = DefaultPruneBatchSize)
+}
+
+// PruneResult counts the rows a prune removed or cleared
+type PruneResult struct {
+	OrphanedDeleted   int64
+	RawDataCleared    int64 // Blocks
+	TxRawDataCleared  int64
+	RawDataPrunedUpTo uint64 // Height the raw_data cursor reached (0 if RawData keeps everything)
+}
+
+// Prune deletes old orphaned_blocks rows and clears raw_data on old finalized blocks
+// and transactions per opts.RawData, keeping their normalized columns. Orphans are
+// deleted BatchSize rows at a time; raw_data is cleared BatchSize heights at a time
+// from the checkpoint's raw_data_pruned_height, which each range advances in the
+// same transaction, so no prune rescans heights already cleared. Every statement
+// commits on its own, so a large backlog never holds locks for long.
+func (s *Storage) Prune(ctx context.Context, chainID types.ChainID, opts PruneOptions) (PruneResult, error) {
+	var res PruneResult
+	if opts.OrphanedMaxAge <= 0 && !opts.RawData.prunesRawData() {
+		return res, nil
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultPruneBatchSize
+	}
+
+	var finalized sql.NullInt64
+	err := s.conn(chainID).QueryRowContext(ctx, `
+		SELECT MAX(height) FROM blocks WHERE chain_id = $1 AND status = 'finalized'
+	`, string(chainID)).Scan(&finalized)
+	if err != nil {
+		return res, fmt.Errorf("getting finalized height: %w", err)
+	}
+	if !finalized.Valid {
+		return res, nil // Nothing finalized yet
+	}
+	height := uint64(finalized.Int64)
+
+	if opts.OrphanedMaxAge > 0 {
+		cutoff := time.Now().Add(-opts.OrphanedMaxAge)
+		res.OrphanedDeleted, err = s.pruneBatches(ctx, chainID, opts.BatchSize, `
+			DELETE FROM orphaned_blocks WHERE id IN (
+				SELECT id FROM orphaned_blocks
+				WHERE chain_id = $1 AND orphaned_at < $2 AND height <= $3
+				LIMIT $4
+			)
+		`, string(chainID), cutoff, height)
+		if err != nil {
+			return res, fmt.Errorf("pruning orphaned blocks: %w", err)
+		}
+	}
+
+	if opts.RawData.prunesRawData() && height > opts.RawDataBlocks {
+		if err := s.pruneRawData(ctx, chainID, opts, height-opts.RawDataBlocks, &res); err != nil {
+			return res, fmt.Errorf("pruning raw data: %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+// pruneRawData clears raw_data on finalized blocks and transactions from the
+// chain's raw_data_pruned_height up to target, one height range per transaction
+func (s *Storage) pruneRawData(ctx context.Context, chainID types.ChainID, opts PruneOptions, target uint64, res *PruneResult) error {
+	var cursor uint64
+	err := s.conn(chainID).QueryRowContext(ctx, `
+		SELECT raw_data_pruned_height FROM checkpoints WHERE chain_id = $1
+	`, string(chainID)).Scan(&cursor)
+	if err != nil {
+		return fmt.Errorf("getting raw_data cursor: %w", err)
+	}
+	res.RawDataPrunedUpTo = cursor
+
+	blocksQuery := rawDataPruneQuery(opts.RawData, "blocks", "height", "hash")
+	txsQuery := rawDataPruneQuery(opts.RawData, "transactions", "block_height", "block_hash")
+
+	for from := cursor + 1; from <= target; from += uint64(opts.BatchSize) {
+		to := min(from+uint64(opts.BatchSize)-1, target)
+		blocks, txs, err := s.pruneRawDataRange(ctx, chainID, blocksQuery, txsQuery, from, to)
+		if err != nil {
+			return err
+		}
+		res.RawDataCleared += blocks
+		res.TxRawDataCleared += txs
+		res.RawDataPrunedUpTo = to
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneRawDataRange clears raw_data between heights from and to and moves the
+// cursor to to, atomically, returning the block and transaction rows cleared
+func (s *Storage) pruneRawDataRange(ctx context.Context, chainID types.ChainID, blocksQuery, txsQuery string, from, to uint64) (int64, int64, error) {
+	tx, err := s.conn(chainID).BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	cleared := make([]int64, 2)
+	for i, query := range []string{blocksQuery, txsQuery} {
+		result, err := tx.ExecContext(ctx, query, string(chainID), from, to)
+		if err != nil {
+			return 0, 0, fmt.Errorf("clearing heights %d-%d: %w", from, to, err)
+		}
+		if cleared[i], err = result.RowsAffected(); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE checkpoints SET raw_data_pruned_height = $2 WHERE chain_id = $1
+	`, string(chainID), to); err != nil {
+		return 0, 0, fmt.Errorf("advancing raw_data cursor: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("committing transaction: %w", err)
+	}
+	return cleared[0], cleared[1], nil
+}
+
+// pruneBatches runs query, whose last placeholder is the batch LIMIT, until it
+// touches fewer than batchSize rows, and returns the total rows affected
+func (s *Storage) pruneBatches(ctx context.Context, chainID types.ChainID, batchSize int, query string, args ...interface{}) (int64, error) {
+	args = append(args, batchSize)
+	var total int64
+	for {
+		result, err := s.conn(chainID).ExecContext(ctx, query, args...)
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < int64(batchSize) {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
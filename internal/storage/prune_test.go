@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/internal/indexer/pkg/types"
+)
+
+func TestPrune_Batches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT MAX\(height\) FROM blocks`).WithArgs("eth").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(1000))
+
+	// Full batches are followed by another until one comes back short
+	mock.ExpectExec(`DELETE FROM orphaned_blocks .* LIMIT \$4`).
+		WithArgs("eth", sqlmock.AnyArg(), uint64(1000), 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM orphaned_blocks`).
+		WithArgs("eth", sqlmock.AnyArg(), uint64(1000), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	res, err := New(db).Prune(context.Background(), types.ChainETH, PruneOptions{
+		OrphanedMaxAge: 24 * time.Hour,
+		BatchSize:      2,
+	})
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if res.OrphanedDeleted != 3 || res.RawDataCleared != 0 {
+		t.Errorf("expected 3 orphans deleted and no raw data cleared, got %+v", res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPrune_RawDataCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT MAX\(height\) FROM blocks`).WithArgs("eth").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(1000))
+	mock.ExpectQuery(`SELECT raw_data_pruned_height FROM checkpoints`).WithArgs("eth").
+		WillReturnRows(sqlmock.NewRows([]string{"raw_data_pruned_height"}).AddRow(895))
+
+	// raw_data is kept on the latest 100 finalized blocks: heights 896-900 are
+	// cleared two at a time, each range moving the cursor in the same transaction
+	for _, r := range [][2]uint64{{896, 897}, {898, 899}, {900, 900}} {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE blocks t SET raw_data = NULL .* BETWEEN \$2 AND \$3 .* NOT EXISTS`).
+			WithArgs("eth", r[0], r[1]).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE transactions t SET raw_data = NULL .* BETWEEN \$2 AND \$3 .* NOT EXISTS`).
+			WithArgs("eth", r[0], r[1]).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(`UPDATE checkpoints SET raw_data_pruned_height = \$2`).
+			WithArgs("eth", r[1]).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	res, err := New(db).Prune(context.Background(), types.ChainETH, PruneOptions{
+		RawData:       RetainRawEvents,
+		RawDataBlocks: 100,
+		BatchSize:     2,
+	})
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if res.RawDataCleared != 3 || res.TxRawDataCleared != 9 || res.RawDataPrunedUpTo != 900 {
+		t.Errorf("expected 3 blocks and 9 txs cleared up to 900, got %+v", res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPrune_NothingFinalized(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT MAX\(height\) FROM blocks`).WithArgs("btc").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+
+	res, err := New(db).Prune(context.Background(), types.ChainBTC, PruneOptions{OrphanedMaxAge: time.Hour, RawData: RetainRawPending})
+	if err != nil || res != (PruneResult{}) {
+		t.Fatalf("expected nothing pruned, got %+v, %v", res, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// GetPublishCheckpoint returns the chain's broker delivery progress, or nil when
+// publishing has never run for the chain
+func (s *Storage) GetPublishCheckpoint(ctx context.Context, chainID types.ChainID) (*types.PublishCheckpoint, error) {
+	var cp types.PublishCheckpoint
+	err := s.conn(chainID).QueryRowContext(ctx, `
+		SELECT published_up_to, generation FROM publish_checkpoints WHERE chain_id = $1
+	`, string(chainID)).Scan(&cp.Height, &cp.Generation)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying publish checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// InitPublishedHeight creates the publish checkpoint at height if it does not exist
+func (s *Storage) InitPublishedHeight(ctx context.Context, chainID types.ChainID, height uint64) error {
+	_, err := s.conn(chainID).ExecContext(ctx, `
+		INSERT INTO publish_checkpoints (chain_id, published_up_to, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (chain_id) DO NOTHING
+	`, string(chainID), height, time.Now())
+	if err != nil {
+		return fmt.Errorf("initializing publish checkpoint: %w", err)
+	}
+	return nil
+}
+
+// AdvancePublishedHeight moves the publish checkpoint from `from` to height `to`. It
+// reports false without error if the checkpoint is no longer at `from` (a rollback
+// ran while the batch was in flight, so it may hold orphaned blocks), so the range
+// is read again and the replacement blocks are published next.
+func (s *Storage) AdvancePublishedHeight(ctx context.Context, chainID types.ChainID, from types.PublishCheckpoint, to uint64) (bool, error) {
+	res, err := s.conn(chainID).ExecContext(ctx, `
+		UPDATE publish_checkpoints SET published_up_to = $4, updated_at = $5
+		WHERE chain_id = $1 AND published_up_to = $2 AND generation = $3
+	`, string(chainID), from.Height, from.Generation, to, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("advancing publish checkpoint: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("advancing publish checkpoint: %w", err)
+	}
+	return n == 1, nil
+}
+
+// GetCommittedRange loads the canonical blocks, transactions, and events in [fromHeight, toHeight]
+func (s *Storage) GetCommittedRange(ctx context.Context, chainID types.ChainID, fromHeight, toHeight uint64) ([]types.Block, []types.Transaction, []types.Event, error) {
+	db := s.conn(chainID)
+
+	blockRows, err := db.QueryContext(ctx, `
+		SELECT chain_id, height, hash, parent_hash, timestamp, status, COALESCE(miner, ''), tx_count, raw_data,
+		       COALESCE(size, 0), COALESCE(stripped_size, 0), COALESCE(weight, 0)
+		FROM blocks
+		WHERE chain_id = $1 AND height BETWEEN $2 AND $3 AND status != 'orphaned'
+		ORDER BY height
+	`, string(chainID), fromHeight, toHeight)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("querying blocks: %w", err)
+	}
+	defer blockRows.Close()
+
+	var blocks []types.Block
+	for blockRows.Next() {
+		var b types.Block
+		var rawData sql.NullString
+		if err := blockRows.Scan(&b.ChainID, &b.Height, &b.Hash, &b.ParentHash, &b.Timestamp, &b.Status, &b.Miner, &b.TxCount, &rawData, &b.Size, &b.StrippedSize, &b.Weight); err != nil {
+			return nil, nil, nil, fmt.Errorf("scanning block: %w", err)
+		}
+		b.RawData = []byte(rawData.String)
+		blocks = append(blocks, b)
+	}
+	if err := blockRows.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("iterating blocks: %w", err)
+	}
+
+	txRows, err := db.QueryContext(ctx, `
+		SELECT chain_id, block_height, block_hash, tx_hash, tx_index,
+			COALESCE(from_addr, ''), COALESCE(to_addr, ''), COALESCE(value::text, ''), COALESCE(fee::text, ''),
+			COALESCE(gas_used, 0), log_count, COALESCE(tx_status, ''), status, raw_data
+		FROM transactions
+		WHERE chain_id = $1 AND block_height BETWEEN $2 AND $3 AND status != 'orphaned'
+		ORDER BY block_height, tx_index
+	`, string(chainID), fromHeight, toHeight)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("querying transactions: %w", err)
+	}
+	defer txRows.Close()
+
+	var txs []types.Transaction
+	for txRows.Next() {
+		var t types.Transaction
+		var rawData sql.NullString
+		if err := txRows.Scan(&t.ChainID, &t.BlockHeight, &t.BlockHash, &t.TxHash, &t.TxIndex,
+			&t.FromAddr, &t.ToAddr, &t.Value, &t.Fee, &t.GasUsed, &t.LogCount, &t.TxStatus, &t.Status, &rawData); err != nil {
+			return nil, nil, nil, fmt.Errorf("scanning transaction: %w", err)
+		}
+		t.RawData = []byte(rawData.String)
+		txs = append(txs, t)
+	}
+	if err := txRows.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("iterating transactions: %w", err)
+	}
+
+	eventRows, err := db.QueryContext(ctx, `
+		SELECT chain_id, block_height, block_hash, tx_hash, log_index, contract_addr,
+			COALESCE(event_name, ''), topic0, topics, data, raw_data, status, decode_failed
+		FROM events
+		WHERE chain_id = $1 AND block_height BETWEEN $2 AND $3 AND status != 'orphaned'
+		ORDER BY block_height, log_index
+	`, string(chainID), fromHeight, toHeight)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("querying events: %w", err)
+	}
+	defer eventRows.Close()
+
+	var events []types.Event
+	for eventRows.Next() {
+		var e types.Event
+		var topics []byte
+		var data, rawData sql.NullString
+		if err := eventRows.Scan(&e.ChainID, &e.BlockHeight, &e.BlockHash, &e.TxHash, &e.LogIndex, &e.ContractAddr,
+			&e.EventName, &e.Topic0, &topics, &data, &rawData, &e.Status, &e.DecodeFailed); err != nil {
+			return nil, nil, nil, fmt.Errorf("scanning event: %w", err)
+		}
+		if len(topics) > 0 {
+			if err := json.Unmarshal(topics, &e.Topics); err != nil {
+				return nil, nil, nil, fmt.Errorf("decoding event topics: %w", err)
+			}
+		}
+		e.Data = []byte(data.String)
+		e.RawData = []byte(rawData.String)
+		events = append(events, e)
+	}
+	if err := eventRows.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("iterating events: %w", err)
+	}
+
+	return blocks, txs, events, nil
+}
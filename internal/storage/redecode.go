@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// redecodeBatch bounds how many failed events are loaded and rewritten per transaction
+const redecodeBatch = 500
+
+// RedecodeFailedEvents re-runs decode over the stored raw_data of a contract's
+// decode_failed events and updates event_name, data and decode_failed in place,
+// so a newly added or fixed ABI applies to history without refetching it from
+// the chain. Events that still fail to decode are left as they are; oversized
+// events, which decoded but were too large to store, are not retried.
+// Returns the number of events decoded and the number still failing.
+func (s *Storage) RedecodeFailedEvents(ctx context.Context, chainID types.ChainID, contractAddr string, decode func(rawData []byte) (string, []byte, error)) (decoded, failed int64, err error) {
+	contractAddr = types.NormalizeAddress(chainID, contractAddr)
+
+	// Keyset over (block_height, id); rows that stay failed are skipped by the cursor
+	var lastHeight, lastID int64 = -1, 0
+	for {
+		n, height, id, d, f, err := s.redecodeEvents(ctx, chainID, contractAddr, lastHeight, lastID, decode)
+		decoded += d
+		failed += f
+		if err != nil {
+			return decoded, failed, err
+		}
+		if n < redecodeBatch {
+			return decoded, failed, nil
+		}
+		lastHeight, lastID = height, id
+	}
+}
+
+// redecodeEvents re-decodes one batch after (afterHeight, afterID) in a single
+// transaction, returning the rows read and the cursor of the last one
+func (s *Storage) redecodeEvents(ctx context.Context, chainID types.ChainID, contractAddr string, afterHeight, afterID int64, decode func([]byte) (string, []byte, error)) (n int, lastHeight, lastID, decoded, failed int64, err error) {
+	tx, err := s.conn(chainID).BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, 0, 0, 0, fmt.Errorf("beginning tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, block_height, COALESCE(raw_data, '')
+		FROM events
+		WHERE chain_id = $1 AND contract_addr = $2 AND decode_failed AND NOT decode_oversized
+		  AND (block_height, id) > ($3, $4)
+		ORDER BY block_height, id
+		LIMIT $5
+		FOR UPDATE
+	`, string(chainID), contractAddr, afterHeight, afterID, redecodeBatch)
+	if err != nil {
+		return 0, 0, 0, 0, 0, fmt.Errorf("querying failed events: %w", err)
+	}
+
+	type failedEvent struct {
+		id, height int64
+		raw        string
+	}
+	var batch []failedEvent
+	for rows.Next() {
+		var e failedEvent
+		if err := rows.Scan(&e.id, &e.height, &e.raw); err != nil {
+			rows.Close()
+			return 0, 0, 0, 0, 0, fmt.Errorf("scanning failed event: %w", err)
+		}
+		batch = append(batch, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, 0, 0, 0, fmt.Errorf("iterating failed events: %w", err)
+	}
+	if len(batch) == 0 {
+		return 0, 0, 0, 0, 0, nil
+	}
+
+	for _, e := range batch {
+		name, data, err := decode([]byte(e.raw))
+		if err != nil {
+			failed++
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE events SET event_name = $1, data = $2, decode_failed = FALSE
+			WHERE id = $3 AND block_height = $4
+		`, name, string(data), e.id, e.height); err != nil {
+			return 0, 0, 0, 0, 0, fmt.Errorf("updating event %d: %w", e.id, err)
+		}
+		decoded++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, 0, 0, 0, fmt.Errorf("committing redecode: %w", err)
+	}
+	last := batch[len(batch)-1]
+	return len(batch), last.height, last.id, decoded, failed, nil
+}
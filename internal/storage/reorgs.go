@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// RollbackReorg is Rollback to reorg.RollbackHeight that also records reorg in
+// the reorgs table, in the same transaction, so the history only ever lists
+// reorgs that were actually rolled back.
+func (s *Storage) RollbackReorg(ctx context.Context, chainID types.ChainID, toHash string, reorg types.Reorg) error {
+	return s.rollback(ctx, chainID, reorg.RollbackHeight, toHash, &reorg)
+}
+
+// insertReorg writes one reorgs row inside the rollback transaction
+func insertReorg(ctx context.Context, tx *sql.Tx, chainID types.ChainID, reorg *types.Reorg) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO reorgs (chain_id, detected_at, rollback_height, depth, old_hash, new_hash)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, string(chainID), reorg.DetectedAt, reorg.RollbackHeight, reorg.Depth, reorg.OldHash, reorg.NewHash)
+	if err != nil {
+		return fmt.Errorf("recording reorg: %w", err)
+	}
+	return nil
+}
+
+// GetReorgs returns a chain's most recent recorded reorgs, newest first
+func (s *Storage) GetReorgs(ctx context.Context, chainID types.ChainID, limit int) ([]types.Reorg, error) {
+	rows, err := s.conn(chainID).QueryContext(ctx, `
+		SELECT id, chain_id, detected_at, rollback_height, depth, old_hash, new_hash
+		FROM reorgs
+		WHERE chain_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, string(chainID), limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying reorgs: %w", err)
+	}
+	defer rows.Close()
+
+	reorgs := []types.Reorg{}
+	for rows.Next() {
+		var r types.Reorg
+		if err := rows.Scan(&r.ID, &r.ChainID, &r.DetectedAt, &r.RollbackHeight, &r.Depth, &r.OldHash, &r.NewHash); err != nil {
+			return nil, fmt.Errorf("scanning reorg: %w", err)
+		}
+		reorgs = append(reorgs, r)
+	}
+	return reorgs, rows.Err()
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+	"github.com/lib/pq"
+)
+
+// writtenBlocks returns the hashes of the batch's blocks that are already stored,
+// e.g. when a batch is polled again after its write committed but the indexer
+// stopped before moving on. Rolled back blocks are deleted, so a stored hash
+// means the block and everything written with it are in place.
+func writtenBlocks(ctx context.Context, tx *sql.Tx, chainID types.ChainID, blocks []types.Block) (map[string]bool, error) {
+	hashes := make([]string, len(blocks))
+	for i, b := range blocks {
+		hashes[i] = b.Hash
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT hash FROM blocks
+		WHERE chain_id = $1 AND height BETWEEN $2 AND $3 AND hash = ANY($4)
+	`, string(chainID), blocks[0].Height, blocks[len(blocks)-1].Height, pq.Array(hashes))
+	if err != nil {
+		return nil, fmt.Errorf("checking for written blocks: %w", err)
+	}
+	defer rows.Close()
+
+	written := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("scanning written block: %w", err)
+		}
+		written[hash] = true
+	}
+	return written, rows.Err()
+}
+
+// commitReplayCheckpoint finishes a batch whose blocks were all stored already.
+// The crash may have come after the blocks committed but before the checkpoint
+// moved past them, so the checkpoint is advanced to the batch's last block,
+// never moved back.
+func commitReplayCheckpoint(ctx context.Context, tx *sql.Tx, chainID types.ChainID, last types.Block) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO checkpoints (chain_id, last_height, last_hash, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chain_id) DO UPDATE SET
+			last_height = EXCLUDED.last_height,
+			last_hash = EXCLUDED.last_hash,
+			updated_at = EXCLUDED.updated_at
+		WHERE checkpoints.last_height < EXCLUDED.last_height
+	`, string(chainID), last.Height, last.Hash, time.Now()); err != nil {
+		return fmt.Errorf("updating checkpoint: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// unwritten drops the items of written blocks, so replaying a batch neither
+// violates unique constraints nor applies its balance and stats deltas twice
+func unwritten[T any](items []T, written map[string]bool, blockHash func(T) string) []T {
+	if len(written) == 0 {
+		return items
+	}
+	kept := make([]T, 0, len(items))
+	for _, item := range items {
+		if !written[blockHash(item)] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func blockHash(b types.Block) string                 { return b.Hash }
+func txBlockHash(t types.Transaction) string         { return t.BlockHash }
+func eventBlockHash(e types.Event) string            { return e.BlockHash }
+func transferBlockHash(t types.TokenTransfer) string { return t.BlockHash }
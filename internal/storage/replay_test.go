@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/internal/indexer/pkg/types"
+	"github.com/lib/pq"
+)
+
+func TestUnwritten(t *testing.T) {
+	txs := []types.Transaction{
+		{TxHash: "tx1", BlockHash: "hash1"},
+		{TxHash: "tx2", BlockHash: "hash2"},
+		{TxHash: "tx3", BlockHash: "hash2"},
+	}
+
+	kept := unwritten(txs, map[string]bool{"hash2": true}, txBlockHash)
+	if len(kept) != 1 || kept[0].TxHash != "tx1" {
+		t.Errorf("expected only tx1 kept, got %+v", kept)
+	}
+	if kept := unwritten(txs, nil, txBlockHash); len(kept) != 3 {
+		t.Errorf("expected all txs kept with nothing written, got %d", len(kept))
+	}
+}
+
+func TestWriteBlocks_ReplaySkipsWrittenBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	blocks := []types.Block{
+		{ChainID: types.ChainBTC, Height: 1, Hash: "hash1", ParentHash: "hash0", Timestamp: time.Now()},
+		{ChainID: types.ChainBTC, Height: 2, Hash: "hash2", ParentHash: "hash1", Timestamp: time.Now()},
+	}
+	txs := []types.Transaction{{ChainID: types.ChainBTC, BlockHeight: 1, BlockHash: "hash1", TxHash: "tx1"}}
+
+	// Both blocks are stored: nothing is inserted, but the checkpoint is still
+	// advanced to the last block in case the crash came before it moved
+	expectReplay := func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT hash FROM blocks`).
+			WithArgs("btc", int64(1), int64(2), pq.Array([]string{"hash1", "hash2"})).
+			WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("hash1").AddRow("hash2"))
+		mock.ExpectExec(`INSERT INTO checkpoints .* WHERE checkpoints.last_height < EXCLUDED.last_height`).
+			WithArgs("btc", int64(2), "hash2", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	store := New(db)
+	expectReplay()
+	if err := store.WriteBlocks(context.Background(), types.ChainBTC, blocks, txs); err != nil {
+		t.Fatalf("WriteBlocks: %v", err)
+	}
+	expectReplay()
+	if err := store.WriteBlocksWithEvents(context.Background(), types.ChainBTC, blocks, txs, nil, nil, nil, nil); err != nil {
+		t.Fatalf("WriteBlocksWithEvents: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
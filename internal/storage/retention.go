@@ -0,0 +1,38 @@
+package storage
+
+// RawDataRetention decides which finalized blocks keep raw_data when Prune runs.
+// Pending blocks always keep it: reorg archival copies it into orphaned_blocks.
+type RawDataRetention string
+
+const (
+	// RetainRawAll keeps raw_data for every block (default)
+	RetainRawAll RawDataRetention = "all"
+	// RetainRawPending clears raw_data once a block is finalized
+	RetainRawPending RawDataRetention = "pending"
+	// RetainRawEvents keeps raw_data only for finalized blocks with monitored-contract events
+	RetainRawEvents RawDataRetention = "events"
+)
+
+// prunesRawData reports whether r clears raw_data on any finalized block
+func (r RawDataRetention) prunesRawData() bool {
+	return r == RetainRawPending || r == RetainRawEvents
+}
+
+// rawDataPruneQuery returns the UPDATE that clears raw_data per r on the finalized
+// rows of table between heights $2 and $3. A transaction keeps its raw_data exactly
+// when its block does.
+func rawDataPruneQuery(r RawDataRetention, table, heightColumn, hashColumn string) string {
+	query := `
+		UPDATE ` + table + ` t SET raw_data = NULL
+		WHERE t.chain_id = $1 AND t.` + heightColumn + ` BETWEEN $2 AND $3
+			AND t.status = 'finalized' AND t.raw_data IS NOT NULL`
+	if r == RetainRawEvents {
+		query += `
+			AND NOT EXISTS (
+				SELECT 1 FROM events e
+				WHERE e.chain_id = t.chain_id AND e.block_height = t.` + heightColumn + `
+					AND e.block_hash = t.` + hashColumn + ` AND e.status != 'orphaned'
+			)`
+	}
+	return query
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/internal/indexer/pkg/types"
+	"github.com/lib/pq"
+)
+
+func TestAddressStatsRows(t *testing.T) {
+	rows := addressStatsRows(map[string]*types.AddressStatsDiff{
+		"0xb": {BalanceDelta: big.NewInt(100), TotalReceived: big.NewInt(100), TxCount: 2, LastSeenHeight: 7},
+		"0xa": {BalanceDelta: big.NewInt(-110), TotalSent: big.NewInt(100), TxCount: 1, LastSeenHeight: 5},
+	})
+
+	want := statsRows{
+		addresses: []string{"0xa", "0xb"},
+		balances:  []string{"-110", "100"},
+		received:  []string{"0", "100"},
+		sent:      []string{"100", "0"},
+		txCounts:  []int64{1, 2},
+		lastSeen:  []int64{5, 7},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("unexpected rows\n got: %+v\nwant: %+v", rows, want)
+	}
+}
+
+func TestTokenBalanceRows(t *testing.T) {
+	addrs, tokens, deltas := tokenBalanceRows(map[string]map[string]*big.Int{
+		"0xb": {"0xt2": big.NewInt(5), "0xt1": big.NewInt(-5)},
+		"0xa": {"0xt1": big.NewInt(0)},
+	})
+
+	if !reflect.DeepEqual(addrs, []string{"0xa", "0xb", "0xb"}) ||
+		!reflect.DeepEqual(tokens, []string{"0xt1", "0xt1", "0xt2"}) ||
+		!reflect.DeepEqual(deltas, []string{"0", "-5", "5"}) {
+		t.Errorf("unexpected rows: %v %v %v", addrs, tokens, deltas)
+	}
+}
+
+func TestUpdateStatsSingleStatement(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO address_stats .* FROM unnest`).
+		WithArgs("eth", pq.Array([]string{"0xa", "0xb"}), pq.Array([]string{"-3", "3"}), pq.Array([]string{"0", "3"}),
+			pq.Array([]string{"3", "0"}), pq.Array([]int64{1, 1}), pq.Array([]int64{9, 9})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO token_balances .* FROM unnest`).
+		WithArgs("eth", pq.Array([]string{"0xa", "0xb"}), pq.Array([]string{"0xt", "0xt"}), pq.Array([]string{"-3", "3"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	s := New(db)
+	err = s.updateAddressStats(ctx, tx, types.ChainETH, map[string]*types.AddressStatsDiff{
+		"0xa": {BalanceDelta: big.NewInt(-3), TotalSent: big.NewInt(3), TxCount: 1, LastSeenHeight: 9},
+		"0xb": {BalanceDelta: big.NewInt(3), TotalReceived: big.NewInt(3), TxCount: 1, LastSeenHeight: 9},
+	})
+	if err != nil {
+		t.Fatalf("updateAddressStats: %v", err)
+	}
+	err = s.updateTokenBalances(ctx, tx, types.ChainETH, map[string]map[string]*big.Int{
+		"0xa": {"0xt": big.NewInt(-3)},
+		"0xb": {"0xt": big.NewInt(3)},
+	})
+	if err != nil {
+		t.Fatalf("updateTokenBalances: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
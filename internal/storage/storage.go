@@ -9,6 +9,7 @@ import (
 	"math/big"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/internal/indexer/pkg/types"
@@ -21,36 +22,196 @@ var migrationsFS embed.FS
 // Storage handles all database operations for the indexer
 type Storage struct {
 	db *sql.DB
+
+	// Optional per-chain connections whose search_path points at a dedicated schema
+	chainDBs     map[types.ChainID]*sql.DB
+	chainSchemas map[types.ChainID]string
+
+	// Chains whose writes are checked for height/parent-hash continuity
+	strictChains map[types.ChainID]bool
+
+	// Chains whose rollbacks are refused below the finalized height
+	finalityGuards map[types.ChainID]bool
+
+	// Chains whose transactions.log_count is populated on write
+	logCountChains map[types.ChainID]bool
+
+	// Chains whose block uncle references are written to the uncles table
+	uncleChains map[types.ChainID]bool
+
+	// Per-chain blocks per transactions/events partition (absent = unpartitioned),
+	// and the height below which partitions are known to exist
+	partitionSizes map[types.ChainID]uint64
+	partitionMu    sync.Mutex
+	partitionedTo  map[types.ChainID]uint64
+
+	// Chains whose aggregate writes are cross-checked in SQL (see SetWriteVerification)
+	verifiers map[types.ChainID]*writeVerifier
 }
 
 // New creates a new Storage instance
 func New(db *sql.DB) *Storage {
-	return &Storage{db: db}
+	return &Storage{
+		db:           db,
+		chainDBs:     make(map[types.ChainID]*sql.DB),
+		chainSchemas: make(map[types.ChainID]string),
+		strictChains: make(map[types.ChainID]bool),
+
+		finalityGuards: make(map[types.ChainID]bool),
+		logCountChains: make(map[types.ChainID]bool),
+		uncleChains:    make(map[types.ChainID]bool),
+
+		partitionSizes: make(map[types.ChainID]uint64),
+		partitionedTo:  make(map[types.ChainID]uint64),
+
+		verifiers: make(map[types.ChainID]*writeVerifier),
+	}
+}
+
+// RegisterChainDB routes all queries for a chain to db, which must be opened with
+// search_path set to schema (see config.DatabaseConfig.DSNWithSchema).
+// Must be called before Migrate so the schema is created and migrated.
+func (s *Storage) RegisterChainDB(chainID types.ChainID, schema string, db *sql.DB) {
+	s.chainDBs[chainID] = db
+	s.chainSchemas[chainID] = schema
+}
+
+// conn returns the connection for a chain, falling back to the shared schema
+func (s *Storage) conn(chainID types.ChainID) *sql.DB {
+	if db, ok := s.chainDBs[chainID]; ok {
+		return db
+	}
+	return s.db
 }
 
-// Migrate runs all pending migrations
+// Migrate runs all pending migrations on the shared schema and every per-chain schema
 func (s *Storage) Migrate(ctx context.Context) error {
+	// Shared schema first so extensions (pg_trgm) land in public, visible to all chains
+	if err := migrate(ctx, s.db); err != nil {
+		return err
+	}
+
+	for chainID, db := range s.chainDBs {
+		schema := s.chainSchemas[chainID]
+		if _, err := s.db.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS `+pq.QuoteIdentifier(schema)); err != nil {
+			return fmt.Errorf("creating schema %s: %w", schema, err)
+		}
+		if err := migrate(ctx, db); err != nil {
+			return fmt.Errorf("migrating schema %s: %w", schema, err)
+		}
+	}
+
+	for chainID := range s.partitionSizes {
+		if err := s.partitionTables(ctx, chainID); err != nil {
+			return fmt.Errorf("partitioning %s tables: %w", chainID, err)
+		}
+	}
+
+	return nil
+}
+
+// MigrateDown rolls back every applied migration above toVersion, newest first,
+// on each per-chain schema and then the shared schema (0 undoes them all). Each
+// down migration runs in its own transaction with the removal of its
+// schema_migrations row. Fails before changing anything unless every up
+// migration has a .down.sql sibling, so a rollback is never partial.
+func (s *Storage) MigrateDown(ctx context.Context, toVersion int) error {
+	if toVersion < 0 {
+		return fmt.Errorf("invalid migration version %d", toVersion)
+	}
+	if _, _, err := migrationFiles(); err != nil {
+		return err
+	}
+
+	for chainID, db := range s.chainDBs {
+		if err := migrateDown(ctx, db, toVersion); err != nil {
+			return fmt.Errorf("rolling back schema %s: %w", s.chainSchemas[chainID], err)
+		}
+	}
+	return migrateDown(ctx, s.db, toVersion)
+}
+
+// migrationLockID is the advisory lock held while migrating in either direction
+const migrationLockID = 7777777
+
+// lockMigrations acquires the migration advisory lock and makes sure the
+// schema_migrations table exists. The returned func releases the lock.
+func lockMigrations(ctx context.Context, db *sql.DB) (func(), error) {
 	// Acquire advisory lock to prevent concurrent migrations
-	const lockID = 7777777
-	if _, err := s.db.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
-		return fmt.Errorf("acquiring migration lock: %w", err)
+	if _, err := db.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, fmt.Errorf("acquiring migration lock: %w", err)
 	}
-	defer s.db.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockID)
+	unlock := func() { db.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID) }
 
 	// Create migrations table if not exists
-	_, err := s.db.ExecContext(ctx, `
+	_, err := db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version INT PRIMARY KEY,
 			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 		)
 	`)
 	if err != nil {
-		return fmt.Errorf("creating migrations table: %w", err)
+		unlock()
+		return nil, fmt.Errorf("creating migrations table: %w", err)
+	}
+	return unlock, nil
+}
+
+// migrationFiles returns the embedded migration file names by version, for each
+// direction. A file name starts with its zero-padded version (e.g.
+// 001_initial_schema.up.sql). Fails listing the up migrations without a down file.
+func migrationFiles() (up, down map[int]string, err error) {
+	entries, err := migrationsFS.ReadDir("migrations")
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading migrations directory: %w", err)
+	}
+
+	up = make(map[int]string)
+	down = make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		var version int
+		var rest string
+		n, _ := fmt.Sscanf(entry.Name(), "%03d_%s", &version, &rest)
+		if n < 1 {
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(entry.Name(), ".up.sql"):
+			up[version] = entry.Name()
+		case strings.HasSuffix(entry.Name(), ".down.sql"):
+			down[version] = entry.Name()
+		}
+	}
+
+	var missing []string
+	for version, name := range up {
+		if _, ok := down[version]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, nil, fmt.Errorf("migrations without a .down.sql file: %s", strings.Join(missing, ", "))
+	}
+	return up, down, nil
+}
+
+// migrate applies pending migrations to the first schema in db's search_path
+func migrate(ctx context.Context, db *sql.DB) error {
+	unlock, err := lockMigrations(ctx, db)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
 	// Get current version
 	var currentVersion int
-	err = s.db.QueryRowContext(ctx, `
+	err = db.QueryRowContext(ctx, `
 		SELECT COALESCE(MAX(version), 0) FROM schema_migrations
 	`).Scan(&currentVersion)
 	if err != nil {
@@ -96,7 +257,7 @@ func (s *Storage) Migrate(ctx context.Context) error {
 			return fmt.Errorf("reading migration %s: %w", entry.Name(), err)
 		}
 
-		tx, err := s.db.BeginTx(ctx, nil)
+		tx, err := db.BeginTx(ctx, nil)
 		if err != nil {
 			return fmt.Errorf("beginning transaction for migration %d: %w", version, err)
 		}
@@ -122,10 +283,77 @@ func (s *Storage) Migrate(ctx context.Context) error {
 	return nil
 }
 
+// migrateDown undoes the migrations applied to the first schema in db's
+// search_path above toVersion, newest first
+func migrateDown(ctx context.Context, db *sql.DB, toVersion int) error {
+	unlock, err := lockMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, down, err := migrationFiles()
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT version FROM schema_migrations WHERE version > $1 ORDER BY version DESC
+	`, toVersion)
+	if err != nil {
+		return fmt.Errorf("listing applied migrations: %w", err)
+	}
+	var versions []int
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning applied migration: %w", err)
+		}
+		versions = append(versions, version)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("listing applied migrations: %w", err)
+	}
+
+	for _, version := range versions {
+		name, ok := down[version]
+		if !ok {
+			return fmt.Errorf("no down migration for applied version %d", version)
+		}
+		content, err := migrationsFS.ReadFile("migrations/" + name)
+		if err != nil {
+			return fmt.Errorf("reading migration %s: %w", name, err)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("beginning transaction for rollback of migration %d: %w", version, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, string(content)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("rolling back migration %d: %w", version, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("unrecording migration %d: %w", version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("committing rollback of migration %d: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
 // GetCheckpoint returns the last indexed checkpoint for a chain
 func (s *Storage) GetCheckpoint(ctx context.Context, chainID types.ChainID) (*types.Checkpoint, error) {
 	var cp types.Checkpoint
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(chainID).QueryRowContext(ctx, `
 		SELECT chain_id, last_height, last_hash, updated_at
 		FROM checkpoints
 		WHERE chain_id = $1
@@ -149,30 +377,69 @@ func toNullableNumeric(s string) interface{} {
 	return s
 }
 
+// toNullableString converts empty strings to SQL NULL for optional text columns
+func toNullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// toNullableInt stores 0 (unknown) as NULL
+func toNullableInt(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
 // WriteBlocks atomically writes blocks, transactions, and updates checkpoint
 func (s *Storage) WriteBlocks(ctx context.Context, chainID types.ChainID, blocks []types.Block, txs []types.Transaction) error {
 	if len(blocks) == 0 {
 		return nil
 	}
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	if err := s.ensurePartitions(ctx, chainID, blocks[0].Height, blocks[len(blocks)-1].Height); err != nil {
+		return err
+	}
+
+	tx, err := s.conn(chainID).BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	// Blocks already stored (a batch polled again after a crash) are skipped with their txs
+	written, err := writtenBlocks(ctx, tx, chainID, blocks)
+	if err != nil {
+		return err
+	}
+	lastBlock := blocks[len(blocks)-1]
+	blocks = unwritten(blocks, written, blockHash)
+	txs = unwritten(txs, written, txBlockHash)
+	if len(blocks) == 0 {
+		return commitReplayCheckpoint(ctx, tx, chainID, lastBlock)
+	}
+
+	if err := s.checkContinuity(ctx, tx, chainID, blocks); err != nil {
+		return err
+	}
+
 	// Insert blocks
 	blockStmt, err := tx.PrepareContext(ctx, pq.CopyIn(
 		"blocks",
-		"chain_id", "height", "hash", "parent_hash", "timestamp", "status", "raw_data",
+		"chain_id", "height", "hash", "parent_hash", "timestamp", "status", "miner", "tx_count", "raw_data",
+		"size", "stripped_size", "weight",
 	))
 	if err != nil {
 		return fmt.Errorf("preparing block insert: %w", err)
 	}
 
+	txCounts := txCountsByBlock(txs)
 	for _, b := range blocks {
 		_, err := blockStmt.ExecContext(ctx,
-			string(b.ChainID), b.Height, b.Hash, b.ParentHash, b.Timestamp, string(b.Status), string(b.RawData),
+			string(b.ChainID), b.Height, b.Hash, b.ParentHash, b.Timestamp, string(b.Status), toNullableString(b.Miner), txCounts[b.Hash], string(b.RawData),
+			toNullableInt(b.Size), toNullableInt(b.StrippedSize), toNullableInt(b.Weight),
 		)
 		if err != nil {
 			blockStmt.Close()
@@ -186,12 +453,16 @@ func (s *Storage) WriteBlocks(ctx context.Context, chainID types.ChainID, blocks
 	}
 	blockStmt.Close()
 
+	if err := s.writeUncles(ctx, tx, chainID, blocks); err != nil {
+		return err
+	}
+
 	// Insert transactions
 	if len(txs) > 0 {
 		txStmt, err := tx.PrepareContext(ctx, pq.CopyIn(
 			"transactions",
 			"chain_id", "block_height", "block_hash", "tx_hash", "tx_index",
-			"from_addr", "to_addr", "value", "fee", "gas_used", "status", "raw_data",
+			"from_addr", "to_addr", "value", "fee", "gas_used", "tx_status", "status", "raw_data",
 		))
 		if err != nil {
 			return fmt.Errorf("preparing tx insert: %w", err)
@@ -208,7 +479,7 @@ func (s *Storage) WriteBlocks(ctx context.Context, chainID types.ChainID, blocks
 
 			_, err := txStmt.ExecContext(ctx,
 				string(t.ChainID), t.BlockHeight, t.BlockHash, t.TxHash, t.TxIndex,
-				fromAddr, toAddr, toNullableNumeric(t.Value), toNullableNumeric(t.Fee), t.GasUsed, string(t.Status), string(t.RawData),
+				fromAddr, toAddr, toNullableNumeric(t.Value), toNullableNumeric(t.Fee), t.GasUsed, toNullableString(t.TxStatus), string(t.Status), string(t.RawData),
 			)
 			if err != nil {
 				txStmt.Close()
@@ -227,8 +498,8 @@ func (s *Storage) WriteBlocks(ctx context.Context, chainID types.ChainID, blocks
 		for _, t := range txs {
 			val, _ := new(big.Int).SetString(t.Value, 10)
 			fee, _ := new(big.Int).SetString(t.Fee, 10)
-			if val == nil {
-				val = big.NewInt(0)
+			if val == nil || t.TxStatus == types.TxStatusReverted {
+				val = big.NewInt(0) // A reverted tx transfers no value but still pays its fee
 			}
 			if fee == nil {
 				fee = big.NewInt(0)
@@ -285,7 +556,7 @@ func (s *Storage) WriteBlocks(ctx context.Context, chainID types.ChainID, blocks
 	}
 
 	// Update or insert checkpoint
-	lastBlock := blocks[len(blocks)-1]
+	lastBlock = blocks[len(blocks)-1]
 	_, err = tx.ExecContext(ctx, `
 		INSERT INTO checkpoints (chain_id, last_height, last_hash, updated_at)
 		VALUES ($1, $2, $3, $4)
@@ -307,7 +578,7 @@ func (s *Storage) WriteBlocks(ctx context.Context, chainID types.ChainID, blocks
 
 // InitCheckpoint creates initial checkpoint if none exists
 func (s *Storage) InitCheckpoint(ctx context.Context, chainID types.ChainID, startHeight uint64) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(chainID).ExecContext(ctx, `
 		INSERT INTO checkpoints (chain_id, last_height, last_hash, updated_at)
 		VALUES ($1, $2, '', $3)
 		ON CONFLICT (chain_id) DO NOTHING
@@ -318,15 +589,39 @@ func (s *Storage) InitCheckpoint(ctx context.Context, chainID types.ChainID, sta
 	return nil
 }
 
+// SaveChainSettings publishes the chain's indexer configuration for the API.
+// Called on every startup, so config changes take effect with the next restart.
+func (s *Storage) SaveChainSettings(ctx context.Context, chainID types.ChainID, cs types.ChainSettings) error {
+	contracts := cs.Contracts
+	if contracts == nil {
+		contracts = []string{}
+	}
+
+	_, err := s.conn(chainID).ExecContext(ctx, `
+		INSERT INTO chain_settings (chain_id, confirmation_depth, max_reorg_depth, start_height, contracts, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (chain_id) DO UPDATE SET
+			confirmation_depth = EXCLUDED.confirmation_depth,
+			max_reorg_depth = EXCLUDED.max_reorg_depth,
+			start_height = EXCLUDED.start_height,
+			contracts = EXCLUDED.contracts,
+			updated_at = EXCLUDED.updated_at
+	`, string(chainID), cs.ConfirmationDepth, cs.MaxReorgDepth, cs.StartHeight, pq.Array(contracts), time.Now())
+	if err != nil {
+		return fmt.Errorf("saving chain settings: %w", err)
+	}
+	return nil
+}
+
 // GetAddressBalance calculates the balance for an address
 func (s *Storage) GetAddressBalance(ctx context.Context, chainID types.ChainID, address string) (string, error) {
 	var balance string
 	// We cast to TEXT because Go Scan prefers strings for Numeric to preserve precision
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(chainID).QueryRowContext(ctx, `
 		SELECT
 			(
-				COALESCE(SUM(CASE WHEN to_addr = $2 THEN value ELSE 0 END), 0) -
-				COALESCE(SUM(CASE WHEN from_addr = $2 THEN value ELSE 0 END), 0) -
+				COALESCE(SUM(CASE WHEN to_addr = $2 AND tx_status IS DISTINCT FROM 'reverted' THEN value ELSE 0 END), 0) -
+				COALESCE(SUM(CASE WHEN from_addr = $2 AND tx_status IS DISTINCT FROM 'reverted' THEN value ELSE 0 END), 0) -
 				COALESCE(SUM(CASE WHEN from_addr = $2 THEN fee ELSE 0 END), 0)
 			)::TEXT
 		FROM transactions
@@ -345,37 +640,65 @@ func (s *Storage) WriteBlocksWithEvents(ctx context.Context, chainID types.Chain
 		return nil
 	}
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	if err := s.ensurePartitions(ctx, chainID, blocks[0].Height, blocks[len(blocks)-1].Height); err != nil {
+		return err
+	}
+
+	tx, err := s.conn(chainID).BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("beginning tx: %w", err)
 	}
 	defer tx.Rollback()
 
+	// Blocks already stored are skipped with their txs, events and transfers; contracts
+	// and tokens are upserts, so writing them again is harmless
+	written, err := writtenBlocks(ctx, tx, chainID, blocks)
+	if err != nil {
+		return err
+	}
+	lastBlock := blocks[len(blocks)-1]
+	blocks = unwritten(blocks, written, blockHash)
+	txs = unwritten(txs, written, txBlockHash)
+	events = unwritten(events, written, eventBlockHash)
+	tokenTransfers = unwritten(tokenTransfers, written, transferBlockHash)
+	if len(blocks) == 0 {
+		return commitReplayCheckpoint(ctx, tx, chainID, lastBlock)
+	}
+
+	if err := s.checkContinuity(ctx, tx, chainID, blocks); err != nil {
+		return err
+	}
+
 	// 1. Prepare statements
-	stmtBlocks, err := tx.PrepareContext(ctx, pq.CopyIn("blocks", "chain_id", "height", "hash", "parent_hash", "timestamp", "status", "raw_data"))
+	stmtBlocks, err := tx.PrepareContext(ctx, pq.CopyIn("blocks", "chain_id", "height", "hash", "parent_hash", "timestamp", "status", "miner", "tx_count", "raw_data", "size", "stripped_size", "weight"))
 	if err != nil {
 		return fmt.Errorf("preparing blocks stmt: %w", err)
 	}
 	defer stmtBlocks.Close()
 
-	stmtTxs, err := tx.PrepareContext(ctx, pq.CopyIn("transactions", "chain_id", "block_height", "block_hash", "tx_hash", "tx_index", "from_addr", "to_addr", "value", "fee", "gas_used", "status", "raw_data"))
+	stmtTxs, err := tx.PrepareContext(ctx, pq.CopyIn("transactions", "chain_id", "block_height", "block_hash", "tx_hash", "tx_index", "from_addr", "to_addr", "value", "fee", "gas_used", "log_count", "tx_status", "status", "raw_data"))
 	if err != nil {
 		return fmt.Errorf("preparing txs stmt: %w", err)
 	}
 	defer stmtTxs.Close()
 
 	// 2. Insert Blocks
+	txCounts := txCountsByBlock(txs)
 	for _, b := range blocks {
-		if _, err := stmtBlocks.ExecContext(ctx, string(b.ChainID), b.Height, b.Hash, b.ParentHash, b.Timestamp, string(b.Status), string(b.RawData)); err != nil {
+		if _, err := stmtBlocks.ExecContext(ctx, string(b.ChainID), b.Height, b.Hash, b.ParentHash, b.Timestamp, string(b.Status), toNullableString(b.Miner), txCounts[b.Hash], string(b.RawData), toNullableInt(b.Size), toNullableInt(b.StrippedSize), toNullableInt(b.Weight)); err != nil {
 			return fmt.Errorf("executing block insert: %w", err)
 		}
 	}
 	if _, err := stmtBlocks.ExecContext(ctx); err != nil {
 		return fmt.Errorf("executing block flush: %w", err)
 	}
+	if err := s.writeUncles(ctx, tx, chainID, blocks); err != nil {
+		return err
+	}
 
 	// 3. Insert Transactions & Aggregate Stats
 	statsDiff := make(map[string]*types.AddressStatsDiff)
+	logCounts := s.logCounts(chainID, events)
 
 	for _, t := range txs {
 		var fromAddr, toAddr interface{}
@@ -386,15 +709,15 @@ func (s *Storage) WriteBlocksWithEvents(ctx context.Context, chainID types.Chain
 			toAddr = t.ToAddr
 		}
 
-		if _, err := stmtTxs.ExecContext(ctx, string(t.ChainID), t.BlockHeight, t.BlockHash, t.TxHash, t.TxIndex, fromAddr, toAddr, toNullableNumeric(t.Value), toNullableNumeric(t.Fee), t.GasUsed, string(t.Status), string(t.RawData)); err != nil {
+		if _, err := stmtTxs.ExecContext(ctx, string(t.ChainID), t.BlockHeight, t.BlockHash, t.TxHash, t.TxIndex, fromAddr, toAddr, toNullableNumeric(t.Value), toNullableNumeric(t.Fee), t.GasUsed, logCounts[t.TxHash], toNullableString(t.TxStatus), string(t.Status), string(t.RawData)); err != nil {
 			return fmt.Errorf("executing tx insert: %w", err)
 		}
 
 		// Aggregate Stats
 		val, _ := new(big.Int).SetString(t.Value, 10)
 		fee, _ := new(big.Int).SetString(t.Fee, 10)
-		if val == nil {
-			val = big.NewInt(0)
+		if val == nil || t.TxStatus == types.TxStatusReverted {
+			val = big.NewInt(0) // A reverted tx transfers no value but still pays its fee
 		}
 		if fee == nil {
 			fee = big.NewInt(0)
@@ -447,7 +770,7 @@ func (s *Storage) WriteBlocksWithEvents(ctx context.Context, chainID types.Chain
 
 	// 4. Insert Events
 	if len(events) > 0 {
-		stmtEvents, err := tx.PrepareContext(ctx, pq.CopyIn("events", "chain_id", "block_height", "block_hash", "tx_hash", "log_index", "contract_addr", "event_name", "topic0", "topics", "data", "raw_data", "status", "decode_failed"))
+		stmtEvents, err := tx.PrepareContext(ctx, pq.CopyIn("events", "chain_id", "block_height", "block_hash", "tx_hash", "log_index", "contract_addr", "event_name", "topic0", "topics", "data", "raw_data", "status", "decode_failed", "tx_status", "decode_oversized"))
 		if err != nil {
 			return fmt.Errorf("preparing events stmt: %w", err)
 		}
@@ -458,7 +781,7 @@ func (s *Storage) WriteBlocksWithEvents(ctx context.Context, chainID types.Chain
 			if err != nil {
 				return fmt.Errorf("marshaling topics: %w", err)
 			}
-			if _, err := stmtEvents.ExecContext(ctx, string(e.ChainID), e.BlockHeight, e.BlockHash, e.TxHash, e.LogIndex, e.ContractAddr, e.EventName, e.Topic0, topicsJSON, string(e.Data), string(e.RawData), string(e.Status), e.DecodeFailed); err != nil {
+			if _, err := stmtEvents.ExecContext(ctx, string(e.ChainID), e.BlockHeight, e.BlockHash, e.TxHash, e.LogIndex, e.ContractAddr, e.EventName, e.Topic0, topicsJSON, string(e.Data), string(e.RawData), string(e.Status), e.DecodeFailed, toNullableString(e.TxStatus), e.Oversized); err != nil {
 				return fmt.Errorf("executing event insert: %w", err)
 			}
 		}
@@ -468,11 +791,18 @@ func (s *Storage) WriteBlocksWithEvents(ctx context.Context, chainID types.Chain
 	}
 
 	// 5. Insert Contracts
+	// A plain INSERT rather than CopyIn: an address can be created again (CREATE2
+	// after SELFDESTRUCT), and the latest creation wins
 	if len(contracts) > 0 {
-		contractStmt, err := tx.PrepareContext(ctx, pq.CopyIn(
-			"contracts",
-			"chain_id", "address", "creator_addr", "tx_hash", "block_height", "created_at",
-		))
+		contractStmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO contracts (chain_id, address, creator_addr, tx_hash, block_height, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (chain_id, address) DO UPDATE SET
+				creator_addr = EXCLUDED.creator_addr,
+				tx_hash = EXCLUDED.tx_hash,
+				block_height = EXCLUDED.block_height,
+				created_at = EXCLUDED.created_at
+		`)
 		if err != nil {
 			return fmt.Errorf("preparing contract stmt: %w", err)
 		}
@@ -483,38 +813,23 @@ func (s *Storage) WriteBlocksWithEvents(ctx context.Context, chainID types.Chain
 				return fmt.Errorf("executing contract insert: %w", err)
 			}
 		}
-		if _, err := contractStmt.ExecContext(ctx); err != nil {
-			return fmt.Errorf("executing contract flush: %w", err)
-		}
 	}
 
 	// 6. Insert Tokens
 	if len(tokens) > 0 {
-		stmtTokens, err := tx.PrepareContext(ctx, pq.CopyIn(
-			"tokens",
-			"chain_id", "address", "name", "symbol", "decimals", "first_seen_height", "last_seen_height",
-		))
-		if err != nil {
-			return fmt.Errorf("preparing tokens stmt: %w", err)
-		}
-		defer stmtTokens.Close()
-
-		for _, t := range tokens {
-			if _, err := stmtTokens.ExecContext(ctx, string(t.ChainID), t.Address, t.Name, t.Symbol, t.Decimals, t.FirstSeenHeight, t.LastSeenHeight); err != nil {
-				// CopyIn doesn't support ON CONFLICT, so this might fail if we don't dedupe or check existence.
-				// For CopyIn, we assume novelty or catch constraint violation?
-				// Actually, CopyIn aborts transaction on duplicate.
-				// Strategy: Use INSERT ... ON CONFLICT DO NOTHING for tokens instead of CopyIn, or ensure `tokens` slice only has NEW tokens.
-				// The poller should ideally filter. But to be safe, let's use standard INSERT for tokens as they are low volume.
-			}
-		}
-		stmtTokens.Close() // Close copy stmt if used.
-
-		// Switch to INSERT logic for tokens to handle duplicates safely
+		// Tokens are reported on every batch they move in, so upsert them: the
+		// poller's resolved decimals are authoritative (e.g. after a restart with a
+		// new override), a name or symbol fills in one an earlier fetch missed, and
+		// the seen heights widen to cover the new batch.
 		tokenInsertStmt, err := tx.PrepareContext(ctx, `
 			INSERT INTO tokens (chain_id, address, name, symbol, decimals, first_seen_height, last_seen_height)
 			VALUES ($1, $2, $3, $4, $5, $6, $7)
-			ON CONFLICT (chain_id, address) DO NOTHING
+			ON CONFLICT (chain_id, address) DO UPDATE SET
+				name = COALESCE(NULLIF(EXCLUDED.name, ''), tokens.name),
+				symbol = COALESCE(NULLIF(EXCLUDED.symbol, ''), tokens.symbol),
+				decimals = EXCLUDED.decimals,
+				first_seen_height = LEAST(tokens.first_seen_height, EXCLUDED.first_seen_height),
+				last_seen_height = GREATEST(tokens.last_seen_height, EXCLUDED.last_seen_height)
 		`)
 		if err != nil {
 			return fmt.Errorf("preparing token insert stmt: %w", err)
@@ -579,6 +894,9 @@ func (s *Storage) WriteBlocksWithEvents(ctx context.Context, chainID types.Chain
 		}
 	}
 
+	// Cross-check the aggregates against the rows just inserted (no-op unless enabled)
+	s.verifyAggregates(ctx, tx, chainID, blocks, statsDiff, tokenBalDiff)
+
 	// 8. Update Token Balances
 	if len(tokenBalDiff) > 0 {
 		if err := s.updateTokenBalances(ctx, tx, chainID, tokenBalDiff); err != nil {
@@ -594,108 +912,129 @@ func (s *Storage) WriteBlocksWithEvents(ctx context.Context, chainID types.Chain
 	}
 
 	// 10. Update Checkpoint
-	if len(blocks) > 0 {
-		lastBlock := blocks[len(blocks)-1]
-		if _, err := tx.ExecContext(ctx, `
-			INSERT INTO checkpoints (chain_id, last_height, last_hash, updated_at)
-			VALUES ($1, $2, $3, NOW())
-			ON CONFLICT (chain_id) DO UPDATE
-			SET last_height = EXCLUDED.last_height,
-				last_hash = EXCLUDED.last_hash,
-				updated_at = NOW()
-		`, string(chainID), lastBlock.Height, lastBlock.Hash); err != nil {
-			return fmt.Errorf("updating checkpoint: %w", err)
-		}
+	lastBlock = blocks[len(blocks)-1]
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO checkpoints (chain_id, last_height, last_hash, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (chain_id) DO UPDATE
+		SET last_height = EXCLUDED.last_height,
+			last_hash = EXCLUDED.last_hash,
+			updated_at = NOW()
+	`, string(chainID), lastBlock.Height, lastBlock.Hash); err != nil {
+		return fmt.Errorf("updating checkpoint: %w", err)
 	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("committing transaction: %w", err)
 	}
 
-	// Finalize blocks after successful write
-	if err := s.FinalizeBlocks(ctx, chainID, 12); err != nil { // Default depth
-		return err
-	}
-
+	// Finalization is left to the coordinator, which knows the chain's finality rule
 	return nil
 }
 
+// updateAddressStats applies a batch's per-address deltas in one statement. Rows
+// are sorted by address so concurrent writers lock them in the same order.
 func (s *Storage) updateAddressStats(ctx context.Context, tx *sql.Tx, chainID types.ChainID, diffs map[string]*types.AddressStatsDiff) error {
-	// Prepare upsert statement
-	// Postgres doesn't support bulk upsert via COPY easily, so we use INSERT ... ON CONFLICT
-	// For performance, we could batch these or use UNNEST.
-	// We'll use a simple loop for now, optimizing if needed.
+	rows := addressStatsRows(diffs)
 
-	stmt, err := tx.PrepareContext(ctx, `
+	// New rows take last_seen as first_seen; existing rows keep the lower one
+	_, err := tx.ExecContext(ctx, `
 		INSERT INTO address_stats (chain_id, address, balance, total_received, total_sent, tx_count, first_seen_height, last_seen_height, last_updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		SELECT $1, d.address, d.balance, d.received, d.sent, d.tx_count, d.last_seen, d.last_seen, NOW()
+		FROM unnest($2::text[], $3::numeric[], $4::numeric[], $5::numeric[], $6::int[], $7::bigint[])
+			AS d(address, balance, received, sent, tx_count, last_seen)
 		ON CONFLICT (chain_id, address) DO UPDATE SET
 			balance = address_stats.balance + EXCLUDED.balance,
 			total_received = address_stats.total_received + EXCLUDED.total_received,
 			total_sent = address_stats.total_sent + EXCLUDED.total_sent,
 			tx_count = address_stats.tx_count + EXCLUDED.tx_count,
+			first_seen_height = LEAST(address_stats.first_seen_height, EXCLUDED.first_seen_height),
 			last_seen_height = GREATEST(address_stats.last_seen_height, EXCLUDED.last_seen_height),
-			last_updated_at = NOW();
-	`)
+			last_updated_at = NOW()
+	`, string(chainID), pq.Array(rows.addresses), pq.Array(rows.balances), pq.Array(rows.received),
+		pq.Array(rows.sent), pq.Array(rows.txCounts), pq.Array(rows.lastSeen))
 	if err != nil {
-		return fmt.Errorf("preparing stats upsert: %w", err)
+		return fmt.Errorf("upserting stats for %d addresses: %w", len(rows.addresses), err)
 	}
-	defer stmt.Close()
+	return nil
+}
 
-	for addr, diff := range diffs {
-		// Ensure nil values are treated as 0
-		balanceStr := "0"
-		receivedStr := "0"
-		sentStr := "0"
-		if diff.BalanceDelta != nil {
-			balanceStr = diff.BalanceDelta.String()
-		}
-		if diff.TotalReceived != nil {
-			receivedStr = diff.TotalReceived.String()
-		}
-		if diff.TotalSent != nil {
-			sentStr = diff.TotalSent.String()
-		}
+// statsRows holds address stats deltas as parallel arrays for unnest, amounts
+// as decimal strings
+type statsRows struct {
+	addresses, balances, received, sent []string
+	txCounts, lastSeen                  []int64
+}
 
-		// Set first_seen to last_seen initially; existing rows won't update first_seen anyway
-		_, err := stmt.ExecContext(ctx,
-			string(chainID),
-			addr,
-			balanceStr,
-			receivedStr,
-			sentStr,
-			diff.TxCount,
-			diff.LastSeenHeight, // first_seen (IF NEW)
-			diff.LastSeenHeight, // last_seen
-		)
-		if err != nil {
-			return fmt.Errorf("upserting stats for %s: %w", addr, err)
+// addressStatsRows flattens diffs into parallel arrays sorted by address, with
+// nil amounts as 0
+func addressStatsRows(diffs map[string]*types.AddressStatsDiff) statsRows {
+	addrs := make([]string, 0, len(diffs))
+	for addr := range diffs {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	amount := func(v *big.Int) string {
+		if v == nil {
+			return "0"
 		}
+		return v.String()
 	}
-	return nil
+
+	rows := statsRows{addresses: addrs}
+	for _, addr := range addrs {
+		diff := diffs[addr]
+		rows.balances = append(rows.balances, amount(diff.BalanceDelta))
+		rows.received = append(rows.received, amount(diff.TotalReceived))
+		rows.sent = append(rows.sent, amount(diff.TotalSent))
+		rows.txCounts = append(rows.txCounts, int64(diff.TxCount))
+		rows.lastSeen = append(rows.lastSeen, diff.LastSeenHeight)
+	}
+	return rows
 }
 
+// updateTokenBalances applies a batch's per-holder token balance deltas in one
+// statement, sorted by holder and token like updateAddressStats
 func (s *Storage) updateTokenBalances(ctx context.Context, tx *sql.Tx, chainID types.ChainID, diffs map[string]map[string]*big.Int) error {
-	stmt, err := tx.PrepareContext(ctx, `
+	addrs, tokens, deltas := tokenBalanceRows(diffs)
+
+	_, err := tx.ExecContext(ctx, `
 		INSERT INTO token_balances (chain_id, address, token_address, balance, last_updated_at)
-		VALUES ($1, $2, $3, $4, NOW())
+		SELECT $1, d.address, d.token_address, d.delta, NOW()
+		FROM unnest($2::text[], $3::text[], $4::numeric[]) AS d(address, token_address, delta)
 		ON CONFLICT (chain_id, address, token_address) DO UPDATE SET
 			balance = token_balances.balance + EXCLUDED.balance,
 			last_updated_at = NOW()
-	`)
+	`, string(chainID), pq.Array(addrs), pq.Array(tokens), pq.Array(deltas))
 	if err != nil {
-		return fmt.Errorf("preparing token balance upsert: %w", err)
+		return fmt.Errorf("upserting %d token balances: %w", len(addrs), err)
 	}
-	defer stmt.Close()
+	return nil
+}
 
-	for addr, tokens := range diffs {
-		for tokenAddr, delta := range tokens {
-			if _, err := stmt.ExecContext(ctx, string(chainID), addr, tokenAddr, delta.String()); err != nil {
-				return fmt.Errorf("upserting token balance for %s %s: %w", addr, tokenAddr, err)
-			}
+// tokenBalanceRows flattens diffs into parallel holder, token and decimal delta
+// arrays sorted by holder, then token
+func tokenBalanceRows(diffs map[string]map[string]*big.Int) (addrs, tokens, deltas []string) {
+	holders := make([]string, 0, len(diffs))
+	for addr := range diffs {
+		holders = append(holders, addr)
+	}
+	sort.Strings(holders)
+
+	for _, addr := range holders {
+		held := make([]string, 0, len(diffs[addr]))
+		for token := range diffs[addr] {
+			held = append(held, token)
+		}
+		sort.Strings(held)
+		for _, token := range held {
+			addrs = append(addrs, addr)
+			tokens = append(tokens, token)
+			deltas = append(deltas, diffs[addr][token].String())
 		}
 	}
-	return nil
+	return addrs, tokens, deltas
 }
 
 // GetBlockByHeight returns a block by chain and height
@@ -703,14 +1042,16 @@ func (s *Storage) GetBlockByHeight(ctx context.Context, chainID types.ChainID, h
 	var b types.Block
 	var rawData []byte
 
-	err := s.db.QueryRowContext(ctx, `
-		SELECT chain_id, height, hash, parent_hash, timestamp, status, raw_data
+	err := s.conn(chainID).QueryRowContext(ctx, `
+		SELECT chain_id, height, hash, parent_hash, timestamp, status, COALESCE(miner, ''), tx_count, raw_data,
+		       COALESCE(size, 0), COALESCE(stripped_size, 0), COALESCE(weight, 0)
 		FROM blocks
 		WHERE chain_id = $1 AND height = $2 AND status != 'orphaned'
 		ORDER BY created_at DESC
 		LIMIT 1
 	`, string(chainID), height).Scan(
-		&b.ChainID, &b.Height, &b.Hash, &b.ParentHash, &b.Timestamp, &b.Status, &rawData,
+		&b.ChainID, &b.Height, &b.Hash, &b.ParentHash, &b.Timestamp, &b.Status, &b.Miner, &b.TxCount, &rawData,
+		&b.Size, &b.StrippedSize, &b.Weight,
 	)
 
 	if err == sql.ErrNoRows {
@@ -724,14 +1065,25 @@ func (s *Storage) GetBlockByHeight(ctx context.Context, chainID types.ChainID, h
 	return &b, nil
 }
 
-// Rollback marks blocks and transactions as orphaned and resets checkpoint
+// Rollback marks blocks and transactions as orphaned and resets checkpoint.
+// With the finality guard enabled it returns ErrRollbackBelowFinalized, and
+// changes nothing, if toHeight is below a finalized block.
 func (s *Storage) Rollback(ctx context.Context, chainID types.ChainID, toHeight uint64, toHash string) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	return s.rollback(ctx, chainID, toHeight, toHash, nil)
+}
+
+// rollback implements Rollback, also inserting reorg into the reorgs table when set
+func (s *Storage) rollback(ctx context.Context, chainID types.ChainID, toHeight uint64, toHash string, reorg *types.Reorg) error {
+	tx, err := s.conn(chainID).BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("beginning rollback transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	if err := s.checkRollbackFinality(ctx, tx, chainID, toHeight); err != nil {
+		return err
+	}
+
 	// Archive orphaned blocks
 	_, err = tx.ExecContext(ctx, `
 		INSERT INTO orphaned_blocks (chain_id, height, hash, parent_hash, original_data)
@@ -761,6 +1113,25 @@ func (s *Storage) Rollback(ctx context.Context, chainID types.ChainID, toHeight
 		return fmt.Errorf("marking events as orphaned: %w", err)
 	}
 
+	// Contracts created in orphaned blocks no longer exist; the replacement
+	// blocks record them again if they are re-mined
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM contracts
+		WHERE chain_id = $1 AND block_height > $2
+	`, string(chainID), toHeight)
+	if err != nil {
+		return fmt.Errorf("deleting orphaned contracts: %w", err)
+	}
+
+	// Uncle references hang off the deleted blocks
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM uncles
+		WHERE chain_id = $1 AND block_height > $2
+	`, string(chainID), toHeight)
+	if err != nil {
+		return fmt.Errorf("deleting orphaned uncles: %w", err)
+	}
+
 	// Delete orphaned blocks from main table
 	_, err = tx.ExecContext(ctx, `
 		DELETE FROM blocks
@@ -770,15 +1141,35 @@ func (s *Storage) Rollback(ctx context.Context, chainID types.ChainID, toHeight
 		return fmt.Errorf("deleting orphaned blocks: %w", err)
 	}
 
-	// Reset checkpoint
+	// Reset checkpoint; heights rewritten after a rollback past finalized blocks
+	// need their raw_data pruned again
 	_, err = tx.ExecContext(ctx, `
-		UPDATE checkpoints SET last_height = $2, last_hash = $3, updated_at = $4
+		UPDATE checkpoints SET last_height = $2, last_hash = $3, updated_at = $4,
+			raw_data_pruned_height = LEAST(raw_data_pruned_height, $2)
 		WHERE chain_id = $1
 	`, string(chainID), toHeight, toHash, time.Now())
 	if err != nil {
 		return fmt.Errorf("resetting checkpoint: %w", err)
 	}
 
+	// Republish replacement blocks: the orphaned ones may already have been delivered.
+	// Bumping the generation also fails a relay batch read before the rollback,
+	// even one that started above toHeight.
+	_, err = tx.ExecContext(ctx, `
+		UPDATE publish_checkpoints
+		SET published_up_to = LEAST(published_up_to, $2), generation = generation + 1, updated_at = $3
+		WHERE chain_id = $1
+	`, string(chainID), toHeight, time.Now())
+	if err != nil {
+		return fmt.Errorf("resetting publish checkpoint: %w", err)
+	}
+
+	if reorg != nil {
+		if err := insertReorg(ctx, tx, chainID, reorg); err != nil {
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("committing rollback: %w", err)
 	}
@@ -788,7 +1179,7 @@ func (s *Storage) Rollback(ctx context.Context, chainID types.ChainID, toHeight
 
 // FinalizeBlocks promotes blocks past confirmation depth to finalized status
 func (s *Storage) FinalizeBlocks(ctx context.Context, chainID types.ChainID, confirmationDepth int) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.conn(chainID).BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("beginning finalization transaction: %w", err)
 	}
@@ -806,14 +1197,42 @@ func (s *Storage) FinalizeBlocks(ctx context.Context, chainID types.ChainID, con
 		return fmt.Errorf("getting tip height: %w", err)
 	}
 
-	// Avoid underflow
-	if tipHeight <= uint64(confirmationDepth) {
+	height := types.HeightBelow(tipHeight, confirmationDepth)
+	if height == 0 {
 		return nil // Not enough blocks yet
 	}
-	finalizeBelow := tipHeight - uint64(confirmationDepth)
 
+	return s.finalize(ctx, tx, chainID, height)
+}
+
+// FinalizeUpTo promotes blocks at or below height to finalized status, for chains
+// whose node reports finality directly (e.g. the ETH "finalized" tag). Heights past
+// the indexed checkpoint are clamped to it.
+func (s *Storage) FinalizeUpTo(ctx context.Context, chainID types.ChainID, height uint64) error {
+	tx, err := s.conn(chainID).BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning finalization transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var tipHeight uint64
+	err = tx.QueryRowContext(ctx, `
+		SELECT last_height FROM checkpoints WHERE chain_id = $1
+	`, string(chainID)).Scan(&tipHeight)
+	if err == sql.ErrNoRows {
+		return nil // Nothing to finalize
+	}
+	if err != nil {
+		return fmt.Errorf("getting tip height: %w", err)
+	}
+
+	return s.finalize(ctx, tx, chainID, min(height, tipHeight))
+}
+
+// finalize marks pending rows at or below finalizeBelow finalized and commits tx
+func (s *Storage) finalize(ctx context.Context, tx *sql.Tx, chainID types.ChainID, finalizeBelow uint64) error {
 	// Finalize blocks
-	_, err = tx.ExecContext(ctx, `
+	_, err := tx.ExecContext(ctx, `
 		UPDATE blocks SET status = 'finalized'
 		WHERE chain_id = $1 AND status = 'pending' AND height <= $2
 	`, string(chainID), finalizeBelow)
@@ -3,6 +3,8 @@ package storage_test
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -38,7 +40,7 @@ func setupTestDB(t *testing.T) (*sql.DB, *storage.Storage, func()) {
 
 	// Clean up tables
 	ctx := context.Background()
-	tables := []string{"orphaned_blocks", "events", "transactions", "blocks", "checkpoints", "schema_migrations"}
+	tables := []string{"webhook_subscriptions", "orphaned_blocks", "uncles", "reorgs", "contracts", "events", "transactions", "blocks", "checkpoints", "schema_migrations"}
 	for _, table := range tables {
 		db.ExecContext(ctx, "DROP TABLE IF EXISTS "+table+" CASCADE")
 	}
@@ -134,6 +136,51 @@ func TestWriteBlocks_AtomicCheckpoint(t *testing.T) {
 	}
 }
 
+func TestWriteBlocks_StrictValidationRejectsBrokenChain(t *testing.T) {
+	_, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	chainID := types.ChainBTC
+	store.SetStrictWriteValidation(chainID, true)
+
+	if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+		t.Fatalf("InitCheckpoint failed: %v", err)
+	}
+
+	// Block 2 is not parented on block 1
+	blocks := []types.Block{
+		{ChainID: chainID, Height: 1, Hash: "block1hash", ParentHash: "genesis", Timestamp: time.Now(), Status: types.StatusPending},
+		{ChainID: chainID, Height: 2, Hash: "block2hash", ParentHash: "otherhash", Timestamp: time.Now(), Status: types.StatusPending},
+	}
+
+	if err := store.WriteBlocks(ctx, chainID, blocks, nil); err == nil {
+		t.Fatal("expected WriteBlocks to reject a broken parent link")
+	}
+
+	// Nothing was written
+	checkpoint, err := store.GetCheckpoint(ctx, chainID)
+	if err != nil {
+		t.Fatalf("GetCheckpoint failed: %v", err)
+	}
+	if checkpoint.LastHeight != 0 {
+		t.Errorf("expected checkpoint height 0, got %d", checkpoint.LastHeight)
+	}
+
+	blocks[1].ParentHash = "block1hash"
+	if err := store.WriteBlocks(ctx, chainID, blocks, nil); err != nil {
+		t.Fatalf("WriteBlocks failed on a valid chain: %v", err)
+	}
+
+	// Next batch must build on the checkpoint hash
+	next := []types.Block{
+		{ChainID: chainID, Height: 3, Hash: "block3hash", ParentHash: "block1hash", Timestamp: time.Now(), Status: types.StatusPending},
+	}
+	if err := store.WriteBlocks(ctx, chainID, next, nil); err == nil {
+		t.Fatal("expected WriteBlocks to reject a batch not parented on the checkpoint")
+	}
+}
+
 func TestRollback_OrphansBlocks(t *testing.T) {
 	_, store, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -199,6 +246,111 @@ func TestRollback_OrphansBlocks(t *testing.T) {
 	}
 }
 
+func TestRollback_BelowFinalizedRefused(t *testing.T) {
+	_, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	chainID := types.ChainBTC
+	store.SetRollbackFinalityGuard(chainID, true)
+
+	if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+		t.Fatalf("InitCheckpoint failed: %v", err)
+	}
+
+	blocks := []types.Block{
+		{ChainID: chainID, Height: 1, Hash: "hash1", ParentHash: "genesis", Timestamp: time.Now(), Status: types.StatusPending},
+		{ChainID: chainID, Height: 2, Hash: "hash2", ParentHash: "hash1", Timestamp: time.Now(), Status: types.StatusPending},
+		{ChainID: chainID, Height: 3, Hash: "hash3", ParentHash: "hash2", Timestamp: time.Now(), Status: types.StatusPending},
+		{ChainID: chainID, Height: 4, Hash: "hash4", ParentHash: "hash3", Timestamp: time.Now(), Status: types.StatusPending},
+	}
+	if err := store.WriteBlocks(ctx, chainID, blocks, nil); err != nil {
+		t.Fatalf("WriteBlocks failed: %v", err)
+	}
+
+	// Finalize blocks 1-2
+	if err := store.FinalizeBlocks(ctx, chainID, 2); err != nil {
+		t.Fatalf("FinalizeBlocks failed: %v", err)
+	}
+
+	if err := store.Rollback(ctx, chainID, 1, "hash1"); !errors.Is(err, storage.ErrRollbackBelowFinalized) {
+		t.Fatalf("expected ErrRollbackBelowFinalized, got %v", err)
+	}
+
+	// Nothing was rolled back
+	checkpoint, err := store.GetCheckpoint(ctx, chainID)
+	if err != nil {
+		t.Fatalf("GetCheckpoint failed: %v", err)
+	}
+	if checkpoint.LastHeight != 4 {
+		t.Errorf("expected checkpoint height 4, got %d", checkpoint.LastHeight)
+	}
+	block2, err := store.GetBlockByHeight(ctx, chainID, 2)
+	if err != nil {
+		t.Fatalf("GetBlockByHeight failed: %v", err)
+	}
+	if block2 == nil || block2.Status != types.StatusFinalized {
+		t.Errorf("expected block 2 to stay finalized, got %+v", block2)
+	}
+
+	// Rolling back to the finalized height only orphans pending blocks
+	if err := store.Rollback(ctx, chainID, 2, "hash2"); err != nil {
+		t.Fatalf("Rollback to finalized height failed: %v", err)
+	}
+}
+
+func TestRollbackReorg_RecordsReorg(t *testing.T) {
+	_, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	chainID := types.ChainETH
+
+	if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+		t.Fatalf("InitCheckpoint failed: %v", err)
+	}
+	blocks := []types.Block{
+		{ChainID: chainID, Height: 1, Hash: "hash1", ParentHash: "genesis", Timestamp: time.Now(), Status: types.StatusPending},
+		{ChainID: chainID, Height: 2, Hash: "hash2", ParentHash: "hash1", Timestamp: time.Now(), Status: types.StatusPending},
+		{ChainID: chainID, Height: 3, Hash: "hash3", ParentHash: "hash2", Timestamp: time.Now(), Status: types.StatusPending},
+	}
+	if err := store.WriteBlocks(ctx, chainID, blocks, nil); err != nil {
+		t.Fatalf("WriteBlocks failed: %v", err)
+	}
+
+	detectedAt := time.Now().UTC().Truncate(time.Second)
+	err := store.RollbackReorg(ctx, chainID, "hash1", types.Reorg{
+		DetectedAt:     detectedAt,
+		RollbackHeight: 1,
+		Depth:          2,
+		OldHash:        "hash3",
+		NewHash:        "hash3_new",
+	})
+	if err != nil {
+		t.Fatalf("RollbackReorg failed: %v", err)
+	}
+
+	reorgs, err := store.GetReorgs(ctx, chainID, 10)
+	if err != nil {
+		t.Fatalf("GetReorgs failed: %v", err)
+	}
+	if len(reorgs) != 1 {
+		t.Fatalf("expected 1 reorg record, got %d", len(reorgs))
+	}
+	r := reorgs[0]
+	if r.ChainID != chainID || r.RollbackHeight != 1 || r.Depth != 2 || r.OldHash != "hash3" || r.NewHash != "hash3_new" || !r.DetectedAt.Equal(detectedAt) {
+		t.Errorf("unexpected reorg record: %+v", r)
+	}
+
+	// A plain Rollback records nothing
+	if err := store.Rollback(ctx, chainID, 0, ""); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if reorgs, _ := store.GetReorgs(ctx, chainID, 10); len(reorgs) != 1 {
+		t.Errorf("expected Rollback to leave 1 reorg record, got %d", len(reorgs))
+	}
+}
+
 func TestFinalization(t *testing.T) {
 	_, store, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -259,6 +411,460 @@ func TestFinalization(t *testing.T) {
 	}
 }
 
+func TestFinalization_LowHeight(t *testing.T) {
+	db, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	chainID := types.ChainETH
+
+	// Finalizing before anything is indexed is a no-op
+	if err := store.FinalizeUpTo(ctx, chainID, 100); err != nil {
+		t.Fatalf("FinalizeUpTo without checkpoint failed: %v", err)
+	}
+
+	if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+		t.Fatalf("InitCheckpoint failed: %v", err)
+	}
+
+	var blocks []types.Block
+	for i := uint64(1); i <= 5; i++ {
+		blocks = append(blocks, types.Block{
+			ChainID: chainID, Height: i, Hash: fmt.Sprintf("hash%d", i), ParentHash: fmt.Sprintf("hash%d", i-1),
+			Timestamp: time.Now(), Status: types.StatusPending,
+		})
+	}
+	if err := store.WriteBlocks(ctx, chainID, blocks, nil); err != nil {
+		t.Fatalf("WriteBlocks failed: %v", err)
+	}
+
+	finalized := func() int {
+		var n int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM blocks WHERE chain_id = $1 AND status = 'finalized'`, string(chainID)).Scan(&n); err != nil {
+			t.Fatalf("counting finalized blocks: %v", err)
+		}
+		return n
+	}
+
+	// Chain shorter than the confirmation depth: nothing to finalize
+	if err := store.FinalizeBlocks(ctx, chainID, 12); err != nil {
+		t.Fatalf("FinalizeBlocks failed: %v", err)
+	}
+	if n := finalized(); n != 0 {
+		t.Errorf("expected no finalized blocks below confirmation depth, got %d", n)
+	}
+
+	// A node-reported finalized height within the indexed range
+	if err := store.FinalizeUpTo(ctx, chainID, 2); err != nil {
+		t.Fatalf("FinalizeUpTo failed: %v", err)
+	}
+	if n := finalized(); n != 2 {
+		t.Errorf("expected 2 finalized blocks, got %d", n)
+	}
+
+	// A height past the indexed tip is clamped to the checkpoint
+	if err := store.FinalizeUpTo(ctx, chainID, 1000); err != nil {
+		t.Fatalf("FinalizeUpTo failed: %v", err)
+	}
+	if n := finalized(); n != 5 {
+		t.Errorf("expected all 5 blocks finalized, got %d", n)
+	}
+}
+
+func TestPrune_RawDataRetention(t *testing.T) {
+	tests := []struct {
+		retention storage.RawDataRetention
+		wantRaw   map[uint64]bool // finalized height -> raw_data kept
+	}{
+		{storage.RetainRawAll, map[uint64]bool{1: true, 2: true}},
+		{storage.RetainRawPending, map[uint64]bool{1: false, 2: false}},
+		{storage.RetainRawEvents, map[uint64]bool{1: false, 2: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.retention), func(t *testing.T) {
+			db, store, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			ctx := context.Background()
+			chainID := types.ChainETH
+
+			if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+				t.Fatalf("InitCheckpoint failed: %v", err)
+			}
+
+			var blocks []types.Block
+			for i := uint64(1); i <= 5; i++ {
+				blocks = append(blocks, types.Block{
+					ChainID:    chainID,
+					Height:     i,
+					Hash:       fmt.Sprintf("hash%d", i),
+					ParentHash: fmt.Sprintf("hash%d", i-1),
+					Timestamp:  time.Now(),
+					Status:     types.StatusPending,
+					RawData:    []byte(`{"raw":true}`),
+				})
+			}
+			// Only block 2 has a monitored-contract event
+			events := []types.Event{{
+				ChainID: chainID, BlockHeight: 2, BlockHash: "hash2", TxHash: "tx2", LogIndex: 0,
+				ContractAddr: "0xabc", Topic0: "0x01", Status: types.StatusPending,
+			}}
+
+			var txs []types.Transaction
+			for i := uint64(1); i <= 2; i++ {
+				txs = append(txs, types.Transaction{
+					ChainID: chainID, BlockHeight: i, BlockHash: fmt.Sprintf("hash%d", i), TxHash: fmt.Sprintf("tx%d", i),
+					Value: "0", Fee: "0", Status: types.StatusPending, RawData: []byte(`{"raw":true}`),
+				})
+			}
+
+			if err := store.WriteBlocksWithEvents(ctx, chainID, blocks, txs, events, nil, nil, nil); err != nil {
+				t.Fatalf("WriteBlocksWithEvents failed: %v", err)
+			}
+
+			// Depth 3 finalizes blocks 1-2
+			if err := store.FinalizeBlocks(ctx, chainID, 3); err != nil {
+				t.Fatalf("FinalizeBlocks failed: %v", err)
+			}
+			res, err := store.Prune(ctx, chainID, storage.PruneOptions{RawData: tt.retention})
+			if err != nil {
+				t.Fatalf("Prune failed: %v", err)
+			}
+			if tt.retention != storage.RetainRawAll && res.RawDataPrunedUpTo != 2 {
+				t.Errorf("expected the raw_data cursor at 2, got %d", res.RawDataPrunedUpTo)
+			}
+
+			for height, keep := range tt.wantRaw {
+				b, err := store.GetBlockByHeight(ctx, chainID, height)
+				if err != nil {
+					t.Fatalf("GetBlockByHeight failed: %v", err)
+				}
+				if got := len(b.RawData) > 0; got != keep {
+					t.Errorf("block %d: raw_data kept = %v, want %v", height, got, keep)
+				}
+
+				// A transaction keeps raw_data exactly when its block does
+				var txKept bool
+				if err := db.QueryRowContext(ctx, `
+					SELECT raw_data IS NOT NULL FROM transactions WHERE chain_id = $1 AND tx_hash = $2
+				`, string(chainID), fmt.Sprintf("tx%d", height)).Scan(&txKept); err != nil {
+					t.Fatalf("querying tx raw_data: %v", err)
+				}
+				if txKept != keep {
+					t.Errorf("tx%d: raw_data kept = %v, want %v", height, txKept, keep)
+				}
+			}
+
+			// Pending blocks always keep raw_data for reorg archival
+			b, err := store.GetBlockByHeight(ctx, chainID, 5)
+			if err != nil {
+				t.Fatalf("GetBlockByHeight failed: %v", err)
+			}
+			if len(b.RawData) == 0 {
+				t.Error("expected pending block 5 to keep raw_data")
+			}
+		})
+	}
+}
+
+func TestWriteBlocks_Partitioned(t *testing.T) {
+	db, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	chainID := types.ChainETH
+
+	if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+		t.Fatalf("InitCheckpoint failed: %v", err)
+	}
+
+	// Rows written before partitioning must survive the conversion
+	writeRange := func(from, to uint64) {
+		var blocks []types.Block
+		var txs []types.Transaction
+		var events []types.Event
+		for i := from; i <= to; i++ {
+			hash := fmt.Sprintf("hash%d", i)
+			blocks = append(blocks, types.Block{
+				ChainID: chainID, Height: i, Hash: hash, ParentHash: fmt.Sprintf("hash%d", i-1),
+				Timestamp: time.Now(), Status: types.StatusPending,
+			})
+			txs = append(txs, types.Transaction{
+				ChainID: chainID, BlockHeight: i, BlockHash: hash, TxHash: fmt.Sprintf("tx%d", i), Status: types.StatusPending,
+			})
+			events = append(events, types.Event{
+				ChainID: chainID, BlockHeight: i, BlockHash: hash, TxHash: fmt.Sprintf("tx%d", i),
+				ContractAddr: "0xabc", Topic0: "0x01", Status: types.StatusPending,
+			})
+		}
+		if err := store.WriteBlocksWithEvents(ctx, chainID, blocks, txs, events, nil, nil, nil); err != nil {
+			t.Fatalf("WriteBlocksWithEvents(%d-%d) failed: %v", from, to, err)
+		}
+	}
+	writeRange(1, 5)
+
+	store.SetPartitionSize(chainID, 10)
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("partitioning migrate failed: %v", err)
+	}
+	// Re-running on already partitioned tables is a no-op
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("second migrate failed: %v", err)
+	}
+
+	// Spans three partitions, two of which did not exist at conversion time
+	writeRange(6, 25)
+
+	for _, table := range []string{"transactions", "events"} {
+		var rows int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table+` WHERE chain_id = $1`, string(chainID)).Scan(&rows); err != nil {
+			t.Fatalf("counting %s: %v", table, err)
+		}
+		if rows != 25 {
+			t.Errorf("expected 25 %s rows, got %d", table, rows)
+		}
+
+		var partitions int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pg_inherits WHERE inhparent = $1::regclass`, table).Scan(&partitions); err != nil {
+			t.Fatalf("counting %s partitions: %v", table, err)
+		}
+		if partitions < 3 {
+			t.Errorf("expected at least 3 %s partitions, got %d", table, partitions)
+		}
+	}
+
+	// Rollback and finalization update rows across partitions
+	if err := store.Rollback(ctx, chainID, 20, "hash20"); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if err := store.FinalizeBlocks(ctx, chainID, 5); err != nil {
+		t.Fatalf("FinalizeBlocks failed: %v", err)
+	}
+
+	var finalized, orphaned int
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FILTER (WHERE status = 'finalized'), COUNT(*) FILTER (WHERE status = 'orphaned')
+		FROM transactions WHERE chain_id = $1
+	`, string(chainID)).Scan(&finalized, &orphaned); err != nil {
+		t.Fatalf("counting statuses: %v", err)
+	}
+	if finalized != 15 || orphaned != 5 {
+		t.Errorf("expected 15 finalized and 5 orphaned txs, got %d and %d", finalized, orphaned)
+	}
+}
+
+func TestWriteBlocks_LogCount(t *testing.T) {
+	db, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	chainID := types.ChainETH
+
+	if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+		t.Fatalf("InitCheckpoint failed: %v", err)
+	}
+
+	blocks := []types.Block{{ChainID: chainID, Height: 1, Hash: "hash1", ParentHash: "hash0", Timestamp: time.Now(), Status: types.StatusPending}}
+	txs := []types.Transaction{
+		{ChainID: chainID, BlockHeight: 1, BlockHash: "hash1", TxHash: "tx1", TxIndex: 0, Status: types.StatusPending},
+		{ChainID: chainID, BlockHeight: 1, BlockHash: "hash1", TxHash: "tx2", TxIndex: 1, Status: types.StatusPending},
+	}
+	var events []types.Event
+	for i := 0; i < 3; i++ {
+		events = append(events, types.Event{
+			ChainID: chainID, BlockHeight: 1, BlockHash: "hash1", TxHash: "tx1", LogIndex: i,
+			ContractAddr: "0xabc", Topic0: "0x01", Status: types.StatusPending,
+		})
+	}
+
+	logCounts := func() map[string]int {
+		rows, err := db.QueryContext(ctx, `SELECT tx_hash, log_count FROM transactions WHERE chain_id = $1`, string(chainID))
+		if err != nil {
+			t.Fatalf("querying log counts: %v", err)
+		}
+		defer rows.Close()
+		counts := make(map[string]int)
+		for rows.Next() {
+			var hash string
+			var n int
+			rows.Scan(&hash, &n)
+			counts[hash] = n
+		}
+		return counts
+	}
+
+	// Written before counting was enabled: zero until recomputed
+	if err := store.WriteBlocksWithEvents(ctx, chainID, blocks, txs, events, nil, nil, nil); err != nil {
+		t.Fatalf("WriteBlocksWithEvents failed: %v", err)
+	}
+	if c := logCounts(); c["tx1"] != 0 {
+		t.Errorf("expected log_count 0 with counting off, got %d", c["tx1"])
+	}
+
+	updated, err := store.RecomputeLogCounts(ctx, chainID, 0, 1)
+	if err != nil {
+		t.Fatalf("RecomputeLogCounts failed: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("expected 2 transactions updated, got %d", updated)
+	}
+	if c := logCounts(); c["tx1"] != 3 || c["tx2"] != 0 {
+		t.Errorf("expected counts tx1=3 tx2=0 after recompute, got %v", c)
+	}
+
+	// With counting on, new writes carry the count directly
+	store.SetLogCounts(chainID, true)
+	blocks[0] = types.Block{ChainID: chainID, Height: 2, Hash: "hash2", ParentHash: "hash1", Timestamp: time.Now(), Status: types.StatusPending}
+	txs = []types.Transaction{{ChainID: chainID, BlockHeight: 2, BlockHash: "hash2", TxHash: "tx3", Status: types.StatusPending}}
+	events = events[:2]
+	for i := range events {
+		events[i].BlockHeight, events[i].BlockHash, events[i].TxHash = 2, "hash2", "tx3"
+	}
+	if err := store.WriteBlocksWithEvents(ctx, chainID, blocks, txs, events, nil, nil, nil); err != nil {
+		t.Fatalf("WriteBlocksWithEvents failed: %v", err)
+	}
+	if c := logCounts(); c["tx3"] != 2 {
+		t.Errorf("expected log_count 2 for tx3, got %d", c["tx3"])
+	}
+}
+
+func TestWriteBlocks_TxCount(t *testing.T) {
+	db, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	chainID := types.ChainBTC
+
+	if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+		t.Fatalf("InitCheckpoint failed: %v", err)
+	}
+
+	blocks := []types.Block{
+		{ChainID: chainID, Height: 1, Hash: "hash1", ParentHash: "hash0", Timestamp: time.Now(), Status: types.StatusPending},
+		{ChainID: chainID, Height: 2, Hash: "hash2", ParentHash: "hash1", Timestamp: time.Now(), Status: types.StatusPending},
+	}
+	txs := []types.Transaction{
+		{ChainID: chainID, BlockHeight: 1, BlockHash: "hash1", TxHash: "tx1", TxIndex: 0, Status: types.StatusPending},
+		{ChainID: chainID, BlockHeight: 1, BlockHash: "hash1", TxHash: "tx2", TxIndex: 1, Status: types.StatusPending},
+	}
+	if err := store.WriteBlocks(ctx, chainID, blocks, txs); err != nil {
+		t.Fatalf("WriteBlocks failed: %v", err)
+	}
+
+	b, err := store.GetBlockByHeight(ctx, chainID, 1)
+	if err != nil || b == nil {
+		t.Fatalf("GetBlockByHeight failed: %v", err)
+	}
+	if b.TxCount != 2 {
+		t.Errorf("expected tx_count 2, got %d", b.TxCount)
+	}
+
+	// Rows written before the column existed are fixed up by the recompute
+	if _, err := db.ExecContext(ctx, `UPDATE blocks SET tx_count = 0 WHERE chain_id = $1`, string(chainID)); err != nil {
+		t.Fatalf("resetting tx counts: %v", err)
+	}
+	updated, err := store.RecomputeTxCounts(ctx, chainID, 0, 2)
+	if err != nil {
+		t.Fatalf("RecomputeTxCounts failed: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("expected 2 blocks updated, got %d", updated)
+	}
+	if b, _ := store.GetBlockByHeight(ctx, chainID, 1); b == nil || b.TxCount != 2 {
+		t.Errorf("expected tx_count 2 after recompute, got %+v", b)
+	}
+}
+
+func TestWriteBlocks_Uncles(t *testing.T) {
+	db, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	chainID := types.ChainETH
+	store.SetUncles(chainID, true)
+
+	if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+		t.Fatalf("InitCheckpoint failed: %v", err)
+	}
+
+	blocks := []types.Block{
+		{ChainID: chainID, Height: 1, Hash: "hash1", ParentHash: "hash0", Timestamp: time.Now(), Status: types.StatusPending, Uncles: []string{"uncleA", "uncleB"}},
+		{ChainID: chainID, Height: 2, Hash: "hash2", ParentHash: "hash1", Timestamp: time.Now(), Status: types.StatusPending, Uncles: []string{"uncleC"}},
+	}
+	if err := store.WriteBlocks(ctx, chainID, blocks, nil); err != nil {
+		t.Fatalf("WriteBlocks failed: %v", err)
+	}
+
+	countUncles := func() int {
+		var n int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM uncles WHERE chain_id = $1`, string(chainID)).Scan(&n); err != nil {
+			t.Fatalf("counting uncles: %v", err)
+		}
+		return n
+	}
+	if n := countUncles(); n != 3 {
+		t.Errorf("expected 3 uncles, got %d", n)
+	}
+
+	// Rolling back block 2 drops its uncle reference
+	if err := store.Rollback(ctx, chainID, 1, "hash1"); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if n := countUncles(); n != 2 {
+		t.Errorf("expected 2 uncles after rollback, got %d", n)
+	}
+}
+
+func TestWriteBlocksWithEvents_Contracts(t *testing.T) {
+	db, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	chainID := types.ChainETH
+
+	if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+		t.Fatalf("InitCheckpoint failed: %v", err)
+	}
+
+	blocks := []types.Block{
+		{ChainID: chainID, Height: 1, Hash: "hash1", ParentHash: "hash0", Timestamp: time.Now(), Status: types.StatusPending},
+		{ChainID: chainID, Height: 2, Hash: "hash2", ParentHash: "hash1", Timestamp: time.Now(), Status: types.StatusPending},
+	}
+	contracts := []types.Contract{
+		{ChainID: chainID, Address: "0xc1", CreatorAddr: "0xaa", TxHash: "0xt1", BlockHeight: 1, CreatedAt: time.Now()},
+		{ChainID: chainID, Address: "0xc2", CreatorAddr: "0xaa", TxHash: "0xt2", BlockHeight: 2, CreatedAt: time.Now()},
+	}
+	if err := store.WriteBlocksWithEvents(ctx, chainID, blocks, nil, nil, contracts, nil, nil); err != nil {
+		t.Fatalf("WriteBlocksWithEvents failed: %v", err)
+	}
+
+	countContracts := func() int {
+		var n int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM contracts WHERE chain_id = $1`, string(chainID)).Scan(&n); err != nil {
+			t.Fatalf("counting contracts: %v", err)
+		}
+		return n
+	}
+	if n := countContracts(); n != 2 {
+		t.Errorf("expected 2 contracts, got %d", n)
+	}
+
+	// Rolling back block 2 drops its contract, and re-indexing it records it again
+	if err := store.Rollback(ctx, chainID, 1, "hash1"); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if n := countContracts(); n != 1 {
+		t.Errorf("expected 1 contract after rollback, got %d", n)
+	}
+	if err := store.WriteBlocksWithEvents(ctx, chainID, blocks[1:], nil, nil, contracts[1:], nil, nil); err != nil {
+		t.Fatalf("re-writing block 2 failed: %v", err)
+	}
+	if n := countContracts(); n != 2 {
+		t.Errorf("expected 2 contracts after re-indexing, got %d", n)
+	}
+}
+
 func TestCrashRecovery(t *testing.T) {
 	// Simulate crash recovery by creating a new storage instance
 	_, store, cleanup := setupTestDB(t)
@@ -300,3 +906,139 @@ func TestCrashRecovery(t *testing.T) {
 	// Polling should continue from height 7
 	// (poller would call Poll(ctx, checkpoint.LastHeight) => Poll(ctx, 7) => fetch from height 8)
 }
+
+func TestWriteBlocks_Replay(t *testing.T) {
+	db, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	chainID := types.ChainBTC
+	store.SetStrictWriteValidation(chainID, true)
+
+	if err := store.InitCheckpoint(ctx, chainID, 0); err != nil {
+		t.Fatalf("InitCheckpoint failed: %v", err)
+	}
+
+	blocks := []types.Block{
+		{ChainID: chainID, Height: 1, Hash: "hash1", ParentHash: "hash0", Timestamp: time.Now(), Status: types.StatusPending},
+		{ChainID: chainID, Height: 2, Hash: "hash2", ParentHash: "hash1", Timestamp: time.Now(), Status: types.StatusPending},
+	}
+	txs := []types.Transaction{
+		{ChainID: chainID, BlockHeight: 1, BlockHash: "hash1", TxHash: "tx1", ToAddr: "addr1", Value: "100", Status: types.StatusPending},
+	}
+	if err := store.WriteBlocks(ctx, chainID, blocks, txs); err != nil {
+		t.Fatalf("WriteBlocks failed: %v", err)
+	}
+
+	// The same batch polled again after a crash is a no-op
+	if err := store.WriteBlocks(ctx, chainID, blocks, txs); err != nil {
+		t.Fatalf("replayed WriteBlocks failed: %v", err)
+	}
+
+	// An overlapping batch only writes the new block and moves the checkpoint on
+	next := append(blocks[1:], types.Block{ChainID: chainID, Height: 3, Hash: "hash3", ParentHash: "hash2", Timestamp: time.Now(), Status: types.StatusPending})
+	if err := store.WriteBlocks(ctx, chainID, next, nil); err != nil {
+		t.Fatalf("overlapping WriteBlocks failed: %v", err)
+	}
+
+	var nBlocks, nTxs int
+	db.QueryRowContext(ctx, `SELECT COUNT(*) FROM blocks WHERE chain_id = $1`, string(chainID)).Scan(&nBlocks)
+	db.QueryRowContext(ctx, `SELECT COUNT(*) FROM transactions WHERE chain_id = $1`, string(chainID)).Scan(&nTxs)
+	if nBlocks != 3 || nTxs != 1 {
+		t.Errorf("expected 3 blocks and 1 tx, got %d and %d", nBlocks, nTxs)
+	}
+
+	var balance string
+	if err := db.QueryRowContext(ctx, `SELECT balance FROM address_stats WHERE chain_id = $1 AND address = 'addr1'`, string(chainID)).Scan(&balance); err != nil {
+		t.Fatalf("querying address stats: %v", err)
+	}
+	if balance != "100" {
+		t.Errorf("expected balance 100 counted once, got %s", balance)
+	}
+
+	cp, err := store.GetCheckpoint(ctx, chainID)
+	if err != nil {
+		t.Fatalf("GetCheckpoint failed: %v", err)
+	}
+	if cp.LastHeight != 3 || cp.LastHash != "hash3" {
+		t.Errorf("expected checkpoint at 3/hash3, got %d/%s", cp.LastHeight, cp.LastHash)
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	db, store, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	columnExists := func(table, column string) bool {
+		t.Helper()
+		var exists bool
+		err := db.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)
+		`, table, column).Scan(&exists)
+		if err != nil {
+			t.Fatalf("checking column %s.%s: %v", table, column, err)
+		}
+		return exists
+	}
+
+	if err := store.MigrateDown(ctx, 16); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	var version int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		t.Fatalf("reading version: %v", err)
+	}
+	if version != 16 {
+		t.Errorf("expected version 16 after rollback, got %d", version)
+	}
+	if columnExists("blocks", "weight") || columnExists("transactions", "tx_status") {
+		t.Error("expected columns of rolled back migrations to be dropped")
+	}
+	if !columnExists("transactions", "log_count") {
+		t.Error("expected migrations at or below the target version to stay applied")
+	}
+
+	// Migrating up again reapplies the rolled back migrations
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate after rollback: %v", err)
+	}
+	if !columnExists("blocks", "weight") {
+		t.Error("expected blocks.weight after migrating up again")
+	}
+}
+
+func TestWebhookSubscriptions(t *testing.T) {
+	_, store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sub := types.WebhookSubscription{ChainID: types.ChainETH, Address: "0xabc", CallbackURL: "https://example.com/hook", Secret: "one"}
+	if err := store.CreateWebhookSubscription(ctx, &sub); err != nil {
+		t.Fatalf("CreateWebhookSubscription failed: %v", err)
+	}
+	if sub.ID == 0 || sub.CreatedAt.IsZero() {
+		t.Fatalf("expected ID and CreatedAt to be filled in, got %+v", sub)
+	}
+
+	// Re-registering the same address and callback replaces the secret
+	again := sub
+	again.Secret = "two"
+	if err := store.CreateWebhookSubscription(ctx, &again); err != nil {
+		t.Fatalf("CreateWebhookSubscription (again) failed: %v", err)
+	}
+	subs, err := store.GetWebhookSubscriptions(ctx, types.ChainETH)
+	if err != nil {
+		t.Fatalf("GetWebhookSubscriptions failed: %v", err)
+	}
+	if again.ID != sub.ID || len(subs) != 1 || subs[0].Secret != "two" {
+		t.Errorf("expected one subscription with the new secret, got %+v", subs)
+	}
+
+	if ok, err := store.DeleteWebhookSubscription(ctx, types.ChainBTC, sub.ID); ok || err != nil {
+		t.Errorf("expected another chain's delete to miss, got %v, %v", ok, err)
+	}
+	if ok, err := store.DeleteWebhookSubscription(ctx, types.ChainETH, sub.ID); !ok || err != nil {
+		t.Errorf("DeleteWebhookSubscription: %v, %v", ok, err)
+	}
+}
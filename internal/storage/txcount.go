@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// txCountBatch is the number of blocks recounted per statement by RecomputeTxCounts
+const txCountBatch = 10000
+
+// txCountsByBlock returns the number of transactions per block hash
+func txCountsByBlock(txs []types.Transaction) map[string]int {
+	counts := make(map[string]int)
+	for _, t := range txs {
+		counts[t.BlockHash]++
+	}
+	return counts
+}
+
+// RecomputeTxCounts rewrites blocks.tx_count for heights [fromHeight, toHeight] from the
+// transactions table, in batches of txCountBatch blocks. Used to backfill blocks written
+// before the column existed. Returns the number of blocks updated.
+func (s *Storage) RecomputeTxCounts(ctx context.Context, chainID types.ChainID, fromHeight, toHeight uint64) (int64, error) {
+	var total int64
+	for start := fromHeight; start <= toHeight; start += txCountBatch {
+		end := min(start+txCountBatch-1, toHeight)
+
+		res, err := s.conn(chainID).ExecContext(ctx, `
+			UPDATE blocks b
+			SET tx_count = (
+				SELECT COUNT(*) FROM transactions t
+				WHERE t.chain_id = b.chain_id AND t.block_height = b.height AND t.block_hash = b.hash
+			)
+			WHERE b.chain_id = $1 AND b.height BETWEEN $2 AND $3
+		`, string(chainID), start, end)
+		if err != nil {
+			return total, fmt.Errorf("recomputing tx counts %d-%d: %w", start, end, err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
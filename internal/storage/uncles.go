@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/internal/indexer/pkg/types"
+	"github.com/lib/pq"
+)
+
+// SetUncles enables recording the uncle (ommer) references of a chain's blocks in the
+// uncles table. Only pre-Merge ETH blocks have uncles; later blocks write nothing.
+func (s *Storage) SetUncles(chainID types.ChainID, enabled bool) {
+	s.uncleChains[chainID] = enabled
+}
+
+// writeUncles inserts the uncle references of blocks inside the write transaction
+func (s *Storage) writeUncles(ctx context.Context, tx *sql.Tx, chainID types.ChainID, blocks []types.Block) error {
+	if !s.uncleChains[chainID] {
+		return nil
+	}
+
+	var n int
+	for _, b := range blocks {
+		n += len(b.Uncles)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("uncles", "chain_id", "block_height", "block_hash", "uncle_index", "uncle_hash"))
+	if err != nil {
+		return fmt.Errorf("preparing uncle insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, b := range blocks {
+		for i, uncle := range b.Uncles {
+			if _, err := stmt.ExecContext(ctx, string(b.ChainID), b.Height, b.Hash, i, uncle); err != nil {
+				return fmt.Errorf("inserting uncle %d of block %d: %w", i, b.Height, err)
+			}
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("flushing uncle inserts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// SetStrictWriteValidation enables continuity checks on every block write for a chain:
+// heights must be consecutive and each block must be parented on the one before it,
+// starting from the stored checkpoint.
+func (s *Storage) SetStrictWriteValidation(chainID types.ChainID, enabled bool) {
+	s.strictChains[chainID] = enabled
+}
+
+// checkContinuity validates blocks against the checkpoint inside the write transaction.
+// The checkpoint row is locked so a concurrent writer cannot move it underneath us.
+func (s *Storage) checkContinuity(ctx context.Context, tx *sql.Tx, chainID types.ChainID, blocks []types.Block) error {
+	if !s.strictChains[chainID] {
+		return nil
+	}
+
+	var cp types.Checkpoint
+	err := tx.QueryRowContext(ctx, `
+		SELECT last_height, last_hash
+		FROM checkpoints
+		WHERE chain_id = $1
+		FOR UPDATE
+	`, string(chainID)).Scan(&cp.LastHeight, &cp.LastHash)
+	if err == sql.ErrNoRows {
+		return validateContinuity(nil, blocks)
+	}
+	if err != nil {
+		return fmt.Errorf("querying checkpoint: %w", err)
+	}
+
+	return validateContinuity(&cp, blocks)
+}
+
+// validateContinuity checks that blocks extend cp without gaps or broken parent links.
+// A nil checkpoint, or one without a hash (fresh InitCheckpoint), only anchors heights.
+func validateContinuity(cp *types.Checkpoint, blocks []types.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	first := blocks[0]
+	if cp != nil {
+		// The genesis block is the one write allowed onto a fresh checkpoint at its own height
+		genesis := first.Height == 0 && cp.LastHeight == 0 && cp.LastHash == ""
+		if first.Height != cp.LastHeight+1 && !genesis {
+			return fmt.Errorf("discontinuous write: first block %d does not follow checkpoint %d", first.Height, cp.LastHeight)
+		}
+		if cp.LastHash != "" && first.ParentHash != cp.LastHash {
+			return fmt.Errorf("discontinuous write: block %d parent %s does not match checkpoint hash %s", first.Height, first.ParentHash, cp.LastHash)
+		}
+	}
+
+	for i := 1; i < len(blocks); i++ {
+		prev, b := blocks[i-1], blocks[i]
+		if b.Height != prev.Height+1 {
+			return fmt.Errorf("discontinuous write: block %d follows block %d", b.Height, prev.Height)
+		}
+		if b.ParentHash != prev.Hash {
+			return fmt.Errorf("discontinuous write: block %d parent %s does not match block %d hash %s", b.Height, b.ParentHash, prev.Height, prev.Hash)
+		}
+	}
+
+	return nil
+}
+
+// ErrRollbackBelowFinalized is returned by Rollback when the finality guard is
+// enabled and the rollback would orphan finalized blocks. Finalized data is never
+// rewritten automatically, so this needs the same manual intervention as a reorg
+// deeper than max_reorg_depth.
+var ErrRollbackBelowFinalized = errors.New("rollback below finalized height")
+
+// SetRollbackFinalityGuard enables refusing rollbacks below the chain's highest
+// finalized block, so a faulty reorg detection cannot orphan finalized data.
+func (s *Storage) SetRollbackFinalityGuard(chainID types.ChainID, enabled bool) {
+	s.finalityGuards[chainID] = enabled
+}
+
+// checkRollbackFinality validates a rollback target inside the rollback transaction
+func (s *Storage) checkRollbackFinality(ctx context.Context, tx *sql.Tx, chainID types.ChainID, toHeight uint64) error {
+	if !s.finalityGuards[chainID] {
+		return nil
+	}
+
+	var finalized sql.NullInt64
+	err := tx.QueryRowContext(ctx, `
+		SELECT MAX(height)
+		FROM blocks
+		WHERE chain_id = $1 AND status = 'finalized'
+	`, string(chainID)).Scan(&finalized)
+	if err != nil {
+		return fmt.Errorf("querying finalized height: %w", err)
+	}
+
+	return validateRollback(finalized, toHeight)
+}
+
+// validateRollback checks that rolling back to toHeight keeps every finalized
+// block: blocks above toHeight are orphaned, so toHeight itself may be finalized.
+func validateRollback(finalized sql.NullInt64, toHeight uint64) error {
+	if finalized.Valid && toHeight < uint64(finalized.Int64) {
+		return fmt.Errorf("%w: rollback to %d would orphan finalized blocks up to %d", ErrRollbackBelowFinalized, toHeight, finalized.Int64)
+	}
+	return nil
+}
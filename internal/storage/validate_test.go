@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+func TestValidateContinuity(t *testing.T) {
+	chain := []types.Block{
+		{Height: 11, Hash: "h11", ParentHash: "h10"},
+		{Height: 12, Hash: "h12", ParentHash: "h11"},
+		{Height: 13, Hash: "h13", ParentHash: "h12"},
+	}
+	cp := &types.Checkpoint{LastHeight: 10, LastHash: "h10"}
+
+	tests := []struct {
+		name    string
+		cp      *types.Checkpoint
+		mutate  func([]types.Block)
+		wantErr bool
+	}{
+		{name: "valid", cp: cp},
+		{name: "no checkpoint", cp: nil},
+		{name: "fresh checkpoint without hash", cp: &types.Checkpoint{LastHeight: 10}},
+		{
+			name: "genesis onto fresh checkpoint",
+			cp:   &types.Checkpoint{LastHeight: 0},
+			mutate: func(b []types.Block) {
+				b[0] = types.Block{Height: 0, Hash: "h0"}
+				b[1] = types.Block{Height: 1, Hash: "h1", ParentHash: "h0"}
+				b[2] = types.Block{Height: 2, Hash: "h2", ParentHash: "h1"}
+			},
+		},
+		{
+			name:    "genesis after genesis written",
+			cp:      &types.Checkpoint{LastHeight: 0, LastHash: "h0"},
+			mutate:  func(b []types.Block) { b[0] = types.Block{Height: 0, Hash: "h0"} },
+			wantErr: true,
+		},
+		{name: "broken parent link", cp: cp, mutate: func(b []types.Block) { b[2].ParentHash = "other" }, wantErr: true},
+		{name: "height gap", cp: cp, mutate: func(b []types.Block) { b[2].Height = 14 }, wantErr: true},
+		{name: "does not follow checkpoint height", cp: &types.Checkpoint{LastHeight: 9, LastHash: "h10"}, wantErr: true},
+		{name: "does not match checkpoint hash", cp: &types.Checkpoint{LastHeight: 10, LastHash: "x"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := append([]types.Block(nil), chain...)
+			if tt.mutate != nil {
+				tt.mutate(blocks)
+			}
+			err := validateContinuity(tt.cp, blocks)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateContinuity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRollback(t *testing.T) {
+	finalized := sql.NullInt64{Int64: 100, Valid: true}
+
+	if err := validateRollback(finalized, 99); !errors.Is(err, ErrRollbackBelowFinalized) {
+		t.Errorf("expected ErrRollbackBelowFinalized below the finalized height, got %v", err)
+	}
+	if err := validateRollback(finalized, 100); err != nil {
+		t.Errorf("rollback to the finalized height keeps it: %v", err)
+	}
+	if err := validateRollback(finalized, 150); err != nil {
+		t.Errorf("unexpected error above the finalized height: %v", err)
+	}
+	if err := validateRollback(sql.NullInt64{}, 0); err != nil {
+		t.Errorf("unexpected error with nothing finalized: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"sync/atomic"
+
+	"github.com/internal/indexer/pkg/types"
+	"github.com/lib/pq"
+)
+
+const zeroAddress = "0x0000000000000000000000000000000000000000"
+
+// writeVerifier recomputes a chain's write aggregates in SQL and reports divergence
+type writeVerifier struct {
+	logger     *slog.Logger
+	mismatches atomic.Uint64
+}
+
+// SetWriteVerification enables dual-write verification for a chain: after
+// WriteBlocksWithEvents inserts a batch, the address-stats and token-balance
+// deltas computed in Go are recomputed in SQL from the inserted rows. Divergences
+// are logged to logger and counted (see VerifyMismatches) but never fail the
+// write. This is a rollout aid for write-path rewrites; it roughly doubles the
+// aggregate work per batch.
+func (s *Storage) SetWriteVerification(chainID types.ChainID, logger *slog.Logger) {
+	s.verifiers[chainID] = &writeVerifier{logger: logger.With("chain", string(chainID))}
+}
+
+// VerifyMismatches returns how many divergent aggregates verification has found
+// for a chain since startup (always 0 when verification is off)
+func (s *Storage) VerifyMismatches(chainID types.ChainID) uint64 {
+	if v := s.verifiers[chainID]; v != nil {
+		return v.mismatches.Load()
+	}
+	return 0
+}
+
+// verifyAggregates compares the Go-computed deltas for blocks with an independent
+// SQL aggregation. It runs inside a savepoint so a failing query cannot abort the
+// surrounding write transaction.
+func (s *Storage) verifyAggregates(ctx context.Context, tx *sql.Tx, chainID types.ChainID, blocks []types.Block, stats map[string]*types.AddressStatsDiff, balances map[string]map[string]*big.Int) {
+	v := s.verifiers[chainID]
+	if v == nil || len(blocks) == 0 {
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT write_verify`); err != nil {
+		v.logger.Warn("write verification skipped", "error", err)
+		return
+	}
+
+	mismatches, err := verifyBatch(ctx, tx, chainID, blocks, stats, balances)
+	if err != nil {
+		v.logger.Warn("write verification failed", "error", err)
+		tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT write_verify`)
+		return
+	}
+	tx.ExecContext(ctx, `RELEASE SAVEPOINT write_verify`)
+
+	for _, m := range mismatches {
+		v.mismatches.Add(1)
+		v.logger.Warn("write verification mismatch",
+			"from", blocks[0].Height,
+			"to", blocks[len(blocks)-1].Height,
+			"detail", m,
+		)
+	}
+}
+
+func verifyBatch(ctx context.Context, tx *sql.Tx, chainID types.ChainID, blocks []types.Block, stats map[string]*types.AddressStatsDiff, balances map[string]map[string]*big.Int) ([]string, error) {
+	hashes := make([]string, len(blocks))
+	for i, b := range blocks {
+		hashes[i] = b.Hash
+	}
+	from, to := blocks[0].Height, blocks[len(blocks)-1].Height
+
+	sqlStats, err := queryAddressStatsDiff(ctx, tx, chainID, from, to, hashes)
+	if err != nil {
+		return nil, err
+	}
+	sqlBalances, err := queryTokenBalanceDiff(ctx, tx, chainID, from, to, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(compareAddressStats(stats, sqlStats), compareTokenBalances(balances, sqlBalances)...), nil
+}
+
+// queryAddressStatsDiff aggregates the batch's transactions per address the same
+// way WriteBlocksWithEvents does: senders pay value+fee, receivers get value,
+// except that a reverted transaction moves no value
+func queryAddressStatsDiff(ctx context.Context, tx *sql.Tx, chainID types.ChainID, from, to uint64, hashes []string) (map[string]*types.AddressStatsDiff, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT address, SUM(balance)::text, SUM(received)::text, SUM(sent)::text, COUNT(*), MAX(block_height)
+		FROM (
+			SELECT from_addr AS address, -(CASE WHEN tx_status = 'reverted' THEN 0 ELSE COALESCE(value, 0) END + COALESCE(fee, 0)) AS balance,
+				0 AS received, CASE WHEN tx_status = 'reverted' THEN 0 ELSE COALESCE(value, 0) END AS sent, block_height
+			FROM transactions
+			WHERE chain_id = $1 AND block_height BETWEEN $2 AND $3 AND block_hash = ANY($4) AND from_addr IS NOT NULL
+			UNION ALL
+			SELECT to_addr, CASE WHEN tx_status = 'reverted' THEN 0 ELSE COALESCE(value, 0) END,
+				CASE WHEN tx_status = 'reverted' THEN 0 ELSE COALESCE(value, 0) END, 0, block_height
+			FROM transactions
+			WHERE chain_id = $1 AND block_height BETWEEN $2 AND $3 AND block_hash = ANY($4) AND to_addr IS NOT NULL
+		) t
+		GROUP BY address
+	`, string(chainID), from, to, pq.Array(hashes))
+	if err != nil {
+		return nil, fmt.Errorf("aggregating address stats: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]*types.AddressStatsDiff)
+	for rows.Next() {
+		var addr, balance, received, sent string
+		d := &types.AddressStatsDiff{}
+		if err := rows.Scan(&addr, &balance, &received, &sent, &d.TxCount, &d.LastSeenHeight); err != nil {
+			return nil, fmt.Errorf("scanning address stats: %w", err)
+		}
+		d.BalanceDelta, _ = new(big.Int).SetString(balance, 10)
+		d.TotalReceived, _ = new(big.Int).SetString(received, 10)
+		d.TotalSent, _ = new(big.Int).SetString(sent, 10)
+		out[addr] = d
+	}
+	return out, rows.Err()
+}
+
+// queryTokenBalanceDiff aggregates the batch's token transfers per holder and
+// token, ignoring the zero address (mints and burns)
+func queryTokenBalanceDiff(ctx context.Context, tx *sql.Tx, chainID types.ChainID, from, to uint64, hashes []string) (map[string]map[string]*big.Int, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT address, token_address, SUM(delta)::text
+		FROM (
+			SELECT from_addr AS address, token_address, -COALESCE(amount, 0) AS delta
+			FROM token_transfers
+			WHERE chain_id = $1 AND block_height BETWEEN $2 AND $3 AND block_hash = ANY($4) AND from_addr NOT IN ('', $5)
+			UNION ALL
+			SELECT to_addr, token_address, COALESCE(amount, 0)
+			FROM token_transfers
+			WHERE chain_id = $1 AND block_height BETWEEN $2 AND $3 AND block_hash = ANY($4) AND to_addr NOT IN ('', $5)
+		) t
+		GROUP BY address, token_address
+	`, string(chainID), from, to, pq.Array(hashes), zeroAddress)
+	if err != nil {
+		return nil, fmt.Errorf("aggregating token balances: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]map[string]*big.Int)
+	for rows.Next() {
+		var addr, token, delta string
+		if err := rows.Scan(&addr, &token, &delta); err != nil {
+			return nil, fmt.Errorf("scanning token balances: %w", err)
+		}
+		if out[addr] == nil {
+			out[addr] = make(map[string]*big.Int)
+		}
+		out[addr][token], _ = new(big.Int).SetString(delta, 10)
+	}
+	return out, rows.Err()
+}
+
+// compareAddressStats describes every address whose Go and SQL deltas differ
+func compareAddressStats(goStats, sqlStats map[string]*types.AddressStatsDiff) []string {
+	var out []string
+	for _, addr := range unionKeys(goStats, sqlStats) {
+		g, q := goStats[addr], sqlStats[addr]
+		if g == nil {
+			g = &types.AddressStatsDiff{}
+		}
+		if q == nil {
+			q = &types.AddressStatsDiff{}
+		}
+		if !bigEqual(g.BalanceDelta, q.BalanceDelta) || !bigEqual(g.TotalReceived, q.TotalReceived) ||
+			!bigEqual(g.TotalSent, q.TotalSent) || g.TxCount != q.TxCount || g.LastSeenHeight != q.LastSeenHeight {
+			out = append(out, fmt.Sprintf("address_stats %s: go=%s sql=%s", addr, formatStatsDiff(g), formatStatsDiff(q)))
+		}
+	}
+	return out
+}
+
+// compareTokenBalances describes every holder/token pair whose Go and SQL deltas differ
+func compareTokenBalances(goBal, sqlBal map[string]map[string]*big.Int) []string {
+	var out []string
+	for _, addr := range unionKeys(goBal, sqlBal) {
+		for _, token := range unionKeys(goBal[addr], sqlBal[addr]) {
+			g, q := goBal[addr][token], sqlBal[addr][token]
+			if !bigEqual(g, q) {
+				out = append(out, fmt.Sprintf("token_balances %s/%s: go=%s sql=%s", addr, token, bigString(g), bigString(q)))
+			}
+		}
+	}
+	return out
+}
+
+// unionKeys returns the keys of a and b, sorted so reports are stable
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// bigEqual treats nil as zero
+func bigEqual(a, b *big.Int) bool {
+	return bigOrZero(a).Cmp(bigOrZero(b)) == 0
+}
+
+func bigOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
+
+func bigString(v *big.Int) string {
+	return bigOrZero(v).String()
+}
+
+func formatStatsDiff(d *types.AddressStatsDiff) string {
+	return fmt.Sprintf("{balance:%s received:%s sent:%s txs:%d last_seen:%d}",
+		bigString(d.BalanceDelta), bigString(d.TotalReceived), bigString(d.TotalSent), d.TxCount, d.LastSeenHeight)
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+func TestCompareAddressStats(t *testing.T) {
+	goStats := map[string]*types.AddressStatsDiff{
+		"0xa": {BalanceDelta: big.NewInt(-110), TotalSent: big.NewInt(100), TxCount: 1, LastSeenHeight: 5},
+		"0xb": {BalanceDelta: big.NewInt(100), TotalReceived: big.NewInt(100), TxCount: 1, LastSeenHeight: 5},
+	}
+	sqlStats := map[string]*types.AddressStatsDiff{
+		// Zero-valued fields come back as 0 rather than nil
+		"0xa": {BalanceDelta: big.NewInt(-110), TotalReceived: big.NewInt(0), TotalSent: big.NewInt(100), TxCount: 1, LastSeenHeight: 5},
+		"0xb": {BalanceDelta: big.NewInt(100), TotalReceived: big.NewInt(100), TotalSent: big.NewInt(0), TxCount: 1, LastSeenHeight: 5},
+	}
+	if got := compareAddressStats(goStats, sqlStats); len(got) != 0 {
+		t.Fatalf("expected no mismatches, got %v", got)
+	}
+
+	sqlStats["0xb"].TxCount = 2
+	sqlStats["0xc"] = &types.AddressStatsDiff{BalanceDelta: big.NewInt(1), TxCount: 1}
+	got := compareAddressStats(goStats, sqlStats)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 mismatches, got %v", got)
+	}
+	if !strings.Contains(got[0], "0xb") || !strings.Contains(got[1], "0xc") {
+		t.Errorf("unexpected mismatches: %v", got)
+	}
+}
+
+func TestCompareTokenBalances(t *testing.T) {
+	goBal := map[string]map[string]*big.Int{
+		"0xa": {"0xt1": big.NewInt(-5), "0xt2": big.NewInt(0)},
+		"0xb": {"0xt1": big.NewInt(5)},
+	}
+	sqlBal := map[string]map[string]*big.Int{
+		"0xa": {"0xt1": big.NewInt(-5)},
+		"0xb": {"0xt1": big.NewInt(4)},
+	}
+
+	got := compareTokenBalances(goBal, sqlBal)
+	if len(got) != 1 || !strings.Contains(got[0], "0xb/0xt1: go=5 sql=4") {
+		t.Errorf("expected one 0xb/0xt1 mismatch, got %v", got)
+	}
+}
+
+func TestVerifyMismatchesDisabled(t *testing.T) {
+	s := New(nil)
+	if n := s.VerifyMismatches(types.ChainETH); n != 0 {
+		t.Errorf("expected 0 mismatches without verification, got %d", n)
+	}
+}
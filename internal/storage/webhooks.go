@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// CreateWebhookSubscription inserts sub, filling in its ID and CreatedAt. An
+// existing subscription for the same address and callback URL gets the new
+// secret instead of a second row.
+func (s *Storage) CreateWebhookSubscription(ctx context.Context, sub *types.WebhookSubscription) error {
+	err := s.conn(sub.ChainID).QueryRowContext(ctx, `
+		INSERT INTO webhook_subscriptions (chain_id, address, callback_url, secret)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chain_id, address, callback_url) DO UPDATE SET secret = EXCLUDED.secret
+		RETURNING id, created_at
+	`, string(sub.ChainID), sub.Address, sub.CallbackURL, sub.Secret).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebhookSubscription removes a chain's subscription by ID, reporting
+// whether it existed
+func (s *Storage) DeleteWebhookSubscription(ctx context.Context, chainID types.ChainID, id int64) (bool, error) {
+	res, err := s.conn(chainID).ExecContext(ctx, `
+		DELETE FROM webhook_subscriptions WHERE chain_id = $1 AND id = $2
+	`, string(chainID), id)
+	if err != nil {
+		return false, fmt.Errorf("deleting webhook subscription: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetWebhookSubscriptions returns all of a chain's subscriptions, secrets included
+func (s *Storage) GetWebhookSubscriptions(ctx context.Context, chainID types.ChainID) ([]types.WebhookSubscription, error) {
+	rows, err := s.conn(chainID).QueryContext(ctx, `
+		SELECT id, chain_id, address, callback_url, secret, created_at
+		FROM webhook_subscriptions
+		WHERE chain_id = $1
+		ORDER BY id
+	`, string(chainID))
+	if err != nil {
+		return nil, fmt.Errorf("querying webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []types.WebhookSubscription{}
+	for rows.Next() {
+		var sub types.WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.ChainID, &sub.Address, &sub.CallbackURL, &sub.Secret, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
@@ -0,0 +1,88 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Channel is the broker channel block summaries travel on from the indexer to the API
+const Channel = "stream:blocks"
+
+// publishTimeout bounds one broker publish in Forward
+const publishTimeout = 2 * time.Second
+
+// Broker carries messages between processes (Redis pub/sub in practice).
+// Delivery is best effort: a message sent while nobody listens is lost.
+type Broker interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe returns the channel's messages until ctx is done, then closes it
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+}
+
+// Forward publishes every summary on h to b until ctx is done. It runs in the
+// indexer: when the broker is slower than indexing, summaries are dropped at
+// the hub instead of holding up block writes.
+func Forward(ctx context.Context, h *Hub, b Broker, logger *slog.Logger) {
+	summaries, unsubscribe := h.Subscribe("")
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-summaries:
+			payload, err := json.Marshal(s)
+			if err != nil {
+				continue
+			}
+			pubCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+			if err := b.Publish(pubCtx, Channel, payload); err != nil {
+				logger.Warn("publishing block summary failed", "chain", s.ChainID, "height", s.Height, "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// resubscribeBackoff is the wait before resubscribing after the broker
+// subscription fails or ends, doubling up to maxResubscribeBackoff
+var resubscribeBackoff = time.Second
+
+// maxResubscribeBackoff caps the doubling wait between resubscribes
+const maxResubscribeBackoff = 30 * time.Second
+
+// Receive publishes the summaries arriving on b to h until ctx is done. It runs
+// in the API server and feeds the stream endpoints. A subscription that fails
+// or ends (e.g. the broker restarted) is retried with backoff.
+func Receive(ctx context.Context, h *Hub, b Broker, logger *slog.Logger) {
+	backoff := resubscribeBackoff
+	for {
+		msgs, err := b.Subscribe(ctx, Channel)
+		if err == nil {
+			for payload := range msgs {
+				backoff = resubscribeBackoff // Delivering again, so retry quickly next time
+				var s BlockSummary
+				if err := json.Unmarshal(payload, &s); err != nil {
+					logger.Warn("discarding malformed block summary", "error", err)
+					continue
+				}
+				h.Publish(s)
+			}
+			err = errors.New("subscription ended")
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Warn("block stream subscription failed, retrying", "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxResubscribeBackoff)
+	}
+}
@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// SubscriberBuffer is how many summaries a subscriber may fall behind before
+// further ones are dropped for it
+const SubscriberBuffer = 64
+
+// BlockSummary is the compact form of a newly indexed block pushed to stream clients
+type BlockSummary struct {
+	ChainID    types.ChainID `json:"chain_id"`
+	Height     uint64        `json:"height"`
+	Hash       string        `json:"hash"`
+	ParentHash string        `json:"parent_hash"`
+	Timestamp  time.Time     `json:"timestamp"`
+	TxCount    int           `json:"tx_count"`
+	Miner      string        `json:"miner,omitempty"`
+}
+
+// Summarize returns the stream summary of b
+func Summarize(b types.Block) BlockSummary {
+	return BlockSummary{
+		ChainID:    b.ChainID,
+		Height:     b.Height,
+		Hash:       b.Hash,
+		ParentHash: b.ParentHash,
+		Timestamp:  b.Timestamp,
+		TxCount:    b.TxCount,
+		Miner:      b.Miner,
+	}
+}
+
+// Hub fans block summaries out to subscribers, one set of channels per chain.
+// Publish never blocks: a subscriber whose buffer is full misses the summary.
+type Hub struct {
+	mu      sync.RWMutex
+	subs    map[types.ChainID]map[chan BlockSummary]struct{} // "" receives every chain
+	dropped atomic.Uint64
+}
+
+// NewHub creates an empty hub
+func NewHub() *Hub {
+	return &Hub{subs: make(map[types.ChainID]map[chan BlockSummary]struct{})}
+}
+
+// Subscribe returns a channel of summaries for chainID ("" for every chain) and
+// a function that unsubscribes and closes it
+func (h *Hub) Subscribe(chainID types.ChainID) (<-chan BlockSummary, func()) {
+	ch := make(chan BlockSummary, SubscriberBuffer)
+
+	h.mu.Lock()
+	if h.subs[chainID] == nil {
+		h.subs[chainID] = make(map[chan BlockSummary]struct{})
+	}
+	h.subs[chainID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[chainID], ch)
+			if len(h.subs[chainID]) == 0 {
+				delete(h.subs, chainID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers b to the subscribers of its chain and of every chain
+func (h *Hub) Publish(b BlockSummary) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, chainID := range []types.ChainID{b.ChainID, ""} {
+		for ch := range h.subs[chainID] {
+			select {
+			case ch <- b:
+			default:
+				h.dropped.Add(1)
+			}
+		}
+	}
+}
+
+// PublishBlocks publishes the summary of each block in order
+func (h *Hub) PublishBlocks(blocks []types.Block) {
+	for _, b := range blocks {
+		h.Publish(Summarize(b))
+	}
+}
+
+// Dropped returns how many summaries were dropped for slow subscribers
+func (h *Hub) Dropped() uint64 {
+	return h.dropped.Load()
+}
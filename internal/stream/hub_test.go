@@ -0,0 +1,184 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+func TestHub_FanOut(t *testing.T) {
+	h := NewHub()
+	eth, unsubETH := h.Subscribe(types.ChainETH)
+	defer unsubETH()
+	all, unsubAll := h.Subscribe("")
+	defer unsubAll()
+
+	h.PublishBlocks([]types.Block{
+		{ChainID: types.ChainBTC, Height: 1, Hash: "b1"},
+		{ChainID: types.ChainETH, Height: 2, Hash: "e2", TxCount: 3},
+	})
+
+	if b := <-eth; b.Hash != "e2" || b.TxCount != 3 {
+		t.Errorf("expected the ETH block on the ETH subscription, got %+v", b)
+	}
+	if len(eth) != 0 {
+		t.Errorf("expected no BTC block on the ETH subscription")
+	}
+	if b1, b2 := <-all, <-all; b1.Hash != "b1" || b2.Hash != "e2" {
+		t.Errorf("expected both blocks in order on the all-chains subscription, got %s, %s", b1.Hash, b2.Hash)
+	}
+}
+
+func TestHub_DropsForSlowSubscriber(t *testing.T) {
+	h := NewHub()
+	slow, unsubscribe := h.Subscribe(types.ChainETH)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < SubscriberBuffer+5; i++ {
+			h.Publish(BlockSummary{ChainID: types.ChainETH, Height: uint64(i)})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a subscriber that is not reading")
+	}
+
+	if len(slow) != SubscriberBuffer || h.Dropped() != 5 {
+		t.Errorf("expected %d buffered and 5 dropped, got %d and %d", SubscriberBuffer, len(slow), h.Dropped())
+	}
+	if b := <-slow; b.Height != 0 {
+		t.Errorf("expected the oldest summaries kept, got height %d first", b.Height)
+	}
+
+	unsubscribe()
+	unsubscribe() // Safe to call twice
+	h.Publish(BlockSummary{ChainID: types.ChainETH})
+	for range slow {
+	}
+}
+
+// chanBroker delivers published messages to one subscriber in process
+type chanBroker struct {
+	msgs chan []byte
+}
+
+func (b *chanBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	b.msgs <- payload
+	return nil
+}
+
+func (b *chanBroker) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m := <-b.msgs:
+				out <- m
+			}
+		}
+	}()
+	return out, nil
+}
+
+func TestForwardReceive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	broker := &chanBroker{msgs: make(chan []byte, 1)}
+
+	indexer, api := NewHub(), NewHub()
+	received, unsubscribe := api.Subscribe(types.ChainBTC)
+	defer unsubscribe()
+
+	go Forward(ctx, indexer, broker, logger)
+	go Receive(ctx, api, broker, logger)
+
+	// Forward subscribes asynchronously; publish until the summary comes through
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	deadline := time.After(time.Second)
+	for {
+		indexer.Publish(BlockSummary{ChainID: types.ChainBTC, Height: 840000, Hash: "h", Timestamp: ts})
+		select {
+		case b := <-received:
+			if b.Height != 840000 || b.Hash != "h" || !b.Timestamp.Equal(ts) {
+				t.Errorf("summary changed in transit: %+v", b)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("summary did not reach the API hub")
+		}
+	}
+}
+
+// flakyBroker fails the first subscribes and ends the first subscription it
+// grants, then delivers published messages like chanBroker
+type flakyBroker struct {
+	chanBroker
+	failures   atomic.Int32 // Subscribes left to fail
+	subscribes atomic.Int32
+}
+
+func (b *flakyBroker) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	if b.failures.Add(-1) >= 0 {
+		return nil, errors.New("connection refused")
+	}
+	if b.subscribes.Add(1) == 1 {
+		out := make(chan []byte)
+		close(out) // The broker dropped the subscription
+		return out, nil
+	}
+	return b.chanBroker.Subscribe(ctx, channel)
+}
+
+func TestReceive_Resubscribes(t *testing.T) {
+	defer func(d time.Duration) { resubscribeBackoff = d }(resubscribeBackoff)
+	resubscribeBackoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := &flakyBroker{chanBroker: chanBroker{msgs: make(chan []byte, 1)}}
+	broker.failures.Store(2)
+
+	api := NewHub()
+	received, unsubscribe := api.Subscribe(types.ChainETH)
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Receive(ctx, api, broker, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}()
+
+	broker.Publish(ctx, Channel, []byte(`{"chain_id":"eth","height":7}`))
+	select {
+	case b := <-received:
+		if b.Height != 7 {
+			t.Errorf("unexpected summary: %+v", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the summary after resubscribing")
+	}
+	if n := broker.subscribes.Load(); n != 2 {
+		t.Errorf("expected a resubscribe after the subscription ended, got %d subscriptions", n)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Receive to return once ctx is done")
+	}
+}
@@ -0,0 +1,400 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// Request headers sent with every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature" // "sha256=" + hex HMAC-SHA256 of the body keyed by the subscription secret
+	DeliveryHeader  = "X-Webhook-Delivery"  // Payload DeliveryID, the same on every attempt
+)
+
+// maxRetryBackoff caps the doubling wait between attempts
+const maxRetryBackoff = time.Minute
+
+// ErrInvalidSubscription is returned by Register for a subscription that cannot be delivered to
+var ErrInvalidSubscription = errors.New("invalid webhook subscription")
+
+// Store persists subscriptions (implemented by storage.Storage)
+type Store interface {
+	CreateWebhookSubscription(ctx context.Context, sub *types.WebhookSubscription) error
+	DeleteWebhookSubscription(ctx context.Context, chainID types.ChainID, id int64) (bool, error)
+	GetWebhookSubscriptions(ctx context.Context, chainID types.ChainID) ([]types.WebhookSubscription, error)
+}
+
+// Config tunes a Dispatcher
+type Config struct {
+	Workers      int           // Deliveries sent concurrently
+	QueueSize    int           // Deliveries waiting for a worker before new ones are dropped
+	Timeout      time.Duration // Per-attempt request timeout
+	MaxAttempts  int           // Attempts per delivery, including the first
+	RetryBackoff time.Duration // Wait before the first retry, doubling after each
+}
+
+// Payload is the JSON body POSTed to a callback: the activity on one subscribed
+// address from one committed batch of blocks
+type Payload struct {
+	DeliveryID     string                `json:"delivery_id"`
+	SubscriptionID int64                 `json:"subscription_id"`
+	ChainID        types.ChainID         `json:"chain"`
+	Address        string                `json:"address"`
+	Transactions   []WebhookTx           `json:"transactions,omitempty"`
+	TokenTransfers []types.TokenTransfer `json:"token_transfers,omitempty"`
+}
+
+// WebhookTx is a transaction as sent in a Payload, without its raw data
+type WebhookTx struct {
+	ChainID     types.ChainID     `json:"chain_id"`
+	BlockHeight uint64            `json:"block_height"`
+	BlockHash   string            `json:"block_hash"`
+	TxHash      string            `json:"tx_hash"`
+	TxIndex     int               `json:"tx_index"`
+	FromAddr    string            `json:"from_addr,omitempty"`
+	ToAddr      string            `json:"to_addr,omitempty"`
+	Value       string            `json:"value"`
+	Fee         string            `json:"fee,omitempty"`
+	GasUsed     uint64            `json:"gas_used,omitempty"`
+	TxStatus    string            `json:"tx_status,omitempty"`
+	Status      types.BlockStatus `json:"status"`
+}
+
+func newWebhookTx(t types.Transaction) WebhookTx {
+	return WebhookTx{
+		ChainID:     t.ChainID,
+		BlockHeight: t.BlockHeight,
+		BlockHash:   t.BlockHash,
+		TxHash:      t.TxHash,
+		TxIndex:     t.TxIndex,
+		FromAddr:    t.FromAddr,
+		ToAddr:      t.ToAddr,
+		Value:       t.Value,
+		Fee:         t.Fee,
+		GasUsed:     t.GasUsed,
+		TxStatus:    t.TxStatus,
+		Status:      t.Status,
+	}
+}
+
+type delivery struct {
+	id   string
+	sub  types.WebhookSubscription
+	body []byte
+}
+
+// Dispatcher POSTs new transactions and token transfers to the callbacks
+// subscribed to their addresses. Notify only matches and queues, so the write
+// path never waits on a callback; workers deliver with retries. Deliveries are
+// held in memory: a full queue drops new ones and a restart loses pending ones.
+type Dispatcher struct {
+	store  Store
+	cfg    Config
+	client *http.Client
+	logger *slog.Logger
+
+	mu   sync.RWMutex
+	subs map[types.ChainID]map[string][]types.WebhookSubscription // Keyed by normalized address
+
+	queue chan delivery
+}
+
+// New creates a dispatcher with no subscriptions; call Load before Run
+func New(store Store, cfg Config, logger *slog.Logger) *Dispatcher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+
+	return &Dispatcher{
+		store:  store,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		logger: logger.With("component", "webhooks"),
+		subs:   make(map[types.ChainID]map[string][]types.WebhookSubscription),
+		queue:  make(chan delivery, cfg.QueueSize),
+	}
+}
+
+// Load reads the stored subscriptions of chains
+func (d *Dispatcher) Load(ctx context.Context, chains []types.ChainID) error {
+	for _, chainID := range chains {
+		subs, err := d.store.GetWebhookSubscriptions(ctx, chainID)
+		if err != nil {
+			return err
+		}
+		d.mu.Lock()
+		d.subs[chainID] = make(map[string][]types.WebhookSubscription)
+		for _, sub := range subs {
+			d.add(sub)
+		}
+		d.mu.Unlock()
+	}
+	return nil
+}
+
+// add indexes sub, replacing a subscription with the same ID. Callers hold mu.
+func (d *Dispatcher) add(sub types.WebhookSubscription) {
+	byAddr := d.subs[sub.ChainID]
+	if byAddr == nil {
+		byAddr = make(map[string][]types.WebhookSubscription)
+		d.subs[sub.ChainID] = byAddr
+	}
+	addr := types.NormalizeAddress(sub.ChainID, sub.Address)
+	for i, existing := range byAddr[addr] {
+		if existing.ID == sub.ID {
+			byAddr[addr][i] = sub
+			return
+		}
+	}
+	byAddr[addr] = append(byAddr[addr], sub)
+}
+
+// Register stores sub and starts delivering to it. A secret is generated when
+// sub has none; registering the same address and callback again replaces the secret.
+func (d *Dispatcher) Register(ctx context.Context, sub *types.WebhookSubscription) error {
+	if sub.Address == "" {
+		return fmt.Errorf("%w: address is required", ErrInvalidSubscription)
+	}
+	u, err := url.Parse(sub.CallbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: callback_url must be an absolute http(s) URL", ErrInvalidSubscription)
+	}
+	sub.Address = types.NormalizeAddress(sub.ChainID, sub.Address)
+	if sub.Secret == "" {
+		sub.Secret = randomHex(32)
+	}
+
+	if err := d.store.CreateWebhookSubscription(ctx, sub); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.add(*sub)
+	d.mu.Unlock()
+	return nil
+}
+
+// Unregister deletes a chain's subscription, reporting whether it existed
+func (d *Dispatcher) Unregister(ctx context.Context, chainID types.ChainID, id int64) (bool, error) {
+	ok, err := d.store.DeleteWebhookSubscription(ctx, chainID, id)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for addr, subs := range d.subs[chainID] {
+		for i, sub := range subs {
+			if sub.ID != id {
+				continue
+			}
+			subs = append(subs[:i:i], subs[i+1:]...)
+			if len(subs) == 0 {
+				delete(d.subs[chainID], addr)
+			} else {
+				d.subs[chainID][addr] = subs
+			}
+			return true, nil
+		}
+	}
+	return true, nil
+}
+
+// List returns a chain's subscriptions without their secrets, oldest first
+func (d *Dispatcher) List(chainID types.ChainID) []types.WebhookSubscription {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	list := []types.WebhookSubscription{}
+	for _, subs := range d.subs[chainID] {
+		for _, sub := range subs {
+			sub.Secret = ""
+			list = append(list, sub)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Notify queues one delivery per subscription whose address sent or received
+// any of txs or transfers. A transaction matches on FromAddr and ToAddr only,
+// which for BTC are its first input's and first output's addresses. It never
+// blocks: deliveries that don't fit in the queue are dropped.
+func (d *Dispatcher) Notify(chainID types.ChainID, txs []types.Transaction, transfers []types.TokenTransfer) {
+	d.mu.RLock()
+	byAddr := d.subs[chainID]
+	if len(byAddr) == 0 {
+		d.mu.RUnlock()
+		return
+	}
+
+	activity := make(map[string]*Payload)
+	touch := func(addr string) *Payload {
+		addr = types.NormalizeAddress(chainID, addr)
+		if addr == "" || len(byAddr[addr]) == 0 {
+			return nil
+		}
+		p := activity[addr]
+		if p == nil {
+			p = &Payload{ChainID: chainID, Address: addr}
+			activity[addr] = p
+		}
+		return p
+	}
+	for _, tx := range txs {
+		from, to := touch(tx.FromAddr), touch(tx.ToAddr)
+		if from != nil {
+			from.Transactions = append(from.Transactions, newWebhookTx(tx))
+		}
+		if to != nil && to != from {
+			to.Transactions = append(to.Transactions, newWebhookTx(tx))
+		}
+	}
+	for _, tr := range transfers {
+		from, to := touch(tr.FromAddr), touch(tr.ToAddr)
+		if from != nil {
+			from.TokenTransfers = append(from.TokenTransfers, tr)
+		}
+		if to != nil && to != from {
+			to.TokenTransfers = append(to.TokenTransfers, tr)
+		}
+	}
+
+	var deliveries []delivery
+	for addr, p := range activity {
+		for _, sub := range byAddr[addr] {
+			payload := *p
+			payload.DeliveryID = randomHex(16)
+			payload.SubscriptionID = sub.ID
+			body, err := json.Marshal(payload)
+			if err != nil {
+				d.logger.Error("encoding webhook payload failed", "subscription", sub.ID, "error", err)
+				continue
+			}
+			deliveries = append(deliveries, delivery{id: payload.DeliveryID, sub: sub, body: body})
+		}
+	}
+	d.mu.RUnlock()
+
+	for _, dl := range deliveries {
+		select {
+		case d.queue <- dl:
+		default:
+			d.logger.Warn("webhook queue full, dropping delivery",
+				"chain", chainID, "subscription", dl.sub.ID, "address", dl.sub.Address)
+		}
+	}
+}
+
+// Run delivers queued notifications with cfg.Workers workers until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range d.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case dl := <-d.queue:
+					d.deliver(ctx, dl)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// deliver POSTs dl, retrying with exponential backoff on network errors, 5xx,
+// 408 and 429. Other responses are final.
+func (d *Dispatcher) deliver(ctx context.Context, dl delivery) {
+	backoff := d.cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(ctx, dl)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= d.cfg.MaxAttempts {
+			d.logger.Warn("webhook delivery failed",
+				"subscription", dl.sub.ID,
+				"delivery", dl.id,
+				"attempts", attempt,
+				"error", err,
+			)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth retrying
+func (d *Dispatcher) post(ctx context.Context, dl delivery) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.sub.CallbackURL, bytes.NewReader(dl.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(dl.sub.Secret, dl.body))
+	req.Header.Set(DeliveryHeader, dl.id)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("callback returned %s", resp.Status)
+}
+
+// Sign returns the SignatureHeader value for body: "sha256=" followed by the
+// hex HMAC-SHA256 of body keyed by secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
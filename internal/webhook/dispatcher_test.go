@@ -0,0 +1,252 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/internal/indexer/pkg/types"
+)
+
+// memStore keeps subscriptions in memory
+type memStore struct {
+	mu     sync.Mutex
+	nextID int64
+	subs   map[int64]types.WebhookSubscription
+}
+
+func newMemStore() *memStore {
+	return &memStore{subs: make(map[int64]types.WebhookSubscription)}
+}
+
+func (m *memStore) CreateWebhookSubscription(ctx context.Context, sub *types.WebhookSubscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	sub.ID, sub.CreatedAt = m.nextID, time.Now()
+	m.subs[sub.ID] = *sub
+	return nil
+}
+
+func (m *memStore) DeleteWebhookSubscription(ctx context.Context, chainID types.ChainID, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subs[id]
+	if !ok || sub.ChainID != chainID {
+		return false, nil
+	}
+	delete(m.subs, id)
+	return true, nil
+}
+
+func (m *memStore) GetWebhookSubscriptions(ctx context.Context, chainID types.ChainID) ([]types.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var subs []types.WebhookSubscription
+	for _, sub := range m.subs {
+		if sub.ChainID == chainID {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+func testDispatcher(store Store, cfg Config) *Dispatcher {
+	return New(store, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRegister(t *testing.T) {
+	d := testDispatcher(newMemStore(), Config{})
+	ctx := context.Background()
+
+	sub := types.WebhookSubscription{ChainID: types.ChainETH, Address: "0xABC", CallbackURL: "https://example.com/hook"}
+	if err := d.Register(ctx, &sub); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if sub.ID == 0 || sub.Address != "0xabc" || len(sub.Secret) != 64 {
+		t.Errorf("expected a stored subscription with a normalized address and generated secret, got %+v", sub)
+	}
+
+	for _, bad := range []types.WebhookSubscription{
+		{ChainID: types.ChainETH, CallbackURL: "https://example.com/hook"},
+		{ChainID: types.ChainETH, Address: "0xabc", CallbackURL: "example.com/hook"},
+		{ChainID: types.ChainETH, Address: "0xabc", CallbackURL: "ftp://example.com/hook"},
+	} {
+		if err := d.Register(ctx, &bad); !errors.Is(err, ErrInvalidSubscription) {
+			t.Errorf("%+v: expected ErrInvalidSubscription, got %v", bad, err)
+		}
+	}
+
+	list := d.List(types.ChainETH)
+	if len(list) != 1 || list[0].Secret != "" {
+		t.Errorf("expected one listed subscription without its secret, got %+v", list)
+	}
+}
+
+func TestNotify_DeliversSignedPayload(t *testing.T) {
+	type received struct {
+		body      []byte
+		signature string
+		delivery  string
+	}
+	got := make(chan received, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{body, r.Header.Get(SignatureHeader), r.Header.Get(DeliveryHeader)}
+	}))
+	defer srv.Close()
+
+	store := newMemStore()
+	store.CreateWebhookSubscription(context.Background(), &types.WebhookSubscription{
+		ChainID: types.ChainETH, Address: "0xaaa", CallbackURL: srv.URL, Secret: "s3cret",
+	})
+	d := testDispatcher(store, Config{Workers: 1})
+	if err := d.Load(context.Background(), []types.ChainID{types.ChainETH}); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	d.Notify(types.ChainETH, []types.Transaction{
+		{TxHash: "0x1", FromAddr: "0xAAA", ToAddr: "0xbbb", Value: "5", RawData: []byte(`{"raw":true}`)},
+		{TxHash: "0x2", FromAddr: "0xccc", ToAddr: "0xddd"},
+		{TxHash: "0x3", FromAddr: "0xaaa", ToAddr: "0xaaa"},
+	}, []types.TokenTransfer{
+		{TxHash: "0x4", FromAddr: "0xbbb", ToAddr: "0xaaa", Amount: "7"},
+	})
+
+	var r received
+	select {
+	case r = <-got:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no delivery")
+	}
+	if r.signature != Sign("s3cret", r.body) {
+		t.Errorf("signature %q does not match the body", r.signature)
+	}
+	var p Payload
+	if err := json.Unmarshal(r.body, &p); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+
+	// Receivers see snake_case transaction fields and no raw data
+	var raw struct {
+		Transactions []map[string]json.RawMessage `json:"transactions"`
+	}
+	if err := json.Unmarshal(r.body, &raw); err != nil || len(raw.Transactions) == 0 {
+		t.Fatalf("decoding transactions: %v", err)
+	}
+	for _, key := range []string{"tx_hash", "from_addr", "to_addr", "value", "block_height"} {
+		if _, ok := raw.Transactions[0][key]; !ok {
+			t.Errorf("expected %q in transaction %s", key, r.body)
+		}
+	}
+	for _, key := range []string{"TxHash", "FromAddr", "RawData", "raw_data"} {
+		if _, ok := raw.Transactions[0][key]; ok {
+			t.Errorf("unexpected %q in transaction %s", key, r.body)
+		}
+	}
+	if p.DeliveryID != r.delivery || p.SubscriptionID != 1 || p.Address != "0xaaa" {
+		t.Errorf("unexpected payload header fields: %+v", p)
+	}
+	if len(p.Transactions) != 2 || p.Transactions[0].TxHash != "0x1" || p.Transactions[1].TxHash != "0x3" {
+		t.Errorf("expected txs 0x1 and 0x3 (a self-transfer once), got %+v", p.Transactions)
+	}
+	if len(p.TokenTransfers) != 1 || p.TokenTransfers[0].Amount != "7" {
+		t.Errorf("expected the received token transfer, got %+v", p.TokenTransfers)
+	}
+
+	// Nothing touching a subscribed address, and other chains, send nothing
+	d.Notify(types.ChainETH, []types.Transaction{{FromAddr: "0xccc", ToAddr: "0xddd"}}, nil)
+	d.Notify(types.ChainBTC, []types.Transaction{{FromAddr: "0xaaa"}}, nil)
+	select {
+	case r := <-got:
+		t.Errorf("unexpected delivery %s", r.body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDeliver_Retries(t *testing.T) {
+	var attempts atomic.Int32
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(status)
+		}
+	}))
+	defer srv.Close()
+
+	d := testDispatcher(newMemStore(), Config{MaxAttempts: 5, RetryBackoff: time.Millisecond})
+	dl := delivery{id: "d1", sub: types.WebhookSubscription{ID: 1, CallbackURL: srv.URL}, body: []byte(`{}`)}
+
+	// Server errors are retried until one succeeds
+	d.deliver(context.Background(), dl)
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("expected success on the 3rd attempt, got %d attempts", n)
+	}
+
+	// Other client errors are final
+	attempts.Store(0)
+	status = http.StatusBadRequest
+	d.deliver(context.Background(), dl)
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("expected a 400 not to be retried, got %d attempts", n)
+	}
+
+	// Attempts are capped
+	attempts.Store(-10)
+	status = http.StatusInternalServerError
+	d.cfg.MaxAttempts = 2
+	d.deliver(context.Background(), dl)
+	if n := attempts.Load(); n != -8 {
+		t.Errorf("expected 2 attempts, got %d", n+10)
+	}
+}
+
+func TestNotify_FullQueueDrops(t *testing.T) {
+	store := newMemStore()
+	d := testDispatcher(store, Config{QueueSize: 1})
+	d.Register(context.Background(), &types.WebhookSubscription{ChainID: types.ChainBTC, Address: "bc1q", CallbackURL: "http://localhost/hook"})
+	d.Register(context.Background(), &types.WebhookSubscription{ChainID: types.ChainBTC, Address: "bc1q", CallbackURL: "http://localhost/other"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Notify(types.ChainBTC, []types.Transaction{{ToAddr: "bc1q"}}, nil)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Notify blocked on a full queue")
+	}
+	if len(d.queue) != 1 {
+		t.Errorf("expected one queued delivery, got %d", len(d.queue))
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	d := testDispatcher(newMemStore(), Config{})
+	ctx := context.Background()
+	sub := types.WebhookSubscription{ChainID: types.ChainETH, Address: "0xabc", CallbackURL: "http://localhost/hook"}
+	d.Register(ctx, &sub)
+
+	if ok, err := d.Unregister(ctx, types.ChainBTC, sub.ID); ok || err != nil {
+		t.Errorf("expected another chain's id not to be found, got %v, %v", ok, err)
+	}
+	if ok, err := d.Unregister(ctx, types.ChainETH, sub.ID); !ok || err != nil {
+		t.Fatalf("Unregister: %v, %v", ok, err)
+	}
+	d.Notify(types.ChainETH, []types.Transaction{{FromAddr: "0xabc"}}, nil)
+	if len(d.queue) != 0 || len(d.List(types.ChainETH)) != 0 {
+		t.Errorf("expected no deliveries after unregistering")
+	}
+}
@@ -0,0 +1,22 @@
+package types
+
+import "strings"
+
+// NormalizeAddress returns addr in the form stored for chainID, so writes and lookups
+// compare equal. ETH addresses are case-insensitive hex and are lowercased (dropping
+// EIP-55 checksum casing); BTC addresses are case-sensitive base58 and kept as-is.
+func NormalizeAddress(chainID ChainID, addr string) string {
+	if chainID == ChainETH {
+		return strings.ToLower(addr)
+	}
+	return addr
+}
+
+// NormalizeAddresses applies NormalizeAddress to each address, returning a new slice
+func NormalizeAddresses(chainID ChainID, addrs []string) []string {
+	out := make([]string, len(addrs))
+	for i, a := range addrs {
+		out[i] = NormalizeAddress(chainID, a)
+	}
+	return out
+}
@@ -0,0 +1,23 @@
+package types
+
+import "testing"
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		chain ChainID
+		addr  string
+		want  string
+	}{
+		{ChainETH, "0x52908400098527886E0F7030069857D2E4169EE7", "0x52908400098527886e0f7030069857d2e4169ee7"},
+		{ChainETH, "0xabc", "0xabc"},
+		{ChainETH, "", ""},
+		{ChainBTC, "1BoatSLRHtKNngkdXEeobR76b53LETtpyT", "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"},
+		{ChainBTC, "coinbase", "coinbase"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeAddress(tt.chain, tt.addr); got != tt.want {
+			t.Errorf("NormalizeAddress(%s, %q) = %q, want %q", tt.chain, tt.addr, got, tt.want)
+		}
+	}
+}
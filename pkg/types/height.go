@@ -0,0 +1,14 @@
+package types
+
+// HeightBelow returns the height depth blocks below height, clamped to 0 so that a
+// depth at or past the height cannot wrap the uint64 around. A zero or negative
+// depth (a misconfiguration) leaves height unchanged.
+func HeightBelow(height uint64, depth int) uint64 {
+	if depth <= 0 {
+		return height
+	}
+	if uint64(depth) >= height {
+		return 0
+	}
+	return height - uint64(depth)
+}
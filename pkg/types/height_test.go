@@ -0,0 +1,30 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHeightBelow(t *testing.T) {
+	tests := []struct {
+		height uint64
+		depth  int
+		want   uint64
+	}{
+		{100, 12, 88},
+		{12, 12, 0},
+		{5, 12, 0},
+		{0, 12, 0},
+		{0, 0, 0},
+		{100, 0, 100},
+		{100, -1, 100},
+		{math.MaxUint64, 1, math.MaxUint64 - 1},
+		{1, math.MaxInt, 0},
+	}
+
+	for _, tt := range tests {
+		if got := HeightBelow(tt.height, tt.depth); got != tt.want {
+			t.Errorf("HeightBelow(%d, %d) = %d, want %d", tt.height, tt.depth, got, tt.want)
+		}
+	}
+}
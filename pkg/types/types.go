@@ -22,6 +22,12 @@ const (
 	StatusOrphaned  BlockStatus = "orphaned"
 )
 
+// Receipt status of the transaction that emitted an event
+const (
+	TxStatusSuccess  = "success"
+	TxStatusReverted = "reverted"
+)
+
 // Block represents a normalized block across chains
 type Block struct {
 	ChainID    ChainID
@@ -30,7 +36,21 @@ type Block struct {
 	ParentHash string
 	Timestamp  time.Time
 	Status     BlockStatus
-	RawData    []byte // JSON-encoded chain-specific data
+	Miner      string   // Block producer: ETH fee recipient, BTC coinbase payout address (empty if unknown)
+	TxCount    int      // Number of transactions in the block
+	Uncles     []string `json:",omitempty"` // Uncle (ommer) block hashes; ETH pre-Merge only
+	RawData    []byte   // JSON-encoded chain-specific data
+
+	// BTC-specific; 0 when the node did not report them
+	Size         int `json:",omitempty"` // Serialized size in bytes, including witness data
+	StrippedSize int `json:",omitempty"` // Serialized size without witness data
+	Weight       int `json:",omitempty"` // BIP 141 weight units
+}
+
+// BlockRef identifies a block by height and hash (used for prev/next navigation)
+type BlockRef struct {
+	Height uint64 `json:"height"`
+	Hash   string `json:"hash"`
 }
 
 // Transaction represents a normalized transaction
@@ -45,6 +65,8 @@ type Transaction struct {
 	Value       string // Decimal string (satoshi for BTC, wei for ETH)
 	Fee         string // Decimal string
 	GasUsed     uint64 // ETH only
+	LogCount    int    // Indexed events emitted by the tx (ETH, when store_log_count is enabled)
+	TxStatus    string // TxStatusSuccess/TxStatusReverted from the receipt (ETH, when fetch_receipts is enabled)
 	Status      BlockStatus
 	RawData     []byte
 }
@@ -63,7 +85,9 @@ type Event struct {
 	Data         []byte // Decoded event data as JSON
 	RawData      []byte // Original log as JSON
 	Status       BlockStatus
-	DecodeFailed bool // True if ABI decode failed
+	DecodeFailed bool   // True if ABI decode failed
+	Oversized    bool   // Decoded, but stored undecoded (DecodeFailed) for exceeding the size limit
+	TxStatus     string // TxStatusSuccess/TxStatusReverted from the receipt, empty if not fetched
 }
 
 // Contract represents an Ethereum smart contract
@@ -84,6 +108,12 @@ type Checkpoint struct {
 	UpdatedAt  time.Time
 }
 
+// PublishCheckpoint is a chain's delivery progress to the message broker
+type PublishCheckpoint struct {
+	Height     uint64 // Highest height delivered
+	Generation int64  // Incremented by every rollback
+}
+
 // NetworkStats holds statistical data for a chain
 type NetworkStats struct {
 	ChainID           ChainID
@@ -92,6 +122,52 @@ type NetworkStats struct {
 	TxsLastMinute     int
 	AvgBlockTime      float64
 	IndexerLagSeconds int64
+	FeesLastMinute    string          `json:",omitempty"` // Decimal sum of fees; omitted unless fee stats are enabled and complete for the chain
+	AvgFeePerTx       string          `json:",omitempty"` // Decimal mean fee over the same transactions
+	Settings          *ChainSettings  // nil until the indexer has published its settings
+	Indexing          *IndexingStatus // nil until the indexer has written a checkpoint
+}
+
+// Reorg is a chain reorganization the indexer detected and rolled back
+type Reorg struct {
+	ID             int64     `json:"id"`
+	ChainID        ChainID   `json:"chain"`
+	DetectedAt     time.Time `json:"detected_at"`
+	RollbackHeight uint64    `json:"rollback_height"` // Fork point; blocks above it were orphaned
+	Depth          int       `json:"depth"`
+	OldHash        string    `json:"old_hash"` // Stored hash that no longer matched the chain
+	NewHash        string    `json:"new_hash"` // The chain's hash at the same height
+}
+
+// WebhookSubscription asks for new activity on Address to be POSTed to CallbackURL,
+// signed with Secret
+type WebhookSubscription struct {
+	ID          int64     `json:"id"`
+	ChainID     ChainID   `json:"chain"`
+	Address     string    `json:"address"`
+	CallbackURL string    `json:"callback_url"`
+	Secret      string    `json:"secret,omitempty"` // Only returned when the subscription is created
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IndexingStatus is indexing progress derived from the checkpoints table, so the
+// API can report it without reaching the indexer's own metrics port
+type IndexingStatus struct {
+	CheckpointHeight    uint64
+	CheckpointUpdatedAt time.Time
+	LagSeconds          int64  // Now minus the timestamp of the checkpoint block
+	CheckpointAge       int64  // Seconds since the indexer last advanced the checkpoint
+	BlocksBehind        uint64 // Estimated blocks between the checkpoint and the chain tip
+}
+
+// ChainSettings is the indexer configuration for a chain, published to the
+// chain_settings table on startup so the API can read it
+type ChainSettings struct {
+	ConfirmationDepth int      // Blocks behind the tip before data is marked finalized
+	MaxReorgDepth     int      // Deepest reorg the indexer will roll back automatically
+	StartHeight       uint64   // Configured first height to index
+	Contracts         []string // Monitored contract addresses (lowercase, ETH only)
+	UpdatedAt         time.Time
 }
 
 // BlockSummary holds simplified block data for range queries
@@ -124,6 +200,26 @@ type AddressStats struct {
 	LastUpdatedAt   time.Time `json:"last_updated_at"`
 }
 
+// Ledger entry reasons
+const (
+	LedgerReasonTransfer = "transfer" // Native value sent or received
+	LedgerReasonFee      = "fee"      // Fee paid by the sender
+)
+
+// LedgerEntry is one signed native-value change to an address, derived from a
+// transaction. Summing an address's entries gives its balance.
+type LedgerEntry struct {
+	ChainID      ChainID     `json:"chain_id"`
+	BlockHeight  uint64      `json:"block_height"`
+	TxHash       string      `json:"tx_hash"`
+	TxIndex      int         `json:"tx_index"`
+	Direction    string      `json:"direction"` // "credit" or "debit"
+	Reason       string      `json:"reason"`    // LedgerReasonTransfer or LedgerReasonFee
+	Amount       string      `json:"amount"`    // Signed decimal string: positive for credits, negative for debits
+	Counterparty string      `json:"counterparty,omitempty"`
+	Status       BlockStatus `json:"status"`
+}
+
 // Token represents an ERC20/ERC721 token
 type Token struct {
 	ChainID         ChainID   `json:"chain_id"`
@@ -158,3 +254,30 @@ type TokenBalance struct {
 	Balance      string    `json:"balance"` // Numeric string
 	LastUpdated  time.Time `json:"last_updated"`
 }
+
+// TokenHolder is an address holding a token, ranked by balance (1 = largest)
+type TokenHolder struct {
+	Rank    int    `json:"rank"`
+	Address string `json:"address"`
+	Balance string `json:"balance"` // Numeric string
+}
+
+// TokenSummary is an address's transfer activity in one token
+type TokenSummary struct {
+	TokenAddress       string `json:"token_address"`
+	TransferCount      int    `json:"transfer_count"`
+	TotalIn            string `json:"total_in"`  // Numeric string
+	TotalOut           string `json:"total_out"` // Numeric string
+	Balance            string `json:"balance"`   // Numeric string, from token_balances
+	LastTransferHeight uint64 `json:"last_transfer_height"`
+}
+
+// AddressTokenSummary is an address's per-token transfer totals. Truncated is set
+// when the address has more transfers than were aggregated, in which case counts
+// and totals cover only the most recent ones; Balance is always the full balance.
+type AddressTokenSummary struct {
+	ChainID   ChainID        `json:"chain_id"`
+	Address   string         `json:"address"`
+	Tokens    []TokenSummary `json:"tokens"`
+	Truncated bool           `json:"truncated"`
+}
@@ -59,7 +59,7 @@ export function SearchInput({ chain, className }: SearchInputProps) {
         } else {
             // Hash (64/66 chars) or unknown -> Use Backend Search
             try {
-                const res = await apiSearch(term);
+                const res = await apiSearch(term, chain);
                 if (res) {
                     if (res.type === 'block') {
                         route = `/block/${chain}/${res.data.Hash}`;
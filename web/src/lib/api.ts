@@ -1,5 +1,5 @@
 import axios from "axios";
-import { Block, Transaction, TxResponse } from "@/types";
+import { Block, BlockSummary, Transaction, TxResponse } from "@/types";
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || "/api";
 
@@ -121,19 +121,26 @@ export const getTokenBalances = async (chain: string, address: string) => {
     return data;
 };
 
-export const getTokenTransfers = async (chain: string, address: string, limit = 20, offset = 0) => {
+export const getTokenTransfers = async (chain: string, address: string, limit = 20, cursor?: string) => {
     const { data } = await api.get(`/tokens/${chain}/${address}/transfers`, {
-        params: { limit, offset }
+        params: { limit, cursor }
     });
     return data;
 };
 
+// Calls onBlock with a summary of each block the indexer writes; returns a function that closes the stream
+export const streamBlocks = (chain: string, onBlock: (block: BlockSummary) => void) => {
+    const source = new EventSource(`${API_BASE_URL}/stream/blocks/${chain}`);
+    source.addEventListener("block", (e) => onBlock(JSON.parse((e as MessageEvent).data)));
+    return () => source.close();
+};
+
 export const getPendingTxs = async (chain: "btc" | "eth"): Promise<Transaction[]> => {
     const { data } = await api.get(`/txs/pending/${chain}`);
     return data;
 };
 
-export const search = async (q: string) => {
-    const { data } = await api.get(`/search`, { params: { q } });
+export const search = async (q: string, chain?: string) => {
+    const { data } = await api.get(`/search`, { params: { q, chain } });
     return data;
 };
@@ -12,6 +12,17 @@ export interface Block {
     RawData?: string; // JSON string
 }
 
+// Pushed by /stream/blocks/{chain} for each newly indexed block
+export interface BlockSummary {
+    chain_id: ChainID;
+    height: number;
+    hash: string;
+    parent_hash: string;
+    timestamp: string;
+    tx_count: number;
+    miner?: string;
+}
+
 export interface Transaction {
     ChainID: ChainID;
     BlockHeight: number;